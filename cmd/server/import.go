@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// runImportLiteLLM converts a LiteLLM config.yaml into letllm-go config.
+// Usage: server import-litellm [-o out.yaml] litellm-config.yaml
+func runImportLiteLLM(args []string) error {
+	fs := flag.NewFlagSet("import-litellm", flag.ContinueOnError)
+	out := fs.String("o", "", "write the converted config to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: import-litellm [-o out.yaml] <litellm-config.yaml>")
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("read litellm config: %w", err)
+	}
+	imported, err := config.ConvertLiteLLM(data)
+	if err != nil {
+		return err
+	}
+	b, err := imported.YAML()
+	if err != nil {
+		return fmt.Errorf("render config: %w", err)
+	}
+
+	for _, note := range imported.Notes {
+		fmt.Fprintln(os.Stderr, "note:", note)
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(*out, b, 0o600)
+}
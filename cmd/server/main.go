@@ -1,16 +1,80 @@
 package main
 
 import (
+	"fmt"
+	"os"
+
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/autoscale"
+	"github.com/luguanyu1234/letllm-go/internal/batch"
+	"github.com/luguanyu1234/letllm-go/internal/budget"
+	"github.com/luguanyu1234/letllm-go/internal/buildinfo"
+	"github.com/luguanyu1234/letllm-go/internal/cache"
+	"github.com/luguanyu1234/letllm-go/internal/compare"
 	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/erasure"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/health"
+	"github.com/luguanyu1234/letllm-go/internal/idempotency"
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/pii"
+	"github.com/luguanyu1234/letllm-go/internal/prompts"
 	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/redis"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
 	"github.com/luguanyu1234/letllm-go/internal/server"
+	"github.com/luguanyu1234/letllm-go/internal/sessions"
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/tracing"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
 	"go.uber.org/fx"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import-litellm" {
+		if err := runImportLiteLLM(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	fx.New(
 		config.Module,
+		buildinfo.Module,
+		ids.Module,
+		health.Module,
+		metrics.Module,
+		storage.Module,
+		redis.Module,
+		cache.Module,
+		keys.Module,
+		artifacts.Module,
+		usage.Module,
+		budget.Module,
+		idempotency.Module,
+		enrich.Module,
 		provider.Module,
+		admission.Module,
+		loadshed.Module,
+		tools.Module,
+		respcache.Module,
+		tracing.Module,
+		files.Module,
+		sessions.Module,
+		prompts.Module,
+		batch.Module,
+		autoscale.Module,
+		erasure.Module,
+		pii.Module,
+		compare.Module,
 		server.Module,
+		server.GRPCModule,
 	).Run()
 }
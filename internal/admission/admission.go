@@ -0,0 +1,267 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+)
+
+// defaultQueueTimeout bounds queueing when a limit does not set one
+const defaultQueueTimeout = 30 * time.Second
+
+// Rejection reasons
+const (
+	ReasonQueueFull    = "queue_full"
+	ReasonQueueTimeout = "queue_timeout"
+	// A low priority request found no free slot, or a queued one gave its
+	// place to a higher priority request
+	ReasonPriorityShed = "priority_shed"
+)
+
+// Ranks of the priority classes; lower ranks are served first
+const (
+	rankHigh = iota
+	rankNormal
+	rankLow
+)
+
+type priorityContextKey struct{}
+
+// WithPriority returns ctx carrying the priority class of the request's
+// caller (config.PriorityHigh, PriorityNormal or PriorityLow)
+func WithPriority(ctx context.Context, priority string) context.Context {
+	return context.WithValue(ctx, priorityContextKey{}, priority)
+}
+
+// priorityRank returns the rank of the priority class ctx carries; requests
+// without one are normal
+func priorityRank(ctx context.Context) int {
+	switch p, _ := ctx.Value(priorityContextKey{}).(string); p {
+	case config.PriorityHigh:
+		return rankHigh
+	case config.PriorityLow:
+		return rankLow
+	default:
+		return rankNormal
+	}
+}
+
+// RejectedError is returned when a request cannot be admitted
+type RejectedError struct {
+	// Scope is "model" or "provider", or "key", "model" or "global" for
+	// rate limits
+	Scope string
+	// Key is the model rule, provider name or key ID whose limit was hit
+	Key    string
+	Reason string
+	// RetryAfter is how long until a rate limit admits the request again
+	RetryAfter time.Duration
+}
+
+func (e *RejectedError) Error() string {
+	if e.Reason == ReasonRateLimited {
+		if e.Key == "" {
+			return fmt.Sprintf("%s rate limit exceeded", e.Scope)
+		}
+		return fmt.Sprintf("rate limit of %s %s exceeded", e.Scope, e.Key)
+	}
+	return fmt.Sprintf("%s %s is at capacity (%s)", e.Scope, e.Key, e.Reason)
+}
+
+// RetryAfterSeconds is the Retry-After to send with the rejection, at
+// least one second
+func (e *RejectedError) RetryAfterSeconds() int {
+	return max(1, int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// Controller tracks in-flight upstream requests per provider and per model
+// and enforces the configured concurrency caps and rate limits. Model caps
+// are checked before provider caps so that a queued heavy model does not
+// hold a slot that cheap models on the same provider could use.
+type Controller struct {
+	models []modelRule
+	rates  *rateLimiter
+
+	mu        sync.Mutex
+	providers map[string]*limiter
+	stats     map[string]*ProviderStats
+
+	inFlight *metrics.GaugeVec
+	queued   *metrics.GaugeVec
+	rejected *metrics.CounterVec
+}
+
+type modelRule struct {
+	pattern string
+	attrs   config.Attributes
+	lim     *limiter
+}
+
+// NewController builds a controller from the admission config
+func NewController(cfg *config.Config, m *metrics.Registry) *Controller {
+	c := &Controller{
+		rates:     newRateLimiter(cfg, m),
+		providers: make(map[string]*limiter),
+		stats:     make(map[string]*ProviderStats),
+		inFlight: m.Gauge("letllm_inflight_requests",
+			"Upstream requests currently in flight.", "provider", "model"),
+		queued: m.Gauge("letllm_admission_queued_requests",
+			"Requests waiting for a concurrency slot.", "scope", "key"),
+		rejected: m.Counter("letllm_admission_rejected_total",
+			"Requests rejected by concurrency admission control and rate limits.", "scope", "key", "reason"),
+	}
+	for _, mc := range cfg.Admission.Models {
+		c.models = append(c.models, modelRule{pattern: mc.Model, attrs: mc.Attributes, lim: newLimiter(mc.ConcurrencyLimit)})
+	}
+	for name, lim := range cfg.Admission.Providers {
+		c.providers[name] = newLimiter(lim)
+	}
+	return c
+}
+
+// Acquire admits a request for model on providerName, waiting for a slot if
+// the model or provider is at capacity. attrs are the request's enrichment
+// attributes, matched against model rules. The priority class ctx carries
+// orders the wait; low priority requests are refused instead of waiting.
+// The returned release func must be called once the upstream request,
+// including any stream, has finished. Requests over the rate limit of
+// their key, model or the gateway are refused without waiting.
+func (c *Controller) Acquire(ctx context.Context, providerName, model string, attrs map[string]string) (func(), error) {
+	if rejected := c.rates.take(ctx, model); rejected != nil {
+		c.rejected.Inc(rejected.Scope, rejected.Key, rejected.Reason)
+		return nil, rejected
+	}
+
+	c.track(providerName, 0, 1)
+	defer c.track(providerName, 0, -1)
+
+	var held []*limiter
+	releaseHeld := func() {
+		for _, l := range held {
+			l.release()
+		}
+	}
+
+	if rule := c.matchModel(model, attrs); rule != nil {
+		if err := c.acquire(ctx, rule.lim, "model", rule.pattern); err != nil {
+			return nil, err
+		}
+		held = append(held, rule.lim)
+	}
+	if lim := c.providerLimiter(providerName); lim != nil {
+		if err := c.acquire(ctx, lim, "provider", providerName); err != nil {
+			releaseHeld()
+			return nil, err
+		}
+		held = append(held, lim)
+	}
+
+	c.inFlight.Add(1, providerName, model)
+	c.track(providerName, 1, 0)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.inFlight.Add(-1, providerName, model)
+			c.track(providerName, -1, 0)
+			releaseHeld()
+		})
+	}, nil
+}
+
+// Spend counts the tokens a request for model used against the token rate
+// limits it is subject to
+func (c *Controller) Spend(ctx context.Context, model string, tokens int) {
+	c.rates.spend(ctx, model, tokens)
+}
+
+// ProviderStats is a snapshot of admission state for one provider
+type ProviderStats struct {
+	// InFlight counts admitted requests that have not finished
+	InFlight int `json:"in_flight"`
+	// Queued counts requests waiting for a model or provider slot
+	Queued int `json:"queued"`
+	// Limit is the provider concurrency cap, 0 when unlimited
+	Limit int `json:"limit"`
+}
+
+// Stats returns the admission state of a provider
+func (c *Controller) Stats(providerName string) ProviderStats {
+	c.mu.Lock()
+	var st ProviderStats
+	if s, ok := c.stats[providerName]; ok {
+		st = *s
+	}
+	lim := c.providers[providerName]
+	c.mu.Unlock()
+
+	if lim != nil {
+		_, _, st.Limit = lim.stats()
+	}
+	return st
+}
+
+// SetProviderLimit changes a provider's concurrency cap at runtime. It
+// reports false when the provider has no configured limit to resize.
+func (c *Controller) SetProviderLimit(providerName string, maxConcurrent int) bool {
+	lim := c.providerLimiter(providerName)
+	if lim == nil {
+		return false
+	}
+	lim.setCapacity(maxConcurrent)
+	return true
+}
+
+func (c *Controller) providerLimiter(providerName string) *limiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.providers[providerName]
+}
+
+// track adjusts the per-provider in-flight and queued counters
+func (c *Controller) track(providerName string, inFlight, queued int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.stats[providerName]
+	if !ok {
+		s = &ProviderStats{}
+		c.stats[providerName] = s
+	}
+	s.InFlight += inFlight
+	s.Queued += queued
+}
+
+func (c *Controller) acquire(ctx context.Context, l *limiter, scope, key string) error {
+	err := l.acquire(ctx, priorityRank(ctx), func(delta float64) { c.queued.Add(delta, scope, key) })
+	if err == nil {
+		return nil
+	}
+	if reason, ok := rejectionReason(err); ok {
+		c.rejected.Inc(scope, key, reason)
+		return &RejectedError{Scope: scope, Key: key, Reason: reason}
+	}
+	return err
+}
+
+// matchModel returns the first rule matching model and attrs
+func (c *Controller) matchModel(model string, attrs map[string]string) *modelRule {
+	for i := range c.models {
+		rule := &c.models[i]
+		if !rule.attrs.Match(attrs) {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(rule.pattern, "*"); ok {
+			if strings.HasPrefix(model, prefix) {
+				return rule
+			}
+		} else if model == rule.pattern {
+			return rule
+		}
+	}
+	return nil
+}
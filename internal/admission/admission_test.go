@@ -0,0 +1,368 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/redis"
+)
+
+func newTestController(t *testing.T) (*Controller, *metrics.Registry) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Admission.Models = []config.ModelConcurrency{
+		{Model: "o3*", ConcurrencyLimit: config.ConcurrencyLimit{MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: 50 * time.Millisecond}},
+	}
+	cfg.Admission.Providers = map[string]config.ConcurrencyLimit{
+		"openai": {MaxConcurrent: 2},
+	}
+	m := metrics.NewRegistry()
+	return NewController(cfg, m), m
+}
+
+func TestControllerModelCap(t *testing.T) {
+	c, m := newTestController(t)
+	ctx := context.Background()
+
+	release, err := c.Acquire(ctx, "openai", "o3-mini", nil)
+	if err != nil {
+		t.Fatalf("First o3 request should be admitted: %v", err)
+	}
+
+	// Cheap models on the same provider are not blocked by the o3 cap
+	cheap, err := c.Acquire(ctx, "openai", "gpt-4o-mini", nil)
+	if err != nil {
+		t.Fatalf("Other model should be admitted: %v", err)
+	}
+	cheap()
+
+	// A second o3 request queues and is admitted once the first finishes
+	done := make(chan error, 1)
+	go func() {
+		r, err := c.Acquire(ctx, "openai", "o3", nil)
+		if err == nil {
+			r()
+		}
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// The queue holds one request, so a third is rejected immediately
+	_, err = c.Acquire(ctx, "openai", "o3", nil)
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != ReasonQueueFull || rejected.Scope != "model" {
+		t.Fatalf("Expected queue_full rejection, got %v", err)
+	}
+
+	release()
+	if err := <-done; err != nil {
+		t.Errorf("Queued request should be admitted after release: %v", err)
+	}
+
+	if v := m.Counter("letllm_admission_rejected_total", "", "scope", "key", "reason").Value("model", "o3*", ReasonQueueFull); v != 1 {
+		t.Errorf("Expected 1 rejection recorded, got %v", v)
+	}
+	if v := m.Gauge("letllm_inflight_requests", "", "provider", "model").Value("openai", "o3-mini"); v != 0 {
+		t.Errorf("Expected no in-flight requests after release, got %v", v)
+	}
+}
+
+func TestControllerQueueTimeout(t *testing.T) {
+	c, _ := newTestController(t)
+	ctx := context.Background()
+
+	release, err := c.Acquire(ctx, "openai", "o3", nil)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer release()
+
+	_, err = c.Acquire(ctx, "openai", "o3", nil)
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != ReasonQueueTimeout {
+		t.Errorf("Expected queue_timeout rejection, got %v", err)
+	}
+}
+
+func TestControllerProviderCap(t *testing.T) {
+	c, _ := newTestController(t)
+	ctx := context.Background()
+
+	r1, _ := c.Acquire(ctx, "openai", "gpt-4o", nil)
+	r2, _ := c.Acquire(ctx, "openai", "gpt-4o-mini", nil)
+
+	_, err := c.Acquire(ctx, "openai", "gpt-4o", nil)
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || rejected.Scope != "provider" {
+		t.Fatalf("Expected provider rejection, got %v", err)
+	}
+
+	// Other providers are unaffected
+	r3, err := c.Acquire(ctx, "gemini", "gemini-pro", nil)
+	if err != nil {
+		t.Errorf("Unlimited provider should be admitted: %v", err)
+	}
+
+	r1()
+	r1() // release is idempotent
+	r2()
+	r3()
+	if r, err := c.Acquire(ctx, "openai", "gpt-4o", nil); err != nil {
+		t.Errorf("Slots should be free after release: %v", err)
+	} else {
+		r()
+	}
+}
+
+func TestControllerSetProviderLimit(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Admission.Providers = map[string]config.ConcurrencyLimit{
+		"vllm": {MaxConcurrent: 1, MaxQueue: 1, QueueTimeout: time.Second},
+	}
+	c := NewController(cfg, metrics.NewRegistry())
+	ctx := context.Background()
+
+	first, err := c.Acquire(ctx, "vllm", "llama-3", nil)
+	if err != nil {
+		t.Fatalf("First request should be admitted: %v", err)
+	}
+	defer first()
+
+	admitted := make(chan func(), 1)
+	go func() {
+		release, err := c.Acquire(ctx, "vllm", "llama-3", nil)
+		if err != nil {
+			t.Errorf("Queued request should be admitted after resize: %v", err)
+			return
+		}
+		admitted <- release
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for c.Stats("vllm").Queued != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if st := c.Stats("vllm"); st.InFlight != 1 || st.Queued != 1 || st.Limit != 1 {
+		t.Fatalf("Unexpected stats before resize: %+v", st)
+	}
+
+	// The backend advertises room for more requests
+	if !c.SetProviderLimit("vllm", 2) {
+		t.Fatal("Expected configured provider limit to be resized")
+	}
+	select {
+	case release := <-admitted:
+		release()
+	case <-time.After(time.Second):
+		t.Fatal("Queued request was not admitted after resize")
+	}
+	if st := c.Stats("vllm"); st.Limit != 2 {
+		t.Errorf("Expected limit 2, got %+v", st)
+	}
+
+	if c.SetProviderLimit("ollama", 4) {
+		t.Error("Expected no resize for a provider without a limit")
+	}
+}
+
+func TestControllerModelRuleAttributes(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Admission.Models = []config.ModelConcurrency{
+		{Model: "gpt-4o", Attributes: config.Attributes{"tier": "free"}, ConcurrencyLimit: config.ConcurrencyLimit{MaxConcurrent: 1}},
+	}
+	c := NewController(cfg, metrics.NewRegistry())
+	ctx := context.Background()
+	free := map[string]string{"tier": "free"}
+
+	release, err := c.Acquire(ctx, "openai", "gpt-4o", free)
+	if err != nil {
+		t.Fatalf("First free request should be admitted: %v", err)
+	}
+	defer release()
+
+	if _, err := c.Acquire(ctx, "openai", "gpt-4o", free); err == nil {
+		t.Error("Expected second free request to be rejected")
+	}
+	r, err := c.Acquire(ctx, "openai", "gpt-4o", map[string]string{"tier": "enterprise"})
+	if err != nil {
+		t.Fatalf("Other tiers are not capped by the rule: %v", err)
+	}
+	r()
+}
+
+func TestControllerPriority(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Admission.Providers = map[string]config.ConcurrencyLimit{
+		"vllm": {MaxConcurrent: 1, MaxQueue: 2, QueueTimeout: time.Second},
+	}
+	c := NewController(cfg, metrics.NewRegistry())
+	high := WithPriority(context.Background(), config.PriorityHigh)
+	low := WithPriority(context.Background(), config.PriorityLow)
+
+	first, err := c.Acquire(low, "vllm", "llama-3", nil)
+	if err != nil {
+		t.Fatalf("Low priority requests are admitted while there is room: %v", err)
+	}
+
+	// Low priority requests are shed rather than queued
+	_, err = c.Acquire(low, "vllm", "llama-3", nil)
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != ReasonPriorityShed {
+		t.Fatalf("Expected priority_shed rejection, got %v", err)
+	}
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result, 3)
+	queue := func(ctx context.Context, name string) {
+		go func() {
+			release, err := c.Acquire(ctx, "vllm", "llama-3", nil)
+			results <- result{name, err}
+			if err == nil {
+				time.Sleep(10 * time.Millisecond)
+				release()
+			}
+		}()
+		deadline := time.Now().Add(time.Second)
+		for c.Stats("vllm").Queued < 1 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	queue(context.Background(), "normal")
+	queue(high, "high-1")
+	// The full queue makes room by displacing the normal request
+	queue(high, "high-2")
+	if r := <-results; r.name != "normal" || !errors.As(r.err, &rejected) || rejected.Reason != ReasonPriorityShed {
+		t.Fatalf("Expected the normal request displaced, got %s: %v", r.name, r.err)
+	}
+
+	first()
+	for _, want := range []string{"high-1", "high-2"} {
+		if r := <-results; r.name != want || r.err != nil {
+			t.Errorf("Expected %s admitted next, got %s: %v", want, r.name, r.err)
+		}
+	}
+}
+
+func TestControllerRateLimits(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Admission.RateLimit = config.RateLimit{RequestsPerMinute: 4}
+	cfg.Admission.ModelRateLimits = []config.ModelRateLimit{
+		{Model: "gpt-4o*", RateLimit: config.RateLimit{TokensPerMinute: 1000}},
+	}
+	m := metrics.NewRegistry()
+	c := NewController(cfg, m)
+	now := time.Unix(1700000000, 0)
+	c.rates.now = func() time.Time { return now }
+
+	acquire := func(ctx context.Context, model string) *RejectedError {
+		t.Helper()
+		release, err := c.Acquire(ctx, "openai", model, nil)
+		if err == nil {
+			release()
+			return nil
+		}
+		var rejected *RejectedError
+		if !errors.As(err, &rejected) {
+			t.Fatalf("Expected a rejection, got %v", err)
+		}
+		return rejected
+	}
+
+	// A key's limit applies before the gateway's
+	key := WithRateLimit(context.Background(), "key-a", config.RateLimit{RequestsPerMinute: 2})
+	for i := 0; i < 2; i++ {
+		if rejected := acquire(key, "claude"); rejected != nil {
+			t.Fatalf("Request %d within the key's limit rejected: %v", i, rejected)
+		}
+	}
+	rejected := acquire(key, "claude")
+	if rejected == nil || rejected.Scope != "key" || rejected.Key != "key-a" || rejected.Reason != ReasonRateLimited {
+		t.Fatalf("Expected the key's limit to reject, got %v", rejected)
+	}
+	if rejected.RetryAfter != 30*time.Second || rejected.RetryAfterSeconds() != 30 {
+		t.Fatalf("Expected a retry after 30s, got %v", rejected.RetryAfter)
+	}
+
+	// Tokens spent beyond the model's budget hold its requests back until
+	// the debt is refilled
+	ctx := context.Background()
+	if rejected := acquire(ctx, "gpt-4o-mini"); rejected != nil {
+		t.Fatalf("Request within the model's budget rejected: %v", rejected)
+	}
+	c.Spend(ctx, "gpt-4o-mini", 1499)
+	rejected = acquire(ctx, "gpt-4o")
+	if rejected == nil || rejected.Scope != "model" || rejected.Key != "gpt-4o*" {
+		t.Fatalf("Expected the model's token budget to reject, got %v", rejected)
+	}
+	if rejected.RetryAfter != 30*time.Second {
+		t.Fatalf("Expected a retry after 30s, got %v", rejected.RetryAfter)
+	}
+
+	// The gateway has one request left this minute
+	if rejected := acquire(ctx, "claude"); rejected != nil {
+		t.Fatalf("Request within the gateway's limit rejected: %v", rejected)
+	}
+	rejected = acquire(ctx, "claude")
+	if rejected == nil || rejected.Scope != "global" || rejected.Error() != "global rate limit exceeded" {
+		t.Fatalf("Expected the gateway's limit to reject, got %v", rejected)
+	}
+	if got := m.Counter("letllm_admission_rejected_total", "", "scope", "key", "reason").Value("global", "", ReasonRateLimited); got != 1 {
+		t.Fatalf("Expected one global rate limit rejection, got %v", got)
+	}
+
+	// Budgets refill over the minute
+	now = now.Add(30 * time.Second)
+	if rejected := acquire(ctx, "gpt-4o"); rejected != nil {
+		t.Fatalf("Request after the refill rejected: %v", rejected)
+	}
+}
+
+// TestControllerSharedRateLimits runs against a real server when
+// LETLLM_TEST_REDIS_ADDR is set
+func TestControllerSharedRateLimits(t *testing.T) {
+	addr := os.Getenv("LETLLM_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("LETLLM_TEST_REDIS_ADDR not set")
+	}
+	cfg := &config.Config{}
+	cfg.Redis.KeyPrefix = fmt.Sprintf("letllm-test-%d:", time.Now().UnixNano())
+	cfg.Admission.ModelRateLimits = []config.ModelRateLimit{
+		{Model: "gpt-4o", RateLimit: config.RateLimit{TokensPerMinute: 100}},
+	}
+	client := redis.New(redis.Options{Addr: addr})
+	defer client.Close()
+	// Two replicas sharing the server
+	a := newSharedController(cfg, metrics.NewRegistry(), client)
+	b := newSharedController(cfg, metrics.NewRegistry(), client)
+
+	key := WithRateLimit(context.Background(), "key-a", config.RateLimit{RequestsPerMinute: 2})
+	for _, c := range []*Controller{a, b} {
+		release, err := c.Acquire(key, "openai", "claude", nil)
+		if err != nil {
+			t.Fatalf("Request within the key's limit rejected: %v", err)
+		}
+		release()
+	}
+	_, err := a.Acquire(key, "openai", "claude", nil)
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || rejected.Scope != "key" || rejected.RetryAfter <= 0 || rejected.RetryAfter > 30*time.Second {
+		t.Fatalf("Expected the key's limit to reject across replicas, got %v", err)
+	}
+
+	ctx := context.Background()
+	a.Spend(ctx, "gpt-4o", 200)
+	_, err = b.Acquire(ctx, "openai", "gpt-4o", nil)
+	if !errors.As(err, &rejected) || rejected.Scope != "model" || rejected.RetryAfter < 30*time.Second {
+		t.Fatalf("Expected tokens spent on another replica to reject, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package admission
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+var (
+	errQueueFull    = errors.New(ReasonQueueFull)
+	errQueueTimeout = errors.New(ReasonQueueTimeout)
+	errPriorityShed = errors.New(ReasonPriorityShed)
+)
+
+// limiter is a counting semaphore with a bounded wait queue, ordered by
+// priority rank and then arrival. Its capacity can change at runtime, e.g.
+// when a backend advertises a new concurrency limit.
+type limiter struct {
+	maxQueue int
+	timeout  time.Duration
+
+	mu       sync.Mutex
+	capacity int
+	active   int
+	waiters  list.List // of *waiter
+}
+
+// waiter is a request queued for a slot
+type waiter struct {
+	rank int
+	// ready is closed when the waiter is granted a slot, or displaced by a
+	// higher priority request, in which case err is set first
+	ready chan struct{}
+	err   error
+}
+
+func newLimiter(lim config.ConcurrencyLimit) *limiter {
+	timeout := lim.QueueTimeout
+	if timeout <= 0 {
+		timeout = defaultQueueTimeout
+	}
+	return &limiter{
+		capacity: lim.MaxConcurrent,
+		maxQueue: lim.MaxQueue,
+		timeout:  timeout,
+	}
+}
+
+// acquire takes a slot, queueing up to timeout when none is free. Low
+// priority requests are refused rather than queued, and a full queue makes
+// room for a request by displacing its newest lower priority waiter.
+// onQueue is called with +1 and -1 as the caller enters and leaves the queue.
+func (l *limiter) acquire(ctx context.Context, rank int, onQueue func(delta float64)) error {
+	l.mu.Lock()
+	if l.active < l.capacity && l.waiters.Len() == 0 {
+		l.active++
+		l.mu.Unlock()
+		return nil
+	}
+	if rank >= rankLow {
+		l.mu.Unlock()
+		return errPriorityShed
+	}
+	if l.waiters.Len() >= l.maxQueue && !l.displaceLocked(rank) {
+		l.mu.Unlock()
+		return errQueueFull
+	}
+	w := &waiter{rank: rank, ready: make(chan struct{})}
+	el := l.enqueueLocked(w)
+	l.mu.Unlock()
+
+	onQueue(1)
+	defer onQueue(-1)
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-w.ready:
+		return w.err
+	case <-timer.C:
+		err = errQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-w.ready:
+		// A slot was handed over while giving up; keep it
+		return w.err
+	default:
+		l.waiters.Remove(el)
+		return err
+	}
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	l.grantLocked()
+}
+
+// setCapacity changes the number of slots; lowering it lets in-flight
+// requests finish and admits no new ones until below the new capacity
+func (l *limiter) setCapacity(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.capacity = n
+	l.grantLocked()
+}
+
+// grantLocked hands free slots to waiters in queue order; callers must
+// hold l.mu
+func (l *limiter) grantLocked() {
+	for l.active < l.capacity && l.waiters.Len() > 0 {
+		el := l.waiters.Front()
+		l.waiters.Remove(el)
+		l.active++
+		close(el.Value.(*waiter).ready)
+	}
+}
+
+// enqueueLocked queues w behind the waiters of its rank or a higher one;
+// callers must hold l.mu
+func (l *limiter) enqueueLocked(w *waiter) *list.Element {
+	for el := l.waiters.Back(); el != nil; el = el.Prev() {
+		if el.Value.(*waiter).rank <= w.rank {
+			return l.waiters.InsertAfter(w, el)
+		}
+	}
+	return l.waiters.PushFront(w)
+}
+
+// displaceLocked sheds the newest waiter of a lower priority than rank,
+// reporting false when there is none; callers must hold l.mu
+func (l *limiter) displaceLocked(rank int) bool {
+	el := l.waiters.Back()
+	if el == nil || el.Value.(*waiter).rank <= rank {
+		return false
+	}
+	l.waiters.Remove(el)
+	w := el.Value.(*waiter)
+	w.err = errPriorityShed
+	close(w.ready)
+	return true
+}
+
+// stats returns the slots in use, requests waiting and current capacity
+func (l *limiter) stats() (active, waiting, capacity int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active, l.waiters.Len(), l.capacity
+}
+
+// rejectionReason maps limiter errors to a RejectedError reason
+func rejectionReason(err error) (string, bool) {
+	switch err {
+	case errQueueFull:
+		return ReasonQueueFull, true
+	case errQueueTimeout:
+		return ReasonQueueTimeout, true
+	case errPriorityShed:
+		return ReasonPriorityShed, true
+	default:
+		return "", false
+	}
+}
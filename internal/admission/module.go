@@ -0,0 +1,21 @@
+package admission
+
+import (
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/redis"
+	"go.uber.org/fx"
+)
+
+// Module provides the admission controller.
+var Module = fx.Provide(newSharedController)
+
+// newSharedController creates the controller, counting rate limits in the
+// shared Redis when one is configured
+func newSharedController(cfg *config.Config, m *metrics.Registry, client *redis.Client) *Controller {
+	c := NewController(cfg, m)
+	if client != nil {
+		c.rates.store = newRedisRates(client, cfg.Redis.KeyPrefix)
+	}
+	return c
+}
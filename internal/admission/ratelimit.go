@@ -0,0 +1,209 @@
+package admission
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+)
+
+// ReasonRateLimited rejects requests over a requests or tokens per minute
+// budget
+const ReasonRateLimited = "rate_limited"
+
+type rateLimitContextKey struct{}
+
+// keyRateLimit is the rate limit of the virtual key a request is made with
+type keyRateLimit struct {
+	id    string
+	limit config.RateLimit
+}
+
+// WithRateLimit returns ctx carrying the rate limit of the virtual key
+// keyID the request is made with
+func WithRateLimit(ctx context.Context, keyID string, limit config.RateLimit) context.Context {
+	return context.WithValue(ctx, rateLimitContextKey{}, keyRateLimit{id: keyID, limit: limit})
+}
+
+// rateBucket is a token bucket refilled continuously at its per-minute
+// rate. Its level goes negative when more tokens are spent than were left,
+// which holds back later requests until the debt is refilled.
+type rateBucket struct {
+	perMinute float64
+	level     float64
+	last      time.Time
+}
+
+func newRateBucket(perMinute int, now time.Time) *rateBucket {
+	return &rateBucket{perMinute: float64(perMinute), level: float64(perMinute), last: now}
+}
+
+func (b *rateBucket) refill(now time.Time) {
+	b.level = min(b.perMinute, b.level+now.Sub(b.last).Minutes()*b.perMinute)
+	b.last = now
+}
+
+// wait returns how long until the bucket holds one unit
+func (b *rateBucket) wait() time.Duration {
+	if b.level >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.level) / b.perMinute * float64(time.Minute))
+}
+
+// rateScope is one budget a request draws from
+type rateScope struct {
+	scope, key string
+	limit      config.RateLimit
+}
+
+// rateStore keeps the request and token buckets of the rate limits
+type rateStore interface {
+	// take admits a request drawing from scopes when each has a request and
+	// a token left, and takes the request. Otherwise it returns the index
+	// of the first exhausted scope and how long until it has.
+	take(ctx context.Context, now time.Time, scopes []rateScope) (int, time.Duration, error)
+	// spend takes tokens from the token buckets of scopes
+	spend(ctx context.Context, now time.Time, scopes []rateScope, tokens int) error
+}
+
+// rateLimiter enforces the rate limits of every limited key, model rule and
+// the gateway as a whole. Requests are admitted when their buckets cannot
+// be read, so an unreachable store does not take the gateway down.
+type rateLimiter struct {
+	global config.RateLimit
+	models []config.ModelRateLimit
+	now    func() time.Time
+	store  rateStore
+	errors *metrics.CounterVec
+}
+
+func newRateLimiter(cfg *config.Config, m *metrics.Registry) *rateLimiter {
+	return &rateLimiter{
+		global: cfg.Admission.RateLimit,
+		models: cfg.Admission.ModelRateLimits,
+		now:    time.Now,
+		store:  newMemoryRates(),
+		errors: m.Counter("letllm_rate_limit_errors_total",
+			"Rate limit checks that failed to read or update their buckets, by operation: take or spend.",
+			"op"),
+	}
+}
+
+// scopes returns the limited budgets a request for model draws from: its
+// key's, its model's and the gateway's
+func (r *rateLimiter) scopes(ctx context.Context, model string) []rateScope {
+	var out []rateScope
+	if k, ok := ctx.Value(rateLimitContextKey{}).(keyRateLimit); ok {
+		out = append(out, rateScope{scope: "key", key: k.id, limit: k.limit})
+	}
+	for _, mr := range r.models {
+		prefix, glob := strings.CutSuffix(mr.Model, "*")
+		if (glob && strings.HasPrefix(model, prefix)) || model == mr.Model {
+			out = append(out, rateScope{scope: "model", key: mr.Model, limit: mr.RateLimit})
+			break
+		}
+	}
+	out = append(out, rateScope{scope: "global", limit: r.global})
+	limited := out[:0]
+	for _, s := range out {
+		if s.limit.RequestsPerMinute > 0 || s.limit.TokensPerMinute > 0 {
+			limited = append(limited, s)
+		}
+	}
+	return limited
+}
+
+// take admits a request for model when every budget it draws from has a
+// request and a token left, and takes the request
+func (r *rateLimiter) take(ctx context.Context, model string) *RejectedError {
+	scopes := r.scopes(ctx, model)
+	if len(scopes) == 0 {
+		return nil
+	}
+	i, wait, err := r.store.take(ctx, r.now(), scopes)
+	if err != nil {
+		r.errors.Inc("take")
+		log.Printf("admission: rate limits: %v", err)
+		return nil
+	}
+	if wait > 0 {
+		return &RejectedError{Scope: scopes[i].scope, Key: scopes[i].key, Reason: ReasonRateLimited, RetryAfter: wait}
+	}
+	return nil
+}
+
+// spend takes the tokens a request for model used from its budgets
+func (r *rateLimiter) spend(ctx context.Context, model string, tokens int) {
+	scopes := r.scopes(ctx, model)
+	if tokens <= 0 || len(scopes) == 0 {
+		return
+	}
+	if err := r.store.spend(ctx, r.now(), scopes, tokens); err != nil {
+		r.errors.Inc("spend")
+		log.Printf("admission: rate limits: %v", err)
+	}
+}
+
+// memoryRates keeps the buckets in-process
+type memoryRates struct {
+	mu       sync.Mutex
+	requests map[string]*rateBucket
+	tokens   map[string]*rateBucket
+}
+
+func newMemoryRates() *memoryRates {
+	return &memoryRates{requests: make(map[string]*rateBucket), tokens: make(map[string]*rateBucket)}
+}
+
+// bucket returns the bucket of a scope at perMinute, replacing it when the
+// limit has changed, e.g. on a key updated through the admin API
+func (m *memoryRates) bucket(buckets map[string]*rateBucket, s rateScope, perMinute int, now time.Time) *rateBucket {
+	id := s.scope + "\x00" + s.key
+	b, ok := buckets[id]
+	if !ok || b.perMinute != float64(perMinute) {
+		b = newRateBucket(perMinute, now)
+		buckets[id] = b
+	}
+	b.refill(now)
+	return b
+}
+
+func (m *memoryRates) take(_ context.Context, now time.Time, scopes []rateScope) (int, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var taken []*rateBucket
+	for i, s := range scopes {
+		var wait time.Duration
+		if s.limit.RequestsPerMinute > 0 {
+			b := m.bucket(m.requests, s, s.limit.RequestsPerMinute, now)
+			wait = b.wait()
+			taken = append(taken, b)
+		}
+		if s.limit.TokensPerMinute > 0 {
+			wait = max(wait, m.bucket(m.tokens, s, s.limit.TokensPerMinute, now).wait())
+		}
+		if wait > 0 {
+			return i, wait, nil
+		}
+	}
+	for _, b := range taken {
+		b.level--
+	}
+	return 0, 0, nil
+}
+
+func (m *memoryRates) spend(_ context.Context, now time.Time, scopes []rateScope, tokens int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range scopes {
+		if s.limit.TokensPerMinute > 0 {
+			m.bucket(m.tokens, s, s.limit.TokensPerMinute, now).level -= float64(tokens)
+		}
+	}
+	return nil
+}
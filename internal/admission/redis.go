@@ -0,0 +1,133 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/redis"
+)
+
+// bucketLua refills a bucket at KEYS[i] holding ARGV[i] per minute, on the
+// server's clock so replicas agree. Buckets are hashes of level, last
+// refill and rate, and expire once refilled.
+const bucketLua = `
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+
+local function level(key, per)
+  local b = redis.call('HMGET', key, 'level', 'last', 'per')
+  local lvl, last = tonumber(b[1]), tonumber(b[2])
+  if lvl == nil or tonumber(b[3]) ~= per then
+    return per
+  end
+  return math.min(per, lvl + math.max(0, now - last) / 60 * per)
+end
+
+local function save(key, per, lvl)
+  redis.call('HSET', key, 'level', string.format('%.17g', lvl), 'last', string.format('%.17g', now), 'per', per)
+  redis.call('EXPIRE', key, math.ceil((per - lvl) / per * 60) + 1)
+end
+`
+
+// takeScript admits a request when every bucket holds one unit and takes
+// one from its request buckets. KEYS pair each scope's request and token
+// bucket; a rate of 0 leaves a bucket out. It returns the 1-based index of
+// the first exhausted scope and the seconds until it has a unit, or 0.
+var takeScript = redis.NewScript(bucketLua + `
+local levels = {}
+for i = 1, #KEYS do
+  local per = tonumber(ARGV[i])
+  if per > 0 then
+    levels[i] = level(KEYS[i], per)
+  end
+end
+for i = 1, #KEYS, 2 do
+  local wait = 0
+  for j = i, i + 1 do
+    local per = tonumber(ARGV[j])
+    if per > 0 and levels[j] < 1 then
+      wait = math.max(wait, (1 - levels[j]) / per * 60)
+    end
+  end
+  if wait > 0 then
+    return {(i + 1) / 2, string.format('%.17g', wait)}
+  end
+end
+for i = 1, #KEYS, 2 do
+  local per = tonumber(ARGV[i])
+  if per > 0 then
+    save(KEYS[i], per, levels[i] - 1)
+  end
+end
+return {0, '0'}
+`)
+
+// spendScript takes ARGV[#KEYS+1] tokens from the token buckets at KEYS
+var spendScript = redis.NewScript(bucketLua + `
+local tokens = tonumber(ARGV[#KEYS + 1])
+for i = 1, #KEYS do
+  local per = tonumber(ARGV[i])
+  save(KEYS[i], per, level(KEYS[i], per) - tokens)
+end
+return 0
+`)
+
+// redisRates keeps the buckets in Redis so that replicas share them
+type redisRates struct {
+	client *redis.Client
+	prefix string
+}
+
+func newRedisRates(client *redis.Client, prefix string) *redisRates {
+	return &redisRates{client: client, prefix: prefix + "ratelimit:"}
+}
+
+func (r *redisRates) key(s rateScope, kind string) string {
+	return r.prefix + s.scope + ":" + s.key + ":" + kind
+}
+
+// take ignores now; buckets refill on the server's clock
+func (r *redisRates) take(ctx context.Context, _ time.Time, scopes []rateScope) (int, time.Duration, error) {
+	keys := make([]string, 0, 2*len(scopes))
+	args := make([]any, 0, 2*len(scopes))
+	for _, s := range scopes {
+		keys = append(keys, r.key(s, "requests"), r.key(s, "tokens"))
+		args = append(args, s.limit.RequestsPerMinute, s.limit.TokensPerMinute)
+	}
+	reply, err := takeScript.Run(ctx, r.client, keys, args...)
+	if err != nil {
+		return 0, 0, err
+	}
+	out, ok := reply.([]any)
+	if !ok || len(out) != 2 {
+		return 0, 0, fmt.Errorf("unexpected reply %v", reply)
+	}
+	i, _ := out[0].(int64)
+	wait, _ := out[1].(string)
+	seconds, err := strconv.ParseFloat(wait, 64)
+	if err != nil || i < 0 || int(i) > len(scopes) {
+		return 0, 0, fmt.Errorf("unexpected reply %v", reply)
+	}
+	if i == 0 {
+		return 0, 0, nil
+	}
+	return int(i) - 1, time.Duration(seconds * float64(time.Second)), nil
+}
+
+func (r *redisRates) spend(ctx context.Context, _ time.Time, scopes []rateScope, tokens int) error {
+	var keys []string
+	var args []any
+	for _, s := range scopes {
+		if s.limit.TokensPerMinute > 0 {
+			keys = append(keys, r.key(s, "tokens"))
+			args = append(args, s.limit.TokensPerMinute)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	_, err := spendScript.Run(ctx, r.client, keys, append(args, tokens)...)
+	return err
+}
@@ -0,0 +1,64 @@
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when an artifact does not exist in the store
+var ErrNotFound = errors.New("artifact not found")
+
+// idPrefix marks artifact IDs as SHA-256 content hashes
+const idPrefix = "sha256:"
+
+// Artifact describes a stored prompt fragment. The ID is derived from the
+// content, so uploading the same fragment twice yields the same artifact.
+type Artifact struct {
+	ID        string    `json:"id"`
+	Size      int       `json:"bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store persists prompt artifacts by content hash
+type Store interface {
+	// Put stores content and returns its artifact; existing content is not rewritten
+	Put(content []byte) (*Artifact, error)
+	Get(id string) ([]byte, error)
+	Stat(id string) (*Artifact, error)
+	Delete(id string) error
+
+	// Claim records subject as an owner of an existing artifact, so that
+	// its content can be erased on the subject's request
+	Claim(id, subject string) error
+	// Erase drops every claim of subject and deletes the artifacts no other
+	// subject claims, returning their IDs. Unclaimed artifacts are kept.
+	Erase(subject string) ([]string, error)
+	// Sample returns up to n artifacts picked at random, with their owners
+	Sample(n int) ([]Claimed, error)
+}
+
+// Claimed is an artifact with the subjects that claimed it; Owners is
+// empty for anonymous uploads
+type Claimed struct {
+	Artifact
+	Owners []string `json:"owners"`
+}
+
+// ID returns the content-addressed ID for content
+func ID(content []byte) string {
+	sum := sha256.Sum256(content)
+	return idPrefix + hex.EncodeToString(sum[:])
+}
+
+// ValidID reports whether id is a well-formed artifact ID
+func ValidID(id string) bool {
+	h, ok := strings.CutPrefix(id, idPrefix)
+	if !ok || len(h) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(h)
+	return err == nil
+}
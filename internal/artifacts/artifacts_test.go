@@ -0,0 +1,170 @@
+package artifacts
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/storage/storagetest"
+)
+
+func TestStores(t *testing.T) {
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"sql":    NewSQLStore(storagetest.Open(t)),
+		"cached": NewCachedStore(NewSQLStore(storagetest.Open(t)), 1024),
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			content := []byte("You are a helpful assistant. " + strings.Repeat("context ", 10))
+
+			a, err := s.Put(content)
+			if err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+			if a.ID != ID(content) || !ValidID(a.ID) || a.Size != len(content) {
+				t.Errorf("Unexpected artifact: %+v", a)
+			}
+
+			again, err := s.Put(content)
+			if err != nil || again.ID != a.ID || !again.CreatedAt.Equal(a.CreatedAt) {
+				t.Errorf("Re-uploading should return the existing artifact, got %+v, %v", again, err)
+			}
+
+			got, err := s.Get(a.ID)
+			if err != nil || string(got) != string(content) {
+				t.Errorf("Get returned %q, %v", got, err)
+			}
+
+			if err := s.Delete(a.ID); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if _, err := s.Get(a.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound after delete, got %v", err)
+			}
+			if _, err := s.Stat(a.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound from Stat, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStoresErase(t *testing.T) {
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"sql":    NewSQLStore(storagetest.Open(t)),
+		"cached": NewCachedStore(NewSQLStore(storagetest.Open(t)), 1024),
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			own, _ := s.Put([]byte("only team-a"))
+			shared, _ := s.Put([]byte("team-a and team-b"))
+			anon, _ := s.Put([]byte("nobody"))
+			for _, claim := range [][2]string{{own.ID, "team-a"}, {shared.ID, "team-a"}, {shared.ID, "team-b"}} {
+				if err := s.Claim(claim[0], claim[1]); err != nil {
+					t.Fatalf("Claim failed: %v", err)
+				}
+			}
+			if err := s.Claim(ID([]byte("missing")), "team-a"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound claiming a missing artifact, got %v", err)
+			}
+			_, _ = s.Get(own.ID)
+
+			deleted, err := s.Erase("team-a")
+			if err != nil || len(deleted) != 1 || deleted[0] != own.ID {
+				t.Fatalf("Expected only the unshared artifact to be erased, got %v, %v", deleted, err)
+			}
+			if _, err := s.Get(own.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected erased artifact to be gone, got %v", err)
+			}
+			for _, id := range []string{shared.ID, anon.ID} {
+				if _, err := s.Get(id); err != nil {
+					t.Errorf("Expected %s to be kept, got %v", id, err)
+				}
+			}
+
+			if deleted, _ := s.Erase("team-b"); len(deleted) != 1 || deleted[0] != shared.ID {
+				t.Errorf("Expected shared artifact erased with its last owner, got %v", deleted)
+			}
+		})
+	}
+}
+
+func TestStoresSample(t *testing.T) {
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"sql":    NewSQLStore(storagetest.Open(t)),
+		"cached": NewCachedStore(NewSQLStore(storagetest.Open(t)), 1024),
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			shared, _ := s.Put([]byte("team-a and team-b"))
+			anon, _ := s.Put([]byte("nobody"))
+			_ = s.Claim(shared.ID, "team-b")
+			_ = s.Claim(shared.ID, "team-a")
+
+			sample, err := s.Sample(10)
+			if err != nil || len(sample) != 2 {
+				t.Fatalf("Expected both artifacts, got %v, %v", sample, err)
+			}
+			owners := map[string]string{}
+			for _, c := range sample {
+				owners[c.ID] = strings.Join(c.Owners, ",")
+			}
+			if owners[shared.ID] != "team-a,team-b" || owners[anon.ID] != "" {
+				t.Errorf("Unexpected owners %v", owners)
+			}
+
+			if sample, _ := s.Sample(1); len(sample) != 1 {
+				t.Errorf("Expected the sample to be capped, got %d", len(sample))
+			}
+		})
+	}
+}
+
+func TestCachedStoreEviction(t *testing.T) {
+	backing := NewMemoryStore()
+	c := NewCachedStore(backing, 10)
+
+	a, _ := c.Put([]byte("aaaaaa"))
+	b, _ := c.Put([]byte("bbbbbb"))
+
+	_, _ = c.Get(a.ID)
+	if c.used != 6 {
+		t.Errorf("Expected 6 cached bytes, got %d", c.used)
+	}
+	_, _ = c.Get(b.ID)
+	if _, ok := c.items[a.ID]; ok || c.used != 6 {
+		t.Errorf("Expected least recently used entry to be evicted, used=%d", c.used)
+	}
+
+	// Cached content is served even if the backing store loses it
+	_ = backing.Delete(b.ID)
+	if got, err := c.Get(b.ID); err != nil || string(got) != "bbbbbb" {
+		t.Errorf("Expected cached content, got %q, %v", got, err)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	s := NewMemoryStore()
+	a, _ := s.Put([]byte("SHARED CONTEXT"))
+
+	out, err := Expand(s, "Before {{artifact:"+a.ID+"}} after")
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if out != "Before SHARED CONTEXT after" {
+		t.Errorf("Unexpected expansion: %q", out)
+	}
+
+	if HasReferences("no placeholders {{artifact:sha256:zz}}") {
+		t.Error("Malformed IDs should not count as references")
+	}
+
+	missing := ID([]byte("never uploaded"))
+	_, err = Expand(s, "{{artifact:"+missing+"}}")
+	var me *MissingError
+	if !errors.As(err, &me) || me.ID != missing {
+		t.Errorf("Expected MissingError, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package artifacts
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CachedStore keeps recently used artifact content in memory in front of a
+// slower Store. Content never changes for a given ID, so cached entries only
+// leave the cache through eviction or Delete.
+type CachedStore struct {
+	Store
+
+	maxBytes int64
+
+	mu    sync.Mutex
+	used  int64
+	order *list.List // front is most recently used
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	id      string
+	content []byte
+}
+
+// NewCachedStore wraps store with an LRU cache holding up to maxBytes of content
+func NewCachedStore(store Store, maxBytes int64) *CachedStore {
+	return &CachedStore{
+		Store:    store,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the content of an artifact, from the cache when possible
+func (c *CachedStore) Get(id string) ([]byte, error) {
+	c.mu.Lock()
+	if el, ok := c.items[id]; ok {
+		c.order.MoveToFront(el)
+		content := el.Value.(*cacheEntry).content
+		c.mu.Unlock()
+		return content, nil
+	}
+	c.mu.Unlock()
+
+	content, err := c.Store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	c.add(id, content)
+	return content, nil
+}
+
+// Delete removes an artifact from the store and the cache
+func (c *CachedStore) Delete(id string) error {
+	c.mu.Lock()
+	if el, ok := c.items[id]; ok {
+		c.remove(el)
+	}
+	c.mu.Unlock()
+	return c.Store.Delete(id)
+}
+
+// Erase erases subject's artifacts from the store and drops them from the cache
+func (c *CachedStore) Erase(subject string) ([]string, error) {
+	deleted, err := c.Store.Erase(subject)
+
+	c.mu.Lock()
+	for _, id := range deleted {
+		if el, ok := c.items[id]; ok {
+			c.remove(el)
+		}
+	}
+	c.mu.Unlock()
+	return deleted, err
+}
+
+func (c *CachedStore) add(id string, content []byte) {
+	size := int64(len(content))
+	if size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.items[id]; ok {
+		return
+	}
+	for c.used+size > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+	c.items[id] = c.order.PushFront(&cacheEntry{id: id, content: content})
+	c.used += size
+}
+
+// remove drops an entry; callers must hold c.mu
+func (c *CachedStore) remove(el *list.Element) {
+	entry := c.order.Remove(el).(*cacheEntry)
+	delete(c.items, entry.id)
+	c.used -= int64(len(entry.content))
+}
@@ -0,0 +1,51 @@
+package artifacts
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// referencePattern matches "{{artifact:sha256:<hex>}}" placeholders in
+// message content
+var referencePattern = regexp.MustCompile(`\{\{artifact:(sha256:[0-9a-f]{64})\}\}`)
+
+// MissingError reports a reference to an artifact that does not exist
+type MissingError struct {
+	ID string
+}
+
+func (e *MissingError) Error() string {
+	return fmt.Sprintf("unknown artifact %s", e.ID)
+}
+
+// HasReferences reports whether text contains artifact placeholders
+func HasReferences(text string) bool {
+	return referencePattern.MatchString(text)
+}
+
+// Expand replaces every artifact placeholder in text with the artifact's
+// content. Expanded content is not scanned again, so artifacts cannot
+// reference other artifacts.
+func Expand(s Store, text string) (string, error) {
+	var firstErr error
+	out := referencePattern.ReplaceAllStringFunc(text, func(ref string) string {
+		if firstErr != nil {
+			return ref
+		}
+		id := referencePattern.FindStringSubmatch(ref)[1]
+		content, err := s.Get(id)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				err = &MissingError{ID: id}
+			}
+			firstErr = err
+			return ref
+		}
+		return string(content)
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return out, nil
+}
@@ -0,0 +1,139 @@
+package artifacts
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory Store
+type MemoryStore struct {
+	mu    sync.RWMutex
+	items map[string]memoryItem
+}
+
+type memoryItem struct {
+	meta    Artifact
+	content []byte
+	owners  map[string]bool
+}
+
+// NewMemoryStore creates an empty in-memory artifact store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]memoryItem)}
+}
+
+// Put stores content under its hash
+func (s *MemoryStore) Put(content []byte) (*Artifact, error) {
+	id := ID(content)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if item, ok := s.items[id]; ok {
+		meta := item.meta
+		return &meta, nil
+	}
+	item := memoryItem{
+		meta:    Artifact{ID: id, Size: len(content), CreatedAt: time.Now().UTC()},
+		content: append([]byte(nil), content...),
+	}
+	s.items[id] = item
+	meta := item.meta
+	return &meta, nil
+}
+
+// Get returns the content of an artifact
+func (s *MemoryStore) Get(id string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return item.content, nil
+}
+
+// Stat returns artifact metadata without its content
+func (s *MemoryStore) Stat(id string) (*Artifact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	meta := item.meta
+	return &meta, nil
+}
+
+// Delete removes an artifact
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.items[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.items, id)
+	return nil
+}
+
+// Claim records subject as an owner of an artifact
+func (s *MemoryStore) Claim(id, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if item.owners == nil {
+		item.owners = make(map[string]bool)
+		s.items[id] = item
+	}
+	item.owners[subject] = true
+	return nil
+}
+
+// Erase drops subject's claims and deletes artifacts left without owners
+func (s *MemoryStore) Erase(subject string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted []string
+	for id, item := range s.items {
+		if !item.owners[subject] {
+			continue
+		}
+		delete(item.owners, subject)
+		if len(item.owners) == 0 {
+			delete(s.items, id)
+			deleted = append(deleted, id)
+		}
+	}
+	return deleted, nil
+}
+
+// Sample returns up to n artifacts picked at random, with their owners
+func (s *MemoryStore) Sample(n int) ([]Claimed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]Claimed, 0, len(s.items))
+	for _, item := range s.items {
+		c := Claimed{Artifact: item.meta, Owners: []string{}}
+		for owner := range item.owners {
+			c.Owners = append(c.Owners, owner)
+		}
+		sort.Strings(c.Owners)
+		out = append(out, c)
+	}
+	rand.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out, nil
+}
@@ -0,0 +1,26 @@
+package artifacts
+
+import (
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+	"go.uber.org/fx"
+)
+
+// Module provides the prompt artifact Store.
+var Module = fx.Provide(NewStore)
+
+// DefaultCacheBytes bounds cached artifact content when not configured
+const DefaultCacheBytes = 64 << 20
+
+// NewStore creates the artifact store. Artifacts live in the shared database
+// behind an in-memory cache when one is configured, in memory otherwise.
+func NewStore(cfg *config.Config, db *storage.DB) Store {
+	if db == nil {
+		return NewMemoryStore()
+	}
+	cacheBytes := cfg.Artifacts.CacheBytes
+	if cacheBytes <= 0 {
+		cacheBytes = DefaultCacheBytes
+	}
+	return NewCachedStore(NewSQLStore(db), cacheBytes)
+}
@@ -0,0 +1,175 @@
+package artifacts
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+)
+
+// SQLStore is a Store backed by the shared database
+type SQLStore struct {
+	db *storage.DB
+}
+
+// NewSQLStore creates an artifact store on db; the prompt_artifacts table is
+// created by the storage migrations
+func NewSQLStore(db *storage.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// Put stores content under its hash
+func (s *SQLStore) Put(content []byte) (*Artifact, error) {
+	a := &Artifact{ID: ID(content), Size: len(content), CreatedAt: time.Now().UTC()}
+	_, err := s.db.Exec(s.db.Rebind(`INSERT INTO prompt_artifacts (id, content, size, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING`), a.ID, content, a.Size, a.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("put artifact %s: %w", a.ID, err)
+	}
+	return s.Stat(a.ID)
+}
+
+// Get returns the content of an artifact
+func (s *SQLStore) Get(id string) ([]byte, error) {
+	var content []byte
+	err := s.db.QueryRow(s.db.Rebind("SELECT content FROM prompt_artifacts WHERE id = ?"), id).Scan(&content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get artifact %s: %w", id, err)
+	}
+	return content, nil
+}
+
+// Stat returns artifact metadata without its content
+func (s *SQLStore) Stat(id string) (*Artifact, error) {
+	var a Artifact
+	err := s.db.QueryRow(s.db.Rebind("SELECT id, size, created_at FROM prompt_artifacts WHERE id = ?"), id).
+		Scan(&a.ID, &a.Size, &a.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat artifact %s: %w", id, err)
+	}
+	return &a, nil
+}
+
+// Delete removes an artifact and its claims
+func (s *SQLStore) Delete(id string) error {
+	res, err := s.db.Exec(s.db.Rebind("DELETE FROM prompt_artifacts WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("delete artifact %s: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if _, err := s.db.Exec(s.db.Rebind("DELETE FROM artifact_owners WHERE artifact_id = ?"), id); err != nil {
+		return fmt.Errorf("delete claims on artifact %s: %w", id, err)
+	}
+	return nil
+}
+
+// Claim records subject as an owner of an artifact
+func (s *SQLStore) Claim(id, subject string) error {
+	if _, err := s.Stat(id); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(s.db.Rebind(`INSERT INTO artifact_owners (artifact_id, subject) VALUES (?, ?)
+		ON CONFLICT (artifact_id, subject) DO NOTHING`), id, subject)
+	if err != nil {
+		return fmt.Errorf("claim artifact %s: %w", id, err)
+	}
+	return nil
+}
+
+// Erase drops subject's claims and deletes artifacts left without owners,
+// in one transaction
+func (s *SQLStore) Erase(subject string) ([]string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("erase artifacts: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(s.db.Rebind("SELECT artifact_id FROM artifact_owners WHERE subject = ?"), subject)
+	if err != nil {
+		return nil, fmt.Errorf("erase artifacts: %w", err)
+	}
+	var claimed []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		claimed = append(claimed, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(s.db.Rebind("DELETE FROM artifact_owners WHERE subject = ?"), subject); err != nil {
+		return nil, fmt.Errorf("erase artifact claims: %w", err)
+	}
+	var deleted []string
+	for _, id := range claimed {
+		res, err := tx.Exec(s.db.Rebind(`DELETE FROM prompt_artifacts WHERE id = ?
+			AND NOT EXISTS (SELECT 1 FROM artifact_owners WHERE artifact_id = ?)`), id, id)
+		if err != nil {
+			return nil, fmt.Errorf("erase artifact %s: %w", id, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			deleted = append(deleted, id)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("erase artifacts: %w", err)
+	}
+	return deleted, nil
+}
+
+// Sample returns up to n artifacts picked at random, with their owners
+func (s *SQLStore) Sample(n int) ([]Claimed, error) {
+	rows, err := s.db.Query(s.db.Rebind("SELECT id, size, created_at FROM prompt_artifacts ORDER BY RANDOM() LIMIT ?"), n)
+	if err != nil {
+		return nil, fmt.Errorf("sample artifacts: %w", err)
+	}
+	var out []Claimed
+	for rows.Next() {
+		c := Claimed{Owners: []string{}}
+		if err := rows.Scan(&c.ID, &c.Size, &c.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range out {
+		owners, err := s.db.Query(s.db.Rebind("SELECT subject FROM artifact_owners WHERE artifact_id = ? ORDER BY subject"), out[i].ID)
+		if err != nil {
+			return nil, fmt.Errorf("owners of artifact %s: %w", out[i].ID, err)
+		}
+		for owners.Next() {
+			var subject string
+			if err := owners.Scan(&subject); err != nil {
+				owners.Close()
+				return nil, err
+			}
+			out[i].Owners = append(out[i].Owners, subject)
+		}
+		owners.Close()
+		if err := owners.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
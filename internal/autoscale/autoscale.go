@@ -0,0 +1,200 @@
+package autoscale
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"go.uber.org/fx"
+)
+
+// webhookTimeout bounds each webhook delivery
+const webhookTimeout = 5 * time.Second
+
+// Signal describes the load on a self-hosted backend in terms an external
+// autoscaler can act on
+type Signal struct {
+	Provider string `json:"provider"`
+	// InFlight counts requests currently being served by the backend
+	InFlight int `json:"in_flight"`
+	// QueueDepth counts requests waiting for admission
+	QueueDepth int `json:"queue_depth"`
+	// ConcurrencyLimit is the gateway's admission cap, 0 when unlimited
+	ConcurrencyLimit int `json:"concurrency_limit"`
+	// AdvertisedConcurrency is the backend's X-Max-Concurrency, 0 when unknown
+	AdvertisedConcurrency int `json:"advertised_concurrency"`
+	TargetConcurrency     int `json:"target_concurrency"`
+	// DesiredReplicas is the replica count that serves current demand at
+	// the target concurrency
+	DesiredReplicas int       `json:"desired_replicas"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// Signaler periodically computes autoscaling signals for the configured
+// providers, exports them as gauges and posts them to webhooks. When asked
+// to, it also resizes admission limits to the backend's advertised
+// concurrency.
+type Signaler struct {
+	targets    map[string]config.AutoscalingTarget
+	adm        *admission.Controller
+	advertised func() map[string]int
+	client     *http.Client
+	now        func() time.Time
+
+	queueDepth      *metrics.GaugeVec
+	inFlight        *metrics.GaugeVec
+	target          *metrics.GaugeVec
+	desired         *metrics.GaugeVec
+	webhookFailures *metrics.CounterVec
+
+	mu      sync.Mutex
+	signals map[string]Signal
+}
+
+// NewSignaler creates a signaler and runs it for the app's lifetime
+func NewSignaler(lc fx.Lifecycle, cfg *config.Config, adm *admission.Controller, r *provider.Router, m *metrics.Registry) *Signaler {
+	s := newSignaler(cfg, adm, r.AdvertisedConcurrency, m)
+	if len(s.targets) == 0 {
+		return s
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				s.run(ctx, cfg.Autoscaling.Interval)
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			<-done
+			return nil
+		},
+	})
+	return s
+}
+
+func newSignaler(cfg *config.Config, adm *admission.Controller, advertised func() map[string]int, m *metrics.Registry) *Signaler {
+	return &Signaler{
+		targets:    cfg.Autoscaling.Providers,
+		adm:        adm,
+		advertised: advertised,
+		client:     &http.Client{Timeout: webhookTimeout},
+		now:        time.Now,
+		queueDepth: m.Gauge("letllm_backend_queue_depth",
+			"Requests waiting for admission to a self-hosted backend.", "provider"),
+		inFlight: m.Gauge("letllm_backend_inflight_requests",
+			"Requests in flight to a self-hosted backend.", "provider"),
+		target: m.Gauge("letllm_backend_target_concurrency",
+			"In-flight requests a single backend replica should serve.", "provider"),
+		desired: m.Gauge("letllm_backend_desired_replicas",
+			"Backend replicas needed to serve current demand at the target concurrency.", "provider"),
+		webhookFailures: m.Counter("letllm_autoscaling_webhook_failures_total",
+			"Autoscaling webhook deliveries that failed.", "provider"),
+		signals: make(map[string]Signal),
+	}
+}
+
+func (s *Signaler) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.Tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick computes, exports and delivers one round of signals
+func (s *Signaler) Tick(ctx context.Context) {
+	advertised := s.advertised()
+	now := s.now().UTC()
+
+	for name, t := range s.targets {
+		if n := advertised[name]; n > 0 && t.HonorAdvertisedConcurrency {
+			s.adm.SetProviderLimit(name, n)
+		}
+
+		st := s.adm.Stats(name)
+		sig := Signal{
+			Provider:              name,
+			InFlight:              st.InFlight,
+			QueueDepth:            st.Queued,
+			ConcurrencyLimit:      st.Limit,
+			AdvertisedConcurrency: advertised[name],
+			TargetConcurrency:     t.TargetConcurrency,
+			DesiredReplicas:       desiredReplicas(st.InFlight+st.Queued, t.TargetConcurrency),
+			Timestamp:             now,
+		}
+
+		s.queueDepth.Set(float64(sig.QueueDepth), name)
+		s.inFlight.Set(float64(sig.InFlight), name)
+		s.target.Set(float64(sig.TargetConcurrency), name)
+		s.desired.Set(float64(sig.DesiredReplicas), name)
+
+		s.mu.Lock()
+		s.signals[name] = sig
+		s.mu.Unlock()
+
+		if t.WebhookURL != "" {
+			if err := s.deliver(ctx, t.WebhookURL, sig); err != nil {
+				s.webhookFailures.Inc(name)
+				log.Printf("autoscaling webhook for %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// Signals returns the latest signal of each provider, sorted by name
+func (s *Signaler) Signals() []Signal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Signal, 0, len(s.signals))
+	for _, sig := range s.signals {
+		out = append(out, sig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Provider < out[j].Provider })
+	return out
+}
+
+func (s *Signaler) deliver(ctx context.Context, url string, sig Signal) error {
+	body, err := json.Marshal(sig)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// desiredReplicas rounds demand up to whole replicas
+func desiredReplicas(demand, target int) int {
+	return (demand + target - 1) / target
+}
@@ -0,0 +1,90 @@
+package autoscale
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+)
+
+func TestSignalerTick(t *testing.T) {
+	received := make(chan Signal, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var sig Signal
+		if err := json.NewDecoder(r.Body).Decode(&sig); err != nil {
+			t.Errorf("decode webhook body: %v", err)
+		}
+		received <- sig
+	}))
+	defer hook.Close()
+
+	cfg := &config.Config{}
+	cfg.Admission.Providers = map[string]config.ConcurrencyLimit{
+		"vllm": {MaxConcurrent: 2, MaxQueue: 4, QueueTimeout: time.Second},
+	}
+	cfg.Autoscaling.Providers = map[string]config.AutoscalingTarget{
+		"vllm": {TargetConcurrency: 2, WebhookURL: hook.URL, HonorAdvertisedConcurrency: true},
+	}
+	m := metrics.NewRegistry()
+	adm := admission.NewController(cfg, m)
+	advertised := map[string]int{}
+	s := newSignaler(cfg, adm, func() map[string]int { return advertised }, m)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		release, err := adm.Acquire(ctx, "vllm", "llama-3", nil)
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		defer release()
+	}
+	go func() {
+		if release, err := adm.Acquire(ctx, "vllm", "llama-3", nil); err == nil {
+			release()
+		}
+	}()
+	deadline := time.Now().Add(time.Second)
+	for adm.Stats("vllm").Queued != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	s.Tick(ctx)
+	sig := <-received
+	if sig.InFlight != 2 || sig.QueueDepth != 1 || sig.ConcurrencyLimit != 2 || sig.DesiredReplicas != 2 {
+		t.Errorf("Unexpected signal: %+v", sig)
+	}
+	if v := m.Gauge("letllm_backend_queue_depth", "", "provider").Value("vllm"); v != 1 {
+		t.Errorf("Expected queue depth gauge 1, got %v", v)
+	}
+
+	// The backend advertises more capacity; the queued request is admitted
+	advertised["vllm"] = 8
+	s.Tick(ctx)
+	sig = <-received
+	if sig.AdvertisedConcurrency != 8 || sig.ConcurrencyLimit != 8 {
+		t.Errorf("Expected limit resized to advertised concurrency: %+v", sig)
+	}
+	if got := s.Signals(); len(got) != 1 || got[0].Provider != "vllm" {
+		t.Errorf("Unexpected signals: %+v", got)
+	}
+}
+
+func TestDesiredReplicas(t *testing.T) {
+	cases := []struct{ demand, target, want int }{
+		{0, 4, 0},
+		{1, 4, 1},
+		{4, 4, 1},
+		{9, 4, 3},
+	}
+	for _, c := range cases {
+		if got := desiredReplicas(c.demand, c.target); got != c.want {
+			t.Errorf("desiredReplicas(%d, %d) = %d, want %d", c.demand, c.target, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package autoscale
+
+import "go.uber.org/fx"
+
+// Module provides the autoscaling signaler.
+var Module = fx.Provide(NewSignaler)
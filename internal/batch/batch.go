@@ -0,0 +1,201 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Batch statuses, as in OpenAI's Batch API
+const (
+	StatusValidating = "validating"
+	StatusFailed     = "failed"
+	StatusInProgress = "in_progress"
+	StatusFinalizing = "finalizing"
+	StatusCompleted  = "completed"
+	StatusExpired    = "expired"
+	StatusCancelling = "cancelling"
+	StatusCancelled  = "cancelled"
+)
+
+// CompletionWindow is the only completion window OpenAI offers
+const CompletionWindow = "24h"
+
+// Endpoints lists the endpoints a batch can target
+var Endpoints = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/embeddings":       true,
+	"/v1/responses":        true,
+}
+
+var (
+	// ErrBatchNotFound is returned for unknown batch IDs
+	ErrBatchNotFound = errors.New("batch not found")
+	// ErrFinished is returned when cancelling a batch that already ended
+	ErrFinished = errors.New("batch has already finished")
+	// ErrInvalid wraps the reasons a batch cannot be created
+	ErrInvalid = errors.New("invalid batch")
+)
+
+// Batch is a set of requests processed asynchronously. Timestamps are Unix
+// seconds and stay null until the batch reaches the status.
+type Batch struct {
+	ID               string            `json:"id"`
+	Object           string            `json:"object"`
+	Endpoint         string            `json:"endpoint"`
+	Errors           *Errors           `json:"errors"`
+	InputFileID      string            `json:"input_file_id"`
+	CompletionWindow string            `json:"completion_window"`
+	Status           string            `json:"status"`
+	OutputFileID     *string           `json:"output_file_id"`
+	ErrorFileID      *string           `json:"error_file_id"`
+	CreatedAt        int64             `json:"created_at"`
+	InProgressAt     *int64            `json:"in_progress_at"`
+	ExpiresAt        int64             `json:"expires_at"`
+	FinalizingAt     *int64            `json:"finalizing_at"`
+	CompletedAt      *int64            `json:"completed_at"`
+	FailedAt         *int64            `json:"failed_at"`
+	ExpiredAt        *int64            `json:"expired_at"`
+	CancellingAt     *int64            `json:"cancelling_at"`
+	CancelledAt      *int64            `json:"cancelled_at"`
+	RequestCounts    RequestCounts     `json:"request_counts"`
+	Metadata         map[string]string `json:"metadata"`
+	// KeyID is the virtual key that created the batch; its requests run as
+	// that key
+	KeyID string `json:"-"`
+}
+
+// Finished reports whether the batch reached a final status
+func (b *Batch) Finished() bool {
+	switch b.Status {
+	case StatusFailed, StatusCompleted, StatusExpired, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+type RequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// Errors lists the problems that failed a batch during validation
+type Errors struct {
+	Object string  `json:"object"`
+	Data   []Error `json:"data"`
+}
+
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
+	// 1-based line of the input file
+	Line *int `json:"line"`
+}
+
+// Request is one line of a batch input file
+type Request struct {
+	CustomID string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	URL      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// Result is one line of a batch output or error file
+type Result struct {
+	ID       string    `json:"id"`
+	CustomID string    `json:"custom_id"`
+	Response *Response `json:"response"`
+	Error    *Error    `json:"error"`
+}
+
+// Response is the gateway's answer to a batch request
+type Response struct {
+	StatusCode int             `json:"status_code"`
+	RequestID  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// maxInputErrors bounds the validation errors reported for one file
+const maxInputErrors = 20
+
+// ParseInput decodes and validates a JSONL input file for endpoint. Every
+// line must POST to endpoint with a unique custom_id; streaming requests
+// are refused as there is no client to stream to.
+func ParseInput(content []byte, endpoint string, maxRequests int) ([]Request, *Errors) {
+	var reqs []Request
+	var errs []Error
+	fail := func(line int, code, format string, args ...interface{}) {
+		if len(errs) < maxInputErrors {
+			l := line
+			errs = append(errs, Error{Code: code, Message: fmt.Sprintf(format, args...), Line: &l})
+		}
+	}
+
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Buffer(make([]byte, 0, 64<<10), len(content)+1)
+	for line := 1; sc.Scan(); line++ {
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var req Request
+		if err := json.Unmarshal(text, &req); err != nil {
+			fail(line, "invalid_json_line", "line is not a JSON object: %v", err)
+			continue
+		}
+		var body struct {
+			Stream bool `json:"stream"`
+		}
+		switch {
+		case req.CustomID == "":
+			fail(line, "missing_required_parameter", "custom_id is required")
+		case seen[req.CustomID]:
+			fail(line, "duplicate_custom_id", "custom_id %q is used more than once", req.CustomID)
+		case req.Method != http.MethodPost:
+			fail(line, "invalid_method", "method must be POST")
+		case req.URL != endpoint:
+			fail(line, "mismatched_endpoint", "url %q does not match the batch endpoint %s", req.URL, endpoint)
+		case json.Unmarshal(req.Body, &body) != nil:
+			fail(line, "invalid_body", "body must be a JSON object")
+		case body.Stream:
+			fail(line, "invalid_body", "streaming requests cannot be batched")
+		default:
+			seen[req.CustomID] = true
+			reqs = append(reqs, req)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, &Errors{Object: "list", Data: []Error{{Code: "invalid_file", Message: err.Error()}}}
+	}
+	if len(errs) == 0 && len(reqs) == 0 {
+		errs = append(errs, Error{Code: "empty_file", Message: "the input file has no requests"})
+	}
+	if len(reqs) > maxRequests {
+		errs = append(errs, Error{Code: "too_many_requests", Message: fmt.Sprintf("the input file has %d requests; at most %d are allowed", len(reqs), maxRequests)})
+	}
+	if len(errs) > 0 {
+		return nil, &Errors{Object: "list", Data: errs}
+	}
+	return reqs, nil
+}
+
+// Store persists batches; their input and output files are kept in the
+// file store
+type Store interface {
+	Create(b *Batch) error
+	Get(id string) (*Batch, error)
+	Update(b *Batch) error
+	// List returns up to limit batches of keyID, newest first, starting
+	// after the batch with ID after when it is set
+	List(keyID, after string, limit int) ([]*Batch, error)
+	// Unfinished returns the batches not in a final status, oldest first
+	Unfinished() ([]*Batch, error)
+	// Erase deletes every batch of a key and returns how many were removed
+	Erase(keyID string) (int, error)
+}
@@ -0,0 +1,308 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/storage/storagetest"
+)
+
+func chatLine(id string) string {
+	return fmt.Sprintf(`{"custom_id":%q,"method":"POST","url":"/v1/chat/completions","body":{"model":"m","messages":[{"role":"user","content":%q}]}}`, id, id)
+}
+
+func TestParseInput(t *testing.T) {
+	for name, tc := range map[string]struct {
+		input string
+		n     int
+		codes []string
+	}{
+		"valid":         {input: chatLine("a") + "\n\n" + chatLine("b") + "\n", n: 2},
+		"no newline":    {input: chatLine("a"), n: 1},
+		"empty":         {input: "\n", codes: []string{"empty_file"}},
+		"duplicate":     {input: chatLine("a") + "\n" + chatLine("a"), codes: []string{"duplicate_custom_id"}},
+		"bad json":      {input: "{nope", codes: []string{"invalid_json_line"}},
+		"wrong url":     {input: strings.Replace(chatLine("a"), "/v1/chat/completions", "/v1/embeddings", 1), codes: []string{"mismatched_endpoint"}},
+		"get":           {input: strings.Replace(chatLine("a"), "POST", "GET", 1), codes: []string{"invalid_method"}},
+		"streaming":     {input: strings.Replace(chatLine("a"), `"model":"m"`, `"model":"m","stream":true`, 1), codes: []string{"invalid_body"}},
+		"too many":      {input: chatLine("a") + "\n" + chatLine("b") + "\n" + chatLine("c"), codes: []string{"too_many_requests"}},
+		"missing id":    {input: strings.Replace(chatLine("a"), `"custom_id":"a"`, `"custom_id":""`, 1), codes: []string{"missing_required_parameter"}},
+		"several lines": {input: "{nope\n" + chatLine("a") + "\n" + chatLine("a"), codes: []string{"invalid_json_line", "duplicate_custom_id"}},
+	} {
+		reqs, errs := ParseInput([]byte(tc.input), "/v1/chat/completions", 2)
+		var codes []string
+		if errs != nil {
+			for _, e := range errs.Data {
+				codes = append(codes, e.Code)
+			}
+		}
+		if strings.Join(codes, ",") != strings.Join(tc.codes, ",") || len(reqs) != tc.n {
+			t.Errorf("%s: expected %d requests and errors %v, got %d and %v", name, tc.n, tc.codes, len(reqs), codes)
+		}
+	}
+
+	_, errs := ParseInput([]byte("\n{nope"), "/v1/chat/completions", 10)
+	if line := errs.Data[0].Line; line == nil || *line != 2 {
+		t.Errorf("Expected the error on line 2, got %v", line)
+	}
+}
+
+func TestStores(t *testing.T) {
+	for name, s := range map[string]Store{
+		"memory": NewMemoryStore(),
+		"sql":    NewSQLStore(storagetest.Open(t)),
+	} {
+		for i, id := range []string{"batch_a", "batch_b", "batch_c"} {
+			b := &Batch{ID: id, Object: "batch", Status: StatusValidating, CreatedAt: int64(100 + i), KeyID: "key_1", Metadata: map[string]string{"n": id}}
+			if err := s.Create(b); err != nil {
+				t.Fatalf("%s: Create: %v", name, err)
+			}
+		}
+		if err := s.Create(&Batch{ID: "batch_other", Status: StatusValidating, CreatedAt: 200, KeyID: "key_2"}); err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := s.Get("batch_b")
+		if err != nil || b.KeyID != "key_1" || b.Metadata["n"] != "batch_b" {
+			t.Errorf("%s: Get returned %+v, %v", name, b, err)
+		}
+		b.Status, b.RequestCounts = StatusCompleted, RequestCounts{Total: 3, Completed: 3}
+		if err := s.Update(b); err != nil {
+			t.Fatalf("%s: Update: %v", name, err)
+		}
+		if b, _ := s.Get("batch_b"); b.Status != StatusCompleted || b.RequestCounts.Completed != 3 {
+			t.Errorf("%s: update not persisted: %+v", name, b)
+		}
+		if err := s.Update(&Batch{ID: "batch_missing"}); !errors.Is(err, ErrBatchNotFound) {
+			t.Errorf("%s: expected ErrBatchNotFound, got %v", name, err)
+		}
+
+		ids := func(bs []*Batch) string {
+			var out []string
+			for _, b := range bs {
+				out = append(out, b.ID)
+			}
+			return strings.Join(out, ",")
+		}
+		if list, _ := s.List("key_1", "", 2); ids(list) != "batch_c,batch_b" {
+			t.Errorf("%s: expected the newest first page, got %s", name, ids(list))
+		}
+		if list, _ := s.List("key_1", "batch_b", 2); ids(list) != "batch_a" {
+			t.Errorf("%s: expected the page after batch_b, got %s", name, ids(list))
+		}
+		if list, _ := s.Unfinished(); ids(list) != "batch_a,batch_c,batch_other" {
+			t.Errorf("%s: expected unfinished batches oldest first, got %s", name, ids(list))
+		}
+	}
+}
+
+// fakeExecutor answers requests by the content of their last message
+type fakeExecutor struct {
+	mu    sync.Mutex
+	keys  []string
+	block chan struct{}
+}
+
+func (e *fakeExecutor) Execute(ctx context.Context, keyID, endpoint string, body []byte) (int, string, []byte) {
+	e.mu.Lock()
+	e.keys = append(e.keys, keyID)
+	e.mu.Unlock()
+	if strings.Contains(string(body), "slow") && e.block != nil {
+		select {
+		case <-e.block:
+		case <-ctx.Done():
+			return http.StatusInternalServerError, "", []byte(`{"error":"context canceled"}`)
+		}
+	}
+	if strings.Contains(string(body), "bad") {
+		return http.StatusBadRequest, "req_bad", []byte(`{"error":"model is required"}`)
+	}
+	return http.StatusOK, "req_ok", []byte(`{"object":"chat.completion"}`)
+}
+
+func newTestRunner(t *testing.T, exec Executor) (*Runner, Store, *metrics.Registry) {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Batches.Concurrency = 2
+	cfg.Batches.MaxRequests = 100
+	m := metrics.NewRegistry()
+	store := NewMemoryStore()
+	fileStore := files.NewStore(files.NewMemoryMetadata(), files.NewMemoryBlobs())
+	return newRunner(cfg, store, fileStore, exec, m), store, m
+}
+
+func submit(t *testing.T, r *Runner, store Store, lines ...string) *Batch {
+	t.Helper()
+	content := []byte(strings.Join(lines, "\n"))
+	f, err := r.files.Put(context.Background(), "key_1", "in.jsonl", files.PurposeBatch, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := r.Create("key_1", "/v1/chat/completions", f.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func results(t *testing.T, r *Runner, id *string) map[string]Result {
+	t.Helper()
+	out := map[string]Result{}
+	if id == nil {
+		return out
+	}
+	content, err := r.files.Content(context.Background(), *id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		var res Result
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			t.Fatal(err)
+		}
+		out[res.CustomID] = res
+	}
+	return out
+}
+
+func TestRunnerCompletes(t *testing.T) {
+	exec := &fakeExecutor{}
+	r, store, m := newTestRunner(t, exec)
+	b := submit(t, r, store, chatLine("one"), chatLine("two"), chatLine("bad"))
+
+	r.processPending(context.Background())
+
+	b, _ = store.Get(b.ID)
+	if b.Status != StatusCompleted || b.CompletedAt == nil || b.FinalizingAt == nil || b.InProgressAt == nil {
+		t.Fatalf("Expected a completed batch, got %+v", b)
+	}
+	if b.RequestCounts != (RequestCounts{Total: 3, Completed: 2, Failed: 1}) {
+		t.Errorf("Unexpected counts %+v", b.RequestCounts)
+	}
+	out := results(t, r, b.OutputFileID)
+	if len(out) != 2 || out["one"].Response.StatusCode != 200 || out["one"].Response.RequestID != "req_ok" {
+		t.Errorf("Unexpected output %+v", out)
+	}
+	failed := results(t, r, b.ErrorFileID)
+	if res := failed["bad"]; len(failed) != 1 || res.Response.StatusCode != 400 || res.Error.Code != "http_400" || res.Error.Message != "model is required" {
+		t.Errorf("Unexpected errors %+v", failed)
+	}
+	if f, _ := r.files.Get(*b.OutputFileID); f.KeyID != "key_1" || f.Purpose != files.PurposeBatchOutput {
+		t.Errorf("Expected the output owned by the batch's key, got %+v", f)
+	}
+	for _, k := range exec.keys {
+		if k != "key_1" {
+			t.Errorf("Expected requests run as key_1, got %q", k)
+		}
+	}
+	if v := m.Counter("letllm_batch_requests_total", "", "result").Value("failed"); v != 1 {
+		t.Errorf("Expected 1 failed request counted, got %v", v)
+	}
+}
+
+func TestRunnerInvalidInput(t *testing.T) {
+	r, store, _ := newTestRunner(t, &fakeExecutor{})
+	b := submit(t, r, store, chatLine("a"), chatLine("a"))
+
+	r.processPending(context.Background())
+
+	b, _ = store.Get(b.ID)
+	if b.Status != StatusFailed || b.FailedAt == nil || b.Errors == nil || b.Errors.Data[0].Code != "duplicate_custom_id" {
+		t.Errorf("Expected a failed batch, got %+v", b)
+	}
+}
+
+func TestRunnerCancel(t *testing.T) {
+	exec := &fakeExecutor{block: make(chan struct{})}
+	defer close(exec.block)
+	r, store, _ := newTestRunner(t, exec)
+
+	queued := submit(t, r, store, chatLine("x"))
+	if b, err := r.Cancel(queued.ID); err != nil || b.Status != StatusCancelled {
+		t.Fatalf("Expected a queued batch cancelled at once, got %+v, %v", b, err)
+	}
+	if _, err := r.Cancel(queued.ID); !errors.Is(err, ErrFinished) {
+		t.Errorf("Expected ErrFinished, got %v", err)
+	}
+
+	b := submit(t, r, store, chatLine("fast"), chatLine("slow1"), chatLine("slow2"), chatLine("slow3"))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.processPending(context.Background())
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		cur, _ := store.Get(b.ID)
+		if cur.RequestCounts.Completed == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Batch did not start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if cur, err := r.Cancel(b.ID); err != nil || cur.Status != StatusCancelling {
+		t.Fatalf("Expected a running batch to be cancelling, got %+v, %v", cur, err)
+	}
+	<-done
+
+	b, _ = store.Get(b.ID)
+	if b.Status != StatusCancelled || b.CancelledAt == nil {
+		t.Fatalf("Expected a cancelled batch, got %+v", b)
+	}
+	if out := results(t, r, b.OutputFileID); len(out) != 1 {
+		t.Errorf("Expected the finished request kept, got %+v", out)
+	}
+	failed := results(t, r, b.ErrorFileID)
+	if len(failed) != 3 || failed["slow3"].Error.Code != "batch_cancelled" {
+		t.Errorf("Expected the other requests reported cancelled, got %+v", failed)
+	}
+}
+
+func TestRunnerExpired(t *testing.T) {
+	r, store, _ := newTestRunner(t, &fakeExecutor{})
+	b := submit(t, r, store, chatLine("a"))
+	b.ExpiresAt = time.Now().Add(-time.Minute).Unix()
+	if err := store.Update(b); err != nil {
+		t.Fatal(err)
+	}
+
+	r.processPending(context.Background())
+
+	b, _ = store.Get(b.ID)
+	if b.Status != StatusExpired || b.ExpiredAt == nil || b.OutputFileID != nil {
+		t.Fatalf("Expected an expired batch, got %+v", b)
+	}
+	if failed := results(t, r, b.ErrorFileID); failed["a"].Error.Code != "batch_expired" {
+		t.Errorf("Expected the request reported expired, got %+v", failed)
+	}
+}
+
+func TestRunnerCreateValidation(t *testing.T) {
+	r, _, _ := newTestRunner(t, &fakeExecutor{})
+	f, _ := r.files.Put(context.Background(), "key_1", "in.jsonl", files.PurposeBatch, []byte(chatLine("a")))
+
+	for name, tc := range map[string]struct {
+		keyID, endpoint, fileID string
+		err                     error
+	}{
+		"endpoint":    {"key_1", "/v1/audio/speech", f.ID, ErrInvalid},
+		"missing":     {"key_1", "/v1/chat/completions", "file-missing", files.ErrNotFound},
+		"other owner": {"key_2", "/v1/chat/completions", f.ID, files.ErrNotFound},
+	} {
+		if _, err := r.Create(tc.keyID, tc.endpoint, tc.fileID, nil); !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %v, got %v", name, tc.err, err)
+		}
+	}
+}
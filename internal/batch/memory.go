@@ -0,0 +1,109 @@
+package batch
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryStore is an in-memory Store
+type MemoryStore struct {
+	mu      sync.RWMutex
+	batches map[string]Batch
+}
+
+// NewMemoryStore creates an empty in-memory batch store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{batches: make(map[string]Batch)}
+}
+
+// Create stores a new batch
+func (s *MemoryStore) Create(b *Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches[b.ID] = *b
+	return nil
+}
+
+// Get returns a batch
+func (s *MemoryStore) Get(id string) (*Batch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, ok := s.batches[id]
+	if !ok {
+		return nil, ErrBatchNotFound
+	}
+	return &b, nil
+}
+
+// Update replaces a stored batch
+func (s *MemoryStore) Update(b *Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.batches[b.ID]; !ok {
+		return ErrBatchNotFound
+	}
+	s.batches[b.ID] = *b
+	return nil
+}
+
+// List returns keyID's batches newest first
+func (s *MemoryStore) List(keyID, after string, limit int) ([]*Batch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*Batch
+	for _, b := range s.batches {
+		if b.KeyID == keyID {
+			b := b
+			out = append(out, &b)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return newer(out[i], out[j]) })
+	if a, ok := s.batches[after]; ok {
+		i := sort.Search(len(out), func(i int) bool { return !newer(out[i], &a) })
+		for i < len(out) && out[i].ID == a.ID {
+			i++
+		}
+		out = out[i:]
+	}
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// Unfinished returns the batches not in a final status, oldest first
+func (s *MemoryStore) Unfinished() ([]*Batch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*Batch
+	for _, b := range s.batches {
+		if !b.Finished() {
+			b := b
+			out = append(out, &b)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return newer(out[j], out[i]) })
+	return out, nil
+}
+
+// Erase deletes every batch of a key
+func (s *MemoryStore) Erase(keyID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, b := range s.batches {
+		if b.KeyID == keyID {
+			delete(s.batches, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// newer orders batches by creation time, then ID
+func newer(a, b *Batch) bool {
+	if a.CreatedAt != b.CreatedAt {
+		return a.CreatedAt > b.CreatedAt
+	}
+	return a.ID > b.ID
+}
@@ -0,0 +1,19 @@
+package batch
+
+import (
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+	"go.uber.org/fx"
+)
+
+// Module provides the batch Store and Runner; the Executor is provided by
+// the HTTP server
+var Module = fx.Provide(NewStore, NewRunner)
+
+// NewStore creates the batch store in the shared database, or in memory
+// when there is none
+func NewStore(db *storage.DB) Store {
+	if db == nil {
+		return NewMemoryStore()
+	}
+	return NewSQLStore(db)
+}
@@ -0,0 +1,388 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"go.uber.org/fx"
+	"golang.org/x/sync/errgroup"
+)
+
+// Executor sends one batch request through the gateway on behalf of the
+// batch's key, returning the status, request ID and body of the answer
+type Executor interface {
+	Execute(ctx context.Context, keyID, endpoint string, body []byte) (status int, requestID string, response []byte)
+}
+
+// Runner processes batches in the background, one batch at a time with a
+// bounded number of its requests in flight. Results are written when a
+// batch ends, so a batch interrupted by a restart runs again from the start.
+type Runner struct {
+	store       Store
+	files       *files.Store
+	exec        Executor
+	concurrency int
+	maxRequests int
+	now         func() time.Time
+	wake        chan struct{}
+
+	requests *metrics.CounterVec
+
+	mu sync.Mutex
+	// Batches being processed; their Batch is only touched under mu
+	running map[string]*run
+}
+
+type run struct {
+	batch  *Batch
+	cancel context.CancelFunc
+}
+
+// NewRunner creates a runner that processes batches for the app's lifetime
+func NewRunner(lc fx.Lifecycle, cfg *config.Config, store Store, fileStore *files.Store, exec Executor, m *metrics.Registry) *Runner {
+	r := newRunner(cfg, store, fileStore, exec, m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				r.run(ctx, cfg.Batches.PollInterval)
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			<-done
+			return nil
+		},
+	})
+	return r
+}
+
+func newRunner(cfg *config.Config, store Store, fileStore *files.Store, exec Executor, m *metrics.Registry) *Runner {
+	return &Runner{
+		store:       store,
+		files:       fileStore,
+		exec:        exec,
+		concurrency: cfg.Batches.Concurrency,
+		maxRequests: cfg.Batches.MaxRequests,
+		now:         time.Now,
+		wake:        make(chan struct{}, 1),
+		requests: m.Counter("letllm_batch_requests_total",
+			"Batch requests run, by result: completed or failed.",
+			"result"),
+		running: make(map[string]*run),
+	}
+}
+
+// Create queues a batch over an input file owned by keyID
+func (r *Runner) Create(keyID, endpoint, inputFileID string, metadata map[string]string) (*Batch, error) {
+	if !Endpoints[endpoint] {
+		return nil, fmt.Errorf("%w: endpoint %q cannot be batched", ErrInvalid, endpoint)
+	}
+	f, err := r.files.Get(inputFileID)
+	if err != nil {
+		return nil, err
+	}
+	if f.KeyID != keyID {
+		return nil, files.ErrNotFound
+	}
+	if f.Purpose != files.PurposeBatch {
+		return nil, fmt.Errorf("%w: file %s has purpose %q, not %q", ErrInvalid, f.ID, f.Purpose, files.PurposeBatch)
+	}
+
+	now := r.now()
+	b := &Batch{
+		ID:               "batch_" + ids.New(),
+		Object:           "batch",
+		Endpoint:         endpoint,
+		InputFileID:      inputFileID,
+		CompletionWindow: CompletionWindow,
+		Status:           StatusValidating,
+		CreatedAt:        now.Unix(),
+		ExpiresAt:        now.Add(24 * time.Hour).Unix(),
+		Metadata:         metadata,
+		KeyID:            keyID,
+	}
+	if err := r.store.Create(b); err != nil {
+		return nil, err
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return b, nil
+}
+
+// Cancel stops a batch. Requests in flight are abandoned and those already
+// answered are kept in the output.
+func (r *Runner) Cancel(id string) (*Batch, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if run, ok := r.running[id]; ok {
+		b := run.batch
+		if b.Status == StatusValidating || b.Status == StatusInProgress {
+			b.Status, b.CancellingAt = StatusCancelling, r.stamp()
+			if err := r.store.Update(b); err != nil {
+				return nil, err
+			}
+			run.cancel()
+		}
+		out := *b
+		return &out, nil
+	}
+
+	b, err := r.store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if b.Finished() {
+		return nil, ErrFinished
+	}
+	// Not started yet, so there is nothing to wait for
+	now := r.stamp()
+	b.Status, b.CancellingAt, b.CancelledAt = StatusCancelled, now, now
+	if err := r.store.Update(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (r *Runner) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.processPending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// processPending processes every unfinished batch, oldest first
+func (r *Runner) processPending(ctx context.Context) {
+	batches, err := r.store.Unfinished()
+	if err != nil {
+		log.Printf("batches: %v", err)
+		return
+	}
+	for _, b := range batches {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := r.process(ctx, b.ID); err != nil {
+			log.Printf("batch %s: %v", b.ID, err)
+		}
+	}
+}
+
+// process runs the requests of a batch and writes its output and error
+// files. It returns early, leaving the batch unfinished, when ctx ends.
+func (r *Runner) process(ctx context.Context, id string) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	r.mu.Lock()
+	b, err := r.store.Get(id)
+	if err != nil || b.Finished() {
+		r.mu.Unlock()
+		return err
+	}
+	runCtx, cancelDeadline := context.WithDeadline(runCtx, time.Unix(b.ExpiresAt, 0))
+	defer cancelDeadline()
+	r.running[id] = &run{batch: b, cancel: cancel}
+	cancelling := b.Status == StatusCancelling
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.running, id)
+		r.mu.Unlock()
+	}()
+
+	var reqs []Request
+	var invalid *Errors
+	if !cancelling {
+		content, err := r.files.Content(ctx, b.InputFileID)
+		if err != nil {
+			invalid = &Errors{Object: "list", Data: []Error{{Code: "invalid_file", Message: err.Error()}}}
+		} else {
+			reqs, invalid = ParseInput(content, b.Endpoint, r.maxRequests)
+		}
+	}
+	if invalid != nil {
+		return r.update(b, func() {
+			b.Status, b.Errors, b.FailedAt = StatusFailed, invalid, r.stamp()
+		})
+	}
+	if err := r.update(b, func() {
+		if b.Status == StatusValidating {
+			b.Status = StatusInProgress
+		}
+		if b.InProgressAt == nil {
+			b.InProgressAt = r.stamp()
+		}
+		b.RequestCounts = RequestCounts{Total: len(reqs)}
+	}); err != nil {
+		return err
+	}
+
+	results := make([]*Result, len(reqs))
+	// Requests run r.concurrency at a time; each writes only its own slot
+	var g errgroup.Group
+	g.SetLimit(r.concurrency)
+	// Progress is saved at most once a second; lastSave is guarded by mu
+	var lastSave time.Time
+	for i := range reqs {
+		if runCtx.Err() != nil {
+			break
+		}
+		i := i
+		g.Go(func() error {
+			res := r.execute(runCtx, b, reqs[i])
+			if runCtx.Err() != nil {
+				// Abandoned; reported with the requests never run
+				return nil
+			}
+			results[i] = res
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			if res.Error == nil {
+				b.RequestCounts.Completed++
+			} else {
+				b.RequestCounts.Failed++
+			}
+			if now := r.now(); now.Sub(lastSave) >= time.Second {
+				lastSave = now
+				if err := r.store.Update(b); err != nil {
+					log.Printf("batch %s: %v", id, err)
+				}
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	final, code, message := StatusCompleted, "", ""
+	if err := r.update(b, func() {
+		switch {
+		case b.Status == StatusCancelling:
+			final, code, message = StatusCancelled, "batch_cancelled", "the batch was cancelled before this request completed"
+		case runCtx.Err() != nil:
+			final, code, message = StatusExpired, "batch_expired", "the batch expired before this request completed"
+		default:
+			b.Status, b.FinalizingAt = StatusFinalizing, r.stamp()
+		}
+	}); err != nil {
+		return err
+	}
+
+	var output, errorsOut bytes.Buffer
+	for i, res := range results {
+		if res == nil {
+			res = &Result{ID: "batch_req_" + ids.New(), CustomID: reqs[i].CustomID, Error: &Error{Code: code, Message: message}}
+		}
+		line, err := json.Marshal(res)
+		if err != nil {
+			return err
+		}
+		if res.Error == nil {
+			output.Write(append(line, '\n'))
+		} else {
+			errorsOut.Write(append(line, '\n'))
+		}
+	}
+	outputID, err := r.putOutput(ctx, b, "output", output.Bytes())
+	if err != nil {
+		return err
+	}
+	errorID, err := r.putOutput(ctx, b, "error", errorsOut.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return r.update(b, func() {
+		b.OutputFileID, b.ErrorFileID = outputID, errorID
+		b.Status = final
+		switch final {
+		case StatusCompleted:
+			b.CompletedAt = r.stamp()
+		case StatusExpired:
+			b.ExpiredAt = r.stamp()
+		case StatusCancelled:
+			b.CancelledAt = r.stamp()
+		}
+	})
+}
+
+// execute runs one request. Non-2xx answers are failures, reported with
+// the error body the gateway returned.
+func (r *Runner) execute(ctx context.Context, b *Batch, req Request) *Result {
+	status, requestID, body := r.exec.Execute(ctx, b.KeyID, b.Endpoint, req.Body)
+	if !json.Valid(body) {
+		body, _ = json.Marshal(string(body))
+	}
+	res := &Result{
+		ID:       "batch_req_" + ids.New(),
+		CustomID: req.CustomID,
+		Response: &Response{StatusCode: status, RequestID: requestID, Body: body},
+	}
+	if status >= 200 && status < 300 {
+		r.requests.Inc("completed")
+		return res
+	}
+	r.requests.Inc("failed")
+	var e struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	_ = json.Unmarshal(body, &e)
+	if e.Code == "" {
+		e.Code = fmt.Sprintf("http_%d", status)
+	}
+	res.Error = &Error{Code: e.Code, Message: e.Error}
+	return res
+}
+
+// putOutput stores one of the files a batch produces; nothing is stored
+// for empty content
+func (r *Runner) putOutput(ctx context.Context, b *Batch, kind string, content []byte) (*string, error) {
+	if len(content) == 0 {
+		return nil, nil
+	}
+	f, err := r.files.Put(ctx, b.KeyID, b.ID+"_"+kind+".jsonl", files.PurposeBatchOutput, content)
+	if err != nil {
+		return nil, err
+	}
+	return &f.ID, nil
+}
+
+// update applies fn to a running batch and saves it
+func (r *Runner) update(b *Batch, fn func()) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn()
+	return r.store.Update(b)
+}
+
+func (r *Runner) stamp() *int64 {
+	t := r.now().Unix()
+	return &t
+}
@@ -0,0 +1,130 @@
+package batch
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+)
+
+// SQLStore is a Store backed by the shared database. Batches are kept as
+// JSON, with the columns needed to look them up alongside.
+type SQLStore struct {
+	db *storage.DB
+}
+
+// NewSQLStore creates a batch store on db; the batches table is created by
+// the storage migrations
+func NewSQLStore(db *storage.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// Create stores a new batch
+func (s *SQLStore) Create(b *Batch) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(s.db.Rebind("INSERT INTO batches (id, key_id, status, created_at, data) VALUES (?, ?, ?, ?, ?)"),
+		b.ID, b.KeyID, b.Status, b.CreatedAt, string(data))
+	if err != nil {
+		return fmt.Errorf("create batch %s: %w", b.ID, err)
+	}
+	return nil
+}
+
+// Get returns a batch
+func (s *SQLStore) Get(id string) (*Batch, error) {
+	b, err := scanBatch(s.db.QueryRow(s.db.Rebind("SELECT key_id, data FROM batches WHERE id = ?"), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get batch %s: %w", id, err)
+	}
+	return b, nil
+}
+
+// Update replaces a stored batch
+func (s *SQLStore) Update(b *Batch) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec(s.db.Rebind("UPDATE batches SET status = ?, data = ? WHERE id = ?"), b.Status, string(data), b.ID)
+	if err != nil {
+		return fmt.Errorf("update batch %s: %w", b.ID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrBatchNotFound
+	}
+	return nil
+}
+
+// List returns keyID's batches newest first
+func (s *SQLStore) List(keyID, after string, limit int) ([]*Batch, error) {
+	query := "SELECT key_id, data FROM batches WHERE key_id = ?"
+	args := []interface{}{keyID}
+	if after != "" {
+		var createdAt int64
+		err := s.db.QueryRow(s.db.Rebind("SELECT created_at FROM batches WHERE id = ?"), after).Scan(&createdAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("list batches: %w", err)
+		}
+		if err == nil {
+			query += " AND (created_at < ? OR (created_at = ? AND id < ?))"
+			args = append(args, createdAt, createdAt, after)
+		}
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+	return s.query(query, args...)
+}
+
+// Unfinished returns the batches not in a final status, oldest first
+func (s *SQLStore) Unfinished() ([]*Batch, error) {
+	return s.query(`SELECT key_id, data FROM batches WHERE status IN (?, ?, ?, ?)
+		ORDER BY created_at, id`, StatusValidating, StatusInProgress, StatusFinalizing, StatusCancelling)
+}
+
+// Erase deletes every batch of a key
+func (s *SQLStore) Erase(keyID string) (int, error) {
+	res, err := s.db.Exec(s.db.Rebind("DELETE FROM batches WHERE key_id = ?"), keyID)
+	if err != nil {
+		return 0, fmt.Errorf("erase batches of %s: %w", keyID, err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+func (s *SQLStore) query(query string, args ...interface{}) ([]*Batch, error) {
+	rows, err := s.db.Query(s.db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list batches: %w", err)
+	}
+	defer rows.Close()
+	var out []*Batch
+	for rows.Next() {
+		b, err := scanBatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list batches: %w", err)
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+func scanBatch(row interface{ Scan(...interface{}) error }) (*Batch, error) {
+	var keyID, data string
+	if err := row.Scan(&keyID, &data); err != nil {
+		return nil, err
+	}
+	var b Batch
+	if err := json.Unmarshal([]byte(data), &b); err != nil {
+		return nil, err
+	}
+	b.KeyID = keyID
+	return &b, nil
+}
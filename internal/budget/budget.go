@@ -0,0 +1,293 @@
+// Package budget tracks the estimated spend of virtual keys and of their
+// owners against daily and monthly budgets
+package budget
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+)
+
+// Budget periods
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// webhookTimeout bounds each webhook delivery
+const webhookTimeout = 5 * time.Second
+
+// ExceededError is returned for requests drawing from an exhausted budget
+type ExceededError struct {
+	// Budget is "key:<id>" or "owner:<owner>"
+	Budget   string
+	Period   string
+	LimitUSD float64
+	SpentUSD float64
+	// Reset is when the period ends
+	Reset time.Time
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s budget of $%.2f for %s is exhausted", e.Period, e.LimitUSD, e.Budget)
+}
+
+// Status is the spend of one budget in its current periods
+type Status struct {
+	Budget          string   `json:"budget"`
+	DailyUSD        float64  `json:"daily_usd,omitempty"`
+	DailySpentUSD   *float64 `json:"daily_spent_usd,omitempty"`
+	MonthlyUSD      float64  `json:"monthly_usd,omitempty"`
+	MonthlySpentUSD *float64 `json:"monthly_spent_usd,omitempty"`
+}
+
+// Event is posted to a budget's webhook when its spend crosses a threshold
+type Event struct {
+	Budget    string  `json:"budget"`
+	Period    string  `json:"period"`
+	Threshold float64 `json:"threshold"`
+	// Exhausted is set when the threshold is the whole budget, after which
+	// requests are refused until the period ends
+	Exhausted bool      `json:"exhausted"`
+	LimitUSD  float64   `json:"limit_usd"`
+	SpentUSD  float64   `json:"spent_usd"`
+	Reset     time.Time `json:"reset"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// counterStore keeps the spend of each budget period
+type counterStore interface {
+	// get returns the spend under each id, 0 for unknown ids
+	get(ctx context.Context, ids []string) ([]float64, error)
+	// add adds usd to the spend under id, kept until expires, and returns
+	// the new spend
+	add(ctx context.Context, id string, usd float64, expires time.Time) (float64, error)
+}
+
+// Tracker counts the cost of requests, as recorded in their usage, against
+// the budgets of the key they are made with, and refuses requests once a
+// budget is exhausted. Requests are admitted when spend
+// cannot be read, so an unreachable store does not take the gateway down.
+type Tracker struct {
+	budgets []config.BudgetConfig
+	store   counterStore
+	client  *http.Client
+	now     func() time.Time
+
+	rejected        *metrics.CounterVec
+	errors          *metrics.CounterVec
+	webhookFailures *metrics.CounterVec
+}
+
+func newTracker(cfg *config.Config, store counterStore, m *metrics.Registry) *Tracker {
+	return &Tracker{
+		budgets: cfg.Budgets,
+		store:   store,
+		client:  &http.Client{Timeout: webhookTimeout},
+		now:     time.Now,
+		rejected: m.Counter("letllm_budget_rejections_total",
+			"Requests refused because a spend budget is exhausted.", "budget", "period"),
+		errors: m.Counter("letllm_budget_errors_total",
+			"Budget checks that failed to read or update spend, by operation: check or spend.", "op"),
+		webhookFailures: m.Counter("letllm_budget_webhook_failures_total",
+			"Budget webhook deliveries that failed.", "budget"),
+	}
+}
+
+// name identifies budget b
+func name(b *config.BudgetConfig) string {
+	if b.Key != "" {
+		return "key:" + b.Key
+	}
+	return "owner:" + b.Owner
+}
+
+// applies reports whether requests made with k draw from b
+func applies(b *config.BudgetConfig, k *keys.Key) bool {
+	return k != nil && (b.Key != "" && b.Key == k.ID || b.Owner != "" && b.Owner == k.Owner)
+}
+
+// period is one capped period of a budget
+type period struct {
+	budget *config.BudgetConfig
+	name   string
+	limit  float64
+	id     string
+	reset  time.Time
+}
+
+// periods returns the capped periods, at now, of every budget that
+// requests made with k draw from, or of every configured budget for a nil
+// k. A budget set on k itself is a key-scoped budget, whose amounts replace
+// those of a configured budget for k.
+func (t *Tracker) periods(k *keys.Key, now time.Time) []period {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	var own *config.BudgetConfig
+	if k != nil && k.Budget != nil {
+		own = &config.BudgetConfig{Key: k.ID}
+	}
+	var budgets []*config.BudgetConfig
+	for i := range t.budgets {
+		b := &t.budgets[i]
+		if k != nil && !applies(b, k) {
+			continue
+		}
+		if own != nil && b.Key == k.ID {
+			*own = *b
+			continue
+		}
+		budgets = append(budgets, b)
+	}
+	if own != nil {
+		if k.Budget.DailyUSD > 0 {
+			own.DailyUSD = k.Budget.DailyUSD
+		}
+		if k.Budget.MonthlyUSD > 0 {
+			own.MonthlyUSD = k.Budget.MonthlyUSD
+		}
+		budgets = append(budgets, own)
+	}
+
+	var out []period
+	for _, b := range budgets {
+		if b.DailyUSD > 0 {
+			out = append(out, period{b, PeriodDaily, b.DailyUSD, name(b) + ":daily:" + day.Format("2006-01-02"), day.AddDate(0, 0, 1)})
+		}
+		if b.MonthlyUSD > 0 {
+			out = append(out, period{b, PeriodMonthly, b.MonthlyUSD, name(b) + ":monthly:" + month.Format("2006-01"), month.AddDate(0, 1, 0)})
+		}
+	}
+	return out
+}
+
+// Check returns an *ExceededError when a budget requests made with k draw
+// from is exhausted
+func (t *Tracker) Check(ctx context.Context, k *keys.Key) error {
+	if k == nil {
+		return nil
+	}
+	periods := t.periods(k, t.now())
+	if len(periods) == 0 {
+		return nil
+	}
+	ids := make([]string, len(periods))
+	for i, p := range periods {
+		ids[i] = p.id
+	}
+	spent, err := t.store.get(ctx, ids)
+	if err != nil {
+		t.errors.Inc("check")
+		log.Printf("budgets: %v", err)
+		return nil
+	}
+	for i, p := range periods {
+		if spent[i] >= p.limit {
+			t.rejected.Inc(name(p.budget), p.name)
+			return &ExceededError{Budget: name(p.budget), Period: p.name, LimitUSD: p.limit, SpentUSD: spent[i], Reset: p.reset}
+		}
+	}
+	return nil
+}
+
+// Spend counts the USD cost of a request made with k against the key's
+// budgets, and notifies the webhooks of budgets whose thresholds it crosses
+func (t *Tracker) Spend(ctx context.Context, k *keys.Key, cost float64) {
+	if k == nil || cost <= 0 {
+		return
+	}
+	now := t.now()
+	for _, p := range t.periods(k, now) {
+		spent, err := t.store.add(ctx, p.id, cost, p.reset.Add(24*time.Hour))
+		if err != nil {
+			t.errors.Inc("spend")
+			log.Printf("budgets: %v", err)
+			continue
+		}
+		if p.budget.WebhookURL == "" {
+			continue
+		}
+		// Exactly one request's spend crosses each threshold, on any
+		// replica, as the store adds atomically
+		thresholds := append([]float64{1}, p.budget.WarnAt...)
+		for _, f := range thresholds {
+			if threshold := f * p.limit; spent-cost < threshold && spent >= threshold {
+				go t.notify(p.budget, Event{
+					Budget: name(p.budget), Period: p.name, Threshold: f, Exhausted: f == 1,
+					LimitUSD: p.limit, SpentUSD: spent, Reset: p.reset, Timestamp: now,
+				})
+			}
+		}
+	}
+}
+
+// Statuses returns the spend of every budget in its current periods
+func (t *Tracker) Statuses(ctx context.Context) ([]Status, error) {
+	periods := t.periods(nil, t.now())
+	ids := make([]string, len(periods))
+	for i, p := range periods {
+		ids[i] = p.id
+	}
+	spent, err := t.store.get(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(t.budgets))
+	index := make(map[*config.BudgetConfig]int)
+	for i, p := range periods {
+		j, ok := index[p.budget]
+		if !ok {
+			j = len(out)
+			index[p.budget] = j
+			out = append(out, Status{Budget: name(p.budget)})
+		}
+		if p.name == PeriodDaily {
+			out[j].DailyUSD, out[j].DailySpentUSD = p.limit, &spent[i]
+		} else {
+			out[j].MonthlyUSD, out[j].MonthlySpentUSD = p.limit, &spent[i]
+		}
+	}
+	return out, nil
+}
+
+func (t *Tracker) notify(b *config.BudgetConfig, ev Event) {
+	if err := t.deliver(b.WebhookURL, ev); err != nil {
+		t.webhookFailures.Inc(ev.Budget)
+		log.Printf("budget webhook for %s: %v", ev.Budget, err)
+	}
+}
+
+func (t *Tracker) deliver(url string, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Enabled reports whether any budget is configured
+func (t *Tracker) Enabled() bool {
+	return len(t.budgets) > 0
+}
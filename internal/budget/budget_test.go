@@ -0,0 +1,128 @@
+package budget
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+func TestTracker(t *testing.T) {
+	events := make(chan Event, 10)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev Event
+		_ = json.NewDecoder(r.Body).Decode(&ev)
+		events <- ev
+	}))
+	defer hook.Close()
+
+	cfg := &config.Config{
+		Budgets: []config.BudgetConfig{
+			{Owner: "team-a", DailyUSD: 1, WarnAt: []float64{0.5}, WebhookURL: hook.URL},
+			{Key: "key-b", MonthlyUSD: 10},
+		},
+	}
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	keyStore := keys.NewMemoryStore()
+	a := &keys.Key{ID: "key-a", Owner: "team-a"}
+	b := &keys.Key{ID: "key-b", Owner: "team-b"}
+	_ = keyStore.Put(a)
+	_ = keyStore.Put(b)
+
+	// The recorded cost of this month's earlier requests is recounted on
+	// start; requests whose cost is unknown are not counted
+	usd := func(v float64) *float64 { return &v }
+	usageStore := usage.NewMemoryStore(10)
+	_ = usageStore.Add(&usage.Record{Time: now.Add(-time.Hour), KeyID: "key-a", Model: "unpriced", PromptTokens: 9_000_000})
+	_ = usageStore.Add(&usage.Record{Time: now.Add(-24 * time.Hour), KeyID: "key-b", Model: "gpt-4o", CostUSD: usd(9.5)})
+	_ = usageStore.Add(&usage.Record{Time: now.Add(-time.Hour), KeyID: "key-a", Model: "gpt-4o", CostUSD: usd(0.4)})
+	counters := newMemoryCounters()
+	tracker := newTracker(cfg, counters, metrics.NewRegistry())
+	tracker.now = func() time.Time { return now }
+	counters.now = tracker.now
+	if err := tracker.recount(usageStore, keyStore); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Requests without a key, or with a key no budget applies to, are not
+	// counted
+	tracker.Spend(ctx, nil, 10)
+	tracker.Spend(ctx, &keys.Key{ID: "key-c"}, 10)
+
+	// The owner's budget crosses its warning threshold, then is exhausted
+	tracker.Spend(ctx, a, 0.2)
+	if ev := <-events; ev.Budget != "owner:team-a" || ev.Period != PeriodDaily || ev.Threshold != 0.5 || ev.Exhausted {
+		t.Fatalf("Expected a warning at half the budget, got %+v", ev)
+	}
+	if err := tracker.Check(ctx, a); err != nil {
+		t.Fatalf("Expected the budget to have room left, got %v", err)
+	}
+	tracker.Spend(ctx, a, 0.5)
+	if ev := <-events; !ev.Exhausted || ev.SpentUSD < 1 {
+		t.Fatalf("Expected the budget to be exhausted, got %+v", ev)
+	}
+	err := tracker.Check(ctx, a)
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) || exceeded.Budget != "owner:team-a" || !exceeded.Reset.Equal(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the owner's daily budget to refuse, got %v", err)
+	}
+
+	// The key's monthly budget has room for the rest of this month only
+	if err := tracker.Check(ctx, b); err != nil {
+		t.Fatalf("Expected the key's budget to have room left, got %v", err)
+	}
+	tracker.Spend(ctx, b, 0.5)
+	if err := tracker.Check(ctx, b); !errors.As(err, &exceeded) || exceeded.Period != PeriodMonthly {
+		t.Fatalf("Expected the key's monthly budget to refuse, got %v", err)
+	}
+
+	statuses, err := tracker.Statuses(ctx)
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("Expected two budgets, got %v (%v)", statuses, err)
+	}
+	if s := statuses[0]; s.DailySpentUSD == nil || *s.DailySpentUSD < 1.09 || s.MonthlySpentUSD != nil {
+		t.Errorf("Expected the owner's daily spend, got %+v", s)
+	}
+
+	// A new day starts a new period
+	now = now.Add(12 * time.Hour)
+	if err := tracker.Check(ctx, a); err != nil {
+		t.Fatalf("Expected the next day's budget to have room, got %v", err)
+	}
+}
+
+func TestTrackerKeyBudget(t *testing.T) {
+	cfg := &config.Config{
+		Budgets: []config.BudgetConfig{{Key: "key-b", DailyUSD: 1, MonthlyUSD: 10}},
+	}
+	tracker := newTracker(cfg, newMemoryCounters(), metrics.NewRegistry())
+	ctx := context.Background()
+
+	// A budget set on the key is enforced without a configured one
+	a := &keys.Key{ID: "key-a", Budget: &keys.Budget{DailyUSD: 1}}
+	tracker.Spend(ctx, a, 1)
+	var exceeded *ExceededError
+	if err := tracker.Check(ctx, a); !errors.As(err, &exceeded) || exceeded.Budget != "key:key-a" || exceeded.Period != PeriodDaily {
+		t.Fatalf("Expected the key's own budget to refuse, got %v", err)
+	}
+
+	// and replaces the amounts it sets of the configured one
+	b := &keys.Key{ID: "key-b", Budget: &keys.Budget{DailyUSD: 5}}
+	tracker.Spend(ctx, b, 2)
+	if err := tracker.Check(ctx, b); err != nil {
+		t.Fatalf("Expected the key's daily budget to have room, got %v", err)
+	}
+	tracker.Spend(ctx, b, 8)
+	if err := tracker.Check(ctx, b); !errors.As(err, &exceeded) || exceeded.Period != PeriodDaily || exceeded.LimitUSD != 5 {
+		t.Fatalf("Expected the key's daily budget to refuse, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/redis"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+	"go.uber.org/fx"
+)
+
+// Module provides the budget Tracker.
+var Module = fx.Provide(NewTracker)
+
+// NewTracker counts spend in the shared Redis when one is configured. In
+// memory, spend is recounted from the usage records of the current month
+// so that budgets survive restarts.
+func NewTracker(cfg *config.Config, m *metrics.Registry, client *redis.Client, usageStore usage.Store, keyStore keys.Store) (*Tracker, error) {
+	if client != nil {
+		return newTracker(cfg, newRedisCounters(client, cfg.Redis.KeyPrefix), m), nil
+	}
+	t := newTracker(cfg, newMemoryCounters(), m)
+	if len(t.budgets) == 0 {
+		return t, nil
+	}
+	if err := t.recount(usageStore, keyStore); err != nil {
+		return nil, fmt.Errorf("budgets: %w", err)
+	}
+	return t, nil
+}
+
+// recount adds the recorded cost of this month's usage records to the
+// current periods of the budgets their keys draw from
+func (t *Tracker) recount(usageStore usage.Store, keyStore keys.Store) error {
+	now := t.now().UTC()
+	records, err := usageStore.Query(time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), now)
+	if err != nil {
+		return err
+	}
+	found := make(map[string]*keys.Key)
+	for _, rec := range records {
+		if rec.KeyID == "" || rec.CostUSD == nil {
+			continue
+		}
+		k, seen := found[rec.KeyID]
+		if !seen {
+			k, _ = keyStore.Get(rec.KeyID)
+			found[rec.KeyID] = k
+		}
+		if k == nil {
+			continue
+		}
+		for _, p := range t.periods(k, rec.Time) {
+			// Only periods still running are counted
+			if p.reset.After(now) {
+				_, _ = t.store.add(context.Background(), p.id, *rec.CostUSD, p.reset.Add(24*time.Hour))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/redis"
+)
+
+// memoryCounters keeps spend in-process
+type memoryCounters struct {
+	now   func() time.Time
+	mu    sync.Mutex
+	spent map[string]memoryCounter
+}
+
+type memoryCounter struct {
+	usd     float64
+	expires time.Time
+}
+
+func newMemoryCounters() *memoryCounters {
+	return &memoryCounters{now: time.Now, spent: make(map[string]memoryCounter)}
+}
+
+func (m *memoryCounters) get(_ context.Context, ids []string) ([]float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]float64, len(ids))
+	for i, id := range ids {
+		out[i] = m.spent[id].usd
+	}
+	return out, nil
+}
+
+func (m *memoryCounters) add(_ context.Context, id string, usd float64, expires time.Time) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Periods that have ended are dropped as new ones start
+	now := m.now()
+	for id, c := range m.spent {
+		if now.After(c.expires) {
+			delete(m.spent, id)
+		}
+	}
+	c := m.spent[id]
+	c.usd += usd
+	c.expires = expires
+	m.spent[id] = c
+	return c.usd, nil
+}
+
+// redisCounters keeps spend in Redis so that replicas share it
+type redisCounters struct {
+	client *redis.Client
+	prefix string
+}
+
+func newRedisCounters(client *redis.Client, prefix string) *redisCounters {
+	return &redisCounters{client: client, prefix: prefix + "budget:"}
+}
+
+func (r *redisCounters) get(ctx context.Context, ids []string) ([]float64, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	args := make([]any, 0, 1+len(ids))
+	args = append(args, "MGET")
+	for _, id := range ids {
+		args = append(args, r.prefix+id)
+	}
+	reply, err := r.client.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := reply.([]any)
+	if !ok || len(values) != len(ids) {
+		return nil, fmt.Errorf("unexpected reply %v", reply)
+	}
+	out := make([]float64, len(ids))
+	for i, v := range values {
+		if s, ok := v.(string); ok {
+			if out[i], err = strconv.ParseFloat(s, 64); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r *redisCounters) add(ctx context.Context, id string, usd float64, expires time.Time) (float64, error) {
+	key := r.prefix + id
+	reply, err := r.client.Do(ctx, "INCRBYFLOAT", key, usd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.client.Do(ctx, "EXPIREAT", key, expires.Unix()); err != nil {
+		return 0, err
+	}
+	s, _ := reply.(string)
+	return strconv.ParseFloat(s, 64)
+}
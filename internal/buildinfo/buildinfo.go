@@ -0,0 +1,61 @@
+// Package buildinfo describes the running binary. Release builds set the
+// version, commit and date with
+//
+//	go build -ldflags "-X github.com/luguanyu1234/letllm-go/internal/buildinfo.Version=v1.4.0 \
+//	  -X github.com/luguanyu1234/letllm-go/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/luguanyu1234/letllm-go/internal/buildinfo.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without them, the commit and date recorded by the go toolchain are used.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"go.uber.org/fx"
+)
+
+// Set with -ldflags "-X"
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Module provides the Info of the running binary
+var Module = fx.Provide(Get)
+
+// Info identifies a build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	// Build tags, e.g. "llamacpp" for builds with the llama.cpp provider
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Get returns the Info of the running binary
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildDate: Date, GoVersion: runtime.Version()}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.BuildDate == "" {
+				info.BuildDate = s.Value
+			}
+		case "-tags":
+			info.Tags = strings.Split(s.Value, ",")
+		}
+	}
+	return info
+}
@@ -0,0 +1,88 @@
+// Package cache keeps short-lived values, such as cached responses and
+// upstream model lists, in memory, in the shared Redis or on disk
+package cache
+
+import (
+	"container/list"
+	"context"
+	"time"
+)
+
+// Cache keeps values under keys until they expire or are evicted. Callers
+// treat errors, e.g. an unreachable Redis, as misses.
+type Cache interface {
+	// Get returns the value under key, unless it is missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set keeps value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete drops the value under key
+	Delete(ctx context.Context, key string) error
+	// Clear drops every value whose key starts with prefix
+	Clear(ctx context.Context, prefix string) error
+}
+
+// lru tracks entries in order of use, bounded by count and total size
+type lru struct {
+	maxEntries int
+	maxBytes   int64
+
+	used  int64
+	order *list.List // front is most recently used
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key     string
+	size    int64
+	expires time.Time
+	// value is kept by the memory cache only
+	value []byte
+}
+
+func newLRU(maxEntries int, maxBytes int64) *lru {
+	return &lru{maxEntries: maxEntries, maxBytes: maxBytes, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get returns the unexpired entry under key, marking it used
+func (l *lru) get(key string, now time.Time) (*lruEntry, bool) {
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if !now.Before(e.expires) {
+		return nil, false
+	}
+	l.order.MoveToFront(el)
+	return e, true
+}
+
+// add replaces the entry under e.key and returns the entries evicted to
+// make room for it. Entries larger than the whole cache are not added.
+func (l *lru) add(e *lruEntry) (evicted []*lruEntry, added bool) {
+	if old, ok := l.remove(e.key); ok {
+		evicted = append(evicted, old)
+	}
+	if e.size > l.maxBytes {
+		return evicted, false
+	}
+	for l.order.Len() > 0 && (l.order.Len() >= l.maxEntries || l.used+e.size > l.maxBytes) {
+		old, _ := l.remove(l.order.Back().Value.(*lruEntry).key)
+		evicted = append(evicted, old)
+	}
+	l.items[e.key] = l.order.PushFront(e)
+	l.used += e.size
+	return evicted, true
+}
+
+// remove drops the entry under key
+func (l *lru) remove(key string) (*lruEntry, bool) {
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := l.order.Remove(el).(*lruEntry)
+	delete(l.items, key)
+	l.used -= e.size
+	return e, true
+}
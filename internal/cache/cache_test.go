@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/redis"
+)
+
+// testCache checks the behavior every backend shares
+func testCache(t *testing.T, c Cache) {
+	t.Helper()
+	ctx := context.Background()
+	if err := c.Set(ctx, "response:a", []byte("first"), time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	_ = c.Set(ctx, "response:b", []byte("second"), time.Minute)
+	_ = c.Set(ctx, "models:openai", []byte("list"), time.Minute)
+
+	if got, ok, err := c.Get(ctx, "response:a"); err != nil || !ok || string(got) != "first" {
+		t.Fatalf("Expected a hit, got %q %v %v", got, ok, err)
+	}
+	if _, ok, err := c.Get(ctx, "response:missing"); err != nil || ok {
+		t.Errorf("Expected a miss, got %v %v", ok, err)
+	}
+	_ = c.Set(ctx, "response:a", []byte("replaced"), time.Minute)
+	if got, _, _ := c.Get(ctx, "response:a"); string(got) != "replaced" {
+		t.Errorf("Expected the value replaced, got %q", got)
+	}
+
+	if err := c.Delete(ctx, "response:b"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok, _ := c.Get(ctx, "response:b"); ok {
+		t.Error("Expected a deleted value gone")
+	}
+
+	if err := c.Clear(ctx, "response:"); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if _, ok, _ := c.Get(ctx, "response:a"); ok {
+		t.Error("Expected cleared values gone")
+	}
+	if _, ok, _ := c.Get(ctx, "models:openai"); !ok {
+		t.Error("Expected values outside the prefix kept")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testCache(t, NewMemory(10, 1<<20))
+
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := NewMemory(2, 1<<20)
+	m.now = func() time.Time { return now }
+	_ = m.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = m.Set(ctx, "b", []byte("2"), time.Hour)
+	_, _, _ = m.Get(ctx, "a")
+	// The least recently used value makes room
+	_ = m.Set(ctx, "c", []byte("3"), time.Hour)
+	if _, ok, _ := m.Get(ctx, "b"); ok {
+		t.Error("Expected the least recently used value evicted")
+	}
+	now = now.Add(time.Minute)
+	if _, ok, _ := m.Get(ctx, "a"); ok {
+		t.Error("Expected an expired value missed")
+	}
+
+	// and so do values beyond the byte bound
+	m = NewMemory(10, 6)
+	_ = m.Set(ctx, "a", []byte("123"), time.Hour)
+	_ = m.Set(ctx, "b", []byte("456"), time.Hour)
+	_ = m.Set(ctx, "too-large", []byte("123456789"), time.Hour)
+	if _, ok, _ := m.Get(ctx, "a"); ok {
+		t.Error("Expected a value evicted to stay within the byte bound")
+	}
+	if _, ok, _ := m.Get(ctx, "b"); !ok {
+		t.Error("Expected a value that fits kept")
+	}
+	if _, ok, _ := m.Get(ctx, "too-large"); ok {
+		t.Error("Expected a value larger than the cache not kept")
+	}
+}
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+	d, err := NewDisk(dir, 10, 1<<20)
+	if err != nil {
+		t.Fatalf("NewDisk failed: %v", err)
+	}
+	testCache(t, d)
+
+	// Expiry is checked against the clock on start
+	ctx := context.Background()
+	now := time.Now()
+	d.now = func() time.Time { return now }
+	for i := 0; i < 12; i++ {
+		_ = d.Set(ctx, fmt.Sprintf("response:%d", i), []byte("value"), time.Hour)
+	}
+	_ = d.Set(ctx, "response:expiring", []byte("value"), time.Minute)
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+entrySuffix))
+	if len(files) != 10 {
+		t.Errorf("Expected evicted values' files removed, got %d files", len(files))
+	}
+
+	// Values survive a restart, expired ones aside
+	reopened, err := NewDisk(dir, 10, 1<<20)
+	if err != nil {
+		t.Fatalf("NewDisk failed: %v", err)
+	}
+	reopened.now = func() time.Time { return now.Add(30 * time.Minute) }
+	if got, ok, err := reopened.Get(ctx, "response:11"); err != nil || !ok || string(got) != "value" {
+		t.Errorf("Expected a value kept across a restart, got %q %v %v", got, ok, err)
+	}
+	if _, ok, _ := reopened.Get(ctx, "response:expiring"); ok {
+		t.Error("Expected an expired value missed after a restart")
+	}
+
+	// Unreadable files are dropped on start
+	_ = os.WriteFile(filepath.Join(dir, "garbage"+entrySuffix), []byte("x"), 0o600)
+	if _, err := NewDisk(dir, 10, 1<<20); err != nil {
+		t.Fatalf("NewDisk failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "garbage"+entrySuffix)); !os.IsNotExist(err) {
+		t.Errorf("Expected an unreadable file removed, got %v", err)
+	}
+}
+
+// TestRedis runs against a real server when LETLLM_TEST_REDIS_ADDR is set
+func TestRedis(t *testing.T) {
+	addr := os.Getenv("LETLLM_TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("LETLLM_TEST_REDIS_ADDR not set")
+	}
+	client := redis.New(redis.Options{Addr: addr})
+	defer client.Close()
+	testCache(t, NewRedis(client, fmt.Sprintf("letllm-test:%d:cache:", time.Now().UnixNano())))
+}
@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// entrySuffix marks entry files, so temporary files are never read
+const entrySuffix = ".entry"
+
+// headerSize is the size of an entry file's expiry and key length, which
+// the key and then the value follow
+const headerSize = 12
+
+// Disk is a Cache of files in a directory, so entries survive restarts. The
+// index of entries is kept in memory and rebuilt from the files on start,
+// least recently written first.
+type Disk struct {
+	dir string
+	now func() time.Time
+
+	mu  sync.Mutex
+	lru *lru
+}
+
+// NewDisk opens the cache in dir, creating it when missing, holding up to
+// maxEntries values of up to maxBytes in total
+func NewDisk(dir string, maxEntries int, maxBytes int64) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("cache: %w", err)
+	}
+	d := &Disk{dir: dir, now: time.Now, lru: newLRU(maxEntries, maxBytes)}
+	if err := d.load(); err != nil {
+		return nil, fmt.Errorf("cache: %w", err)
+	}
+	return d, nil
+}
+
+// load indexes the entry files in the directory, dropping expired and
+// unreadable ones
+func (d *Disk) load() error {
+	dirents, err := os.ReadDir(d.dir)
+	if err != nil {
+		return err
+	}
+	type found struct {
+		entry    *lruEntry
+		modified time.Time
+	}
+	var entries []found
+	now := d.now()
+	for _, de := range dirents {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), entrySuffix) {
+			continue
+		}
+		path := filepath.Join(d.dir, de.Name())
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		key, expires, err := readHeader(path)
+		if err != nil || !now.Before(expires) || d.path(key) != path {
+			_ = os.Remove(path)
+			continue
+		}
+		entries = append(entries, found{&lruEntry{key: key, size: info.Size(), expires: expires}, info.ModTime()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modified.Before(entries[j].modified) })
+	for _, f := range entries {
+		d.index(f.entry)
+	}
+	return nil
+}
+
+// path is the file of the entry under key
+func (d *Disk) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+entrySuffix)
+}
+
+// index adds e to the index and removes the files of the entries it evicts;
+// callers must hold d.mu
+func (d *Disk) index(e *lruEntry) bool {
+	evicted, added := d.lru.add(e)
+	for _, old := range evicted {
+		if old.key != e.key {
+			_ = os.Remove(d.path(old.key))
+		}
+	}
+	return added
+}
+
+// Get returns the value under key, unless it is missing or expired
+func (d *Disk) Get(_ context.Context, key string) ([]byte, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.lru.get(key, d.now()); !ok {
+		if _, indexed := d.lru.remove(key); indexed {
+			_ = os.Remove(d.path(key))
+		}
+		return nil, false, nil
+	}
+	data, err := os.ReadFile(d.path(key))
+	if err != nil {
+		d.lru.remove(key)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("cache: %w", err)
+	}
+	if len(data) < headerSize || len(data) < headerSize+int(binary.BigEndian.Uint32(data[8:headerSize])) {
+		d.lru.remove(key)
+		_ = os.Remove(d.path(key))
+		return nil, false, nil
+	}
+	return data[headerSize+int(binary.BigEndian.Uint32(data[8:headerSize])):], true, nil
+}
+
+// Set keeps value under key for ttl
+func (d *Disk) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	expires := d.now().Add(ttl)
+	size := int64(headerSize + len(key) + len(value))
+	if !d.index(&lruEntry{key: key, size: size, expires: expires}) {
+		_ = os.Remove(d.path(key))
+		return nil
+	}
+
+	// Written aside and renamed into place, so readers never see part of
+	// an entry
+	f, err := os.CreateTemp(d.dir, "tmp-*")
+	if err != nil {
+		d.lru.remove(key)
+		return fmt.Errorf("cache: %w", err)
+	}
+	header := make([]byte, headerSize, headerSize+len(key))
+	binary.BigEndian.PutUint64(header, uint64(expires.UnixNano()))
+	binary.BigEndian.PutUint32(header[8:], uint32(len(key)))
+	_, err = f.Write(append(header, key...))
+	if err == nil {
+		_, err = f.Write(value)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), d.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		d.lru.remove(key)
+		return fmt.Errorf("cache: %w", err)
+	}
+	return nil
+}
+
+// Delete drops the value under key
+func (d *Disk) Delete(_ context.Context, key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lru.remove(key)
+	if err := os.Remove(d.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cache: %w", err)
+	}
+	return nil
+}
+
+// Clear drops every value whose key starts with prefix
+func (d *Disk) Clear(_ context.Context, prefix string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key := range d.lru.items {
+		if strings.HasPrefix(key, prefix) {
+			d.lru.remove(key)
+			if err := os.Remove(d.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("cache: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// readHeader returns the key and expiry of an entry file
+func readHeader(path string) (string, time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer f.Close()
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return "", time.Time{}, err
+	}
+	key := make([]byte, binary.BigEndian.Uint32(header[8:]))
+	if _, err := io.ReadFull(f, key); err != nil {
+		return "", time.Time{}, err
+	}
+	return string(key), time.Unix(0, int64(binary.BigEndian.Uint64(header))), nil
+}
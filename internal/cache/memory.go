@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is a Cache in the process, evicting the least recently used
+// values beyond its bounds. Values are shared between hits and must not be
+// modified.
+type Memory struct {
+	now func() time.Time
+
+	mu  sync.Mutex
+	lru *lru
+}
+
+// NewMemory creates a cache holding up to maxEntries values of up to
+// maxBytes in total
+func NewMemory(maxEntries int, maxBytes int64) *Memory {
+	return &Memory{now: time.Now, lru: newLRU(maxEntries, maxBytes)}
+}
+
+// Get returns the value under key, unless it is missing or expired
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.lru.get(key, m.now())
+	if !ok {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Set keeps value under key for ttl
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lru.add(&lruEntry{key: key, size: int64(len(key) + len(value)), expires: m.now().Add(ttl), value: value})
+	return nil
+}
+
+// Delete drops the value under key
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lru.remove(key)
+	return nil
+}
+
+// Clear drops every value whose key starts with prefix
+func (m *Memory) Clear(_ context.Context, prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.lru.items {
+		if strings.HasPrefix(key, prefix) {
+			m.lru.remove(key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package cache
+
+import (
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/redis"
+	"go.uber.org/fx"
+)
+
+// Module provides the Cache selected by cache.backend.
+var Module = fx.Provide(New)
+
+// New creates the configured cache. Redis entries are kept under
+// "<redis.key_prefix>cache:".
+func New(cfg *config.Config, client *redis.Client) (Cache, error) {
+	switch cfg.Cache.Backend {
+	case config.CacheBackendRedis:
+		return NewRedis(client, cfg.Redis.KeyPrefix+"cache:"), nil
+	case config.CacheBackendDisk:
+		return NewDisk(cfg.Cache.Dir, cfg.Cache.MaxEntries, cfg.Cache.MaxBytes)
+	default:
+		return NewMemory(cfg.Cache.MaxEntries, cfg.Cache.MaxBytes), nil
+	}
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/redis"
+)
+
+// Redis is a Cache in the shared Redis, so every replica sees the entries
+// of the others. Redis evicts according to its own maxmemory policy.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis keeps entries under keys starting with prefix
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+// Get returns the value under key, unless it is missing or expired
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.client.Do(ctx, "GET", r.prefix+key)
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("cache: %w", err)
+	}
+	s, ok := reply.(string)
+	if !ok {
+		return nil, false, fmt.Errorf("cache: unexpected reply %v", reply)
+	}
+	return []byte(s), true, nil
+}
+
+// Set keeps value under key for ttl
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if _, err := r.client.Do(ctx, "SET", r.prefix+key, value, "PX", max(ttl.Milliseconds(), 1)); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	return nil
+}
+
+// Delete drops the value under key
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	if _, err := r.client.Do(ctx, "DEL", r.prefix+key); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	return nil
+}
+
+// Clear drops every value whose key starts with prefix, scanning for them
+// so that Redis is not blocked
+func (r *Redis) Clear(ctx context.Context, prefix string) error {
+	pattern := globEscaper.Replace(r.prefix+prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := r.client.Do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", 1000)
+		if err != nil {
+			return fmt.Errorf("cache: %w", err)
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return fmt.Errorf("cache: unexpected reply %v", reply)
+		}
+		cursor, _ = page[0].(string)
+		if keys, _ := page[1].([]any); len(keys) > 0 {
+			if _, err := r.client.Do(ctx, append([]any{"DEL"}, keys...)...); err != nil {
+				return fmt.Errorf("cache: %w", err)
+			}
+		}
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// globEscaper quotes the characters SCAN's MATCH patterns treat specially
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
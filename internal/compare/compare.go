@@ -0,0 +1,436 @@
+package compare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"golang.org/x/sync/errgroup"
+)
+
+// compareEndpoint is the endpoint compared targets are routed as
+const compareEndpoint = "/admin/compare"
+
+// MaxPrompts bounds the prompts of one comparison
+const MaxPrompts = 50
+
+// MaxParallel bounds the prompts compared at once
+const MaxParallel = 4
+
+// Judge preferences
+const (
+	PreferBaseline  = "baseline"
+	PreferCandidate = "candidate"
+	PreferTie       = "tie"
+)
+
+// Target is one side of a comparison. Attributes select attribute-gated
+// routes, as enrichment would for a live request.
+type Target struct {
+	Model      string            `json:"model"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Prompt is one conversation to run against both targets; Prompt is
+// shorthand for a single user message
+type Prompt struct {
+	ID       string             `json:"id,omitempty"`
+	Prompt   string             `json:"prompt,omitempty"`
+	Messages []provider.Message `json:"messages,omitempty"`
+}
+
+// Request describes a comparison
+type Request struct {
+	Baseline    Target   `json:"baseline"`
+	Candidate   Target   `json:"candidate"`
+	Prompts     []Prompt `json:"prompts"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	// Judge, when set, is asked which answer is better for each prompt
+	Judge *Target `json:"judge,omitempty"`
+}
+
+// Result is one target's answer to a prompt. CostUSD is nil when neither the
+// provider nor the pricing config gives a cost.
+type Result struct {
+	Provider  string         `json:"provider"`
+	Content   string         `json:"content"`
+	LatencyMS int64          `json:"latency_ms"`
+	Usage     provider.Usage `json:"usage"`
+	CostUSD   *float64       `json:"cost_usd,omitempty"`
+	Error     string         `json:"error,omitempty"`
+
+	// refused is the admission error of a target that was not admitted
+	refused error
+}
+
+// Diff compares the candidate's answer to the baseline's; deltas are
+// candidate minus baseline
+type Diff struct {
+	// Ops is omitted when the answers are too long to diff
+	Ops            []DiffOp `json:"ops,omitempty"`
+	Similarity     *float64 `json:"similarity,omitempty"`
+	LengthDelta    int      `json:"length_delta"`
+	TokenDelta     int      `json:"completion_token_delta"`
+	LatencyDeltaMS int64    `json:"latency_delta_ms"`
+	CostDeltaUSD   *float64 `json:"cost_delta_usd,omitempty"`
+}
+
+// Judgement is the judge model's preference for one prompt
+type Judgement struct {
+	Preference string `json:"preference,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Comparison is the outcome for one prompt; Diff is only set when both
+// targets answered
+type Comparison struct {
+	ID        string     `json:"id,omitempty"`
+	Baseline  Result     `json:"baseline"`
+	Candidate Result     `json:"candidate"`
+	Diff      *Diff      `json:"diff,omitempty"`
+	Judge     *Judgement `json:"judge,omitempty"`
+}
+
+// Summary aggregates all prompts. Costs are nil unless known for every
+// answer on that side.
+type Summary struct {
+	Prompts                int            `json:"prompts"`
+	BaselineErrors         int            `json:"baseline_errors"`
+	CandidateErrors        int            `json:"candidate_errors"`
+	MeanSimilarity         *float64       `json:"mean_similarity,omitempty"`
+	BaselineMeanLatencyMS  int64          `json:"baseline_mean_latency_ms"`
+	CandidateMeanLatencyMS int64          `json:"candidate_mean_latency_ms"`
+	BaselineCostUSD        *float64       `json:"baseline_cost_usd,omitempty"`
+	CandidateCostUSD       *float64       `json:"candidate_cost_usd,omitempty"`
+	JudgePreferences       map[string]int `json:"judge_preferences,omitempty"`
+}
+
+// Report is the result of a comparison
+type Report struct {
+	Baseline    Target       `json:"baseline"`
+	Candidate   Target       `json:"candidate"`
+	Judge       *Target      `json:"judge,omitempty"`
+	Comparisons []Comparison `json:"comparisons"`
+	Summary     Summary      `json:"summary"`
+}
+
+type router interface {
+	Route(req *provider.RouteRequest) (provider.Provider, error)
+}
+
+// admitter holds admission slots, as admission.Controller does
+type admitter interface {
+	Acquire(ctx context.Context, providerName, model string, attrs map[string]string) (func(), error)
+}
+
+// Comparer runs prompts against two targets through the gateway's routing
+type Comparer struct {
+	router  router
+	pricing map[string]config.ModelPrice
+	// adm admits every upstream call; nil admits them all
+	adm admitter
+}
+
+// NewComparer creates a comparer routing through r and admitting its
+// upstream calls through adm
+func NewComparer(cfg *config.Config, r *provider.Router, adm *admission.Controller) *Comparer {
+	return &Comparer{router: r, pricing: cfg.Pricing, adm: adm}
+}
+
+// Compare runs every prompt against both targets concurrently and diffs the
+// answers. Errors are only returned for invalid requests, unroutable targets
+// or a cancelled ctx; failed generations are reported per prompt.
+func (c *Comparer) Compare(ctx context.Context, req *Request) (*Report, error) {
+	if req.Baseline.Model == "" || req.Candidate.Model == "" {
+		return nil, fmt.Errorf("baseline and candidate models are required")
+	}
+	if len(req.Prompts) == 0 || len(req.Prompts) > MaxPrompts {
+		return nil, fmt.Errorf("between 1 and %d prompts are required", MaxPrompts)
+	}
+	for i, p := range req.Prompts {
+		if p.Prompt == "" && len(p.Messages) == 0 {
+			return nil, fmt.Errorf("prompts[%d]: prompt or messages is required", i)
+		}
+	}
+
+	baseline, err := c.route(compareEndpoint, "baseline", req.Baseline)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := c.route(compareEndpoint, "candidate", req.Candidate)
+	if err != nil {
+		return nil, err
+	}
+	var judge provider.Provider
+	if req.Judge != nil {
+		if judge, err = c.route(compareEndpoint, "judge", *req.Judge); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &Report{Baseline: req.Baseline, Candidate: req.Candidate, Judge: req.Judge}
+	report.Comparisons = make([]Comparison, len(req.Prompts))
+
+	// Prompts run MaxParallel at a time; each writes only its own slot
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(MaxParallel)
+	for i, p := range req.Prompts {
+		i, p := i, p
+		g.Go(func() error {
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+			cmp, err := c.compare(gctx, baseline, candidate, judge, req, p, i)
+			report.Comparisons[i] = cmp
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	report.Summary = summarize(report.Comparisons)
+	return report, nil
+}
+
+// compare runs one prompt against both targets at once. Only cancellation is
+// returned as an error, so one failed generation does not cancel the rest.
+func (c *Comparer) compare(ctx context.Context, baseline, candidate, judge provider.Provider, req *Request, p Prompt, i int) (Comparison, error) {
+	messages := p.Messages
+	if len(messages) == 0 {
+		messages = []provider.Message{{Role: provider.RoleUser, Content: p.Prompt}}
+	}
+
+	cmp := Comparison{ID: p.ID}
+	var g errgroup.Group
+	g.Go(func() error {
+		cmp.Baseline = c.run(ctx, baseline, req.Baseline, messages, req)
+		return nil
+	})
+	g.Go(func() error {
+		cmp.Candidate = c.run(ctx, candidate, req.Candidate, messages, req)
+		return nil
+	})
+	_ = g.Wait()
+	if err := ctx.Err(); err != nil {
+		return cmp, err
+	}
+
+	if cmp.Baseline.Error == "" && cmp.Candidate.Error == "" {
+		cmp.Diff = diff(&cmp.Baseline, &cmp.Candidate)
+		if judge != nil {
+			// Alternate which answer is shown first to offset position bias
+			cmp.Judge = c.judge(ctx, judge, *req.Judge, messages, &cmp, i%2 == 1)
+		}
+	}
+	return cmp, nil
+}
+
+func (c *Comparer) route(endpoint, side string, t Target) (provider.Provider, error) {
+	p, err := c.router.Route(&provider.RouteRequest{Model: t.Model, Endpoint: endpoint, Attributes: t.Attributes})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", side, err)
+	}
+	return p, nil
+}
+
+// admit holds an admission slot for t on p until the returned release func
+// is called
+func (c *Comparer) admit(ctx context.Context, p provider.Provider, t Target) (func(), error) {
+	if c.adm == nil {
+		return func() {}, nil
+	}
+	return c.adm.Acquire(ctx, p.GetInfo().Name, t.Model, t.Attributes)
+}
+
+// cost is the cost resp reports in metadata, or its usage at the price of
+// model; nil when neither is known
+func (c *Comparer) cost(model string, resp *provider.GenerateResponse) *float64 {
+	if cost, ok := resp.Metadata[provider.MetadataCostUSD].(float64); ok {
+		return &cost
+	}
+	if price, ok := c.pricing[model]; ok {
+		cost := resp.Usage.Cost(price)
+		return &cost
+	}
+	return nil
+}
+
+func (c *Comparer) run(ctx context.Context, p provider.Provider, t Target, messages []provider.Message, req *Request) Result {
+	res := Result{Provider: p.GetInfo().Name}
+	release, err := c.admit(ctx, p, t)
+	if err != nil {
+		res.Error, res.refused = err.Error(), err
+		return res
+	}
+	defer release()
+
+	start := time.Now()
+	resp, err := p.Generate(ctx, &provider.GenerateRequest{StandardRequest: &provider.StandardRequest{
+		Model:       t.Model,
+		Messages:    messages,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}})
+	res.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	if len(resp.Choices) > 0 && resp.Choices[0].Message != nil {
+		res.Content = resp.Choices[0].Message.Content
+	}
+	res.Usage = resp.Usage
+	res.CostUSD = c.cost(t.Model, resp)
+	return res
+}
+
+const judgeInstructions = `You compare two answers to the same conversation. Judge helpfulness, correctness and instruction following; ignore length unless it hurts the answer. Reply with JSON only: {"preference": "A", "B" or "tie", "reason": "<one sentence>"}`
+
+// judge asks the judge model which answer is better; swap shows the
+// candidate's answer as A
+func (c *Comparer) judge(ctx context.Context, p provider.Provider, t Target, messages []provider.Message, cmp *Comparison, swap bool) *Judgement {
+	a, b := cmp.Baseline.Content, cmp.Candidate.Content
+	if swap {
+		a, b = b, a
+	}
+
+	var conv strings.Builder
+	for _, m := range messages {
+		fmt.Fprintf(&conv, "[%s] %s\n", m.Role, m.Content)
+	}
+	release, err := c.admit(ctx, p, t)
+	if err != nil {
+		return &Judgement{Error: err.Error()}
+	}
+	defer release()
+	maxTokens, temperature := 256, 0.0
+	resp, err := p.Generate(ctx, &provider.GenerateRequest{StandardRequest: &provider.StandardRequest{
+		Model: t.Model,
+		Messages: []provider.Message{
+			{Role: provider.RoleSystem, Content: judgeInstructions},
+			{Role: provider.RoleUser, Content: fmt.Sprintf("Conversation:\n%s\nAnswer A:\n%s\n\nAnswer B:\n%s", conv.String(), a, b)},
+		},
+		MaxTokens:   &maxTokens,
+		Temperature: &temperature,
+	}})
+	if err != nil {
+		return &Judgement{Error: err.Error()}
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message == nil {
+		return &Judgement{Error: "judge returned no answer"}
+	}
+
+	// Models often wrap the JSON in prose or code fences
+	text := resp.Choices[0].Message.Content
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	var out struct {
+		Preference string `json:"preference"`
+		Reason     string `json:"reason"`
+	}
+	if start < 0 || end < start || json.Unmarshal([]byte(text[start:end+1]), &out) != nil {
+		return &Judgement{Error: fmt.Sprintf("unparseable judge answer: %q", text)}
+	}
+
+	j := &Judgement{Reason: out.Reason}
+	switch strings.ToUpper(strings.TrimSpace(out.Preference)) {
+	case "A":
+		j.Preference = PreferBaseline
+		if swap {
+			j.Preference = PreferCandidate
+		}
+	case "B":
+		j.Preference = PreferCandidate
+		if swap {
+			j.Preference = PreferBaseline
+		}
+	case "TIE":
+		j.Preference = PreferTie
+	default:
+		return &Judgement{Error: fmt.Sprintf("unknown judge preference %q", out.Preference)}
+	}
+	return j
+}
+
+func diff(baseline, candidate *Result) *Diff {
+	d := &Diff{
+		LengthDelta:    len([]rune(candidate.Content)) - len([]rune(baseline.Content)),
+		TokenDelta:     candidate.Usage.CompletionTokens - baseline.Usage.CompletionTokens,
+		LatencyDeltaMS: candidate.LatencyMS - baseline.LatencyMS,
+	}
+	if ops, similarity, ok := DiffWords(baseline.Content, candidate.Content); ok {
+		d.Ops = ops
+		d.Similarity = &similarity
+	}
+	if baseline.CostUSD != nil && candidate.CostUSD != nil {
+		delta := *candidate.CostUSD - *baseline.CostUSD
+		d.CostDeltaUSD = &delta
+	}
+	return d
+}
+
+func summarize(comparisons []Comparison) Summary {
+	s := Summary{Prompts: len(comparisons)}
+	var (
+		similarity               float64
+		diffed                   int
+		baseLatency, candLatency int64
+		baseCost, candCost       = 0.0, 0.0
+		baseKnown, candKnown     = true, true
+	)
+	for _, cmp := range comparisons {
+		if cmp.Baseline.Error != "" {
+			s.BaselineErrors++
+		}
+		if cmp.Candidate.Error != "" {
+			s.CandidateErrors++
+		}
+		baseLatency += cmp.Baseline.LatencyMS
+		candLatency += cmp.Candidate.LatencyMS
+
+		if cmp.Baseline.CostUSD != nil {
+			baseCost += *cmp.Baseline.CostUSD
+		} else if cmp.Baseline.Error == "" {
+			baseKnown = false
+		}
+		if cmp.Candidate.CostUSD != nil {
+			candCost += *cmp.Candidate.CostUSD
+		} else if cmp.Candidate.Error == "" {
+			candKnown = false
+		}
+
+		if cmp.Diff != nil && cmp.Diff.Similarity != nil {
+			similarity += *cmp.Diff.Similarity
+			diffed++
+		}
+		if cmp.Judge != nil && cmp.Judge.Preference != "" {
+			if s.JudgePreferences == nil {
+				s.JudgePreferences = make(map[string]int)
+			}
+			s.JudgePreferences[cmp.Judge.Preference]++
+		}
+	}
+
+	if n := int64(len(comparisons)); n > 0 {
+		s.BaselineMeanLatencyMS = baseLatency / n
+		s.CandidateMeanLatencyMS = candLatency / n
+	}
+	if diffed > 0 {
+		mean := similarity / float64(diffed)
+		s.MeanSimilarity = &mean
+	}
+	if baseKnown {
+		s.BaselineCostUSD = &baseCost
+	}
+	if candKnown {
+		s.CandidateCostUSD = &candCost
+	}
+	return s
+}
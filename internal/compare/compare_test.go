@@ -0,0 +1,284 @@
+package compare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// answerProvider answers every request through a function of its model
+type answerProvider struct {
+	name   string
+	answer func(req *provider.StandardRequest) (string, error)
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (a *answerProvider) Generate(ctx context.Context, req *provider.GenerateRequest) (*provider.GenerateResponse, error) {
+	a.mu.Lock()
+	a.calls++
+	a.mu.Unlock()
+	text, err := a.answer(req.StandardRequest)
+	if err != nil {
+		return nil, err
+	}
+	msg := provider.Message{Role: provider.RoleAssistant, Content: text}
+	return &provider.GenerateResponse{StandardResponse: provider.CreateStandardResponse("r", req.Model,
+		[]provider.Choice{{Message: &msg}}, provider.Usage{PromptTokens: 1000, CompletionTokens: len(strings.Fields(text))})}, nil
+}
+
+func (a *answerProvider) StreamGenerate(ctx context.Context, req *provider.GenerateRequest) (io.ReadCloser, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a *answerProvider) Embed(ctx context.Context, req *provider.EmbeddingRequest) (*provider.EmbeddingResponse, error) {
+	return nil, provider.ErrEmbeddingsUnsupported
+}
+
+func (a *answerProvider) Transcribe(ctx context.Context, req *provider.TranscriptionRequest) (*provider.TranscriptionResponse, error) {
+	return nil, provider.ErrTranscriptionUnsupported
+}
+
+func (a *answerProvider) GetCapabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{}
+}
+
+func (a *answerProvider) GetInfo() provider.ProviderInfo { return provider.ProviderInfo{Name: a.name} }
+
+func (a *answerProvider) Close() error { return nil }
+
+type modelRouter map[string]provider.Provider
+
+func (m modelRouter) Route(req *provider.RouteRequest) (provider.Provider, error) {
+	if p, ok := m[req.Model]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("no provider matched model %q", req.Model)
+}
+
+func TestCompare(t *testing.T) {
+	models := &answerProvider{name: "openai", answer: func(req *provider.StandardRequest) (string, error) {
+		switch {
+		case req.Model == "old":
+			return "The capital of France is Paris.", nil
+		case strings.Contains(req.Messages[0].Content, "fail"):
+			return "", errors.New("upstream error")
+		default:
+			return "Paris is the capital of France.", nil
+		}
+	}}
+	// The judge always prefers the answer shown as B
+	judge := &answerProvider{name: "judge", answer: func(req *provider.StandardRequest) (string, error) {
+		return "Sure:\n```json\n{\"preference\": \"B\", \"reason\": \"clearer\"}\n```", nil
+	}}
+	c := &Comparer{
+		router:  modelRouter{"old": models, "new": models, "judge": judge},
+		pricing: map[string]config.ModelPrice{"old": {InputPerMTok: 5, OutputPerMTok: 15}, "new": {InputPerMTok: 1, OutputPerMTok: 2}},
+	}
+
+	report, err := c.Compare(context.Background(), &Request{
+		Baseline:  Target{Model: "old"},
+		Candidate: Target{Model: "new"},
+		Judge:     &Target{Model: "judge"},
+		Prompts: []Prompt{
+			{ID: "capital", Prompt: "What is the capital of France?"},
+			{ID: "swapped", Prompt: "Capital of France?"},
+			{ID: "broken", Prompt: "please fail"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+
+	first := report.Comparisons[0]
+	if first.Diff == nil || first.Diff.Similarity == nil || *first.Diff.Similarity >= 1 || len(first.Diff.Ops) == 0 {
+		t.Fatalf("Expected a partial match diff, got %+v", first.Diff)
+	}
+	if first.Diff.CostDeltaUSD == nil || *first.Diff.CostDeltaUSD >= 0 {
+		t.Errorf("Expected the cheaper candidate to have a negative cost delta, got %v", first.Diff.CostDeltaUSD)
+	}
+	// Answer order alternates, so the same judge verdict maps to both sides
+	if first.Judge.Preference != PreferCandidate || report.Comparisons[1].Judge.Preference != PreferBaseline {
+		t.Errorf("Unexpected judgements: %+v, %+v", first.Judge, report.Comparisons[1].Judge)
+	}
+
+	broken := report.Comparisons[2]
+	if broken.Candidate.Error == "" || broken.Diff != nil || broken.Judge != nil {
+		t.Errorf("Expected failed candidate without diff or judgement, got %+v", broken)
+	}
+	if judge.calls != 2 {
+		t.Errorf("Expected 2 judge calls, got %d", judge.calls)
+	}
+
+	s := report.Summary
+	if s.Prompts != 3 || s.CandidateErrors != 1 || s.BaselineErrors != 0 || s.JudgePreferences[PreferCandidate] != 1 {
+		t.Errorf("Unexpected summary: %+v", s)
+	}
+	if s.BaselineCostUSD == nil || s.CandidateCostUSD == nil || *s.CandidateCostUSD >= *s.BaselineCostUSD {
+		t.Errorf("Unexpected summary costs: %+v", s)
+	}
+}
+
+func TestCompareBoundedParallelism(t *testing.T) {
+	var (
+		mu             sync.Mutex
+		inFlight, peak int
+	)
+	models := &answerProvider{name: "openai", answer: func(req *provider.StandardRequest) (string, error) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return req.Model + " " + req.Messages[0].Content, nil
+	}}
+	c := &Comparer{router: modelRouter{"old": models, "new": models}}
+
+	req := &Request{Baseline: Target{Model: "old"}, Candidate: Target{Model: "new"}}
+	for i := 0; i < 3*MaxParallel; i++ {
+		req.Prompts = append(req.Prompts, Prompt{ID: fmt.Sprint(i), Prompt: fmt.Sprint("q", i)})
+	}
+	report, err := c.Compare(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+
+	// Each prompt calls both targets at once
+	if peak > 2*MaxParallel {
+		t.Errorf("Expected at most %d calls in flight, got %d", 2*MaxParallel, peak)
+	}
+	for i, cmp := range report.Comparisons {
+		if cmp.ID != fmt.Sprint(i) || cmp.Baseline.Content != fmt.Sprint("old q", i) || cmp.Candidate.Content != fmt.Sprint("new q", i) {
+			t.Errorf("Comparison %d out of order: %+v", i, cmp)
+		}
+	}
+}
+
+func TestCompareCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	models := &answerProvider{name: "openai", answer: func(req *provider.StandardRequest) (string, error) {
+		cancel()
+		return "", context.Canceled
+	}}
+	c := &Comparer{router: modelRouter{"old": models, "new": models}}
+
+	req := &Request{Baseline: Target{Model: "old"}, Candidate: Target{Model: "new"}}
+	for i := 0; i < 2*MaxParallel; i++ {
+		req.Prompts = append(req.Prompts, Prompt{Prompt: "hi"})
+	}
+	if _, err := c.Compare(ctx, req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	// Prompts queued behind the limit never start once the group is cancelled
+	if models.calls >= 2*len(req.Prompts) {
+		t.Errorf("Expected cancellation to skip remaining prompts, got %d calls", models.calls)
+	}
+}
+
+func TestCompareValidation(t *testing.T) {
+	c := &Comparer{router: modelRouter{}}
+	for name, req := range map[string]*Request{
+		"no models":    {Prompts: []Prompt{{Prompt: "hi"}}},
+		"no prompts":   {Baseline: Target{Model: "a"}, Candidate: Target{Model: "b"}},
+		"empty prompt": {Baseline: Target{Model: "a"}, Candidate: Target{Model: "b"}, Prompts: []Prompt{{ID: "x"}}},
+		"unroutable":   {Baseline: Target{Model: "a"}, Candidate: Target{Model: "b"}, Prompts: []Prompt{{Prompt: "hi"}}},
+	} {
+		if _, err := c.Compare(context.Background(), req); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestFanout(t *testing.T) {
+	models := &answerProvider{name: "openai", answer: func(req *provider.StandardRequest) (string, error) {
+		if req.Model == "broken" {
+			return "", errors.New("upstream error")
+		}
+		return "Answer from " + req.Model, nil
+	}}
+	var judged string
+	// The judge picks the second answer it is shown
+	judge := &answerProvider{name: "judge", answer: func(req *provider.StandardRequest) (string, error) {
+		judged = req.Messages[1].Content
+		return `{"best": 2, "reason": "more precise"}`, nil
+	}}
+	c := &Comparer{
+		router:  modelRouter{"a": models, "b": models, "c": models, "broken": models, "judge": judge},
+		pricing: map[string]config.ModelPrice{"a": {InputPerMTok: 5}, "b": {InputPerMTok: 1}, "c": {InputPerMTok: 3}},
+	}
+	targets := []Target{{Model: "a"}, {Model: "broken"}, {Model: "b"}, {Model: "c"}}
+
+	report, err := c.Fanout(context.Background(), &FanoutRequest{Targets: targets, Prompt: "hi", Scorer: ScoreJudge, Judge: &Target{Model: "judge"}})
+	if err != nil {
+		t.Fatalf("Fanout failed: %v", err)
+	}
+	if len(report.Candidates) != 4 || report.Candidates[1].Error == "" || report.Candidates[3].Content != "Answer from c" {
+		t.Fatalf("Unexpected candidates: %+v", report.Candidates)
+	}
+	// Failed targets are not shown to the judge, so its second answer is b's
+	if report.Best == nil || *report.Best != 2 || report.Reason != "more precise" || strings.Contains(judged, "upstream error") {
+		t.Errorf("Expected b picked by the judge, got %v %q: %s", report.Best, report.Reason, judged)
+	}
+
+	report, err = c.Fanout(context.Background(), &FanoutRequest{Targets: targets, Prompt: "hi", Scorer: ScoreCheapest, BestOnly: true})
+	if err != nil {
+		t.Fatalf("Fanout failed: %v", err)
+	}
+	if len(report.Candidates) != 1 || report.Candidates[0].Model != "b" || *report.Best != 0 {
+		t.Errorf("Expected only the cheapest candidate, got %+v", report.Candidates)
+	}
+
+	for _, req := range []FanoutRequest{
+		{Targets: targets[:1], Prompt: "hi"},
+		{Targets: targets},
+		{Targets: targets, Prompt: "hi", Scorer: ScoreJudge},
+		{Targets: targets, Prompt: "hi", Scorer: "loudest"},
+		{Targets: targets, Prompt: "hi", BestOnly: true},
+		{Targets: []Target{{Model: "a"}, {Model: "unknown"}}, Prompt: "hi"},
+	} {
+		if _, err := c.Fanout(context.Background(), &req); err == nil {
+			t.Errorf("Expected %+v to be rejected", req)
+		}
+	}
+}
+
+func TestDiffWords(t *testing.T) {
+	ops, similarity, ok := DiffWords("the quick brown fox", "the slow brown fox jumps")
+	if !ok {
+		t.Fatal("Expected texts to be diffed")
+	}
+	want := []DiffOp{
+		{Op: OpEqual, Text: "the"},
+		{Op: OpDelete, Text: "quick"},
+		{Op: OpInsert, Text: "slow"},
+		{Op: OpEqual, Text: "brown fox"},
+		{Op: OpInsert, Text: "jumps"},
+	}
+	if fmt.Sprint(ops) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, ops)
+	}
+	if similarity != 2*3.0/9 {
+		t.Errorf("Unexpected similarity %v", similarity)
+	}
+
+	if _, s, _ := DiffWords("same words", "same  words"); s != 1 {
+		t.Errorf("Expected whitespace-only changes to be identical, got %v", s)
+	}
+	long := strings.Repeat("word ", 3000)
+	if _, _, ok := DiffWords(long, long); ok {
+		t.Error("Expected texts beyond the diff bound to be skipped")
+	}
+}
@@ -0,0 +1,74 @@
+package compare
+
+import "strings"
+
+// Diff operations
+const (
+	OpEqual  = "equal"
+	OpDelete = "delete"
+	OpInsert = "insert"
+)
+
+// maxDiffCells bounds the word LCS table; longer pairs are not diffed
+const maxDiffCells = 4 << 20
+
+// DiffOp is a run of words kept, removed from the baseline or added by the
+// candidate
+type DiffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffWords diffs two texts word by word. Similarity is the share of words
+// the texts have in common, 1 for identical texts. ok is false when the
+// texts are too long to diff.
+func DiffWords(a, b string) (ops []DiffOp, similarity float64, ok bool) {
+	aw, bw := strings.Fields(a), strings.Fields(b)
+	n, m := len(aw), len(bw)
+	if n+m == 0 {
+		return nil, 1, true
+	}
+	if (n+1)*(m+1) > maxDiffCells {
+		return nil, 0, false
+	}
+
+	// lcs[i*(m+1)+j] is the LCS length of aw[i:] and bw[j:]
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case aw[i] == bw[j]:
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j]
+			default:
+				lcs[i*(m+1)+j] = lcs[i*(m+1)+j+1]
+			}
+		}
+	}
+
+	emit := func(op, word string) {
+		if last := len(ops) - 1; last >= 0 && ops[last].Op == op {
+			ops[last].Text += " " + word
+			return
+		}
+		ops = append(ops, DiffOp{Op: op, Text: word})
+	}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && aw[i] == bw[j]:
+			emit(OpEqual, aw[i])
+			i++
+			j++
+		case j == m || (i < n && lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]):
+			emit(OpDelete, aw[i])
+			i++
+		default:
+			emit(OpInsert, bw[j])
+			j++
+		}
+	}
+
+	return ops, 2 * float64(lcs[0]) / float64(n+m), true
+}
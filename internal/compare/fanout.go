@@ -0,0 +1,250 @@
+package compare
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"golang.org/x/sync/errgroup"
+)
+
+// fanoutEndpoint is the endpoint fan-out targets are routed as
+const fanoutEndpoint = "/v1/fanout"
+
+// MaxTargets bounds the targets of one fan-out
+const MaxTargets = 8
+
+// Fan-out scorers picking the best candidate
+const (
+	ScoreJudge    = "judge"
+	ScoreFastest  = "fastest"
+	ScoreCheapest = "cheapest"
+)
+
+// FanoutRequest sends one conversation to several targets at once; Prompt
+// is shorthand for a single user message. With a scorer the best answer is
+// picked, and with BestOnly it is the only one returned.
+type FanoutRequest struct {
+	Targets     []Target           `json:"targets"`
+	Prompt      string             `json:"prompt,omitempty"`
+	Messages    []provider.Message `json:"messages,omitempty"`
+	MaxTokens   *int               `json:"max_tokens,omitempty"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	// Scorer is "judge", "fastest" or "cheapest"; empty picks none
+	Scorer string `json:"scorer,omitempty"`
+	// Judge is the model asked for the best answer by the judge scorer
+	Judge    *Target `json:"judge,omitempty"`
+	BestOnly bool    `json:"best_only,omitempty"`
+}
+
+// Candidate is one target's answer
+type Candidate struct {
+	Target
+	Result
+}
+
+// FanoutReport holds the candidates in target order. Best indexes the
+// picked candidate, nil without a scorer or when none could be picked.
+type FanoutReport struct {
+	Candidates []Candidate `json:"candidates"`
+	Best       *int        `json:"best,omitempty"`
+	Reason     string      `json:"reason,omitempty"`
+	// Error tells why no candidate was picked
+	Error string `json:"error,omitempty"`
+	// Usage totals the tokens of every candidate and the judge, and
+	// CostUSD the costs of theirs that are known
+	Usage   provider.Usage `json:"usage"`
+	CostUSD *float64       `json:"cost_usd,omitempty"`
+}
+
+// add counts the usage and cost of one upstream call toward the totals
+func (r *FanoutReport) add(u provider.Usage, cost *float64) {
+	r.Usage.PromptTokens += u.PromptTokens
+	r.Usage.CompletionTokens += u.CompletionTokens
+	r.Usage.TotalTokens += u.TotalTokens
+	r.Usage.CachedPromptTokens += u.CachedPromptTokens
+	r.Usage.ReasoningTokens += u.ReasoningTokens
+	r.Usage.AudioTokens += u.AudioTokens
+	if cost != nil {
+		total := *cost
+		if r.CostUSD != nil {
+			total += *r.CostUSD
+		}
+		r.CostUSD = &total
+	}
+}
+
+// Fanout runs req against every target concurrently and scores the
+// answers. Errors are only returned for invalid requests, unroutable
+// targets, a cancelled ctx or when no target was admitted; failed
+// generations are reported per candidate.
+func (c *Comparer) Fanout(ctx context.Context, req *FanoutRequest) (*FanoutReport, error) {
+	if len(req.Targets) < 2 || len(req.Targets) > MaxTargets {
+		return nil, fmt.Errorf("between 2 and %d targets are required", MaxTargets)
+	}
+	if req.Prompt == "" && len(req.Messages) == 0 {
+		return nil, fmt.Errorf("prompt or messages is required")
+	}
+	switch req.Scorer {
+	case "", ScoreFastest, ScoreCheapest:
+		if req.BestOnly && req.Scorer == "" {
+			return nil, fmt.Errorf("best_only requires a scorer")
+		}
+	case ScoreJudge:
+		if req.Judge == nil || req.Judge.Model == "" {
+			return nil, fmt.Errorf("the judge scorer requires a judge model")
+		}
+	default:
+		return nil, fmt.Errorf("scorer must be %q, %q or %q", ScoreJudge, ScoreFastest, ScoreCheapest)
+	}
+
+	targets := make([]provider.Provider, len(req.Targets))
+	for i, t := range req.Targets {
+		p, err := c.route(fanoutEndpoint, fmt.Sprintf("targets[%d]", i), t)
+		if err != nil {
+			return nil, err
+		}
+		targets[i] = p
+	}
+	var judge provider.Provider
+	if req.Scorer == ScoreJudge {
+		var err error
+		if judge, err = c.route(fanoutEndpoint, "judge", *req.Judge); err != nil {
+			return nil, err
+		}
+	}
+
+	messages := req.Messages
+	if len(messages) == 0 {
+		messages = []provider.Message{{Role: provider.RoleUser, Content: req.Prompt}}
+	}
+	run := &Request{MaxTokens: req.MaxTokens, Temperature: req.Temperature}
+	report := &FanoutReport{Candidates: make([]Candidate, len(req.Targets))}
+	var g errgroup.Group
+	for i, t := range req.Targets {
+		i, t := i, t
+		g.Go(func() error {
+			report.Candidates[i] = Candidate{Target: t, Result: c.run(ctx, targets[i], t, messages, run)}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// A fan-out none of whose targets was admitted is refused as a whole
+	var refused error
+	for _, cand := range report.Candidates {
+		if refused = cand.refused; refused == nil {
+			break
+		}
+	}
+	if refused != nil {
+		return nil, refused
+	}
+	for _, cand := range report.Candidates {
+		report.add(cand.Usage, cand.CostUSD)
+	}
+
+	switch req.Scorer {
+	case ScoreFastest:
+		report.pick(func(a, b *Candidate) bool { return a.LatencyMS < b.LatencyMS })
+	case ScoreCheapest:
+		report.pick(func(a, b *Candidate) bool { return a.CostUSD != nil && (b.CostUSD == nil || *a.CostUSD < *b.CostUSD) })
+	case ScoreJudge:
+		c.judgeBest(ctx, judge, *req.Judge, messages, report)
+	}
+
+	if req.BestOnly && report.Best != nil {
+		report.Candidates = report.Candidates[*report.Best : *report.Best+1]
+		zero := 0
+		report.Best = &zero
+	}
+	return report, nil
+}
+
+// pick marks the candidate that answered and is less than every other
+// answering candidate as best
+func (r *FanoutReport) pick(less func(a, b *Candidate) bool) {
+	for i := range r.Candidates {
+		cand := &r.Candidates[i]
+		if cand.Error != "" {
+			continue
+		}
+		if r.Best == nil || less(cand, &r.Candidates[*r.Best]) {
+			i := i
+			r.Best = &i
+		}
+	}
+	if r.Best == nil {
+		r.Error = "no target answered"
+	}
+}
+
+const bestInstructions = `You pick the best of several answers to the same conversation. Judge helpfulness, correctness and instruction following; ignore length unless it hurts the answer. Reply with JSON only: {"best": <number of the best answer>, "reason": "<one sentence>"}`
+
+// judgeBest asks the judge model which answering candidate is best
+func (c *Comparer) judgeBest(ctx context.Context, p provider.Provider, t Target, messages []provider.Message, report *FanoutReport) {
+	var prompt strings.Builder
+	prompt.WriteString("Conversation:\n")
+	for _, m := range messages {
+		fmt.Fprintf(&prompt, "[%s] %s\n", m.Role, m.Content)
+	}
+	// Answers are numbered from 1 in the prompt, skipping failed targets
+	var shown []int
+	for i, cand := range report.Candidates {
+		if cand.Error == "" {
+			shown = append(shown, i)
+			fmt.Fprintf(&prompt, "\nAnswer %d:\n%s\n", len(shown), cand.Content)
+		}
+	}
+	if len(shown) == 0 {
+		report.Error = "no target answered"
+		return
+	}
+
+	release, err := c.admit(ctx, p, t)
+	if err != nil {
+		report.Error = err.Error()
+		return
+	}
+	defer release()
+	maxTokens, temperature := 256, 0.0
+	resp, err := p.Generate(ctx, &provider.GenerateRequest{StandardRequest: &provider.StandardRequest{
+		Model: t.Model,
+		Messages: []provider.Message{
+			{Role: provider.RoleSystem, Content: bestInstructions},
+			{Role: provider.RoleUser, Content: prompt.String()},
+		},
+		MaxTokens:   &maxTokens,
+		Temperature: &temperature,
+	}})
+	if err != nil {
+		report.Error = err.Error()
+		return
+	}
+	report.add(resp.Usage, c.cost(t.Model, resp))
+	if len(resp.Choices) == 0 || resp.Choices[0].Message == nil {
+		report.Error = "judge returned no answer"
+		return
+	}
+
+	text := resp.Choices[0].Message.Content
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	var out struct {
+		Best   int    `json:"best"`
+		Reason string `json:"reason"`
+	}
+	if start < 0 || end < start || json.Unmarshal([]byte(text[start:end+1]), &out) != nil {
+		report.Error = fmt.Sprintf("unparseable judge answer: %q", text)
+		return
+	}
+	if out.Best < 1 || out.Best > len(shown) {
+		report.Error = fmt.Sprintf("judge picked unknown answer %d", out.Best)
+		return
+	}
+	best := shown[out.Best-1]
+	report.Best, report.Reason = &best, out.Reason
+}
@@ -0,0 +1,6 @@
+package compare
+
+import "go.uber.org/fx"
+
+// Module provides the model comparer.
+var Module = fx.Provide(NewComparer)
@@ -0,0 +1,23 @@
+package config
+
+// Attributes are request properties supplied by an external identity
+// service, e.g. {"tier": "enterprise"}. Routes and admission rules may
+// require them. Example:
+//
+//	routes:
+//	  - prefix: "gpt-4o"
+//	    provider: "openai"
+//	    attributes:
+//	      tier: "enterprise"
+type Attributes map[string]string
+
+// Match reports whether got carries every attribute in a with the same
+// value; empty requirements match any request
+func (a Attributes) Match(got map[string]string) bool {
+	for k, v := range a {
+		if got[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"runtime"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -11,16 +14,43 @@ import (
 type Config struct {
 	Server struct {
 		Addr string `yaml:"addr"`
+		// Listeners replace addr, e.g. to listen on both address families
+		// or on IPv6 only, with TLS per address
+		Listeners []Listener `yaml:"listeners"`
+		// Buffering between upstream streams and clients
+		Streaming Streaming `yaml:"streaming"`
+		// Serve the gRPC chat service on this address, e.g. ":9090"
+		GRPCAddr string `yaml:"grpc_addr"`
 	} `yaml:"server"`
 
-	// Route model names to a provider by prefix match (first match wins).
+	// Route model names to a provider by prefix, glob or regex match (first
+	// match wins).
 	// Example:
 	// routes:
 	//   - prefix: "gpt-"
 	//     provider: "openai"
 	//   - prefix: "gemini-"
 	//     provider: "gemini"
+	//   - prefix: "mistral-"
+	//     provider: "mistral"
 	Routes []Route `yaml:"routes"`
+	// How a route is picked among those matching a request: "first"
+	// (default); "cheapest", the matching route with the lowest estimated
+	// cost whose model can serve the request, priced by the route's price
+	// or else pricing; or "sticky", which pins every turn of a
+	// conversation to one of the matching routes by hashing its identifier
+	// (X-LetLLM-Conversation, session_id or user), for prompt caching and
+	// reproducible A/B tests
+	RoutingStrategy string `yaml:"routing_strategy"`
+
+	// Stable model names clients use, mapped to the models they currently
+	// stand for, e.g. default-chat: gpt-4o-mini; resolved before routing,
+	// after the caller key's own aliases
+	ModelAliases map[string]string `yaml:"model_aliases"`
+
+	// A/B tests splitting the chat completions of a model between variants
+	// by caller, so downstream evaluation can compare them on real traffic
+	Experiments []Experiment `yaml:"experiments"`
 
 	// Provider settings
 	OpenAI struct {
@@ -34,11 +64,994 @@ type Config struct {
 		BaseURL      string `yaml:"base_url"`
 		DefaultModel string `yaml:"default_model"`
 	} `yaml:"gemini"`
+
+	Mistral struct {
+		APIKey       string `yaml:"api_key"`
+		BaseURL      string `yaml:"base_url"`
+		DefaultModel string `yaml:"default_model"`
+	} `yaml:"mistral"`
+
+	Cohere struct {
+		APIKey       string `yaml:"api_key"`
+		BaseURL      string `yaml:"base_url"`
+		DefaultModel string `yaml:"default_model"`
+	} `yaml:"cohere"`
+
+	DeepSeek struct {
+		APIKey       string `yaml:"api_key"`
+		BaseURL      string `yaml:"base_url"`
+		DefaultModel string `yaml:"default_model"`
+	} `yaml:"deepseek"`
+
+	OpenRouter struct {
+		APIKey       string `yaml:"api_key"`
+		BaseURL      string `yaml:"base_url"`
+		DefaultModel string `yaml:"default_model"`
+		// App attribution sent as HTTP-Referer and X-Title
+		Referer string `yaml:"referer"`
+		Title   string `yaml:"title"`
+	} `yaml:"openrouter"`
+
+	Perplexity struct {
+		APIKey       string `yaml:"api_key"`
+		BaseURL      string `yaml:"base_url"`
+		DefaultModel string `yaml:"default_model"`
+	} `yaml:"perplexity"`
+
+	// Zhipu keys have the form <id>.<secret>; requests are signed with a JWT
+	Zhipu struct {
+		APIKey       string `yaml:"api_key"`
+		BaseURL      string `yaml:"base_url"`
+		DefaultModel string `yaml:"default_model"`
+	} `yaml:"zhipu"`
+
+	// A GGUF model run in process by llama.cpp; needs a build with -tags llamacpp
+	LlamaCpp struct {
+		ModelPath string `yaml:"model_path"`
+		// Name clients request (default the file name without ".gguf")
+		ModelName string `yaml:"model_name"`
+		// Context window in tokens (default 4096)
+		ContextSize int `yaml:"context_size"`
+		// CPU threads used for generation (default the number of CPUs)
+		Threads int `yaml:"threads"`
+		// Layers offloaded to the GPU
+		GPULayers int `yaml:"gpu_layers"`
+		// Prompt format the model was trained on: "chatml" (default) or "llama3"
+		ChatTemplate string `yaml:"chat_template"`
+	} `yaml:"llamacpp"`
+
+	// Certificate verification per provider name, including openai_compatible
+	// instances, for private CAs and TLS-intercepting proxies
+	ProviderTLS map[string]ProviderTLS `yaml:"provider_tls"`
+
+	// Several upstream API keys per provider name, used in turn in place of
+	// the provider's api_key (which must still be set to enable it), to scale
+	// beyond the quota of a single key
+	ProviderKeys map[string]ProviderKeyPool `yaml:"provider_keys"`
+
+	// Retries of failed upstream requests per provider name. Requests are
+	// retried before any of the response reaches the client, so streams are
+	// retried only until their first byte.
+	ProviderRetries map[string]RetryPolicy `yaml:"provider_retries"`
+
+	// Timeouts of upstream requests per provider name
+	ProviderTimeouts map[string]ProviderTimeouts `yaml:"provider_timeouts"`
+
+	// Active/standby provider pairs; traffic for an active provider goes to
+	// its standby while failed over
+	Failover []FailoverPair `yaml:"failover"`
+
+	// Named endpoints speaking the OpenAI chat API, such as vLLM or LM
+	// Studio; routes refer to an instance by its name
+	OpenAICompatible []OpenAICompatibleConfig `yaml:"openai_compatible"`
+
+	// Other letllm-go gateways, such as a regional one, that selected models
+	// are routed to; routes refer to an instance by its name
+	RemoteLetLLM []RemoteLetLLMConfig `yaml:"remote_letllm"`
+
+	// Requests arriving from other gateways' remote_letllm providers
+	Federation struct {
+		// IDs of the virtual keys peer gateways call with; only their
+		// requests may name the key usage is recorded against at the origin
+		TrustedKeys []string `yaml:"trusted_keys"`
+	} `yaml:"federation"`
+
+	// Forward /v1 requests for endpoints the gateway does not implement
+	Passthrough struct {
+		// Name of the OpenAI or OpenAI-compatible provider they go to, with
+		// its credentials in place of the caller's; empty disables it
+		Provider string `yaml:"provider"`
+	} `yaml:"passthrough"`
+
+	// Named providers answering from canned responses, for tests and demos
+	// without API keys; routes refer to an instance by its name
+	Mock []MockConfig `yaml:"mock"`
+
+	// Record provider traffic to cassettes, or replay it without calling
+	// upstreams, for hermetic tests of the whole HTTP path
+	VCR VCRConfig `yaml:"vcr"`
+
+	// Admin API settings
+	Admin struct {
+		// Bearer token required for /admin endpoints; the admin API is disabled when empty
+		Token string `yaml:"token"`
+		// Summaries of the most recent API requests kept in memory for
+		// /admin/recent, whatever the storage driver (default 500)
+		RecentRequests int `yaml:"recent_requests"`
+	} `yaml:"admin"`
+
+	// Persistent state for keys and other stateful subsystems
+	Storage struct {
+		// "sqlite" (default), "postgres" or "memory"
+		Driver string `yaml:"driver"`
+		// Data source name; for sqlite a file path (defaults to "letllm.db"),
+		// for postgres a connection URL
+		DSN string `yaml:"dsn"`
+		// Connection pool cap for postgres (0 = unlimited)
+		MaxOpenConns int `yaml:"max_open_conns"`
+	} `yaml:"storage"`
+
+	// Redis server (5 or later) shared by the gateway's replicas; when set,
+	// rate limits are counted there so they hold across the cluster
+	Redis struct {
+		// host:port
+		Addr     string `yaml:"addr"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+		DB       int    `yaml:"db"`
+		// Prefix of every key the gateway writes (default "letllm:")
+		KeyPrefix string `yaml:"key_prefix"`
+		// Idle connections kept for reuse (default 10)
+		PoolSize int `yaml:"pool_size"`
+		// Bound on dialing and on each command (default 1s)
+		Timeout time.Duration `yaml:"timeout"`
+	} `yaml:"redis"`
+
+	// Where the response cache and the upstream model list cache keep
+	// their entries
+	Cache struct {
+		// "memory" (default), in each replica; "redis", the shared Redis,
+		// so replicas share entries; or "disk", files under dir that
+		// survive restarts
+		Backend string `yaml:"backend"`
+		// Bounds of the memory and disk caches, past which the least
+		// recently used entries are evicted (defaults 10000 and 256MiB)
+		MaxEntries int    `yaml:"max_entries"`
+		MaxBytes   int64  `yaml:"max_bytes"`
+		Dir        string `yaml:"dir"`
+	} `yaml:"cache"`
+
+	// Virtual API keys issued by the gateway
+	Keys []KeyConfig `yaml:"keys"`
+	// Refuse API requests, over HTTP under /v1 and /v1beta or over gRPC,
+	// that carry no enabled virtual key
+	RequireKeys bool `yaml:"require_keys"`
+
+	// Daily and monthly spend caps of virtual keys or of every key of an
+	// owner. Spend is estimated from pricing, so models without a price
+	// do not count toward it.
+	Budgets []BudgetConfig `yaml:"budgets"`
+
+	// Handling of the Idempotency-Key header on POST API requests.
+	// Duplicates of a request in flight wait for its response, and retries
+	// get the stored response, kept in the cache backend.
+	Idempotency struct {
+		// Ignore the header
+		Disabled bool `yaml:"disabled"`
+		// How long responses are kept for retries (default 24h)
+		TTL time.Duration `yaml:"ttl"`
+		// Larger responses are not kept (default 1MiB)
+		MaxResponseBytes int `yaml:"max_response_bytes"`
+	} `yaml:"idempotency"`
+
+	// Bounds on API requests, checked before they reach a provider. Zero
+	// leaves a bound unset.
+	RequestLimits struct {
+		// Largest JSON body of a request under /v1 and /v1beta; file and
+		// audio uploads have limits of their own
+		MaxBodyBytes int64 `yaml:"max_body_bytes"`
+		// Most messages in a conversation
+		MaxMessages int `yaml:"max_messages"`
+		// Most characters of message content, reasoning and function calls
+		MaxPromptChars int `yaml:"max_prompt_chars"`
+		// Most prompt tokens, as estimated by the gateway
+		MaxPromptTokens int `yaml:"max_prompt_tokens"`
+	} `yaml:"request_limits"`
+
+	// Adaptive pacing toward upstreams based on their rate limit headers
+	Pacing struct {
+		// Turn pacing off; upstream quota is still exported as metrics
+		Disabled bool `yaml:"disabled"`
+		// Fraction of remaining quota below which requests are spread out (default 0.1)
+		Threshold float64 `yaml:"threshold"`
+		// Longest delay added to a single request, e.g. "10s" (default 10s)
+		MaxDelay time.Duration `yaml:"max_delay"`
+	} `yaml:"pacing"`
+
+	// Concurrency caps and queueing applied before dispatching upstream
+	Admission struct {
+		// Caps on in-flight requests per provider, keyed by provider name
+		Providers map[string]ConcurrencyLimit `yaml:"providers"`
+		// Caps on in-flight requests per model; the first matching rule wins
+		Models []ModelConcurrency `yaml:"models"`
+		// Requests and tokens per minute across the gateway; virtual keys
+		// carry their own. Counted in redis when it is configured and in
+		// each replica otherwise.
+		RateLimit RateLimit `yaml:"rate_limit"`
+		// Requests and tokens per minute per model; the first matching rule
+		// wins
+		ModelRateLimits []ModelRateLimit `yaml:"model_rate_limits"`
+	} `yaml:"admission"`
+
+	// Refusal of new streaming requests while the process is under memory
+	// or goroutine pressure; thresholds can be changed at runtime through
+	// /admin/load-shedding
+	LoadShedding struct {
+		// Memory obtained from the OS and not released, in bytes (0 = unchecked)
+		MaxMemoryBytes uint64 `yaml:"max_memory_bytes"`
+		// Live goroutines (0 = unchecked)
+		MaxGoroutines int `yaml:"max_goroutines"`
+		// How often pressure is sampled (default 1s)
+		SampleInterval time.Duration `yaml:"sample_interval"`
+		// Retry-After sent with shed requests (default 5s)
+		RetryAfter time.Duration `yaml:"retry_after"`
+	} `yaml:"load_shedding"`
+
+	// Demand signals for external autoscalers of self-hosted backends
+	Autoscaling struct {
+		// Targets keyed by provider name, e.g. an Ollama or vLLM route
+		Providers map[string]AutoscalingTarget `yaml:"providers"`
+		// How often signals are computed and webhooks called (default 15s)
+		Interval time.Duration `yaml:"interval"`
+	} `yaml:"autoscaling"`
+
+	// Per-request attributes from an external identity or entitlement service
+	Enrichment struct {
+		// Service receiving a JSON POST per request; disabled when empty
+		URL string `yaml:"url"`
+		// Client request headers sent to the service (default Authorization)
+		ForwardHeaders []string `yaml:"forward_headers"`
+		// Longest wait for the service (default 2s)
+		Timeout time.Duration `yaml:"timeout"`
+		// How long attributes are cached per identity (default 5m)
+		CacheTTL time.Duration `yaml:"cache_ttl"`
+		// Serve requests without attributes when the service fails, instead
+		// of rejecting them
+		FailOpen bool `yaml:"fail_open"`
+	} `yaml:"enrichment"`
+
+	// Named prompts clients expand with variables by passing template on
+	// chat completions, instead of sending the messages themselves
+	PromptTemplates []PromptTemplate `yaml:"prompt_templates"`
+
+	// Tools the gateway executes itself when a model calls them. Streams are
+	// relayed as they arrive, with tool progress events while a called tool
+	// runs.
+	Tools []ToolConfig `yaml:"tools"`
+
+	// Per-request budget of the server-side tool loop
+	ToolLoop struct {
+		// Model calls per request (default 8)
+		MaxIterations int `yaml:"max_iterations"`
+		// Tokens summed over all model calls (0 = unlimited)
+		MaxTotalTokens int `yaml:"max_total_tokens"`
+		// Wall time of the whole loop (0 = unlimited)
+		MaxDuration time.Duration `yaml:"max_duration"`
+		// Summed cost_usd of executed tool calls (0 = unlimited)
+		MaxToolCostUSD float64 `yaml:"max_tool_cost_usd"`
+	} `yaml:"tool_loop"`
+
+	// Content-addressed prompt fragments that messages reference by ID
+	Artifacts struct {
+		// Largest accepted upload in bytes (default 8 MiB)
+		MaxBytes int64 `yaml:"max_bytes"`
+		// Artifact content kept in memory for expansion, in bytes (default 64 MiB)
+		CacheBytes int64 `yaml:"cache_bytes"`
+	} `yaml:"artifacts"`
+
+	// Periodic reports of personal data found in stored prompt artifacts, so
+	// compliance can check that clients redact what they send
+	PIIReports struct {
+		// How often a report is produced; reporting is off when unset
+		Interval time.Duration `yaml:"interval"`
+		// Artifacts classified per report (default 200)
+		SampleSize int `yaml:"sample_size"`
+		// Reports kept in memory for /admin/pii/reports (default 48)
+		Keep int `yaml:"keep"`
+	} `yaml:"pii_reports"`
+
+	// Full-fidelity timelines of a sample of streaming requests, kept for
+	// latency analysis
+	Tracing struct {
+		// Fraction of streams traced, between 0 (default, off) and 1
+		SampleRate float64 `yaml:"sample_rate"`
+		// Reads, writes and flushes recorded per stream (default 10000);
+		// longer streams are marked truncated
+		MaxEvents int `yaml:"max_events"`
+		// Traces retained, oldest dropped first (default 1000)
+		Keep int `yaml:"keep"`
+	} `yaml:"tracing"`
+
+	// Files uploaded through /v1/files, such as batch inputs and outputs
+	Files struct {
+		// Where file content is kept: "database" (default), "memory"
+		// (default with the memory storage driver), "disk" or "s3"
+		Storage string `yaml:"storage"`
+		// Directory of disk storage (default "files")
+		Dir string `yaml:"dir"`
+		// Size limit of uploads (default 512MiB); batch inputs are also
+		// bounded by batches.max_file_bytes
+		MaxBytes int64    `yaml:"max_bytes"`
+		S3       S3Config `yaml:"s3"`
+	} `yaml:"files"`
+
+	// Asynchronous batches submitted through /v1/batches
+	Batches struct {
+		// Requests of one batch in flight at once (default 4)
+		Concurrency int `yaml:"concurrency"`
+		// Requests allowed in one input file (default 50000)
+		MaxRequests int `yaml:"max_requests"`
+		// Size limit of uploaded files (default 100MiB)
+		MaxFileBytes int64 `yaml:"max_file_bytes"`
+		// How often unfinished batches are looked for (default 5s); new
+		// batches start right away
+		PollInterval time.Duration `yaml:"poll_interval"`
+	} `yaml:"batches"`
+
+	// Limits and features of models or model families, refining the
+	// built-in catalog; the first matching entry wins
+	Models []ModelSpec `yaml:"models"`
+
+	// Prices by model name, for cost estimates where the provider does not
+	// report the cost itself
+	Pricing map[string]ModelPrice `yaml:"pricing"`
+
+	// Per-request usage metadata and how it may be exported
+	Usage struct {
+		// "raw" allows exporting individual records; "aggregated" only allows
+		// grouped counts and histograms (default "raw")
+		ExportMode string `yaml:"export_mode"`
+		// Aggregated exports suppress groups with fewer requests (default 10)
+		MinGroupSize int `yaml:"min_group_size"`
+		// Where records are written: "storage" (default), the shared
+		// database or memory, or "clickhouse"
+		Backend    string           `yaml:"backend"`
+		ClickHouse ClickHouseConfig `yaml:"clickhouse"`
+		// Records are written in the background, in batches of up to
+		// batch_size (default 100) at least every flush_interval (default
+		// 1s). Beyond queue_size (default 10000) waiting records are dropped.
+		BatchSize     int           `yaml:"batch_size"`
+		FlushInterval time.Duration `yaml:"flush_interval"`
+		QueueSize     int           `yaml:"queue_size"`
+	} `yaml:"usage"`
+
+	// Identifiers the gateway assigns to requests and responses
+	IDs struct {
+		// "ulid" (default), which sorts by time, or "uuid"
+		Format string `yaml:"format"`
+	} `yaml:"ids"`
+
+	// Request transformation reporting
+	Transform struct {
+		// List fields the provider could not honour in an X-LetLLM-Dropped-Fields response header
+		ReportDroppedFields bool `yaml:"report_dropped_fields"`
+	} `yaml:"transform"`
 }
 
 type Route struct {
 	Prefix   string `yaml:"prefix"`
-	Provider string `yaml:"provider"` // "openai" or "gemini"
+	Provider string `yaml:"provider"` // "openai", "gemini", "mistral", "cohere", "deepseek", "openrouter", "perplexity", "zhipu", "llamacpp" or an openai_compatible or mock name
+	// Optional model name sent upstream instead of the requested one, e.g.
+	// an Azure deployment; responses report the requested name
+	Model string `yaml:"model,omitempty"`
+
+	// How models are matched: "prefix" (default) by prefix, or "glob" and
+	// "regex" by pattern against the whole model name
+	Match   string `yaml:"match,omitempty"`
+	Pattern string `yaml:"pattern,omitempty"`
+
+	// Optional time-of-day access policy; requests outside it are refused
+	Schedule *Schedule `yaml:"schedule,omitempty"`
+
+	// Optional enrichment attributes a request must carry for this route to
+	// match; non-matching requests fall through to later routes
+	Attributes Attributes `yaml:"attributes,omitempty"`
+
+	// Optional request headers and the values a request must send for this
+	// route to match, e.g. X-Env: staging; non-matching requests fall
+	// through to later routes
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Optional caching of non-streaming chat completions; requests are only
+	// cached on routes that set it
+	Cache *RouteCache `yaml:"cache,omitempty"`
+
+	// Optional price of models on this route, overriding pricing, e.g.
+	// for a provider reselling a model at its own rates
+	Price *ModelPrice `yaml:"price,omitempty"`
+
+	// Optional share of the route's requests sent to another provider,
+	// e.g. a new backend being rolled out
+	Canary *RouteCanary `yaml:"canary,omitempty"`
+
+	// Optional provider chat completions on this route are copied to in
+	// the background; its answers are compared and logged, never returned
+	Mirror *RouteMirror `yaml:"mirror,omitempty"`
+
+	// Optional provider non-streaming chat completions refused with a
+	// content_filter finish reason are retried on before the refusal is
+	// returned
+	ContentFilterFallback *RouteFallback `yaml:"content_filter_fallback,omitempty"`
+
+	// Optional provider streamed chat completions are also sent to when the
+	// routed provider has not streamed anything after a delay; whichever
+	// streams first is relayed and the other cancelled
+	Hedge *RouteHedge `yaml:"hedge,omitempty"`
+}
+
+// RouteHedge races a slow stream against a second provider to cut tail
+// latency
+type RouteHedge struct {
+	Provider string `yaml:"provider"`
+	// Model the hedge is asked for (default the requested model)
+	Model string `yaml:"model"`
+	// How long the routed provider has to stream its first chunk
+	After time.Duration `yaml:"after"`
+}
+
+// RouteFallback names the provider, and optionally the model, a route
+// retries requests on
+type RouteFallback struct {
+	Provider string `yaml:"provider"`
+	// Model the fallback is asked for (default the requested model)
+	Model string `yaml:"model"`
+}
+
+// RouteMirror shadows a route's chat completions on another provider, for
+// offline evaluation of a model before it serves traffic
+type RouteMirror struct {
+	Provider string `yaml:"provider"`
+	// Model the mirror is asked for (default the requested model)
+	Model string `yaml:"model"`
+	// Share of requests mirrored (default 100)
+	Percent float64 `yaml:"percent"`
+}
+
+// RouteCanary sends a percentage of a route's requests to another provider.
+// Requests carrying a conversation identifier are bucketed by it, so every
+// turn of a conversation stays on the same side.
+type RouteCanary struct {
+	Provider string  `yaml:"provider"`
+	Percent  float64 `yaml:"percent"`
+}
+
+// Routing strategies
+const (
+	RoutingFirst    = "first"
+	RoutingCheapest = "cheapest"
+	RoutingSticky   = "sticky"
+)
+
+// RouteCache allows identical chat completions on a route to be answered
+// from a cache. Clients send Cache-Control: no-cache to skip cached answers,
+// no-store to keep the exchange out of the cache entirely, and max-age to
+// accept answers up to that many seconds old and cache theirs as long.
+type RouteCache struct {
+	// How long responses are cached for clients without a max-age; zero
+	// caches only for clients that opt in with max-age
+	TTL time.Duration `yaml:"ttl"`
+	// Longest max-age honored from clients (default 1h, or ttl when longer)
+	MaxTTL time.Duration `yaml:"max_ttl"`
+}
+
+// OpenAICompatibleConfig declares one OpenAI-compatible provider instance
+type OpenAICompatibleConfig struct {
+	Name    string `yaml:"name"`
+	BaseURL string `yaml:"base_url"`
+	// Optional; local servers commonly run without auth
+	APIKey       string `yaml:"api_key"`
+	DefaultModel string `yaml:"default_model"`
+	// Models served by the instance; requests for them are routed here
+	// without a matching entry in routes
+	Models []string `yaml:"models"`
+}
+
+// RemoteLetLLMConfig declares another letllm-go gateway serving models
+// through this one
+type RemoteLetLLMConfig struct {
+	Name string `yaml:"name"`
+	// The remote gateway's API root, e.g. "https://eu.gateway.example/v1"
+	BaseURL string `yaml:"base_url"`
+	// Virtual key the remote gateway issued to this one
+	APIKey string `yaml:"api_key"`
+	// Send the caller's own key instead, for gateways sharing their keys
+	ForwardAuth  bool   `yaml:"forward_auth"`
+	DefaultModel string `yaml:"default_model"`
+	// Models served by the remote gateway; requests for them are routed
+	// there without a matching entry in routes
+	Models []string `yaml:"models"`
+}
+
+// builtinProviders are the provider names with a dedicated config block
+var builtinProviders = []string{"openai", "gemini", "mistral", "cohere", "deepseek", "openrouter", "perplexity", "zhipu", "llamacpp"}
+
+// Listener networks
+const (
+	NetworkTCP  = "tcp"  // dual-stack for unspecified hosts such as "[::]:8080"
+	NetworkTCP4 = "tcp4" // IPv4 only
+	NetworkTCP6 = "tcp6" // IPv6 only
+)
+
+// MockConfig is a provider replying with scripted responses
+type MockConfig struct {
+	Name string `yaml:"name"`
+	// Models routed here without a matching entry in routes
+	Models []string `yaml:"models"`
+	// Delay before a response or the first stream chunk
+	Latency time.Duration `yaml:"latency"`
+	// Delay between stream chunks
+	ChunkDelay time.Duration `yaml:"chunk_delay"`
+	// Scripted conversations, checked in order before responses
+	Scenarios []MockScenario `yaml:"scenarios"`
+	// Checked in order; the first match answers. Requests nothing matches
+	// get their last user message echoed back.
+	Responses []MockResponse `yaml:"responses"`
+}
+
+// MockScenario is a scripted multi-turn conversation. The turn played is
+// picked by the number of assistant messages already in the conversation,
+// so a client replaying the history gets the same answers every time.
+type MockScenario struct {
+	Name string `yaml:"name"`
+	// Substring of the conversation's first user message; empty matches
+	// every conversation
+	Match string `yaml:"match"`
+	// Assistant turns in order; their match is ignored. Conversations
+	// longer than the script fall back to responses.
+	Turns []MockResponse `yaml:"turns"`
+}
+
+// MockResponse is one canned answer of a mock provider. Content, deltas and
+// function call arguments are Go templates over the request; see the mock
+// provider for the available fields.
+type MockResponse struct {
+	// Substring of the last user message; empty matches every request
+	Match   string `yaml:"match"`
+	Content string `yaml:"content"`
+	// Stream deltas; content is split into words when empty
+	Deltas []string `yaml:"deltas"`
+	// Call a function after any content
+	FunctionCall *MockFunctionCall `yaml:"function_call"`
+	// Pauses injected into streams
+	Stalls []MockStall `yaml:"stalls"`
+	// Finish reason (default "function_call" with a function call, "stop"
+	// otherwise)
+	FinishReason string `yaml:"finish_reason"`
+	// Fail the request with this message instead of answering
+	Error string `yaml:"error"`
+}
+
+// Validate checks the function call and stalls
+func (r MockResponse) Validate() error {
+	if r.FunctionCall != nil && r.FunctionCall.Name == "" {
+		return fmt.Errorf("function_call: name is required")
+	}
+	for _, st := range r.Stalls {
+		if st.After < 0 || st.Duration <= 0 {
+			return fmt.Errorf("stalls: after must not be negative and duration must be positive")
+		}
+	}
+	return nil
+}
+
+// MockFunctionCall is a function call made by a mock answer
+type MockFunctionCall struct {
+	Name string `yaml:"name"`
+	// JSON-encoded arguments
+	Arguments string `yaml:"arguments"`
+}
+
+// MockStall pauses a mock stream on top of the chunk delay
+type MockStall struct {
+	// Number of content deltas sent before the pause
+	After    int           `yaml:"after"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+// VCR modes
+const (
+	VCRRecord = "record"
+	VCRReplay = "replay"
+)
+
+// VCRConfig wraps every provider in a recorder or a replayer
+type VCRConfig struct {
+	// VCRRecord or VCRReplay; empty disables the VCR
+	Mode string `yaml:"mode"`
+	// Directory holding one cassette file per distinct request
+	Dir string `yaml:"dir"`
+	// Replay stream chunks with their recorded spacing instead of at once
+	ReplayDelays bool `yaml:"replay_delays"`
+}
+
+// Listener is one address the gateway serves on
+type Listener struct {
+	// host:port, with IPv6 hosts in brackets, e.g. "[::]:8080"
+	Addr string `yaml:"addr"`
+	// "tcp", "tcp4" or "tcp6" (default "tcp")
+	Network string `yaml:"network"`
+	// Serve HTTPS with this certificate and key
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+	// Require client certificates signed by a CA in this PEM bundle
+	ClientCAFile string `yaml:"client_ca_file"`
+	// Client certificate subjects, as a distinguished name such as
+	// "CN=search,O=Acme" or a bare common name, mapped to the virtual key
+	// IDs their requests are made as in place of a bearer token
+	ClientKeys map[string]string `yaml:"client_keys"`
+}
+
+// Streaming tunes how streamed responses are relayed. Upstream reads are
+// queued for the client writer; when the client falls behind, queued reads
+// are coalesced into fewer writes and flushes are batched.
+type Streaming struct {
+	// Bytes read from the upstream at a time (default 4096)
+	ReadBufferSize int `yaml:"read_buffer_size"`
+	// Reads queued while the client is slower than the upstream (default 8)
+	ChannelBuffer int `yaml:"channel_buffer"`
+	// Queued reads are merged into one write until it reaches this many
+	// bytes (default 16384)
+	CoalesceBytes int `yaml:"coalesce_bytes"`
+	// Keep one write per upstream read
+	DisableCoalescing bool `yaml:"disable_coalescing"`
+	// While reads are queued, flush after this many unflushed bytes
+	// (default 32768); an idle queue always flushes at once
+	FlushBytes int `yaml:"flush_bytes"`
+	// While reads are queued, longest time written data waits for a flush
+	// (default 50ms)
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// Longest a single write or flush to the client may block (default 10s)
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// Longest the client may stay behind the upstream, with reads still
+	// queued after each write, before the stream is ended with a
+	// slow_client error (default 30s)
+	SlowClientTimeout time.Duration `yaml:"slow_client_timeout"`
+}
+
+// ProviderTLS customizes how a provider's server certificates are verified
+type ProviderTLS struct {
+	// PEM bundle of CAs trusted in addition to the system trust store
+	CAFile string `yaml:"ca_file"`
+	// Trust only CAFile, not the system trust store
+	ExcludeSystemRoots bool `yaml:"exclude_system_roots"`
+	// Base64 SHA-256 hashes of certificate public keys (SPKI), optionally
+	// prefixed "sha256/"; the verified chain must contain one of them
+	PinnedSHA256 []string `yaml:"pinned_sha256"`
+}
+
+// Key pool strategies
+const (
+	KeyPoolRoundRobin = "round_robin"
+	KeyPoolRemaining  = "remaining"
+)
+
+// ProviderKeyPool rotates the upstream API keys of a provider. A key the
+// upstream answers with 429 cools down and the request is retried once on
+// another key.
+type ProviderKeyPool struct {
+	Keys []string `yaml:"keys"`
+	// "round_robin" (default), or "remaining": the key with the most
+	// requests left in the upstream's rate limit window
+	Strategy string `yaml:"strategy"`
+	// How long a rate limited key is skipped when the upstream sends no
+	// Retry-After (default 1m)
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+// ProviderTimeouts bound a provider's upstream requests. Zero leaves a
+// bound unset.
+type ProviderTimeouts struct {
+	// Longest to wait for a connection, TLS handshake included
+	Connect time.Duration `yaml:"connect"`
+	// Longest to wait for the first byte of the response body, per attempt
+	// when retried
+	FirstByte time.Duration `yaml:"first_byte"`
+	// Longest a request may take, retries and reading the whole response
+	// included
+	Total time.Duration `yaml:"total"`
+}
+
+// RetryPolicy retries upstream requests that fail to connect or answer
+// with a retryable status, waiting with exponential backoff in between, or
+// as long as the upstream's Retry-After asks
+type RetryPolicy struct {
+	// Attempts in all, the first included (default 3)
+	MaxAttempts int `yaml:"max_attempts"`
+	// Wait before the first retry (default 500ms), multiplied by multiplier
+	// (default 2) for each retry after it
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	Multiplier     float64       `yaml:"multiplier"`
+	// Cap on each wait (default 10s). Retries the upstream's Retry-After
+	// puts further off are not made.
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// Fraction of each wait that is randomized, from 0 to 1 (default 0.2),
+	// so that clients failed together do not retry together
+	Jitter *float64 `yaml:"jitter"`
+	// Statuses that are retried (default 408, 429, 500, 502, 503 and 504)
+	RetryOn []int `yaml:"retry_on"`
+}
+
+// DefaultRetryOn are the statuses retried unless retry_on is set
+var DefaultRetryOn = []int{408, 429, 500, 502, 503, 504}
+
+// DefaultRetryJitter is the jitter unless set
+const DefaultRetryJitter = 0.2
+
+// FailoverPair declares Standby as the stand-in for Active
+type FailoverPair struct {
+	Active  string `yaml:"active"`
+	Standby string `yaml:"standby"`
+	// Consecutive failed requests to Active that switch traffic to Standby
+	// automatically (0 = manual switch only)
+	FailureThreshold int `yaml:"failure_threshold"`
+}
+
+// Usage export modes
+const (
+	UsageExportRaw        = "raw"
+	UsageExportAggregated = "aggregated"
+)
+
+// Usage record backends
+const (
+	UsageBackendStorage    = "storage"
+	UsageBackendClickHouse = "clickhouse"
+)
+
+// Cache backends
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+	CacheBackendDisk   = "disk"
+)
+
+// identifierPattern matches the database and table names written into
+// ClickHouse queries
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ClickHouseConfig locates the ClickHouse table usage records are written
+// to over its HTTP interface
+type ClickHouseConfig struct {
+	// e.g. http://clickhouse:8123
+	URL      string `yaml:"url"`
+	Database string `yaml:"database"`
+	// Created when missing (default "usage_records")
+	Table    string `yaml:"table"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// ConcurrencyLimit caps in-flight upstream requests. Requests beyond the cap
+// wait in a bounded queue and are rejected when it is full or they time out.
+type ConcurrencyLimit struct {
+	MaxConcurrent int `yaml:"max_concurrent"`
+	// Requests allowed to wait for a slot (0 = reject immediately)
+	MaxQueue int `yaml:"max_queue"`
+	// Longest time a request waits for a slot (default 30s)
+	QueueTimeout time.Duration `yaml:"queue_timeout"`
+}
+
+// ModelConcurrency applies a ConcurrencyLimit to models. Model is an exact
+// name or a prefix ending in "*"; all models matching a rule share its slots.
+type ModelConcurrency struct {
+	Model string `yaml:"model"`
+	// Optional enrichment attributes a request must carry for the rule to
+	// apply, e.g. a lower cap for {tier: free}
+	Attributes       Attributes `yaml:"attributes,omitempty"`
+	ConcurrencyLimit `yaml:",inline"`
+}
+
+// RateLimit is a budget of requests and tokens per minute; zero is
+// unlimited. Tokens are counted once a response reports its usage, so a
+// request is admitted while any token budget is left.
+type RateLimit struct {
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	TokensPerMinute   int `yaml:"tokens_per_minute"`
+}
+
+// BudgetConfig caps the estimated spend of a key, or of every key of an
+// owner such as a team, per UTC calendar day and month
+type BudgetConfig struct {
+	// Exactly one of the key ID and owner the budget applies to
+	Key   string `yaml:"key"`
+	Owner string `yaml:"owner"`
+	// USD per day and per month (0 = uncapped)
+	DailyUSD   float64 `yaml:"daily_usd"`
+	MonthlyUSD float64 `yaml:"monthly_usd"`
+	// Fractions of a budget whose crossing is posted to the webhook
+	// (default 0.8); exhausting it always is
+	WarnAt []float64 `yaml:"warn_at"`
+	// Receives a JSON POST for each crossed threshold
+	WebhookURL string `yaml:"webhook_url"`
+}
+
+// ModelRateLimit is the rate limit of a model; Model may end in * to match
+// a prefix
+type ModelRateLimit struct {
+	Model     string `yaml:"model"`
+	RateLimit `yaml:",inline"`
+}
+
+// AutoscalingTarget describes how one backend replica should be loaded
+type AutoscalingTarget struct {
+	// In-flight requests a single replica should serve
+	TargetConcurrency int `yaml:"target_concurrency"`
+	// Optional URL that receives each signal as a JSON POST
+	WebhookURL string `yaml:"webhook_url"`
+	// Resize the provider's admission limit to the concurrency the backend
+	// advertises in X-Max-Concurrency; requires admission.providers.<name>
+	HonorAdvertisedConcurrency bool `yaml:"honor_advertised_concurrency"`
+}
+
+// S3Config locates an S3 bucket, or a bucket of a compatible service such
+// as MinIO or R2
+type S3Config struct {
+	Bucket string `yaml:"bucket"`
+	Region string `yaml:"region"`
+	// Service URL, e.g. "http://minio:9000" (default AWS for the region)
+	Endpoint string `yaml:"endpoint"`
+	// Prepended to object keys, e.g. "letllm/"
+	Prefix string `yaml:"prefix"`
+	// Address the bucket in the path rather than the host name, as most
+	// compatible services need
+	PathStyle bool `yaml:"path_style"`
+	// Credentials (default the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+	// AWS_SESSION_TOKEN environment variables)
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
+}
+
+// ModelSpec describes a model, or with a trailing "*" a family of models
+// sharing a prefix, e.g. "qwen2.5-*". Unset fields keep the catalog's value.
+type ModelSpec struct {
+	Model string `yaml:"model"`
+	// Provider the entry applies to, e.g. a local server with a smaller
+	// context than the model was trained for; empty applies to all
+	Provider string `yaml:"provider"`
+	// Tokens of prompt and completion together
+	ContextLength   int `yaml:"context_length"`
+	MaxOutputTokens int `yaml:"max_output_tokens"`
+	// Function calling; false turns it off for a provider that otherwise has it
+	Functions *bool `yaml:"functions"`
+	// e.g. ["text", "image"]
+	InputModalities []string `yaml:"input_modalities"`
+	// Model requests too long for this one are sent to instead, e.g.
+	// gpt-4-32k for gpt-4; requests nothing fits are refused
+	LongContextModel string `yaml:"long_context_model"`
+}
+
+// ModelPrice is the USD price of a model per million tokens
+type ModelPrice struct {
+	InputPerMTok  float64 `yaml:"input_per_mtok"`
+	OutputPerMTok float64 `yaml:"output_per_mtok"`
+	// Prompt tokens served from the provider's prompt cache and audio input
+	// (default input_per_mtok)
+	CachedInputPerMTok float64 `yaml:"cached_input_per_mtok"`
+	AudioInputPerMTok  float64 `yaml:"audio_input_per_mtok"`
+	// Reasoning tokens (default output_per_mtok)
+	ReasoningPerMTok float64 `yaml:"reasoning_per_mtok"`
+}
+
+// ToolConfig declares a server-side tool backed by an HTTP endpoint
+type ToolConfig struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// JSON schema of the arguments
+	Parameters map[string]interface{} `yaml:"parameters"`
+	// Endpoint receiving the call arguments as a JSON POST body; the
+	// response body is returned to the model
+	URL string `yaml:"url"`
+	// Longest time a call may take (default 30s)
+	Timeout time.Duration `yaml:"timeout"`
+	// Cache results for calls with identical arguments for this long; only
+	// set for deterministic tools (0 = never cache)
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+	// Cost charged per executed call against tool_loop.max_tool_cost_usd;
+	// cached results are free
+	CostUSD float64 `yaml:"cost_usd"`
+}
+
+// Experiment splits the callers of a model between variants. A caller is
+// bucketed by the request's user field, else its key, so it always gets
+// the same variant; anonymous requests are not enrolled.
+type Experiment struct {
+	Name string `yaml:"name"`
+	// Requested model, after aliases, whose chat completions are split
+	Model    string              `yaml:"model"`
+	Variants []ExperimentVariant `yaml:"variants"`
+}
+
+// ExperimentVariant is one arm of an experiment. Parameters it sets
+// override the client's.
+type ExperimentVariant struct {
+	Name string `yaml:"name"`
+	// Share of callers relative to the other variants (default 1)
+	Weight int `yaml:"weight"`
+	// Model asked for (default the requested model)
+	Model string `yaml:"model"`
+	// Provider serving the variant, bypassing routes (default routed)
+	Provider    string   `yaml:"provider"`
+	Temperature *float64 `yaml:"temperature"`
+	TopP        *float64 `yaml:"top_p"`
+	MaxTokens   *int     `yaml:"max_tokens"`
+}
+
+// PromptTemplate is a named list of messages whose content are Go
+// templates over the caller's variables, e.g. "You help {{.customer}}."
+type PromptTemplate struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Model of requests that name none
+	Model     string             `yaml:"model"`
+	Variables []TemplateVariable `yaml:"variables"`
+	Messages  []TemplateMessage  `yaml:"messages"`
+}
+
+// TemplateVariable declares a variable of a prompt template; callers may
+// only pass declared variables
+type TemplateVariable struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Value used when the caller passes none; a variable without a default
+	// is required
+	Default *string `yaml:"default"`
+}
+
+// TemplateMessage is one message of a prompt template
+type TemplateMessage struct {
+	Role    string `yaml:"role"`
+	Content string `yaml:"content"`
+}
+
+// KeyConfig declares a virtual API key. The secret is hashed at startup.
+type KeyConfig struct {
+	ID                string  `yaml:"id"`
+	Name              string  `yaml:"name"`
+	Key               string  `yaml:"key"`
+	DailyBudgetUSD    float64 `yaml:"daily_budget_usd"`
+	MonthlyBudgetUSD  float64 `yaml:"monthly_budget_usd"`
+	RequestsPerMinute int     `yaml:"requests_per_minute"`
+	TokensPerMinute   int     `yaml:"tokens_per_minute"`
+	// Model names the key's callers use, mapped to the models they stand
+	// for; resolved before routing so applications never change model names
+	ModelAliases map[string]string `yaml:"model_aliases"`
+	// Priority class of the key's requests when concurrency limits are
+	// reached: "high", "normal" (default) or "low"
+	Priority string `yaml:"priority"`
+	// Who the key was issued to, and free-form tags, e.g. for cost reports
+	Owner string   `yaml:"owner"`
+	Tags  []string `yaml:"tags"`
+	// When the key stops being accepted, e.g. 2026-01-01T00:00:00Z
+	ExpiresAt *time.Time `yaml:"expires_at"`
+}
+
+// Priority classes of virtual keys. When a concurrency limit is reached,
+// high priority requests are queued ahead of normal ones and low priority
+// requests are refused instead of queued.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// ValidPriority reports whether p is a priority class; empty means normal
+func ValidPriority(p string) bool {
+	switch p {
+	case "", PriorityHigh, PriorityNormal, PriorityLow:
+		return true
+	}
+	return false
 }
 
 // Load loads configuration from the provided file path.
@@ -58,8 +1071,549 @@ func Load(path string) (*Config, error) {
 	if v := os.Getenv("GEMINI_API_KEY"); v != "" {
 		cfg.Gemini.APIKey = v
 	}
+	if v := os.Getenv("MISTRAL_API_KEY"); v != "" {
+		cfg.Mistral.APIKey = v
+	}
+	if v := os.Getenv("COHERE_API_KEY"); v != "" {
+		cfg.Cohere.APIKey = v
+	}
+	if v := os.Getenv("DEEPSEEK_API_KEY"); v != "" {
+		cfg.DeepSeek.APIKey = v
+	}
+	if v := os.Getenv("OPENROUTER_API_KEY"); v != "" {
+		cfg.OpenRouter.APIKey = v
+	}
+	if v := os.Getenv("PERPLEXITY_API_KEY"); v != "" {
+		cfg.Perplexity.APIKey = v
+	}
+	if v := os.Getenv("ZHIPU_API_KEY"); v != "" {
+		cfg.Zhipu.APIKey = v
+	}
+	if v := os.Getenv("LETLLM_ADMIN_TOKEN"); v != "" {
+		cfg.Admin.Token = v
+	}
 	if cfg.Server.Addr == "" {
 		cfg.Server.Addr = ":8080"
 	}
+	if lc := &cfg.LlamaCpp; lc.ModelPath != "" {
+		if lc.ContextSize <= 0 {
+			lc.ContextSize = 4096
+		}
+		if lc.Threads <= 0 {
+			lc.Threads = runtime.NumCPU()
+		}
+		switch lc.ChatTemplate {
+		case "":
+			lc.ChatTemplate = "chatml"
+		case "chatml", "llama3":
+		default:
+			return nil, fmt.Errorf("llamacpp.chat_template must be %q or %q", "chatml", "llama3")
+		}
+	}
+	switch cfg.IDs.Format {
+	case "":
+		cfg.IDs.Format = "ulid"
+	case "ulid", "uuid":
+	default:
+		return nil, fmt.Errorf("ids.format must be %q or %q", "ulid", "uuid")
+	}
+	st := &cfg.Server.Streaming
+	if st.ReadBufferSize < 0 || st.ChannelBuffer < 0 || st.CoalesceBytes < 0 || st.FlushBytes < 0 || st.FlushInterval < 0 ||
+		st.WriteTimeout < 0 || st.SlowClientTimeout < 0 {
+		return nil, fmt.Errorf("server.streaming: sizes and intervals must not be negative")
+	}
+	if st.ReadBufferSize == 0 {
+		st.ReadBufferSize = 4096
+	}
+	if st.ChannelBuffer == 0 {
+		st.ChannelBuffer = 8
+	}
+	if st.CoalesceBytes == 0 {
+		st.CoalesceBytes = 16384
+	}
+	if st.FlushBytes == 0 {
+		st.FlushBytes = 32768
+	}
+	if st.FlushInterval == 0 {
+		st.FlushInterval = 50 * time.Millisecond
+	}
+	if st.WriteTimeout == 0 {
+		st.WriteTimeout = 10 * time.Second
+	}
+	if st.SlowClientTimeout == 0 {
+		st.SlowClientTimeout = 30 * time.Second
+	}
+	for i := range cfg.Server.Listeners {
+		l := &cfg.Server.Listeners[i]
+		if l.Addr == "" {
+			return nil, fmt.Errorf("server.listeners[%d]: addr is required", i)
+		}
+		switch l.Network {
+		case "":
+			l.Network = NetworkTCP
+		case NetworkTCP, NetworkTCP4, NetworkTCP6:
+		default:
+			return nil, fmt.Errorf("server.listeners[%d]: unknown network %q", i, l.Network)
+		}
+		if (l.TLSCertFile == "") != (l.TLSKeyFile == "") {
+			return nil, fmt.Errorf("server.listeners[%d]: tls_cert_file and tls_key_file must be set together", i)
+		}
+		if l.ClientCAFile != "" && l.TLSCertFile == "" {
+			return nil, fmt.Errorf("server.listeners[%d]: client_ca_file requires tls_cert_file", i)
+		}
+		if len(l.ClientKeys) > 0 && l.ClientCAFile == "" {
+			return nil, fmt.Errorf("server.listeners[%d]: client_keys requires client_ca_file", i)
+		}
+	}
+	if cfg.Pacing.Threshold < 0 || cfg.Pacing.Threshold > 1 {
+		return nil, fmt.Errorf("pacing threshold must be between 0 and 1")
+	}
+	switch cfg.Usage.ExportMode {
+	case "":
+		cfg.Usage.ExportMode = UsageExportRaw
+	case UsageExportRaw, UsageExportAggregated:
+	default:
+		return nil, fmt.Errorf("usage.export_mode must be %q or %q", UsageExportRaw, UsageExportAggregated)
+	}
+	if cfg.Usage.MinGroupSize <= 0 {
+		cfg.Usage.MinGroupSize = 10
+	}
+	switch cfg.Usage.Backend {
+	case "":
+		cfg.Usage.Backend = UsageBackendStorage
+	case UsageBackendStorage:
+	case UsageBackendClickHouse:
+		if cfg.Usage.ClickHouse.URL == "" {
+			return nil, fmt.Errorf("usage.clickhouse.url is required for the clickhouse backend")
+		}
+		if cfg.Usage.ClickHouse.Table == "" {
+			cfg.Usage.ClickHouse.Table = "usage_records"
+		}
+		if !identifierPattern.MatchString(cfg.Usage.ClickHouse.Table) || (cfg.Usage.ClickHouse.Database != "" && !identifierPattern.MatchString(cfg.Usage.ClickHouse.Database)) {
+			return nil, fmt.Errorf("usage.clickhouse: database and table must be plain identifiers")
+		}
+	default:
+		return nil, fmt.Errorf("usage.backend must be %q or %q", UsageBackendStorage, UsageBackendClickHouse)
+	}
+	if cfg.Usage.BatchSize <= 0 {
+		cfg.Usage.BatchSize = 100
+	}
+	if cfg.Usage.FlushInterval <= 0 {
+		cfg.Usage.FlushInterval = time.Second
+	}
+	if cfg.Usage.QueueSize <= 0 {
+		cfg.Usage.QueueSize = 10000
+	}
+	if cfg.Tracing.SampleRate < 0 || cfg.Tracing.SampleRate > 1 {
+		return nil, fmt.Errorf("tracing.sample_rate must be between 0 and 1")
+	}
+	if cfg.Tracing.MaxEvents <= 0 {
+		cfg.Tracing.MaxEvents = 10000
+	}
+	if cfg.Tracing.Keep <= 0 {
+		cfg.Tracing.Keep = 1000
+	}
+	if cfg.PIIReports.Interval < 0 {
+		return nil, fmt.Errorf("pii_reports.interval must not be negative")
+	}
+	if cfg.PIIReports.SampleSize <= 0 {
+		cfg.PIIReports.SampleSize = 200
+	}
+	if cfg.PIIReports.Keep <= 0 {
+		cfg.PIIReports.Keep = 48
+	}
+	switch cfg.Files.Storage {
+	case "":
+		cfg.Files.Storage = "database"
+		if cfg.Storage.Driver == "memory" {
+			cfg.Files.Storage = "memory"
+		}
+	case "database", "memory", "disk":
+	case "s3":
+		s3 := &cfg.Files.S3
+		if s3.Bucket == "" || s3.Region == "" {
+			return nil, fmt.Errorf("files.s3: bucket and region are required")
+		}
+		if s3.AccessKeyID == "" {
+			s3.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+			s3.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+			s3.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+	default:
+		return nil, fmt.Errorf("files.storage must be one of database, memory, disk or s3")
+	}
+	if cfg.Files.Dir == "" {
+		cfg.Files.Dir = "files"
+	}
+	if cfg.Files.MaxBytes <= 0 {
+		cfg.Files.MaxBytes = 512 << 20
+	}
+	if cfg.Batches.Concurrency <= 0 {
+		cfg.Batches.Concurrency = 4
+	}
+	if cfg.Batches.MaxRequests <= 0 {
+		cfg.Batches.MaxRequests = 50000
+	}
+	if cfg.Batches.MaxFileBytes <= 0 {
+		cfg.Batches.MaxFileBytes = 100 << 20
+	}
+	if cfg.Batches.PollInterval <= 0 {
+		cfg.Batches.PollInterval = 5 * time.Second
+	}
+	if cfg.Admin.RecentRequests <= 0 {
+		cfg.Admin.RecentRequests = 500
+	}
+	for name, lim := range cfg.Admission.Providers {
+		if lim.MaxConcurrent <= 0 {
+			return nil, fmt.Errorf("admission.providers.%s: max_concurrent must be positive", name)
+		}
+	}
+	for i, ms := range cfg.Models {
+		if ms.Model == "" || ms.ContextLength < 0 || ms.MaxOutputTokens < 0 {
+			return nil, fmt.Errorf("models[%d]: model is required and limits must not be negative", i)
+		}
+	}
+	for i, mc := range cfg.Admission.Models {
+		if mc.Model == "" || mc.MaxConcurrent <= 0 {
+			return nil, fmt.Errorf("admission.models[%d]: model and a positive max_concurrent are required", i)
+		}
+	}
+	for i := range cfg.Budgets {
+		b := &cfg.Budgets[i]
+		if (b.Key == "") == (b.Owner == "") {
+			return nil, fmt.Errorf("budgets[%d]: exactly one of key and owner is required", i)
+		}
+		if b.DailyUSD < 0 || b.MonthlyUSD < 0 || b.DailyUSD == 0 && b.MonthlyUSD == 0 {
+			return nil, fmt.Errorf("budgets[%d]: daily_usd or monthly_usd is required and must not be negative", i)
+		}
+		if len(b.WarnAt) == 0 {
+			b.WarnAt = []float64{0.8}
+		}
+		for _, f := range b.WarnAt {
+			if f <= 0 || f >= 1 {
+				return nil, fmt.Errorf("budgets[%d]: warn_at fractions must be between 0 and 1", i)
+			}
+		}
+	}
+	if cfg.Redis.KeyPrefix == "" {
+		cfg.Redis.KeyPrefix = "letllm:"
+	}
+	switch cfg.Cache.Backend {
+	case "":
+		cfg.Cache.Backend = CacheBackendMemory
+	case CacheBackendMemory:
+	case CacheBackendRedis:
+		if cfg.Redis.Addr == "" {
+			return nil, fmt.Errorf("cache: the redis backend requires redis.addr")
+		}
+	case CacheBackendDisk:
+		if cfg.Cache.Dir == "" {
+			return nil, fmt.Errorf("cache.dir is required for the disk backend")
+		}
+	default:
+		return nil, fmt.Errorf("cache.backend must be %q, %q or %q", CacheBackendMemory, CacheBackendRedis, CacheBackendDisk)
+	}
+	if cfg.Cache.MaxEntries <= 0 {
+		cfg.Cache.MaxEntries = 10000
+	}
+	if cfg.Cache.MaxBytes <= 0 {
+		cfg.Cache.MaxBytes = 256 << 20
+	}
+	if cfg.Idempotency.TTL <= 0 {
+		cfg.Idempotency.TTL = 24 * time.Hour
+	}
+	if cfg.Idempotency.MaxResponseBytes <= 0 {
+		cfg.Idempotency.MaxResponseBytes = 1 << 20
+	}
+	if rl := cfg.RequestLimits; rl.MaxBodyBytes < 0 || rl.MaxMessages < 0 || rl.MaxPromptChars < 0 || rl.MaxPromptTokens < 0 {
+		return nil, fmt.Errorf("request_limits: limits must not be negative")
+	}
+	if rl := cfg.Admission.RateLimit; rl.RequestsPerMinute < 0 || rl.TokensPerMinute < 0 {
+		return nil, fmt.Errorf("admission.rate_limit: limits must not be negative")
+	}
+	for i, mr := range cfg.Admission.ModelRateLimits {
+		if mr.Model == "" || mr.RequestsPerMinute < 0 || mr.TokensPerMinute < 0 {
+			return nil, fmt.Errorf("admission.model_rate_limits[%d]: model is required and limits must not be negative", i)
+		}
+	}
+	if cfg.Enrichment.URL != "" {
+		if len(cfg.Enrichment.ForwardHeaders) == 0 {
+			cfg.Enrichment.ForwardHeaders = []string{"Authorization"}
+		}
+		if cfg.Enrichment.Timeout <= 0 {
+			cfg.Enrichment.Timeout = 2 * time.Second
+		}
+		if cfg.Enrichment.CacheTTL <= 0 {
+			cfg.Enrichment.CacheTTL = 5 * time.Minute
+		}
+	}
+	toolNames := make(map[string]bool)
+	for i, tc := range cfg.Tools {
+		if tc.Name == "" || tc.URL == "" {
+			return nil, fmt.Errorf("tools[%d]: name and url are required", i)
+		}
+		if toolNames[tc.Name] {
+			return nil, fmt.Errorf("tools[%d]: duplicate tool name %q", i, tc.Name)
+		}
+		toolNames[tc.Name] = true
+	}
+	templateNames := make(map[string]bool)
+	for i, pt := range cfg.PromptTemplates {
+		if pt.Name == "" || len(pt.Messages) == 0 {
+			return nil, fmt.Errorf("prompt_templates[%d]: name and messages are required", i)
+		}
+		if templateNames[pt.Name] {
+			return nil, fmt.Errorf("prompt_templates[%d]: duplicate template name %q", i, pt.Name)
+		}
+		templateNames[pt.Name] = true
+		for j, tv := range pt.Variables {
+			if tv.Name == "" {
+				return nil, fmt.Errorf("prompt_templates %s: variables[%d]: name is required", pt.Name, j)
+			}
+		}
+	}
+	experimentModels := make(map[string]bool)
+	for i := range cfg.Experiments {
+		ex := &cfg.Experiments[i]
+		if ex.Name == "" || ex.Model == "" || len(ex.Variants) == 0 {
+			return nil, fmt.Errorf("experiments[%d]: name, model and variants are required", i)
+		}
+		if experimentModels[ex.Model] {
+			return nil, fmt.Errorf("experiments[%d]: model %q is already in an experiment", i, ex.Model)
+		}
+		experimentModels[ex.Model] = true
+		variantNames := make(map[string]bool)
+		for j := range ex.Variants {
+			v := &ex.Variants[j]
+			if v.Name == "" || variantNames[v.Name] {
+				return nil, fmt.Errorf("experiment %s: variants[%d]: a unique name is required", ex.Name, j)
+			}
+			variantNames[v.Name] = true
+			if v.Weight < 0 {
+				return nil, fmt.Errorf("experiment %s: variant %s: weight must not be negative", ex.Name, v.Name)
+			}
+			if v.Weight == 0 {
+				v.Weight = 1
+			}
+		}
+	}
+	if cfg.LoadShedding.MaxGoroutines < 0 {
+		return nil, fmt.Errorf("load_shedding.max_goroutines must not be negative")
+	}
+	if cfg.LoadShedding.SampleInterval <= 0 {
+		cfg.LoadShedding.SampleInterval = time.Second
+	}
+	if cfg.LoadShedding.RetryAfter <= 0 {
+		cfg.LoadShedding.RetryAfter = 5 * time.Second
+	}
+	if cfg.Autoscaling.Interval <= 0 {
+		cfg.Autoscaling.Interval = 15 * time.Second
+	}
+	for name, t := range cfg.Autoscaling.Providers {
+		if t.TargetConcurrency <= 0 {
+			return nil, fmt.Errorf("autoscaling.providers.%s: target_concurrency must be positive", name)
+		}
+		if _, ok := cfg.Admission.Providers[name]; t.HonorAdvertisedConcurrency && !ok {
+			return nil, fmt.Errorf("autoscaling.providers.%s: honor_advertised_concurrency requires admission.providers.%s", name, name)
+		}
+	}
+	names := make(map[string]bool)
+	for _, name := range builtinProviders {
+		names[name] = true
+	}
+	for i, oc := range cfg.OpenAICompatible {
+		if oc.Name == "" || oc.BaseURL == "" {
+			return nil, fmt.Errorf("openai_compatible[%d]: name and base_url are required", i)
+		}
+		if names[oc.Name] {
+			return nil, fmt.Errorf("openai_compatible[%d]: provider name %q is already in use", i, oc.Name)
+		}
+		names[oc.Name] = true
+	}
+	for i, rc := range cfg.RemoteLetLLM {
+		if rc.Name == "" || rc.BaseURL == "" {
+			return nil, fmt.Errorf("remote_letllm[%d]: name and base_url are required", i)
+		}
+		if rc.APIKey == "" && !rc.ForwardAuth {
+			return nil, fmt.Errorf("remote_letllm[%d]: api_key is required unless forward_auth is set", i)
+		}
+		if names[rc.Name] {
+			return nil, fmt.Errorf("remote_letllm[%d]: provider name %q is already in use", i, rc.Name)
+		}
+		names[rc.Name] = true
+	}
+	for i, mc := range cfg.Mock {
+		if mc.Name == "" {
+			return nil, fmt.Errorf("mock[%d]: name is required", i)
+		}
+		if names[mc.Name] {
+			return nil, fmt.Errorf("mock[%d]: provider name %q is already in use", i, mc.Name)
+		}
+		names[mc.Name] = true
+		for j, sc := range mc.Scenarios {
+			if len(sc.Turns) == 0 {
+				return nil, fmt.Errorf("mock %s: scenarios[%d]: turns are required", mc.Name, j)
+			}
+			for k, turn := range sc.Turns {
+				if err := turn.Validate(); err != nil {
+					return nil, fmt.Errorf("mock %s: scenarios[%d].turns[%d]: %w", mc.Name, j, k, err)
+				}
+			}
+		}
+		for j, resp := range mc.Responses {
+			if err := resp.Validate(); err != nil {
+				return nil, fmt.Errorf("mock %s: responses[%d]: %w", mc.Name, j, err)
+			}
+		}
+	}
+	switch cfg.VCR.Mode {
+	case "":
+	case VCRRecord, VCRReplay:
+		if cfg.VCR.Dir == "" {
+			return nil, fmt.Errorf("vcr: dir is required")
+		}
+	default:
+		return nil, fmt.Errorf("vcr: unknown mode %q", cfg.VCR.Mode)
+	}
+	// Gemini, llama.cpp and mocks send no requests through a transport
+	// the gateway sets up
+	untransported := map[string]bool{"gemini": true, "llamacpp": true}
+	for _, mc := range cfg.Mock {
+		untransported[mc.Name] = true
+	}
+	for name, pt := range cfg.ProviderTLS {
+		if !names[name] {
+			return nil, fmt.Errorf("provider_tls: unknown provider %q", name)
+		}
+		if untransported[name] {
+			return nil, fmt.Errorf("provider_tls: %s does not support custom TLS settings", name)
+		}
+		if pt.ExcludeSystemRoots && pt.CAFile == "" {
+			return nil, fmt.Errorf("provider_tls %s: exclude_system_roots requires ca_file", name)
+		}
+	}
+	for name, kp := range cfg.ProviderKeys {
+		if !names[name] {
+			return nil, fmt.Errorf("provider_keys: unknown provider %q", name)
+		}
+		if len(kp.Keys) == 0 {
+			return nil, fmt.Errorf("provider_keys %s: keys are required", name)
+		}
+		switch kp.Strategy {
+		case "":
+			kp.Strategy = KeyPoolRoundRobin
+		case KeyPoolRoundRobin, KeyPoolRemaining:
+		default:
+			return nil, fmt.Errorf("provider_keys %s: unknown strategy %q", name, kp.Strategy)
+		}
+		if kp.Cooldown <= 0 {
+			kp.Cooldown = time.Minute
+		}
+		cfg.ProviderKeys[name] = kp
+	}
+	for name, pt := range cfg.ProviderTimeouts {
+		if !names[name] {
+			return nil, fmt.Errorf("provider_timeouts: unknown provider %q", name)
+		}
+		if pt.Connect < 0 || pt.FirstByte < 0 || pt.Total < 0 {
+			return nil, fmt.Errorf("provider_timeouts %s: timeouts must not be negative", name)
+		}
+	}
+	for name, rp := range cfg.ProviderRetries {
+		if !names[name] {
+			return nil, fmt.Errorf("provider_retries: unknown provider %q", name)
+		}
+		if rp.MaxAttempts <= 0 {
+			rp.MaxAttempts = 3
+		}
+		if rp.InitialBackoff <= 0 {
+			rp.InitialBackoff = 500 * time.Millisecond
+		}
+		if rp.Multiplier == 0 {
+			rp.Multiplier = 2
+		}
+		if rp.MaxBackoff <= 0 {
+			rp.MaxBackoff = 10 * time.Second
+		}
+		if rp.Jitter == nil {
+			jitter := DefaultRetryJitter
+			rp.Jitter = &jitter
+		}
+		if len(rp.RetryOn) == 0 {
+			rp.RetryOn = DefaultRetryOn
+		}
+		if rp.Multiplier < 1 || *rp.Jitter < 0 || *rp.Jitter > 1 {
+			return nil, fmt.Errorf("provider_retries %s: multiplier must be at least 1 and jitter between 0 and 1", name)
+		}
+		for _, status := range rp.RetryOn {
+			if status < 400 || status > 599 {
+				return nil, fmt.Errorf("provider_retries %s: retry_on must list 4xx and 5xx statuses", name)
+			}
+		}
+		cfg.ProviderRetries[name] = rp
+	}
+	inPair := make(map[string]bool)
+	for i, fp := range cfg.Failover {
+		if fp.Active == "" || fp.Standby == "" || fp.Active == fp.Standby {
+			return nil, fmt.Errorf("failover[%d]: distinct active and standby providers are required", i)
+		}
+		if inPair[fp.Active] || inPair[fp.Standby] {
+			return nil, fmt.Errorf("failover[%d]: a provider may belong to only one failover pair", i)
+		}
+		inPair[fp.Active], inPair[fp.Standby] = true, true
+	}
+	for alias, target := range cfg.ModelAliases {
+		if alias == "" || target == "" {
+			return nil, fmt.Errorf("model_aliases %q: alias and target are required", alias)
+		}
+		if alias == target {
+			return nil, fmt.Errorf("model_aliases %q: refers to itself", alias)
+		}
+	}
+	switch cfg.RoutingStrategy {
+	case "":
+		cfg.RoutingStrategy = RoutingFirst
+	case RoutingFirst, RoutingCheapest, RoutingSticky:
+	default:
+		return nil, fmt.Errorf("routing_strategy must be %q, %q or %q", RoutingFirst, RoutingCheapest, RoutingSticky)
+	}
+	for i, rt := range cfg.Routes {
+		if err := rt.validateMatch(); err != nil {
+			return nil, fmt.Errorf("routes[%d]: %w", i, err)
+		}
+		if rc := rt.Canary; rc != nil && (rc.Provider == "" || rc.Percent < 0 || rc.Percent > 100) {
+			return nil, fmt.Errorf("routes[%d] canary: provider and a percent between 0 and 100 are required", i)
+		}
+		if rm := rt.Mirror; rm != nil {
+			if rm.Provider == "" || rm.Percent < 0 || rm.Percent > 100 {
+				return nil, fmt.Errorf("routes[%d] mirror: provider and a percent between 0 and 100 are required", i)
+			}
+			if rm.Percent == 0 {
+				rm.Percent = 100
+			}
+		}
+		if rh := rt.Hedge; rh != nil && (rh.Provider == "" || rh.After <= 0) {
+			return nil, fmt.Errorf("routes[%d] hedge: provider and a positive after are required", i)
+		}
+		if rf := rt.ContentFilterFallback; rf != nil && rf.Provider == "" {
+			return nil, fmt.Errorf("routes[%d] content_filter_fallback: provider is required", i)
+		}
+		if rt.Schedule != nil {
+			if err := rt.Schedule.Validate(); err != nil {
+				return nil, fmt.Errorf("routes[%d] schedule: %w", i, err)
+			}
+		}
+		if rc := rt.Cache; rc != nil {
+			if rc.TTL < 0 || rc.MaxTTL < 0 {
+				return nil, fmt.Errorf("routes[%d] cache: ttl and max_ttl must not be negative", i)
+			}
+			if rc.MaxTTL == 0 {
+				rc.MaxTTL = max(time.Hour, rc.TTL)
+			}
+			if rc.TTL > rc.MaxTTL {
+				return nil, fmt.Errorf("routes[%d] cache: ttl exceeds max_ttl", i)
+			}
+		}
+	}
 	return &cfg, nil
 }
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadProviderTLS(t *testing.T) {
+	for name, tt := range map[string]struct {
+		provider string
+		wantErr  string
+	}{
+		"http provider": {provider: "openai"},
+		"gemini":        {provider: "gemini", wantErr: "does not support custom TLS settings"},
+		"mock":          {provider: "demo", wantErr: "does not support custom TLS settings"},
+	} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		yaml := "mock:\n  - name: demo\nprovider_tls:\n  " + tt.provider + ":\n    ca_file: /etc/ssl/ca.pem\n"
+		if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(path)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: expected %q, got %v", name, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// liteLLMConfig is the subset of a LiteLLM proxy config.yaml that can be
+// translated into letllm-go configuration
+type liteLLMConfig struct {
+	ModelList []struct {
+		ModelName     string `yaml:"model_name"`
+		LiteLLMParams struct {
+			Model   string `yaml:"model"`
+			APIKey  string `yaml:"api_key"`
+			APIBase string `yaml:"api_base"`
+		} `yaml:"litellm_params"`
+	} `yaml:"model_list"`
+
+	RouterSettings map[string]interface{} `yaml:"router_settings"`
+
+	GeneralSettings struct {
+		MasterKey string `yaml:"master_key"`
+	} `yaml:"general_settings"`
+}
+
+// LiteLLMImport is the result of converting a LiteLLM config
+type LiteLLMImport struct {
+	Config *Config
+	// Notes lists settings that could not be translated exactly
+	Notes []string
+}
+
+// ConvertLiteLLM translates a LiteLLM proxy config into letllm-go config
+func ConvertLiteLLM(data []byte) (*LiteLLMImport, error) {
+	var in liteLLMConfig
+	if err := yaml.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("parse litellm yaml: %w", err)
+	}
+	if len(in.ModelList) == 0 {
+		return nil, fmt.Errorf("litellm config has no model_list entries")
+	}
+
+	out := &LiteLLMImport{Config: &Config{}}
+	cfg := out.Config
+	notef := func(format string, args ...interface{}) {
+		out.Notes = append(out.Notes, fmt.Sprintf(format, args...))
+	}
+
+	seen := make(map[string]bool)
+	for i, m := range in.ModelList {
+		params := m.LiteLLMParams
+		providerName, upstreamModel := splitLiteLLMModel(params.Model)
+
+		if name, ok := liteLLMCompatibleNames[providerName]; ok {
+			if seen[upstreamModel] {
+				notef("model_list[%d] %q: additional deployments of the same model are not load balanced, skipped", i, m.ModelName)
+				continue
+			}
+			if params.APIBase == "" {
+				notef("model_list[%d] %q: %s requires api_base, skipped", i, m.ModelName, providerName)
+				continue
+			}
+			seen[upstreamModel] = true
+			key, _ := resolveLiteLLMSecret(params.APIKey)
+			inst := compatibleInstance(cfg, name, params.APIBase, key)
+			inst.Models = append(inst.Models, upstreamModel)
+			cfg.Routes = append(cfg.Routes, Route{Prefix: upstreamModel, Provider: inst.Name})
+			continue
+		}
+
+		if providerName == "openrouter" {
+			// OpenRouter model IDs contain a slash themselves; routes keep
+			// the prefix so they reach the openrouter provider
+			upstreamModel = params.Model
+			if seen[upstreamModel] {
+				notef("model_list[%d] %q: additional deployments of the same model are not load balanced, skipped", i, m.ModelName)
+				continue
+			}
+			seen[upstreamModel] = true
+			if cfg.OpenRouter.DefaultModel == "" {
+				cfg.OpenRouter.DefaultModel = upstreamModel
+			}
+			if key, ok := resolveLiteLLMSecret(params.APIKey); ok {
+				cfg.OpenRouter.APIKey = key
+			}
+			cfg.Routes = append(cfg.Routes, Route{Prefix: upstreamModel, Provider: providerName})
+			continue
+		}
+
+		var block *struct {
+			APIKey       string `yaml:"api_key"`
+			BaseURL      string `yaml:"base_url"`
+			DefaultModel string `yaml:"default_model"`
+		}
+		switch providerName {
+		case "openai":
+			block = &cfg.OpenAI
+		case "gemini":
+			block = &cfg.Gemini
+		case "mistral":
+			block = &cfg.Mistral
+		case "cohere", "cohere_chat":
+			providerName = "cohere"
+			block = &cfg.Cohere
+		case "deepseek":
+			block = &cfg.DeepSeek
+		case "perplexity":
+			block = &cfg.Perplexity
+		default:
+			notef("model_list[%d] %q: provider %q is not supported, skipped", i, m.ModelName, providerName)
+			continue
+		}
+
+		if seen[upstreamModel] {
+			notef("model_list[%d] %q: additional deployments of the same model are not load balanced, skipped", i, m.ModelName)
+			continue
+		}
+		seen[upstreamModel] = true
+
+		if upstreamModel != m.ModelName {
+			notef("model_list[%d] %q: upstream model %q differs from model_name; clients must request %q", i, m.ModelName, upstreamModel, upstreamModel)
+		}
+
+		if block.DefaultModel == "" {
+			block.DefaultModel = upstreamModel
+		}
+		if params.APIBase != "" {
+			if block.BaseURL != "" && block.BaseURL != params.APIBase {
+				notef("model_list[%d] %q: only one %s api_base is supported, keeping %s", i, m.ModelName, providerName, block.BaseURL)
+			} else {
+				block.BaseURL = params.APIBase
+			}
+		}
+		if key, ok := resolveLiteLLMSecret(params.APIKey); ok {
+			if block.APIKey != "" && block.APIKey != key {
+				notef("model_list[%d] %q: only one %s api_key is supported", i, m.ModelName, providerName)
+			} else {
+				block.APIKey = key
+			}
+		} else if params.APIKey != "" {
+			envVar := strings.TrimPrefix(params.APIKey, "os.environ/")
+			if want := strings.ToUpper(providerName) + "_API_KEY"; envVar != want {
+				notef("model_list[%d] %q: api_key is read from $%s; set %s instead", i, m.ModelName, envVar, want)
+			}
+		}
+
+		cfg.Routes = append(cfg.Routes, Route{Prefix: upstreamModel, Provider: providerName})
+	}
+
+	if len(in.RouterSettings) > 0 {
+		notef("router_settings are not translated; routes use first-match prefix routing")
+	}
+	if mk := in.GeneralSettings.MasterKey; mk != "" {
+		if key, ok := resolveLiteLLMSecret(mk); ok {
+			cfg.Admin.Token = key
+		} else {
+			notef("general_settings.master_key is read from the environment; set LETLLM_ADMIN_TOKEN instead")
+		}
+	}
+
+	return out, nil
+}
+
+// YAML renders the imported configuration, omitting unset sections
+func (li *LiteLLMImport) YAML() ([]byte, error) {
+	type providerBlock struct {
+		APIKey       string `yaml:"api_key,omitempty"`
+		BaseURL      string `yaml:"base_url,omitempty"`
+		DefaultModel string `yaml:"default_model,omitempty"`
+	}
+	type adminBlock struct {
+		Token string `yaml:"token"`
+	}
+	doc := struct {
+		Admin    *adminBlock    `yaml:"admin,omitempty"`
+		Routes   []Route        `yaml:"routes"`
+		OpenAI   *providerBlock `yaml:"openai,omitempty"`
+		Gemini   *providerBlock `yaml:"gemini,omitempty"`
+		Mistral  *providerBlock `yaml:"mistral,omitempty"`
+		Cohere   *providerBlock `yaml:"cohere,omitempty"`
+		DeepSeek *providerBlock `yaml:"deepseek,omitempty"`
+
+		OpenRouter *providerBlock `yaml:"openrouter,omitempty"`
+		Perplexity *providerBlock `yaml:"perplexity,omitempty"`
+
+		OpenAICompatible []OpenAICompatibleConfig `yaml:"openai_compatible,omitempty"`
+	}{Routes: li.Config.Routes, OpenAICompatible: li.Config.OpenAICompatible}
+
+	if li.Config.Admin.Token != "" {
+		doc.Admin = &adminBlock{Token: li.Config.Admin.Token}
+	}
+	if o := li.Config.OpenAI; o.DefaultModel != "" {
+		doc.OpenAI = &providerBlock{APIKey: o.APIKey, BaseURL: o.BaseURL, DefaultModel: o.DefaultModel}
+	}
+	if g := li.Config.Gemini; g.DefaultModel != "" {
+		doc.Gemini = &providerBlock{APIKey: g.APIKey, BaseURL: g.BaseURL, DefaultModel: g.DefaultModel}
+	}
+	if m := li.Config.Mistral; m.DefaultModel != "" {
+		doc.Mistral = &providerBlock{APIKey: m.APIKey, BaseURL: m.BaseURL, DefaultModel: m.DefaultModel}
+	}
+	if c := li.Config.Cohere; c.DefaultModel != "" {
+		doc.Cohere = &providerBlock{APIKey: c.APIKey, BaseURL: c.BaseURL, DefaultModel: c.DefaultModel}
+	}
+	if d := li.Config.DeepSeek; d.DefaultModel != "" {
+		doc.DeepSeek = &providerBlock{APIKey: d.APIKey, BaseURL: d.BaseURL, DefaultModel: d.DefaultModel}
+	}
+	if o := li.Config.OpenRouter; o.DefaultModel != "" {
+		doc.OpenRouter = &providerBlock{APIKey: o.APIKey, BaseURL: o.BaseURL, DefaultModel: o.DefaultModel}
+	}
+	if p := li.Config.Perplexity; p.DefaultModel != "" {
+		doc.Perplexity = &providerBlock{APIKey: p.APIKey, BaseURL: p.BaseURL, DefaultModel: p.DefaultModel}
+	}
+	return yaml.Marshal(doc)
+}
+
+// liteLLMCompatibleNames maps LiteLLM providers for self-hosted
+// OpenAI-compatible servers to openai_compatible instance names
+var liteLLMCompatibleNames = map[string]string{
+	"hosted_vllm": "vllm",
+	"lm_studio":   "lm-studio",
+}
+
+// compatibleInstance returns the openai_compatible instance for baseURL,
+// adding one named after name (suffixed when taken) if there is none
+func compatibleInstance(cfg *Config, name, baseURL, apiKey string) *OpenAICompatibleConfig {
+	for i := range cfg.OpenAICompatible {
+		if inst := &cfg.OpenAICompatible[i]; inst.BaseURL == baseURL {
+			return inst
+		}
+	}
+	unique := name
+	for n := 2; ; n++ {
+		if !hasCompatibleInstance(cfg, unique) {
+			break
+		}
+		unique = fmt.Sprintf("%s-%d", name, n)
+	}
+	cfg.OpenAICompatible = append(cfg.OpenAICompatible, OpenAICompatibleConfig{Name: unique, BaseURL: baseURL, APIKey: apiKey})
+	return &cfg.OpenAICompatible[len(cfg.OpenAICompatible)-1]
+}
+
+func hasCompatibleInstance(cfg *Config, name string) bool {
+	for _, inst := range cfg.OpenAICompatible {
+		if inst.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// splitLiteLLMModel splits "openai/gpt-4o" into provider and model. Models
+// without a provider prefix are assumed to be OpenAI, as in LiteLLM.
+func splitLiteLLMModel(model string) (string, string) {
+	if i := strings.Index(model, "/"); i > 0 {
+		return model[:i], model[i+1:]
+	}
+	return "openai", model
+}
+
+// resolveLiteLLMSecret returns literal secrets; "os.environ/NAME" references
+// are left for the gateway's own environment overrides
+func resolveLiteLLMSecret(v string) (string, bool) {
+	if v == "" || strings.HasPrefix(v, "os.environ/") {
+		return "", false
+	}
+	return v, true
+}
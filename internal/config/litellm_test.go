@@ -0,0 +1,169 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestConvertLiteLLM(t *testing.T) {
+	in := `
+model_list:
+  - model_name: gpt-4o
+    litellm_params:
+      model: openai/gpt-4o
+      api_key: os.environ/OPENAI_API_KEY
+      api_base: https://proxy.example.com/v1
+  - model_name: gpt-4o
+    litellm_params:
+      model: openai/gpt-4o
+  - model_name: fast
+    litellm_params:
+      model: gemini/gemini-1.5-flash
+      api_key: test-gemini-key
+  - model_name: claude
+    litellm_params:
+      model: anthropic/claude-3-5-sonnet
+general_settings:
+  master_key: sk-1234
+`
+	imported, err := ConvertLiteLLM([]byte(in))
+	if err != nil {
+		t.Fatalf("ConvertLiteLLM failed: %v", err)
+	}
+	cfg := imported.Config
+
+	if len(cfg.Routes) != 2 {
+		t.Fatalf("Expected 2 routes, got %+v", cfg.Routes)
+	}
+	if cfg.Routes[0].Prefix != "gpt-4o" || cfg.Routes[0].Provider != "openai" {
+		t.Errorf("Unexpected first route: %+v", cfg.Routes[0])
+	}
+	if cfg.Routes[1].Prefix != "gemini-1.5-flash" || cfg.Routes[1].Provider != "gemini" {
+		t.Errorf("Unexpected second route: %+v", cfg.Routes[1])
+	}
+	if cfg.OpenAI.BaseURL != "https://proxy.example.com/v1" || cfg.OpenAI.APIKey != "" {
+		t.Errorf("Unexpected openai block: %+v", cfg.OpenAI)
+	}
+	if cfg.Gemini.APIKey != "test-gemini-key" || cfg.Gemini.DefaultModel != "gemini-1.5-flash" {
+		t.Errorf("Unexpected gemini block: %+v", cfg.Gemini)
+	}
+	if cfg.Admin.Token != "sk-1234" {
+		t.Errorf("Expected master key as admin token, got %q", cfg.Admin.Token)
+	}
+
+	notes := strings.Join(imported.Notes, "\n")
+	for _, want := range []string{"not load balanced", `provider "anthropic" is not supported`, "differs from model_name"} {
+		if !strings.Contains(notes, want) {
+			t.Errorf("Expected a note containing %q, got:\n%s", want, notes)
+		}
+	}
+
+	// The rendered YAML must load back as letllm-go config
+	b, err := imported.YAML()
+	if err != nil {
+		t.Fatalf("YAML failed: %v", err)
+	}
+	var roundTrip Config
+	if err := yaml.Unmarshal(b, &roundTrip); err != nil {
+		t.Fatalf("Rendered YAML does not parse: %v", err)
+	}
+	if len(roundTrip.Routes) != 2 || roundTrip.Gemini.APIKey != "test-gemini-key" {
+		t.Errorf("Round trip lost data: %+v", roundTrip)
+	}
+}
+
+func TestConvertLiteLLMEmpty(t *testing.T) {
+	if _, err := ConvertLiteLLM([]byte("general_settings: {}")); err == nil {
+		t.Error("Expected error for config without model_list")
+	}
+}
+
+func TestConvertLiteLLMProviders(t *testing.T) {
+	in := `
+model_list:
+  - model_name: mistral-large-latest
+    litellm_params:
+      model: mistral/mistral-large-latest
+  - model_name: command-r
+    litellm_params:
+      model: cohere_chat/command-r
+      api_key: test-cohere-key
+  - model_name: deepseek-chat
+    litellm_params:
+      model: deepseek/deepseek-chat
+  - model_name: claude
+    litellm_params:
+      model: openrouter/anthropic/claude-3.5-sonnet
+  - model_name: sonar-pro
+    litellm_params:
+      model: perplexity/sonar-pro
+`
+	imported, err := ConvertLiteLLM([]byte(in))
+	if err != nil {
+		t.Fatalf("ConvertLiteLLM failed: %v", err)
+	}
+	cfg := imported.Config
+
+	want := []Route{
+		{Prefix: "mistral-large-latest", Provider: "mistral"},
+		{Prefix: "command-r", Provider: "cohere"},
+		{Prefix: "deepseek-chat", Provider: "deepseek"},
+		{Prefix: "openrouter/anthropic/claude-3.5-sonnet", Provider: "openrouter"},
+		{Prefix: "sonar-pro", Provider: "perplexity"},
+	}
+	if len(cfg.Routes) != len(want) {
+		t.Fatalf("Expected %d routes, got %+v", len(want), cfg.Routes)
+	}
+	for i, rt := range want {
+		if cfg.Routes[i].Prefix != rt.Prefix || cfg.Routes[i].Provider != rt.Provider {
+			t.Errorf("Route %d: expected %+v, got %+v", i, rt, cfg.Routes[i])
+		}
+	}
+	if cfg.Cohere.APIKey != "test-cohere-key" || cfg.DeepSeek.DefaultModel != "deepseek-chat" {
+		t.Errorf("Unexpected provider blocks: cohere %+v, deepseek %+v", cfg.Cohere, cfg.DeepSeek)
+	}
+}
+
+func TestConvertLiteLLMSelfHosted(t *testing.T) {
+	in := `
+model_list:
+  - model_name: llama-3-8b
+    litellm_params:
+      model: hosted_vllm/meta-llama/Meta-Llama-3-8B-Instruct
+      api_base: http://gpu-1:8000/v1
+  - model_name: qwen
+    litellm_params:
+      model: hosted_vllm/Qwen/Qwen2-7B-Instruct
+      api_base: http://gpu-1:8000/v1
+  - model_name: phi
+    litellm_params:
+      model: hosted_vllm/microsoft/phi-3-mini
+      api_base: http://gpu-2:8000/v1
+  - model_name: local
+    litellm_params:
+      model: lm_studio/llama-3.2-3b
+`
+	imported, err := ConvertLiteLLM([]byte(in))
+	if err != nil {
+		t.Fatalf("ConvertLiteLLM failed: %v", err)
+	}
+	cfg := imported.Config
+
+	if len(cfg.OpenAICompatible) != 2 {
+		t.Fatalf("Expected 2 instances, got %+v", cfg.OpenAICompatible)
+	}
+	if inst := cfg.OpenAICompatible[0]; inst.Name != "vllm" || len(inst.Models) != 2 {
+		t.Errorf("Unexpected first instance: %+v", inst)
+	}
+	if inst := cfg.OpenAICompatible[1]; inst.Name != "vllm-2" || inst.BaseURL != "http://gpu-2:8000/v1" {
+		t.Errorf("Unexpected second instance: %+v", inst)
+	}
+	if len(cfg.Routes) != 3 || cfg.Routes[2].Provider != "vllm-2" {
+		t.Errorf("Unexpected routes: %+v", cfg.Routes)
+	}
+	if notes := strings.Join(imported.Notes, "\n"); !strings.Contains(notes, "requires api_base") {
+		t.Errorf("Expected a note about the missing api_base, got:\n%s", notes)
+	}
+}
@@ -1,24 +1,44 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
 
+	"github.com/luguanyu1234/letllm-go/internal/health"
 	"go.uber.org/fx"
 )
 
 // Module provides *Config loaded from a YAML file.
-var Module = fx.Provide(LoadFromEnv)
+var Module = fx.Provide(
+	LoadFromEnv,
+	fx.Annotate(readinessCheck, fx.ResultTags(`group:"readiness"`)),
+)
 
 // LoadFromEnv loads configuration from LETLLM_CONFIG or defaults to "config.yaml".
 func LoadFromEnv() (*Config, error) {
-	path := os.Getenv("LETLLM_CONFIG")
-	if path == "" {
-		path = "config.yaml"
-	}
+	path := configPath()
 	cfg, err := Load(path)
 	if err != nil {
 		return nil, fmt.Errorf("load config from %s: %w", path, err)
 	}
 	return cfg, nil
 }
+
+func configPath() string {
+	if path := os.Getenv("LETLLM_CONFIG"); path != "" {
+		return path
+	}
+	return "config.yaml"
+}
+
+// readinessCheck reports the configuration as loaded; it can only be
+// constructed once *Config was provided successfully
+func readinessCheck(cfg *Config) health.Check {
+	return health.Check{
+		Name: "config",
+		Probe: func(ctx context.Context) (map[string]string, error) {
+			return map[string]string{"path": configPath(), "routes": fmt.Sprint(len(cfg.Routes))}, nil
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"net/textproto"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Route match kinds. Patterns match the whole model name, e.g.
+//
+//	routes:
+//	  - match: "regex"
+//	    pattern: ".*-32k"
+//	    provider: "azure-32k-pool"
+//	  - match: "glob"
+//	    pattern: "llama-3.?-*"
+//	    provider: "local"
+const (
+	MatchPrefix = "prefix" // the default; prefix is matched
+	MatchGlob   = "glob"   // * and ? wildcards and [...] classes
+	MatchRegex  = "regex"  // RE2 syntax
+)
+
+// routeRegexps caches compiled regex patterns, which routes keep as text so
+// configurations stay comparable
+var routeRegexps sync.Map
+
+// MatchModel reports whether the route's prefix or pattern matches model
+func (rt *Route) MatchModel(model string) bool {
+	switch rt.Match {
+	case MatchGlob:
+		ok, _ := path.Match(rt.Pattern, model)
+		return ok
+	case MatchRegex:
+		re, err := routeRegexp(rt.Pattern)
+		return err == nil && re.MatchString(model)
+	default:
+		return strings.HasPrefix(model, rt.Prefix)
+	}
+}
+
+// MatchHeaders reports whether headers, keyed by canonical name, carry
+// every header the route requires with the same value
+func (rt *Route) MatchHeaders(headers map[string]string) bool {
+	for name, value := range rt.Headers {
+		if headers[textproto.CanonicalMIMEHeaderKey(name)] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Label returns the route's pattern, or its prefix, for messages
+func (rt *Route) Label() string {
+	if rt.Match == MatchGlob || rt.Match == MatchRegex {
+		return rt.Pattern
+	}
+	return rt.Prefix
+}
+
+// validateMatch checks the match kind and compiles the route's pattern
+func (rt *Route) validateMatch() error {
+	switch rt.Match {
+	case "", MatchPrefix:
+		return nil
+	case MatchGlob:
+		if rt.Pattern == "" {
+			return fmt.Errorf("pattern is required")
+		}
+		if _, err := path.Match(rt.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", rt.Pattern, err)
+		}
+		return nil
+	case MatchRegex:
+		if rt.Pattern == "" {
+			return fmt.Errorf("pattern is required")
+		}
+		if _, err := routeRegexp(rt.Pattern); err != nil {
+			return fmt.Errorf("invalid regex %q: %w", rt.Pattern, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("match must be %q, %q or %q", MatchPrefix, MatchGlob, MatchRegex)
+	}
+}
+
+// routeRegexp compiles pattern anchored to the whole model name
+func routeRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := routeRegexps.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, err
+	}
+	routeRegexps.Store(pattern, re)
+	return re, nil
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule restricts when a route may be used. Example:
+//
+//	schedule:
+//	  timezone: "America/New_York"
+//	  allow:
+//	    - days: [mon, tue, wed, thu, fri]
+//	      start: "09:00"
+//	      end: "18:00"
+//	  blackouts:
+//	    - from: 2025-12-24T00:00:00Z
+//	      to: 2025-12-27T00:00:00Z
+//	      reason: "holiday freeze"
+type Schedule struct {
+	// IANA time zone name used to evaluate Allow windows (defaults to UTC)
+	Timezone string `yaml:"timezone"`
+	// If non-empty, the route is only usable inside one of these windows
+	Allow []TimeWindow `yaml:"allow"`
+	// Absolute periods during which the route is never usable
+	Blackouts []Blackout `yaml:"blackouts"`
+}
+
+// TimeWindow is a recurring daily window, optionally limited to some weekdays.
+// An End earlier than Start wraps past midnight.
+type TimeWindow struct {
+	Days  []string `yaml:"days"`  // "mon".."sun"; empty means every day
+	Start string   `yaml:"start"` // "HH:MM"
+	End   string   `yaml:"end"`   // "HH:MM"
+}
+
+// Blackout is an absolute period during which a route is disabled.
+type Blackout struct {
+	From   time.Time `yaml:"from"`
+	To     time.Time `yaml:"to"`
+	Reason string    `yaml:"reason"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Validate checks the schedule for malformed windows and time zones.
+func (s *Schedule) Validate() error {
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+		}
+	}
+	for i, w := range s.Allow {
+		if _, err := parseClock(w.Start); err != nil {
+			return fmt.Errorf("allow[%d].start: %w", i, err)
+		}
+		if _, err := parseClock(w.End); err != nil {
+			return fmt.Errorf("allow[%d].end: %w", i, err)
+		}
+		for _, d := range w.Days {
+			if _, ok := weekdays[strings.ToLower(d)]; !ok {
+				return fmt.Errorf("allow[%d]: unknown day %q", i, d)
+			}
+		}
+	}
+	for i, b := range s.Blackouts {
+		if !b.To.After(b.From) {
+			return fmt.Errorf("blackouts[%d]: to must be after from", i)
+		}
+	}
+	return nil
+}
+
+// Check reports whether the schedule permits use at t. When it does not,
+// the returned string explains why.
+func (s *Schedule) Check(t time.Time) (bool, string) {
+	for _, b := range s.Blackouts {
+		if !t.Before(b.From) && t.Before(b.To) {
+			reason := fmt.Sprintf("blackout until %s", b.To.UTC().Format(time.RFC3339))
+			if b.Reason != "" {
+				reason += ": " + b.Reason
+			}
+			return false, reason
+		}
+	}
+
+	if len(s.Allow) == 0 {
+		return true, ""
+	}
+
+	loc := time.UTC
+	if s.Timezone != "" {
+		if l, err := time.LoadLocation(s.Timezone); err == nil {
+			loc = l
+		}
+	}
+	local := t.In(loc)
+	for _, w := range s.Allow {
+		if w.contains(local) {
+			return true, ""
+		}
+	}
+	return false, "outside allowed time windows"
+}
+
+// contains reports whether local falls inside the window
+func (w TimeWindow) contains(local time.Time) bool {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+	if start <= end {
+		return w.onDay(day) && minute >= start && minute < end
+	}
+	// Window wraps past midnight: the early-morning part belongs to the previous day
+	if minute >= start {
+		return w.onDay(day)
+	}
+	if minute < end {
+		return w.onDay((day + 6) % 7)
+	}
+	return false
+}
+
+func (w TimeWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,155 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// maxCacheEntries bounds the attribute cache; expired entries are dropped
+// first and the cache is reset if that is not enough
+const maxCacheEntries = 10000
+
+// Request is the body POSTed to the enrichment service
+type Request struct {
+	// Headers holds the forwarded client headers that identify the caller
+	Headers map[string]string `json:"headers"`
+}
+
+// Response is the body expected back from the enrichment service
+type Response struct {
+	Attributes map[string]string `json:"attributes"`
+}
+
+// Enricher looks up request attributes from an external identity service
+// and caches them per identity
+type Enricher struct {
+	url     string
+	headers []string
+	ttl     time.Duration
+	client  *http.Client
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	attrs   map[string]string
+	expires time.Time
+}
+
+// NewEnricher creates an enricher from config; it is disabled when no
+// service URL is set
+func NewEnricher(cfg *config.Config) *Enricher {
+	ec := cfg.Enrichment
+	return &Enricher{
+		url:     ec.URL,
+		headers: ec.ForwardHeaders,
+		ttl:     ec.CacheTTL,
+		client:  &http.Client{Timeout: ec.Timeout},
+		now:     time.Now,
+		cache:   make(map[string]cacheEntry),
+	}
+}
+
+// Enabled reports whether an enrichment service is configured
+func (e *Enricher) Enabled() bool {
+	return e.url != ""
+}
+
+// Enrich returns the attributes of the caller identified by h
+func (e *Enricher) Enrich(ctx context.Context, h http.Header) (map[string]string, error) {
+	if !e.Enabled() {
+		return nil, nil
+	}
+
+	req := Request{Headers: make(map[string]string, len(e.headers))}
+	for _, name := range e.headers {
+		if v := h.Get(name); v != "" {
+			req.Headers[http.CanonicalHeaderKey(name)] = v
+		}
+	}
+	key := cacheKey(req.Headers, e.headers)
+
+	now := e.now()
+	e.mu.Lock()
+	entry, ok := e.cache[key]
+	e.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.attrs, nil
+	}
+
+	attrs, err := e.fetch(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.cache) >= maxCacheEntries {
+		for k, v := range e.cache {
+			if !now.Before(v.expires) {
+				delete(e.cache, k)
+			}
+		}
+		if len(e.cache) >= maxCacheEntries {
+			e.cache = make(map[string]cacheEntry)
+		}
+	}
+	e.cache[key] = cacheEntry{attrs: attrs, expires: now.Add(e.ttl)}
+	return attrs, nil
+}
+
+// FlushCache drops every cached identity, e.g. on an erasure request
+func (e *Enricher) FlushCache() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cache = make(map[string]cacheEntry)
+}
+
+func (e *Enricher) fetch(ctx context.Context, in *Request) (map[string]string, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("enrichment request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("enrichment service returned status %d", resp.StatusCode)
+	}
+
+	var out Response
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode enrichment response: %w", err)
+	}
+	return out.Attributes, nil
+}
+
+// cacheKey hashes the forwarded header values so that secrets such as
+// bearer tokens are not kept in memory as map keys
+func cacheKey(headers map[string]string, names []string) string {
+	h := sha256.New()
+	for _, name := range names {
+		h.Write([]byte(headers[http.CanonicalHeaderKey(name)]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,84 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestEnricherCachesPerIdentity(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var in Request
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		tier := "free"
+		if in.Headers["Authorization"] == "Bearer sk-gold" {
+			tier = "enterprise"
+		}
+		if _, ok := in.Headers["Cookie"]; ok {
+			t.Error("Only configured headers should be forwarded")
+		}
+		json.NewEncoder(w).Encode(Response{Attributes: map[string]string{"tier": tier}})
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.Enrichment.URL = srv.URL
+	cfg.Enrichment.ForwardHeaders = []string{"Authorization"}
+	cfg.Enrichment.Timeout = time.Second
+	cfg.Enrichment.CacheTTL = time.Minute
+	e := NewEnricher(cfg)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+
+	h := http.Header{}
+	h.Set("Authorization", "Bearer sk-gold")
+	h.Set("Cookie", "session=1")
+	for i := 0; i < 2; i++ {
+		attrs, err := e.Enrich(context.Background(), h)
+		if err != nil || attrs["tier"] != "enterprise" {
+			t.Fatalf("Unexpected attributes %v, %v", attrs, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected a cached second lookup, got %d calls", calls)
+	}
+
+	h.Set("Authorization", "Bearer sk-other")
+	if attrs, _ := e.Enrich(context.Background(), h); attrs["tier"] != "free" {
+		t.Errorf("Expected a separate lookup per identity, got %v", attrs)
+	}
+
+	now = now.Add(2 * time.Minute)
+	h.Set("Authorization", "Bearer sk-gold")
+	if _, err := e.Enrich(context.Background(), h); err != nil || calls != 3 {
+		t.Errorf("Expected expired entry to be refreshed, got %d calls, %v", calls, err)
+	}
+}
+
+func TestEnricherErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.Enrichment.URL = srv.URL
+	cfg.Enrichment.Timeout = time.Second
+	if _, err := NewEnricher(cfg).Enrich(context.Background(), http.Header{}); err == nil {
+		t.Error("Expected error for a failing service")
+	}
+
+	disabled := NewEnricher(&config.Config{})
+	if attrs, err := disabled.Enrich(context.Background(), http.Header{}); attrs != nil || err != nil {
+		t.Errorf("Expected no-op when disabled, got %v, %v", attrs, err)
+	}
+}
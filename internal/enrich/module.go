@@ -0,0 +1,6 @@
+package enrich
+
+import "go.uber.org/fx"
+
+// Module provides the request enricher.
+var Module = fx.Provide(NewEnricher)
@@ -0,0 +1,212 @@
+package erasure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/batch"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/idempotency"
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
+	"github.com/luguanyu1234/letllm-go/internal/sessions"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/tracing"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+// What happened to the subject's virtual key
+const (
+	KeyDeleted  = "deleted"
+	KeyDisabled = "disabled"
+	KeyNotFound = "not_found"
+)
+
+// Caches flushed by an erasure. Their entries are keyed by hashes that
+// cannot be traced back to a subject, so they are flushed entirely.
+const (
+	CacheEnrichment  = "enrichment"
+	CacheToolResults = "tool_results"
+	CacheResponses   = "responses"
+	// Responses kept for Idempotency-Key retries are stored per key, so
+	// only the subject's are dropped
+	CacheIdempotency = "idempotency"
+)
+
+// Record is the audit entry of one erasure. It holds only a hash of the
+// subject, so the audit log itself does not identify anyone.
+type Record struct {
+	ID            string    `json:"id"`
+	SubjectHash   string    `json:"subject_hash"`
+	RequestedAt   time.Time `json:"requested_at"`
+	Reference     string    `json:"reference,omitempty"`
+	UsageRecords  int       `json:"usage_records"`
+	Artifacts     int       `json:"artifacts"`
+	Sessions      int       `json:"sessions"`
+	Files         int       `json:"files"`
+	Batches       int       `json:"batches"`
+	Traces        int       `json:"traces"`
+	Key           string    `json:"key"`
+	CachesFlushed []string  `json:"caches_flushed"`
+}
+
+// Store persists the erasure audit log
+type Store interface {
+	Add(r *Record) error
+	// List returns all records, oldest first
+	List() ([]*Record, error)
+}
+
+// Options controls a single erasure
+type Options struct {
+	// DeleteKey removes the subject's key; by default it is soft-deleted,
+	// i.e. disabled with its name cleared, so the ID cannot be reissued
+	DeleteKey bool
+	// Reference is an external ticket or request ID kept in the audit record
+	Reference string
+}
+
+// Eraser removes everything the gateway stores about a subject. A subject
+// is a virtual key ID, the only identity the gateway records data under.
+type Eraser struct {
+	audit     Store
+	usage     usage.Store
+	artifacts artifacts.Store
+	keys      keys.Store
+	sessions  sessions.Store
+	files     *files.Store
+	batches   batch.Store
+	traces    tracing.Store
+	enricher  *enrich.Enricher
+	tools     *tools.Runtime
+	responses *respcache.Cache
+	idem      *idempotency.Store
+	now       func() time.Time
+}
+
+// NewEraser creates an eraser over the gateway's stores and caches
+func NewEraser(audit Store, usageStore usage.Store, artifactStore artifacts.Store, keyStore keys.Store, sessionStore sessions.Store, fileStore *files.Store, batchStore batch.Store, traceStore tracing.Store, enricher *enrich.Enricher, toolRuntime *tools.Runtime, responses *respcache.Cache, idem *idempotency.Store) *Eraser {
+	return &Eraser{
+		audit:     audit,
+		usage:     usageStore,
+		artifacts: artifactStore,
+		keys:      keyStore,
+		sessions:  sessionStore,
+		files:     fileStore,
+		batches:   batchStore,
+		traces:    traceStore,
+		enricher:  enricher,
+		tools:     toolRuntime,
+		responses: responses,
+		idem:      idem,
+		now:       time.Now,
+	}
+}
+
+// Erase purges the subject's usage records, the artifacts only it claimed,
+// its sessions with their history, its batches and files with their
+// content and its stream traces, flushes caches, removes or soft-deletes
+// its key and records the erasure. Erasing is idempotent, so a failed
+// request can be retried.
+func (e *Eraser) Erase(subject string, opts Options) (*Record, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("subject is required")
+	}
+	rec := &Record{
+		ID:          newID(),
+		SubjectHash: HashSubject(subject),
+		RequestedAt: e.now().UTC(),
+		Reference:   opts.Reference,
+	}
+
+	n, err := e.usage.Erase(subject)
+	if err != nil {
+		return nil, err
+	}
+	rec.UsageRecords = n
+
+	deleted, err := e.artifacts.Erase(subject)
+	if err != nil {
+		return nil, err
+	}
+	rec.Artifacts = len(deleted)
+
+	if rec.Sessions, err = e.sessions.Erase(subject); err != nil {
+		return nil, err
+	}
+	// Batches go before their files, so none still running refers to
+	// files that are gone
+	if rec.Batches, err = e.batches.Erase(subject); err != nil {
+		return nil, err
+	}
+	if rec.Files, err = e.files.Erase(context.Background(), subject); err != nil {
+		return nil, err
+	}
+	if rec.Traces, err = e.traces.Erase(subject); err != nil {
+		return nil, err
+	}
+
+	e.enricher.FlushCache()
+	e.tools.FlushCache()
+	if err := e.responses.Flush(context.Background()); err != nil {
+		return nil, err
+	}
+	if err := e.idem.Erase(context.Background(), subject); err != nil {
+		return nil, err
+	}
+	rec.CachesFlushed = []string{CacheEnrichment, CacheToolResults, CacheResponses, CacheIdempotency}
+
+	if rec.Key, err = e.eraseKey(subject, opts.DeleteKey); err != nil {
+		return nil, err
+	}
+
+	if err := e.audit.Add(rec); err != nil {
+		return nil, fmt.Errorf("record erasure: %w", err)
+	}
+	return rec, nil
+}
+
+func (e *Eraser) eraseKey(id string, hardDelete bool) (string, error) {
+	k, err := e.keys.Get(id)
+	if errors.Is(err, keys.ErrNotFound) {
+		return KeyNotFound, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if hardDelete {
+		if err := e.keys.Delete(id); err != nil {
+			return "", err
+		}
+		return KeyDeleted, nil
+	}
+	k.Disabled = true
+	k.Name = ""
+	if err := e.keys.Put(k); err != nil {
+		return "", err
+	}
+	return KeyDisabled, nil
+}
+
+// History returns the erasure audit log
+func (e *Eraser) History() ([]*Record, error) {
+	return e.audit.List()
+}
+
+// HashSubject returns the hex-encoded SHA-256 hash of a subject, as kept in
+// audit records
+func HashSubject(subject string) string {
+	sum := sha256.Sum256([]byte(subject))
+	return hex.EncodeToString(sum[:])
+}
+
+func newID() string {
+	return "er_" + ids.New()
+}
@@ -0,0 +1,143 @@
+package erasure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/batch"
+	"github.com/luguanyu1234/letllm-go/internal/cache"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/idempotency"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
+	"github.com/luguanyu1234/letllm-go/internal/sessions"
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+	"github.com/luguanyu1234/letllm-go/internal/storage/storagetest"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/tracing"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+func newTestEraser(t *testing.T) (*Eraser, *storage.DB) {
+	t.Helper()
+	cfg := &config.Config{}
+	db := storagetest.Open(t)
+	e := NewEraser(NewSQLStore(db), usage.NewSQLStore(db), artifacts.NewSQLStore(db), keys.NewSQLStore(db),
+		sessions.NewSQLStore(db), files.NewStore(files.NewSQLMetadata(db), files.NewSQLBlobs(db)), batch.NewSQLStore(db),
+		tracing.NewSQLStore(db, 10), enrich.NewEnricher(cfg), tools.NewRuntime(cfg, metrics.NewRegistry()), respcache.NewCache(),
+		idempotency.NewStore(cfg, cache.NewMemory(10, 1<<20)))
+	return e, db
+}
+
+func TestErase(t *testing.T) {
+	e, _ := newTestEraser(t)
+	now := time.Now().UTC()
+
+	_ = e.keys.Put(&keys.Key{ID: "alice", Name: "Alice", Hash: keys.HashSecret("sk-alice")})
+	_ = e.usage.Add(&usage.Record{Time: now, KeyID: "alice", Model: "gpt-4o", Status: 200})
+	_ = e.usage.Add(&usage.Record{Time: now, KeyID: "bob", Model: "gpt-4o", Status: 200})
+	a, _ := e.artifacts.Put([]byte("alice's notes"))
+	_ = e.artifacts.Claim(a.ID, "alice")
+	hello := []provider.Message{{Role: "user", Content: "hello"}}
+	aliceSess, bobSess := sessions.New("alice", "", nil), sessions.New("bob", "", nil)
+	_ = e.sessions.Create(aliceSess, hello)
+	_ = e.sessions.Create(bobSess, hello)
+	_ = e.traces.Add(&tracing.Trace{ID: "req-1", KeyID: "alice", StartedAt: now})
+	_ = e.traces.Add(&tracing.Trace{ID: "req-2", KeyID: "bob", StartedAt: now})
+
+	rec, err := e.Erase("alice", Options{Reference: "DSR-42"})
+	if err != nil {
+		t.Fatalf("Erase failed: %v", err)
+	}
+	if rec.UsageRecords != 1 || rec.Artifacts != 1 || rec.Sessions != 1 || rec.Traces != 1 || rec.Key != KeyDisabled || rec.Reference != "DSR-42" {
+		t.Errorf("Unexpected erasure record: %+v", rec)
+	}
+	if rec.SubjectHash != HashSubject("alice") || len(rec.CachesFlushed) != 4 {
+		t.Errorf("Unexpected erasure record: %+v", rec)
+	}
+
+	if k, err := e.keys.Get("alice"); err != nil || !k.Disabled || k.Name != "" {
+		t.Errorf("Expected key to be soft-deleted, got %+v, %v", k, err)
+	}
+	if _, err := e.artifacts.Get(a.ID); !errors.Is(err, artifacts.ErrNotFound) {
+		t.Errorf("Expected artifact to be erased, got %v", err)
+	}
+	if _, err := e.sessions.Messages(aliceSess.ID); !errors.Is(err, sessions.ErrNotFound) {
+		t.Errorf("Expected session to be erased, got %v", err)
+	}
+	if history, _ := e.sessions.Messages(bobSess.ID); len(history) != 1 {
+		t.Errorf("Expected bob's session to remain, got %v", history)
+	}
+	if _, err := e.traces.Get("req-1"); !errors.Is(err, tracing.ErrNotFound) {
+		t.Errorf("Expected trace to be erased, got %v", err)
+	}
+	if _, err := e.traces.Get("req-2"); err != nil {
+		t.Errorf("Expected bob's trace to remain, got %v", err)
+	}
+	if left, _ := e.usage.Query(now.Add(-time.Minute), now.Add(time.Minute)); len(left) != 1 || left[0].KeyID != "bob" {
+		t.Errorf("Expected only bob's usage to remain, got %d records", len(left))
+	}
+
+	// A repeated request deletes the key and is audited again
+	rec, err = e.Erase("alice", Options{DeleteKey: true})
+	if err != nil || rec.Key != KeyDeleted || rec.UsageRecords != 0 {
+		t.Errorf("Unexpected second erasure: %+v, %v", rec, err)
+	}
+	if rec, _ := e.Erase("alice", Options{}); rec.Key != KeyNotFound {
+		t.Errorf("Expected key not found, got %+v", rec)
+	}
+
+	history, err := e.History()
+	if err != nil || len(history) != 3 || history[0].Reference != "DSR-42" || history[0].Sessions != 1 || history[0].CachesFlushed[1] != CacheToolResults {
+		t.Errorf("Unexpected audit log: %v, %v", history, err)
+	}
+}
+
+func TestEraseFilesAndBatches(t *testing.T) {
+	e, db := newTestEraser(t)
+	ctx := context.Background()
+	blobs := files.NewSQLBlobs(db)
+
+	input, _ := e.files.Put(ctx, "alice", "input.jsonl", files.PurposeBatch, []byte("{}"))
+	output, _ := e.files.Put(ctx, "alice", "batch_1_output.jsonl", files.PurposeBatchOutput, []byte("{}"))
+	kept, _ := e.files.Put(ctx, "bob", "input.jsonl", files.PurposeBatch, []byte("{}"))
+	_ = e.batches.Create(&batch.Batch{ID: "batch_1", KeyID: "alice", InputFileID: input.ID, Status: batch.StatusCompleted})
+	_ = e.batches.Create(&batch.Batch{ID: "batch_2", KeyID: "bob", InputFileID: kept.ID, Status: batch.StatusInProgress})
+
+	rec, err := e.Erase("alice", Options{})
+	if err != nil {
+		t.Fatalf("Erase failed: %v", err)
+	}
+	if rec.Files != 2 || rec.Batches != 1 {
+		t.Errorf("Unexpected erasure record: %+v", rec)
+	}
+	for _, f := range []*files.File{input, output} {
+		if _, err := e.files.Get(f.ID); !errors.Is(err, files.ErrNotFound) {
+			t.Errorf("Expected file %s to be erased, got %v", f.Filename, err)
+		}
+		if _, err := blobs.Get(ctx, f.ID); !errors.Is(err, files.ErrNotFound) {
+			t.Errorf("Expected content of %s to be erased, got %v", f.Filename, err)
+		}
+	}
+	if _, err := e.batches.Get("batch_1"); !errors.Is(err, batch.ErrBatchNotFound) {
+		t.Errorf("Expected batch to be erased, got %v", err)
+	}
+	if _, err := e.files.Content(ctx, kept.ID); err != nil {
+		t.Errorf("Expected bob's file to remain, got %v", err)
+	}
+	if _, err := e.batches.Get("batch_2"); err != nil {
+		t.Errorf("Expected bob's batch to remain, got %v", err)
+	}
+
+	history, _ := e.History()
+	if len(history) != 1 || history[0].Files != 2 || history[0].Batches != 1 {
+		t.Errorf("Unexpected audit log: %+v", history)
+	}
+}
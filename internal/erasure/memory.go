@@ -0,0 +1,37 @@
+package erasure
+
+import "sync"
+
+// MemoryStore is an in-memory audit Store
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records []*Record
+}
+
+// NewMemoryStore creates an empty in-memory audit store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Add appends a record
+func (s *MemoryStore) Add(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cp := *r
+	s.records = append(s.records, &cp)
+	return nil
+}
+
+// List returns all records, oldest first
+func (s *MemoryStore) List() ([]*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Record, len(s.records))
+	for i, r := range s.records {
+		cp := *r
+		out[i] = &cp
+	}
+	return out, nil
+}
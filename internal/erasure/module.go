@@ -0,0 +1,18 @@
+package erasure
+
+import (
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+	"go.uber.org/fx"
+)
+
+// Module provides the erasure audit Store and the Eraser.
+var Module = fx.Provide(NewStore, NewEraser)
+
+// NewStore keeps the audit log in the shared database when one is
+// configured, in memory otherwise
+func NewStore(db *storage.DB) Store {
+	if db == nil {
+		return NewMemoryStore()
+	}
+	return NewSQLStore(db)
+}
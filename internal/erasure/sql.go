@@ -0,0 +1,59 @@
+package erasure
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+)
+
+// SQLStore is an audit Store backed by the shared database
+type SQLStore struct {
+	db *storage.DB
+}
+
+// NewSQLStore creates an audit store on db; the erasure_audit table is
+// created by the storage migrations
+func NewSQLStore(db *storage.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// Add inserts a record
+func (s *SQLStore) Add(r *Record) error {
+	_, err := s.db.Exec(s.db.Rebind(`INSERT INTO erasure_audit
+		(id, subject_hash, requested_at, reference, usage_records, artifacts, sessions, files, batches, traces, key_action, caches)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		r.ID, r.SubjectHash, r.RequestedAt.UTC(), r.Reference, r.UsageRecords, r.Artifacts, r.Sessions, r.Files, r.Batches, r.Traces,
+		r.Key, strings.Join(r.CachesFlushed, ","))
+	if err != nil {
+		return fmt.Errorf("add erasure record: %w", err)
+	}
+	return nil
+}
+
+// List returns all records, oldest first
+func (s *SQLStore) List() ([]*Record, error) {
+	rows, err := s.db.Query(`SELECT id, subject_hash, requested_at, reference, usage_records, artifacts,
+		sessions, files, batches, traces, key_action, caches FROM erasure_audit ORDER BY requested_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("list erasure records: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*Record
+	for rows.Next() {
+		var (
+			r      Record
+			caches string
+		)
+		if err := rows.Scan(&r.ID, &r.SubjectHash, &r.RequestedAt, &r.Reference, &r.UsageRecords,
+			&r.Artifacts, &r.Sessions, &r.Files, &r.Batches, &r.Traces, &r.Key, &caches); err != nil {
+			return nil, err
+		}
+		if caches != "" {
+			r.CachesFlushed = strings.Split(caches, ",")
+		}
+		out = append(out, &r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,82 @@
+package experiments
+
+import (
+	"hash/fnv"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// Assignment is the variant of an experiment a caller is bucketed into
+type Assignment struct {
+	Experiment string
+	Variant    string
+
+	variant *config.ExperimentVariant
+}
+
+// Provider returns the provider pinned by the variant, or "" to route
+func (a Assignment) Provider() string {
+	return a.variant.Provider
+}
+
+// Apply sets the model and parameters of the variant on req
+func (a Assignment) Apply(req *provider.StandardRequest) {
+	v := a.variant
+	if v.Model != "" {
+		req.Model = v.Model
+	}
+	if v.Temperature != nil {
+		req.Temperature = v.Temperature
+	}
+	if v.TopP != nil {
+		req.TopP = v.TopP
+	}
+	if v.MaxTokens != nil {
+		req.MaxTokens = v.MaxTokens
+	}
+}
+
+// Assigner buckets callers into the variants of the configured experiments
+type Assigner struct {
+	byModel map[string]*config.Experiment
+}
+
+// NewAssigner indexes the configured experiments by model
+func NewAssigner(cfg *config.Config) *Assigner {
+	a := &Assigner{byModel: make(map[string]*config.Experiment, len(cfg.Experiments))}
+	for i := range cfg.Experiments {
+		a.byModel[cfg.Experiments[i].Model] = &cfg.Experiments[i]
+	}
+	return a
+}
+
+// Assign returns the variant caller gets for requests of model, and false
+// when no experiment runs on model or the caller is anonymous. A caller
+// keeps its variant as long as the experiment's variants and weights do.
+func (a *Assigner) Assign(model, caller string) (Assignment, bool) {
+	ex, ok := a.byModel[model]
+	if !ok || caller == "" {
+		return Assignment{}, false
+	}
+	total := 0
+	for _, v := range ex.Variants {
+		total += v.Weight
+	}
+	if total == 0 {
+		return Assignment{}, false
+	}
+	h := fnv.New64a()
+	h.Write([]byte(ex.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(caller))
+	bucket := int(h.Sum64() % uint64(total))
+	for i := range ex.Variants {
+		v := &ex.Variants[i]
+		if bucket < v.Weight {
+			return Assignment{Experiment: ex.Name, Variant: v.Name, variant: v}, true
+		}
+		bucket -= v.Weight
+	}
+	return Assignment{}, false
+}
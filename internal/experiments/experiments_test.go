@@ -0,0 +1,58 @@
+package experiments
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+func TestAssign(t *testing.T) {
+	temperature := 0.2
+	cfg := &config.Config{Experiments: []config.Experiment{{
+		Name:  "chat-v2",
+		Model: "chat",
+		Variants: []config.ExperimentVariant{
+			{Name: "control", Weight: 3},
+			{Name: "candidate", Weight: 1, Model: "chat-v2", Provider: "next", Temperature: &temperature},
+		},
+	}}}
+	a := NewAssigner(cfg)
+
+	if _, ok := a.Assign("other", "alice"); ok {
+		t.Error("Expected models without an experiment not enrolled")
+	}
+	if _, ok := a.Assign("chat", ""); ok {
+		t.Error("Expected anonymous callers not enrolled")
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		caller := fmt.Sprintf("user-%d", i)
+		first, ok := a.Assign("chat", caller)
+		if !ok {
+			t.Fatalf("Expected %s enrolled", caller)
+		}
+		if again, _ := a.Assign("chat", caller); again.Variant != first.Variant {
+			t.Fatalf("Expected %s to keep variant %s, got %s", caller, first.Variant, again.Variant)
+		}
+		counts[first.Variant]++
+	}
+	if counts["control"] < 650 || counts["control"] > 850 {
+		t.Errorf("Expected about 3 in 4 callers on control, got %v", counts)
+	}
+
+	for i := 0; ; i++ {
+		as, _ := a.Assign("chat", fmt.Sprintf("user-%d", i))
+		if as.Variant != "candidate" {
+			continue
+		}
+		req := &provider.StandardRequest{Model: "chat"}
+		as.Apply(req)
+		if req.Model != "chat-v2" || req.Temperature == nil || *req.Temperature != temperature || as.Provider() != "next" {
+			t.Errorf("Unexpected candidate request %+v via %q", req, as.Provider())
+		}
+		break
+	}
+}
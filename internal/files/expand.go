@@ -0,0 +1,76 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+// referencePattern matches "{{file:<id>}}" placeholders in message content
+var referencePattern = regexp.MustCompile(`\{\{file:(file-[0-9A-Za-z-]+)\}\}`)
+
+// ReferenceError reports a placeholder naming a file that cannot be
+// attached: unknown, owned by another key, or not user data text
+type ReferenceError struct {
+	ID     string
+	Reason string
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("file %s %s", e.ID, e.Reason)
+}
+
+// HasReferences reports whether text contains file placeholders
+func HasReferences(text string) bool {
+	return referencePattern.MatchString(text)
+}
+
+// Expand replaces every file placeholder in text with the content of the
+// file, which must be a user_data file of keyID holding UTF-8 text.
+// Expanded content is not scanned again.
+func (s *Store) Expand(ctx context.Context, keyID, text string) (string, error) {
+	var firstErr error
+	out := referencePattern.ReplaceAllStringFunc(text, func(ref string) string {
+		if firstErr != nil {
+			return ref
+		}
+		id := referencePattern.FindStringSubmatch(ref)[1]
+		content, err := s.attachment(ctx, keyID, id)
+		if err != nil {
+			firstErr = err
+			return ref
+		}
+		return content
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return out, nil
+}
+
+// attachment returns the content of file id for a message of keyID
+func (s *Store) attachment(ctx context.Context, keyID, id string) (string, error) {
+	f, err := s.meta.Get(id)
+	if err == nil && f.KeyID != keyID {
+		err = ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return "", &ReferenceError{ID: id, Reason: "not found"}
+		}
+		return "", err
+	}
+	if f.Purpose != PurposeUserData {
+		return "", &ReferenceError{ID: id, Reason: fmt.Sprintf("has purpose %q; only %q files can be attached", f.Purpose, PurposeUserData)}
+	}
+	content, err := s.blobs.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(content) {
+		return "", &ReferenceError{ID: id, Reason: "is not text"}
+	}
+	return string(content), nil
+}
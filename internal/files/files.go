@@ -0,0 +1,150 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+)
+
+// File purposes, as in OpenAI's Files API
+const (
+	PurposeBatch       = "batch"
+	PurposeBatchOutput = "batch_output"
+	PurposeVision      = "vision"
+	PurposeUserData    = "user_data"
+	PurposeAssistants  = "assistants"
+	PurposeFineTune    = "fine-tune"
+)
+
+// Uploadable lists the purposes clients may upload files for; the others
+// are only written by the gateway. Messages carry only text, so vision
+// files could never be attached and are refused.
+var Uploadable = map[string]bool{
+	PurposeBatch:      true,
+	PurposeUserData:   true,
+	PurposeAssistants: true,
+	PurposeFineTune:   true,
+}
+
+// ErrNotFound is returned for unknown file IDs
+var ErrNotFound = errors.New("file not found")
+
+// File describes a stored file; its content is kept apart in Blobs
+type File struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int    `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	// KeyID is the virtual key owning the file, empty for callers without one
+	KeyID string `json:"-"`
+}
+
+// Metadata persists file descriptions
+type Metadata interface {
+	Create(f *File) error
+	Get(id string) (*File, error)
+	// List returns up to limit files of keyID, newest first, starting after
+	// the file with ID after when it is set; an empty purpose matches all
+	List(keyID, purpose, after string, limit int) ([]*File, error)
+	Delete(id string) error
+}
+
+// Blobs holds file content by file ID
+type Blobs interface {
+	Put(ctx context.Context, id string, content []byte) error
+	// Get returns ErrNotFound for missing content
+	Get(ctx context.Context, id string) ([]byte, error)
+	// Delete succeeds for missing content
+	Delete(ctx context.Context, id string) error
+}
+
+// Store keeps files: their descriptions in Metadata and content in Blobs
+type Store struct {
+	meta  Metadata
+	blobs Blobs
+}
+
+// NewStore creates a file store over meta and blobs
+func NewStore(meta Metadata, blobs Blobs) *Store {
+	return &Store{meta: meta, blobs: blobs}
+}
+
+// Put stores a new file owned by keyID. Content is written first, so a
+// listed file always has content.
+func (s *Store) Put(ctx context.Context, keyID, filename, purpose string, content []byte) (*File, error) {
+	f := &File{
+		ID:        "file-" + ids.New(),
+		Object:    "file",
+		Bytes:     len(content),
+		CreatedAt: time.Now().Unix(),
+		Filename:  filename,
+		Purpose:   purpose,
+		KeyID:     keyID,
+	}
+	if err := s.blobs.Put(ctx, f.ID, content); err != nil {
+		return nil, fmt.Errorf("store file content: %w", err)
+	}
+	if err := s.meta.Create(f); err != nil {
+		_ = s.blobs.Delete(ctx, f.ID)
+		return nil, err
+	}
+	return f, nil
+}
+
+// Get returns the description of a file
+func (s *Store) Get(id string) (*File, error) {
+	return s.meta.Get(id)
+}
+
+// Content returns the content of a file
+func (s *Store) Content(ctx context.Context, id string) ([]byte, error) {
+	if _, err := s.meta.Get(id); err != nil {
+		return nil, err
+	}
+	return s.blobs.Get(ctx, id)
+}
+
+// List returns keyID's files newest first; see Metadata.List
+func (s *Store) List(keyID, purpose, after string, limit int) ([]*File, error) {
+	return s.meta.List(keyID, purpose, after, limit)
+}
+
+// Delete removes a file. Content left behind when its removal fails is
+// unreachable and only logged.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	if err := s.meta.Delete(id); err != nil {
+		return err
+	}
+	if err := s.blobs.Delete(ctx, id); err != nil {
+		log.Printf("files: delete content of %s: %v", id, err)
+	}
+	return nil
+}
+
+// Erase removes every file of keyID with its content and returns how many
+// were removed. Content goes first, so a failed erasure leaves the files
+// listed and can be retried.
+func (s *Store) Erase(ctx context.Context, keyID string) (int, error) {
+	n := 0
+	for {
+		page, err := s.meta.List(keyID, "", "", 100)
+		if err != nil || len(page) == 0 {
+			return n, err
+		}
+		for _, f := range page {
+			if err := s.blobs.Delete(ctx, f.ID); err != nil {
+				return n, fmt.Errorf("erase file content %s: %w", f.ID, err)
+			}
+			if err := s.meta.Delete(f.ID); err != nil && !errors.Is(err, ErrNotFound) {
+				return n, err
+			}
+			n++
+		}
+	}
+}
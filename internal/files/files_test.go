@@ -0,0 +1,196 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/storage/storagetest"
+)
+
+func TestMetadata(t *testing.T) {
+	for name, m := range map[string]Metadata{
+		"memory": NewMemoryMetadata(),
+		"sql":    NewSQLMetadata(storagetest.Open(t)),
+	} {
+		for i, id := range []string{"file-a", "file-b", "file-c"} {
+			purpose := PurposeBatch
+			if i == 1 {
+				purpose = PurposeVision
+			}
+			if err := m.Create(&File{ID: id, Object: "file", CreatedAt: int64(100 + i), Filename: id, Purpose: purpose, KeyID: "key_1"}); err != nil {
+				t.Fatalf("%s: Create: %v", name, err)
+			}
+		}
+		_ = m.Create(&File{ID: "file-other", Object: "file", CreatedAt: 200, Purpose: PurposeBatch, KeyID: "key_2"})
+
+		if f, err := m.Get("file-b"); err != nil || f.Purpose != PurposeVision || f.KeyID != "key_1" {
+			t.Errorf("%s: Get returned %+v, %v", name, f, err)
+		}
+
+		ids := func(fs []*File) string {
+			var out []string
+			for _, f := range fs {
+				out = append(out, f.ID)
+			}
+			return strings.Join(out, ",")
+		}
+		for query, want := range map[[3]string]string{
+			{"key_1", "", ""}:              "file-c,file-b,file-a",
+			{"key_1", PurposeBatch, ""}:    "file-c,file-a",
+			{"key_1", "", "file-c"}:        "file-b,file-a",
+			{"key_2", "", ""}:              "file-other",
+			{"key_1", PurposeFineTune, ""}: "",
+		} {
+			got, err := m.List(query[0], query[1], query[2], 10)
+			if err != nil || ids(got) != want {
+				t.Errorf("%s: List%v returned %s, %v; expected %s", name, query, ids(got), err, want)
+			}
+		}
+
+		if err := m.Delete("file-b"); err != nil {
+			t.Fatalf("%s: Delete: %v", name, err)
+		}
+		if _, err := m.Get("file-b"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound after delete, got %v", name, err)
+		}
+		if err := m.Delete("file-b"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound deleting twice, got %v", name, err)
+		}
+	}
+}
+
+// fakeS3 serves objects from memory, refusing unsigned requests
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	paths   []string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") ||
+		r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paths = append(f.paths, r.URL.Path)
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestBlobs(t *testing.T) {
+	s3 := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(s3)
+	defer srv.Close()
+	s3Blobs, err := NewS3Blobs(config.S3Config{Bucket: "bucket", Region: "eu-west-1", Endpoint: srv.URL, Prefix: "letllm/",
+		PathStyle: true, AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk, err := NewDiskBlobs(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for name, b := range map[string]Blobs{
+		"memory": NewMemoryBlobs(),
+		"sql":    NewSQLBlobs(storagetest.Open(t)),
+		"disk":   disk,
+		"s3":     s3Blobs,
+	} {
+		if err := b.Put(ctx, "file-1", []byte("hello")); err != nil {
+			t.Fatalf("%s: Put: %v", name, err)
+		}
+		if c, err := b.Get(ctx, "file-1"); err != nil || string(c) != "hello" {
+			t.Errorf("%s: Get returned %q, %v", name, c, err)
+		}
+		if err := b.Delete(ctx, "file-1"); err != nil {
+			t.Errorf("%s: Delete: %v", name, err)
+		}
+		if _, err := b.Get(ctx, "file-1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound after delete, got %v", name, err)
+		}
+		if err := b.Delete(ctx, "file-1"); err != nil {
+			t.Errorf("%s: deleting missing content should succeed, got %v", name, err)
+		}
+	}
+
+	if s3.paths[0] != "/bucket/letllm/file-1" {
+		t.Errorf("Expected a path-style object URL, got %s", s3.paths[0])
+	}
+	if err := disk.Put(ctx, "../escape", []byte("x")); err == nil {
+		t.Error("Expected IDs with path separators to be refused")
+	}
+}
+
+func TestStoreDelete(t *testing.T) {
+	s := NewStore(NewMemoryMetadata(), NewMemoryBlobs())
+	ctx := context.Background()
+	f, err := s.Put(ctx, "key_1", "notes.txt", PurposeUserData, []byte("notes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(f.ID, "file-") || f.Bytes != 5 || f.Object != "file" {
+		t.Errorf("Unexpected file %+v", f)
+	}
+	if c, err := s.Content(ctx, f.ID); err != nil || string(c) != "notes" {
+		t.Errorf("Content returned %q, %v", c, err)
+	}
+	if err := s.Delete(ctx, f.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Content(ctx, f.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestStoreExpand(t *testing.T) {
+	s := NewStore(NewMemoryMetadata(), NewMemoryBlobs())
+	ctx := context.Background()
+	notes, _ := s.Put(ctx, "key_1", "notes.txt", PurposeUserData, []byte("meeting notes"))
+	binary, _ := s.Put(ctx, "key_1", "blob.bin", PurposeUserData, []byte{0xff, 0xfe})
+	batch, _ := s.Put(ctx, "key_1", "in.jsonl", PurposeBatch, []byte("{}"))
+
+	out, err := s.Expand(ctx, "key_1", "Summarize {{file:"+notes.ID+"}} please")
+	if err != nil || out != "Summarize meeting notes please" {
+		t.Fatalf("Expand returned %q, %v", out, err)
+	}
+	if HasReferences("no placeholders {{file:}}") {
+		t.Error("Expected no references")
+	}
+
+	for name, tc := range map[string]struct{ keyID, id string }{
+		"unknown":       {"key_1", "file-missing"},
+		"another key's": {"key_2", notes.ID},
+		"binary":        {"key_1", binary.ID},
+		"batch input":   {"key_1", batch.ID},
+	} {
+		var refErr *ReferenceError
+		if _, err := s.Expand(ctx, tc.keyID, "{{file:"+tc.id+"}}"); !errors.As(err, &refErr) || refErr.ID != tc.id {
+			t.Errorf("%s: expected a ReferenceError, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package files
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// MemoryMetadata is an in-memory Metadata
+type MemoryMetadata struct {
+	mu    sync.RWMutex
+	files map[string]File
+}
+
+// NewMemoryMetadata creates an empty in-memory Metadata
+func NewMemoryMetadata() *MemoryMetadata {
+	return &MemoryMetadata{files: make(map[string]File)}
+}
+
+// Create stores a new file description
+func (m *MemoryMetadata) Create(f *File) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[f.ID] = *f
+	return nil
+}
+
+// Get returns a file description
+func (m *MemoryMetadata) Get(id string) (*File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.files[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &f, nil
+}
+
+// List returns keyID's files newest first
+func (m *MemoryMetadata) List(keyID, purpose, after string, limit int) ([]*File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []*File
+	for _, f := range m.files {
+		if f.KeyID == keyID && (purpose == "" || f.Purpose == purpose) {
+			f := f
+			out = append(out, &f)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return newer(out[i], out[j]) })
+	if a, ok := m.files[after]; ok {
+		i := sort.Search(len(out), func(i int) bool { return !newer(out[i], &a) })
+		for i < len(out) && out[i].ID == a.ID {
+			i++
+		}
+		out = out[i:]
+	}
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// Delete removes a file description
+func (m *MemoryMetadata) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.files, id)
+	return nil
+}
+
+// newer orders files by creation time, then ID
+func newer(a, b *File) bool {
+	if a.CreatedAt != b.CreatedAt {
+		return a.CreatedAt > b.CreatedAt
+	}
+	return a.ID > b.ID
+}
+
+// MemoryBlobs is an in-memory Blobs
+type MemoryBlobs struct {
+	mu      sync.RWMutex
+	content map[string][]byte
+}
+
+// NewMemoryBlobs creates an empty in-memory Blobs
+func NewMemoryBlobs() *MemoryBlobs {
+	return &MemoryBlobs{content: make(map[string][]byte)}
+}
+
+// Put stores content
+func (m *MemoryBlobs) Put(_ context.Context, id string, content []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.content[id] = append([]byte(nil), content...)
+	return nil
+}
+
+// Get returns content
+func (m *MemoryBlobs) Get(_ context.Context, id string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.content[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return c, nil
+}
+
+// Delete removes content
+func (m *MemoryBlobs) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.content, id)
+	return nil
+}
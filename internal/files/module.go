@@ -0,0 +1,50 @@
+package files
+
+import (
+	"fmt"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+	"go.uber.org/fx"
+)
+
+// Module provides the file Store.
+var Module = fx.Provide(NewFileStore)
+
+// NewFileStore creates the file store. Descriptions live in the shared
+// database, or in memory when there is none; content lives where
+// files.storage says.
+func NewFileStore(cfg *config.Config, db *storage.DB) (*Store, error) {
+	var meta Metadata = NewMemoryMetadata()
+	if db != nil {
+		meta = NewSQLMetadata(db)
+	}
+
+	kind := cfg.Files.Storage
+	if kind == "" && db != nil {
+		kind = "database"
+	}
+	switch kind {
+	case "", "memory":
+		return NewStore(meta, NewMemoryBlobs()), nil
+	case "database":
+		if db == nil {
+			return nil, fmt.Errorf("files.storage %q needs a storage driver other than memory", kind)
+		}
+		return NewStore(meta, NewSQLBlobs(db)), nil
+	case "disk":
+		blobs, err := NewDiskBlobs(cfg.Files.Dir)
+		if err != nil {
+			return nil, err
+		}
+		return NewStore(meta, blobs), nil
+	case "s3":
+		blobs, err := NewS3Blobs(cfg.Files.S3)
+		if err != nil {
+			return nil, err
+		}
+		return NewStore(meta, blobs), nil
+	default:
+		return nil, fmt.Errorf("unknown files.storage %q", kind)
+	}
+}
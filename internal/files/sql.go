@@ -0,0 +1,141 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+)
+
+// SQLMetadata is a Metadata backed by the files table of the shared database
+type SQLMetadata struct {
+	db *storage.DB
+}
+
+// NewSQLMetadata creates a Metadata on db
+func NewSQLMetadata(db *storage.DB) *SQLMetadata {
+	return &SQLMetadata{db: db}
+}
+
+// Create stores a new file description
+func (s *SQLMetadata) Create(f *File) error {
+	_, err := s.db.Exec(s.db.Rebind(`INSERT INTO files (id, key_id, filename, purpose, bytes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`), f.ID, f.KeyID, f.Filename, f.Purpose, f.Bytes, f.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create file %s: %w", f.ID, err)
+	}
+	return nil
+}
+
+// Get returns a file description
+func (s *SQLMetadata) Get(id string) (*File, error) {
+	f, err := scanFile(s.db.QueryRow(s.db.Rebind("SELECT id, key_id, filename, purpose, bytes, created_at FROM files WHERE id = ?"), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get file %s: %w", id, err)
+	}
+	return f, nil
+}
+
+// List returns keyID's files newest first
+func (s *SQLMetadata) List(keyID, purpose, after string, limit int) ([]*File, error) {
+	query := "SELECT id, key_id, filename, purpose, bytes, created_at FROM files WHERE key_id = ?"
+	args := []interface{}{keyID}
+	if purpose != "" {
+		query += " AND purpose = ?"
+		args = append(args, purpose)
+	}
+	if after != "" {
+		var createdAt int64
+		err := s.db.QueryRow(s.db.Rebind("SELECT created_at FROM files WHERE id = ?"), after).Scan(&createdAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("list files: %w", err)
+		}
+		if err == nil {
+			query += " AND (created_at < ? OR (created_at = ? AND id < ?))"
+			args = append(args, createdAt, createdAt, after)
+		}
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.db.Query(s.db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+	defer rows.Close()
+	var out []*File
+	for rows.Next() {
+		f, err := scanFile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list files: %w", err)
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+// Delete removes a file description
+func (s *SQLMetadata) Delete(id string) error {
+	res, err := s.db.Exec(s.db.Rebind("DELETE FROM files WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("delete file %s: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanFile(row interface{ Scan(...interface{}) error }) (*File, error) {
+	f := File{Object: "file"}
+	if err := row.Scan(&f.ID, &f.KeyID, &f.Filename, &f.Purpose, &f.Bytes, &f.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// SQLBlobs is a Blobs keeping content in the file_blobs table of the shared
+// database
+type SQLBlobs struct {
+	db *storage.DB
+}
+
+// NewSQLBlobs creates a Blobs on db
+func NewSQLBlobs(db *storage.DB) *SQLBlobs {
+	return &SQLBlobs{db: db}
+}
+
+// Put stores content
+func (s *SQLBlobs) Put(ctx context.Context, id string, content []byte) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind("INSERT INTO file_blobs (id, content) VALUES (?, ?)"), id, content)
+	if err != nil {
+		return fmt.Errorf("put file content %s: %w", id, err)
+	}
+	return nil
+}
+
+// Get returns content
+func (s *SQLBlobs) Get(ctx context.Context, id string) ([]byte, error) {
+	var content []byte
+	err := s.db.QueryRowContext(ctx, s.db.Rebind("SELECT content FROM file_blobs WHERE id = ?"), id).Scan(&content)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get file content %s: %w", id, err)
+	}
+	return content, nil
+}
+
+// Delete removes content
+func (s *SQLBlobs) Delete(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind("DELETE FROM file_blobs WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("delete file content %s: %w", id, err)
+	}
+	return nil
+}
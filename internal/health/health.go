@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Check is a named readiness probe for one dependency. Probes may return
+// per-component details (e.g. one entry per provider) alongside the verdict.
+type Check struct {
+	Name  string
+	Probe func(ctx context.Context) (details map[string]string, err error)
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Status  string            `json:"status"`
+	Error   string            `json:"error,omitempty"`
+	Latency string            `json:"latency"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Report is the readiness summary returned by /readyz
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Status values
+const (
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+// Checker runs readiness checks concurrently
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+// NewChecker creates a Checker; each probe is bounded by timeout
+func NewChecker(checks []Check, timeout time.Duration) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// Run executes all checks and reports ready only if every check passes
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusReady, Checks: make(map[string]Result, len(c.checks))}
+
+	// Failing checks are recorded, not returned, so one failure never cancels the others
+	var (
+		mu sync.Mutex
+		g  errgroup.Group
+	)
+	for _, check := range c.checks {
+		check := check
+		g.Go(func() error {
+			probeCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			details, err := check.Probe(probeCtx)
+			res := Result{Status: StatusOK, Latency: time.Since(start).Round(time.Millisecond).String(), Details: details}
+			if err != nil {
+				res.Status = StatusFailing
+				res.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = res
+			if err != nil {
+				report.Status = StatusNotReady
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return report
+}
@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckerRun(t *testing.T) {
+	ok := Check{Name: "config", Probe: func(ctx context.Context) (map[string]string, error) {
+		return map[string]string{"path": "config.yaml"}, nil
+	}}
+	failing := Check{Name: "storage", Probe: func(ctx context.Context) (map[string]string, error) {
+		return nil, errors.New("connection refused")
+	}}
+	slow := Check{Name: "redis", Probe: func(ctx context.Context) (map[string]string, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+
+	report := NewChecker([]Check{ok}, time.Second).Run(context.Background())
+	if report.Status != StatusReady {
+		t.Errorf("Expected ready, got %s", report.Status)
+	}
+	if report.Checks["config"].Details["path"] != "config.yaml" {
+		t.Errorf("Details not propagated: %+v", report.Checks["config"])
+	}
+
+	report = NewChecker([]Check{ok, failing, slow}, 50*time.Millisecond).Run(context.Background())
+	if report.Status != StatusNotReady {
+		t.Errorf("Expected not_ready, got %s", report.Status)
+	}
+	if r := report.Checks["storage"]; r.Status != StatusFailing || r.Error != "connection refused" {
+		t.Errorf("Unexpected storage result: %+v", r)
+	}
+	if r := report.Checks["redis"]; r.Status != StatusFailing {
+		t.Errorf("Expected slow check to time out, got %+v", r)
+	}
+	if r := report.Checks["config"]; r.Status != StatusOK {
+		t.Errorf("Expected config ok, got %+v", r)
+	}
+}
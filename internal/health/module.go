@@ -0,0 +1,23 @@
+package health
+
+import (
+	"time"
+
+	"go.uber.org/fx"
+)
+
+// Module provides a Checker built from every Check in the "readiness" group.
+// Other modules contribute checks with fx.ResultTags(`group:"readiness"`).
+var Module = fx.Provide(NewCheckerFromGroup)
+
+// CheckerParams collects the readiness checks contributed by other modules
+type CheckerParams struct {
+	fx.In
+
+	Checks []Check `group:"readiness"`
+}
+
+// NewCheckerFromGroup builds the process Checker
+func NewCheckerFromGroup(p CheckerParams) *Checker {
+	return NewChecker(p.Checks, 3*time.Second)
+}
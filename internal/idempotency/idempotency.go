@@ -0,0 +1,172 @@
+// Package idempotency keeps the responses of requests made with an
+// Idempotency-Key, so that retries get the same response and duplicates
+// sent while the first is in flight wait for it instead of calling the
+// upstream again
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/cache"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// keyPrefix sets the responses apart from other entries of the backend
+const keyPrefix = "idempotency:"
+
+// Response is a stored response
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+	// Fingerprint of the request that produced it, so that a key reused
+	// for a different request is told apart from a retry
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Storable reports whether a response with status is kept. Server errors,
+// rate limiting and conflicts are not, so that retries try again.
+func Storable(status int) bool {
+	return status < http.StatusInternalServerError && status != http.StatusTooManyRequests && status != http.StatusConflict
+}
+
+// Store keeps responses in a cache.Cache. Duplicates are coalesced within
+// the replica they reach; once stored, responses are seen by every replica
+// sharing the backend.
+type Store struct {
+	backend          cache.Cache
+	disabled         bool
+	ttl              time.Duration
+	maxResponseBytes int
+
+	mu       sync.Mutex
+	inflight map[string]*call
+}
+
+// call is a request in flight that duplicates wait for
+type call struct {
+	done chan struct{}
+	// Set before done is closed when the response was stored
+	resp *Response
+}
+
+// NewStore keeps responses in backend as configured
+func NewStore(cfg *config.Config, backend cache.Cache) *Store {
+	return &Store{
+		backend:          backend,
+		disabled:         cfg.Idempotency.Disabled,
+		ttl:              cfg.Idempotency.TTL,
+		maxResponseBytes: cfg.Idempotency.MaxResponseBytes,
+		inflight:         make(map[string]*call),
+	}
+}
+
+// Enabled reports whether the Idempotency-Key header is honored
+func (s *Store) Enabled() bool {
+	return !s.disabled
+}
+
+// MaxResponseBytes bounds the responses that are kept
+func (s *Store) MaxResponseBytes() int {
+	return s.maxResponseBytes
+}
+
+// Key is the store key of an idempotency key sent by the caller with the
+// virtual key keyID, so that callers never see each other's responses
+func Key(keyID, idempotencyKey string) string {
+	sum := sha256.Sum256([]byte(idempotencyKey))
+	return scope(keyID) + hex.EncodeToString(sum[:])
+}
+
+// scope is the prefix of the store keys of keyID
+func scope(keyID string) string {
+	sum := sha256.Sum256([]byte(keyID))
+	return keyPrefix + hex.EncodeToString(sum[:8]) + ":"
+}
+
+// Begin returns the stored response under key, waiting for a request in
+// flight with the same key first. Without one, the caller becomes the
+// request in flight and must call finish with its response, or nil when it
+// is not to be kept, once done.
+func (s *Store) Begin(ctx context.Context, key string) (resp *Response, finish func(*Response), err error) {
+	for {
+		s.mu.Lock()
+		if c, ok := s.inflight[key]; ok {
+			s.mu.Unlock()
+			select {
+			case <-c.done:
+				if c.resp != nil {
+					return c.resp, nil, nil
+				}
+				// The response was not kept; take its place
+				continue
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+		}
+		c := &call{done: make(chan struct{})}
+		s.inflight[key] = c
+		s.mu.Unlock()
+
+		if stored, ok := s.get(ctx, key); ok {
+			s.release(key, c, stored)
+			return stored, nil, nil
+		}
+		return nil, func(resp *Response) {
+			if resp != nil && !s.put(context.WithoutCancel(ctx), key, resp) {
+				resp = nil
+			}
+			s.release(key, c, resp)
+		}, nil
+	}
+}
+
+// release hands resp to the duplicates waiting on c
+func (s *Store) release(key string, c *call, resp *Response) {
+	c.resp = resp
+	s.mu.Lock()
+	delete(s.inflight, key)
+	s.mu.Unlock()
+	close(c.done)
+}
+
+func (s *Store) get(ctx context.Context, key string) (*Response, bool) {
+	data, ok, err := s.backend.Get(ctx, key)
+	if err != nil {
+		log.Printf("idempotency: %v", err)
+		return nil, false
+	}
+	var resp Response
+	if !ok || json.Unmarshal(data, &resp) != nil {
+		return nil, false
+	}
+	return &resp, true
+}
+
+// put keeps resp and reports whether it did
+func (s *Store) put(ctx context.Context, key string, resp *Response) bool {
+	if len(resp.Body) > s.maxResponseBytes {
+		return false
+	}
+	data, err := json.Marshal(resp)
+	if err == nil {
+		err = s.backend.Set(ctx, key, data, s.ttl)
+	}
+	if err != nil {
+		log.Printf("idempotency: %v", err)
+		return false
+	}
+	return true
+}
+
+// Erase drops the responses of the virtual key keyID
+func (s *Store) Erase(ctx context.Context, keyID string) error {
+	return s.backend.Clear(ctx, scope(keyID))
+}
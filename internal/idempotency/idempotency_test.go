@@ -0,0 +1,89 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/cache"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func newTestStore() *Store {
+	cfg := &config.Config{}
+	cfg.Idempotency.TTL = time.Hour
+	cfg.Idempotency.MaxResponseBytes = 16
+	return NewStore(cfg, cache.NewMemory(100, 1<<20))
+}
+
+func TestStore(t *testing.T) {
+	s := newTestStore()
+	ctx := context.Background()
+	key := Key("team-a", "retry-1")
+
+	resp, finish, err := s.Begin(ctx, key)
+	if err != nil || resp != nil || finish == nil {
+		t.Fatalf("Expected to run the first request, got %v %v", resp, err)
+	}
+	// A duplicate waits for the first request
+	waited := make(chan *Response)
+	go func() {
+		resp, _, _ := s.Begin(ctx, key)
+		waited <- resp
+	}()
+	select {
+	case <-waited:
+		t.Fatal("Expected the duplicate to wait")
+	case <-time.After(20 * time.Millisecond):
+	}
+	finish(&Response{Status: http.StatusOK, Body: []byte("ok"), Fingerprint: "f"})
+	if resp := <-waited; resp == nil || string(resp.Body) != "ok" {
+		t.Fatalf("Expected the first response, got %+v", resp)
+	}
+	if resp, _, _ := s.Begin(ctx, key); resp == nil || resp.Fingerprint != "f" {
+		t.Errorf("Expected the stored response, got %+v", resp)
+	}
+
+	// Responses that are not kept leave the next request to run
+	other := Key("team-a", "retry-2")
+	_, finish, _ = s.Begin(ctx, other)
+	finish(&Response{Status: http.StatusOK, Body: []byte("larger than sixteen bytes")})
+	if resp, finish, _ := s.Begin(ctx, other); resp != nil || finish == nil {
+		t.Errorf("Expected an oversized response not kept, got %+v", resp)
+	} else {
+		finish(nil)
+	}
+
+	// Waiting ends with the caller
+	_, finish, _ = s.Begin(ctx, other)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, _, err := s.Begin(cancelled, other); err == nil {
+		t.Error("Expected a cancelled duplicate to stop waiting")
+	}
+	finish(nil)
+
+	// Erasure drops the responses of one key only
+	_, finish, _ = s.Begin(ctx, Key("team-b", "retry-1"))
+	finish(&Response{Status: http.StatusOK, Body: []byte("b")})
+	if err := s.Erase(ctx, "team-a"); err != nil {
+		t.Fatalf("Erase failed: %v", err)
+	}
+	if resp, finish, _ := s.Begin(ctx, key); resp != nil {
+		t.Error("Expected team-a's response erased")
+	} else {
+		finish(nil)
+	}
+	if resp, _, _ := s.Begin(ctx, Key("team-b", "retry-1")); resp == nil {
+		t.Error("Expected team-b's response kept")
+	}
+}
+
+func TestStorable(t *testing.T) {
+	for status, want := range map[int]bool{200: true, 400: true, 409: false, 429: false, 500: false, 502: false} {
+		if got := Storable(status); got != want {
+			t.Errorf("Storable(%d) = %v, expected %v", status, got, want)
+		}
+	}
+}
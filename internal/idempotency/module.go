@@ -0,0 +1,7 @@
+package idempotency
+
+import "go.uber.org/fx"
+
+// Module provides the idempotency Store, kept in the configured cache
+// backend.
+var Module = fx.Provide(NewStore)
@@ -0,0 +1,115 @@
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Formats of generated identifiers
+const (
+	// FormatULID is 26 characters of Crockford base32 that sort by creation
+	// time, e.g. "01HZX3K8Q4V7N2M5T9B6C1D0EF"
+	FormatULID = "ulid"
+	// FormatUUID is a random (version 4) UUID
+	FormatUUID = "uuid"
+)
+
+// Generator creates unique identifiers
+type Generator interface {
+	New() string
+}
+
+// NewGenerator returns a generator for format; empty means FormatULID
+func NewGenerator(format string) (Generator, error) {
+	switch format {
+	case FormatULID, "":
+		return &ULIDGenerator{now: time.Now}, nil
+	case FormatUUID:
+		return UUIDGenerator{}, nil
+	default:
+		return nil, fmt.Errorf("unknown id format %q", format)
+	}
+}
+
+var defaultGenerator atomic.Value
+
+func init() {
+	SetDefault(&ULIDGenerator{now: time.Now})
+}
+
+// SetDefault replaces the generator used by New
+func SetDefault(g Generator) {
+	defaultGenerator.Store(&g)
+}
+
+// New returns an identifier from the default generator
+func New() string {
+	return (*defaultGenerator.Load().(*Generator)).New()
+}
+
+// crockford is the ULID alphabet
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator creates ULIDs. IDs from one generator are strictly
+// increasing: within a millisecond the random part is incremented.
+type ULIDGenerator struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	lastMS  uint64
+	entropy [10]byte
+}
+
+// New returns the next ULID
+func (g *ULIDGenerator) New() string {
+	g.mu.Lock()
+	ms := uint64(g.now().UnixMilli())
+	if ms > g.lastMS {
+		g.lastMS = ms
+		_, _ = rand.Read(g.entropy[:])
+	} else {
+		// Same millisecond, or the clock went back
+		for i := len(g.entropy) - 1; i >= 0; i-- {
+			g.entropy[i]++
+			if g.entropy[i] != 0 {
+				break
+			}
+		}
+	}
+	var raw [16]byte
+	binary.BigEndian.PutUint16(raw[0:2], uint16(g.lastMS>>32))
+	binary.BigEndian.PutUint32(raw[2:6], uint32(g.lastMS))
+	copy(raw[6:], g.entropy[:])
+	g.mu.Unlock()
+
+	return encodeULID(raw)
+}
+
+// encodeULID writes the 128 bits as 26 base32 digits, most significant first
+func encodeULID(raw [16]byte) string {
+	hi := binary.BigEndian.Uint64(raw[:8])
+	lo := binary.BigEndian.Uint64(raw[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// UUIDGenerator creates random (version 4) UUIDs
+type UUIDGenerator struct{}
+
+// New returns a new UUID
+func (UUIDGenerator) New() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,75 @@
+package ids
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestULIDGenerator(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	g := &ULIDGenerator{now: func() time.Time { return now }}
+
+	ulid := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+	prev := ""
+	for i := 0; i < 1000; i++ {
+		if i == 500 {
+			now = now.Add(time.Millisecond)
+		}
+		id := g.New()
+		if !ulid.MatchString(id) {
+			t.Fatalf("Invalid ULID %q", id)
+		}
+		if id <= prev {
+			t.Fatalf("Expected increasing IDs, got %q after %q", id, prev)
+		}
+		prev = id
+	}
+
+	// The first 10 characters encode the timestamp
+	if got := g.New()[:10]; got != "01HF7YAT01" {
+		t.Errorf("Unexpected timestamp encoding %q", got)
+	}
+}
+
+func TestUUIDGenerator(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if id := (UUIDGenerator{}).New(); !uuid.MatchString(id) {
+		t.Errorf("Invalid UUID %q", id)
+	}
+}
+
+func TestDefaultGenerator(t *testing.T) {
+	defer SetDefault(&ULIDGenerator{now: time.Now})
+
+	g, err := NewGenerator(FormatUUID)
+	if err != nil {
+		t.Fatalf("NewGenerator: %v", err)
+	}
+	SetDefault(g)
+
+	var wg sync.WaitGroup
+	seen := make(chan string, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seen <- New()
+		}()
+	}
+	wg.Wait()
+	close(seen)
+
+	unique := make(map[string]bool)
+	for id := range seen {
+		if len(id) != 36 || unique[id] {
+			t.Fatalf("Unexpected or duplicate ID %q", id)
+		}
+		unique[id] = true
+	}
+
+	if _, err := NewGenerator("snowflake"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
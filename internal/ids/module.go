@@ -0,0 +1,18 @@
+package ids
+
+import (
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"go.uber.org/fx"
+)
+
+// Module provides the configured Generator and makes it the default, so IDs
+// minted outside the dependency graph use the same format
+var Module = fx.Options(
+	fx.Provide(NewFromConfig),
+	fx.Invoke(SetDefault),
+)
+
+// NewFromConfig returns the generator for the configured format
+func NewFromConfig(cfg *config.Config) (Generator, error) {
+	return NewGenerator(cfg.IDs.Format)
+}
@@ -0,0 +1,105 @@
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound is returned when a key does not exist in the store
+var ErrNotFound = errors.New("key not found")
+
+// Key is a gateway-issued virtual API key. Only the SHA-256 hash of the
+// secret is ever stored.
+type Key struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Hash      string    `json:"hash"`
+	Disabled  bool      `json:"disabled,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Budget    *Budget   `json:"budget,omitempty"`
+	Limits    *Limits   `json:"limits,omitempty"`
+	// ModelAliases maps model names the key's callers use to the models
+	// they currently stand for
+	ModelAliases map[string]string `json:"model_aliases,omitempty"`
+	// Priority class of the key's requests under concurrency limits
+	// (config.PriorityHigh, PriorityNormal or PriorityLow; empty is normal)
+	Priority string `json:"priority,omitempty"`
+	// Owner is who the key was issued to, e.g. a team or a person
+	Owner string   `json:"owner,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	// ExpiresAt is when the key stops being accepted; nil never expires
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the key has expired at now
+func (k *Key) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Budget caps the estimated spend of a key
+type Budget struct {
+	DailyUSD   float64 `json:"daily_usd,omitempty"`
+	MonthlyUSD float64 `json:"monthly_usd,omitempty"`
+}
+
+// Limits caps the request rate of a key
+type Limits struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+	TokensPerMinute   int `json:"tokens_per_minute,omitempty"`
+}
+
+// ResolveModel returns the model an alias of the key stands for, and
+// whether model was an alias; aliases do not chain
+func (k *Key) ResolveModel(model string) (string, bool) {
+	target, ok := k.ModelAliases[model]
+	if !ok {
+		return model, false
+	}
+	return target, true
+}
+
+// ValidateModelAliases rejects aliases without a target or naming themselves
+func ValidateModelAliases(aliases map[string]string) error {
+	for alias, target := range aliases {
+		if alias == "" || target == "" {
+			return fmt.Errorf("model alias %q: alias and target are required", alias)
+		}
+		if alias == target {
+			return fmt.Errorf("model alias %q: refers to itself", alias)
+		}
+	}
+	return nil
+}
+
+// Store persists virtual keys
+type Store interface {
+	Get(id string) (*Key, error)
+	GetByHash(hash string) (*Key, error)
+	List() ([]*Key, error)
+	Put(key *Key) error
+	Delete(id string) error
+}
+
+// secretPrefix marks secrets issued by the gateway, so leaked ones are easy
+// to spot
+const secretPrefix = "sk-letllm-"
+
+// NewSecret returns a random key secret
+func NewSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate key secret: %w", err)
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashSecret returns the hex-encoded SHA-256 hash of a key secret
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,80 @@
+package keys
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryStore is an in-memory Store
+type MemoryStore struct {
+	mu   sync.RWMutex
+	keys map[string]*Key
+}
+
+// NewMemoryStore creates an empty in-memory key store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keys: make(map[string]*Key)}
+}
+
+// Get returns the key with the given ID
+func (s *MemoryStore) Get(id string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.keys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cp := *k
+	return &cp, nil
+}
+
+// GetByHash returns the key whose secret hashes to hash
+func (s *MemoryStore) GetByHash(hash string) (*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.keys {
+		if k.Hash == hash {
+			cp := *k
+			return &cp, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// List returns all keys ordered by ID
+func (s *MemoryStore) List() ([]*Key, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Key, 0, len(s.keys))
+	for _, k := range s.keys {
+		cp := *k
+		out = append(out, &cp)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// Put creates or replaces a key
+func (s *MemoryStore) Put(key *Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cp := *key
+	s.keys[key.ID] = &cp
+	return nil
+}
+
+// Delete removes a key
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.keys, id)
+	return nil
+}
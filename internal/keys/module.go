@@ -0,0 +1,99 @@
+package keys
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+	"go.uber.org/fx"
+)
+
+// Module provides the virtual key Store seeded from configuration.
+var Module = fx.Provide(NewStore)
+
+// NewStore creates the key store and loads keys declared in the config. Keys
+// live in the shared database when one is configured, in memory otherwise.
+func NewStore(cfg *config.Config, db *storage.DB) (Store, error) {
+	var s Store = NewMemoryStore()
+	if db != nil {
+		s = NewSQLStore(db)
+	}
+
+	for i, kc := range cfg.Keys {
+		if kc.ID == "" || kc.Key == "" {
+			return nil, fmt.Errorf("keys[%d]: id and key are required", i)
+		}
+		if err := ValidateModelAliases(kc.ModelAliases); err != nil {
+			return nil, fmt.Errorf("keys[%d]: %w", i, err)
+		}
+		if !config.ValidPriority(kc.Priority) {
+			return nil, fmt.Errorf("keys[%d]: unknown priority %q", i, kc.Priority)
+		}
+		k := &Key{
+			ID:           kc.ID,
+			Name:         kc.Name,
+			Hash:         HashSecret(kc.Key),
+			CreatedAt:    time.Now().UTC(),
+			ModelAliases: kc.ModelAliases,
+			Priority:     kc.Priority,
+			Owner:        kc.Owner,
+			Tags:         kc.Tags,
+			ExpiresAt:    kc.ExpiresAt,
+		}
+		if kc.DailyBudgetUSD > 0 || kc.MonthlyBudgetUSD > 0 {
+			k.Budget = &Budget{DailyUSD: kc.DailyBudgetUSD, MonthlyUSD: kc.MonthlyBudgetUSD}
+		}
+		if kc.RequestsPerMinute > 0 || kc.TokensPerMinute > 0 {
+			k.Limits = &Limits{RequestsPerMinute: kc.RequestsPerMinute, TokensPerMinute: kc.TokensPerMinute}
+		}
+		if existing, err := s.Get(kc.ID); err == nil {
+			// Keys revoked or re-budgeted through the admin API stay so
+			// across restarts; the config only fills what they lack
+			k.CreatedAt = existing.CreatedAt
+			k.Disabled = existing.Disabled
+			k.Budget = mergeBudget(existing.Budget, k.Budget)
+			k.Limits = mergeLimits(existing.Limits, k.Limits)
+		}
+		if err := s.Put(k); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// mergeBudget returns the stored budget with the amounts it lacks taken
+// from the configured one
+func mergeBudget(stored, configured *Budget) *Budget {
+	if stored == nil {
+		return configured
+	}
+	b := *stored
+	if configured != nil {
+		if b.DailyUSD == 0 {
+			b.DailyUSD = configured.DailyUSD
+		}
+		if b.MonthlyUSD == 0 {
+			b.MonthlyUSD = configured.MonthlyUSD
+		}
+	}
+	return &b
+}
+
+// mergeLimits returns the stored limits with the rates they lack taken
+// from the configured ones
+func mergeLimits(stored, configured *Limits) *Limits {
+	if stored == nil {
+		return configured
+	}
+	l := *stored
+	if configured != nil {
+		if l.RequestsPerMinute == 0 {
+			l.RequestsPerMinute = configured.RequestsPerMinute
+		}
+		if l.TokensPerMinute == 0 {
+			l.TokensPerMinute = configured.TokensPerMinute
+		}
+	}
+	return &l
+}
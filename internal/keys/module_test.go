@@ -0,0 +1,43 @@
+package keys
+
+import (
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/storage/storagetest"
+)
+
+func TestNewStoreKeepsAdminChanges(t *testing.T) {
+	db := storagetest.Open(t)
+	cfg := &config.Config{}
+	cfg.Keys = []config.KeyConfig{{ID: "team-a", Key: "sk-a", DailyBudgetUSD: 5, RequestsPerMinute: 60}}
+	s, err := NewStore(cfg, db)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+
+	// Revoke the key and raise its budget, as the admin API does
+	k, _ := s.Get("team-a")
+	k.Disabled = true
+	k.Budget = &Budget{DailyUSD: 20}
+	if err := s.Put(k); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	// Restart, now with a monthly budget configured as well
+	cfg.Keys[0].MonthlyBudgetUSD = 100
+	s, err = NewStore(cfg, db)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	got, _ := s.Get("team-a")
+	if !got.Disabled {
+		t.Error("Expected the revoked key to stay revoked")
+	}
+	if got.Budget == nil || got.Budget.DailyUSD != 20 || got.Budget.MonthlyUSD != 100 {
+		t.Errorf("Expected the admin budget kept and the monthly one filled in, got %+v", got.Budget)
+	}
+	if got.Limits == nil || got.Limits.RequestsPerMinute != 60 {
+		t.Errorf("Unexpected limits: %+v", got.Limits)
+	}
+}
@@ -0,0 +1,170 @@
+package keys
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+)
+
+// SQLStore is a Store backed by the shared database
+type SQLStore struct {
+	db *storage.DB
+}
+
+// NewSQLStore creates a key store on db; the api_keys table is created by
+// the storage migrations
+func NewSQLStore(db *storage.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+const keyColumns = "id, name, hash, disabled, created_at, budget, limits, model_aliases, priority, owner, tags, expires_at"
+
+// Get returns the key with the given ID
+func (s *SQLStore) Get(id string) (*Key, error) {
+	return s.queryOne("SELECT "+keyColumns+" FROM api_keys WHERE id = ?", id)
+}
+
+// GetByHash returns the key whose secret hashes to hash
+func (s *SQLStore) GetByHash(hash string) (*Key, error) {
+	return s.queryOne("SELECT "+keyColumns+" FROM api_keys WHERE hash = ?", hash)
+}
+
+// List returns all keys ordered by ID
+func (s *SQLStore) List() ([]*Key, error) {
+	rows, err := s.db.Query("SELECT " + keyColumns + " FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("list keys: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*Key
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// Put creates or replaces a key
+func (s *SQLStore) Put(key *Key) error {
+	budget, err := marshalNullable(key.Budget)
+	if err != nil {
+		return err
+	}
+	limits, err := marshalNullable(key.Limits)
+	if err != nil {
+		return err
+	}
+	var aliases sql.NullString
+	if len(key.ModelAliases) > 0 {
+		if aliases, err = marshalNullable(&key.ModelAliases); err != nil {
+			return err
+		}
+	}
+	var tags sql.NullString
+	if len(key.Tags) > 0 {
+		if tags, err = marshalNullable(&key.Tags); err != nil {
+			return err
+		}
+	}
+	var expiresAt sql.NullTime
+	if key.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: key.ExpiresAt.UTC(), Valid: true}
+	}
+	createdAt := key.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+
+	_, err = s.db.Exec(s.db.Rebind(`INSERT INTO api_keys (`+keyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, hash = excluded.hash, disabled = excluded.disabled,
+			created_at = excluded.created_at, budget = excluded.budget, limits = excluded.limits,
+			model_aliases = excluded.model_aliases, priority = excluded.priority, owner = excluded.owner,
+			tags = excluded.tags, expires_at = excluded.expires_at`),
+		key.ID, key.Name, key.Hash, key.Disabled, createdAt.UTC(), budget, limits, aliases, key.Priority, key.Owner, tags, expiresAt)
+	if err != nil {
+		return fmt.Errorf("put key %s: %w", key.ID, err)
+	}
+	return nil
+}
+
+// Delete removes a key
+func (s *SQLStore) Delete(id string) error {
+	res, err := s.db.Exec(s.db.Rebind("DELETE FROM api_keys WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("delete key %s: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) queryOne(query string, arg string) (*Key, error) {
+	k, err := scanKey(s.db.QueryRow(s.db.Rebind(query), arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return k, err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanKey(row scanner) (*Key, error) {
+	var (
+		k                             Key
+		budget, limits, aliases, tags sql.NullString
+		expiresAt                     sql.NullTime
+	)
+	if err := row.Scan(&k.ID, &k.Name, &k.Hash, &k.Disabled, &k.CreatedAt, &budget, &limits, &aliases, &k.Priority, &k.Owner, &tags, &expiresAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		t := expiresAt.Time.UTC()
+		k.ExpiresAt = &t
+	}
+	if budget.Valid {
+		k.Budget = &Budget{}
+		if err := json.Unmarshal([]byte(budget.String), k.Budget); err != nil {
+			return nil, fmt.Errorf("decode budget for key %s: %w", k.ID, err)
+		}
+	}
+	if limits.Valid {
+		k.Limits = &Limits{}
+		if err := json.Unmarshal([]byte(limits.String), k.Limits); err != nil {
+			return nil, fmt.Errorf("decode limits for key %s: %w", k.ID, err)
+		}
+	}
+	if aliases.Valid {
+		if err := json.Unmarshal([]byte(aliases.String), &k.ModelAliases); err != nil {
+			return nil, fmt.Errorf("decode model aliases for key %s: %w", k.ID, err)
+		}
+	}
+	if tags.Valid {
+		if err := json.Unmarshal([]byte(tags.String), &k.Tags); err != nil {
+			return nil, fmt.Errorf("decode tags for key %s: %w", k.ID, err)
+		}
+	}
+	return &k, nil
+}
+
+// marshalNullable encodes v as JSON, or SQL NULL when v is a nil pointer
+func marshalNullable[T any](v *T) (sql.NullString, error) {
+	if v == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
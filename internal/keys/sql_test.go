@@ -0,0 +1,71 @@
+package keys
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/storage/storagetest"
+)
+
+func TestSQLStore(t *testing.T) {
+	s := NewSQLStore(storagetest.Open(t))
+
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	key := &Key{ID: "team-a", Name: "Team A", Hash: HashSecret("sk-a"), CreatedAt: created, Budget: &Budget{MonthlyUSD: 50}}
+	if err := s.Put(key); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	got, err := s.GetByHash(HashSecret("sk-a"))
+	if err != nil {
+		t.Fatalf("GetByHash failed: %v", err)
+	}
+	if got.ID != "team-a" || got.Name != "Team A" || !got.CreatedAt.Equal(created) {
+		t.Errorf("Unexpected key: %+v", got)
+	}
+	if got.Budget == nil || got.Budget.MonthlyUSD != 50 || got.Limits != nil {
+		t.Errorf("Unexpected budget/limits: %+v %+v", got.Budget, got.Limits)
+	}
+
+	// Put replaces existing keys
+	key.Disabled = true
+	key.Limits = &Limits{TokensPerMinute: 1000}
+	key.ModelAliases = map[string]string{"prod-chat": "gpt-4o"}
+	key.Priority = config.PriorityHigh
+	expires := created.AddDate(1, 0, 0)
+	key.Owner, key.Tags, key.ExpiresAt = "alice", []string{"search", "prod"}, &expires
+	if err := s.Put(key); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	got, _ = s.Get("team-a")
+	if !got.Disabled || got.Limits == nil || got.Limits.TokensPerMinute != 1000 || got.ModelAliases["prod-chat"] != "gpt-4o" || got.Priority != config.PriorityHigh {
+		t.Errorf("Update not applied: %+v", got)
+	}
+	if got.Owner != "alice" || len(got.Tags) != 2 || got.Tags[1] != "prod" || got.ExpiresAt == nil || !got.ExpiresAt.Equal(expires) {
+		t.Errorf("Metadata not applied: %+v", got)
+	}
+	if got.Expired(expires.Add(-time.Second)) || !got.Expired(expires) {
+		t.Errorf("Expected the key to expire at %v", expires)
+	}
+
+	_ = s.Put(&Key{ID: "team-b", Hash: HashSecret("sk-b")})
+	if b, _ := s.Get("team-b"); b.ExpiresAt != nil || b.Tags != nil || b.Expired(time.Now()) {
+		t.Errorf("Expected a key without metadata, got %+v", b)
+	}
+	all, err := s.List()
+	if err != nil || len(all) != 2 || all[0].ID != "team-a" {
+		t.Errorf("Unexpected list %v: %v", all, err)
+	}
+
+	if err := s.Delete("team-b"); err != nil {
+		t.Errorf("Delete failed: %v", err)
+	}
+	if _, err := s.Get("team-b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if err := s.Delete("team-b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting missing key, got %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package keys
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// ExportVersion is the current bulk export format version
+const ExportVersion = 1
+
+// Export is the portable JSON document produced by bulk key export
+type Export struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Keys       []*Key    `json:"keys"`
+}
+
+// ImportResult summarizes a bulk import
+type ImportResult struct {
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+	Skipped int      `json:"skipped"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// ExportAll snapshots every key in the store
+func ExportAll(s Store, now time.Time) (*Export, error) {
+	all, err := s.List()
+	if err != nil {
+		return nil, fmt.Errorf("list keys: %w", err)
+	}
+	return &Export{Version: ExportVersion, ExportedAt: now.UTC(), Keys: all}, nil
+}
+
+// Import writes keys into the store. Existing keys are left untouched
+// unless overwrite is set. A key whose secret hash already belongs to a
+// key with another ID is reported as an error and not written, since the
+// secret could then authenticate only one of them.
+func Import(s Store, in []*Key, overwrite bool) (*ImportResult, error) {
+	res := &ImportResult{}
+	for i, k := range in {
+		if k == nil || k.ID == "" || k.Hash == "" {
+			res.Errors = append(res.Errors, fmt.Sprintf("keys[%d]: id and hash are required", i))
+			continue
+		}
+		if err := ValidateModelAliases(k.ModelAliases); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("keys[%d]: %v", i, err))
+			continue
+		}
+		if !config.ValidPriority(k.Priority) {
+			res.Errors = append(res.Errors, fmt.Sprintf("keys[%d]: unknown priority %q", i, k.Priority))
+			continue
+		}
+
+		_, err := s.Get(k.ID)
+		exists := err == nil
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return res, fmt.Errorf("lookup key %s: %w", k.ID, err)
+		}
+		if exists && !overwrite {
+			res.Skipped++
+			continue
+		}
+		other, err := s.GetByHash(k.Hash)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return res, fmt.Errorf("lookup hash of key %s: %w", k.ID, err)
+		}
+		if err == nil && other.ID != k.ID {
+			res.Errors = append(res.Errors, fmt.Sprintf("keys[%d]: secret hash already used by key %s", i, other.ID))
+			continue
+		}
+
+		if k.CreatedAt.IsZero() {
+			k.CreatedAt = time.Now().UTC()
+		}
+		if err := s.Put(k); err != nil {
+			return res, fmt.Errorf("store key %s: %w", k.ID, err)
+		}
+		if exists {
+			res.Updated++
+		} else {
+			res.Created++
+		}
+	}
+	return res, nil
+}
+
+// LiteLLMKey mirrors a LiteLLM verification token record as returned by its
+// key listing APIs. LiteLLM stores the SHA-256 hex of the secret in Token,
+// which is the same hashing scheme used here, so migrated keys keep working.
+type LiteLLMKey struct {
+	Token          string     `json:"token"`
+	KeyAlias       *string    `json:"key_alias"`
+	KeyName        *string    `json:"key_name"`
+	MaxBudget      *float64   `json:"max_budget"`
+	BudgetDuration *string    `json:"budget_duration"`
+	RPMLimit       *int       `json:"rpm_limit"`
+	TPMLimit       *int       `json:"tpm_limit"`
+	Blocked        *bool      `json:"blocked"`
+	CreatedAt      *time.Time `json:"created_at"`
+	// Model aliases scoped to the key
+	Aliases map[string]string `json:"aliases"`
+}
+
+// FromLiteLLM converts LiteLLM key records into gateway keys
+func FromLiteLLM(in []LiteLLMKey) ([]*Key, error) {
+	out := make([]*Key, 0, len(in))
+	for i, lk := range in {
+		if lk.Token == "" {
+			return nil, fmt.Errorf("keys[%d]: token is required", i)
+		}
+
+		k := &Key{
+			ID:   "litellm-" + shortHash(lk.Token),
+			Hash: lk.Token,
+		}
+		if lk.KeyAlias != nil && *lk.KeyAlias != "" {
+			k.ID = *lk.KeyAlias
+			k.Name = *lk.KeyAlias
+		}
+		if lk.KeyName != nil && k.Name == "" {
+			k.Name = *lk.KeyName
+		}
+		if lk.Blocked != nil {
+			k.Disabled = *lk.Blocked
+		}
+		if lk.CreatedAt != nil {
+			k.CreatedAt = lk.CreatedAt.UTC()
+		}
+
+		if lk.MaxBudget != nil && *lk.MaxBudget > 0 {
+			duration := "30d"
+			if lk.BudgetDuration != nil && *lk.BudgetDuration != "" {
+				duration = *lk.BudgetDuration
+			}
+			days, err := parseBudgetDays(duration)
+			if err != nil {
+				return nil, fmt.Errorf("keys[%d]: %w", i, err)
+			}
+			if days <= 1 {
+				k.Budget = &Budget{DailyUSD: *lk.MaxBudget}
+			} else {
+				k.Budget = &Budget{MonthlyUSD: *lk.MaxBudget}
+			}
+		}
+
+		if lk.RPMLimit != nil || lk.TPMLimit != nil {
+			k.Limits = &Limits{}
+			if lk.RPMLimit != nil {
+				k.Limits.RequestsPerMinute = *lk.RPMLimit
+			}
+			if lk.TPMLimit != nil {
+				k.Limits.TokensPerMinute = *lk.TPMLimit
+			}
+		}
+
+		if len(lk.Aliases) > 0 {
+			k.ModelAliases = lk.Aliases
+		}
+
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+// parseBudgetDays converts LiteLLM durations such as "1d", "24h" or "1mo"
+// into a number of days
+func parseBudgetDays(s string) (float64, error) {
+	units := []struct {
+		suffix string
+		days   float64
+	}{
+		{"mo", 30},
+		{"d", 1},
+		{"h", 1.0 / 24},
+		{"m", 1.0 / 1440},
+		{"s", 1.0 / 86400},
+	}
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(s, u.suffix), 64)
+			if err != nil {
+				break
+			}
+			return n * u.days, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid budget_duration %q", s)
+}
+
+func shortHash(h string) string {
+	if len(h) > 12 {
+		return h[:12]
+	}
+	return h
+}
@@ -0,0 +1,109 @@
+package keys
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := NewMemoryStore()
+	_ = src.Put(&Key{ID: "team-a", Hash: HashSecret("sk-a"), Budget: &Budget{DailyUSD: 5}})
+	_ = src.Put(&Key{ID: "team-b", Hash: HashSecret("sk-b"), Limits: &Limits{RequestsPerMinute: 60}})
+
+	export, err := ExportAll(src, time.Now())
+	if err != nil {
+		t.Fatalf("ExportAll failed: %v", err)
+	}
+	if export.Version != ExportVersion || len(export.Keys) != 2 {
+		t.Fatalf("Unexpected export: %+v", export)
+	}
+
+	dst := NewMemoryStore()
+	_ = dst.Put(&Key{ID: "team-a", Hash: "old"})
+
+	res, err := Import(dst, export.Keys, false)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if res.Created != 1 || res.Skipped != 1 {
+		t.Errorf("Expected 1 created and 1 skipped, got %+v", res)
+	}
+	if k, _ := dst.Get("team-a"); k.Hash != "old" {
+		t.Error("Existing key should not be overwritten without overwrite")
+	}
+
+	res, err = Import(dst, export.Keys, true)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if res.Updated != 2 {
+		t.Errorf("Expected 2 updated, got %+v", res)
+	}
+	k, err := dst.GetByHash(HashSecret("sk-a"))
+	if err != nil {
+		t.Fatalf("Imported key should be found by hash: %v", err)
+	}
+	if k.Budget == nil || k.Budget.DailyUSD != 5 {
+		t.Errorf("Budget not preserved: %+v", k.Budget)
+	}
+
+	res, _ = Import(dst, []*Key{{ID: "no-hash"}}, false)
+	if len(res.Errors) != 1 {
+		t.Errorf("Expected validation error, got %+v", res)
+	}
+
+	res, err = Import(dst, []*Key{{ID: "team-c", Hash: HashSecret("sk-a")}}, true)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if res.Created != 0 || len(res.Errors) != 1 || !strings.Contains(res.Errors[0], "team-a") {
+		t.Errorf("Expected the reused secret hash reported, got %+v", res)
+	}
+	if _, err := dst.Get("team-c"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the key with a reused hash not stored, got %v", err)
+	}
+}
+
+func TestFromLiteLLM(t *testing.T) {
+	alias := "prod-app"
+	budget := 10.0
+	daily := "1d"
+	rpm := 100
+	blocked := true
+
+	out, err := FromLiteLLM([]LiteLLMKey{
+		{Token: HashSecret("sk-1234"), KeyAlias: &alias, MaxBudget: &budget, BudgetDuration: &daily, RPMLimit: &rpm,
+			Aliases: map[string]string{"prod-chat": "gpt-4o"}},
+		{Token: "abcdef0123456789", MaxBudget: &budget, Blocked: &blocked},
+	})
+	if err != nil {
+		t.Fatalf("FromLiteLLM failed: %v", err)
+	}
+
+	if out[0].ID != "prod-app" || out[0].Hash != HashSecret("sk-1234") {
+		t.Errorf("Unexpected first key: %+v", out[0])
+	}
+	if out[0].Budget == nil || out[0].Budget.DailyUSD != 10 {
+		t.Errorf("Expected daily budget, got %+v", out[0].Budget)
+	}
+	if out[0].Limits == nil || out[0].Limits.RequestsPerMinute != 100 {
+		t.Errorf("Expected rpm limit, got %+v", out[0].Limits)
+	}
+	if out[0].ModelAliases["prod-chat"] != "gpt-4o" || out[1].ModelAliases != nil {
+		t.Errorf("Expected aliases carried over, got %v and %v", out[0].ModelAliases, out[1].ModelAliases)
+	}
+
+	if out[1].ID != "litellm-abcdef012345" || !out[1].Disabled {
+		t.Errorf("Unexpected second key: %+v", out[1])
+	}
+	if out[1].Budget == nil || out[1].Budget.MonthlyUSD != 10 {
+		t.Errorf("Expected monthly budget by default, got %+v", out[1].Budget)
+	}
+
+	bad := "soon"
+	if _, err := FromLiteLLM([]LiteLLMKey{{Token: "x", MaxBudget: &budget, BudgetDuration: &bad}}); err == nil {
+		t.Error("Expected error for invalid budget_duration")
+	}
+}
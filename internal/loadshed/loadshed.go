@@ -0,0 +1,201 @@
+package loadshed
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"go.uber.org/fx"
+)
+
+// Shedding reasons
+const (
+	ReasonMemory     = "memory"
+	ReasonGoroutines = "goroutines"
+)
+
+// Policy holds the thresholds above which new streams are shed; a zero
+// threshold is not checked
+type Policy struct {
+	MaxMemoryBytes    uint64 `json:"max_memory_bytes"`
+	MaxGoroutines     int    `json:"max_goroutines"`
+	RetryAfterSeconds int    `json:"retry_after_seconds"`
+}
+
+// Validate checks the policy's values
+func (p Policy) Validate() error {
+	if p.MaxGoroutines < 0 {
+		return fmt.Errorf("max_goroutines must not be negative")
+	}
+	if p.RetryAfterSeconds <= 0 {
+		return fmt.Errorf("retry_after_seconds must be positive")
+	}
+	return nil
+}
+
+// Pressure is one sample of the process's resource use
+type Pressure struct {
+	// MemoryBytes is memory obtained from the OS and not yet returned
+	MemoryBytes uint64    `json:"memory_bytes"`
+	Goroutines  int       `json:"goroutines"`
+	SampledAt   time.Time `json:"sampled_at"`
+}
+
+// Status is the policy, the latest sample and whether requests are shed
+type Status struct {
+	Policy   Policy   `json:"policy"`
+	Pressure Pressure `json:"pressure"`
+	// Reason is why requests are being shed, empty when they are not
+	Reason string `json:"reason,omitempty"`
+}
+
+// ShedError is returned for requests refused under pressure
+type ShedError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *ShedError) Error() string {
+	return fmt.Sprintf("gateway is overloaded (%s), retry later", e.Reason)
+}
+
+// Shedder samples memory and goroutine counts and refuses new streaming
+// requests while a threshold is exceeded. Checks use the latest sample, so
+// they cost no more than a lock.
+type Shedder struct {
+	read func() Pressure
+
+	memory     *metrics.GaugeVec
+	goroutines *metrics.GaugeVec
+	shed       *metrics.CounterVec
+
+	mu       sync.RWMutex
+	policy   Policy
+	pressure Pressure
+}
+
+// NewShedder creates a shedder sampling for the app's lifetime
+func NewShedder(lc fx.Lifecycle, cfg *config.Config, m *metrics.Registry) *Shedder {
+	s := New(cfg, m)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				s.run(ctx, cfg.LoadShedding.SampleInterval)
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			<-done
+			return nil
+		},
+	})
+	return s
+}
+
+// New creates a shedder that samples only when Sample is called
+func New(cfg *config.Config, m *metrics.Registry) *Shedder {
+	ls := cfg.LoadShedding
+	return &Shedder{
+		read: readPressure,
+		policy: Policy{
+			MaxMemoryBytes:    ls.MaxMemoryBytes,
+			MaxGoroutines:     ls.MaxGoroutines,
+			RetryAfterSeconds: int(math.Ceil(ls.RetryAfter.Seconds())),
+		},
+		memory: m.Gauge("letllm_process_memory_bytes",
+			"Memory obtained from the OS and not yet returned to it."),
+		goroutines: m.Gauge("letllm_process_goroutines",
+			"Live goroutines."),
+		shed: m.Counter("letllm_load_shed_total",
+			"Streaming requests refused under memory or goroutine pressure.", "reason"),
+	}
+}
+
+func (s *Shedder) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.Sample()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readPressure samples the Go runtime
+func readPressure() Pressure {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return Pressure{
+		MemoryBytes: ms.Sys - ms.HeapReleased,
+		Goroutines:  runtime.NumGoroutine(),
+		SampledAt:   time.Now().UTC(),
+	}
+}
+
+// Sample takes a new pressure sample and exports it
+func (s *Shedder) Sample() {
+	p := s.read()
+	s.memory.Set(float64(p.MemoryBytes))
+	s.goroutines.Set(float64(p.Goroutines))
+
+	s.mu.Lock()
+	s.pressure = p
+	s.mu.Unlock()
+}
+
+// Check returns a ShedError when a new streaming request should be refused,
+// nil otherwise
+func (s *Shedder) Check() *ShedError {
+	s.mu.RLock()
+	reason := s.reasonLocked()
+	retryAfter := time.Duration(s.policy.RetryAfterSeconds) * time.Second
+	s.mu.RUnlock()
+
+	if reason == "" {
+		return nil
+	}
+	s.shed.Inc(reason)
+	return &ShedError{Reason: reason, RetryAfter: retryAfter}
+}
+
+// reasonLocked returns the exceeded threshold, if any; callers must hold s.mu
+func (s *Shedder) reasonLocked() string {
+	switch {
+	case s.policy.MaxMemoryBytes > 0 && s.pressure.MemoryBytes > s.policy.MaxMemoryBytes:
+		return ReasonMemory
+	case s.policy.MaxGoroutines > 0 && s.pressure.Goroutines > s.policy.MaxGoroutines:
+		return ReasonGoroutines
+	}
+	return ""
+}
+
+// Status returns the policy and the latest sample
+func (s *Shedder) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Status{Policy: s.policy, Pressure: s.pressure, Reason: s.reasonLocked()}
+}
+
+// SetPolicy replaces the thresholds, taking effect with the next check
+func (s *Shedder) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.policy = p
+	s.mu.Unlock()
+	return nil
+}
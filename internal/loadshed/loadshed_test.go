@@ -0,0 +1,74 @@
+package loadshed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+)
+
+func TestShedderCheck(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.LoadShedding.MaxMemoryBytes = 1 << 30
+	cfg.LoadShedding.MaxGoroutines = 1000
+	cfg.LoadShedding.RetryAfter = 1500 * time.Millisecond
+	m := metrics.NewRegistry()
+	s := New(cfg, m)
+
+	var p Pressure
+	s.read = func() Pressure { return p }
+
+	for name, tc := range map[string]struct {
+		pressure Pressure
+		reason   string
+	}{
+		"idle":            {Pressure{MemoryBytes: 64 << 20, Goroutines: 50}, ""},
+		"at thresholds":   {Pressure{MemoryBytes: 1 << 30, Goroutines: 1000}, ""},
+		"memory":          {Pressure{MemoryBytes: 2 << 30, Goroutines: 50}, ReasonMemory},
+		"goroutines":      {Pressure{MemoryBytes: 64 << 20, Goroutines: 5000}, ReasonGoroutines},
+		"memory prevails": {Pressure{MemoryBytes: 2 << 30, Goroutines: 5000}, ReasonMemory},
+	} {
+		p = tc.pressure
+		s.Sample()
+		shed := s.Check()
+		if (shed == nil) != (tc.reason == "") || (shed != nil && (shed.Reason != tc.reason || shed.RetryAfter != 2*time.Second)) {
+			t.Errorf("%s: expected reason %q, got %+v", name, tc.reason, shed)
+		}
+		if st := s.Status(); st.Reason != tc.reason || st.Pressure.Goroutines != tc.pressure.Goroutines {
+			t.Errorf("%s: unexpected status %+v", name, st)
+		}
+	}
+	p = Pressure{Goroutines: 5000}
+	s.Sample()
+	if v := m.Gauge("letllm_process_goroutines", "").Value(); v != 5000 {
+		t.Errorf("Expected the goroutine gauge to follow samples, got %v", v)
+	}
+	if v := m.Counter("letllm_load_shed_total", "", "reason").Value(ReasonMemory); v != 2 {
+		t.Errorf("Expected 2 memory sheds counted, got %v", v)
+	}
+}
+
+func TestShedderSetPolicy(t *testing.T) {
+	s := New(&config.Config{}, metrics.NewRegistry())
+	s.read = func() Pressure { return Pressure{Goroutines: 200} }
+	s.Sample()
+
+	if s.Check() != nil {
+		t.Fatal("Expected no shedding without thresholds")
+	}
+	if err := s.SetPolicy(Policy{MaxGoroutines: 100, RetryAfterSeconds: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if shed := s.Check(); shed == nil || shed.RetryAfter != 3*time.Second {
+		t.Errorf("Expected the new policy to apply, got %+v", shed)
+	}
+	for name, p := range map[string]Policy{
+		"negative goroutines": {MaxGoroutines: -1, RetryAfterSeconds: 1},
+		"no retry after":      {MaxGoroutines: 1},
+	} {
+		if err := s.SetPolicy(p); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package loadshed
+
+import "go.uber.org/fx"
+
+// Module provides the load Shedder.
+var Module = fx.Provide(NewShedder)
@@ -0,0 +1,213 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds named metric families and renders them in the Prometheus
+// text exposition format
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry creates an empty metrics registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+// CounterVec is a monotonically increasing metric partitioned by labels
+type CounterVec struct{ f *family }
+
+// GaugeVec is a metric that can go up and down, partitioned by labels
+type GaugeVec struct{ f *family }
+
+// Counter returns the counter family with the given name, creating it on first use
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{f: r.family(name, help, "counter", labels)}
+}
+
+// Gauge returns the gauge family with the given name, creating it on first use
+func (r *Registry) Gauge(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{f: r.family(name, help, "gauge", labels)}
+}
+
+func (r *Registry) family(name, help, kind string, labels []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		return f
+	}
+	f := &family{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		series: make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+// Inc increments the counter for the given label values by one
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter for the given label values; negative deltas are ignored
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.f.add(delta, labelValues)
+}
+
+// Value returns the current counter value for the given label values
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	return c.f.get(labelValues)
+}
+
+// Set sets the gauge for the given label values
+func (g *GaugeVec) Set(v float64, labelValues ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	g.f.lookup(labelValues).value = v
+}
+
+// Add adds delta (which may be negative) to the gauge for the given label values
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.f.add(delta, labelValues)
+}
+
+// Value returns the current gauge value for the given label values
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	return g.f.get(labelValues)
+}
+
+// Delete removes the series for the given label values, so it is no
+// longer exported
+func (g *GaugeVec) Delete(labelValues ...string) {
+	g.f.mu.Lock()
+	defer g.f.mu.Unlock()
+	delete(g.f.series, seriesKey(labelValues))
+}
+
+func (f *family) add(delta float64, labelValues []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookup(labelValues).value += delta
+}
+
+func (f *family) get(labelValues []string) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[seriesKey(labelValues)]; ok {
+		return s.value
+	}
+	return 0
+}
+
+// lookup returns the series for labelValues; callers must hold f.mu
+func (f *family) lookup(labelValues []string) *series {
+	key := seriesKey(labelValues)
+	s, ok := f.series[key]
+	if !ok {
+		values := make([]string, len(f.labels))
+		copy(values, labelValues)
+		s = &series{labelValues: values}
+		f.series[key] = s
+	}
+	return s
+}
+
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	for _, f := range families {
+		if err := f.writeText(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *family) writeText(w io.Writer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := f.series[k]
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", f.name, formatLabels(f.labels, s.labelValues), strconv.FormatFloat(s.value, 'g', -1, 64)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCounterAndGauge(t *testing.T) {
+	r := NewRegistry()
+
+	c := r.Counter("requests_total", "Total requests.", "provider")
+	c.Inc("openai")
+	c.Add(2, "openai")
+	c.Add(-5, "openai") // ignored
+	c.Inc("gemini")
+
+	if v := c.Value("openai"); v != 3 {
+		t.Errorf("Expected openai counter 3, got %v", v)
+	}
+
+	// Re-registering returns the same family
+	if v := r.Counter("requests_total", "Total requests.", "provider").Value("gemini"); v != 1 {
+		t.Errorf("Expected gemini counter 1, got %v", v)
+	}
+
+	g := r.Gauge("in_flight", "In-flight requests.")
+	g.Add(2)
+	g.Add(-1)
+	if v := g.Value(); v != 1 {
+		t.Errorf("Expected gauge 1, got %v", v)
+	}
+	g.Set(7)
+	if v := g.Value(); v != 7 {
+		t.Errorf("Expected gauge 7, got %v", v)
+	}
+
+	queued := r.Gauge("queued", "Queued requests.", "provider")
+	queued.Set(3, "openai")
+	queued.Delete("openai")
+	var buf bytes.Buffer
+	_ = r.WriteText(&buf)
+	if strings.Contains(buf.String(), `queued{provider="openai"}`) {
+		t.Errorf("Expected the deleted series gone, got %s", buf.String())
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("b_total", "B help.", "field").Inc(`say "hi"`)
+	r.Gauge("a_gauge", "A help.").Set(1.5)
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"# HELP a_gauge A help.",
+		"# TYPE a_gauge gauge",
+		"a_gauge 1.5",
+		"# HELP b_total B help.",
+		"# TYPE b_total counter",
+		`b_total{field="say \"hi\""} 1`,
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
@@ -0,0 +1,6 @@
+package pii
+
+import "go.uber.org/fx"
+
+// Module provides the PII Reporter.
+var Module = fx.Provide(NewReporter)
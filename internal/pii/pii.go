@@ -0,0 +1,78 @@
+package pii
+
+import (
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Categories of personal data the classifier detects
+const (
+	CategoryEmail      = "email"
+	CategoryPhone      = "phone"
+	CategoryCreditCard = "credit_card"
+	CategorySSN        = "ssn"
+	CategoryIPAddress  = "ip_address"
+)
+
+// Categories lists every category in report order
+var Categories = []string{CategoryEmail, CategoryPhone, CategoryCreditCard, CategorySSN, CategoryIPAddress}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// Separators are required, so that bare numbers such as IDs do not count
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]\d{3}[ .-]\d{4}\b`)
+	cardPattern  = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	ssnPattern   = regexp.MustCompile(`\b(\d{3})-(\d{2})-(\d{4})\b`)
+	ipv4Pattern  = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}\b`)
+)
+
+// Classify counts the personal data found in text by category. Only
+// counts are returned; matched values never leave the classifier.
+func Classify(text string) map[string]int {
+	found := make(map[string]int)
+	if n := len(emailPattern.FindAllString(text, -1)); n > 0 {
+		found[CategoryEmail] = n
+	}
+	if n := len(phonePattern.FindAllString(text, -1)); n > 0 {
+		found[CategoryPhone] = n
+	}
+	for _, m := range cardPattern.FindAllString(text, -1) {
+		if luhnValid(m) {
+			found[CategoryCreditCard]++
+		}
+	}
+	for _, m := range ssnPattern.FindAllStringSubmatch(text, -1) {
+		// Area 000, 666 and 9xx, group 00 and serial 0000 are never issued
+		if m[1] != "000" && m[1] != "666" && m[1][0] != '9' && m[2] != "00" && m[3] != "0000" {
+			found[CategorySSN]++
+		}
+	}
+	for _, m := range ipv4Pattern.FindAllString(text, -1) {
+		if net.ParseIP(m) != nil {
+			found[CategoryIPAddress]++
+		}
+	}
+	return found
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum
+func luhnValid(s string) bool {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+	sum := 0
+	for i := range digits {
+		d := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
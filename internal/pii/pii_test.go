@@ -0,0 +1,84 @@
+package pii
+
+import (
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+)
+
+func TestClassify(t *testing.T) {
+	for name, tc := range map[string]struct {
+		text string
+		want map[string]int
+	}{
+		"clean":       {"Summarise the quarterly report in 200 words.", map[string]int{}},
+		"email":       {"Reply to jane.doe@example.com and ops@example.org", map[string]int{CategoryEmail: 2}},
+		"phone":       {"Call +1 (555) 010-4477 or 555.010.4478", map[string]int{CategoryPhone: 2}},
+		"card":        {"Card 4111 1111 1111 1111 expires soon", map[string]int{CategoryCreditCard: 1}},
+		"not a card":  {"Order 4111 1111 1111 1112 shipped", map[string]int{}},
+		"ssn":         {"SSN 123-45-6789, not 000-12-3456", map[string]int{CategorySSN: 1}},
+		"ip address":  {"Blocked 203.0.113.7 but not 999.1.1.1", map[string]int{CategoryIPAddress: 1}},
+		"bare number": {"Ticket 5550104477 was closed", map[string]int{}},
+	} {
+		got := Classify(tc.text)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: expected %v, got %v", name, tc.want, got)
+			continue
+		}
+		for cat, n := range tc.want {
+			if got[cat] != n {
+				t.Errorf("%s: expected %v, got %v", name, tc.want, got)
+			}
+		}
+	}
+}
+
+func TestReporterRun(t *testing.T) {
+	store := artifacts.NewMemoryStore()
+	put := func(content string, owners ...string) {
+		a, _ := store.Put([]byte(content))
+		for _, o := range owners {
+			_ = store.Claim(a.ID, o)
+		}
+	}
+	put("Contact jane.doe@example.com about 203.0.113.7", "key-a", "key-b")
+	put("Summarise this paragraph", "key-a")
+	put("SSN 123-45-6789")
+
+	cfg := &config.Config{}
+	cfg.PIIReports.SampleSize = 10
+	cfg.PIIReports.Keep = 2
+	m := metrics.NewRegistry()
+	r := newReporter(cfg, store, m)
+
+	rep, err := r.Run()
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if rep.Sampled != 3 || rep.Flagged != 2 || rep.Categories[CategoryEmail] != 1 || rep.Categories[CategorySSN] != 1 {
+		t.Errorf("Unexpected totals %+v", rep)
+	}
+	want := map[string][2]int{"": {1, 1}, "key-a": {2, 1}, "key-b": {1, 1}}
+	if len(rep.Keys) != len(want) {
+		t.Fatalf("Unexpected keys %+v", rep.Keys)
+	}
+	for _, kr := range rep.Keys {
+		if w := want[kr.KeyID]; kr.Sampled != w[0] || kr.Flagged != w[1] {
+			t.Errorf("%q: expected sampled/flagged %v, got %+v", kr.KeyID, w, kr)
+		}
+	}
+	if v := m.Gauge("letllm_pii_sampled_findings", "", "category").Value(CategoryIPAddress); v != 1 {
+		t.Errorf("Expected ip_address gauge 1, got %v", v)
+	}
+
+	_, _ = r.Run()
+	last, _ := r.Run()
+	if reports := r.Reports(0); len(reports) != 2 || reports[0] != last {
+		t.Errorf("Expected the 2 newest reports, newest first, got %d", len(reports))
+	}
+	if reports := r.Reports(1); len(reports) != 1 {
+		t.Errorf("Expected limit to apply, got %d", len(reports))
+	}
+}
@@ -0,0 +1,192 @@
+package pii
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"go.uber.org/fx"
+)
+
+// Report summarises the personal data found in one sample of stored prompt
+// artifacts. Artifacts are the only conversation content the gateway keeps
+// and are not tied to a route, so findings are broken down by the virtual
+// key that uploaded them. Reports hold counts only, never matched values.
+type Report struct {
+	ID          string    `json:"id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Sampled counts the artifacts classified, Flagged those with findings
+	Sampled    int            `json:"sampled"`
+	Flagged    int            `json:"flagged"`
+	Categories map[string]int `json:"categories"`
+	Keys       []KeyReport    `json:"keys"`
+}
+
+// KeyReport holds the findings in artifacts claimed by one virtual key.
+// An artifact claimed by several keys counts for each of them.
+type KeyReport struct {
+	// KeyID is empty for artifacts uploaded without a virtual key
+	KeyID      string         `json:"key_id"`
+	Sampled    int            `json:"sampled"`
+	Flagged    int            `json:"flagged"`
+	Categories map[string]int `json:"categories"`
+}
+
+// Reporter periodically classifies a random sample of stored artifacts and
+// keeps the latest reports in memory
+type Reporter struct {
+	store      artifacts.Store
+	sampleSize int
+	keep       int
+	now        func() time.Time
+
+	findings *metrics.GaugeVec
+
+	mu      sync.Mutex
+	reports []*Report // oldest first
+}
+
+// NewReporter creates a reporter and, when pii_reports.interval is set,
+// runs it for the app's lifetime
+func NewReporter(lc fx.Lifecycle, cfg *config.Config, store artifacts.Store, m *metrics.Registry) *Reporter {
+	r := newReporter(cfg, store, m)
+	if cfg.PIIReports.Interval <= 0 {
+		return r
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				r.run(ctx, cfg.PIIReports.Interval)
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			<-done
+			return nil
+		},
+	})
+	return r
+}
+
+func newReporter(cfg *config.Config, store artifacts.Store, m *metrics.Registry) *Reporter {
+	return &Reporter{
+		store:      store,
+		sampleSize: cfg.PIIReports.SampleSize,
+		keep:       cfg.PIIReports.Keep,
+		now:        time.Now,
+		findings: m.Gauge("letllm_pii_sampled_findings",
+			"Personal data findings in the latest sample of stored prompt artifacts.", "category"),
+	}
+}
+
+func (r *Reporter) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := r.Run(); err != nil {
+			log.Printf("pii report: %v", err)
+		}
+	}
+}
+
+// Run classifies a new sample, stores the report and returns it
+func (r *Reporter) Run() (*Report, error) {
+	sample, err := r.store.Sample(r.sampleSize)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &Report{
+		ID:          "pii_" + ids.New(),
+		GeneratedAt: r.now().UTC(),
+		Categories:  make(map[string]int),
+	}
+	byKey := make(map[string]*KeyReport)
+	for _, a := range sample {
+		content, err := r.store.Get(a.ID)
+		if err != nil {
+			// Erased since it was sampled
+			continue
+		}
+		found := Classify(string(content))
+
+		rep.Sampled++
+		if len(found) > 0 {
+			rep.Flagged++
+		}
+		addFindings(rep.Categories, found)
+
+		owners := a.Owners
+		if len(owners) == 0 {
+			owners = []string{""}
+		}
+		for _, keyID := range owners {
+			kr, ok := byKey[keyID]
+			if !ok {
+				kr = &KeyReport{KeyID: keyID, Categories: make(map[string]int)}
+				byKey[keyID] = kr
+			}
+			kr.Sampled++
+			if len(found) > 0 {
+				kr.Flagged++
+			}
+			addFindings(kr.Categories, found)
+		}
+	}
+
+	rep.Keys = make([]KeyReport, 0, len(byKey))
+	for _, kr := range byKey {
+		rep.Keys = append(rep.Keys, *kr)
+	}
+	sort.Slice(rep.Keys, func(i, j int) bool { return rep.Keys[i].KeyID < rep.Keys[j].KeyID })
+
+	for _, cat := range Categories {
+		r.findings.Set(float64(rep.Categories[cat]), cat)
+	}
+
+	r.mu.Lock()
+	r.reports = append(r.reports, rep)
+	if len(r.reports) > r.keep {
+		r.reports = r.reports[len(r.reports)-r.keep:]
+	}
+	r.mu.Unlock()
+	return rep, nil
+}
+
+// Reports returns up to limit stored reports, newest first; limit <= 0
+// returns all of them
+func (r *Reporter) Reports(limit int) []*Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]*Report, 0, len(r.reports))
+	for i := len(r.reports) - 1; i >= 0; i-- {
+		if limit > 0 && len(out) == limit {
+			break
+		}
+		out = append(out, r.reports[i])
+	}
+	return out
+}
+
+func addFindings(dst, found map[string]int) {
+	for cat, n := range found {
+		dst[cat] += n
+	}
+}
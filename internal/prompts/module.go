@@ -0,0 +1,6 @@
+package prompts
+
+import "go.uber.org/fx"
+
+// Module provides the prompt template Registry.
+var Module = fx.Provide(NewRegistry)
@@ -0,0 +1,152 @@
+package prompts
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// ErrNotFound is returned for templates that are not configured
+var ErrNotFound = errors.New("prompt template not found")
+
+// VariableError reports variables a render is missing or does not know
+type VariableError struct {
+	Template string
+	Missing  []string
+	Unknown  []string
+}
+
+func (e *VariableError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unknown) > 0 {
+		parts = append(parts, "unknown "+strings.Join(e.Unknown, ", "))
+	}
+	return fmt.Sprintf("template %s: variables: %s", e.Template, strings.Join(parts, "; "))
+}
+
+// Template is a configured prompt template with its messages parsed
+type Template struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Model       string     `json:"model,omitempty"`
+	Variables   []Variable `json:"variables"`
+
+	roles    []string
+	contents []*template.Template
+}
+
+// Variable is a variable a template accepts
+type Variable struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Default     *string `json:"default,omitempty"`
+	Required    bool    `json:"required"`
+}
+
+// Registry holds the configured prompt templates
+type Registry struct {
+	templates map[string]*Template
+}
+
+// NewRegistry parses the configured prompt templates
+func NewRegistry(cfg *config.Config) (*Registry, error) {
+	r := &Registry{templates: make(map[string]*Template, len(cfg.PromptTemplates))}
+	for _, pt := range cfg.PromptTemplates {
+		t := &Template{Name: pt.Name, Description: pt.Description, Model: pt.Model, Variables: make([]Variable, len(pt.Variables))}
+		for i, v := range pt.Variables {
+			t.Variables[i] = Variable{Name: v.Name, Description: v.Description, Default: v.Default, Required: v.Default == nil}
+		}
+		// Executing with every variable set catches references to
+		// undeclared ones at startup rather than on requests
+		probe := make(map[string]string, len(pt.Variables))
+		for _, v := range pt.Variables {
+			probe[v.Name] = ""
+		}
+		for i, msg := range pt.Messages {
+			if msg.Role == "" {
+				return nil, fmt.Errorf("prompt template %s: messages[%d]: role is required", pt.Name, i)
+			}
+			content, err := template.New("").Option("missingkey=error").Parse(msg.Content)
+			if err == nil {
+				err = content.Execute(io.Discard, probe)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("prompt template %s: messages[%d]: %w", pt.Name, i, err)
+			}
+			t.roles = append(t.roles, msg.Role)
+			t.contents = append(t.contents, content)
+		}
+		r.templates[pt.Name] = t
+	}
+	return r, nil
+}
+
+// Get returns a template
+func (r *Registry) Get(name string) (*Template, error) {
+	t, ok := r.templates[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return t, nil
+}
+
+// List returns the templates ordered by name
+func (r *Registry) List() []*Template {
+	out := make([]*Template, 0, len(r.templates))
+	for _, t := range r.templates {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Render expands a template's messages with vars, filling in defaults
+func (r *Registry) Render(name string, vars map[string]string) (*Template, []provider.Message, error) {
+	t, err := r.Get(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := make(map[string]string, len(t.Variables))
+	declared := make(map[string]bool, len(t.Variables))
+	verr := &VariableError{Template: name}
+	for _, v := range t.Variables {
+		declared[v.Name] = true
+		switch value, ok := vars[v.Name]; {
+		case ok:
+			data[v.Name] = value
+		case v.Default != nil:
+			data[v.Name] = *v.Default
+		default:
+			verr.Missing = append(verr.Missing, v.Name)
+		}
+	}
+	for name := range vars {
+		if !declared[name] {
+			verr.Unknown = append(verr.Unknown, name)
+		}
+	}
+	if len(verr.Missing) > 0 || len(verr.Unknown) > 0 {
+		sort.Strings(verr.Unknown)
+		return nil, nil, verr
+	}
+
+	messages := make([]provider.Message, len(t.contents))
+	for i, content := range t.contents {
+		var b strings.Builder
+		if err := content.Execute(&b, data); err != nil {
+			return nil, nil, fmt.Errorf("template %s: messages[%d]: %w", name, i, err)
+		}
+		messages[i] = provider.Message{Role: t.roles[i], Content: b.String()}
+	}
+	return t, messages, nil
+}
@@ -0,0 +1,55 @@
+package prompts
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+func TestRender(t *testing.T) {
+	tone := "friendly"
+	cfg := &config.Config{PromptTemplates: []config.PromptTemplate{{
+		Name:  "support_agent",
+		Model: "demo-chat",
+		Variables: []config.TemplateVariable{
+			{Name: "customer"},
+			{Name: "tone", Default: &tone},
+		},
+		Messages: []config.TemplateMessage{
+			{Role: "system", Content: "You help {{.customer}} in a {{.tone}} tone."},
+		},
+	}}}
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, messages, err := r.Render("support_agent", map[string]string{"customer": "Acme"})
+	want := []provider.Message{{Role: "system", Content: "You help Acme in a friendly tone."}}
+	if err != nil || tmpl.Model != "demo-chat" || !reflect.DeepEqual(messages, want) {
+		t.Errorf("Expected %+v, got %+v, %v", want, messages, err)
+	}
+
+	var verr *VariableError
+	_, _, err = r.Render("support_agent", map[string]string{"tone": "curt", "mood": "sad"})
+	if !errors.As(err, &verr) || !reflect.DeepEqual(verr.Missing, []string{"customer"}) || !reflect.DeepEqual(verr.Unknown, []string{"mood"}) {
+		t.Errorf("Expected customer missing and mood unknown, got %v", err)
+	}
+
+	if _, _, err := r.Render("billing", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestNewRegistryRejectsUndeclaredVariables(t *testing.T) {
+	cfg := &config.Config{PromptTemplates: []config.PromptTemplate{{
+		Name:     "support_agent",
+		Messages: []config.TemplateMessage{{Role: "system", Content: "You help {{.customer}}."}},
+	}}}
+	if _, err := NewRegistry(cfg); err == nil {
+		t.Error("Expected an error for a reference to an undeclared variable")
+	}
+}
@@ -0,0 +1,316 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// ModelCapabilities are the limits and features of one model as served by
+// one provider
+type ModelCapabilities struct {
+	// Family is the catalog entry the model matched, empty when only the
+	// provider-wide capabilities apply
+	Family string `json:"family,omitempty"`
+	// Tokens of prompt and completion together
+	ContextLength     int      `json:"context_length"`
+	MaxOutputTokens   int      `json:"max_output_tokens"`
+	SupportsFunctions bool     `json:"supports_functions"`
+	InputModalities   []string `json:"input_modalities"`
+	// Request fields the gateway does not model, e.g. response_format, are
+	// forwarded upstream
+	SupportsPassthrough bool `json:"supports_passthrough"`
+}
+
+// modelFamily is a built-in catalog entry for the models starting with prefix
+type modelFamily struct {
+	prefix          string
+	contextLength   int
+	maxOutputTokens int
+	functions       bool
+	modalities      []string
+}
+
+var (
+	textOnly  = []string{"text"}
+	textImage = []string{"text", "image"}
+)
+
+// modelCatalog lists known model families, more specific prefixes first
+var modelCatalog = []modelFamily{
+	{"gpt-4o-mini", 128000, 16384, true, textImage},
+	{"gpt-4o", 128000, 16384, true, textImage},
+	{"gpt-4.1", 1047576, 32768, true, textImage},
+	{"gpt-4-turbo", 128000, 4096, true, textImage},
+	{"gpt-4-32k", 32768, 4096, true, textOnly},
+	{"gpt-4", 8192, 4096, true, textOnly},
+	{"gpt-3.5-turbo", 16385, 4096, true, textOnly},
+	{"o1-mini", 128000, 65536, false, textOnly},
+	{"o1", 200000, 100000, true, textImage},
+	{"o3-mini", 200000, 100000, true, textOnly},
+	{"o3", 200000, 100000, true, textImage},
+	{"o4-mini", 200000, 100000, true, textImage},
+	{"gemini-pro-vision", 16384, 2048, false, textImage},
+	{"gemini-pro", 32768, 8192, true, textOnly},
+	{"gemini-1.5-pro", 2097152, 8192, true, textImage},
+	{"gemini-1.5-flash", 1048576, 8192, true, textImage},
+	{"gemini-2.0-flash", 1048576, 8192, true, textImage},
+	{"gemini-2.5", 1048576, 65536, true, textImage},
+	{"deepseek-reasoner", 64000, 8192, false, textOnly},
+	{"deepseek-chat", 64000, 8192, true, textOnly},
+	{"mistral-large", 128000, 8192, true, textOnly},
+	{"mistral-small", 32000, 8192, true, textOnly},
+	{"pixtral", 128000, 8192, true, textImage},
+	{"codestral", 256000, 8192, true, textOnly},
+	{"command-r", 128000, 4096, true, textOnly},
+	{"command-a", 256000, 8192, true, textOnly},
+	{"sonar-reasoning", 127072, 8192, false, textOnly},
+	{"sonar", 127072, 8192, false, textOnly},
+	{"glm-4v", 8192, 1024, false, textImage},
+	{"glm-4", 128000, 4096, true, textOnly},
+}
+
+// CapabilitiesFor returns the capabilities of model on a provider with the
+// provider-wide caps: those of its family in the built-in catalog, then the
+// first matching entry of specs on top. Functions are only supported when
+// the provider supports them too. Models routed through aggregators, e.g.
+// "openrouter/openai/gpt-4o", match by their last path element.
+func CapabilitiesFor(caps ProviderCapabilities, model string, specs []config.ModelSpec) ModelCapabilities {
+	mc := ModelCapabilities{
+		ContextLength:       caps.MaxContextLength,
+		MaxOutputTokens:     caps.MaxTokens,
+		SupportsFunctions:   caps.SupportsFunctions,
+		InputModalities:     textOnly,
+		SupportsPassthrough: caps.SupportsPassthrough,
+	}
+
+	base := model[strings.LastIndex(model, "/")+1:]
+	for _, f := range modelCatalog {
+		if strings.HasPrefix(base, f.prefix) {
+			mc.Family = f.prefix
+			mc.ContextLength, mc.MaxOutputTokens = f.contextLength, f.maxOutputTokens
+			mc.SupportsFunctions = caps.SupportsFunctions && f.functions
+			mc.InputModalities = f.modalities
+			break
+		}
+	}
+
+	for _, s := range specs {
+		if !matchModelSpec(s.Model, model) {
+			continue
+		}
+		mc.Family = s.Model
+		if s.ContextLength > 0 {
+			mc.ContextLength = s.ContextLength
+		}
+		if s.MaxOutputTokens > 0 {
+			mc.MaxOutputTokens = s.MaxOutputTokens
+		}
+		if s.Functions != nil {
+			mc.SupportsFunctions = caps.SupportsFunctions && *s.Functions
+		}
+		if len(s.InputModalities) > 0 {
+			mc.InputModalities = s.InputModalities
+		}
+		break
+	}
+	return mc
+}
+
+// matchModelSpec matches a model name, or a prefix ending in "*"
+func matchModelSpec(pattern, model string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(model, prefix)
+	}
+	return model == pattern
+}
+
+// ModelSpec returns the configured models entry that applies to model as
+// served by p, if any
+func (r *Registry) ModelSpec(p Provider, model string) (config.ModelSpec, bool) {
+	name := p.GetInfo().Name
+	for _, s := range r.config().Models {
+		if (s.Provider == "" || s.Provider == name) && matchModelSpec(s.Model, model) {
+			return s, true
+		}
+	}
+	return config.ModelSpec{}, false
+}
+
+// ModelCapabilities returns the capabilities of model as served by p
+func (r *Registry) ModelCapabilities(p Provider, model string) ModelCapabilities {
+	name := p.GetInfo().Name
+	specs := make([]config.ModelSpec, 0, len(r.cfg.Models))
+	for _, s := range r.cfg.Models {
+		if s.Provider == "" || s.Provider == name {
+			specs = append(specs, s)
+		}
+	}
+	return CapabilitiesFor(p.GetCapabilities(), model, specs)
+}
+
+// LongContextModel returns the long_context_model configured for req's
+// model as routed, when req.Needs exceed that model's context length
+func (r *Registry) LongContextModel(req *RouteRequest) (string, bool) {
+	if req.Needs == nil {
+		return "", false
+	}
+	p, err := r.Route(req)
+	if err != nil {
+		return "", false
+	}
+	spec, ok := r.ModelSpec(p, req.Model)
+	if !ok || spec.LongContextModel == "" || spec.LongContextModel == req.Model {
+		return "", false
+	}
+	if mc := r.ModelCapabilities(p, req.Model); mc.ContextLength <= 0 || req.Needs.Tokens <= mc.ContextLength {
+		return "", false
+	}
+	return spec.LongContextModel, true
+}
+
+// ModelNeeds is what a request requires of the model serving it
+type ModelNeeds struct {
+	// Estimated prompt tokens plus the requested completion tokens
+	Tokens int
+	// Of Tokens, the requested completion tokens
+	CompletionTokens int
+	Functions        bool
+	// Passthrough fields, e.g. response_format for JSON mode, which only
+	// some providers forward
+	Passthrough bool
+}
+
+// NeedsOf returns what req requires of its model
+func NeedsOf(req *StandardRequest) *ModelNeeds {
+	n := &ModelNeeds{Tokens: EstimatePromptTokens(req), Functions: len(req.Functions) > 0, Passthrough: len(req.Passthrough) > 0}
+	if req.MaxTokens != nil {
+		n.Tokens += *req.MaxTokens
+		n.CompletionTokens = *req.MaxTokens
+	}
+	return n
+}
+
+// Satisfies reports whether a model with these capabilities can serve n;
+// zero limits are unknown and never exceeded
+func (mc ModelCapabilities) Satisfies(n *ModelNeeds) bool {
+	if n.Functions && !mc.SupportsFunctions || n.Passthrough && !mc.SupportsPassthrough {
+		return false
+	}
+	return mc.ContextLength <= 0 || n.Tokens <= mc.ContextLength
+}
+
+// EstimatePromptTokens approximates the prompt tokens of req at four bytes
+// per token plus a few tokens of framing per message. Tokenizers differ per
+// model, so this is only good for limit checks with some headroom.
+func EstimatePromptTokens(req *StandardRequest) int {
+	bytes := 0
+	for _, msg := range req.Messages {
+		bytes += len(msg.Content) + len(msg.ReasoningContent)
+		if msg.FunctionCall != nil {
+			bytes += len(msg.FunctionCall.Name) + len(msg.FunctionCall.Arguments)
+		}
+	}
+	if len(req.Functions) > 0 {
+		if b, err := json.Marshal(req.Functions); err == nil {
+			bytes += len(b)
+		}
+	}
+	return bytes/4 + 4*len(req.Messages)
+}
+
+// Limit error codes
+const (
+	LimitCodeContextLength = "context_length_exceeded"
+	// Codes of the gateway's request_limits
+	LimitCodeMessages     = "too_many_messages"
+	LimitCodePromptChars  = "prompt_too_long"
+	LimitCodePromptTokens = "prompt_too_many_tokens"
+)
+
+// LimitError is returned for requests that cannot fit the model's limits
+// or the gateway's request_limits
+type LimitError struct {
+	Code  string
+	Model string
+	Limit int
+	// Estimated tokens of the request, or its messages or characters by
+	// Code
+	Requested int
+}
+
+func (e *LimitError) Error() string {
+	switch e.Code {
+	case LimitCodeMessages:
+		return fmt.Sprintf("the request has %d messages; at most %d are allowed", e.Requested, e.Limit)
+	case LimitCodePromptChars:
+		return fmt.Sprintf("the prompt has %d characters; at most %d are allowed", e.Requested, e.Limit)
+	case LimitCodePromptTokens:
+		return fmt.Sprintf("the prompt is about %d tokens; at most %d are allowed", e.Requested, e.Limit)
+	}
+	return fmt.Sprintf("model %s has a context length of %d tokens; the prompt is about %d tokens", e.Model, e.Limit, e.Requested)
+}
+
+// CheckRequestLimits checks req against the gateway's request_limits,
+// returning a *LimitError when it exceeds one
+func (r *Registry) CheckRequestLimits(req *StandardRequest) error {
+	limits := r.config().RequestLimits
+	if n := len(req.Messages); limits.MaxMessages > 0 && n > limits.MaxMessages {
+		return &LimitError{Code: LimitCodeMessages, Model: req.Model, Limit: limits.MaxMessages, Requested: n}
+	}
+	if limits.MaxPromptChars > 0 {
+		if n := promptChars(req); n > limits.MaxPromptChars {
+			return &LimitError{Code: LimitCodePromptChars, Model: req.Model, Limit: limits.MaxPromptChars, Requested: n}
+		}
+	}
+	if limits.MaxPromptTokens > 0 {
+		if n := EstimatePromptTokens(req); n > limits.MaxPromptTokens {
+			return &LimitError{Code: LimitCodePromptTokens, Model: req.Model, Limit: limits.MaxPromptTokens, Requested: n}
+		}
+	}
+	return nil
+}
+
+// promptChars counts the characters of req's messages that
+// EstimatePromptTokens counts the bytes of
+func promptChars(req *StandardRequest) int {
+	n := 0
+	for _, msg := range req.Messages {
+		n += utf8.RuneCountInString(msg.Content) + utf8.RuneCountInString(msg.ReasoningContent)
+		if msg.FunctionCall != nil {
+			n += utf8.RuneCountInString(msg.FunctionCall.Name) + utf8.RuneCountInString(msg.FunctionCall.Arguments)
+		}
+	}
+	return n
+}
+
+// ApplyLimits fits req to the model's limits. A max_tokens above the
+// model's output limit, or beyond the context left after the prompt, is
+// lowered with a warning; a prompt filling the whole context is refused
+// with a *LimitError.
+func (mc ModelCapabilities) ApplyLimits(req *StandardRequest) ([]Warning, error) {
+	prompt := EstimatePromptTokens(req)
+	if mc.ContextLength > 0 && prompt >= mc.ContextLength {
+		return nil, &LimitError{Code: LimitCodeContextLength, Model: req.Model, Limit: mc.ContextLength, Requested: prompt}
+	}
+	if req.MaxTokens == nil {
+		return nil, nil
+	}
+
+	limit := *req.MaxTokens
+	if mc.MaxOutputTokens > 0 && limit > mc.MaxOutputTokens {
+		limit = mc.MaxOutputTokens
+	}
+	if mc.ContextLength > 0 && prompt+limit > mc.ContextLength {
+		limit = mc.ContextLength - prompt
+	}
+	if limit == *req.MaxTokens {
+		return nil, nil
+	}
+	msg := fmt.Sprintf("max_tokens lowered from %d to %d to fit the limits of %s", *req.MaxTokens, limit, req.Model)
+	req.MaxTokens = &limit
+	return []Warning{{Code: WarningTruncated, Message: msg, Param: "max_tokens"}}, nil
+}
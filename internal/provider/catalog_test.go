@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestCapabilitiesFor(t *testing.T) {
+	caps := ProviderCapabilities{SupportsFunctions: true, MaxTokens: 4096, MaxContextLength: 128000}
+	off := false
+	specs := []config.ModelSpec{
+		{Model: "gpt-4o-2024-08-06", ContextLength: 64000},
+		{Model: "qwen2.5-*", ContextLength: 32768, Functions: &off},
+	}
+
+	tests := map[string]struct {
+		model     string
+		caps      ProviderCapabilities
+		family    string
+		context   int
+		output    int
+		functions bool
+	}{
+		"provider-wide fallback": {model: "my-finetune", caps: caps, context: 128000, output: 4096, functions: true},
+		"family":                 {model: "gpt-4-0613", caps: caps, family: "gpt-4", context: 8192, output: 4096, functions: true},
+		"more specific family":   {model: "gpt-4o-mini", caps: caps, family: "gpt-4o-mini", context: 128000, output: 16384, functions: true},
+		"aggregator path":        {model: "openrouter/openai/gpt-4o", caps: caps, family: "gpt-4o", context: 128000, output: 16384, functions: true},
+		"family without tools":   {model: "deepseek-reasoner", caps: caps, family: "deepseek-reasoner", context: 64000, output: 8192},
+		"provider without tools": {model: "gpt-4o", caps: ProviderCapabilities{}, family: "gpt-4o", context: 128000, output: 16384},
+		"config over family":     {model: "gpt-4o-2024-08-06", caps: caps, family: "gpt-4o-2024-08-06", context: 64000, output: 16384, functions: true},
+		"config prefix":          {model: "qwen2.5-7b", caps: caps, family: "qwen2.5-*", context: 32768, output: 4096},
+	}
+	for name, tt := range tests {
+		mc := CapabilitiesFor(tt.caps, tt.model, specs)
+		if mc.Family != tt.family || mc.ContextLength != tt.context || mc.MaxOutputTokens != tt.output || mc.SupportsFunctions != tt.functions {
+			t.Errorf("%s: unexpected capabilities %+v", name, mc)
+		}
+	}
+}
+
+func TestApplyLimits(t *testing.T) {
+	mc := ModelCapabilities{ContextLength: 1000, MaxOutputTokens: 500}
+	prompt := strings.Repeat("a", 2000) // ~504 tokens with framing
+
+	tests := map[string]struct {
+		content   string
+		maxTokens int
+		want      int
+		warned    bool
+	}{
+		"within limits":       {content: "hi", maxTokens: 100, want: 100},
+		"above output limit":  {content: "hi", maxTokens: 800, want: 500, warned: true},
+		"beyond context left": {content: prompt, maxTokens: 500, want: 496, warned: true},
+	}
+	for name, tt := range tests {
+		maxTokens := tt.maxTokens
+		req := &StandardRequest{Model: "m", Messages: []Message{{Role: RoleUser, Content: tt.content}}, MaxTokens: &maxTokens}
+		warnings, err := mc.ApplyLimits(req)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if *req.MaxTokens != tt.want || (len(warnings) == 1) != tt.warned {
+			t.Errorf("%s: expected max_tokens %d, got %d with warnings %v", name, tt.want, *req.MaxTokens, warnings)
+		}
+		if tt.warned && warnings[0].Code != WarningTruncated {
+			t.Errorf("%s: unexpected warning %+v", name, warnings[0])
+		}
+	}
+
+	req := &StandardRequest{Model: "m", Messages: []Message{{Role: RoleUser, Content: strings.Repeat("a", 4000)}}}
+	_, err := mc.ApplyLimits(req)
+	limitErr, ok := err.(*LimitError)
+	if !ok || limitErr.Code != LimitCodeContextLength || limitErr.Limit != 1000 {
+		t.Errorf("Expected a context length error, got %v", err)
+	}
+}
+
+func TestRequestLimits(t *testing.T) {
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo"}}}
+	cfg.RequestLimits.MaxPromptTokens = 100
+	r, err := NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &StandardRequest{Model: "m", Messages: []Message{{Role: RoleUser, Content: "hi"}}}
+	if err := r.CheckRequestLimits(req); err != nil {
+		t.Errorf("Expected a short prompt allowed, got %v", err)
+	}
+	req.Messages[0].Content = strings.Repeat("a", 800)
+	err = r.CheckRequestLimits(req)
+	limitErr, ok := err.(*LimitError)
+	if !ok || limitErr.Code != LimitCodePromptTokens || limitErr.Limit != 100 || limitErr.Requested != 204 {
+		t.Errorf("Expected a prompt token error, got %v", err)
+	}
+}
+
+func TestRouteByModelCapabilities(t *testing.T) {
+	cfg := &config.Config{
+		Mock: []config.MockConfig{
+			{Name: "small"},
+			{Name: "large", Responses: []config.MockResponse{{FunctionCall: &config.MockFunctionCall{Name: "lookup", Arguments: "{}"}}}},
+		},
+		OpenAICompatible: []config.OpenAICompatibleConfig{{Name: "compat", BaseURL: "http://127.0.0.1:1/v1"}},
+		Routes: []config.Route{
+			{Prefix: "local-", Provider: "small"},
+			{Prefix: "local-", Provider: "large"},
+			{Prefix: "local-", Provider: "compat"},
+		},
+		Models: []config.ModelSpec{
+			{Model: "local-*", Provider: "small", ContextLength: 100},
+			{Model: "local-*", Provider: "compat", ContextLength: 1000},
+		},
+	}
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		needs *ModelNeeds
+		want  string
+	}{
+		"no needs":             {want: "small"},
+		"fits":                 {needs: &ModelNeeds{Tokens: 50}, want: "small"},
+		"beyond small context": {needs: &ModelNeeds{Tokens: 500}, want: "large"},
+		"functions":            {needs: &ModelNeeds{Tokens: 50, Functions: true}, want: "large"},
+		"passthrough":          {needs: &ModelNeeds{Tokens: 50, Passthrough: true}, want: "compat"},
+		"nothing fits, first":  {needs: &ModelNeeds{Tokens: 1 << 20}, want: "small"},
+	}
+	for name, tt := range tests {
+		p, err := r.Route(&RouteRequest{Model: "local-llama", Needs: tt.needs})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got := p.GetInfo().Name; got != tt.want {
+			t.Errorf("%s: expected %s, got %s", name, tt.want, got)
+		}
+	}
+}
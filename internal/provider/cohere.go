@@ -0,0 +1,417 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultCohereBaseURL is the Cohere API endpoint
+const DefaultCohereBaseURL = "https://api.cohere.com/v1"
+
+// CohereProvider implements the Provider interface using Cohere's chat API
+// for the Command R family. Cohere takes the latest user turn as message,
+// earlier turns as chat_history and system prompts as a preamble.
+type CohereProvider struct {
+	httpClient   *http.Client
+	apiKey       string
+	baseURL      string
+	modelName    string
+	capabilities ProviderCapabilities
+	pacer        *Pacer
+}
+
+// NewCohereProvider creates a new Cohere provider instance
+func NewCohereProvider(apiKey, baseURL, modelName string) (*CohereProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("cohere apiKey is required")
+	}
+	if modelName == "" {
+		modelName = "command-r-plus"
+	}
+	if baseURL == "" {
+		baseURL = DefaultCohereBaseURL
+	}
+
+	// Define Cohere capabilities
+	capabilities := ProviderCapabilities{
+		SupportsStreaming:   true,
+		SupportsFunctions:   false, // Cohere tools use a different parameter schema
+		SupportsSystemRole:  true,  // Mapped to the preamble
+		MaxTokens:           4096,
+		MaxContextLength:    128000,
+		SupportedModels:     []string{"command-r", "command-r-plus", "command-r-08-2024", "command-r-plus-08-2024"},
+		SupportedParameters: []string{"temperature", "top_p", "max_tokens", "stream"},
+	}
+
+	pacer := NewPacer()
+	return &CohereProvider{
+		httpClient:   newPacedClient(pacer),
+		apiKey:       apiKey,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		modelName:    modelName,
+		capabilities: capabilities,
+		pacer:        pacer,
+	}, nil
+}
+
+// cohereChatRequest is the body of POST /chat
+type cohereChatRequest struct {
+	Model       string              `json:"model"`
+	Message     string              `json:"message"`
+	ChatHistory []cohereChatMessage `json:"chat_history,omitempty"`
+	Preamble    string              `json:"preamble,omitempty"`
+	Temperature *float64            `json:"temperature,omitempty"`
+	P           *float64            `json:"p,omitempty"`
+	MaxTokens   *int                `json:"max_tokens,omitempty"`
+	Stream      bool                `json:"stream,omitempty"`
+}
+
+type cohereChatMessage struct {
+	Role    string `json:"role"`
+	Message string `json:"message"`
+}
+
+type cohereCitation struct {
+	Start       int      `json:"start"`
+	End         int      `json:"end"`
+	Text        string   `json:"text"`
+	DocumentIDs []string `json:"document_ids"`
+}
+
+// cohereChatResponse is the non-streaming response, also embedded in the
+// stream-end event
+type cohereChatResponse struct {
+	ResponseID   string                   `json:"response_id"`
+	GenerationID string                   `json:"generation_id"`
+	Text         string                   `json:"text"`
+	FinishReason string                   `json:"finish_reason"`
+	Citations    []cohereCitation         `json:"citations"`
+	Documents    []map[string]interface{} `json:"documents"`
+	Meta         struct {
+		BilledUnits struct {
+			InputTokens  float64 `json:"input_tokens"`
+			OutputTokens float64 `json:"output_tokens"`
+		} `json:"billed_units"`
+	} `json:"meta"`
+}
+
+// cohereStreamEvent is one line of the newline-delimited JSON stream
+type cohereStreamEvent struct {
+	EventType    string              `json:"event_type"`
+	GenerationID string              `json:"generation_id"`
+	Text         string              `json:"text"`
+	Citations    []cohereCitation    `json:"citations"`
+	FinishReason string              `json:"finish_reason"`
+	Response     *cohereChatResponse `json:"response"`
+}
+
+// Generate generates a completion for the given request
+func (c *CohereProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	cohereReq, err := c.transformRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform request: %w", err)
+	}
+
+	body, err := c.do(ctx, cohereReq)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp cohereChatResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("decode cohere response: %w", err)
+	}
+
+	return &GenerateResponse{
+		StandardResponse: c.transformResponse(&resp, req.Model),
+	}, nil
+}
+
+// StreamGenerate generates a streaming completion for the given request
+func (c *CohereProvider) StreamGenerate(ctx context.Context, req *GenerateRequest) (io.ReadCloser, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	cohereReq, err := c.transformRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform request: %w", err)
+	}
+	cohereReq.Stream = true
+
+	body, err := c.do(ctx, cohereReq)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer body.Close()
+		defer pw.Close()
+
+		chunkID := NewResponseID()
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+
+			var event cohereStreamEvent
+			if err := json.Unmarshal(line, &event); err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("decode cohere stream event: %w", err))
+				return
+			}
+			if event.GenerationID != "" {
+				chunkID = event.GenerationID
+			}
+
+			chunk := c.transformStreamEvent(&event, chunkID, req.Model)
+			if chunk == nil {
+				continue
+			}
+
+			chunkData, err := json.Marshal(chunk)
+			if err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("failed to marshal chunk: %w", err))
+				return
+			}
+
+			if _, werr := pw.Write(append(chunkData, '\n')); werr != nil {
+				_ = pw.CloseWithError(werr)
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			_ = pw.CloseWithError(fmt.Errorf("cohere stream recv error: %w", err))
+		}
+	}()
+
+	return pr, nil
+}
+
+// Embed is not supported
+func (c *CohereProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	return nil, fmt.Errorf("cohere: %w", ErrEmbeddingsUnsupported)
+}
+
+// Transcribe is not supported
+func (c *CohereProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("cohere: %w", ErrTranscriptionUnsupported)
+}
+
+// GetCapabilities returns the capabilities of the Cohere provider
+func (c *CohereProvider) GetCapabilities() ProviderCapabilities {
+	return c.capabilities
+}
+
+// GetInfo returns information about the Cohere provider
+func (c *CohereProvider) GetInfo() ProviderInfo {
+	return ProviderInfo{
+		Name:         "cohere",
+		Version:      "1.0.0",
+		Capabilities: c.capabilities,
+		Status:       "active",
+		LastUpdated:  time.Now(),
+	}
+}
+
+// Health verifies the API is reachable and the key is accepted
+func (c *CohereProvider) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/models?page_size=1", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cohere list models: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cohere list models: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Pacer returns the pacer tracking Cohere rate limit headers
+func (c *CohereProvider) Pacer() *Pacer {
+	return c.pacer
+}
+
+// Close closes any underlying resources (no-op for the HTTP client)
+func (c *CohereProvider) Close() error {
+	return nil
+}
+
+// do posts a chat request and returns the response body on success
+func (c *CohereProvider) do(ctx context.Context, cohereReq *cohereChatRequest) (io.ReadCloser, error) {
+	payload, err := json.Marshal(cohereReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("cohere chat error: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&apiErr)
+		return nil, fmt.Errorf("cohere chat error: status %d: %s", resp.StatusCode, apiErr.Message)
+	}
+	return resp.Body, nil
+}
+
+// transformRequest converts a StandardRequest to Cohere format. System
+// messages are joined into the preamble, the final user message becomes
+// message and everything before it becomes chat_history.
+func (c *CohereProvider) transformRequest(req *GenerateRequest) (*cohereChatRequest, error) {
+	last := len(req.Messages) - 1
+	if req.Messages[last].Role != RoleUser {
+		return nil, fmt.Errorf("cohere requires the last message to be from the user, got %s", req.Messages[last].Role)
+	}
+
+	var preamble []string
+	history := make([]cohereChatMessage, 0, last)
+	for _, msg := range req.Messages[:last] {
+		switch msg.Role {
+		case RoleSystem:
+			preamble = append(preamble, msg.Content)
+		case RoleUser:
+			history = append(history, cohereChatMessage{Role: "USER", Message: msg.Content})
+		case RoleAssistant:
+			history = append(history, cohereChatMessage{Role: "CHATBOT", Message: msg.Content})
+		default:
+			return nil, fmt.Errorf("unsupported message role: %s", msg.Role)
+		}
+	}
+
+	return &cohereChatRequest{
+		Model:       req.Model,
+		Message:     req.Messages[last].Content,
+		ChatHistory: history,
+		Preamble:    strings.Join(preamble, "\n\n"),
+		Temperature: req.Temperature,
+		P:           req.TopP,
+		MaxTokens:   req.MaxTokens,
+		Stream:      req.Stream,
+	}, nil
+}
+
+// transformResponse converts a Cohere response to StandardResponse
+func (c *CohereProvider) transformResponse(resp *cohereChatResponse, model string) *StandardResponse {
+	msg := &Message{
+		Role:    RoleAssistant,
+		Content: resp.Text,
+	}
+	if len(resp.Citations) > 0 {
+		msg.Metadata = map[string]interface{}{MetadataCitations: c.normalizeCitations(resp.Citations)}
+		if len(resp.Documents) > 0 {
+			msg.Metadata["documents"] = resp.Documents
+		}
+	}
+
+	choices := []Choice{{
+		Index:        0,
+		Message:      msg,
+		FinishReason: c.mapFinishReason(resp.FinishReason),
+	}}
+
+	usage := Usage{
+		PromptTokens:     int(resp.Meta.BilledUnits.InputTokens),
+		CompletionTokens: int(resp.Meta.BilledUnits.OutputTokens),
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	id := resp.ResponseID
+	if id == "" {
+		id = resp.GenerationID
+	}
+	return CreateStandardResponse(id, model, choices, usage)
+}
+
+// transformStreamEvent converts a Cohere stream event to StreamChunk; events
+// that carry nothing for the client yield nil
+func (c *CohereProvider) transformStreamEvent(event *cohereStreamEvent, chunkID, model string) *StreamChunk {
+	switch event.EventType {
+	case "text-generation":
+		return CreateStreamChunk(chunkID, model, []Choice{{
+			Delta: &Message{Role: RoleAssistant, Content: event.Text},
+		}}, false)
+	case "citation-generation":
+		return CreateStreamChunk(chunkID, model, []Choice{{
+			Delta: &Message{
+				Role:     RoleAssistant,
+				Metadata: map[string]interface{}{MetadataCitations: c.normalizeCitations(event.Citations)},
+			},
+		}}, false)
+	case "stream-end":
+		chunk := CreateStreamChunk(chunkID, model, []Choice{{
+			Delta:        &Message{Role: RoleAssistant},
+			FinishReason: c.mapFinishReason(event.FinishReason),
+		}}, true)
+		if event.Response != nil {
+			in := int(event.Response.Meta.BilledUnits.InputTokens)
+			out := int(event.Response.Meta.BilledUnits.OutputTokens)
+			chunk.Usage = &Usage{PromptTokens: in, CompletionTokens: out, TotalTokens: in + out}
+		}
+		return chunk
+	default:
+		return nil
+	}
+}
+
+// normalizeCitations converts Cohere citations to the standard shape
+func (c *CohereProvider) normalizeCitations(in []cohereCitation) []Citation {
+	out := make([]Citation, len(in))
+	for i, cit := range in {
+		out[i] = Citation{Start: cit.Start, End: cit.End, Text: cit.Text, DocumentIDs: cit.DocumentIDs}
+	}
+	return out
+}
+
+// mapFinishReason maps Cohere finish reasons to standard format
+func (c *CohereProvider) mapFinishReason(reason string) *string {
+	var r string
+	switch reason {
+	case "":
+		return nil
+	case "COMPLETE", "STOP_SEQUENCE":
+		r = FinishReasonStop
+	case "MAX_TOKENS":
+		r = FinishReasonLength
+	case "ERROR_TOXIC":
+		r = FinishReasonContentFilter
+	default:
+		r = strings.ToLower(reason)
+	}
+	return &r
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCohereGenerate(t *testing.T) {
+	var got cohereChatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat" || r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Unexpected request %s %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"response_id":"r-1","text":"Paris is the capital.","finish_reason":"COMPLETE",
+			"citations":[{"start":0,"end":5,"text":"Paris","document_ids":["doc_0"]}],
+			"documents":[{"id":"doc_0","title":"France"}],
+			"meta":{"billed_units":{"input_tokens":12,"output_tokens":4}}}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewCohereProvider("test-key", srv.URL, "")
+	if err != nil {
+		t.Fatalf("Failed to create Cohere provider: %v", err)
+	}
+
+	resp, err := p.Generate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model: "command-r-plus",
+		Messages: []Message{
+			{Role: RoleSystem, Content: "Be brief."},
+			{Role: RoleUser, Content: "Hi"},
+			{Role: RoleAssistant, Content: "Hello"},
+			{Role: RoleUser, Content: "Capital of France?"},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if got.Preamble != "Be brief." || got.Message != "Capital of France?" {
+		t.Errorf("Unexpected preamble/message: %+v", got)
+	}
+	if len(got.ChatHistory) != 2 || got.ChatHistory[0].Role != "USER" || got.ChatHistory[1].Role != "CHATBOT" {
+		t.Errorf("Unexpected chat_history: %+v", got.ChatHistory)
+	}
+
+	msg := resp.Choices[0].Message
+	citations, ok := msg.Metadata[MetadataCitations].([]Citation)
+	if !ok || len(citations) != 1 || citations[0].DocumentIDs[0] != "doc_0" {
+		t.Errorf("Expected normalized citations, got %+v", msg.Metadata)
+	}
+	if *resp.Choices[0].FinishReason != FinishReasonStop {
+		t.Errorf("Expected stop finish reason, got %s", *resp.Choices[0].FinishReason)
+	}
+	if resp.Usage.TotalTokens != 16 {
+		t.Errorf("Expected 16 total tokens, got %d", resp.Usage.TotalTokens)
+	}
+
+	_, err = p.Generate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "command-r",
+		Messages: []Message{{Role: RoleUser, Content: "Hi"}, {Role: RoleAssistant, Content: "Hello"}},
+	}})
+	if err == nil {
+		t.Error("Expected error when the last message is not from the user")
+	}
+}
+
+func TestCohereStreamGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"event_type":"stream-start","generation_id":"g-1"}`)
+		fmt.Fprintln(w, `{"event_type":"text-generation","text":"Paris"}`)
+		fmt.Fprintln(w, `{"event_type":"citation-generation","citations":[{"start":0,"end":5,"text":"Paris","document_ids":["doc_0"]}]}`)
+		fmt.Fprintln(w, `{"event_type":"stream-end","finish_reason":"MAX_TOKENS","response":{"meta":{"billed_units":{"input_tokens":3,"output_tokens":1}}}}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewCohereProvider("test-key", srv.URL, "")
+	if err != nil {
+		t.Fatalf("Failed to create Cohere provider: %v", err)
+	}
+
+	stream, err := p.StreamGenerate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "command-r",
+		Messages: []Message{{Role: RoleUser, Content: "Capital of France?"}},
+		Stream:   true,
+	}})
+	if err != nil {
+		t.Fatalf("StreamGenerate failed: %v", err)
+	}
+	defer stream.Close()
+
+	var chunks []StreamChunk
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		var chunk StreamChunk
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatalf("Invalid chunk: %v", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d", len(chunks))
+	}
+	if chunks[0].ID != "g-1" || chunks[0].Choices[0].Delta.Content != "Paris" {
+		t.Errorf("Unexpected text chunk: %+v", chunks[0])
+	}
+	if _, ok := chunks[1].Choices[0].Delta.Metadata[MetadataCitations]; !ok {
+		t.Errorf("Expected citations in delta metadata: %+v", chunks[1].Choices[0].Delta)
+	}
+	last := chunks[2]
+	if !last.Done || *last.Choices[0].FinishReason != FinishReasonLength || last.Usage.TotalTokens != 4 {
+		t.Errorf("Unexpected final chunk: %+v", last)
+	}
+}
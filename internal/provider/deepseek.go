@@ -0,0 +1,268 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultDeepSeekBaseURL is the DeepSeek API endpoint
+const DefaultDeepSeekBaseURL = "https://api.deepseek.com/v1"
+
+// DeepSeekProvider implements the Provider interface using DeepSeek's
+// OpenAI-compatible chat API. deepseek-reasoner returns its chain of thought
+// in reasoning_content, which is surfaced as Message.ReasoningContent.
+type DeepSeekProvider struct {
+	client       *openai.Client
+	modelName    string
+	capabilities ProviderCapabilities
+	pacer        *Pacer
+}
+
+// NewDeepSeekProvider creates a new DeepSeek provider instance
+func NewDeepSeekProvider(apiKey, baseURL, modelName string) (*DeepSeekProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("deepseek apiKey is required")
+	}
+	if modelName == "" {
+		modelName = "deepseek-chat"
+	}
+	if baseURL == "" {
+		baseURL = DefaultDeepSeekBaseURL
+	}
+
+	pacer := NewPacer()
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = baseURL
+	config.HTTPClient = newPacedClient(pacer)
+
+	// Define DeepSeek capabilities
+	capabilities := ProviderCapabilities{
+		SupportsStreaming:   true,
+		SupportsFunctions:   true, // deepseek-chat only; deepseek-reasoner ignores tools
+		SupportsSystemRole:  true,
+		MaxTokens:           8192,
+		MaxContextLength:    64000,
+		SupportedModels:     []string{"deepseek-chat", "deepseek-reasoner"},
+		SupportedParameters: []string{"temperature", "top_p", "max_tokens", "stream", "functions"},
+	}
+
+	return &DeepSeekProvider{
+		client:       openai.NewClientWithConfig(config),
+		modelName:    modelName,
+		capabilities: capabilities,
+		pacer:        pacer,
+	}, nil
+}
+
+// Generate generates a completion for the given request
+func (d *DeepSeekProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	resp, err := d.client.CreateChatCompletion(ctx, *d.transformRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("deepseek completion error: %w", err)
+	}
+
+	return &GenerateResponse{
+		StandardResponse: d.transformResponse(&resp),
+	}, nil
+}
+
+// StreamGenerate generates a streaming completion for the given request.
+// Reasoning and answer text are emitted in separate chunks.
+func (d *DeepSeekProvider) StreamGenerate(ctx context.Context, req *GenerateRequest) (io.ReadCloser, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	deepseekReq := d.transformRequest(req)
+	deepseekReq.Stream = true
+
+	stream, err := d.client.CreateChatCompletionStream(ctx, *deepseekReq)
+	if err != nil {
+		return nil, fmt.Errorf("deepseek start stream error: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer stream.Close()
+		defer pw.Close()
+
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("deepseek stream recv error: %w", err))
+				return
+			}
+
+			for _, chunk := range d.transformStreamChunks(&resp) {
+				chunkData, err := json.Marshal(chunk)
+				if err != nil {
+					_ = pw.CloseWithError(fmt.Errorf("failed to marshal chunk: %w", err))
+					return
+				}
+
+				if _, werr := pw.Write(append(chunkData, '\n')); werr != nil {
+					_ = pw.CloseWithError(werr)
+					return
+				}
+			}
+		}
+	}()
+
+	return pr, nil
+}
+
+// Embed is not supported
+func (d *DeepSeekProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	return nil, fmt.Errorf("deepseek: %w", ErrEmbeddingsUnsupported)
+}
+
+// Transcribe is not supported
+func (d *DeepSeekProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("deepseek: %w", ErrTranscriptionUnsupported)
+}
+
+// GetCapabilities returns the capabilities of the DeepSeek provider
+func (d *DeepSeekProvider) GetCapabilities() ProviderCapabilities {
+	return d.capabilities
+}
+
+// GetInfo returns information about the DeepSeek provider
+func (d *DeepSeekProvider) GetInfo() ProviderInfo {
+	return ProviderInfo{
+		Name:         "deepseek",
+		Version:      "1.0.0",
+		Capabilities: d.capabilities,
+		Status:       "active",
+		LastUpdated:  time.Now(),
+	}
+}
+
+// ListModels returns the IDs of the models the DeepSeek API serves
+func (d *DeepSeekProvider) ListModels(ctx context.Context) ([]string, error) {
+	list, err := d.client.ListModels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("deepseek list models: %w", err)
+	}
+	ids := make([]string, len(list.Models))
+	for i, m := range list.Models {
+		ids[i] = m.ID
+	}
+	return ids, nil
+}
+
+// Health verifies the API is reachable and the key is accepted
+func (d *DeepSeekProvider) Health(ctx context.Context) error {
+	if _, err := d.client.ListModels(ctx); err != nil {
+		return fmt.Errorf("deepseek list models: %w", err)
+	}
+	return nil
+}
+
+// Pacer returns the pacer tracking DeepSeek rate limit headers
+func (d *DeepSeekProvider) Pacer() *Pacer {
+	return d.pacer
+}
+
+// Close closes any underlying resources (no-op for the HTTP client)
+func (d *DeepSeekProvider) Close() error {
+	return nil
+}
+
+// transformRequest converts a StandardRequest to DeepSeek format. Earlier
+// reasoning is never sent back, as the API rejects reasoning_content in input.
+func (d *DeepSeekProvider) transformRequest(req *GenerateRequest) *openai.ChatCompletionRequest {
+	deepseekReq := &openai.ChatCompletionRequest{
+		Model:    req.Model,
+		Messages: toolMessages(req.Messages),
+		Tools:    functionTools(req.Functions),
+		Stream:   req.Stream,
+	}
+
+	if req.MaxTokens != nil {
+		deepseekReq.MaxTokens = *req.MaxTokens
+	}
+
+	if req.Temperature != nil {
+		deepseekReq.Temperature = float32(*req.Temperature)
+	}
+
+	if req.TopP != nil {
+		deepseekReq.TopP = float32(*req.TopP)
+	}
+
+	return deepseekReq
+}
+
+// transformResponse converts a DeepSeek response to StandardResponse
+func (d *DeepSeekProvider) transformResponse(resp *openai.ChatCompletionResponse) *StandardResponse {
+	choices := make([]Choice, len(resp.Choices))
+
+	for i, choice := range resp.Choices {
+		choices[i] = Choice{
+			Index: choice.Index,
+			Message: &Message{
+				Role:             choice.Message.Role,
+				Content:          choice.Message.Content,
+				ReasoningContent: choice.Message.ReasoningContent,
+				FunctionCall:     toolFunctionCall(choice.Message.ToolCalls),
+			},
+			FinishReason: toolFinishReason(choice.FinishReason),
+		}
+	}
+
+	return CreateStandardResponse(resp.ID, resp.Model, choices, openAIUsage(resp.Usage))
+}
+
+// transformStreamChunks converts a DeepSeek stream response to StreamChunks.
+// A delta carrying both reasoning and answer text is split in two so the
+// reasoning chunk always precedes and never mixes with content.
+func (d *DeepSeekProvider) transformStreamChunks(resp *openai.ChatCompletionStreamResponse) []*StreamChunk {
+	var reasoning []Choice
+	choices := make([]Choice, 0, len(resp.Choices))
+
+	for _, choice := range resp.Choices {
+		delta := &Message{
+			Role:         choice.Delta.Role,
+			Content:      choice.Delta.Content,
+			FunctionCall: toolFunctionCall(choice.Delta.ToolCalls),
+		}
+
+		if choice.Delta.ReasoningContent != "" {
+			if delta.Content == "" && delta.FunctionCall == nil && choice.FinishReason == "" {
+				delta.ReasoningContent = choice.Delta.ReasoningContent
+			} else {
+				reasoning = append(reasoning, Choice{
+					Index: choice.Index,
+					Delta: &Message{Role: choice.Delta.Role, ReasoningContent: choice.Delta.ReasoningContent},
+				})
+			}
+		}
+
+		choices = append(choices, Choice{
+			Index:        choice.Index,
+			Delta:        delta,
+			FinishReason: toolFinishReason(choice.FinishReason),
+		})
+	}
+
+	done := len(resp.Choices) > 0 && resp.Choices[0].FinishReason != ""
+
+	var out []*StreamChunk
+	if len(reasoning) > 0 {
+		out = append(out, CreateStreamChunk(resp.ID, resp.Model, reasoning, false))
+	}
+	return append(out, CreateStreamChunk(resp.ID, resp.Model, choices, done))
+}
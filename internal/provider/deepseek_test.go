@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeepSeekReasoningContent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"ds-1","model":"deepseek-reasoner","choices":[{"index":0,
+			"message":{"role":"assistant","content":"9.11 < 9.8","reasoning_content":"Compare the decimals..."},
+			"finish_reason":"stop"}],"usage":{"prompt_tokens":10,"completion_tokens":20,"total_tokens":30}}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewDeepSeekProvider("test-key", srv.URL, "")
+	if err != nil {
+		t.Fatalf("Failed to create DeepSeek provider: %v", err)
+	}
+
+	resp, err := p.Generate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "deepseek-reasoner",
+		Messages: []Message{{Role: RoleUser, Content: "Which is larger, 9.11 or 9.8?"}},
+	}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	msg := resp.Choices[0].Message
+	if msg.Content != "9.11 < 9.8" || msg.ReasoningContent != "Compare the decimals..." {
+		t.Errorf("Expected reasoning kept apart from content, got %+v", msg)
+	}
+}
+
+func TestDeepSeekStreamSeparatesReasoning(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"id\":\"ds-1\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"reasoning_content\":\"Think\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"id\":\"ds-1\",\"choices\":[{\"index\":0,\"delta\":{\"reasoning_content\":\" more\",\"content\":\"Answer\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"id\":\"ds-1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"!\"},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p, err := NewDeepSeekProvider("test-key", srv.URL, "")
+	if err != nil {
+		t.Fatalf("Failed to create DeepSeek provider: %v", err)
+	}
+
+	stream, err := p.StreamGenerate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "deepseek-reasoner",
+		Messages: []Message{{Role: RoleUser, Content: "Hi"}},
+		Stream:   true,
+	}})
+	if err != nil {
+		t.Fatalf("StreamGenerate failed: %v", err)
+	}
+	defer stream.Close()
+
+	var reasoning, content string
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		var chunk StreamChunk
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatalf("Invalid chunk: %v", err)
+		}
+		delta := chunk.Choices[0].Delta
+		if delta.ReasoningContent != "" && delta.Content != "" {
+			t.Errorf("Reasoning and content should not share a chunk: %+v", delta)
+		}
+		reasoning += delta.ReasoningContent
+		content += delta.Content
+	}
+
+	if reasoning != "Think more" || content != "Answer!" {
+		t.Errorf("Unexpected stream: reasoning=%q content=%q", reasoning, content)
+	}
+}
@@ -0,0 +1,307 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ModelLister is implemented by providers that can list the models their
+// upstream serves
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// RevalidatingModelLister is a ModelLister whose upstream honours
+// conditional requests, so a cached list can be revalidated cheaply
+type RevalidatingModelLister interface {
+	ModelLister
+	ListModelsIfChanged(ctx context.Context, v ModelListValidators) (*ModelList, error)
+}
+
+// ModelListValidators are the cache validators an upstream sent with a
+// model list
+type ModelListValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// ModelList is the result of a conditional model list request
+type ModelList struct {
+	// Unset when NotModified
+	Models     []string
+	Validators ModelListValidators
+	// The upstream confirmed the list described by the validators
+	NotModified bool
+}
+
+// ModelListFreshness describes a provider's cached upstream model list
+type ModelListFreshness struct {
+	Provider string `json:"provider"`
+	// When the list was last downloaded in full
+	FetchedAt time.Time `json:"fetched_at"`
+	// When the upstream last sent or confirmed the list
+	CheckedAt time.Time `json:"checked_at"`
+	// Until when the list is served without asking the upstream
+	ExpiresAt time.Time `json:"expires_at"`
+	ETag      string    `json:"etag,omitempty"`
+	// How many times an unchanged list was revalidated with a 304
+	Revalidations int `json:"revalidations"`
+	// Set when the last attempt failed and an older list is served
+	Stale bool   `json:"stale,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// discoveryTTL bounds how often upstream model lists are fetched
+const discoveryTTL = 5 * time.Minute
+
+// discoveryTimeout bounds each upstream model list request
+const discoveryTimeout = 5 * time.Second
+
+// discoveryParallel bounds the upstream model list requests made at once
+const discoveryParallel = 8
+
+// discoveryCacheEntries and discoveryCacheBytes bound the in-memory cache
+// of model lists registries start with
+const (
+	discoveryCacheEntries = 1000
+	discoveryCacheBytes   = 16 << 20
+)
+
+// discoveryRetention is how long a model list is kept in the cache, to be
+// served when its upstream fails and revalidated when it recovers
+const discoveryRetention = 24 * time.Hour
+
+// discoveryEntry is a provider's upstream model list as kept in the cache
+type discoveryEntry struct {
+	Models     []string            `json:"models"`
+	Validators ModelListValidators `json:"validators"`
+	// When the list was last downloaded, last downloaded or revalidated,
+	// and last asked for
+	Fetched       time.Time `json:"fetched"`
+	Confirmed     time.Time `json:"confirmed"`
+	Attempted     time.Time `json:"attempted"`
+	Revalidations int       `json:"revalidations"`
+	// Set when the last attempt failed
+	Error string `json:"error,omitempty"`
+}
+
+// discoveryKey is the cache key of a provider's model list
+func discoveryKey(name string) string {
+	return "models:" + name
+}
+
+// cachedModelList returns the cached model list of a provider. Cache
+// errors are logged and treated as misses.
+func (r *Registry) cachedModelList(ctx context.Context, name string) (discoveryEntry, bool) {
+	var entry discoveryEntry
+	data, ok, err := r.cache.Get(ctx, discoveryKey(name))
+	if err != nil {
+		log.Printf("model list cache: %v", err)
+		return entry, false
+	}
+	if !ok || json.Unmarshal(data, &entry) != nil {
+		return discoveryEntry{}, false
+	}
+	return entry, true
+}
+
+func (r *Registry) cacheModelList(ctx context.Context, name string, entry discoveryEntry) {
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = r.cache.Set(ctx, discoveryKey(name), data, discoveryRetention)
+	}
+	if err != nil {
+		log.Printf("model list cache: %v", err)
+	}
+}
+
+// ModelListing is a model known to the gateway
+type ModelListing struct {
+	ID string `json:"id"`
+	// OwnedBy is the provider that declared or discovered the model
+	OwnedBy string `json:"owned_by"`
+	// Provider is the provider requests for the model route to, empty when
+	// no provider matches without request attributes
+	Provider string `json:"provider,omitempty"`
+	// Discovered is set for models only known from the upstream's model list
+	Discovered bool `json:"discovered"` // Capabilities of the model on the provider it routes to, or else on
+	// its owner
+	Capabilities ModelCapabilities `json:"capabilities"`
+}
+
+// Models lists the models of all registered providers, sorted by ID: the
+// declared SupportedModels plus, for ModelListers, the upstream's model
+// list, cached for discoveryTTL and then revalidated with a conditional
+// request where the upstream supports it. Upstreams that fail to list
+// contribute their last good list, or their declared models only. A model offered by several providers is
+// listed once, owned by the provider it routes to when that is one of them.
+func (r *Registry) Models(ctx context.Context) []ModelListing {
+	r.mu.RLock()
+	providers := make(map[string]Provider, len(r.providers))
+	for name, p := range r.providers {
+		providers[name] = p
+	}
+	r.mu.RUnlock()
+
+	discovered := r.discoverModels(ctx, providers)
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// owners maps each model to the providers offering it, in name order
+	owners := make(map[string][]string)
+	declared := make(map[string]bool)
+	add := func(id, name string) {
+		for _, owner := range owners[id] {
+			if owner == name {
+				return
+			}
+		}
+		owners[id] = append(owners[id], name)
+	}
+	for _, name := range names {
+		for _, id := range providers[name].GetCapabilities().SupportedModels {
+			add(id, name)
+			declared[id] = true
+		}
+		for _, id := range discovered[name] {
+			add(id, name)
+		}
+	}
+
+	out := make([]ModelListing, 0, len(owners))
+	for id, offered := range owners {
+		listing := ModelListing{ID: id, OwnedBy: offered[0], Discovered: !declared[id]}
+		serving := providers[listing.OwnedBy]
+		if p, err := r.Route(&RouteRequest{Model: id}); err == nil {
+			serving = p
+			listing.Provider = p.GetInfo().Name
+			for _, owner := range offered {
+				if owner == listing.Provider {
+					listing.OwnedBy = owner
+				}
+			}
+		}
+		listing.Capabilities = r.ModelCapabilities(serving, id)
+		out = append(out, listing)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// discoverModels returns the upstream model lists of the ModelListers among
+// providers, keyed by provider name, fetching expired lists concurrently
+func (r *Registry) discoverModels(ctx context.Context, providers map[string]Provider) map[string][]string {
+	out := make(map[string][]string)
+	// Failed fetches keep the last good list, so none cancels the others
+	var (
+		mu sync.Mutex
+		g  errgroup.Group
+	)
+	g.SetLimit(discoveryParallel)
+	for name, p := range providers {
+		lister, ok := p.(ModelLister)
+		if !ok {
+			continue
+		}
+
+		entry, cached := r.cachedModelList(ctx, name)
+		if cached && r.now().Sub(entry.Attempted) < discoveryTTL {
+			out[name] = entry.Models
+			continue
+		}
+
+		name, lister, entry := name, lister, entry
+		g.Go(func() error {
+			lctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+			defer cancel()
+			next := r.fetchModels(lctx, lister, entry)
+			r.cacheModelList(ctx, name, next)
+			mu.Lock()
+			out[name] = next.Models
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return out
+}
+
+// fetchModels refreshes a cached list, revalidating it when the upstream
+// supports conditional requests. On failure the last good list is kept.
+func (r *Registry) fetchModels(ctx context.Context, lister ModelLister, entry discoveryEntry) discoveryEntry {
+	now := r.now()
+	entry.Attempted = now
+	var (
+		list *ModelList
+		err  error
+	)
+	if rl, ok := lister.(RevalidatingModelLister); ok {
+		v := entry.Validators
+		if entry.Fetched.IsZero() {
+			v = ModelListValidators{}
+		}
+		list, err = rl.ListModelsIfChanged(ctx, v)
+	} else {
+		var models []string
+		if models, err = lister.ListModels(ctx); err == nil {
+			list = &ModelList{Models: models}
+		}
+	}
+
+	entry.Error = ""
+	switch {
+	case err != nil:
+		entry.Error = err.Error()
+	case list.NotModified:
+		entry.Confirmed = now
+		entry.Revalidations++
+	default:
+		entry.Models, entry.Validators = list.Models, list.Validators
+		entry.Fetched, entry.Confirmed = now, now
+		entry.Revalidations = 0
+	}
+	return entry
+}
+
+// ModelListFreshness reports the cached upstream model lists, sorted by
+// provider
+func (r *Registry) ModelListFreshness() []ModelListFreshness {
+	r.mu.RLock()
+	var names []string
+	for name, p := range r.providers {
+		if _, ok := p.(ModelLister); ok {
+			names = append(names, name)
+		}
+	}
+	r.mu.RUnlock()
+
+	out := make([]ModelListFreshness, 0, len(names))
+	for _, name := range names {
+		e, ok := r.cachedModelList(context.Background(), name)
+		if !ok {
+			continue
+		}
+		out = append(out, ModelListFreshness{
+			Provider:      name,
+			FetchedAt:     e.Fetched,
+			CheckedAt:     e.Confirmed,
+			ExpiresAt:     e.Attempted.Add(discoveryTTL),
+			ETag:          e.Validators.ETag,
+			Revalidations: e.Revalidations,
+			Stale:         e.Error != "",
+			Error:         e.Error,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Provider < out[j].Provider })
+	return out
+}
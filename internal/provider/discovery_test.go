@@ -0,0 +1,181 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/cache"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// listerStub is a stubProvider that lists models
+type listerStub struct {
+	stubProvider
+	models []string
+	err    error
+	calls  int
+}
+
+func (l *listerStub) ListModels(ctx context.Context) ([]string, error) {
+	l.calls++
+	return l.models, l.err
+}
+
+func TestRegistryModels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"gpt-4o","object":"model"},{"id":"o3-mini","object":"model"}]}`)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat", "gpt-4o"}}}}
+	cfg.OpenAI.APIKey = "test-key"
+	cfg.OpenAI.BaseURL = srv.URL
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	flaky := &listerStub{stubProvider: stubProvider{name: "flaky"}, models: []string{"flaky-1"}}
+	_ = r.RegisterProvider("flaky", flaky)
+
+	listings := make(map[string]ModelListing)
+	for _, m := range r.Models(context.Background()) {
+		if _, dup := listings[m.ID]; dup {
+			t.Errorf("Model %s listed twice", m.ID)
+		}
+		listings[m.ID] = m
+	}
+	for id, want := range map[string]ModelListing{
+		"demo-chat":   {ID: "demo-chat", OwnedBy: "demo", Provider: "demo"},
+		"gpt-4o":      {ID: "gpt-4o", OwnedBy: "demo", Provider: "demo"},
+		"gpt-4-turbo": {ID: "gpt-4-turbo", OwnedBy: "openai", Provider: "openai"},
+		"o3-mini":     {ID: "o3-mini", OwnedBy: "openai", Discovered: true},
+		"flaky-1":     {ID: "flaky-1", OwnedBy: "flaky", Discovered: true},
+	} {
+		got := listings[id]
+		got.Capabilities = ModelCapabilities{}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %+v, got %+v", id, want, got)
+		}
+	}
+	if caps := listings["gpt-4-turbo"].Capabilities; caps.Family != "gpt-4-turbo" || caps.ContextLength != 128000 {
+		t.Errorf("Expected gpt-4-turbo family capabilities, got %+v", caps)
+	}
+
+	// Lists are cached, and the last good list survives failures
+	flaky.err = errors.New("unavailable")
+	flaky.models = nil
+	r.Models(context.Background())
+	if flaky.calls != 1 {
+		t.Errorf("Expected a cached list, got %d calls", flaky.calls)
+	}
+	now = now.Add(discoveryTTL)
+	found := false
+	for _, m := range r.Models(context.Background()) {
+		found = found || m.ID == "flaky-1"
+	}
+	if flaky.calls != 2 || !found {
+		t.Errorf("Expected a refetch keeping the last good list, got %d calls, found %v", flaky.calls, found)
+	}
+}
+
+func TestModelListRevalidation(t *testing.T) {
+	var conditional []string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditional = append(conditional, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"v1"` && status == http.StatusOK {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"o3-mini","object":"model"}]}`)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "test-key"
+	cfg.OpenAI.BaseURL = srv.URL
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	discovered := func() bool {
+		for _, m := range r.Models(context.Background()) {
+			if m.ID == "o3-mini" {
+				return true
+			}
+		}
+		return false
+	}
+	if !discovered() {
+		t.Fatal("Expected the upstream list")
+	}
+	fetched := now
+
+	now = now.Add(discoveryTTL)
+	if !discovered() {
+		t.Error("Expected the cached list kept on 304")
+	}
+	if len(conditional) != 2 || conditional[0] != "" || conditional[1] != `"v1"` {
+		t.Errorf("Expected an unconditional fetch then a revalidation, got %q", conditional)
+	}
+	f := r.ModelListFreshness()
+	if len(f) != 1 || !f[0].FetchedAt.Equal(fetched) || !f[0].CheckedAt.Equal(now) || f[0].Revalidations != 1 || f[0].ETag != `"v1"` || f[0].Stale {
+		t.Errorf("Unexpected freshness %+v", f)
+	}
+
+	status = http.StatusBadGateway
+	now = now.Add(discoveryTTL)
+	if !discovered() {
+		t.Error("Expected the last good list kept when the upstream fails")
+	}
+	if f := r.ModelListFreshness(); !f[0].Stale || f[0].Error == "" || !f[0].ExpiresAt.Equal(now.Add(discoveryTTL)) {
+		t.Errorf("Expected a stale list, got %+v", f)
+	}
+}
+
+func TestModelListSharedCache(t *testing.T) {
+	shared := cache.NewMemory(10, 1<<20)
+	var listers []*listerStub
+	for i := 0; i < 2; i++ {
+		r, err := NewRegistry(&config.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		r.cache = shared
+		l := &listerStub{stubProvider: stubProvider{name: "upstream"}, models: []string{"upstream-1"}}
+		_ = r.RegisterProvider("upstream", l)
+		listers = append(listers, l)
+
+		if models := r.Models(context.Background()); len(models) != 1 || models[0].ID != "upstream-1" {
+			t.Fatalf("Expected the upstream list, got %+v", models)
+		}
+	}
+	// The second registry, e.g. another replica, reads the first's list
+	if listers[0].calls != 1 || listers[1].calls != 0 {
+		t.Errorf("Expected one upstream fetch, got %d and %d", listers[0].calls, listers[1].calls)
+	}
+}
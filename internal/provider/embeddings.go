@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ErrEmbeddingsUnsupported is returned by Embed on providers without an
+// embeddings API
+var ErrEmbeddingsUnsupported = errors.New("embeddings are not supported by this provider")
+
+// ValidateEmbeddingRequest validates an embedding request
+func ValidateEmbeddingRequest(req *EmbeddingRequest) error {
+	if req == nil {
+		return fmt.Errorf("request cannot be nil")
+	}
+	if req.Model == "" {
+		return fmt.Errorf("model is required")
+	}
+	if len(req.Input) == 0 {
+		return fmt.Errorf("input is required")
+	}
+	if req.Dimensions != nil && *req.Dimensions <= 0 {
+		return fmt.Errorf("dimensions must be positive")
+	}
+	return nil
+}
+
+// CreateEmbeddingResponse wraps vectors, in input order, as a response
+func CreateEmbeddingResponse(model string, vectors [][]float32, usage Usage) *EmbeddingResponse {
+	data := make([]Embedding, len(vectors))
+	for i, v := range vectors {
+		data[i] = Embedding{Object: "embedding", Index: i, Embedding: v}
+	}
+	return &EmbeddingResponse{Object: "list", Data: data, Model: model, Usage: usage}
+}
+
+// openAIEmbed calls the embeddings endpoint of an OpenAI-compatible API
+func openAIEmbed(ctx context.Context, client *openai.Client, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	if err := ValidateEmbeddingRequest(req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	embReq := openai.EmbeddingRequest{
+		Input: req.Input,
+		Model: openai.EmbeddingModel(req.Model),
+	}
+	if req.Dimensions != nil {
+		embReq.Dimensions = *req.Dimensions
+	}
+
+	resp, err := client.CreateEmbeddings(ctx, embReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries carry their input index and need not arrive in order
+	vectors := make([][]float32, len(req.Input))
+	for _, e := range resp.Data {
+		if e.Index < 0 || e.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", e.Index)
+		}
+		vectors[e.Index] = e.Embedding
+	}
+
+	model := string(resp.Model)
+	if model == "" {
+		model = req.Model
+	}
+	return CreateEmbeddingResponse(model, vectors, Usage{
+		PromptTokens: resp.Usage.PromptTokens,
+		TotalTokens:  resp.Usage.TotalTokens,
+	}), nil
+}
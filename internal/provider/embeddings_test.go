@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestOpenAIEmbed(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		// Entries out of input order
+		fmt.Fprint(w, `{"object":"list","model":"text-embedding-3-small","data":[
+			{"object":"embedding","index":1,"embedding":[0.3,0.4]},
+			{"object":"embedding","index":0,"embedding":[0.1,0.2]}],
+			"usage":{"prompt_tokens":5,"total_tokens":5}}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewOpenAIProvider("test-key", srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dims := 2
+	resp, err := p.Embed(context.Background(), &EmbeddingRequest{Model: "text-embedding-3-small", Input: []string{"a", "b"}, Dimensions: &dims})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+
+	if got["model"] != "text-embedding-3-small" || got["dimensions"] != float64(2) || !reflect.DeepEqual(got["input"], []interface{}{"a", "b"}) {
+		t.Errorf("Unexpected upstream request %v", got)
+	}
+	want := CreateEmbeddingResponse("text-embedding-3-small", [][]float32{{0.1, 0.2}, {0.3, 0.4}}, Usage{PromptTokens: 5, TotalTokens: 5})
+	if !reflect.DeepEqual(resp, want) {
+		t.Errorf("Expected %+v, got %+v", want, resp)
+	}
+}
+
+func TestMockEmbed(t *testing.T) {
+	p, err := NewMockProvider(config.MockConfig{Name: "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := p.Embed(context.Background(), &EmbeddingRequest{Model: "m", Input: []string{"same", "other", "same"}})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if len(resp.Data) != 3 || len(resp.Data[0].Embedding) != defaultMockDimensions {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+	if !reflect.DeepEqual(resp.Data[0].Embedding, resp.Data[2].Embedding) || reflect.DeepEqual(resp.Data[0].Embedding, resp.Data[1].Embedding) {
+		t.Error("Expected equal inputs, and only those, to embed equally")
+	}
+	var norm float64
+	for _, x := range resp.Data[1].Embedding {
+		norm += float64(x) * float64(x)
+	}
+	if math.Abs(norm-1) > 1e-5 {
+		t.Errorf("Expected a unit vector, got norm %v", norm)
+	}
+}
+
+func TestEmbedUnsupported(t *testing.T) {
+	p, err := NewCohereProvider("test-key", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Embed(context.Background(), &EmbeddingRequest{Model: "m", Input: []string{"x"}}); !errors.Is(err, ErrEmbeddingsUnsupported) {
+		t.Errorf("Expected ErrEmbeddingsUnsupported, got %v", err)
+	}
+}
+
+func TestRegistryEmbeddingRouting(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "test-key"
+	cfg.Gemini.APIKey = "test-key"
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for model, want := range map[string]string{
+		"text-embedding-3-small": "openai",
+		"text-embedding-ada-002": "openai",
+		"text-embedding-004":     "gemini",
+		"embedding-001":          "gemini",
+	} {
+		p, err := r.GetProviderForModel(model)
+		if err != nil {
+			t.Errorf("%s: %v", model, err)
+			continue
+		}
+		if name := p.GetInfo().Name; name != want {
+			t.Errorf("%s: expected %s, got %s", model, want, name)
+		}
+	}
+}
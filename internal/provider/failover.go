@@ -0,0 +1,139 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// FailoverState describes an active/standby provider pair
+type FailoverState struct {
+	Active  string `json:"active"`
+	Standby string `json:"standby"`
+	// Serving is the provider currently receiving the pair's traffic
+	Serving string `json:"serving"`
+	// Automatic is set when the standby took over after sustained failure
+	Automatic bool `json:"automatic"`
+	// ConsecutiveFailures counts failed requests to the active provider
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	FailureThreshold    int       `json:"failure_threshold"`
+	SwitchedAt          time.Time `json:"switched_at"`
+}
+
+// failoverSet tracks which side of each pair is serving, keyed by the
+// active provider's name
+type failoverSet struct {
+	mu    sync.Mutex
+	pairs map[string]*FailoverState
+}
+
+func newFailoverSet(pairs []config.FailoverPair, providers map[string]Provider) (*failoverSet, error) {
+	fs := &failoverSet{pairs: make(map[string]*FailoverState)}
+	for _, p := range pairs {
+		for _, name := range []string{p.Active, p.Standby} {
+			if _, ok := providers[name]; !ok {
+				return nil, fmt.Errorf("failover provider %s not configured", name)
+			}
+		}
+		fs.pairs[p.Active] = &FailoverState{
+			Active:           p.Active,
+			Standby:          p.Standby,
+			Serving:          p.Active,
+			FailureThreshold: p.FailureThreshold,
+		}
+	}
+	return fs, nil
+}
+
+// serving returns the provider that should receive traffic addressed to name
+func (fs *failoverSet) serving(name string) string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if st, ok := fs.pairs[name]; ok {
+		return st.Serving
+	}
+	return name
+}
+
+// lookup returns the provider registered under name, or its standby while
+// the pair is failed over, unless it is disabled; callers must hold r.mu
+func (r *Registry) lookup(name string) (Provider, bool) {
+	name = r.failover.serving(name)
+	if r.disabled[name] {
+		return nil, false
+	}
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// SetFailover switches the pair whose active provider is active to its
+// standby, or back to the active provider when toStandby is false
+func (r *Registry) SetFailover(active string, toStandby bool) (FailoverState, error) {
+	fs := r.failover
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	st, ok := fs.pairs[active]
+	if !ok {
+		return FailoverState{}, fmt.Errorf("provider %s has no standby", active)
+	}
+	serving := st.Active
+	if toStandby {
+		serving = st.Standby
+	}
+	if st.Serving != serving {
+		st.Serving = serving
+		st.SwitchedAt = r.now()
+	}
+	st.Automatic = false
+	st.ConsecutiveFailures = 0
+	return *st, nil
+}
+
+// FailoverStates returns the state of every pair, sorted by active provider
+func (r *Registry) FailoverStates() []FailoverState {
+	fs := r.failover
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	out := make([]FailoverState, 0, len(fs.pairs))
+	for _, st := range fs.pairs {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Active < out[j].Active })
+	return out
+}
+
+// ReportOutcome records the result of an upstream request to the named
+// provider. After FailureThreshold consecutive failures an active provider
+// fails over to its standby; switching back is left to the operator.
+// Cancellations by the client do not count as failures.
+func (r *Registry) ReportOutcome(name string, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	fs := r.failover
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	st, ok := fs.pairs[name]
+	if !ok || st.Serving != st.Active {
+		return
+	}
+	if err == nil {
+		st.ConsecutiveFailures = 0
+		return
+	}
+	st.ConsecutiveFailures++
+	if st.FailureThreshold > 0 && st.ConsecutiveFailures >= st.FailureThreshold {
+		st.Serving = st.Standby
+		st.Automatic = true
+		st.SwitchedAt = r.now()
+	}
+}
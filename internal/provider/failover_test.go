@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func newFailoverRegistry(t *testing.T, threshold int) *Registry {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "test-openai-key"
+	cfg.Mistral.APIKey = "test-mistral-key"
+	cfg.Failover = []config.FailoverPair{{Active: "openai", Standby: "mistral", FailureThreshold: threshold}}
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+	return r
+}
+
+func routedName(t *testing.T, r *Registry, model string) string {
+	t.Helper()
+	p, err := r.Route(&RouteRequest{Model: model})
+	if err != nil {
+		t.Fatalf("Failed to route %s: %v", model, err)
+	}
+	return p.GetInfo().Name
+}
+
+func TestRegistryManualFailover(t *testing.T) {
+	r := newFailoverRegistry(t, 0)
+
+	if got := routedName(t, r, "gpt-4o"); got != "openai" {
+		t.Fatalf("Expected active provider, got %s", got)
+	}
+
+	st, err := r.SetFailover("openai", true)
+	if err != nil || st.Serving != "mistral" || st.SwitchedAt.IsZero() {
+		t.Fatalf("Unexpected failover result: %+v, %v", st, err)
+	}
+	if got := routedName(t, r, "gpt-4o"); got != "mistral" {
+		t.Errorf("Expected standby while failed over, got %s", got)
+	}
+	// Traffic addressed to the standby itself is unaffected
+	if got := routedName(t, r, "mistral-large-latest"); got != "mistral" {
+		t.Errorf("Expected mistral, got %s", got)
+	}
+
+	if _, err := r.SetFailover("openai", false); err != nil {
+		t.Fatalf("Switch back failed: %v", err)
+	}
+	if got := routedName(t, r, "gpt-4o"); got != "openai" {
+		t.Errorf("Expected active provider after switching back, got %s", got)
+	}
+
+	if _, err := r.SetFailover("mistral", true); err == nil {
+		t.Error("Expected error for a provider without a standby")
+	}
+}
+
+func TestRegistryAutomaticFailover(t *testing.T) {
+	r := newFailoverRegistry(t, 3)
+	upstreamErr := errors.New("502 bad gateway")
+
+	r.ReportOutcome("openai", upstreamErr)
+	r.ReportOutcome("openai", upstreamErr)
+	r.ReportOutcome("openai", nil)
+	r.ReportOutcome("openai", upstreamErr)
+	r.ReportOutcome("openai", context.Canceled)
+	if got := routedName(t, r, "gpt-4o"); got != "openai" {
+		t.Fatalf("Failures were not consecutive; expected openai, got %s", got)
+	}
+
+	r.ReportOutcome("openai", upstreamErr)
+	r.ReportOutcome("openai", upstreamErr)
+	if got := routedName(t, r, "gpt-4o"); got != "mistral" {
+		t.Fatalf("Expected automatic failover to mistral, got %s", got)
+	}
+	states := r.FailoverStates()
+	if len(states) != 1 || !states[0].Automatic {
+		t.Errorf("Expected automatic failover state, got %+v", states)
+	}
+}
+
+func TestRegistryFailoverRequiresProviders(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "test-openai-key"
+	cfg.Failover = []config.FailoverPair{{Active: "openai", Standby: "mistral"}}
+	if _, err := NewRegistry(cfg); err == nil {
+		t.Error("Expected error when the standby is not configured")
+	}
+}
@@ -0,0 +1,98 @@
+package provider
+
+import "sort"
+
+// FidelityIssue describes a request field that will not reach the upstream
+// provider exactly as the client sent it
+type FidelityIssue struct {
+	Field  string `json:"field"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Fidelity issue kinds
+const (
+	// FidelityDropped means the field is silently removed before dispatch
+	FidelityDropped = "dropped"
+	// FidelityUnsupported means the gateway itself cannot represent the field
+	FidelityUnsupported = "unsupported"
+	// FidelityDegraded means the field is sent in an approximated form
+	FidelityDegraded = "degraded"
+)
+
+// CheckFidelity reports the fields of req that a provider with the given
+// capabilities, serving a model with capabilities model, will drop or
+// approximate
+func CheckFidelity(caps ProviderCapabilities, model ModelCapabilities, req *StandardRequest) []FidelityIssue {
+	if req == nil {
+		return nil
+	}
+
+	var issues []FidelityIssue
+
+	params := make(map[string]bool, len(caps.SupportedParameters))
+	for _, p := range caps.SupportedParameters {
+		params[p] = true
+	}
+
+	checkParam := func(set bool, name string) {
+		if set && !params[name] {
+			issues = append(issues, FidelityIssue{Field: name, Kind: FidelityDropped, Detail: "parameter not supported by provider"})
+		}
+	}
+
+	checkParam(req.Temperature != nil, "temperature")
+	checkParam(req.TopP != nil, "top_p")
+	checkParam(req.MaxTokens != nil, "max_tokens")
+
+	if len(req.Functions) > 0 {
+		switch {
+		case !caps.SupportsFunctions || !params["functions"]:
+			issues = append(issues, FidelityIssue{Field: "functions", Kind: FidelityDropped, Detail: "function calling not supported by provider"})
+		case !model.SupportsFunctions:
+			issues = append(issues, FidelityIssue{Field: "functions", Kind: FidelityDropped, Detail: "function calling not supported by model"})
+		}
+	}
+
+	if !caps.SupportsPassthrough {
+		fields := make([]string, 0, len(req.Passthrough))
+		for field := range req.Passthrough {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			issues = append(issues, FidelityIssue{Field: field, Kind: FidelityDropped, Detail: "parameter not forwarded by provider"})
+		}
+	}
+
+	if req.Stream && !caps.SupportsStreaming {
+		issues = append(issues, FidelityIssue{Field: "stream", Kind: FidelityDegraded, Detail: "provider does not stream; response is buffered"})
+	}
+
+	if !caps.SupportsSystemRole {
+		for _, msg := range req.Messages {
+			if msg.Role == RoleSystem {
+				issues = append(issues, FidelityIssue{Field: "messages.system", Kind: FidelityDegraded, Detail: "system prompt merged into first user message"})
+				break
+			}
+		}
+	}
+
+	return issues
+}
+
+// Warning converts the issue into a client-facing warning
+func (i FidelityIssue) Warning() Warning {
+	code := WarningParameterDropped
+	switch i.Kind {
+	case FidelityUnsupported:
+		code = WarningParameterUnsupported
+	case FidelityDegraded:
+		code = WarningParameterDegraded
+	}
+	msg := i.Field + " " + i.Kind
+	if i.Detail != "" {
+		msg += ": " + i.Detail
+	}
+	return Warning{Code: code, Message: msg, Param: i.Field}
+}
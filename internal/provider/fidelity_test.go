@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCheckFidelity(t *testing.T) {
+	gemini, err := NewGeminiProvider("test-key", "", "gemini-pro")
+	if err != nil {
+		t.Fatalf("Failed to create Gemini provider: %v", err)
+	}
+	openai, err := NewOpenAIProvider("test-key", "", "gpt-4")
+	if err != nil {
+		t.Fatalf("Failed to create OpenAI provider: %v", err)
+	}
+
+	req := &StandardRequest{
+		Model: "any",
+		Messages: []Message{
+			{Role: RoleSystem, Content: "be brief"},
+			{Role: RoleUser, Content: "hi"},
+		},
+		Temperature: floatPtr(0.5),
+		Functions:   []Function{{Name: "lookup"}},
+	}
+
+	issues := checkFidelity(gemini.GetCapabilities(), req)
+	kinds := make(map[string]string)
+	for _, issue := range issues {
+		kinds[issue.Field] = issue.Kind
+	}
+	if _, ok := kinds["functions"]; ok {
+		t.Error("Functions are supported by gemini and should not be reported")
+	}
+	if kinds["messages.system"] != FidelityDegraded {
+		t.Errorf("Expected system role to be degraded for gemini, got %q", kinds["messages.system"])
+	}
+	if _, ok := kinds["temperature"]; ok {
+		t.Error("Temperature is supported by gemini and should not be reported")
+	}
+
+	issues = checkFidelity(ProviderCapabilities{SupportsSystemRole: true, SupportedParameters: []string{"temperature"}}, req)
+	if len(issues) != 1 || issues[0].Field != "functions" || issues[0].Kind != FidelityDropped {
+		t.Errorf("Expected only functions to be dropped, got %v", issues)
+	}
+
+	if issues := checkFidelity(openai.GetCapabilities(), req); len(issues) != 0 {
+		t.Errorf("Expected no issues for openai, got %v", issues)
+	}
+
+	req.Passthrough = map[string]json.RawMessage{"seed": json.RawMessage("7"), "logit_bias": json.RawMessage("{}")}
+	if issues := checkFidelity(openai.GetCapabilities(), req); len(issues) != 0 {
+		t.Errorf("Expected passthrough fields to be forwarded by openai, got %v", issues)
+	}
+	issues = checkFidelity(ProviderCapabilities{SupportsSystemRole: true, SupportsFunctions: true, SupportedParameters: []string{"temperature", "functions"}}, req)
+	if len(issues) != 2 || issues[0].Field != "logit_bias" || issues[1].Field != "seed" || issues[0].Kind != FidelityDropped {
+		t.Errorf("Expected passthrough fields to be dropped in order, got %v", issues)
+	}
+
+	// Function calling is also a property of the model
+	req.Model = "deepseek-reasoner"
+	issues = checkFidelity(openai.GetCapabilities(), req)
+	if len(issues) != 1 || issues[0].Field != "functions" || issues[0].Detail != "function calling not supported by model" {
+		t.Errorf("Expected functions to be dropped for deepseek-reasoner, got %v", issues)
+	}
+
+	if issues := CheckFidelity(ProviderCapabilities{}, ModelCapabilities{}, nil); issues != nil {
+		t.Errorf("Expected no issues for nil request, got %v", issues)
+	}
+}
+
+func TestFidelityIssueWarning(t *testing.T) {
+	w := FidelityIssue{Field: "logprobs", Kind: FidelityUnsupported, Detail: "not forwarded"}.Warning()
+	if w.Code != WarningParameterUnsupported {
+		t.Errorf("Expected code %s, got %s", WarningParameterUnsupported, w.Code)
+	}
+	if w.Param != "logprobs" {
+		t.Errorf("Expected param 'logprobs', got '%s'", w.Param)
+	}
+	if w.Message != "logprobs unsupported: not forwarded" {
+		t.Errorf("Unexpected message '%s'", w.Message)
+	}
+}
+
+// checkFidelity checks req against caps and the model capabilities they imply
+func checkFidelity(caps ProviderCapabilities, req *StandardRequest) []FidelityIssue {
+	return CheckFidelity(caps, CapabilitiesFor(caps, req.Model, nil), req)
+}
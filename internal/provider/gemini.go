@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -49,7 +50,7 @@ func NewGeminiProvider(apiKey, baseURL, modelName string) (*GeminiProvider, erro
 		MaxTokens:           2048,
 		MaxContextLength:    32768, // For Gemini Pro
 		SupportedModels:     []string{"gemini-pro", "gemini-pro-vision", "gemini-1.5-pro", "gemini-1.5-flash"},
-		SupportedParameters: []string{"temperature", "top_p", "max_tokens", "stream"},
+		SupportedParameters: []string{"temperature", "top_p", "max_tokens", "stream", "functions"},
 	}
 
 	return &GeminiProvider{
@@ -65,31 +66,12 @@ func (g *GeminiProvider) Generate(ctx context.Context, req *GenerateRequest) (*G
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	model := g.client.GenerativeModel(req.Model)
-
-	// Configure model parameters
-	if req.Temperature != nil {
-		temp := float32(*req.Temperature)
-		model.Temperature = &temp
-	}
-
-	if req.TopP != nil {
-		topP := float32(*req.TopP)
-		model.TopP = &topP
-	}
-
-	if req.MaxTokens != nil {
-		maxTokens := int32(*req.MaxTokens)
-		model.MaxOutputTokens = &maxTokens
-	}
-
-	// Convert messages to Gemini format
-	parts, err := g.convertMessagesToParts(req.Messages)
+	chat, parts, err := g.newChat(req.StandardRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert messages: %w", err)
 	}
 
-	resp, err := model.GenerateContent(ctx, parts...)
+	resp, err := chat.SendMessage(ctx, parts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate content: %w", err)
 	}
@@ -110,31 +92,12 @@ func (g *GeminiProvider) StreamGenerate(ctx context.Context, req *GenerateReques
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	model := g.client.GenerativeModel(req.Model)
-
-	// Configure model parameters
-	if req.Temperature != nil {
-		temp := float32(*req.Temperature)
-		model.Temperature = &temp
-	}
-
-	if req.TopP != nil {
-		topP := float32(*req.TopP)
-		model.TopP = &topP
-	}
-
-	if req.MaxTokens != nil {
-		maxTokens := int32(*req.MaxTokens)
-		model.MaxOutputTokens = &maxTokens
-	}
-
-	// Convert messages to Gemini format
-	parts, err := g.convertMessagesToParts(req.Messages)
+	chat, parts, err := g.newChat(req.StandardRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert messages: %w", err)
 	}
 
-	iter := model.GenerateContentStream(ctx, parts...)
+	iter := chat.SendMessageStream(ctx, parts...)
 
 	// Create a pipe for streaming the response
 	pr, pw := io.Pipe()
@@ -142,14 +105,17 @@ func (g *GeminiProvider) StreamGenerate(ctx context.Context, req *GenerateReques
 	go func() {
 		defer pw.Close()
 
-		chunkID := fmt.Sprintf("chatcmpl-%d", time.Now().Unix())
+		// Gemini has no response IDs; every chunk shares one ID and timestamp
+		chunkID, created := NewResponseID(), time.Now().Unix()
 		chunkIndex := 0
+		calledFunction := false
 
 		for {
 			resp, err := iter.Next()
-			if err == io.EOF {
+			if err == iterator.Done {
 				// Send final chunk
 				finalChunk := CreateStreamChunk(chunkID, req.Model, []Choice{}, true)
+				finalChunk.Created = created
 				if chunkData, marshalErr := json.Marshal(finalChunk); marshalErr == nil {
 					pw.Write(append(chunkData, '\n'))
 				}
@@ -166,6 +132,19 @@ func (g *GeminiProvider) StreamGenerate(ctx context.Context, req *GenerateReques
 				pw.CloseWithError(fmt.Errorf("failed to transform stream chunk: %w", err))
 				return
 			}
+			chunk.Created = created
+
+			// The call and the finish reason may arrive in different chunks
+			for i := range chunk.Choices {
+				choice := &chunk.Choices[i]
+				if choice.Delta.FunctionCall != nil {
+					calledFunction = true
+				}
+				if calledFunction && choice.FinishReason != nil && *choice.FinishReason == FinishReasonStop {
+					reason := FinishReasonFunctionCall
+					choice.FinishReason = &reason
+				}
+			}
 
 			// Write chunk as JSON
 			chunkData, err := json.Marshal(chunk)
@@ -186,6 +165,81 @@ func (g *GeminiProvider) StreamGenerate(ctx context.Context, req *GenerateReques
 	return pr, nil
 }
 
+// Embed returns embeddings from the Gemini batch embeddings API, which
+// reports no token usage
+func (g *GeminiProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	if err := ValidateEmbeddingRequest(req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if req.Dimensions != nil {
+		return nil, fmt.Errorf("invalid request: dimensions is not supported by gemini")
+	}
+
+	model := g.client.EmbeddingModel(req.Model)
+	batch := model.NewBatch()
+	for _, in := range req.Input {
+		batch.AddContent(genai.Text(in))
+	}
+
+	resp, err := model.BatchEmbedContents(ctx, batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed content: %w", err)
+	}
+	if len(resp.Embeddings) != len(req.Input) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(req.Input), len(resp.Embeddings))
+	}
+
+	vectors := make([][]float32, len(resp.Embeddings))
+	for i, e := range resp.Embeddings {
+		vectors[i] = e.Values
+	}
+	return CreateEmbeddingResponse(req.Model, vectors, Usage{}), nil
+}
+
+// geminiInlineAudioLimit is the largest audio sent inline with a request
+const geminiInlineAudioLimit = 20 << 20
+
+// Transcribe asks a multimodal Gemini model to transcribe the audio
+func (g *GeminiProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	if err := ValidateTranscriptionRequest(req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if len(req.Audio) > geminiInlineAudioLimit {
+		return nil, fmt.Errorf("invalid request: gemini accepts at most %d MB of audio", geminiInlineAudioLimit>>20)
+	}
+	mimeType, err := AudioMIMEType(req)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	model := g.client.GenerativeModel(req.Model)
+	if req.Temperature != nil {
+		temp := float32(*req.Temperature)
+		model.Temperature = &temp
+	}
+
+	instruction := "Transcribe the speech in this audio verbatim. Reply with the transcript only."
+	if req.Language != "" {
+		instruction += fmt.Sprintf(" The audio is in the language with ISO-639-1 code %q.", req.Language)
+	}
+	if req.Prompt != "" {
+		instruction += " Context for names and spelling: " + req.Prompt
+	}
+
+	resp, err := model.GenerateContent(ctx, genai.Blob{MIMEType: mimeType, Data: req.Audio}, genai.Text(instruction))
+	if err != nil {
+		return nil, fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+	if len(resp.Candidates) == 0 {
+		return nil, fmt.Errorf("no transcript returned")
+	}
+	msg, err := candidateMessage(resp.Candidates[0])
+	if err != nil {
+		return nil, err
+	}
+	return &TranscriptionResponse{Text: strings.TrimSpace(msg.Content), Language: req.Language, Model: req.Model}, nil
+}
+
 // GetCapabilities returns the capabilities of the Gemini provider
 func (g *GeminiProvider) GetCapabilities() ProviderCapabilities {
 	return g.capabilities
@@ -202,18 +256,96 @@ func (g *GeminiProvider) GetInfo() ProviderInfo {
 	}
 }
 
+// ListModels returns the IDs of the models the Gemini API serves, without
+// the "models/" resource prefix
+func (g *GeminiProvider) ListModels(ctx context.Context) ([]string, error) {
+	var ids []string
+	it := g.client.ListModels(ctx)
+	for {
+		m, err := it.Next()
+		if err == iterator.Done {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gemini list models: %w", err)
+		}
+		ids = append(ids, strings.TrimPrefix(m.Name, "models/"))
+	}
+}
+
+// Health verifies the API is reachable and the key is accepted
+func (g *GeminiProvider) Health(ctx context.Context) error {
+	if _, err := g.client.ListModels(ctx).Next(); err != nil && err != iterator.Done {
+		return fmt.Errorf("gemini list models: %w", err)
+	}
+	return nil
+}
+
 // Close closes the Gemini client
 func (g *GeminiProvider) Close() error {
 	return g.client.Close()
 }
 
-// convertMessagesToParts converts standard messages to Gemini parts
-func (g *GeminiProvider) convertMessagesToParts(messages []Message) ([]genai.Part, error) {
-	var parts []genai.Part
+// newChat configures a model for req and loads all but the last turn of the
+// conversation as chat history. The returned parts are the last turn.
+func (g *GeminiProvider) newChat(req *StandardRequest) (*genai.ChatSession, []genai.Part, error) {
+	contents, err := g.convertMessages(req.Messages)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := contents[len(contents)-1]
+	if last.Role != geminiRoleUser {
+		return nil, nil, fmt.Errorf("conversation must end with a user or function message")
+	}
+
+	model := g.client.GenerativeModel(req.Model)
+
+	// Configure model parameters
+	if req.Temperature != nil {
+		temp := float32(*req.Temperature)
+		model.Temperature = &temp
+	}
+
+	if req.TopP != nil {
+		topP := float32(*req.TopP)
+		model.TopP = &topP
+	}
+
+	if req.MaxTokens != nil {
+		maxTokens := int32(*req.MaxTokens)
+		model.MaxOutputTokens = &maxTokens
+	}
+
+	model.Tools = geminiTools(req.Functions)
+
+	chat := model.StartChat()
+	chat.History = contents[:len(contents)-1]
+	return chat, last.Parts, nil
+}
+
+// Gemini conversation roles
+const (
+	geminiRoleUser  = "user"
+	geminiRoleModel = "model"
+)
+
+// convertMessages converts standard messages to Gemini turns. Function calls
+// become FunctionCall parts of model turns and function results become
+// FunctionResponse parts of user turns; consecutive messages of the same role
+// share a turn, since Gemini requires turns to alternate.
+func (g *GeminiProvider) convertMessages(messages []Message) ([]*genai.Content, error) {
+	var contents []*genai.Content
+	add := func(role string, part genai.Part) {
+		if n := len(contents); n > 0 && contents[n-1].Role == role {
+			contents[n-1].Parts = append(contents[n-1].Parts, part)
+			return
+		}
+		contents = append(contents, &genai.Content{Role: role, Parts: []genai.Part{part}})
+	}
 
 	// Gemini doesn't support system messages directly, so we'll prepend system messages to the first user message
 	var systemContent strings.Builder
-	var conversationParts []genai.Part
+	lastCall := ""
 
 	for _, msg := range messages {
 		switch msg.Role {
@@ -228,16 +360,182 @@ func (g *GeminiProvider) convertMessagesToParts(messages []Message) ([]genai.Par
 				content = systemContent.String() + "\n\n" + content
 				systemContent.Reset() // Only prepend to first user message
 			}
-			conversationParts = append(conversationParts, genai.Text(content))
+			add(geminiRoleUser, genai.Text(content))
 		case RoleAssistant:
-			conversationParts = append(conversationParts, genai.Text(msg.Content))
+			if msg.Content != "" || msg.FunctionCall == nil {
+				add(geminiRoleModel, genai.Text(msg.Content))
+			}
+			if msg.FunctionCall != nil {
+				call, err := geminiFunctionCall(msg.FunctionCall)
+				if err != nil {
+					return nil, err
+				}
+				add(geminiRoleModel, call)
+				lastCall = call.Name
+			}
+		case RoleFunction:
+			name := lastCall
+			if msg.Name != nil {
+				name = *msg.Name
+			}
+			if name == "" {
+				return nil, fmt.Errorf("function message has no name and follows no function call")
+			}
+			add(geminiRoleUser, genai.FunctionResponse{Name: name, Response: geminiFunctionResult(msg.Content)})
 		default:
 			return nil, fmt.Errorf("unsupported message role: %s", msg.Role)
 		}
 	}
 
-	parts = append(parts, conversationParts...)
-	return parts, nil
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("no user messages")
+	}
+	return contents, nil
+}
+
+// geminiFunctionCall converts a function call, whose arguments are a JSON
+// object, to a Gemini part
+func geminiFunctionCall(call *FunctionCall) (genai.FunctionCall, error) {
+	var args map[string]any
+	if strings.TrimSpace(call.Arguments) != "" {
+		if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+			return genai.FunctionCall{}, fmt.Errorf("function call %s: arguments must be a JSON object: %w", call.Name, err)
+		}
+	}
+	return genai.FunctionCall{Name: call.Name, Args: args}, nil
+}
+
+// geminiFunctionResult converts a function result to the object Gemini
+// expects. Results that are not JSON objects are wrapped as {"content": ...}.
+func geminiFunctionResult(content string) map[string]any {
+	var result map[string]any
+	if err := json.Unmarshal([]byte(content), &result); err == nil && result != nil {
+		return result
+	}
+	var value any
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		value = content
+	}
+	return map[string]any{"content": value}
+}
+
+// geminiTools declares functions as a Gemini tool
+func geminiTools(fns []Function) []*genai.Tool {
+	if len(fns) == 0 {
+		return nil
+	}
+	decls := make([]*genai.FunctionDeclaration, len(fns))
+	for i, fn := range fns {
+		decls[i] = &genai.FunctionDeclaration{
+			Name:        fn.Name,
+			Description: fn.Description,
+			Parameters:  geminiSchema(fn.Parameters),
+		}
+	}
+	return []*genai.Tool{{FunctionDeclarations: decls}}
+}
+
+// geminiSchema converts the subset of JSON Schema Gemini understands.
+// Keywords Gemini has no equivalent for are ignored.
+func geminiSchema(schema map[string]interface{}) *genai.Schema {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	out := &genai.Schema{}
+	typ, _ := schema["type"].(string)
+	if types, ok := schema["type"].([]interface{}); ok {
+		// ["string", "null"] is how JSON Schema spells a nullable string
+		for _, t := range types {
+			if t == "null" {
+				out.Nullable = true
+			} else if s, ok := t.(string); ok {
+				typ = s
+			}
+		}
+	}
+	switch typ {
+	case "string":
+		out.Type = genai.TypeString
+	case "number":
+		out.Type = genai.TypeNumber
+	case "integer":
+		out.Type = genai.TypeInteger
+	case "boolean":
+		out.Type = genai.TypeBoolean
+	case "array":
+		out.Type = genai.TypeArray
+	case "object":
+		out.Type = genai.TypeObject
+	}
+
+	out.Description, _ = schema["description"].(string)
+	out.Format, _ = schema["format"].(string)
+	if nullable, ok := schema["nullable"].(bool); ok {
+		out.Nullable = nullable
+	}
+	out.Enum = stringList(schema["enum"])
+	out.Required = stringList(schema["required"])
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		out.Items = geminiSchema(items)
+	}
+	if props, ok := schema["properties"].(map[string]interface{}); ok {
+		out.Properties = make(map[string]*genai.Schema, len(props))
+		for name, prop := range props {
+			if p, ok := prop.(map[string]interface{}); ok {
+				out.Properties[name] = geminiSchema(p)
+			}
+		}
+	}
+	return out
+}
+
+// stringList returns the strings in a decoded JSON array, which may also be
+// a []string when built in Go
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// candidateMessage collects a candidate's text and its first function call
+func candidateMessage(candidate *genai.Candidate) (*Message, error) {
+	msg := &Message{Role: RoleAssistant}
+	if candidate.Content == nil {
+		return msg, nil
+	}
+
+	var content strings.Builder
+	for _, part := range candidate.Content.Parts {
+		switch part := part.(type) {
+		case genai.Text:
+			content.WriteString(string(part))
+		case genai.FunctionCall:
+			if msg.FunctionCall != nil {
+				continue
+			}
+			args, err := json.Marshal(part.Args)
+			if err != nil {
+				return nil, fmt.Errorf("function call %s: %w", part.Name, err)
+			}
+			if part.Args == nil {
+				args = []byte("{}")
+			}
+			msg.FunctionCall = &FunctionCall{Name: part.Name, Arguments: string(args)}
+		}
+	}
+	msg.Content = content.String()
+	return msg, nil
 }
 
 // transformResponse converts a Gemini response to StandardResponse
@@ -249,26 +547,12 @@ func (g *GeminiProvider) transformResponse(resp *genai.GenerateContentResponse,
 	choices := make([]Choice, len(resp.Candidates))
 
 	for i, candidate := range resp.Candidates {
-		var content strings.Builder
-
-		if candidate.Content != nil {
-			for _, part := range candidate.Content.Parts {
-				if text, ok := part.(genai.Text); ok {
-					content.WriteString(string(text))
-				}
-			}
-		}
-
-		msg := &Message{
-			Role:    RoleAssistant,
-			Content: content.String(),
+		msg, err := candidateMessage(candidate)
+		if err != nil {
+			return nil, err
 		}
 
-		var finishReason *string
-		if candidate.FinishReason != 0 {
-			reason := g.mapFinishReason(candidate.FinishReason)
-			finishReason = &reason
-		}
+		finishReason := g.finishReason(candidate, msg)
 
 		choices[i] = Choice{
 			Index:        i,
@@ -284,8 +568,7 @@ func (g *GeminiProvider) transformResponse(resp *genai.GenerateContentResponse,
 		TotalTokens:      0, // Not available from Gemini
 	}
 
-	responseID := fmt.Sprintf("chatcmpl-%d", time.Now().Unix())
-	return CreateStandardResponse(responseID, model, choices, usage), nil
+	return CreateStandardResponse(NewResponseID(), model, choices, usage), nil
 }
 
 // transformStreamChunk converts a Gemini stream response to StreamChunk
@@ -293,26 +576,12 @@ func (g *GeminiProvider) transformStreamChunk(resp *genai.GenerateContentRespons
 	choices := make([]Choice, 0, len(resp.Candidates))
 
 	for i, candidate := range resp.Candidates {
-		var content strings.Builder
-
-		if candidate.Content != nil {
-			for _, part := range candidate.Content.Parts {
-				if text, ok := part.(genai.Text); ok {
-					content.WriteString(string(text))
-				}
-			}
+		delta, err := candidateMessage(candidate)
+		if err != nil {
+			return nil, err
 		}
 
-		delta := &Message{
-			Role:    RoleAssistant,
-			Content: content.String(),
-		}
-
-		var finishReason *string
-		if candidate.FinishReason != 0 {
-			reason := g.mapFinishReason(candidate.FinishReason)
-			finishReason = &reason
-		}
+		finishReason := g.finishReason(candidate, delta)
 
 		choices = append(choices, Choice{
 			Index:        i,
@@ -326,6 +595,19 @@ func (g *GeminiProvider) transformStreamChunk(resp *genai.GenerateContentRespons
 	return CreateStreamChunk(chunkID, model, choices, done), nil
 }
 
+// finishReason reports function_call for candidates that call a function,
+// which Gemini marks as a normal stop
+func (g *GeminiProvider) finishReason(candidate *genai.Candidate, msg *Message) *string {
+	if candidate.FinishReason == 0 {
+		return nil
+	}
+	reason := g.mapFinishReason(candidate.FinishReason)
+	if msg.FunctionCall != nil && candidate.FinishReason == genai.FinishReasonStop {
+		reason = FinishReasonFunctionCall
+	}
+	return &reason
+}
+
 // mapFinishReason maps Gemini finish reasons to standard format
 func (g *GeminiProvider) mapFinishReason(reason genai.FinishReason) string {
 	switch reason {
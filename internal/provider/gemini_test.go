@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestGeminiConvertMultiStepToolConversation(t *testing.T) {
+	g := &GeminiProvider{}
+	weather := "get_weather"
+	contents, err := g.convertMessages([]Message{
+		{Role: RoleSystem, Content: "be brief"},
+		{Role: RoleUser, Content: "Weather in Paris and Rome?"},
+		{Role: RoleAssistant, Content: "Checking Paris.", FunctionCall: &FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+		{Role: RoleFunction, Name: &weather, Content: `{"temp":21}`},
+		{Role: RoleAssistant, FunctionCall: &FunctionCall{Name: "get_weather", Arguments: `{"city":"Rome"}`}},
+		// No name: answers the latest call
+		{Role: RoleFunction, Content: `sunny`},
+		{Role: RoleUser, Content: "Thanks"},
+	})
+	if err != nil {
+		t.Fatalf("convertMessages: %v", err)
+	}
+
+	want := []*genai.Content{
+		{Role: "user", Parts: []genai.Part{genai.Text("be brief\n\nWeather in Paris and Rome?")}},
+		{Role: "model", Parts: []genai.Part{
+			genai.Text("Checking Paris."),
+			genai.FunctionCall{Name: "get_weather", Args: map[string]any{"city": "Paris"}},
+		}},
+		{Role: "user", Parts: []genai.Part{genai.FunctionResponse{Name: "get_weather", Response: map[string]any{"temp": float64(21)}}}},
+		{Role: "model", Parts: []genai.Part{genai.FunctionCall{Name: "get_weather", Args: map[string]any{"city": "Rome"}}}},
+		// The result and the follow-up question share one user turn
+		{Role: "user", Parts: []genai.Part{
+			genai.FunctionResponse{Name: "get_weather", Response: map[string]any{"content": "sunny"}},
+			genai.Text("Thanks"),
+		}},
+	}
+	if !reflect.DeepEqual(contents, want) {
+		for i, c := range contents {
+			t.Logf("turn %d: %s %#v", i, c.Role, c.Parts)
+		}
+		t.Fatal("Unexpected Gemini turns")
+	}
+}
+
+func TestGeminiConvertMessagesErrors(t *testing.T) {
+	g := &GeminiProvider{}
+	for name, msgs := range map[string][]Message{
+		"bad arguments":    {{Role: RoleUser, Content: "hi"}, {Role: RoleAssistant, FunctionCall: &FunctionCall{Name: "f", Arguments: "not json"}}},
+		"unnamed result":   {{Role: RoleUser, Content: "hi"}, {Role: RoleFunction, Content: "{}"}},
+		"unsupported role": {{Role: "tool", Content: "{}"}},
+		"only system":      {{Role: RoleSystem, Content: "be brief"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := g.convertMessages(msgs); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestGeminiFunctionResult(t *testing.T) {
+	for content, want := range map[string]map[string]any{
+		`{"ok":true}`: {"ok": true},
+		`[1,2]`:       {"content": []any{float64(1), float64(2)}},
+		`42`:          {"content": float64(42)},
+		`plain text`:  {"content": "plain text"},
+		``:            {"content": ""},
+	} {
+		if got := geminiFunctionResult(content); !reflect.DeepEqual(got, want) {
+			t.Errorf("geminiFunctionResult(%q) = %v, want %v", content, got, want)
+		}
+	}
+}
+
+func TestGeminiTools(t *testing.T) {
+	tools := geminiTools([]Function{{
+		Name:        "get_weather",
+		Description: "Current weather",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"city":  map[string]interface{}{"type": "string", "description": "City name"},
+				"unit":  map[string]interface{}{"type": []interface{}{"string", "null"}, "enum": []interface{}{"c", "f"}},
+				"days":  map[string]interface{}{"type": "integer"},
+				"hours": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "number"}},
+			},
+			"required": []interface{}{"city"},
+		},
+	}})
+
+	want := []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{
+		Name:        "get_weather",
+		Description: "Current weather",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"city":  {Type: genai.TypeString, Description: "City name"},
+				"unit":  {Type: genai.TypeString, Nullable: true, Enum: []string{"c", "f"}},
+				"days":  {Type: genai.TypeInteger},
+				"hours": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeNumber}},
+			},
+			Required: []string{"city"},
+		},
+	}}}}
+	if !reflect.DeepEqual(tools, want) {
+		t.Errorf("Unexpected tools: %+v", tools[0].FunctionDeclarations[0].Parameters)
+	}
+
+	if geminiTools(nil) != nil {
+		t.Error("Expected no tools without functions")
+	}
+}
+
+func TestGeminiTransformFunctionCallResponse(t *testing.T) {
+	g := &GeminiProvider{}
+	resp := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		Content: &genai.Content{Role: "model", Parts: []genai.Part{
+			genai.FunctionCall{Name: "get_weather", Args: map[string]any{"city": "Paris"}},
+		}},
+		FinishReason: genai.FinishReasonStop,
+	}}}
+
+	out, err := g.transformResponse(resp, "gemini-pro")
+	if err != nil {
+		t.Fatalf("transformResponse: %v", err)
+	}
+	choice := out.Choices[0]
+	if call := choice.Message.FunctionCall; call == nil || call.Name != "get_weather" || call.Arguments != `{"city":"Paris"}` {
+		t.Errorf("Unexpected function call %+v", choice.Message.FunctionCall)
+	}
+	if choice.FinishReason == nil || *choice.FinishReason != FinishReasonFunctionCall {
+		t.Errorf("Expected finish reason %q, got %v", FinishReasonFunctionCall, choice.FinishReason)
+	}
+
+	// The call round-trips into the next request unchanged
+	contents, err := g.convertMessages([]Message{{Role: RoleUser, Content: "hi"}, *choice.Message})
+	if err != nil {
+		t.Fatalf("convertMessages: %v", err)
+	}
+	if got := contents[1].Parts; !reflect.DeepEqual(got, resp.Candidates[0].Content.Parts) {
+		t.Errorf("Expected the call to round-trip, got %#v", got)
+	}
+
+	chunk, err := g.transformStreamChunk(resp, "chatcmpl-1", "gemini-pro", 0)
+	if err != nil {
+		t.Fatalf("transformStreamChunk: %v", err)
+	}
+	if chunk.Choices[0].Delta.FunctionCall == nil || !chunk.Done {
+		t.Errorf("Expected a final chunk with the call, got %+v", chunk.Choices[0])
+	}
+}
@@ -0,0 +1,204 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+)
+
+// Golden transformation fixtures live in testdata/golden/<provider>/<case>/:
+//
+//	request.json                  StandardRequest sent to the provider
+//	upstream.json | upstream.sse  native response the fake upstream serves;
+//	                              .sse cases call StreamGenerate
+//	upstream_request.golden.json  expected native request (method, path, body)
+//	response.golden.json          expected StandardResponse, or the stream
+//	                              chunks for .sse cases
+//
+// Run `go test ./internal/provider -run TestGolden -update` to rewrite the
+// golden files after an intended change, and review the diff.
+var updateGolden = flag.Bool("update", false, "rewrite golden files")
+
+// goldenProviders builds each provider under test against a fake upstream
+var goldenProviders = map[string]func(baseURL string) (Provider, error){
+	"openai": func(u string) (Provider, error) { return NewOpenAIProvider("test-key", u, "") },
+	"deepseek": func(u string) (Provider, error) {
+		return NewDeepSeekProvider("test-key", u, "")
+	},
+	"mistral": func(u string) (Provider, error) { return NewMistralProvider("test-key", u, "") },
+	"openrouter": func(u string) (Provider, error) {
+		return NewOpenRouterProvider("test-key", u, "", "", "")
+	},
+	"perplexity": func(u string) (Provider, error) { return NewPerplexityProvider("test-key", u, "") },
+	"cohere":     func(u string) (Provider, error) { return NewCohereProvider("test-key", u, "") },
+	"zhipu":      func(u string) (Provider, error) { return NewZhipuProvider("id.secret", u, "") },
+}
+
+// goldenIDs makes generated IDs deterministic
+type goldenIDs struct{ n int }
+
+func (g *goldenIDs) New() string {
+	g.n++
+	return fmt.Sprintf("golden%04d", g.n)
+}
+
+func TestGolden(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "golden", "*", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) == 0 {
+		t.Fatal("No golden fixtures found")
+	}
+
+	for _, dir := range dirs {
+		dir := dir
+		name := filepath.Base(filepath.Dir(dir))
+		newProvider, ok := goldenProviders[name]
+		if !ok {
+			t.Errorf("No golden provider %q for %s", name, dir)
+			continue
+		}
+		t.Run(name+"/"+filepath.Base(dir), func(t *testing.T) {
+			runGoldenCase(t, dir, newProvider)
+		})
+	}
+}
+
+func runGoldenCase(t *testing.T, dir string, newProvider func(string) (Provider, error)) {
+	ids.SetDefault(&goldenIDs{})
+	defer func() {
+		g, _ := ids.NewGenerator(ids.FormatULID)
+		ids.SetDefault(g)
+	}()
+
+	var req StandardRequest
+	if err := json.Unmarshal(readFixture(t, dir, "request.json"), &req); err != nil {
+		t.Fatalf("request.json: %v", err)
+	}
+
+	stream := false
+	upstream, err := os.ReadFile(filepath.Join(dir, "upstream.json"))
+	if os.IsNotExist(err) {
+		upstream, stream = readFixture(t, dir, "upstream.sse"), true
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	var sent []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("upstream request is not JSON: %v", err)
+		}
+		sent = marshalGolden(t, map[string]interface{}{"method": r.Method, "path": r.URL.Path, "body": payload})
+
+		if stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		_, _ = w.Write(upstream)
+	}))
+	defer srv.Close()
+
+	p, err := newProvider(srv.URL)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	var got []byte
+	if stream {
+		rc, err := p.StreamGenerate(context.Background(), &GenerateRequest{StandardRequest: &req})
+		if err != nil {
+			t.Fatalf("StreamGenerate: %v", err)
+		}
+		defer rc.Close()
+
+		var chunks []*StreamChunk
+		scanner := bufio.NewScanner(rc)
+		for scanner.Scan() {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			var chunk StreamChunk
+			if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+				t.Fatalf("Invalid chunk %q: %v", scanner.Text(), err)
+			}
+			chunk.Created = 0
+			chunks = append(chunks, &chunk)
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatalf("Stream failed: %v", err)
+		}
+		got = marshalGolden(t, chunks)
+	} else {
+		resp, err := p.Generate(context.Background(), &GenerateRequest{StandardRequest: &req})
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		resp.Created = 0
+		got = marshalGolden(t, resp.StandardResponse)
+	}
+
+	compareGolden(t, dir, "upstream_request.golden.json", sent)
+	compareGolden(t, dir, "response.golden.json", got)
+}
+
+func readFixture(t *testing.T, dir, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("Missing fixture: %v", err)
+	}
+	return b
+}
+
+// marshalGolden encodes v with sorted keys and indentation so golden diffs
+// are stable and readable
+func marshalGolden(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	out, err := json.MarshalIndent(generic, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return append(out, '\n')
+}
+
+func compareGolden(t *testing.T, dir, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if *updateGolden {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Missing golden file, run with -update: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s mismatch (run with -update to accept)\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
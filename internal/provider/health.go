@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/health"
+)
+
+// HealthChecker is implemented by providers that can probe their upstream
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+// healthTTL bounds how often upstreams are probed by readiness checks
+const healthTTL = 30 * time.Second
+
+type healthEntry struct {
+	err     error
+	checked time.Time
+}
+
+// CheckHealth probes every registered provider, reusing results younger than
+// healthTTL, and returns the outcome keyed by provider name. Providers that
+// do not implement HealthChecker are considered healthy once registered.
+func (r *Registry) CheckHealth(ctx context.Context) map[string]error {
+	r.mu.RLock()
+	providers := make(map[string]Provider, len(r.providers))
+	for name, p := range r.providers {
+		providers[name] = p
+	}
+	r.mu.RUnlock()
+
+	out := make(map[string]error, len(providers))
+	for name, p := range providers {
+		hc, ok := p.(HealthChecker)
+		if !ok {
+			out[name] = nil
+			continue
+		}
+
+		r.healthMu.Lock()
+		entry, cached := r.health[name]
+		r.healthMu.Unlock()
+		if cached && r.now().Sub(entry.checked) < healthTTL {
+			out[name] = entry.err
+			continue
+		}
+
+		err := hc.Health(ctx)
+		r.healthMu.Lock()
+		r.health[name] = healthEntry{err: err, checked: r.now()}
+		r.healthMu.Unlock()
+		out[name] = err
+	}
+	return out
+}
+
+// ReadinessCheck reports ready when at least one provider is healthy
+func (r *Registry) ReadinessCheck() health.Check {
+	return health.Check{
+		Name: "providers",
+		Probe: func(ctx context.Context) (map[string]string, error) {
+			results := r.CheckHealth(ctx)
+			if len(results) == 0 {
+				return nil, fmt.Errorf("no providers configured")
+			}
+
+			details := make(map[string]string, len(results))
+			var failing []string
+			for name, err := range results {
+				if err != nil {
+					details[name] = err.Error()
+					failing = append(failing, name)
+				} else {
+					details[name] = health.StatusOK
+				}
+			}
+			if len(failing) == len(results) {
+				sort.Strings(failing)
+				return details, fmt.Errorf("no healthy providers (failing: %s)", strings.Join(failing, ", "))
+			}
+			return details, nil
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// stubProvider is a minimal Provider for registry tests
+type stubProvider struct {
+	name      string
+	healthErr error
+	probes    int
+}
+
+func (s *stubProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubProvider) StreamGenerate(ctx context.Context, req *GenerateRequest) (io.ReadCloser, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *stubProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	return nil, ErrEmbeddingsUnsupported
+}
+
+func (s *stubProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, ErrTranscriptionUnsupported
+}
+
+func (s *stubProvider) GetCapabilities() ProviderCapabilities { return ProviderCapabilities{} }
+
+func (s *stubProvider) GetInfo() ProviderInfo { return ProviderInfo{Name: s.name, Status: "active"} }
+
+func (s *stubProvider) Close() error { return nil }
+
+func (s *stubProvider) Health(ctx context.Context) error {
+	s.probes++
+	return s.healthErr
+}
+
+func TestRegistryReadinessCheck(t *testing.T) {
+	registry, err := NewRegistry(&config.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+	check := registry.ReadinessCheck()
+
+	if _, err := check.Probe(context.Background()); err == nil {
+		t.Error("Expected failure with no providers")
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	registry.now = func() time.Time { return now }
+
+	down := &stubProvider{name: "down", healthErr: errors.New("401 unauthorized")}
+	_ = registry.RegisterProvider("down", down)
+
+	details, err := check.Probe(context.Background())
+	if err == nil {
+		t.Error("Expected failure when all providers are unhealthy")
+	}
+	if details["down"] != "401 unauthorized" {
+		t.Errorf("Expected per-provider detail, got %v", details)
+	}
+
+	up := &stubProvider{name: "up"}
+	_ = registry.RegisterProvider("up", up)
+
+	details, err = check.Probe(context.Background())
+	if err != nil {
+		t.Errorf("Expected ready with one healthy provider, got %v", err)
+	}
+	if details["up"] != "ok" || details["down"] == "ok" {
+		t.Errorf("Unexpected details: %v", details)
+	}
+
+	// Results are cached within healthTTL
+	if down.probes != 1 {
+		t.Errorf("Expected cached health result, got %d probes", down.probes)
+	}
+	now = now.Add(healthTTL)
+	_, _ = check.Probe(context.Background())
+	if down.probes != 2 {
+		t.Errorf("Expected re-probe after TTL, got %d probes", down.probes)
+	}
+}
@@ -0,0 +1,139 @@
+package provider
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// keyPool spreads a provider's upstream requests over several API keys. It
+// sits below the pacer, replacing the bearer credential of each request.
+type keyPool struct {
+	base     http.RoundTripper
+	strategy string
+	cooldown time.Duration
+	now      func() time.Time
+
+	mu   sync.Mutex
+	keys []*pooledKey
+	next int
+}
+
+// pooledKey is one upstream key and what the upstream last said about it
+type pooledKey struct {
+	secret string
+	// remaining requests in the upstream's window, -1 until reported
+	remaining int
+	coolUntil time.Time
+}
+
+func newKeyPool(kp config.ProviderKeyPool, base http.RoundTripper) *keyPool {
+	p := &keyPool{base: base, strategy: kp.Strategy, cooldown: kp.Cooldown, now: time.Now}
+	if p.cooldown <= 0 {
+		p.cooldown = time.Minute
+	}
+	for _, secret := range kp.Keys {
+		p.keys = append(p.keys, &pooledKey{secret: secret, remaining: -1})
+	}
+	return p
+}
+
+func (p *keyPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	k := p.pick(nil)
+	resp, err := p.send(req, k)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests || req.GetBody == nil {
+		return resp, err
+	}
+	// Retry once on a key that is not cooling down
+	retry := p.pick(k)
+	if retry == nil {
+		return resp, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return resp, nil
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	req = req.Clone(req.Context())
+	req.Body = body
+	return p.send(req, retry)
+}
+
+// send makes req with key k and records the rate limit state of the answer
+func (p *keyPool) send(req *http.Request, k *pooledKey) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+k.secret)
+	resp, err := p.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	now := p.now()
+	s, ok := ParseRateLimitHeaders(resp.Header, now)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ok && s.RemainingRequests >= 0 {
+		k.remaining = s.RemainingRequests
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		cooldown := p.cooldown
+		if s.RetryAfter > 0 {
+			cooldown = s.RetryAfter
+		}
+		k.coolUntil = now.Add(cooldown)
+	}
+	return resp, nil
+}
+
+// pick returns the key for the next request by the pool's strategy,
+// skipping keys cooling down. When retrying, keys other than except are
+// considered and nil is returned if none is available; otherwise, with
+// every key cooling down, the one available soonest is used.
+func (p *keyPool) pick(except *pooledKey) *pooledKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var best, soonest *pooledKey
+	for i := range p.keys {
+		k := p.keys[(p.next+i)%len(p.keys)]
+		if k == except {
+			continue
+		}
+		if now.Before(k.coolUntil) {
+			if soonest == nil || k.coolUntil.Before(soonest.coolUntil) {
+				soonest = k
+			}
+			continue
+		}
+		if best == nil {
+			best = k
+			if p.strategy != config.KeyPoolRemaining {
+				break
+			}
+		} else if rank(k) > rank(best) {
+			best = k
+		}
+	}
+	p.next = (p.next + 1) % len(p.keys)
+	if best == nil && except == nil {
+		best = soonest
+	}
+	if best != nil && best.remaining > 0 {
+		// Reserve one request so concurrent picks spread out
+		best.remaining--
+	}
+	return best
+}
+
+// rank orders keys for the remaining strategy; keys the upstream has not
+// reported on yet come first
+func rank(k *pooledKey) int {
+	if k.remaining < 0 {
+		return int(^uint(0) >> 1)
+	}
+	return k.remaining
+}
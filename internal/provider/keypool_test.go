@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestProviderKeyPool(t *testing.T) {
+	var mu sync.Mutex
+	var used []string
+	limited := map[string]bool{"sk-2": true}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		mu.Lock()
+		used = append(used, key)
+		mu.Unlock()
+		if limited[key] {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"message":"rate limited"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "sk-1"
+	cfg.OpenAI.BaseURL = srv.URL
+	cfg.ProviderKeys = map[string]config.ProviderKeyPool{"openai": {Keys: []string{"sk-1", "sk-2", "sk-3"}}}
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := registry.GetProvider("openai")
+	generate := func() error {
+		_, err := p.Generate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+			Model:    "gpt-4o",
+			Messages: []Message{{Role: RoleUser, Content: "Hello"}},
+		}})
+		return err
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := generate(); err != nil {
+			t.Fatalf("Request %d: %v", i, err)
+		}
+	}
+	// sk-2 is rate limited, retried on sk-3 and then skipped while cooling down
+	want := "sk-1 sk-2 sk-3 sk-1 sk-3"
+	if got := strings.Join(used, " "); got != want {
+		t.Errorf("Expected keys %q, got %q", want, got)
+	}
+}
+
+func TestKeyPoolRemaining(t *testing.T) {
+	pool := newKeyPool(config.ProviderKeyPool{Keys: []string{"a", "b", "c"}, Strategy: config.KeyPoolRemaining}, nil)
+	pool.keys[0].remaining = 5
+	pool.keys[1].remaining = 50
+	pool.keys[2].remaining = 20
+	if k := pool.pick(nil); k.secret != "b" {
+		t.Errorf("Expected the key with the most requests left, got %s", k.secret)
+	}
+	pool.keys[1].coolUntil = pool.now().Add(time.Minute)
+	if k := pool.pick(nil); k.secret != "c" {
+		t.Errorf("Expected cooling keys skipped, got %s", k.secret)
+	}
+	if k := pool.pick(pool.keys[2]); k.secret != "a" {
+		t.Errorf("Expected a retry on another key, got %s", k.secret)
+	}
+}
+
+func TestProviderKeyPoolUnsupported(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Gemini.APIKey = "key"
+	cfg.ProviderKeys = map[string]config.ProviderKeyPool{"gemini": {Keys: []string{"a", "b"}}}
+	if _, err := NewRegistry(cfg); err == nil || !strings.Contains(err.Error(), "key pools") {
+		t.Errorf("Expected key pools refused for gemini, got %v", err)
+	}
+}
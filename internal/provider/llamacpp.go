@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ErrLlamaCppUnavailable is returned when a llama.cpp model is configured but
+// the binary was built without the llamacpp build tag
+var ErrLlamaCppUnavailable = errors.New("llama.cpp support is not compiled in; rebuild with -tags llamacpp")
+
+// Chat templates for rendering messages into a llama.cpp prompt
+const (
+	ChatTemplateChatML = "chatml"
+	ChatTemplateLlama3 = "llama3"
+)
+
+// LlamaCppOptions configures the in-process llama.cpp provider
+type LlamaCppOptions struct {
+	// Path to a GGUF model file
+	ModelPath string
+	// Model name clients request; defaults to the file name without ".gguf"
+	ModelName string
+	// Context window in tokens
+	ContextSize int
+	// CPU threads used for generation
+	Threads int
+	// Layers offloaded to the GPU, if llama.cpp was built with GPU support
+	GPULayers int
+	// ChatTemplateChatML or ChatTemplateLlama3
+	ChatTemplate string
+}
+
+// LlamaCppModelName returns the model name clients use for opts
+func LlamaCppModelName(opts LlamaCppOptions) string {
+	if opts.ModelName != "" {
+		return opts.ModelName
+	}
+	return strings.TrimSuffix(filepath.Base(opts.ModelPath), ".gguf")
+}
+
+// renderChatPrompt flattens messages into the prompt format the model was
+// trained on, ending with an open assistant turn. The returned stop words end
+// the assistant's turn.
+func renderChatPrompt(template string, messages []Message) (string, []string, error) {
+	var b strings.Builder
+	switch template {
+	case ChatTemplateChatML, "":
+		for _, m := range messages {
+			fmt.Fprintf(&b, "<|im_start|>%s\n%s<|im_end|>\n", m.Role, m.Content)
+		}
+		b.WriteString("<|im_start|>assistant\n")
+		return b.String(), []string{"<|im_end|>"}, nil
+	case ChatTemplateLlama3:
+		// llama.cpp adds <|begin_of_text|> itself
+		for _, m := range messages {
+			fmt.Fprintf(&b, "<|start_header_id|>%s<|end_header_id|>\n\n%s<|eot_id|>", m.Role, m.Content)
+		}
+		b.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+		return b.String(), []string{"<|eot_id|>"}, nil
+	default:
+		return "", nil, fmt.Errorf("unknown chat template %q", template)
+	}
+}
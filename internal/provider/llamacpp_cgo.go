@@ -0,0 +1,224 @@
+//go:build llamacpp
+
+package provider
+
+// Building with -tags llamacpp requires cgo and the llama.cpp sources that
+// github.com/go-skynet/go-llama.cpp vendors as a submodule; see that module's
+// README for building libbinding.a, then add it with
+// `go get github.com/go-skynet/go-llama.cpp`.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	llama "github.com/go-skynet/go-llama.cpp"
+)
+
+// defaultLlamaCppMaxTokens bounds completions that do not set max_tokens
+const defaultLlamaCppMaxTokens = 512
+
+// LlamaCppProvider implements the Provider interface by running a GGUF model
+// in process through llama.cpp, with no network dependency. A model serves
+// one request at a time; concurrent requests wait their turn.
+type LlamaCppProvider struct {
+	model        *llama.LLama
+	name         string
+	opts         LlamaCppOptions
+	capabilities ProviderCapabilities
+
+	// llama.cpp contexts are not safe for concurrent use
+	mu sync.Mutex
+}
+
+// NewLlamaCppProvider loads the model at opts.ModelPath
+func NewLlamaCppProvider(opts LlamaCppOptions) (Provider, error) {
+	if opts.ModelPath == "" {
+		return nil, fmt.Errorf("llamacpp model_path is required")
+	}
+	if _, _, err := renderChatPrompt(opts.ChatTemplate, nil); err != nil {
+		return nil, err
+	}
+
+	model, err := llama.New(opts.ModelPath,
+		llama.SetContext(opts.ContextSize),
+		llama.SetGPULayers(opts.GPULayers),
+		llama.EnableF16Memory,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", opts.ModelPath, err)
+	}
+
+	name := LlamaCppModelName(opts)
+	return &LlamaCppProvider{
+		model: model,
+		name:  name,
+		opts:  opts,
+		capabilities: ProviderCapabilities{
+			SupportsStreaming:   true,
+			SupportsFunctions:   false,
+			SupportsSystemRole:  true,
+			MaxTokens:           opts.ContextSize,
+			MaxContextLength:    opts.ContextSize,
+			SupportedModels:     []string{name},
+			SupportedParameters: []string{"temperature", "top_p", "max_tokens", "stream"},
+		},
+	}, nil
+}
+
+// Generate generates a completion for the given request
+func (l *LlamaCppProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	text, usage, finish, err := l.predict(ctx, req.StandardRequest, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{Role: RoleAssistant, Content: text}
+	return &GenerateResponse{
+		StandardResponse: CreateStandardResponse(NewResponseID(), l.name, []Choice{{Message: msg, FinishReason: &finish}}, usage),
+	}, nil
+}
+
+// StreamGenerate generates a streaming completion with one chunk per token
+func (l *LlamaCppProvider) StreamGenerate(ctx context.Context, req *GenerateRequest) (io.ReadCloser, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	id, created := NewResponseID(), time.Now().Unix()
+	write := func(chunk *StreamChunk) error {
+		chunk.Created = created
+		chunkData, err := json.Marshal(chunk)
+		if err != nil {
+			return fmt.Errorf("failed to marshal chunk: %w", err)
+		}
+		_, err = pw.Write(append(chunkData, '\n'))
+		return err
+	}
+
+	go func() {
+		defer pw.Close()
+
+		_, usage, finish, err := l.predict(ctx, req.StandardRequest, func(token string) bool {
+			delta := &Message{Role: RoleAssistant, Content: token}
+			return write(CreateStreamChunk(id, l.name, []Choice{{Delta: delta}}, false)) == nil
+		})
+		if err != nil {
+			_ = pw.CloseWithError(fmt.Errorf("llamacpp stream error: %w", err))
+			return
+		}
+
+		final := CreateStreamChunk(id, l.name, []Choice{{Delta: &Message{}, FinishReason: &finish}}, true)
+		final.Usage = &usage
+		if werr := write(final); werr != nil {
+			_ = pw.CloseWithError(werr)
+		}
+	}()
+
+	return pr, nil
+}
+
+// Embed is not supported
+func (l *LlamaCppProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	return nil, fmt.Errorf("llamacpp: %w", ErrEmbeddingsUnsupported)
+}
+
+// Transcribe is not supported
+func (l *LlamaCppProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("llamacpp: %w", ErrTranscriptionUnsupported)
+}
+
+// GetCapabilities returns the capabilities of the llama.cpp provider
+func (l *LlamaCppProvider) GetCapabilities() ProviderCapabilities {
+	return l.capabilities
+}
+
+// GetInfo returns information about the llama.cpp provider
+func (l *LlamaCppProvider) GetInfo() ProviderInfo {
+	return ProviderInfo{
+		Name:         "llamacpp",
+		Version:      "1.0.0",
+		Capabilities: l.capabilities,
+		Status:       "active",
+		LastUpdated:  time.Now(),
+	}
+}
+
+// Close frees the model
+func (l *LlamaCppProvider) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.model.Free()
+	return nil
+}
+
+// predict runs one completion, passing each token to onToken if set. Returning
+// false from onToken, or cancelling ctx, stops generation early.
+func (l *LlamaCppProvider) predict(ctx context.Context, req *StandardRequest, onToken func(string) bool) (string, Usage, string, error) {
+	prompt, stop, err := renderChatPrompt(l.opts.ChatTemplate, req.Messages)
+	if err != nil {
+		return "", Usage{}, "", err
+	}
+
+	maxTokens := defaultLlamaCppMaxTokens
+	if req.MaxTokens != nil {
+		maxTokens = *req.MaxTokens
+	}
+	opts := []llama.PredictOption{
+		llama.SetTokens(maxTokens),
+		llama.SetThreads(l.opts.Threads),
+		llama.SetStopWords(stop...),
+	}
+	if req.Temperature != nil {
+		opts = append(opts, llama.SetTemperature(float32(*req.Temperature)))
+	}
+	if req.TopP != nil {
+		opts = append(opts, llama.SetTopP(float32(*req.TopP)))
+	}
+
+	completionTokens := 0
+	opts = append(opts, llama.SetTokenCallback(func(token string) bool {
+		completionTokens++
+		if ctx.Err() != nil {
+			return false
+		}
+		return onToken == nil || onToken(token)
+	}))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return "", Usage{}, "", err
+	}
+
+	promptTokens, _, err := l.model.TokenizeString(prompt, opts...)
+	if err != nil {
+		return "", Usage{}, "", fmt.Errorf("tokenize prompt: %w", err)
+	}
+	text, err := l.model.Predict(prompt, opts...)
+	if err != nil {
+		return "", Usage{}, "", fmt.Errorf("llamacpp predict: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", Usage{}, "", err
+	}
+
+	finish := "stop"
+	if completionTokens >= maxTokens {
+		finish = "length"
+	}
+	usage := Usage{
+		PromptTokens:     int(promptTokens),
+		CompletionTokens: completionTokens,
+		TotalTokens:      int(promptTokens) + completionTokens,
+	}
+	return text, usage, finish, nil
+}
@@ -0,0 +1,8 @@
+//go:build !llamacpp
+
+package provider
+
+// NewLlamaCppProvider always fails in builds without the llamacpp tag
+func NewLlamaCppProvider(opts LlamaCppOptions) (Provider, error) {
+	return nil, ErrLlamaCppUnavailable
+}
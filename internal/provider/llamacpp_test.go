@@ -0,0 +1,41 @@
+package provider
+
+import "testing"
+
+func TestRenderChatPrompt(t *testing.T) {
+	messages := []Message{
+		{Role: RoleSystem, Content: "Be brief."},
+		{Role: RoleUser, Content: "Hi"},
+	}
+	for template, want := range map[string]struct{ prompt, stop string }{
+		ChatTemplateChatML: {
+			prompt: "<|im_start|>system\nBe brief.<|im_end|>\n<|im_start|>user\nHi<|im_end|>\n<|im_start|>assistant\n",
+			stop:   "<|im_end|>",
+		},
+		ChatTemplateLlama3: {
+			prompt: "<|start_header_id|>system<|end_header_id|>\n\nBe brief.<|eot_id|><|start_header_id|>user<|end_header_id|>\n\nHi<|eot_id|><|start_header_id|>assistant<|end_header_id|>\n\n",
+			stop:   "<|eot_id|>",
+		},
+	} {
+		prompt, stop, err := renderChatPrompt(template, messages)
+		if err != nil {
+			t.Fatalf("%s: %v", template, err)
+		}
+		if prompt != want.prompt || len(stop) != 1 || stop[0] != want.stop {
+			t.Errorf("%s: unexpected prompt %q stop %q", template, prompt, stop)
+		}
+	}
+
+	if _, _, err := renderChatPrompt("alpaca", messages); err == nil {
+		t.Error("Expected an error for an unknown template")
+	}
+}
+
+func TestLlamaCppModelName(t *testing.T) {
+	if name := LlamaCppModelName(LlamaCppOptions{ModelPath: "/models/qwen2.5-7b-instruct-q4_k_m.gguf"}); name != "qwen2.5-7b-instruct-q4_k_m" {
+		t.Errorf("Expected the file name, got %q", name)
+	}
+	if name := LlamaCppModelName(LlamaCppOptions{ModelPath: "/models/x.gguf", ModelName: "local"}); name != "local" {
+		t.Errorf("Expected the configured name, got %q", name)
+	}
+}
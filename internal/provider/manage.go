@@ -0,0 +1,193 @@
+package provider
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// ProviderTypeOpenAICompatible is the type of named OpenAI-compatible
+// instances; the other types are the built-in providers, which are
+// registered under their type
+const ProviderTypeOpenAICompatible = "openai_compatible"
+
+// ProviderSpec describes a provider that can be registered, changed and
+// removed at runtime. Changes are not written back to the configuration
+// file, so they last until the process restarts.
+type ProviderSpec struct {
+	Name string `json:"name"`
+	// "openai_compatible" or a built-in provider such as "openai"
+	Type         string   `json:"type"`
+	APIKey       string   `json:"api_key,omitempty"`
+	BaseURL      string   `json:"base_url,omitempty"`
+	DefaultModel string   `json:"default_model,omitempty"`
+	Models       []string `json:"models,omitempty"`
+}
+
+// configSpecs returns the specs of the configured providers built from an
+// API key and base URL
+func configSpecs(cfg *config.Config) map[string]ProviderSpec {
+	specs := make(map[string]ProviderSpec)
+	builtin := func(name, apiKey, baseURL, model string) {
+		if apiKey != "" {
+			specs[name] = ProviderSpec{Name: name, Type: name, APIKey: apiKey, BaseURL: baseURL, DefaultModel: model}
+		}
+	}
+	builtin("openai", cfg.OpenAI.APIKey, cfg.OpenAI.BaseURL, cfg.OpenAI.DefaultModel)
+	builtin("gemini", cfg.Gemini.APIKey, cfg.Gemini.BaseURL, cfg.Gemini.DefaultModel)
+	builtin("mistral", cfg.Mistral.APIKey, cfg.Mistral.BaseURL, cfg.Mistral.DefaultModel)
+	builtin("cohere", cfg.Cohere.APIKey, cfg.Cohere.BaseURL, cfg.Cohere.DefaultModel)
+	builtin("deepseek", cfg.DeepSeek.APIKey, cfg.DeepSeek.BaseURL, cfg.DeepSeek.DefaultModel)
+	builtin("openrouter", cfg.OpenRouter.APIKey, cfg.OpenRouter.BaseURL, cfg.OpenRouter.DefaultModel)
+	builtin("perplexity", cfg.Perplexity.APIKey, cfg.Perplexity.BaseURL, cfg.Perplexity.DefaultModel)
+	builtin("zhipu", cfg.Zhipu.APIKey, cfg.Zhipu.BaseURL, cfg.Zhipu.DefaultModel)
+	for _, oc := range cfg.OpenAICompatible {
+		specs[oc.Name] = ProviderSpec{Name: oc.Name, Type: ProviderTypeOpenAICompatible, APIKey: oc.APIKey,
+			BaseURL: oc.BaseURL, DefaultModel: oc.DefaultModel, Models: oc.Models}
+	}
+	return specs
+}
+
+// newProviderFromSpec creates the provider a spec describes
+func (r *Registry) newProviderFromSpec(spec ProviderSpec) (Provider, error) {
+	if spec.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if spec.Type != ProviderTypeOpenAICompatible {
+		if spec.Name != spec.Type {
+			return nil, fmt.Errorf("%s providers are named %q; use %s for further instances", spec.Type, spec.Type, ProviderTypeOpenAICompatible)
+		}
+		if len(spec.Models) > 0 {
+			return nil, fmt.Errorf("models can only be declared for %s providers", ProviderTypeOpenAICompatible)
+		}
+	}
+	switch spec.Type {
+	case ProviderTypeOpenAICompatible:
+		return NewOpenAICompatibleProvider(spec.Name, spec.APIKey, spec.BaseURL, spec.DefaultModel, spec.Models)
+	case "openai":
+		return NewOpenAIProvider(spec.APIKey, spec.BaseURL, spec.DefaultModel)
+	case "gemini":
+		return NewGeminiProvider(spec.APIKey, spec.BaseURL, spec.DefaultModel)
+	case "mistral":
+		return NewMistralProvider(spec.APIKey, spec.BaseURL, spec.DefaultModel)
+	case "cohere":
+		return NewCohereProvider(spec.APIKey, spec.BaseURL, spec.DefaultModel)
+	case "deepseek":
+		return NewDeepSeekProvider(spec.APIKey, spec.BaseURL, spec.DefaultModel)
+	case "openrouter":
+		cfg := r.config()
+		return NewOpenRouterProvider(spec.APIKey, spec.BaseURL, spec.DefaultModel, cfg.OpenRouter.Referer, cfg.OpenRouter.Title)
+	case "perplexity":
+		return NewPerplexityProvider(spec.APIKey, spec.BaseURL, spec.DefaultModel)
+	case "zhipu":
+		return NewZhipuProvider(spec.APIKey, spec.BaseURL, spec.DefaultModel)
+	default:
+		return nil, fmt.Errorf("unknown provider type %q", spec.Type)
+	}
+}
+
+// PutProvider registers the provider spec describes, replacing the one of
+// the same name. In-flight requests finish on the instance they were
+// routed to.
+func (r *Registry) PutProvider(spec ProviderSpec) error {
+	p, err := r.newProviderFromSpec(spec)
+	if err != nil {
+		return err
+	}
+	if p, err = r.setUp(spec.Name, p); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.providers[spec.Name]; exists {
+		if _, managed := r.specs[spec.Name]; !managed {
+			return fmt.Errorf("provider %s cannot be changed at runtime", spec.Name)
+		}
+	}
+	r.providers[spec.Name] = p
+	r.specs[spec.Name] = spec
+	r.unmapModels(spec.Name)
+	for _, m := range spec.Models {
+		if _, taken := r.models[m]; !taken {
+			r.models[m] = spec.Name
+		}
+	}
+	return nil
+}
+
+// ProviderSpec returns the spec of a provider that can be changed at runtime
+func (r *Registry) ProviderSpec(name string) (ProviderSpec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	spec, ok := r.specs[name]
+	return spec, ok
+}
+
+// ProviderSpecs returns the specs of the providers that can be changed at
+// runtime, by name
+func (r *Registry) ProviderSpecs() []ProviderSpec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	specs := make([]ProviderSpec, 0, len(r.specs))
+	for _, spec := range r.specs {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// SetProviderDisabled takes a provider out of routing, or puts it back.
+// Routes to a disabled provider fail as if it were not configured.
+func (r *Registry) SetProviderDisabled(name string, disabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.providers[name]; !exists {
+		return fmt.Errorf("provider %s not configured", name)
+	}
+	if disabled {
+		r.disabled[name] = true
+	} else {
+		delete(r.disabled, name)
+	}
+	return nil
+}
+
+// ProviderDisabled reports whether a provider is taken out of routing
+func (r *Registry) ProviderDisabled(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.disabled[name]
+}
+
+// RemoveProvider unregisters a provider and the models it declared, and
+// closes it
+func (r *Registry) RemoveProvider(name string) error {
+	r.mu.Lock()
+	p, exists := r.providers[name]
+	if !exists {
+		r.mu.Unlock()
+		return fmt.Errorf("provider %s not configured", name)
+	}
+	delete(r.providers, name)
+	delete(r.specs, name)
+	delete(r.disabled, name)
+	r.unmapModels(name)
+	r.mu.Unlock()
+
+	return p.Close()
+}
+
+// unmapModels drops the models mapped to a provider; callers must hold r.mu
+func (r *Registry) unmapModels(name string) {
+	for m, owner := range r.models {
+		if owner == name {
+			delete(r.models, m)
+		}
+	}
+}
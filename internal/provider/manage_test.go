@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestRegistryManageProviders(t *testing.T) {
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}}}}
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	spec := ProviderSpec{Name: "local", Type: ProviderTypeOpenAICompatible, BaseURL: "http://127.0.0.1:1/v1", Models: []string{"local-chat"}}
+	if err := r.PutProvider(spec); err != nil {
+		t.Fatalf("Failed to add provider: %v", err)
+	}
+	if got := routedName(t, r, "local-chat"); got != "local" {
+		t.Fatalf("Expected the new provider routed, got %s", got)
+	}
+
+	// Replacing the provider remaps its models
+	spec.Models = []string{"local-large"}
+	if err := r.PutProvider(spec); err != nil {
+		t.Fatalf("Failed to update provider: %v", err)
+	}
+	if got := routedName(t, r, "local-large"); got != "local" {
+		t.Fatalf("Expected the new model routed, got %s", got)
+	}
+	if got, _ := r.ProviderSpec("local"); len(got.Models) != 1 || got.Models[0] != "local-large" {
+		t.Errorf("Expected the updated spec, got %+v", got)
+	}
+
+	if err := r.SetProviderDisabled("local", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Route(&RouteRequest{Model: "local-large"}); err == nil {
+		t.Error("Expected routing to a disabled provider to fail")
+	}
+	_ = r.SetProviderDisabled("local", false)
+	if got := routedName(t, r, "local-large"); got != "local" {
+		t.Fatalf("Expected the provider routed once enabled, got %s", got)
+	}
+
+	if err := r.RemoveProvider("local"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Route(&RouteRequest{Model: "local-large"}); err == nil {
+		t.Error("Expected routing to a removed provider to fail")
+	}
+	if _, ok := r.ProviderSpec("local"); ok {
+		t.Error("Expected the spec removed")
+	}
+
+	for name, spec := range map[string]ProviderSpec{
+		"configured mock":     {Name: "demo", Type: ProviderTypeOpenAICompatible, BaseURL: "http://127.0.0.1:1/v1"},
+		"renamed builtin":     {Name: "openai-2", Type: "openai", APIKey: "sk"},
+		"builtin with models": {Name: "openai", Type: "openai", APIKey: "sk", Models: []string{"x"}},
+		"unknown type":        {Name: "x", Type: "nope"},
+		"missing name":        {Type: ProviderTypeOpenAICompatible, BaseURL: "http://127.0.0.1:1/v1"},
+	} {
+		if err := r.PutProvider(spec); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultMistralBaseURL is the Mistral platform API endpoint
+const DefaultMistralBaseURL = "https://api.mistral.ai/v1"
+
+// MistralProvider implements the Provider interface using Mistral's chat
+// completions API. The wire format is OpenAI-compatible except that function
+// calling is only available through tools (see tools.go).
+type MistralProvider struct {
+	client       *openai.Client
+	modelName    string
+	capabilities ProviderCapabilities
+	pacer        *Pacer
+}
+
+// NewMistralProvider creates a new Mistral provider instance
+func NewMistralProvider(apiKey, baseURL, modelName string) (*MistralProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("mistral apiKey is required")
+	}
+	if modelName == "" {
+		modelName = "mistral-large-latest"
+	}
+	if baseURL == "" {
+		baseURL = DefaultMistralBaseURL
+	}
+
+	pacer := NewPacer()
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = baseURL
+	config.HTTPClient = newPacedClient(pacer)
+
+	// Define Mistral capabilities
+	capabilities := ProviderCapabilities{
+		SupportsStreaming:   true,
+		SupportsFunctions:   true,
+		SupportsSystemRole:  true,
+		MaxTokens:           8192,
+		MaxContextLength:    128000, // For mistral-large
+		SupportedModels:     []string{"mistral-large-latest", "mistral-small-latest", "codestral-latest", "open-mistral-nemo"},
+		SupportedParameters: []string{"temperature", "top_p", "max_tokens", "stream", "functions"},
+	}
+
+	return &MistralProvider{
+		client:       openai.NewClientWithConfig(config),
+		modelName:    modelName,
+		capabilities: capabilities,
+		pacer:        pacer,
+	}, nil
+}
+
+// Generate generates a completion for the given request
+func (m *MistralProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	mistralReq := m.transformRequest(req)
+
+	resp, err := m.client.CreateChatCompletion(ctx, *mistralReq)
+	if err != nil {
+		return nil, fmt.Errorf("mistral completion error: %w", err)
+	}
+
+	return &GenerateResponse{
+		StandardResponse: m.transformResponse(&resp),
+	}, nil
+}
+
+// StreamGenerate generates a streaming completion for the given request
+func (m *MistralProvider) StreamGenerate(ctx context.Context, req *GenerateRequest) (io.ReadCloser, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	mistralReq := m.transformRequest(req)
+	mistralReq.Stream = true
+
+	stream, err := m.client.CreateChatCompletionStream(ctx, *mistralReq)
+	if err != nil {
+		return nil, fmt.Errorf("mistral start stream error: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer stream.Close()
+		defer pw.Close()
+
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("mistral stream recv error: %w", err))
+				return
+			}
+
+			chunkData, err := json.Marshal(m.transformStreamChunk(&resp))
+			if err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("failed to marshal chunk: %w", err))
+				return
+			}
+
+			if _, werr := pw.Write(append(chunkData, '\n')); werr != nil {
+				_ = pw.CloseWithError(werr)
+				return
+			}
+		}
+	}()
+
+	return pr, nil
+}
+
+// Embed is not supported
+func (m *MistralProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	return nil, fmt.Errorf("mistral: %w", ErrEmbeddingsUnsupported)
+}
+
+// Transcribe is not supported
+func (m *MistralProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("mistral: %w", ErrTranscriptionUnsupported)
+}
+
+// GetCapabilities returns the capabilities of the Mistral provider
+func (m *MistralProvider) GetCapabilities() ProviderCapabilities {
+	return m.capabilities
+}
+
+// GetInfo returns information about the Mistral provider
+func (m *MistralProvider) GetInfo() ProviderInfo {
+	return ProviderInfo{
+		Name:         "mistral",
+		Version:      "1.0.0",
+		Capabilities: m.capabilities,
+		Status:       "active",
+		LastUpdated:  time.Now(),
+	}
+}
+
+// ListModels returns the IDs of the models the Mistral API serves
+func (m *MistralProvider) ListModels(ctx context.Context) ([]string, error) {
+	list, err := m.client.ListModels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("mistral list models: %w", err)
+	}
+	ids := make([]string, len(list.Models))
+	for i, m := range list.Models {
+		ids[i] = m.ID
+	}
+	return ids, nil
+}
+
+// Health verifies the API is reachable and the key is accepted
+func (m *MistralProvider) Health(ctx context.Context) error {
+	if _, err := m.client.ListModels(ctx); err != nil {
+		return fmt.Errorf("mistral list models: %w", err)
+	}
+	return nil
+}
+
+// Pacer returns the pacer tracking Mistral rate limit headers
+func (m *MistralProvider) Pacer() *Pacer {
+	return m.pacer
+}
+
+// Close closes any underlying resources (no-op for the HTTP client)
+func (m *MistralProvider) Close() error {
+	return nil
+}
+
+// transformRequest converts a StandardRequest to Mistral format
+func (m *MistralProvider) transformRequest(req *GenerateRequest) *openai.ChatCompletionRequest {
+	mistralReq := &openai.ChatCompletionRequest{
+		Model:    req.Model,
+		Messages: toolMessages(req.Messages),
+		Tools:    functionTools(req.Functions),
+		Stream:   req.Stream,
+	}
+
+	if req.MaxTokens != nil {
+		mistralReq.MaxTokens = *req.MaxTokens
+	}
+
+	if req.Temperature != nil {
+		mistralReq.Temperature = float32(*req.Temperature)
+	}
+
+	if req.TopP != nil {
+		mistralReq.TopP = float32(*req.TopP)
+	}
+
+	return mistralReq
+}
+
+// transformResponse converts a Mistral response to StandardResponse
+func (m *MistralProvider) transformResponse(resp *openai.ChatCompletionResponse) *StandardResponse {
+	choices := make([]Choice, len(resp.Choices))
+
+	for i, choice := range resp.Choices {
+		choices[i] = Choice{
+			Index: choice.Index,
+			Message: &Message{
+				Role:         choice.Message.Role,
+				Content:      choice.Message.Content,
+				FunctionCall: toolFunctionCall(choice.Message.ToolCalls),
+			},
+			FinishReason: toolFinishReason(choice.FinishReason),
+		}
+	}
+
+	return CreateStandardResponse(resp.ID, resp.Model, choices, openAIUsage(resp.Usage))
+}
+
+// transformStreamChunk converts a Mistral stream response to StreamChunk.
+// Mistral emits each tool call whole in a single delta.
+func (m *MistralProvider) transformStreamChunk(resp *openai.ChatCompletionStreamResponse) *StreamChunk {
+	choices := make([]Choice, len(resp.Choices))
+
+	for i, choice := range resp.Choices {
+		choices[i] = Choice{
+			Index: choice.Index,
+			Delta: &Message{
+				Role:         choice.Delta.Role,
+				Content:      choice.Delta.Content,
+				FunctionCall: toolFunctionCall(choice.Delta.ToolCalls),
+			},
+			FinishReason: toolFinishReason(choice.FinishReason),
+		}
+	}
+
+	done := len(resp.Choices) > 0 && resp.Choices[0].FinishReason != ""
+
+	return CreateStreamChunk(resp.ID, resp.Model, choices, done)
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMistralToolCallTranslation(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"cmpl-1","model":"mistral-large-latest","choices":[{"index":0,
+			"message":{"role":"assistant","content":"","tool_calls":[{"id":"abc123xyz","type":"function",
+			"function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},
+			"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewMistralProvider("test-key", srv.URL, "")
+	if err != nil {
+		t.Fatalf("Failed to create Mistral provider: %v", err)
+	}
+
+	name := "get_weather"
+	resp, err := p.Generate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model: "mistral-large-latest",
+		Messages: []Message{
+			{Role: RoleUser, Content: "Weather in Paris?"},
+			{Role: RoleAssistant, FunctionCall: &FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+			{Role: RoleFunction, Name: &name, Content: `{"temp":21}`},
+		},
+		Functions: []Function{{Name: "get_weather", Parameters: map[string]interface{}{"type": "object"}}},
+	}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if _, ok := got["functions"]; ok {
+		t.Error("Functions should be sent as tools")
+	}
+	tools, _ := got["tools"].([]interface{})
+	if len(tools) != 1 {
+		t.Fatalf("Expected 1 tool, got %v", got["tools"])
+	}
+
+	msgs := got["messages"].([]interface{})
+	call := msgs[1].(map[string]interface{})["tool_calls"].([]interface{})[0].(map[string]interface{})
+	result := msgs[2].(map[string]interface{})
+	if result["role"] != "tool" || result["tool_call_id"] != call["id"] {
+		t.Errorf("Function result should answer the tool call: call=%v result=%v", call, result)
+	}
+	if id, _ := call["id"].(string); len(id) != 9 {
+		t.Errorf("Expected 9 character tool call id, got %q", id)
+	}
+
+	choice := resp.Choices[0]
+	if choice.Message.FunctionCall == nil || choice.Message.FunctionCall.Name != "get_weather" {
+		t.Errorf("Expected function call in response, got %+v", choice.Message)
+	}
+	if choice.FinishReason == nil || *choice.FinishReason != FinishReasonFunctionCall {
+		t.Errorf("Expected finish reason %q, got %v", FinishReasonFunctionCall, choice.FinishReason)
+	}
+}
+
+func TestMistralStreamGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"id\":\"c1\",\"model\":\"codestral-latest\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"def\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"id\":\"c1\",\"model\":\"codestral-latest\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\" main\"},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p, err := NewMistralProvider("test-key", srv.URL, "")
+	if err != nil {
+		t.Fatalf("Failed to create Mistral provider: %v", err)
+	}
+
+	stream, err := p.StreamGenerate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "codestral-latest",
+		Messages: []Message{{Role: RoleUser, Content: "write main"}},
+		Stream:   true,
+	}})
+	if err != nil {
+		t.Fatalf("StreamGenerate failed: %v", err)
+	}
+	defer stream.Close()
+
+	var content string
+	var done bool
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		var chunk StreamChunk
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatalf("Invalid chunk: %v", err)
+		}
+		content += chunk.Choices[0].Delta.Content
+		done = chunk.Done
+	}
+	if content != "def main" || !done {
+		t.Errorf("Unexpected stream result: content=%q done=%v", content, done)
+	}
+}
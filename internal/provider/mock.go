@@ -0,0 +1,458 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// MockProvider implements the Provider interface with scripted responses, so
+// integration tests and demos run without API keys. Answers depend only on
+// the request, and token usage counts words.
+//
+// Configured content, deltas and function call arguments are templates
+// executed with mockTemplateData, e.g. "It is {{.FunctionResult}} today".
+type MockProvider struct {
+	cfg          config.MockConfig
+	capabilities ProviderCapabilities
+	templates    map[string]*template.Template
+}
+
+// mockTemplateData is what answer templates can refer to
+type mockTemplateData struct {
+	Model string
+	// Last and first user messages
+	Prompt      string
+	FirstPrompt string
+	// Name and content of the last function result message
+	FunctionName   string
+	FunctionResult string
+	// Number of the assistant turn being answered, from 1
+	Turn int
+}
+
+// NewMockProvider creates a mock provider from its configuration
+func NewMockProvider(cfg config.MockConfig) (*MockProvider, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("mock name is required")
+	}
+
+	m := &MockProvider{
+		cfg:       cfg,
+		templates: make(map[string]*template.Template),
+		capabilities: ProviderCapabilities{
+			SupportsStreaming:   true,
+			SupportsSystemRole:  true,
+			MaxTokens:           4096,
+			MaxContextLength:    128000,
+			SupportedModels:     cfg.Models,
+			SupportedParameters: []string{"max_tokens", "stream"},
+		},
+	}
+
+	answers := append([]config.MockResponse(nil), cfg.Responses...)
+	for _, sc := range cfg.Scenarios {
+		answers = append(answers, sc.Turns...)
+	}
+	for _, a := range answers {
+		sources := append([]string{a.Content}, a.Deltas...)
+		if a.FunctionCall != nil {
+			sources = append(sources, a.FunctionCall.Arguments)
+			// Scripted function calls only make sense if functions reach us
+			m.capabilities.SupportsFunctions = true
+		}
+		for _, src := range sources {
+			if err := m.parseTemplate(src); err != nil {
+				return nil, fmt.Errorf("mock %s: %w", cfg.Name, err)
+			}
+		}
+	}
+	if m.capabilities.SupportsFunctions {
+		m.capabilities.SupportedParameters = append(m.capabilities.SupportedParameters, "functions")
+	}
+	return m, nil
+}
+
+func (m *MockProvider) parseTemplate(src string) error {
+	if _, ok := m.templates[src]; ok || !strings.Contains(src, "{{") {
+		return nil
+	}
+	tmpl, err := template.New("").Option("missingkey=error").Parse(src)
+	if err != nil {
+		return err
+	}
+	m.templates[src] = tmpl
+	return nil
+}
+
+// render executes src as a template, or returns it as-is when it has no
+// actions
+func (m *MockProvider) render(src string, data *mockTemplateData) (string, error) {
+	tmpl, ok := m.templates[src]
+	if !ok {
+		return src, nil
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("mock %s: %w", m.cfg.Name, err)
+	}
+	return b.String(), nil
+}
+
+// Generate answers with the first matching canned response
+func (m *MockProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	resp, err := m.respond(req.StandardRequest)
+	if err != nil {
+		return nil, err
+	}
+	if err := sleepContext(ctx, m.cfg.Latency); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+
+	msg := &Message{Role: RoleAssistant, Content: resp.Content, FunctionCall: mockFunctionCall(resp.FunctionCall)}
+	return &GenerateResponse{
+		StandardResponse: CreateStandardResponse(NewResponseID(), req.Model,
+			[]Choice{{Message: msg, FinishReason: &resp.FinishReason}}, m.usage(req.StandardRequest, resp.Content)),
+	}, nil
+}
+
+// StreamGenerate streams the matching response's deltas, ChunkDelay apart
+// plus any stalls, then its function call in one chunk
+func (m *MockProvider) StreamGenerate(ctx context.Context, req *GenerateRequest) (io.ReadCloser, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	resp, err := m.respond(req.StandardRequest)
+	if err != nil {
+		return nil, err
+	}
+	if err := sleepContext(ctx, m.cfg.Latency); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+
+	pr, pw := io.Pipe()
+	id, created := NewResponseID(), time.Now().Unix()
+
+	go func() {
+		defer pw.Close()
+
+		write := func(chunk *StreamChunk) error {
+			chunk.Created = created
+			chunkData, err := json.Marshal(chunk)
+			if err != nil {
+				return fmt.Errorf("failed to marshal chunk: %w", err)
+			}
+			_, err = pw.Write(append(chunkData, '\n'))
+			return err
+		}
+
+		stalls := make(map[int]time.Duration)
+		for _, st := range resp.Stalls {
+			stalls[st.After] += st.Duration
+		}
+		pause := func(i int) error {
+			d := stalls[i]
+			if i > 0 {
+				d += m.cfg.ChunkDelay
+			}
+			return sleepContext(ctx, d)
+		}
+
+		deltas := make([]*Message, 0, len(resp.Deltas)+1)
+		for _, delta := range resp.Deltas {
+			deltas = append(deltas, &Message{Content: delta})
+		}
+		if call := mockFunctionCall(resp.FunctionCall); call != nil {
+			deltas = append(deltas, &Message{FunctionCall: call})
+		}
+		for i, msg := range deltas {
+			// Stalls after the last content delta hold back the function call
+			if err := pause(i); err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+			if i == 0 {
+				msg.Role = RoleAssistant
+			}
+			if err := write(CreateStreamChunk(id, req.Model, []Choice{{Delta: msg}}, false)); err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+		}
+		if d := stalls[len(deltas)]; d > 0 && len(deltas) > 0 {
+			if err := sleepContext(ctx, d); err != nil {
+				_ = pw.CloseWithError(err)
+				return
+			}
+		}
+
+		usage := m.usage(req.StandardRequest, resp.Content)
+		final := CreateStreamChunk(id, req.Model, []Choice{{Delta: &Message{}, FinishReason: &resp.FinishReason}}, true)
+		final.Usage = &usage
+		if err := write(final); err != nil {
+			_ = pw.CloseWithError(err)
+		}
+	}()
+
+	return pr, nil
+}
+
+// Embed returns a deterministic unit vector per input, derived from its
+// hash, so equal inputs embed equally
+func (m *MockProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	if err := ValidateEmbeddingRequest(req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if err := sleepContext(ctx, m.cfg.Latency); err != nil {
+		return nil, err
+	}
+
+	dims := defaultMockDimensions
+	if req.Dimensions != nil {
+		dims = *req.Dimensions
+	}
+	vectors := make([][]float32, len(req.Input))
+	tokens := 0
+	for i, in := range req.Input {
+		vectors[i] = mockVector(in, dims)
+		tokens += len(strings.Fields(in))
+	}
+	return CreateEmbeddingResponse(req.Model, vectors, Usage{PromptTokens: tokens, TotalTokens: tokens}), nil
+}
+
+// Transcribe returns a transcript naming the file and its size, so tests can
+// tell uploads apart
+func (m *MockProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	if err := ValidateTranscriptionRequest(req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if err := sleepContext(ctx, m.cfg.Latency); err != nil {
+		return nil, err
+	}
+	text := fmt.Sprintf("Transcript of %s (%d bytes)", req.Filename, len(req.Audio))
+	return &TranscriptionResponse{Text: text, Language: req.Language, Model: req.Model}, nil
+}
+
+// mockSpeechRate is the sample rate of mock speech; each word of input
+// yields mockWordDuration of 16-bit mono silence
+const (
+	mockSpeechRate   = 8000
+	mockWordDuration = 100 * time.Millisecond
+)
+
+// Speak returns silence whose length follows the word count of the input,
+// as wav or pcm; other formats are refused
+func (m *MockProvider) Speak(ctx context.Context, req *SpeechRequest) (*SpeechResponse, error) {
+	if err := ValidateSpeechRequest(req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	if req.Format != "wav" && req.Format != "pcm" {
+		return nil, fmt.Errorf("mock speech only produces wav and pcm, not %q", req.Format)
+	}
+	if err := sleepContext(ctx, m.cfg.Latency); err != nil {
+		return nil, err
+	}
+
+	samples := len(strings.Fields(req.Input)) * int(mockWordDuration.Seconds()*mockSpeechRate)
+	pcm := make([]byte, samples*2)
+	if req.Format == "pcm" {
+		return &SpeechResponse{Audio: io.NopCloser(bytes.NewReader(pcm)), ContentType: SpeechFormats["pcm"]}, nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVEfmt ")
+	// PCM, mono, sample rate, byte rate, block align, bits per sample
+	for _, v := range []any{uint32(16), uint16(1), uint16(1), uint32(mockSpeechRate), uint32(mockSpeechRate * 2), uint16(2), uint16(16)} {
+		_ = binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+	return &SpeechResponse{Audio: io.NopCloser(&buf), ContentType: SpeechFormats["wav"]}, nil
+}
+
+// GetCapabilities returns the capabilities of the mock provider
+func (m *MockProvider) GetCapabilities() ProviderCapabilities {
+	return m.capabilities
+}
+
+// GetInfo returns information about the mock provider
+func (m *MockProvider) GetInfo() ProviderInfo {
+	return ProviderInfo{
+		Name:         m.cfg.Name,
+		Version:      "1.0.0",
+		Capabilities: m.capabilities,
+		Status:       "active",
+		LastUpdated:  time.Now(),
+	}
+}
+
+// Close closes any underlying resources (no-op for the mock)
+func (m *MockProvider) Close() error {
+	return nil
+}
+
+// respond picks the scenario turn or canned response for req, rendering
+// its templates and filling in defaults
+func (m *MockProvider) respond(req *StandardRequest) (config.MockResponse, error) {
+	data := &mockTemplateData{Model: req.Model, Turn: 1}
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case RoleUser:
+			if data.FirstPrompt == "" {
+				data.FirstPrompt = msg.Content
+			}
+			data.Prompt = msg.Content
+		case RoleAssistant:
+			data.Turn++
+		case RoleFunction:
+			data.FunctionResult = msg.Content
+			if msg.Name != nil {
+				data.FunctionName = *msg.Name
+			}
+		}
+	}
+
+	// The echo is the client's own text, never a template
+	resp, scripted := config.MockResponse{Content: data.Prompt}, false
+	for _, sc := range m.cfg.Scenarios {
+		if strings.Contains(data.FirstPrompt, sc.Match) && data.Turn <= len(sc.Turns) {
+			resp, scripted = sc.Turns[data.Turn-1], true
+			break
+		}
+	}
+	if !scripted {
+		for _, r := range m.cfg.Responses {
+			if strings.Contains(data.Prompt, r.Match) {
+				resp, scripted = r, true
+				break
+			}
+		}
+	}
+
+	if scripted {
+		var err error
+		if resp.Content, err = m.render(resp.Content, data); err != nil {
+			return resp, err
+		}
+		deltas := make([]string, len(resp.Deltas))
+		for i, d := range resp.Deltas {
+			if deltas[i], err = m.render(d, data); err != nil {
+				return resp, err
+			}
+		}
+		resp.Deltas = deltas
+		if resp.FunctionCall != nil {
+			call := *resp.FunctionCall
+			if call.Arguments, err = m.render(call.Arguments, data); err != nil {
+				return resp, err
+			}
+			resp.FunctionCall = &call
+		}
+	}
+
+	if len(resp.Deltas) == 0 {
+		resp.Deltas = splitWords(resp.Content)
+	} else if resp.Content == "" {
+		resp.Content = strings.Join(resp.Deltas, "")
+	}
+	if resp.FinishReason == "" {
+		resp.FinishReason = "stop"
+		if resp.FunctionCall != nil {
+			resp.FinishReason = "function_call"
+		}
+	}
+	return resp, nil
+}
+
+func mockFunctionCall(call *config.MockFunctionCall) *FunctionCall {
+	if call == nil {
+		return nil
+	}
+	args := call.Arguments
+	if args == "" {
+		args = "{}"
+	}
+	return &FunctionCall{Name: call.Name, Arguments: args}
+}
+
+func (m *MockProvider) usage(req *StandardRequest, completion string) Usage {
+	prompt := 0
+	for _, msg := range req.Messages {
+		prompt += len(strings.Fields(msg.Content))
+	}
+	completionTokens := len(strings.Fields(completion))
+	return Usage{PromptTokens: prompt, CompletionTokens: completionTokens, TotalTokens: prompt + completionTokens}
+}
+
+// defaultMockDimensions is the mock embedding size when none is requested
+const defaultMockDimensions = 16
+
+// mockVector spreads the FNV hash of s over dims components and normalises
+// the result to unit length
+func mockVector(s string, dims int) []float32 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	seed := h.Sum64()
+
+	v := make([]float32, dims)
+	var norm float64
+	for i := range v {
+		// xorshift keeps consecutive components uncorrelated
+		seed ^= seed << 13
+		seed ^= seed >> 7
+		seed ^= seed << 17
+		x := float64(seed%2001)/1000 - 1
+		v[i] = float32(x)
+		norm += x * x
+	}
+	if norm == 0 {
+		return v
+	}
+	scale := 1 / math.Sqrt(norm)
+	for i := range v {
+		v[i] = float32(float64(v[i]) * scale)
+	}
+	return v
+}
+
+// splitWords splits s into deltas of one word each, keeping the spaces so
+// the deltas concatenate back to s
+func splitWords(s string) []string {
+	var out []string
+	for len(s) > 0 {
+		i := strings.IndexByte(strings.TrimLeft(s, " "), ' ')
+		if i < 0 {
+			out = append(out, s)
+			break
+		}
+		i += len(s) - len(strings.TrimLeft(s, " "))
+		out = append(out, s[:i])
+		s = s[i:]
+	}
+	return out
+}
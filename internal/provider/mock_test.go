@@ -0,0 +1,208 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func newTestMock(t *testing.T, cfg config.MockConfig) *MockProvider {
+	t.Helper()
+	cfg.Name = "mock"
+	p, err := NewMockProvider(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create mock provider: %v", err)
+	}
+	return p
+}
+
+func mockRequest(prompt string) *GenerateRequest {
+	return &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "mock-1",
+		Messages: []Message{{Role: RoleUser, Content: prompt}},
+	}}
+}
+
+func TestMockGenerate(t *testing.T) {
+	p := newTestMock(t, config.MockConfig{Responses: []config.MockResponse{
+		{Match: "weather", Content: "Sunny and 21C", FinishReason: "length"},
+		{Match: "fail", Error: "upstream overloaded"},
+	}})
+
+	for name, tc := range map[string]struct {
+		prompt, content, finish, err string
+	}{
+		"canned": {prompt: "What's the weather?", content: "Sunny and 21C", finish: "length"},
+		"echo":   {prompt: "Hello there", content: "Hello there", finish: "stop"},
+		"error":  {prompt: "please fail", err: "upstream overloaded"},
+	} {
+		resp, err := p.Generate(context.Background(), mockRequest(tc.prompt))
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%s: expected error %q, got %v", name, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Generate failed: %v", name, err)
+		}
+		choice := resp.Choices[0]
+		if choice.Message.Content != tc.content || *choice.FinishReason != tc.finish {
+			t.Errorf("%s: unexpected choice %+v", name, choice)
+		}
+		if resp.Usage.CompletionTokens != len(strings.Fields(tc.content)) {
+			t.Errorf("%s: unexpected usage %+v", name, resp.Usage)
+		}
+	}
+}
+
+func TestMockStreamGenerate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		resp config.MockResponse
+		want []string
+	}{
+		"scripted deltas": {resp: config.MockResponse{Deltas: []string{"Sun", "ny"}}, want: []string{"Sun", "ny"}},
+		"split content":   {resp: config.MockResponse{Content: "It is  sunny"}, want: []string{"It", " is", "  sunny"}},
+	} {
+		p := newTestMock(t, config.MockConfig{ChunkDelay: time.Millisecond, Responses: []config.MockResponse{tc.resp}})
+		stream, err := p.StreamGenerate(context.Background(), mockRequest("weather?"))
+		if err != nil {
+			t.Fatalf("%s: StreamGenerate failed: %v", name, err)
+		}
+
+		var chunks []StreamChunk
+		scanner := bufio.NewScanner(stream)
+		for scanner.Scan() {
+			var chunk StreamChunk
+			if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+				t.Fatalf("%s: invalid chunk: %v", name, err)
+			}
+			chunks = append(chunks, chunk)
+		}
+		stream.Close()
+
+		var deltas []string
+		for _, c := range chunks[:len(chunks)-1] {
+			deltas = append(deltas, c.Choices[0].Delta.Content)
+		}
+		if strings.Join(deltas, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("%s: expected deltas %q, got %q", name, tc.want, deltas)
+		}
+		last := chunks[len(chunks)-1]
+		if !last.Done || last.Usage == nil || *last.Choices[0].FinishReason != "stop" {
+			t.Errorf("%s: unexpected final chunk %+v", name, last)
+		}
+	}
+}
+
+func TestMockLatencyHonoursCancellation(t *testing.T) {
+	p := newTestMock(t, config.MockConfig{Latency: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Generate(ctx, mockRequest("hi")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to cut latency short, got %v", err)
+	}
+}
+
+func TestMockScenario(t *testing.T) {
+	p := newTestMock(t, config.MockConfig{
+		Scenarios: []config.MockScenario{{
+			Name:  "weather agent",
+			Match: "weather",
+			Turns: []config.MockResponse{
+				{Content: "Checking.", FunctionCall: &config.MockFunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+				{Content: "{{.FunctionName}} says {{.FunctionResult}} (turn {{.Turn}})"},
+			},
+		}},
+		Responses: []config.MockResponse{{Content: "fallback"}},
+	})
+	if !p.GetCapabilities().SupportsFunctions {
+		t.Error("Expected function calls to enable function support")
+	}
+
+	req := mockRequest("What's the weather in Paris?")
+	resp, err := p.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := resp.Choices[0].Message
+	if msg.Content != "Checking." || msg.FunctionCall == nil || msg.FunctionCall.Name != "get_weather" || *resp.Choices[0].FinishReason != "function_call" {
+		t.Fatalf("Unexpected first turn %+v", resp.Choices[0])
+	}
+
+	name := "get_weather"
+	req.Messages = append(req.Messages, *msg, Message{Role: RoleFunction, Name: &name, Content: "sunny"})
+	resp, err = p.Generate(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "get_weather says sunny (turn 2)" {
+		t.Errorf("Unexpected second turn %q", got)
+	}
+
+	// Past the end of the script, and outside it, responses answer
+	req.Messages = append(req.Messages, *resp.Choices[0].Message, Message{Role: RoleUser, Content: "thanks"})
+	for _, r := range []*GenerateRequest{req, mockRequest("hello")} {
+		if resp, err := p.Generate(context.Background(), r); err != nil || resp.Choices[0].Message.Content != "fallback" {
+			t.Errorf("Expected the fallback response, got %+v, %v", resp, err)
+		}
+	}
+}
+
+func TestMockScenarioStream(t *testing.T) {
+	p := newTestMock(t, config.MockConfig{Scenarios: []config.MockScenario{{Turns: []config.MockResponse{{
+		Deltas:       []string{"One", " moment"},
+		FunctionCall: &config.MockFunctionCall{Name: "lookup"},
+		Stalls:       []config.MockStall{{After: 1, Duration: 50 * time.Millisecond}},
+	}}}}})
+
+	start := time.Now()
+	stream, err := p.StreamGenerate(context.Background(), mockRequest("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var chunks []StreamChunk
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		var chunk StreamChunk
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatalf("invalid chunk: %v", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected the stall to delay the stream, took %v", elapsed)
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("Expected 2 content chunks, a function call and the final chunk, got %d", len(chunks))
+	}
+	if call := chunks[2].Choices[0].Delta.FunctionCall; call == nil || call.Name != "lookup" || call.Arguments != "{}" {
+		t.Errorf("Unexpected function call chunk %+v", chunks[2].Choices[0].Delta)
+	}
+	if *chunks[3].Choices[0].FinishReason != "function_call" {
+		t.Errorf("Expected finish reason function_call, got %q", *chunks[3].Choices[0].FinishReason)
+	}
+}
+
+func TestMockTemplateErrors(t *testing.T) {
+	if _, err := NewMockProvider(config.MockConfig{Name: "m", Responses: []config.MockResponse{{Content: "{{.Prompt"}}}); err == nil {
+		t.Error("Expected an unparsable template to be rejected")
+	}
+	p := newTestMock(t, config.MockConfig{Responses: []config.MockResponse{{Content: "{{.Nope}}"}}})
+	if _, err := p.Generate(context.Background(), mockRequest("hi")); err == nil {
+		t.Error("Expected an unknown template field to fail the request")
+	}
+	// The echo of client input is never executed as a template
+	echo := newTestMock(t, config.MockConfig{})
+	if resp, err := echo.Generate(context.Background(), mockRequest("{{.Prompt}}")); err != nil || resp.Choices[0].Message.Content != "{{.Prompt}}" {
+		t.Errorf("Unexpected echo %+v, %v", resp, err)
+	}
+}
@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -14,6 +15,10 @@ type StandardRequest struct {
 	TopP        *float64               `json:"top_p,omitempty"`
 	Functions   []Function             `json:"functions,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+
+	// Passthrough holds request fields the gateway does not model, forwarded
+	// verbatim by providers that support it
+	Passthrough map[string]json.RawMessage `json:"passthrough,omitempty"`
 }
 
 // StandardResponse represents a standardized response format
@@ -25,6 +30,14 @@ type StandardResponse struct {
 	Choices  []Choice               `json:"choices"`
 	Usage    Usage                  `json:"usage"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Warnings []Warning              `json:"warnings,omitempty"`
+}
+
+// Warning is a non-fatal notice about a gateway-side adjustment to a request
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Param   string `json:"param,omitempty"`
 }
 
 // Message represents a chat message
@@ -34,6 +47,58 @@ type Message struct {
 	Name         *string                `json:"name,omitempty"`
 	FunctionCall *FunctionCall          `json:"function_call,omitempty"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+
+	// ReasoningContent carries the chain of thought of reasoning models,
+	// kept apart from Content so clients can show or drop it separately
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// EmbeddingRequest asks for vector embeddings of one or more inputs
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+	// Output size, for models that can shorten their embeddings
+	Dimensions *int `json:"dimensions,omitempty"`
+}
+
+// Embedding is the vector of one input
+type Embedding struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// EmbeddingResponse holds one embedding per input, in input order
+type EmbeddingResponse struct {
+	Object string      `json:"object"`
+	Data   []Embedding `json:"data"`
+	Model  string      `json:"model"`
+	Usage  Usage       `json:"usage"`
+}
+
+// TranscriptionRequest asks for the text spoken in an audio file
+type TranscriptionRequest struct {
+	Model string `json:"model"`
+	Audio []byte `json:"audio"`
+	// Name of the uploaded file; its extension identifies the audio format
+	Filename string `json:"filename"`
+	// MIME type of the audio when the client sent one
+	ContentType string `json:"content_type,omitempty"`
+	// ISO-639-1 language of the audio, if known
+	Language string `json:"language,omitempty"`
+	// Text to guide the style or vocabulary of the transcript
+	Prompt      string   `json:"prompt,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+// TranscriptionResponse is the transcript of an audio file
+type TranscriptionResponse struct {
+	Text string `json:"text"`
+	// Detected or requested language, when the provider reports one
+	Language string `json:"language,omitempty"`
+	// Length of the audio in seconds, when the provider reports it
+	Duration float64 `json:"duration,omitempty"`
+	Model    string  `json:"model"`
 }
 
 // Choice represents a completion choice
@@ -49,6 +114,32 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+
+	// Breakdowns reported by some providers; zero when not reported.
+	// CachedPromptTokens and AudioTokens are part of PromptTokens, the
+	// prompt tokens served from the provider's prompt cache and the audio
+	// input. ReasoningTokens are part of CompletionTokens.
+	CachedPromptTokens int `json:"cached_prompt_tokens,omitempty"`
+	ReasoningTokens    int `json:"reasoning_tokens,omitempty"`
+	AudioTokens        int `json:"audio_tokens,omitempty"`
+}
+
+// MetadataCitations is the Metadata key holding []Citation for providers
+// that ground answers in documents or web results. Cohere sets it on the
+// message, Perplexity on the response.
+const MetadataCitations = "citations"
+
+// Citation is a provider-neutral source backing generated text. Span
+// citations set Start, End and Text; web citations set URL and are
+// referenced from the text by their 1-based position, e.g. "[1]".
+type Citation struct {
+	Start       int      `json:"start,omitempty"`
+	End         int      `json:"end,omitempty"`
+	Text        string   `json:"text,omitempty"`
+	DocumentIDs []string `json:"document_ids,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Title       string   `json:"title,omitempty"`
+	Date        string   `json:"date,omitempty"`
 }
 
 // Function represents a function definition for function calling
@@ -69,6 +160,8 @@ type ProviderCapabilities struct {
 	SupportsStreaming   bool     `json:"supports_streaming"`
 	SupportsFunctions   bool     `json:"supports_functions"`
 	SupportsSystemRole  bool     `json:"supports_system_role"`
+	// Forwards StandardRequest.Passthrough fields upstream
+	SupportsPassthrough bool     `json:"supports_passthrough"`
 	MaxTokens           int      `json:"max_tokens"`
 	MaxContextLength    int      `json:"max_context_length"`
 	SupportedModels     []string `json:"supported_models"`
@@ -106,6 +199,10 @@ type StreamChunk struct {
 	Done    bool         `json:"done"`
 	Usage   *Usage       `json:"usage,omitempty"`
 	Error   *ErrorDetail `json:"error,omitempty"`
+
+	// Extension: progress of a server-side tool the gateway runs between
+	// model calls, on chunks carrying nothing else
+	ToolProgress json.RawMessage `json:"tool_progress,omitempty"`
 }
 
 // ErrorDetail represents detailed error information
@@ -132,6 +229,17 @@ const (
 	FinishReasonContentFilter = "content_filter"
 )
 
+// Common warning code constants
+const (
+	WarningParameterDropped      = "parameter_dropped"
+	WarningParameterUnsupported  = "parameter_unsupported"
+	WarningParameterDegraded     = "parameter_degraded"
+	WarningModelAliased          = "model_alias_substituted"
+	WarningLongContextModel      = "long_context_model_substituted"
+	WarningTruncated             = "truncation_applied"
+	WarningContentFilterFallback = "content_filter_fallback"
+)
+
 // Common object type constants
 const (
 	ObjectChatCompletion      = "chat.completion"
@@ -1,6 +1,28 @@
 package provider
 
-import "go.uber.org/fx"
+import (
+	"github.com/luguanyu1234/letllm-go/internal/cache"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/health"
+	"go.uber.org/fx"
+)
 
 // Module exports the provider router for dependency injection.
-var Module = fx.Provide(NewRouter)
+var Module = fx.Provide(
+	newCachedRouter,
+	fx.Annotate(
+		func(r *Router) health.Check { return r.ReadinessCheck() },
+		fx.ResultTags(`group:"readiness"`),
+	),
+)
+
+// newCachedRouter creates the router, keeping upstream model lists in the
+// configured cache backend
+func newCachedRouter(cfg *config.Config, c cache.Cache) (*Router, error) {
+	r, err := NewRouter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r.cache = c
+	return r, nil
+}
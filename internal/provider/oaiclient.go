@@ -0,0 +1,307 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// oaiClient is a thin client for the OpenAI chat completions API. Unlike an
+// SDK's request structs it forwards fields it does not know about, taken
+// from StandardRequest.Passthrough, so new OpenAI parameters reach the
+// upstream without waiting for a library release.
+type oaiClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+func newOAIClient(apiKey, baseURL string, httpClient *http.Client) *oaiClient {
+	return &oaiClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		apiKey:  apiKey,
+		http:    httpClient,
+	}
+}
+
+// oaiAPIError is a non-2xx response from an OpenAI-compatible API
+type oaiAPIError struct {
+	StatusCode int
+	Type       string
+	Code       string
+	Message    string
+}
+
+func (e *oaiAPIError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Message)
+}
+
+type oaiFunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+type oaiMessage struct {
+	Role         string           `json:"role,omitempty"`
+	Content      string           `json:"content,omitempty"`
+	Name         string           `json:"name,omitempty"`
+	FunctionCall *oaiFunctionCall `json:"function_call,omitempty"`
+
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+type oaiFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+type oaiChatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []oaiMessage  `json:"messages"`
+	MaxTokens   *int          `json:"max_tokens,omitempty"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	TopP        *float64      `json:"top_p,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+	Functions   []oaiFunction `json:"functions,omitempty"`
+}
+
+type oaiChoice struct {
+	Index        int        `json:"index"`
+	Message      oaiMessage `json:"message"`
+	Delta        oaiMessage `json:"delta"`
+	FinishReason string     `json:"finish_reason"`
+}
+
+// oaiChatResponse is both a completion and a stream chunk
+type oaiChatResponse struct {
+	ID      string      `json:"id"`
+	Model   string      `json:"model"`
+	Choices []oaiChoice `json:"choices"`
+	// Sent on streams only when requested with stream_options
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// oaiChatRequestFrom converts a StandardRequest to the wire format
+func oaiChatRequestFrom(req *StandardRequest) *oaiChatRequest {
+	out := &oaiChatRequest{
+		Model:       req.Model,
+		Messages:    make([]oaiMessage, len(req.Messages)),
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stream:      req.Stream,
+	}
+	for i, msg := range req.Messages {
+		m := oaiMessage{Role: msg.Role, Content: msg.Content}
+		if msg.Name != nil {
+			m.Name = *msg.Name
+		}
+		if msg.FunctionCall != nil {
+			m.FunctionCall = &oaiFunctionCall{Name: msg.FunctionCall.Name, Arguments: msg.FunctionCall.Arguments}
+		}
+		out.Messages[i] = m
+	}
+	for _, fn := range req.Functions {
+		out.Functions = append(out.Functions, oaiFunction{Name: fn.Name, Description: fn.Description, Parameters: fn.Parameters})
+	}
+	return out
+}
+
+// withPassthrough encodes body, then adds the passthrough fields it does
+// not set itself; fields the gateway sets always win
+func withPassthrough(body interface{}, passthrough map[string]json.RawMessage) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil || len(passthrough) == 0 {
+		return payload, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range passthrough {
+		if _, set := fields[k]; !set {
+			fields[k] = v
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// ChatCompletion sends a non-streaming chat completion request
+func (c *oaiClient) ChatCompletion(ctx context.Context, req *StandardRequest) (*oaiChatResponse, error) {
+	body := oaiChatRequestFrom(req)
+	body.Stream = false
+
+	resp, err := c.post(ctx, "/chat/completions", body, req.Passthrough)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out oaiChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &out, nil
+}
+
+// ChatCompletionStream starts a streaming chat completion; the caller must
+// close the returned stream
+func (c *oaiClient) ChatCompletionStream(ctx context.Context, req *StandardRequest) (*oaiStream, error) {
+	body := oaiChatRequestFrom(req)
+	body.Stream = true
+
+	resp, err := c.post(ctx, "/chat/completions", body, req.Passthrough)
+	if err != nil {
+		return nil, err
+	}
+	return &oaiStream{body: resp.Body, r: bufio.NewReader(resp.Body)}, nil
+}
+
+// ListModels fetches the model list unless the one v describes is still
+// current, which the upstream confirms with 304 Not Modified
+func (c *oaiClient) ListModels(ctx context.Context, v ModelListValidators) (*ModelList, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if v.ETag != "" {
+		httpReq.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		httpReq.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return &ModelList{Validators: v, NotModified: true}, nil
+	case resp.StatusCode/100 != 2:
+		return nil, decodeOAIError(resp)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decode model list: %w", err)
+	}
+	out := &ModelList{
+		Models:     make([]string, len(list.Data)),
+		Validators: ModelListValidators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")},
+	}
+	for i, m := range list.Data {
+		out.Models[i] = m.ID
+	}
+	return out, nil
+}
+
+func (c *oaiClient) post(ctx context.Context, path string, body interface{}, passthrough map[string]json.RawMessage) (*http.Response, error) {
+	payload, err := withPassthrough(body, passthrough)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, decodeOAIError(resp)
+	}
+	return resp, nil
+}
+
+// decodeOAIError reads an error response, falling back to the raw body for
+// upstreams that do not use OpenAI's error envelope
+func decodeOAIError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := &oaiAPIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+
+	var envelope struct {
+		Error struct {
+			Message string          `json:"message"`
+			Type    string          `json:"type"`
+			Code    json.RawMessage `json:"code"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &envelope) == nil && envelope.Error.Message != "" {
+		apiErr.Message = envelope.Error.Message
+		apiErr.Type = envelope.Error.Type
+		// Code is a string on OpenAI and a number on some compatible servers
+		apiErr.Code = strings.Trim(string(envelope.Error.Code), `"`)
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+// oaiStream reads server-sent chat completion chunks
+type oaiStream struct {
+	body io.ReadCloser
+	r    *bufio.Reader
+}
+
+// Recv returns the next chunk, or io.EOF after [DONE] or the end of the body
+func (s *oaiStream) Recv() (*oaiChatResponse, error) {
+	for {
+		line, err := s.r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 && err != nil {
+			return nil, err
+		}
+
+		data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:"))
+		if !ok {
+			// Blank lines, comments and other SSE fields
+			continue
+		}
+		data = bytes.TrimSpace(data)
+		if string(data) == "[DONE]" {
+			return nil, io.EOF
+		}
+
+		var chunk struct {
+			oaiChatResponse
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return nil, fmt.Errorf("decode chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return nil, errors.New(chunk.Error.Message)
+		}
+		return &chunk.oaiChatResponse, nil
+	}
+}
+
+func (s *oaiStream) Close() error {
+	return s.body.Close()
+}
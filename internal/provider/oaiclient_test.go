@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAIClientErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		status int
+		body   string
+		want   oaiAPIError
+	}{
+		"openai envelope": {http.StatusTooManyRequests, `{"error":{"message":"slow down","type":"rate_limit","code":"rate_limit_exceeded"}}`,
+			oaiAPIError{StatusCode: 429, Type: "rate_limit", Code: "rate_limit_exceeded", Message: "slow down"}},
+		"numeric code": {http.StatusBadRequest, `{"error":{"message":"bad","code":400}}`,
+			oaiAPIError{StatusCode: 400, Code: "400", Message: "bad"}},
+		"plain text": {http.StatusBadGateway, "upstream down\n", oaiAPIError{StatusCode: 502, Message: "upstream down"}},
+		"empty body": {http.StatusServiceUnavailable, "", oaiAPIError{StatusCode: 503, Message: "Service Unavailable"}},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer srv.Close()
+
+			c := newOAIClient("k", srv.URL, srv.Client())
+			_, err := c.ChatCompletion(context.Background(), &StandardRequest{Model: "m"})
+			var apiErr *oaiAPIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected oaiAPIError, got %v", err)
+			}
+			if *apiErr != tc.want {
+				t.Errorf("Expected %+v, got %+v", tc.want, *apiErr)
+			}
+		})
+	}
+}
+
+func TestOAIStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "data: {\"id\":\"c1\",\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		fmt.Fprint(w, "data:{\"id\":\"c1\",\"choices\":[{\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2,\"total_tokens\":5}}\n\n")
+		fmt.Fprint(w, "data: {\"error\":{\"message\":\"overloaded\"}}\n\n")
+	}))
+	defer srv.Close()
+
+	stream, err := newOAIClient("", srv.URL, srv.Client()).ChatCompletionStream(context.Background(), &StandardRequest{Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	first, err := stream.Recv()
+	if err != nil || first.Choices[0].Delta.Content != "Hel" {
+		t.Fatalf("Unexpected first chunk %+v, %v", first, err)
+	}
+	second, err := stream.Recv()
+	if err != nil || second.Choices[0].FinishReason != "stop" || second.Usage == nil || second.Usage.TotalTokens != 5 {
+		t.Fatalf("Unexpected second chunk %+v, %v", second, err)
+	}
+	if _, err := stream.Recv(); err == nil || err.Error() != "overloaded" {
+		t.Errorf("Expected in-stream error, got %v", err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected io.EOF at end of body, got %v", err)
+	}
+}
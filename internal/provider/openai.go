@@ -10,11 +10,15 @@ import (
 	openai "github.com/sashabaranov/go-openai"
 )
 
-// OpenAIProvider implements the Provider interface using OpenAI's Chat Completions API
+// OpenAIProvider implements the Provider interface using OpenAI's Chat
+// Completions API. Chat requests go through the thin oaiClient so unknown
+// parameters pass through; embeddings and models use go-openai.
 type OpenAIProvider struct {
+	chat         *oaiClient
 	client       *openai.Client
 	modelName    string
 	capabilities ProviderCapabilities
+	pacer        *Pacer
 }
 
 // NewOpenAIProvider creates a new OpenAI provider instance
@@ -27,21 +31,24 @@ func NewOpenAIProvider(apiKey, baseURL, modelName string) (*OpenAIProvider, erro
 		modelName = "gpt-4o-mini"
 	}
 
-	// Create client with optional custom base URL
-	var client *openai.Client
+	// Create client with optional custom base URL; requests are paced by
+	// the upstream's rate limit headers
+	pacer := NewPacer()
+	config := openai.DefaultConfig(apiKey)
 	if baseURL != "" {
-		config := openai.DefaultConfig(apiKey)
 		config.BaseURL = baseURL
-		client = openai.NewClientWithConfig(config)
-	} else {
-		client = openai.NewClient(apiKey)
 	}
+	httpClient := newPacedClient(pacer)
+	config.HTTPClient = httpClient
+	client := openai.NewClientWithConfig(config)
+	chat := newOAIClient(apiKey, config.BaseURL, httpClient)
 
 	// Define OpenAI capabilities
 	capabilities := ProviderCapabilities{
 		SupportsStreaming:   true,
 		SupportsFunctions:   true,
 		SupportsSystemRole:  true,
+		SupportsPassthrough: true,
 		MaxTokens:           4096,
 		MaxContextLength:    128000, // For GPT-4 models
 		SupportedModels:     []string{"gpt-4", "gpt-4-turbo", "gpt-4o", "gpt-4o-mini", "gpt-3.5-turbo"},
@@ -49,9 +56,11 @@ func NewOpenAIProvider(apiKey, baseURL, modelName string) (*OpenAIProvider, erro
 	}
 
 	return &OpenAIProvider{
+		chat:         chat,
 		client:       client,
 		modelName:    modelName,
 		capabilities: capabilities,
+		pacer:        pacer,
 	}, nil
 }
 
@@ -61,17 +70,12 @@ func (o *OpenAIProvider) Generate(ctx context.Context, req *GenerateRequest) (*G
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	openaiReq, err := o.transformRequest(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to transform request: %w", err)
-	}
-
-	resp, err := o.client.CreateChatCompletion(ctx, *openaiReq)
+	resp, err := o.chat.ChatCompletion(ctx, req.StandardRequest)
 	if err != nil {
 		return nil, fmt.Errorf("openai completion error: %w", err)
 	}
 
-	standardResp, err := o.transformResponse(&resp)
+	standardResp, err := o.transformResponse(resp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to transform response: %w", err)
 	}
@@ -87,14 +91,7 @@ func (o *OpenAIProvider) StreamGenerate(ctx context.Context, req *GenerateReques
 		return nil, fmt.Errorf("invalid request: %w", err)
 	}
 
-	openaiReq, err := o.transformRequest(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to transform request: %w", err)
-	}
-
-	openaiReq.Stream = true
-
-	stream, err := o.client.CreateChatCompletionStream(ctx, *openaiReq)
+	stream, err := o.chat.ChatCompletionStream(ctx, req.StandardRequest)
 	if err != nil {
 		return nil, fmt.Errorf("openai start stream error: %w", err)
 	}
@@ -116,7 +113,7 @@ func (o *OpenAIProvider) StreamGenerate(ctx context.Context, req *GenerateReques
 			}
 
 			// Transform streaming response to standard format
-			chunk, err := o.transformStreamChunk(&resp)
+			chunk, err := o.transformStreamChunk(resp)
 			if err != nil {
 				_ = pw.CloseWithError(fmt.Errorf("failed to transform stream chunk: %w", err))
 				return
@@ -139,6 +136,21 @@ func (o *OpenAIProvider) StreamGenerate(ctx context.Context, req *GenerateReques
 	return pr, nil
 }
 
+// Embed returns embeddings from the OpenAI embeddings API
+func (o *OpenAIProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	return openAIEmbed(ctx, o.client, req)
+}
+
+// Transcribe transcribes audio with Whisper or another transcription model
+func (o *OpenAIProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return openAITranscribe(ctx, o.client, req)
+}
+
+// Speak synthesises speech with a TTS model, streaming the upstream's audio
+func (o *OpenAIProvider) Speak(ctx context.Context, req *SpeechRequest) (*SpeechResponse, error) {
+	return openAISpeak(ctx, o.client, req)
+}
+
 // GetCapabilities returns the capabilities of the OpenAI provider
 func (o *OpenAIProvider) GetCapabilities() ProviderCapabilities {
 	return o.capabilities
@@ -155,70 +167,45 @@ func (o *OpenAIProvider) GetInfo() ProviderInfo {
 	}
 }
 
-// Close closes any underlying resources (no-op for the OpenAI client)
-func (o *OpenAIProvider) Close() error {
-	return nil
-}
-
-// transformRequest converts a StandardRequest to OpenAI format
-func (o *OpenAIProvider) transformRequest(req *GenerateRequest) (*openai.ChatCompletionRequest, error) {
-	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
-
-	for i, msg := range req.Messages {
-		openaiMsg := openai.ChatCompletionMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		}
-
-		if msg.Name != nil {
-			openaiMsg.Name = *msg.Name
-		}
-
-		if msg.FunctionCall != nil {
-			openaiMsg.FunctionCall = &openai.FunctionCall{
-				Name:      msg.FunctionCall.Name,
-				Arguments: msg.FunctionCall.Arguments,
-			}
-		}
-
-		messages[i] = openaiMsg
-	}
-
-	openaiReq := &openai.ChatCompletionRequest{
-		Model:    req.Model,
-		Messages: messages,
-		Stream:   req.Stream,
-	}
-
-	if req.MaxTokens != nil {
-		openaiReq.MaxTokens = *req.MaxTokens
+// ListModels returns the IDs of the models the OpenAI API serves
+func (o *OpenAIProvider) ListModels(ctx context.Context) ([]string, error) {
+	list, err := o.ListModelsIfChanged(ctx, ModelListValidators{})
+	if err != nil {
+		return nil, err
 	}
+	return list.Models, nil
+}
 
-	if req.Temperature != nil {
-		openaiReq.Temperature = float32(*req.Temperature)
+// ListModelsIfChanged lists models with a conditional request, so an
+// unchanged list costs the upstream a 304
+func (o *OpenAIProvider) ListModelsIfChanged(ctx context.Context, v ModelListValidators) (*ModelList, error) {
+	list, err := o.chat.ListModels(ctx, v)
+	if err != nil {
+		return nil, fmt.Errorf("openai list models: %w", err)
 	}
+	return list, nil
+}
 
-	if req.TopP != nil {
-		openaiReq.TopP = float32(*req.TopP)
+// Health verifies the API is reachable and the key is accepted
+func (o *OpenAIProvider) Health(ctx context.Context) error {
+	if _, err := o.client.ListModels(ctx); err != nil {
+		return fmt.Errorf("openai list models: %w", err)
 	}
+	return nil
+}
 
-	if len(req.Functions) > 0 {
-		functions := make([]openai.FunctionDefinition, len(req.Functions))
-		for i, fn := range req.Functions {
-			functions[i] = openai.FunctionDefinition{
-				Name:        fn.Name,
-				Description: fn.Description,
-				Parameters:  fn.Parameters,
-			}
-		}
-		openaiReq.Functions = functions
-	}
+// Pacer returns the pacer tracking OpenAI rate limit headers
+func (o *OpenAIProvider) Pacer() *Pacer {
+	return o.pacer
+}
 
-	return openaiReq, nil
+// Close closes any underlying resources (no-op for the OpenAI client)
+func (o *OpenAIProvider) Close() error {
+	return nil
 }
 
 // transformResponse converts an OpenAI response to StandardResponse
-func (o *OpenAIProvider) transformResponse(resp *openai.ChatCompletionResponse) (*StandardResponse, error) {
+func (o *OpenAIProvider) transformResponse(resp *oaiChatResponse) (*StandardResponse, error) {
 	choices := make([]Choice, len(resp.Choices))
 
 	for i, choice := range resp.Choices {
@@ -240,7 +227,7 @@ func (o *OpenAIProvider) transformResponse(resp *openai.ChatCompletionResponse)
 
 		var finishReason *string
 		if choice.FinishReason != "" {
-			reason := string(choice.FinishReason)
+			reason := choice.FinishReason
 			finishReason = &reason
 		}
 
@@ -251,17 +238,16 @@ func (o *OpenAIProvider) transformResponse(resp *openai.ChatCompletionResponse)
 		}
 	}
 
-	usage := Usage{
-		PromptTokens:     resp.Usage.PromptTokens,
-		CompletionTokens: resp.Usage.CompletionTokens,
-		TotalTokens:      resp.Usage.TotalTokens,
+	var usage Usage
+	if resp.Usage != nil {
+		usage = *resp.Usage
 	}
 
 	return CreateStandardResponse(resp.ID, resp.Model, choices, usage), nil
 }
 
 // transformStreamChunk converts an OpenAI stream response to StreamChunk
-func (o *OpenAIProvider) transformStreamChunk(resp *openai.ChatCompletionStreamResponse) (*StreamChunk, error) {
+func (o *OpenAIProvider) transformStreamChunk(resp *oaiChatResponse) (*StreamChunk, error) {
 	choices := make([]Choice, len(resp.Choices))
 
 	for i, choice := range resp.Choices {
@@ -279,7 +265,7 @@ func (o *OpenAIProvider) transformStreamChunk(resp *openai.ChatCompletionStreamR
 
 		var finishReason *string
 		if choice.FinishReason != "" {
-			reason := string(choice.FinishReason)
+			reason := choice.FinishReason
 			finishReason = &reason
 		}
 
@@ -292,5 +278,7 @@ func (o *OpenAIProvider) transformStreamChunk(resp *openai.ChatCompletionStreamR
 
 	done := len(resp.Choices) > 0 && resp.Choices[0].FinishReason != ""
 
-	return CreateStreamChunk(resp.ID, resp.Model, choices, done), nil
+	chunk := CreateStreamChunk(resp.ID, resp.Model, choices, done)
+	chunk.Usage = resp.Usage
+	return chunk, nil
 }
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"fmt"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// OpenAICompatibleProvider serves any endpoint implementing the OpenAI chat
+// completions API, such as vLLM, LM Studio or llama.cpp's server. Several
+// instances can run side by side, each under its own name.
+type OpenAICompatibleProvider struct {
+	*OpenAIProvider
+	name string
+}
+
+// NewOpenAICompatibleProvider creates a named OpenAI-compatible provider.
+// apiKey is optional since local servers commonly run without auth.
+func NewOpenAICompatibleProvider(name, apiKey, baseURL, modelName string, models []string) (*OpenAICompatibleProvider, error) {
+	if name == "" {
+		return nil, fmt.Errorf("openai_compatible name is required")
+	}
+	if baseURL == "" {
+		return nil, fmt.Errorf("openai_compatible %s: base_url is required", name)
+	}
+	if modelName == "" && len(models) > 0 {
+		modelName = models[0]
+	}
+
+	pacer := NewPacer()
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = baseURL
+	httpClient := newPacedClient(pacer)
+	config.HTTPClient = httpClient
+
+	// Limits depend on the served model; leave them unset
+	capabilities := ProviderCapabilities{
+		SupportsStreaming:   true,
+		SupportsFunctions:   true,
+		SupportsSystemRole:  true,
+		SupportsPassthrough: true,
+		SupportedModels:     models,
+		SupportedParameters: []string{"temperature", "top_p", "max_tokens", "stream", "functions"},
+	}
+
+	return &OpenAICompatibleProvider{
+		OpenAIProvider: &OpenAIProvider{
+			chat:         newOAIClient(apiKey, baseURL, httpClient),
+			client:       openai.NewClientWithConfig(config),
+			modelName:    modelName,
+			capabilities: capabilities,
+			pacer:        pacer,
+		},
+		name: name,
+	}, nil
+}
+
+// GetInfo returns information about the instance under its configured name
+func (o *OpenAICompatibleProvider) GetInfo() ProviderInfo {
+	return ProviderInfo{
+		Name:         o.name,
+		Version:      "1.0.0",
+		Capabilities: o.capabilities,
+		Status:       "active",
+		LastUpdated:  time.Now(),
+	}
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestOpenAICompatibleGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c-1","model":"llama-3","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewOpenAICompatibleProvider("vllm", "", srv.URL+"/v1", "", []string{"llama-3"})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if p.GetInfo().Name != "vllm" || p.modelName != "llama-3" {
+		t.Errorf("Unexpected instance: %+v, default model %q", p.GetInfo(), p.modelName)
+	}
+
+	resp, err := p.Generate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "llama-3",
+		Messages: []Message{{Role: RoleUser, Content: "Hello"}},
+	}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "hi" || resp.Usage.TotalTokens != 4 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	if _, err := NewOpenAICompatibleProvider("vllm", "", "", "", nil); err == nil {
+		t.Error("Expected error without base URL")
+	}
+}
+
+func TestRegistryOpenAICompatibleRouting(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "test-openai-key"
+	cfg.OpenAICompatible = []config.OpenAICompatibleConfig{
+		{Name: "vllm", BaseURL: "http://gpu-1:8000/v1", Models: []string{"gpt-oss-20b"}},
+		{Name: "lm-studio", BaseURL: "http://localhost:1234/v1"},
+	}
+	cfg.Routes = []config.Route{{Prefix: "local/", Provider: "lm-studio"}}
+
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	for model, want := range map[string]string{
+		"gpt-oss-20b": "vllm",
+		"local/qwen":  "lm-studio",
+		"gpt-4o":      "openai",
+	} {
+		p, err := r.Route(&RouteRequest{Model: model})
+		if err != nil {
+			t.Errorf("Failed to route %s: %v", model, err)
+			continue
+		}
+		if got := p.GetInfo().Name; got != want {
+			t.Errorf("Model %s routed to %s, want %s", model, got, want)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultOpenRouterBaseURL is the OpenRouter API endpoint
+const DefaultOpenRouterBaseURL = "https://openrouter.ai/api/v1"
+
+// Response metadata keys describing where a request was actually served,
+// for reconciling gateway usage with upstream invoices
+const (
+	MetadataGenerationID     = "generation_id"
+	MetadataUpstreamProvider = "upstream_provider"
+	MetadataUpstreamModel    = "upstream_model"
+	MetadataCostUSD          = "cost_usd"
+)
+
+// OpenRouterProvider implements the Provider interface using OpenRouter's
+// OpenAI-compatible API. Requests carry the configured attribution headers
+// and responses report the upstream provider, model and cost that served
+// them in StandardResponse.Metadata. Model IDs may be given with an
+// "openrouter/" prefix, which is stripped.
+type OpenRouterProvider struct {
+	httpClient   *http.Client
+	apiKey       string
+	baseURL      string
+	modelName    string
+	referer      string
+	title        string
+	capabilities ProviderCapabilities
+	pacer        *Pacer
+}
+
+// NewOpenRouterProvider creates a new OpenRouter provider instance. referer
+// and title are sent as HTTP-Referer and X-Title for app attribution.
+func NewOpenRouterProvider(apiKey, baseURL, modelName, referer, title string) (*OpenRouterProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("openrouter apiKey is required")
+	}
+	if modelName == "" {
+		modelName = "openrouter/auto"
+	}
+	if baseURL == "" {
+		baseURL = DefaultOpenRouterBaseURL
+	}
+
+	// Define OpenRouter capabilities; limits depend on the routed model
+	capabilities := ProviderCapabilities{
+		SupportsStreaming:   true,
+		SupportsFunctions:   true,
+		SupportsSystemRole:  true,
+		SupportedModels:     []string{"openrouter/auto"},
+		SupportedParameters: []string{"temperature", "top_p", "max_tokens", "stream", "functions"},
+	}
+
+	pacer := NewPacer()
+	return &OpenRouterProvider{
+		httpClient:   newPacedClient(pacer),
+		apiKey:       apiKey,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		modelName:    modelName,
+		referer:      referer,
+		title:        title,
+		capabilities: capabilities,
+		pacer:        pacer,
+	}, nil
+}
+
+// openRouterRequest adds OpenRouter's usage accounting option to the
+// OpenAI request body
+type openRouterRequest struct {
+	openai.ChatCompletionRequest
+	Usage struct {
+		Include bool `json:"include"`
+	} `json:"usage"`
+}
+
+type openRouterUsage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+	usageDetails
+}
+
+// usage converts OpenRouter usage, including its breakdowns
+func (u *openRouterUsage) usage() Usage {
+	out := Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens}
+	u.usageDetails.apply(&out)
+	return out
+}
+
+// openRouterResponse is the OpenAI response plus OpenRouter's routing and
+// cost fields
+type openRouterResponse struct {
+	openai.ChatCompletionResponse
+	Provider string          `json:"provider"`
+	Usage    openRouterUsage `json:"usage"`
+}
+
+type openRouterStreamResponse struct {
+	openai.ChatCompletionStreamResponse
+	Provider string           `json:"provider"`
+	Usage    *openRouterUsage `json:"usage"`
+}
+
+// Generate generates a completion for the given request
+func (o *OpenRouterProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	body, err := o.do(ctx, o.transformRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp openRouterResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("decode openrouter response: %w", err)
+	}
+
+	return &GenerateResponse{
+		StandardResponse: o.transformResponse(&resp),
+	}, nil
+}
+
+// StreamGenerate generates a streaming completion for the given request. The
+// routing metadata is attached to the final chunk's delta.
+func (o *OpenRouterProvider) StreamGenerate(ctx context.Context, req *GenerateRequest) (io.ReadCloser, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	orReq := o.transformRequest(req)
+	orReq.Stream = true
+	orReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	body, err := o.do(ctx, orReq)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer body.Close()
+		defer pw.Close()
+
+		var (
+			finish  *string
+			last    *openRouterStreamResponse
+			emitted bool
+		)
+		write := func(chunk *StreamChunk) bool {
+			chunkData, err := json.Marshal(chunk)
+			if err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("failed to marshal chunk: %w", err))
+				return false
+			}
+			if _, werr := pw.Write(append(chunkData, '\n')); werr != nil {
+				_ = pw.CloseWithError(werr)
+				return false
+			}
+			return true
+		}
+
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			// SSE lines; ": OPENROUTER PROCESSING" comments keep the connection alive
+			data, ok := bytes.CutPrefix(bytes.TrimSpace(scanner.Bytes()), []byte("data:"))
+			if !ok {
+				continue
+			}
+			data = bytes.TrimSpace(data)
+			if string(data) == "[DONE]" {
+				break
+			}
+
+			var event openRouterStreamResponse
+			if err := json.Unmarshal(data, &event); err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("decode openrouter stream event: %w", err))
+				return
+			}
+			last = &event
+
+			// The usage-only event follows the finish reason; hold the
+			// final chunk back until then
+			if len(event.Choices) == 0 {
+				continue
+			}
+			if r := toolFinishReason(event.Choices[0].FinishReason); r != nil {
+				finish = r
+			}
+			if !write(o.transformStreamChunk(&event)) {
+				return
+			}
+			emitted = true
+		}
+		if err := scanner.Err(); err != nil {
+			_ = pw.CloseWithError(fmt.Errorf("openrouter stream recv error: %w", err))
+			return
+		}
+		if last == nil || !emitted {
+			return
+		}
+
+		final := CreateStreamChunk(last.ID, last.Model, []Choice{{
+			Delta:        &Message{Role: RoleAssistant, Metadata: o.metadata(last.ID, last.Provider, last.Model, last.Usage)},
+			FinishReason: finish,
+		}}, true)
+		if last.Usage != nil {
+			usage := last.Usage.usage()
+			final.Usage = &usage
+		}
+		write(final)
+	}()
+
+	return pr, nil
+}
+
+// Embed is not supported
+func (o *OpenRouterProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	return nil, fmt.Errorf("openrouter: %w", ErrEmbeddingsUnsupported)
+}
+
+// Transcribe is not supported
+func (o *OpenRouterProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("openrouter: %w", ErrTranscriptionUnsupported)
+}
+
+// GetCapabilities returns the capabilities of the OpenRouter provider
+func (o *OpenRouterProvider) GetCapabilities() ProviderCapabilities {
+	return o.capabilities
+}
+
+// GetInfo returns information about the OpenRouter provider
+func (o *OpenRouterProvider) GetInfo() ProviderInfo {
+	return ProviderInfo{
+		Name:         "openrouter",
+		Version:      "1.0.0",
+		Capabilities: o.capabilities,
+		Status:       "active",
+		LastUpdated:  time.Now(),
+	}
+}
+
+// Health verifies the API is reachable and the key is accepted
+func (o *OpenRouterProvider) Health(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.baseURL+"/key", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("openrouter key info: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("openrouter key info: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Pacer returns the pacer tracking OpenRouter rate limit headers
+func (o *OpenRouterProvider) Pacer() *Pacer {
+	return o.pacer
+}
+
+// Close closes any underlying resources (no-op for the HTTP client)
+func (o *OpenRouterProvider) Close() error {
+	return nil
+}
+
+// do posts a chat completion request and returns the response body on success
+func (o *OpenRouterProvider) do(ctx context.Context, orReq *openRouterRequest) (io.ReadCloser, error) {
+	payload, err := json.Marshal(orReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	if o.referer != "" {
+		httpReq.Header.Set("HTTP-Referer", o.referer)
+	}
+	if o.title != "" {
+		httpReq.Header.Set("X-Title", o.title)
+	}
+
+	resp, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("openrouter completion error: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&apiErr)
+		return nil, fmt.Errorf("openrouter completion error: status %d: %s", resp.StatusCode, apiErr.Error.Message)
+	}
+	return resp.Body, nil
+}
+
+// transformRequest converts a StandardRequest to OpenRouter format
+func (o *OpenRouterProvider) transformRequest(req *GenerateRequest) *openRouterRequest {
+	orReq := &openRouterRequest{ChatCompletionRequest: openai.ChatCompletionRequest{
+		Model:    strings.TrimPrefix(req.Model, "openrouter/"),
+		Messages: toolMessages(req.Messages),
+		Tools:    functionTools(req.Functions),
+		Stream:   req.Stream,
+	}}
+	if orReq.Model == "auto" {
+		orReq.Model = "openrouter/auto"
+	}
+	orReq.Usage.Include = true
+
+	if req.MaxTokens != nil {
+		orReq.MaxTokens = *req.MaxTokens
+	}
+
+	if req.Temperature != nil {
+		orReq.Temperature = float32(*req.Temperature)
+	}
+
+	if req.TopP != nil {
+		orReq.TopP = float32(*req.TopP)
+	}
+
+	return orReq
+}
+
+// transformResponse converts an OpenRouter response to StandardResponse
+func (o *OpenRouterProvider) transformResponse(resp *openRouterResponse) *StandardResponse {
+	choices := make([]Choice, len(resp.Choices))
+
+	for i, choice := range resp.Choices {
+		choices[i] = Choice{
+			Index: choice.Index,
+			Message: &Message{
+				Role:         choice.Message.Role,
+				Content:      choice.Message.Content,
+				FunctionCall: toolFunctionCall(choice.Message.ToolCalls),
+			},
+			FinishReason: toolFinishReason(choice.FinishReason),
+		}
+	}
+
+	out := CreateStandardResponse(resp.ID, resp.Model, choices, resp.Usage.usage())
+	out.Metadata = o.metadata(resp.ID, resp.Provider, resp.Model, &resp.Usage)
+	return out
+}
+
+// transformStreamChunk converts an OpenRouter stream event to StreamChunk;
+// the final chunk is emitted separately once usage is known
+func (o *OpenRouterProvider) transformStreamChunk(resp *openRouterStreamResponse) *StreamChunk {
+	choices := make([]Choice, len(resp.Choices))
+
+	for i, choice := range resp.Choices {
+		choices[i] = Choice{
+			Index: choice.Index,
+			Delta: &Message{
+				Role:         choice.Delta.Role,
+				Content:      choice.Delta.Content,
+				FunctionCall: toolFunctionCall(choice.Delta.ToolCalls),
+			},
+		}
+	}
+
+	return CreateStreamChunk(resp.ID, resp.Model, choices, false)
+}
+
+// metadata describes which upstream served the generation and its cost
+func (o *OpenRouterProvider) metadata(id, upstream, model string, usage *openRouterUsage) map[string]interface{} {
+	md := map[string]interface{}{
+		MetadataGenerationID:  id,
+		MetadataUpstreamModel: model,
+	}
+	if upstream != "" {
+		md[MetadataUpstreamProvider] = upstream
+	}
+	if usage != nil {
+		md[MetadataCostUSD] = usage.Cost
+	}
+	return md
+}
@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenRouterGenerate(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("HTTP-Referer") != "https://app.example.com" || r.Header.Get("X-Title") != "Example" {
+			t.Errorf("Missing attribution headers: %v", r.Header)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"gen-1","model":"anthropic/claude-3.5-sonnet","provider":"Anthropic",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12,"cost":0.00036}}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewOpenRouterProvider("test-key", srv.URL, "", "https://app.example.com", "Example")
+	if err != nil {
+		t.Fatalf("Failed to create OpenRouter provider: %v", err)
+	}
+
+	resp, err := p.Generate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "openrouter/anthropic/claude-3.5-sonnet",
+		Messages: []Message{{Role: RoleUser, Content: "Hello"}},
+	}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if got["model"] != "anthropic/claude-3.5-sonnet" {
+		t.Errorf("Expected openrouter/ prefix to be stripped, got %v", got["model"])
+	}
+	if usage, _ := got["usage"].(map[string]interface{}); usage["include"] != true {
+		t.Errorf("Expected usage accounting to be requested, got %v", got["usage"])
+	}
+
+	md := resp.Metadata
+	if md[MetadataUpstreamProvider] != "Anthropic" || md[MetadataGenerationID] != "gen-1" || md[MetadataCostUSD] != 0.00036 {
+		t.Errorf("Unexpected metadata: %v", md)
+	}
+	if resp.Usage.TotalTokens != 12 || resp.Choices[0].Message.Content != "hi" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+}
+
+func TestOpenRouterStreamGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": OPENROUTER PROCESSING\n\n")
+		fmt.Fprint(w, `data: {"id":"gen-2","model":"openai/gpt-4o","provider":"OpenAI","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"id":"gen-2","model":"openai/gpt-4o","provider":"OpenAI","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"id":"gen-2","model":"openai/gpt-4o","provider":"OpenAI","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5,"cost":0.0001}}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p, err := NewOpenRouterProvider("test-key", srv.URL, "", "", "")
+	if err != nil {
+		t.Fatalf("Failed to create OpenRouter provider: %v", err)
+	}
+
+	stream, err := p.StreamGenerate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "openai/gpt-4o",
+		Messages: []Message{{Role: RoleUser, Content: "Hello"}},
+		Stream:   true,
+	}})
+	if err != nil {
+		t.Fatalf("StreamGenerate failed: %v", err)
+	}
+	defer stream.Close()
+
+	var chunks []StreamChunk
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		var chunk StreamChunk
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatalf("Invalid chunk: %v", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d", len(chunks))
+	}
+	if chunks[0].Choices[0].Delta.Content != "Hel" || chunks[1].Choices[0].Delta.Content != "lo" {
+		t.Errorf("Unexpected content chunks: %+v", chunks[:2])
+	}
+	last := chunks[2]
+	if !last.Done || *last.Choices[0].FinishReason != FinishReasonStop || last.Usage.TotalTokens != 5 {
+		t.Errorf("Unexpected final chunk: %+v", last)
+	}
+	if md := last.Choices[0].Delta.Metadata; md[MetadataUpstreamProvider] != "OpenAI" || md[MetadataCostUSD] != 0.0001 {
+		t.Errorf("Expected routing metadata on the final chunk, got %v", md)
+	}
+}
@@ -0,0 +1,28 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// PassthroughProvider is implemented by providers that can take requests
+// for API endpoints the gateway does not model, forwarded as they are
+type PassthroughProvider interface {
+	// PassthroughTarget returns the API base URL, such as
+	// "https://api.openai.com/v1", and the headers authenticating requests
+	PassthroughTarget() (*url.URL, http.Header, error)
+}
+
+// PassthroughTarget returns the API base URL and its bearer key
+func (o *OpenAIProvider) PassthroughTarget() (*url.URL, http.Header, error) {
+	u, err := url.Parse(o.chat.baseURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("base url: %w", err)
+	}
+	header := http.Header{}
+	if o.chat.apiKey != "" {
+		header.Set("Authorization", "Bearer "+o.chat.apiKey)
+	}
+	return u, header, nil
+}
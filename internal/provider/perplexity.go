@@ -0,0 +1,373 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultPerplexityBaseURL is the Perplexity API endpoint
+const DefaultPerplexityBaseURL = "https://api.perplexity.ai"
+
+// PerplexityProvider implements the Provider interface using Perplexity's
+// chat completions API for the Sonar family. The web sources an answer was
+// grounded in are returned as []Citation under MetadataCitations in
+// StandardResponse.Metadata, in the order the answer's "[n]" markers use.
+type PerplexityProvider struct {
+	httpClient   *http.Client
+	apiKey       string
+	baseURL      string
+	modelName    string
+	capabilities ProviderCapabilities
+	pacer        *Pacer
+}
+
+// NewPerplexityProvider creates a new Perplexity provider instance
+func NewPerplexityProvider(apiKey, baseURL, modelName string) (*PerplexityProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("perplexity apiKey is required")
+	}
+	if modelName == "" {
+		modelName = "sonar"
+	}
+	if baseURL == "" {
+		baseURL = DefaultPerplexityBaseURL
+	}
+
+	// Define Perplexity capabilities
+	capabilities := ProviderCapabilities{
+		SupportsStreaming:   true,
+		SupportsFunctions:   false,
+		SupportsSystemRole:  true,
+		MaxTokens:           8192,
+		MaxContextLength:    127072,
+		SupportedModels:     []string{"sonar", "sonar-pro", "sonar-reasoning", "sonar-reasoning-pro", "sonar-deep-research"},
+		SupportedParameters: []string{"temperature", "top_p", "max_tokens", "stream"},
+	}
+
+	pacer := NewPacer()
+	return &PerplexityProvider{
+		httpClient:   newPacedClient(pacer),
+		apiKey:       apiKey,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		modelName:    modelName,
+		capabilities: capabilities,
+		pacer:        pacer,
+	}, nil
+}
+
+type perplexityMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type perplexityRequest struct {
+	Model       string              `json:"model"`
+	Messages    []perplexityMessage `json:"messages"`
+	MaxTokens   int                 `json:"max_tokens,omitempty"`
+	Temperature *float64            `json:"temperature,omitempty"`
+	TopP        *float64            `json:"top_p,omitempty"`
+	Stream      bool                `json:"stream,omitempty"`
+}
+
+type perplexitySearchResult struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	Date  string `json:"date"`
+}
+
+type perplexityChoice struct {
+	Index        int                `json:"index"`
+	Message      *perplexityMessage `json:"message"`
+	Delta        *perplexityMessage `json:"delta"`
+	FinishReason string             `json:"finish_reason"`
+}
+
+// perplexityResponse is both the completion response and a stream event;
+// citations and search results repeat on every event
+type perplexityResponse struct {
+	ID            string                   `json:"id"`
+	Model         string                   `json:"model"`
+	Choices       []perplexityChoice       `json:"choices"`
+	Usage         *Usage                   `json:"usage"`
+	Citations     []string                 `json:"citations"`
+	SearchResults []perplexitySearchResult `json:"search_results"`
+}
+
+// Generate generates a completion for the given request
+func (p *PerplexityProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	body, err := p.do(ctx, p.transformRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp perplexityResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("decode perplexity response: %w", err)
+	}
+
+	return &GenerateResponse{
+		StandardResponse: p.transformResponse(&resp),
+	}, nil
+}
+
+// StreamGenerate generates a streaming completion for the given request. The
+// citations are attached to the delta of the chunk carrying the finish reason.
+func (p *PerplexityProvider) StreamGenerate(ctx context.Context, req *GenerateRequest) (io.ReadCloser, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	pplxReq := p.transformRequest(req)
+	pplxReq.Stream = true
+
+	body, err := p.do(ctx, pplxReq)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer body.Close()
+		defer pw.Close()
+
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			data, ok := bytes.CutPrefix(bytes.TrimSpace(scanner.Bytes()), []byte("data:"))
+			if !ok {
+				continue
+			}
+			data = bytes.TrimSpace(data)
+			if string(data) == "[DONE]" {
+				break
+			}
+
+			var event perplexityResponse
+			if err := json.Unmarshal(data, &event); err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("decode perplexity stream event: %w", err))
+				return
+			}
+
+			chunkData, err := json.Marshal(p.transformStreamChunk(&event))
+			if err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("failed to marshal chunk: %w", err))
+				return
+			}
+			if _, werr := pw.Write(append(chunkData, '\n')); werr != nil {
+				_ = pw.CloseWithError(werr)
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			_ = pw.CloseWithError(fmt.Errorf("perplexity stream recv error: %w", err))
+		}
+	}()
+
+	return pr, nil
+}
+
+// Embed is not supported
+func (p *PerplexityProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	return nil, fmt.Errorf("perplexity: %w", ErrEmbeddingsUnsupported)
+}
+
+// Transcribe is not supported
+func (p *PerplexityProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("perplexity: %w", ErrTranscriptionUnsupported)
+}
+
+// GetCapabilities returns the capabilities of the Perplexity provider
+func (p *PerplexityProvider) GetCapabilities() ProviderCapabilities {
+	return p.capabilities
+}
+
+// GetInfo returns information about the Perplexity provider
+func (p *PerplexityProvider) GetInfo() ProviderInfo {
+	return ProviderInfo{
+		Name:         "perplexity",
+		Version:      "1.0.0",
+		Capabilities: p.capabilities,
+		Status:       "active",
+		LastUpdated:  time.Now(),
+	}
+}
+
+// Health verifies the API is reachable. Perplexity has no models or key
+// endpoint, so the cheapest check is a one-token completion.
+func (p *PerplexityProvider) Health(ctx context.Context) error {
+	body, err := p.do(ctx, &perplexityRequest{
+		Model:     p.modelName,
+		Messages:  []perplexityMessage{{Role: RoleUser, Content: "ping"}},
+		MaxTokens: 1,
+	})
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// Pacer returns the pacer tracking Perplexity rate limit headers
+func (p *PerplexityProvider) Pacer() *Pacer {
+	return p.pacer
+}
+
+// Close closes any underlying resources (no-op for the HTTP client)
+func (p *PerplexityProvider) Close() error {
+	return nil
+}
+
+// do posts a chat completion request and returns the response body on success
+func (p *PerplexityProvider) do(ctx context.Context, pplxReq *perplexityRequest) (io.ReadCloser, error) {
+	payload, err := json.Marshal(pplxReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("perplexity completion error: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&apiErr)
+		return nil, fmt.Errorf("perplexity completion error: status %d: %s", resp.StatusCode, apiErr.Error.Message)
+	}
+	return resp.Body, nil
+}
+
+// transformRequest converts a StandardRequest to Perplexity format
+func (p *PerplexityProvider) transformRequest(req *GenerateRequest) *perplexityRequest {
+	pplxReq := &perplexityRequest{
+		Model:       req.Model,
+		Messages:    make([]perplexityMessage, len(req.Messages)),
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stream:      req.Stream,
+	}
+	for i, msg := range req.Messages {
+		pplxReq.Messages[i] = perplexityMessage{Role: msg.Role, Content: msg.Content}
+	}
+
+	if req.MaxTokens != nil {
+		pplxReq.MaxTokens = *req.MaxTokens
+	}
+
+	return pplxReq
+}
+
+// transformResponse converts a Perplexity response to StandardResponse
+func (p *PerplexityProvider) transformResponse(resp *perplexityResponse) *StandardResponse {
+	choices := make([]Choice, len(resp.Choices))
+
+	for i, choice := range resp.Choices {
+		msg := &Message{Role: RoleAssistant}
+		if choice.Message != nil {
+			msg.Content = choice.Message.Content
+		}
+		choices[i] = Choice{
+			Index:        choice.Index,
+			Message:      msg,
+			FinishReason: p.mapFinishReason(choice.FinishReason),
+		}
+	}
+
+	var usage Usage
+	if resp.Usage != nil {
+		usage = *resp.Usage
+	}
+
+	out := CreateStandardResponse(resp.ID, resp.Model, choices, usage)
+	if citations := p.normalizeCitations(resp); len(citations) > 0 {
+		out.Metadata = map[string]interface{}{MetadataCitations: citations}
+	}
+	return out
+}
+
+// transformStreamChunk converts a Perplexity stream event to StreamChunk
+func (p *PerplexityProvider) transformStreamChunk(resp *perplexityResponse) *StreamChunk {
+	choices := make([]Choice, len(resp.Choices))
+	done := false
+
+	for i, choice := range resp.Choices {
+		delta := &Message{}
+		if choice.Delta != nil {
+			delta.Role = choice.Delta.Role
+			delta.Content = choice.Delta.Content
+		}
+		if choice.FinishReason != "" {
+			done = true
+			if citations := p.normalizeCitations(resp); len(citations) > 0 {
+				delta.Metadata = map[string]interface{}{MetadataCitations: citations}
+			}
+		}
+		choices[i] = Choice{
+			Index:        choice.Index,
+			Delta:        delta,
+			FinishReason: p.mapFinishReason(choice.FinishReason),
+		}
+	}
+
+	chunk := CreateStreamChunk(resp.ID, resp.Model, choices, done)
+	if done && resp.Usage != nil {
+		chunk.Usage = resp.Usage
+	}
+	return chunk
+}
+
+// mapFinishReason returns nil for events that do not finish a choice;
+// Perplexity already uses OpenAI's stop and length reasons
+func (p *PerplexityProvider) mapFinishReason(reason string) *string {
+	if reason == "" {
+		return nil
+	}
+	return &reason
+}
+
+// normalizeCitations converts Perplexity's citation URLs to the standard
+// shape, filling in titles and dates from the matching search results
+func (p *PerplexityProvider) normalizeCitations(resp *perplexityResponse) []Citation {
+	results := make(map[string]perplexitySearchResult, len(resp.SearchResults))
+	for _, r := range resp.SearchResults {
+		results[r.URL] = r
+	}
+
+	urls := resp.Citations
+	if len(urls) == 0 {
+		// Newer responses may carry search_results alone
+		for _, r := range resp.SearchResults {
+			urls = append(urls, r.URL)
+		}
+	}
+
+	out := make([]Citation, len(urls))
+	for i, url := range urls {
+		r := results[url]
+		out[i] = Citation{URL: url, Title: r.Title, Date: r.Date}
+	}
+	return out
+}
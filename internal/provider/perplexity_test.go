@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPerplexityGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("Unexpected request: %s %v", r.URL.Path, r.Header)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"p-1","model":"sonar","citations":["https://a.example","https://b.example"],
+			"search_results":[{"title":"A","url":"https://a.example","date":"2024-05-01"}],
+			"choices":[{"index":0,"message":{"role":"assistant","content":"Paris [1][2]"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":5,"completion_tokens":4,"total_tokens":9}}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewPerplexityProvider("test-key", srv.URL, "")
+	if err != nil {
+		t.Fatalf("Failed to create Perplexity provider: %v", err)
+	}
+
+	resp, err := p.Generate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "sonar",
+		Messages: []Message{{Role: RoleUser, Content: "Capital of France?"}},
+	}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	citations, ok := resp.Metadata[MetadataCitations].([]Citation)
+	if !ok || len(citations) != 2 {
+		t.Fatalf("Expected two citations, got %v", resp.Metadata)
+	}
+	if c := citations[0]; c.URL != "https://a.example" || c.Title != "A" || c.Date != "2024-05-01" || citations[1].URL != "https://b.example" {
+		t.Errorf("Unexpected citations: %+v", citations)
+	}
+	if resp.Usage.TotalTokens != 9 || resp.Choices[0].Message.Content != "Paris [1][2]" {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+}
+
+func TestPerplexityStreamGenerate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"p-2","model":"sonar","citations":["https://a.example"],"choices":[{"index":0,"delta":{"role":"assistant","content":"Par"}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"id":"p-2","model":"sonar","citations":["https://a.example"],"choices":[{"index":0,"delta":{"content":"is [1]"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`+"\n\n")
+	}))
+	defer srv.Close()
+
+	p, err := NewPerplexityProvider("test-key", srv.URL, "")
+	if err != nil {
+		t.Fatalf("Failed to create Perplexity provider: %v", err)
+	}
+
+	stream, err := p.StreamGenerate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "sonar",
+		Messages: []Message{{Role: RoleUser, Content: "Capital of France?"}},
+		Stream:   true,
+	}})
+	if err != nil {
+		t.Fatalf("StreamGenerate failed: %v", err)
+	}
+	defer stream.Close()
+
+	var chunks []StreamChunk
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		var chunk StreamChunk
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			t.Fatalf("Invalid chunk: %v", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	if len(chunks) != 2 {
+		t.Fatalf("Expected 2 chunks, got %d", len(chunks))
+	}
+	if chunks[0].Choices[0].Delta.Metadata != nil {
+		t.Errorf("Expected citations only on the final chunk, got %v", chunks[0].Choices[0].Delta.Metadata)
+	}
+	last := chunks[1]
+	if !last.Done || last.Usage == nil || last.Usage.TotalTokens != 5 {
+		t.Errorf("Unexpected final chunk: %+v", last)
+	}
+	if _, ok := last.Choices[0].Delta.Metadata[MetadataCitations]; !ok {
+		t.Errorf("Expected citations on the final chunk, got %v", last.Choices[0].Delta.Metadata)
+	}
+}
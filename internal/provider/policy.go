@@ -0,0 +1,20 @@
+package provider
+
+import "fmt"
+
+// PolicyError is returned when a request is refused by gateway policy
+// rather than failing at the provider
+type PolicyError struct {
+	Code   string
+	Route  string
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("route %s refused by policy: %s", e.Route, e.Reason)
+}
+
+// Policy error codes
+const (
+	PolicyCodeOutsideSchedule = "route_outside_schedule"
+)
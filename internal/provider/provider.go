@@ -13,6 +13,14 @@ type Provider interface {
 	// StreamGenerate performs a streaming text generation request
 	StreamGenerate(ctx context.Context, req *GenerateRequest) (io.ReadCloser, error)
 
+	// Embed returns vector embeddings of the request inputs; providers
+	// without an embeddings API return ErrEmbeddingsUnsupported
+	Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error)
+
+	// Transcribe returns the text spoken in the request's audio; providers
+	// without a speech-to-text API return ErrTranscriptionUnsupported
+	Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error)
+
 	// GetCapabilities returns the capabilities of this provider
 	GetCapabilities() ProviderCapabilities
 
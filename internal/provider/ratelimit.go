@@ -0,0 +1,341 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// RateLimitState is the most recent upstream quota reported in response
+// headers. Counts are -1 when the upstream did not report them.
+type RateLimitState struct {
+	LimitRequests     int           `json:"limit_requests"`
+	RemainingRequests int           `json:"remaining_requests"`
+	ResetRequests     time.Duration `json:"reset_requests"`
+	LimitTokens       int           `json:"limit_tokens"`
+	RemainingTokens   int           `json:"remaining_tokens"`
+	ResetTokens       time.Duration `json:"reset_tokens"`
+	RetryAfter        time.Duration `json:"retry_after,omitempty"`
+	// MaxConcurrency is the concurrent request limit advertised by
+	// self-hosted backends
+	MaxConcurrency int       `json:"max_concurrency"`
+	ObservedAt     time.Time `json:"observed_at"`
+}
+
+// rateLimitHeaders lists the header names used by each upstream family for
+// the same value, in order of preference
+var rateLimitHeaders = struct {
+	limitRequests, remainingRequests, resetRequests []string
+	limitTokens, remainingTokens, resetTokens       []string
+	maxConcurrency                                  []string
+}{
+	limitRequests:     []string{"x-ratelimit-limit-requests", "anthropic-ratelimit-requests-limit"},
+	remainingRequests: []string{"x-ratelimit-remaining-requests", "anthropic-ratelimit-requests-remaining"},
+	resetRequests:     []string{"x-ratelimit-reset-requests", "anthropic-ratelimit-requests-reset"},
+	limitTokens:       []string{"x-ratelimit-limit-tokens", "x-ratelimit-limit-tokens-minute", "ratelimitbysize-limit", "anthropic-ratelimit-tokens-limit"},
+	remainingTokens:   []string{"x-ratelimit-remaining-tokens", "x-ratelimit-remaining-tokens-minute", "ratelimitbysize-remaining", "anthropic-ratelimit-tokens-remaining"},
+	resetTokens:       []string{"x-ratelimit-reset-tokens", "ratelimitbysize-reset", "anthropic-ratelimit-tokens-reset"},
+	maxConcurrency:    []string{"x-max-concurrency", "x-concurrency-limit"},
+}
+
+// ParseRateLimitHeaders extracts quota information from OpenAI-style
+// x-ratelimit-* headers, the Mistral and Anthropic variants and the
+// X-Max-Concurrency header of self-hosted backends. It reports false when
+// the response carries no such information.
+func ParseRateLimitHeaders(h http.Header, now time.Time) (RateLimitState, bool) {
+	s := RateLimitState{
+		LimitRequests:     headerInt(h, rateLimitHeaders.limitRequests),
+		RemainingRequests: headerInt(h, rateLimitHeaders.remainingRequests),
+		ResetRequests:     headerReset(h, rateLimitHeaders.resetRequests, now),
+		LimitTokens:       headerInt(h, rateLimitHeaders.limitTokens),
+		RemainingTokens:   headerInt(h, rateLimitHeaders.remainingTokens),
+		ResetTokens:       headerReset(h, rateLimitHeaders.resetTokens, now),
+		RetryAfter:        headerReset(h, []string{"retry-after"}, now),
+		MaxConcurrency:    headerInt(h, rateLimitHeaders.maxConcurrency),
+		ObservedAt:        now,
+	}
+	ok := s.RemainingRequests >= 0 || s.RemainingTokens >= 0 || s.RetryAfter > 0 || s.MaxConcurrency > 0
+	return s, ok
+}
+
+func headerInt(h http.Header, names []string) int {
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return n
+			}
+		}
+	}
+	return -1
+}
+
+// headerReset parses reset values, which upstreams send as Go-style
+// durations ("6m0s", "20ms"), plain seconds or RFC 3339 timestamps
+func headerReset(h http.Header, names []string, now time.Time) time.Duration {
+	for _, name := range names {
+		v := strings.TrimSpace(h.Get(name))
+		if v == "" {
+			continue
+		}
+		if secs, err := strconv.ParseFloat(v, 64); err == nil {
+			return time.Duration(secs * float64(time.Second))
+		}
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			if d := t.Sub(now); d > 0 {
+				return d
+			}
+			return 0
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			if d := t.Sub(now); d > 0 {
+				return d
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// PacerConfig tunes adaptive pacing
+type PacerConfig struct {
+	// Disabled turns pacing off; quota is still tracked for metrics
+	Disabled bool
+	// Threshold is the fraction of remaining quota below which requests are
+	// spread evenly over the time left until the upstream window resets
+	Threshold float64
+	// MaxDelay caps the delay added to a single request
+	MaxDelay time.Duration
+}
+
+// DefaultPacerConfig is used until a provider's pacer is configured
+var DefaultPacerConfig = PacerConfig{Threshold: 0.1, MaxDelay: 10 * time.Second}
+
+// Pacer slows requests to an upstream as its reported quota runs low, so the
+// gateway backs off before the upstream starts returning hard 429s
+type Pacer struct {
+	mu    sync.Mutex
+	cfg   PacerConfig
+	state RateLimitState
+	seen  bool
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+
+	// transport replaces http.DefaultTransport for paced requests when set
+	transport http.RoundTripper
+}
+
+// NewPacer creates a pacer with DefaultPacerConfig
+func NewPacer() *Pacer {
+	return &Pacer{cfg: DefaultPacerConfig, now: time.Now, sleep: sleepContext}
+}
+
+// SetTransport sends paced requests through rt, e.g. a transport with
+// custom TLS verification
+func (p *Pacer) SetTransport(rt http.RoundTripper) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.transport = rt
+}
+
+// Configure replaces the pacing settings
+func (p *Pacer) Configure(cfg PacerConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cfg = cfg
+}
+
+// Observe records the quota reported by the latest upstream response
+func (p *Pacer) Observe(s RateLimitState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state = s
+	p.seen = true
+}
+
+// State returns the last observed quota and whether any has been observed
+func (p *Pacer) State() (RateLimitState, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state, p.seen
+}
+
+// Delay returns how long the next request should wait
+func (p *Pacer) Delay() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.delayLocked()
+}
+
+func (p *Pacer) delayLocked() time.Duration {
+	if p.cfg.Disabled || !p.seen {
+		return 0
+	}
+	now := p.now()
+	s := p.state
+
+	var d time.Duration
+	if s.RetryAfter > 0 {
+		d = s.ObservedAt.Add(s.RetryAfter).Sub(now)
+	}
+	if rd := paceDelay(s.LimitRequests, s.RemainingRequests, s.ObservedAt.Add(s.ResetRequests).Sub(now), p.cfg.Threshold); rd > d {
+		d = rd
+	}
+	if td := paceDelay(s.LimitTokens, s.RemainingTokens, s.ObservedAt.Add(s.ResetTokens).Sub(now), p.cfg.Threshold); td > d {
+		d = td
+	}
+
+	if d < 0 {
+		return 0
+	}
+	if p.cfg.MaxDelay > 0 && d > p.cfg.MaxDelay {
+		return p.cfg.MaxDelay
+	}
+	return d
+}
+
+// paceDelay spreads the remaining quota over the time left in the window
+// once it drops below threshold, and waits out the window when exhausted
+func paceDelay(limit, remaining int, untilReset time.Duration, threshold float64) time.Duration {
+	if limit <= 0 || remaining < 0 || untilReset <= 0 {
+		return 0
+	}
+	if remaining == 0 {
+		return untilReset
+	}
+	if float64(remaining) >= threshold*float64(limit) {
+		return 0
+	}
+	return untilReset / time.Duration(remaining)
+}
+
+// Wait blocks until the next request may be sent. Each call reserves one
+// request from the observed quota so concurrent callers do not all act on
+// the same stale remaining count.
+func (p *Pacer) Wait(ctx context.Context) error {
+	p.mu.Lock()
+	d := p.delayLocked()
+	if p.seen && p.state.RemainingRequests > 0 {
+		p.state.RemainingRequests--
+	}
+	p.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+	return p.sleep(ctx, d)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// RateLimited is implemented by providers that track upstream quota
+type RateLimited interface {
+	Pacer() *Pacer
+}
+
+// pacedTransport applies a pacer to every upstream HTTP request and feeds it
+// the rate limit headers of each response
+type pacedTransport struct {
+	base  http.RoundTripper
+	pacer *Pacer
+}
+
+// newPacedClient returns an HTTP client whose requests go through pacer
+func newPacedClient(pacer *Pacer) *http.Client {
+	return &http.Client{Transport: &pacedTransport{base: http.DefaultTransport, pacer: pacer}}
+}
+
+func (t *pacedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.pacer.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	base := t.base
+	t.pacer.mu.Lock()
+	if t.pacer.transport != nil {
+		base = t.pacer.transport
+	}
+	t.pacer.mu.Unlock()
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := ParseRateLimitHeaders(resp.Header, t.pacer.now()); ok {
+		t.pacer.Observe(s)
+	}
+	return resp, nil
+}
+
+// RateLimits returns the last observed upstream quota keyed by provider name
+func (r *Registry) RateLimits() map[string]RateLimitState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[string]RateLimitState)
+	for name, p := range r.providers {
+		rl, ok := p.(RateLimited)
+		if !ok {
+			continue
+		}
+		if s, seen := rl.Pacer().State(); seen {
+			out[name] = s
+		}
+	}
+	return out
+}
+
+// AdvertisedConcurrency returns the max concurrency most recently advertised
+// by each provider's backend, omitting providers that advertise none
+func (r *Registry) AdvertisedConcurrency() map[string]int {
+	out := make(map[string]int)
+	for name, s := range r.RateLimits() {
+		if s.MaxConcurrency > 0 {
+			out[name] = s.MaxConcurrency
+		}
+	}
+	return out
+}
+
+// PacingDelays returns the delay the next request to each provider would incur
+func (r *Registry) PacingDelays() map[string]time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[string]time.Duration)
+	for name, p := range r.providers {
+		if rl, ok := p.(RateLimited); ok {
+			out[name] = rl.Pacer().Delay()
+		}
+	}
+	return out
+}
+
+// pacerConfig applies the pacing section of the config over the defaults
+func pacerConfig(cfg *config.Config) PacerConfig {
+	pc := DefaultPacerConfig
+	pc.Disabled = cfg.Pacing.Disabled
+	if cfg.Pacing.Threshold > 0 {
+		pc.Threshold = cfg.Pacing.Threshold
+	}
+	if cfg.Pacing.MaxDelay > 0 {
+		pc.MaxDelay = cfg.Pacing.MaxDelay
+	}
+	return pc
+}
@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	h := http.Header{}
+	h.Set("x-ratelimit-limit-requests", "500")
+	h.Set("x-ratelimit-remaining-requests", "12")
+	h.Set("x-ratelimit-reset-requests", "6m0s")
+	h.Set("x-ratelimit-limit-tokens", "30000")
+	h.Set("x-ratelimit-remaining-tokens", "29000")
+	h.Set("x-ratelimit-reset-tokens", "20ms")
+
+	s, ok := ParseRateLimitHeaders(h, now)
+	if !ok {
+		t.Fatal("Expected rate limit headers to be detected")
+	}
+	if s.LimitRequests != 500 || s.RemainingRequests != 12 || s.ResetRequests != 6*time.Minute {
+		t.Errorf("Unexpected request quota: %+v", s)
+	}
+	if s.RemainingTokens != 29000 || s.ResetTokens != 20*time.Millisecond {
+		t.Errorf("Unexpected token quota: %+v", s)
+	}
+
+	// Anthropic reports resets as timestamps
+	h = http.Header{}
+	h.Set("anthropic-ratelimit-requests-limit", "50")
+	h.Set("anthropic-ratelimit-requests-remaining", "0")
+	h.Set("anthropic-ratelimit-requests-reset", now.Add(30*time.Second).Format(time.RFC3339))
+	s, _ = ParseRateLimitHeaders(h, now)
+	if s.RemainingRequests != 0 || s.ResetRequests != 30*time.Second || s.RemainingTokens != -1 {
+		t.Errorf("Unexpected anthropic quota: %+v", s)
+	}
+
+	h = http.Header{}
+	h.Set("Retry-After", "2")
+	if s, ok = ParseRateLimitHeaders(h, now); !ok || s.RetryAfter != 2*time.Second {
+		t.Errorf("Expected retry-after, got %+v", s)
+	}
+
+	h = http.Header{}
+	h.Set("X-Max-Concurrency", "16")
+	if s, ok = ParseRateLimitHeaders(h, now); !ok || s.MaxConcurrency != 16 || s.RemainingRequests != -1 {
+		t.Errorf("Expected advertised concurrency, got %+v", s)
+	}
+
+	if _, ok := ParseRateLimitHeaders(http.Header{}, now); ok {
+		t.Error("Expected no rate limit info without headers")
+	}
+}
+
+func TestPacerDelay(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := NewPacer()
+	p.now = func() time.Time { return now }
+	p.Configure(PacerConfig{Threshold: 0.1, MaxDelay: time.Minute})
+
+	if d := p.Delay(); d != 0 {
+		t.Errorf("Expected no delay before any observation, got %v", d)
+	}
+
+	// Plenty of quota left: no pacing
+	p.Observe(RateLimitState{LimitRequests: 100, RemainingRequests: 50, ResetRequests: time.Minute, LimitTokens: -1, RemainingTokens: -1, ObservedAt: now})
+	if d := p.Delay(); d != 0 {
+		t.Errorf("Expected no delay above threshold, got %v", d)
+	}
+
+	// Below 10%: spread the remaining 5 requests over the minute
+	p.Observe(RateLimitState{LimitRequests: 100, RemainingRequests: 5, ResetRequests: time.Minute, LimitTokens: -1, RemainingTokens: -1, ObservedAt: now})
+	if d := p.Delay(); d != 12*time.Second {
+		t.Errorf("Expected paced delay, got %v", d)
+	}
+
+	// Exhausted: wait out the window, capped by MaxDelay
+	p.Observe(RateLimitState{LimitRequests: 100, RemainingRequests: 0, ResetRequests: 3 * time.Second, LimitTokens: -1, RemainingTokens: -1, ObservedAt: now})
+	if d := p.Delay(); d != 3*time.Second {
+		t.Errorf("Expected delay until reset, got %v", d)
+	}
+	p.Configure(PacerConfig{Threshold: 0.1, MaxDelay: time.Second})
+	if d := p.Delay(); d != time.Second {
+		t.Errorf("Expected delay capped at MaxDelay, got %v", d)
+	}
+
+	// The window has reset
+	now = now.Add(5 * time.Second)
+	if d := p.Delay(); d != 0 {
+		t.Errorf("Expected no delay after reset, got %v", d)
+	}
+
+	p.Observe(RateLimitState{LimitRequests: 100, RemainingRequests: 0, ResetRequests: time.Minute, ObservedAt: now})
+	p.Configure(PacerConfig{Disabled: true})
+	if d := p.Delay(); d != 0 {
+		t.Errorf("Expected no delay when disabled, got %v", d)
+	}
+}
+
+func TestPacedTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ratelimit-limit-requests", "10")
+		w.Header().Set("x-ratelimit-remaining-requests", "0")
+		w.Header().Set("x-ratelimit-reset-requests", "1s")
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	pacer := NewPacer()
+	var slept time.Duration
+	pacer.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = d
+		return nil
+	}
+	client := newPacedClient(pacer)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if s, ok := pacer.State(); !ok || s.LimitRequests != 10 {
+		t.Errorf("Expected observed quota, got %+v", s)
+	}
+	if slept <= 0 || slept > time.Second {
+		t.Errorf("Expected second request to be paced, slept %v", slept)
+	}
+}
@@ -0,0 +1,35 @@
+package provider
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// RealtimeProvider is implemented by providers with a Realtime API, served
+// over a WebSocket per session
+type RealtimeProvider interface {
+	// RealtimeEndpoint returns the WebSocket URL of a session with model and
+	// the headers authenticating it
+	RealtimeEndpoint(model string) (*url.URL, http.Header, error)
+}
+
+// RealtimeEndpoint returns the Realtime API session URL under the base URL
+func (o *OpenAIProvider) RealtimeEndpoint(model string) (*url.URL, http.Header, error) {
+	base, header, err := o.PassthroughTarget()
+	if err != nil {
+		return nil, nil, fmt.Errorf("realtime url: %w", err)
+	}
+	u := base.JoinPath("realtime")
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	default:
+		return nil, nil, fmt.Errorf("realtime url: unsupported scheme %q", u.Scheme)
+	}
+	u.RawQuery = url.Values{"model": {model}}.Encode()
+	header.Set("OpenAI-Beta", "realtime=v1")
+	return u, header, nil
+}
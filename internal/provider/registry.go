@@ -2,18 +2,41 @@ package provider
 
 import (
 	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net/http"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/luguanyu1234/letllm-go/internal/cache"
 	"github.com/luguanyu1234/letllm-go/internal/config"
 )
 
 // RouteRequest represents a request for provider routing
 type RouteRequest struct {
-	Model    string            `json:"model"`
-	ClientID string            `json:"client_id,omitempty"`
-	Endpoint string            `json:"endpoint,omitempty"`
-	Headers  map[string]string `json:"headers,omitempty"`
+	Model    string `json:"model"`
+	ClientID string `json:"client_id,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	// Request headers by canonical name, matched against route headers
+	Headers map[string]string `json:"headers,omitempty"`
+	// Attributes from request enrichment, matched against route requirements
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Conversation the request belongs to, for the sticky strategy and
+	// canary bucketing
+	Conversation string `json:"conversation,omitempty"`
+
+	// Provider, when set, serves the request instead of the matched route's
+	Provider string `json:"provider,omitempty"`
+
+	// Arm is set by Route on routes with a canary: ArmCanary or ArmBaseline
+	Arm string `json:"-"`
+	// UpstreamModel is set by Route on routes that rename the model upstream
+	UpstreamModel string `json:"-"`
+	// Needs, when set, skip matching routes whose provider's model cannot
+	// serve the request, e.g. a small local context, for later ones
+	Needs *ModelNeeds `json:"-"`
 }
 
 // RouterInterface defines the interface for provider routing
@@ -30,13 +53,48 @@ type Registry struct {
 	cfg       *config.Config
 	providers map[string]Provider
 	mu        sync.RWMutex
+
+	// now is the clock used for schedule checks; overridable in tests
+	now func() time.Time
+
+	healthMu sync.Mutex
+	health   map[string]healthEntry
+
+	// cache keeps upstream model lists
+	cache cache.Cache
+
+	failover *failoverSet
+
+	// models maps models declared by openai_compatible, remote_letllm and
+	// mock instances, and the llama.cpp model, to the provider serving them
+	models map[string]string
+
+	// specs describe the providers that can be changed at runtime, and
+	// disabled names those routing skips
+	specs    map[string]ProviderSpec
+	disabled map[string]bool
+
+	// retired instances were replaced by a reload; they are closed
+	// retireDelay later, or with the registry, as streams routed to them
+	// may still be running. retireAfter overrides the delay in tests.
+	retired     []Provider
+	retireAfter time.Duration
 }
 
+// retireDelay is how long instances replaced by a reload stay open for the
+// requests and streams already routed to them
+const retireDelay = 15 * time.Minute
+
 // NewRegistry creates a new provider registry
 func NewRegistry(cfg *config.Config) (*Registry, error) {
 	r := &Registry{
 		cfg:       cfg,
 		providers: make(map[string]Provider),
+		now:       time.Now,
+		health:    make(map[string]healthEntry),
+		cache:     cache.NewMemory(discoveryCacheEntries, discoveryCacheBytes),
+		models:    make(map[string]string),
+		disabled:  make(map[string]bool),
 	}
 
 	// Initialize providers if API keys are present
@@ -56,26 +114,435 @@ func NewRegistry(cfg *config.Config) (*Registry, error) {
 		r.providers["gemini"] = p
 	}
 
+	if cfg.Mistral.APIKey != "" {
+		p, err := NewMistralProvider(cfg.Mistral.APIKey, cfg.Mistral.BaseURL, cfg.Mistral.DefaultModel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Mistral provider: %w", err)
+		}
+		r.providers["mistral"] = p
+	}
+
+	if cfg.Cohere.APIKey != "" {
+		p, err := NewCohereProvider(cfg.Cohere.APIKey, cfg.Cohere.BaseURL, cfg.Cohere.DefaultModel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Cohere provider: %w", err)
+		}
+		r.providers["cohere"] = p
+	}
+
+	if cfg.DeepSeek.APIKey != "" {
+		p, err := NewDeepSeekProvider(cfg.DeepSeek.APIKey, cfg.DeepSeek.BaseURL, cfg.DeepSeek.DefaultModel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create DeepSeek provider: %w", err)
+		}
+		r.providers["deepseek"] = p
+	}
+
+	if cfg.OpenRouter.APIKey != "" {
+		p, err := NewOpenRouterProvider(cfg.OpenRouter.APIKey, cfg.OpenRouter.BaseURL, cfg.OpenRouter.DefaultModel, cfg.OpenRouter.Referer, cfg.OpenRouter.Title)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OpenRouter provider: %w", err)
+		}
+		r.providers["openrouter"] = p
+	}
+
+	if cfg.Perplexity.APIKey != "" {
+		p, err := NewPerplexityProvider(cfg.Perplexity.APIKey, cfg.Perplexity.BaseURL, cfg.Perplexity.DefaultModel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Perplexity provider: %w", err)
+		}
+		r.providers["perplexity"] = p
+	}
+
+	if cfg.Zhipu.APIKey != "" {
+		p, err := NewZhipuProvider(cfg.Zhipu.APIKey, cfg.Zhipu.BaseURL, cfg.Zhipu.DefaultModel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Zhipu provider: %w", err)
+		}
+		r.providers["zhipu"] = p
+	}
+
+	if lc := cfg.LlamaCpp; lc.ModelPath != "" {
+		opts := LlamaCppOptions{
+			ModelPath:    lc.ModelPath,
+			ModelName:    lc.ModelName,
+			ContextSize:  lc.ContextSize,
+			Threads:      lc.Threads,
+			GPULayers:    lc.GPULayers,
+			ChatTemplate: lc.ChatTemplate,
+		}
+		p, err := NewLlamaCppProvider(opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create llama.cpp provider: %w", err)
+		}
+		r.providers["llamacpp"] = p
+		r.models[LlamaCppModelName(opts)] = "llamacpp"
+	}
+
+	for _, oc := range cfg.OpenAICompatible {
+		p, err := NewOpenAICompatibleProvider(oc.Name, oc.APIKey, oc.BaseURL, oc.DefaultModel, oc.Models)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OpenAI-compatible provider %s: %w", oc.Name, err)
+		}
+		r.providers[oc.Name] = p
+		for _, m := range oc.Models {
+			if _, taken := r.models[m]; !taken {
+				r.models[m] = oc.Name
+			}
+		}
+	}
+
+	for _, rc := range cfg.RemoteLetLLM {
+		p, err := NewRemoteLetLLMProvider(rc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create remote letllm provider %s: %w", rc.Name, err)
+		}
+		r.providers[rc.Name] = p
+		for _, m := range rc.Models {
+			if _, taken := r.models[m]; !taken {
+				r.models[m] = rc.Name
+			}
+		}
+	}
+
+	for _, mc := range cfg.Mock {
+		p, err := NewMockProvider(mc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create mock provider %s: %w", mc.Name, err)
+		}
+		r.providers[mc.Name] = p
+		for _, m := range mc.Models {
+			if _, taken := r.models[m]; !taken {
+				r.models[m] = mc.Name
+			}
+		}
+	}
+
+	failover, err := newFailoverSet(cfg.Failover, r.providers)
+	if err != nil {
+		return nil, err
+	}
+	r.failover = failover
+
+	for name, p := range r.providers {
+		if r.providers[name], err = r.setUp(name, p); err != nil {
+			return nil, err
+		}
+	}
+	r.specs = configSpecs(cfg)
+
 	return r, nil
 }
 
+// setUp applies the pacing, TLS, timeout, key pool, retry and VCR settings
+// to a provider, returning the instance to register
+func (r *Registry) setUp(name string, p Provider) (Provider, error) {
+	cfg := r.config()
+	if rl, ok := p.(RateLimited); ok {
+		rl.Pacer().Configure(pacerConfig(cfg))
+	}
+	var transport http.RoundTripper
+	if pt, ok := cfg.ProviderTLS[name]; ok {
+		if _, ok := p.(RateLimited); !ok {
+			return nil, fmt.Errorf("provider_tls: %s does not support custom TLS settings", name)
+		}
+		tr, err := newTLSTransport(pt)
+		if err != nil {
+			return nil, fmt.Errorf("provider_tls %s: %w", name, err)
+		}
+		transport = tr
+	}
+	timeouts, hasTimeouts := cfg.ProviderTimeouts[name]
+	if hasTimeouts {
+		if _, ok := p.(RateLimited); !ok {
+			return nil, fmt.Errorf("provider_timeouts: %s does not support timeouts", name)
+		}
+		if timeouts.Connect > 0 {
+			transport = withConnectTimeout(transport, timeouts.Connect)
+		}
+	}
+	if kp, ok := cfg.ProviderKeys[name]; ok {
+		// Zhipu signs requests and remote gateways may forward the
+		// caller's token above the pacer, where a pool cannot reach
+		switch p.(type) {
+		case *ZhipuProvider, *RemoteLetLLMProvider:
+			return nil, fmt.Errorf("provider_keys: %s does not support key pools", name)
+		}
+		if _, ok := p.(RateLimited); !ok {
+			return nil, fmt.Errorf("provider_keys: %s does not support key pools", name)
+		}
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		transport = newKeyPool(kp, transport)
+	}
+	if timeouts.FirstByte > 0 {
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		transport = &timeoutTransport{base: transport, firstByte: timeouts.FirstByte}
+	}
+	if rp, ok := cfg.ProviderRetries[name]; ok {
+		if _, ok := p.(RateLimited); !ok {
+			return nil, fmt.Errorf("provider_retries: %s does not support retries", name)
+		}
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		transport = newRetryTransport(rp, transport)
+	}
+	if timeouts.Total > 0 {
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		transport = &timeoutTransport{base: transport, total: timeouts.Total}
+	}
+	if transport != nil {
+		p.(RateLimited).Pacer().SetTransport(transport)
+	}
+	if cfg.VCR.Mode != "" {
+		p = NewVCRProvider(name, p, cfg.VCR)
+	}
+	return p, nil
+}
+
+// config returns the configuration the registry currently applies
+func (r *Registry) config() *config.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cfg
+}
+
 // Route routes a request to the appropriate provider based on routing rules
 func (r *Registry) Route(req *RouteRequest) (Provider, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	// Requests routed again, e.g. on failover, must not keep the model of
+	// the route they matched before
+	req.UpstreamModel = ""
+	if req.Provider != "" {
+		if provider, exists := r.lookup(req.Provider); exists {
+			return provider, nil
+		}
+		return nil, fmt.Errorf("provider %s not configured", req.Provider)
+	}
+
 	// First, try explicit routing rules from config
-	for _, rt := range r.cfg.Routes {
-		if strings.HasPrefix(req.Model, rt.Prefix) {
-			if provider, exists := r.providers[rt.Provider]; exists {
-				return provider, nil
+	if rt := r.matchRoute(req); rt != nil {
+		if rt.Schedule != nil {
+			if ok, reason := rt.Schedule.Check(r.now()); !ok {
+				return nil, &PolicyError{Code: PolicyCodeOutsideSchedule, Route: rt.Label(), Reason: reason}
+			}
+		}
+		name := rt.Provider
+		req.UpstreamModel = rt.Model
+		if rt.Canary != nil {
+			req.Arm = ArmBaseline
+			if _, ok := r.lookup(rt.Canary.Provider); ok && r.inCanary(rt, req) {
+				name, req.Arm = rt.Canary.Provider, ArmCanary
 			}
-			return nil, fmt.Errorf("provider %s not configured", rt.Provider)
 		}
+		if provider, exists := r.lookup(name); exists {
+			return provider, nil
+		}
+		return nil, fmt.Errorf("provider %s not configured", name)
 	}
 
 	// Fallback: try provider default by model name hint
-	return r.GetProviderForModel(req.Model)
+	return r.providerForModel(req.Model)
+}
+
+// ResolveModelAlias returns the model a configured model alias stands for,
+// and whether model was an alias; aliases do not chain
+func (r *Registry) ResolveModelAlias(model string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	target, ok := r.cfg.ModelAliases[model]
+	if !ok {
+		return model, false
+	}
+	return target, true
+}
+
+// Arms of a route with a canary
+const (
+	ArmBaseline = "baseline"
+	ArmCanary   = "canary"
+)
+
+// inCanary reports whether req falls in the canary share of rt; callers
+// must hold r.mu
+func (r *Registry) inCanary(rt *config.Route, req *RouteRequest) bool {
+	bucket := rand.Float64() * 100
+	if req.Conversation != "" {
+		h := fnv.New64a()
+		h.Write([]byte(req.Conversation))
+		h.Write([]byte{0})
+		h.Write([]byte(rt.Label()))
+		bucket = float64(h.Sum64()%10000) / 100
+	}
+	return bucket < rt.Canary.Percent
+}
+
+// Mirror returns the provider and model the route req matches mirrors it
+// to, when the route has a mirror and req is in its sampled share
+func (r *Registry) Mirror(req *RouteRequest) (Provider, string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rt := r.matchRoute(req)
+	if rt == nil || rt.Mirror == nil || rand.Float64()*100 >= rt.Mirror.Percent {
+		return nil, "", false
+	}
+	p, ok := r.lookup(rt.Mirror.Provider)
+	if !ok {
+		return nil, "", false
+	}
+	model := rt.Mirror.Model
+	if model == "" {
+		model = req.Model
+	}
+	return p, model, true
+}
+
+// ContentFilterFallback returns the provider and model requests on the
+// route req matches are retried on when refused by a content filter
+func (r *Registry) ContentFilterFallback(req *RouteRequest) (Provider, string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rt := r.matchRoute(req)
+	if rt == nil || rt.ContentFilterFallback == nil {
+		return nil, "", false
+	}
+	p, ok := r.lookup(rt.ContentFilterFallback.Provider)
+	if !ok {
+		return nil, "", false
+	}
+	model := rt.ContentFilterFallback.Model
+	if model == "" {
+		model = req.Model
+	}
+	return p, model, true
+}
+
+// Hedge returns the provider and model streams on the route req matches
+// are hedged on, and how long the routed provider has to start streaming
+func (r *Registry) Hedge(req *RouteRequest) (Provider, string, time.Duration, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rt := r.matchRoute(req)
+	if rt == nil || rt.Hedge == nil {
+		return nil, "", 0, false
+	}
+	p, ok := r.lookup(rt.Hedge.Provider)
+	if !ok {
+		return nil, "", 0, false
+	}
+	model := rt.Hedge.Model
+	if model == "" {
+		model = req.Model
+	}
+	return p, model, rt.Hedge.After, true
+}
+
+// RouteCache returns the response caching policy of the configured route a
+// request matches; nil when the route does not allow caching
+func (r *Registry) RouteCache(req *RouteRequest) *config.RouteCache {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if rt := r.matchRoute(req); rt != nil {
+		return rt.Cache
+	}
+	return nil
+}
+
+// matchRoute returns the first configured route matching req whose model
+// meets req.Needs, or the first matching route when none does. With the
+// cheapest strategy, the cheapest route meeting req.Needs is returned
+// instead, and with the sticky strategy the one req.Conversation hashes to.
+// Callers must hold r.mu.
+func (r *Registry) matchRoute(req *RouteRequest) *config.Route {
+	cheapest := r.cfg.RoutingStrategy == config.RoutingCheapest
+	sticky := r.cfg.RoutingStrategy == config.RoutingSticky && req.Conversation != ""
+	var first, best *config.Route
+	bestCost, bestWeight := 0.0, uint64(0)
+	for i := range r.cfg.Routes {
+		rt := &r.cfg.Routes[i]
+		if !rt.MatchModel(req.Model) || !rt.MatchHeaders(req.Headers) || !rt.Attributes.Match(req.Attributes) {
+			continue
+		}
+		if req.Needs == nil {
+			return rt
+		}
+		if first == nil {
+			first = rt
+		}
+		p, ok := r.lookup(rt.Provider)
+		if !ok || !r.ModelCapabilities(p, req.Model).Satisfies(req.Needs) {
+			continue
+		}
+		if sticky {
+			// Rendezvous hashing, so conversations only move when their
+			// route goes away
+			if w := stickyWeight(req.Conversation, rt); best == nil || w > bestWeight {
+				best, bestWeight = rt, w
+			}
+			continue
+		}
+		if !cheapest {
+			return rt
+		}
+		// Unpriced routes only win when no route is priced
+		cost, priced := r.estimateCost(rt, req)
+		if !priced {
+			cost = math.Inf(1)
+		}
+		if best == nil || cost < bestCost {
+			best, bestCost = rt, cost
+		}
+	}
+	if best != nil {
+		return best
+	}
+	return first
+}
+
+// stickyWeight ranks rt for a conversation under the sticky strategy
+func stickyWeight(conversation string, rt *config.Route) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(conversation))
+	h.Write([]byte{0})
+	h.Write([]byte(rt.Provider))
+	return h.Sum64()
+}
+
+// estimateCost returns the USD cost of req on rt, assuming the whole
+// requested completion is generated, and whether the route's model is
+// priced; callers must hold r.mu
+func (r *Registry) estimateCost(rt *config.Route, req *RouteRequest) (float64, bool) {
+	price, ok := r.cfg.Pricing[req.Model]
+	if rt.Price != nil {
+		price, ok = *rt.Price, true
+	}
+	if !ok {
+		return 0, false
+	}
+	usage := Usage{PromptTokens: req.Needs.Tokens - req.Needs.CompletionTokens, CompletionTokens: req.Needs.CompletionTokens}
+	return usage.Cost(price), true
+}
+
+// Price returns the price of model in the pricing catalog
+func (r *Registry) Price(model string) (config.ModelPrice, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	price, ok := r.cfg.Pricing[model]
+	return price, ok
 }
 
 // GetProviderForModel returns a provider for the given model using fallback logic
@@ -83,6 +550,48 @@ func (r *Registry) GetProviderForModel(model string) (Provider, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	return r.providerForModel(model)
+}
+
+// providerForModel implements GetProviderForModel; callers must hold r.mu
+func (r *Registry) providerForModel(model string) (Provider, error) {
+	// Models declared by an openai_compatible instance take precedence over
+	// name heuristics, e.g. a local "gpt-oss-20b"
+	if name, ok := r.models[model]; ok {
+		if provider, exists := r.lookup(name); exists {
+			return provider, nil
+		}
+	}
+
+	// Any model through OpenRouter, e.g. "openrouter/openai/gpt-4o"; checked
+	// before name hints, which would match the upstream model ID
+	if strings.HasPrefix(model, "openrouter/") {
+		if provider, exists := r.lookup("openrouter"); exists {
+			return provider, nil
+		}
+	}
+
+	// Embedding models; Gemini's share OpenAI's "text-embedding-" prefix
+	if model == "embedding-001" ||
+		strings.HasPrefix(model, "text-embedding-00") ||
+		strings.HasPrefix(model, "text-multilingual-embedding-") {
+		if provider, exists := r.lookup("gemini"); exists {
+			return provider, nil
+		}
+	}
+	if strings.HasPrefix(model, "text-embedding-") {
+		if provider, exists := r.lookup("openai"); exists {
+			return provider, nil
+		}
+	}
+
+	// Whisper speech-to-text and TTS models
+	if strings.HasPrefix(model, "whisper-") || strings.HasPrefix(model, "tts-") {
+		if provider, exists := r.lookup("openai"); exists {
+			return provider, nil
+		}
+	}
+
 	// Try model name-based routing as fallback
 	// More flexible OpenAI routing - check for common patterns and openai-compatible models
 	if strings.HasPrefix(model, "gpt-") ||
@@ -90,7 +599,7 @@ func (r *Registry) GetProviderForModel(model string) (Provider, error) {
 		strings.Contains(model, "gpt") ||
 		strings.HasSuffix(model, "-openai") ||
 		strings.Contains(model, "openai") {
-		if provider, exists := r.providers["openai"]; exists {
+		if provider, exists := r.lookup("openai"); exists {
 			return provider, nil
 		}
 	}
@@ -99,7 +608,44 @@ func (r *Registry) GetProviderForModel(model string) (Provider, error) {
 	if strings.HasPrefix(model, "gemini-") ||
 		strings.Contains(model, "gemini") ||
 		strings.HasSuffix(model, "-gemini") {
-		if provider, exists := r.providers["gemini"]; exists {
+		if provider, exists := r.lookup("gemini"); exists {
+			return provider, nil
+		}
+	}
+
+	// Mistral platform models, including codestral
+	if strings.HasPrefix(model, "mistral-") ||
+		strings.HasPrefix(model, "open-mistral-") ||
+		strings.HasPrefix(model, "codestral-") {
+		if provider, exists := r.lookup("mistral"); exists {
+			return provider, nil
+		}
+	}
+
+	// Cohere Command models
+	if strings.HasPrefix(model, "command-") || model == "command" {
+		if provider, exists := r.lookup("cohere"); exists {
+			return provider, nil
+		}
+	}
+
+	// DeepSeek chat and reasoner models
+	if strings.HasPrefix(model, "deepseek-") {
+		if provider, exists := r.lookup("deepseek"); exists {
+			return provider, nil
+		}
+	}
+
+	// Perplexity Sonar models
+	if model == "sonar" || strings.HasPrefix(model, "sonar-") {
+		if provider, exists := r.lookup("perplexity"); exists {
+			return provider, nil
+		}
+	}
+
+	// Zhipu GLM models
+	if strings.HasPrefix(model, "glm-") {
+		if provider, exists := r.lookup("zhipu"); exists {
 			return provider, nil
 		}
 	}
@@ -126,8 +672,12 @@ func (r *Registry) ListProviders() []ProviderInfo {
 	defer r.mu.RUnlock()
 
 	infos := make([]ProviderInfo, 0, len(r.providers))
-	for _, provider := range r.providers {
-		infos = append(infos, provider.GetInfo())
+	for name, provider := range r.providers {
+		info := provider.GetInfo()
+		if r.disabled[name] {
+			info.Status = "disabled"
+		}
+		infos = append(infos, info)
 	}
 
 	return infos
@@ -142,7 +692,7 @@ func (r *Registry) GetProvider(name string) (Provider, bool) {
 	return provider, exists
 }
 
-// Close closes all registered providers
+// Close closes all registered providers and those replaced by reloads
 func (r *Registry) Close() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -153,6 +703,12 @@ func (r *Registry) Close() error {
 			lastErr = fmt.Errorf("failed to close provider %s: %w", name, err)
 		}
 	}
+	for _, provider := range r.retired {
+		if err := provider.Close(); err != nil {
+			lastErr = fmt.Errorf("failed to close replaced provider %s: %w", provider.GetInfo().Name, err)
+		}
+	}
+	r.retired = nil
 
 	return lastErr
 }
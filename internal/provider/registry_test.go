@@ -1,7 +1,10 @@
 package provider
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/luguanyu1234/letllm-go/internal/config"
 )
@@ -227,3 +230,421 @@ func TestBackwardCompatibility(t *testing.T) {
 		t.Error("Provider should not be nil")
 	}
 }
+
+func TestRegistryRouteSchedule(t *testing.T) {
+	cfg := &config.Config{
+		Routes: []config.Route{
+			{
+				Prefix:   "gpt-4.1",
+				Provider: "openai",
+				Schedule: &config.Schedule{
+					Allow: []config.TimeWindow{
+						{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "18:00"},
+					},
+					Blackouts: []config.Blackout{
+						{
+							From: time.Date(2025, 12, 24, 0, 0, 0, 0, time.UTC),
+							To:   time.Date(2025, 12, 27, 0, 0, 0, 0, time.UTC),
+						},
+					},
+				},
+			},
+		},
+		OpenAI: struct {
+			APIKey       string `yaml:"api_key"`
+			BaseURL      string `yaml:"base_url"`
+			DefaultModel string `yaml:"default_model"`
+		}{
+			APIKey: "test-openai-key",
+		},
+	}
+
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		allowed bool
+	}{
+		{"weekday business hours", time.Date(2025, 6, 4, 10, 0, 0, 0, time.UTC), true},
+		{"weekday evening", time.Date(2025, 6, 4, 19, 0, 0, 0, time.UTC), false},
+		{"weekend", time.Date(2025, 6, 7, 10, 0, 0, 0, time.UTC), false},
+		{"blackout", time.Date(2025, 12, 24, 10, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry.now = func() time.Time { return tt.now }
+
+			_, err := registry.Route(&RouteRequest{Model: "gpt-4.1"})
+			if tt.allowed && err != nil {
+				t.Errorf("Expected route to be allowed, got %v", err)
+			}
+			if !tt.allowed {
+				var policyErr *PolicyError
+				if !errors.As(err, &policyErr) {
+					t.Fatalf("Expected PolicyError, got %v", err)
+				}
+				if policyErr.Code != PolicyCodeOutsideSchedule {
+					t.Errorf("Expected code %s, got %s", PolicyCodeOutsideSchedule, policyErr.Code)
+				}
+			}
+		})
+	}
+
+	// Routes without a schedule are unaffected
+	registry.now = func() time.Time { return time.Date(2025, 6, 7, 23, 0, 0, 0, time.UTC) }
+	if _, err := registry.Route(&RouteRequest{Model: "gpt-4o"}); err != nil {
+		t.Errorf("Unscheduled model should route, got %v", err)
+	}
+}
+
+func TestRegistryMistralRouting(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Mistral.APIKey = "test-mistral-key"
+
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	for _, model := range []string{"mistral-large-latest", "codestral-latest"} {
+		p, err := registry.GetProviderForModel(model)
+		if err != nil {
+			t.Fatalf("Expected %s to route, got %v", model, err)
+		}
+		if p.GetInfo().Name != "mistral" {
+			t.Errorf("Expected mistral provider for %s, got %s", model, p.GetInfo().Name)
+		}
+	}
+}
+
+func TestRegistryRouteAttributes(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "test-openai-key"
+	cfg.Mistral.APIKey = "test-mistral-key"
+	cfg.Routes = []config.Route{
+		{Prefix: "chat-", Provider: "openai", Attributes: config.Attributes{"tier": "enterprise"}},
+		{Prefix: "chat-", Provider: "mistral"},
+	}
+
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	for tier, want := range map[string]string{"enterprise": "openai", "free": "mistral", "": "mistral"} {
+		p, err := registry.Route(&RouteRequest{Model: "chat-default", Attributes: map[string]string{"tier": tier}})
+		if err != nil {
+			t.Fatalf("Failed to route tier %q: %v", tier, err)
+		}
+		if got := p.GetInfo().Name; got != want {
+			t.Errorf("Tier %q routed to %s, want %s", tier, got, want)
+		}
+	}
+}
+
+func TestRegistryRouteHeaders(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "test-openai-key"
+	cfg.Gemini.APIKey = "test-gemini-key"
+	cfg.Routes = []config.Route{
+		{Prefix: "chat-", Provider: "gemini", Headers: map[string]string{"x-env": "staging"}},
+		{Prefix: "chat-", Provider: "openai"},
+	}
+
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	for env, want := range map[string]string{"staging": "gemini", "prod": "openai", "": "openai"} {
+		p, err := registry.Route(&RouteRequest{Model: "chat-default", Headers: map[string]string{"X-Env": env}})
+		if err != nil {
+			t.Fatalf("Failed to route env %q: %v", env, err)
+		}
+		if got := p.GetInfo().Name; got != want {
+			t.Errorf("Env %q routed to %s, want %s", env, got, want)
+		}
+	}
+}
+
+func TestRegistryRouteUpstreamModel(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "test-openai-key"
+	cfg.Gemini.APIKey = "test-gemini-key"
+	cfg.Routes = []config.Route{{Prefix: "chat-", Provider: "openai", Model: "gpt-4o"}}
+
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	req := &RouteRequest{Model: "chat-default"}
+	if _, err := registry.Route(req); err != nil || req.UpstreamModel != "gpt-4o" {
+		t.Fatalf("Expected the route's model, got %q (%v)", req.UpstreamModel, err)
+	}
+	// Routed again to another provider, the request keeps its own model
+	req.Provider = "gemini"
+	if _, err := registry.Route(req); err != nil || req.UpstreamModel != "" {
+		t.Errorf("Expected the route's model cleared, got %q (%v)", req.UpstreamModel, err)
+	}
+}
+
+func TestRegistryRoutePatterns(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "test-openai-key"
+	cfg.Mistral.APIKey = "test-mistral-key"
+	cfg.Gemini.APIKey = "test-gemini-key"
+	cfg.Routes = []config.Route{
+		{Match: config.MatchRegex, Pattern: `.*-32k`, Provider: "mistral"},
+		{Match: config.MatchGlob, Pattern: "chat-?-*", Provider: "gemini"},
+		{Prefix: "chat-", Provider: "openai"},
+	}
+
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	for model, want := range map[string]string{
+		"chat-large-32k": "mistral",
+		"chat-32k-large": "openai", // regex patterns match the whole name
+		"chat-4-mini":    "gemini",
+		"chat-mini":      "openai",
+	} {
+		p, err := registry.Route(&RouteRequest{Model: model})
+		if err != nil {
+			t.Fatalf("Failed to route %s: %v", model, err)
+		}
+		if got := p.GetInfo().Name; got != want {
+			t.Errorf("%s routed to %s, want %s", model, got, want)
+		}
+	}
+}
+
+func TestRegistryOpenRouterRouting(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "test-openai-key"
+	cfg.OpenRouter.APIKey = "test-openrouter-key"
+
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	// The openrouter/ prefix wins over name hints such as "gpt"
+	p, err := registry.Route(&RouteRequest{Model: "openrouter/openai/gpt-4o"})
+	if err != nil {
+		t.Fatalf("Failed to route: %v", err)
+	}
+	if name := p.GetInfo().Name; name != "openrouter" {
+		t.Errorf("Expected openrouter, got %s", name)
+	}
+}
+
+func TestRegistryPerplexityRouting(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Perplexity.APIKey = "test-perplexity-key"
+
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	for _, model := range []string{"sonar", "sonar-reasoning-pro"} {
+		p, err := registry.Route(&RouteRequest{Model: model})
+		if err != nil {
+			t.Fatalf("Failed to route %s: %v", model, err)
+		}
+		if name := p.GetInfo().Name; name != "perplexity" {
+			t.Errorf("Expected perplexity for %s, got %s", model, name)
+		}
+	}
+}
+
+func TestRegistryZhipuRouting(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Zhipu.APIKey = "test-id.test-secret"
+
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	p, err := registry.Route(&RouteRequest{Model: "glm-4-flash"})
+	if err != nil {
+		t.Fatalf("Failed to route glm-4-flash: %v", err)
+	}
+	if name := p.GetInfo().Name; name != "zhipu" {
+		t.Errorf("Expected zhipu, got %s", name)
+	}
+}
+
+func TestRegistryMockRouting(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Mock = []config.MockConfig{{Name: "demo", Models: []string{"gpt-4o"}}}
+
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	p, err := registry.Route(&RouteRequest{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Failed to route gpt-4o: %v", err)
+	}
+	if name := p.GetInfo().Name; name != "demo" {
+		t.Errorf("Expected demo, got %s", name)
+	}
+}
+
+func TestRegistryCheapestRouting(t *testing.T) {
+	cfg := &config.Config{
+		Mock: []config.MockConfig{
+			{Name: "standard"},
+			{Name: "premium", Responses: []config.MockResponse{{FunctionCall: &config.MockFunctionCall{Name: "lookup", Arguments: "{}"}}}},
+			{Name: "small"},
+		},
+		Routes: []config.Route{
+			{Prefix: "local-", Provider: "standard"},
+			{Prefix: "local-", Provider: "premium", Price: &config.ModelPrice{InputPerMTok: 10, OutputPerMTok: 30}},
+			{Prefix: "local-", Provider: "small", Price: &config.ModelPrice{InputPerMTok: 0.1, OutputPerMTok: 0.2}},
+		},
+		RoutingStrategy: config.RoutingCheapest,
+		Pricing:         map[string]config.ModelPrice{"local-llama": {InputPerMTok: 5, OutputPerMTok: 15}},
+		Models:          []config.ModelSpec{{Model: "local-*", Provider: "small", ContextLength: 100}},
+	}
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		model string
+		needs *ModelNeeds
+		want  string
+	}{
+		"no needs, first":      {model: "local-llama", want: "standard"},
+		"cheapest":             {model: "local-llama", needs: &ModelNeeds{Tokens: 50}, want: "small"},
+		"beyond small context": {model: "local-llama", needs: &ModelNeeds{Tokens: 500}, want: "standard"},
+		"functions":            {model: "local-llama", needs: &ModelNeeds{Tokens: 50, Functions: true}, want: "premium"},
+		"unpriced last":        {model: "local-qwen", needs: &ModelNeeds{Tokens: 500}, want: "premium"},
+	}
+	for name, tt := range tests {
+		p, err := r.Route(&RouteRequest{Model: tt.model, Needs: tt.needs})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got := p.GetInfo().Name; got != tt.want {
+			t.Errorf("%s: expected %s, got %s", name, tt.want, got)
+		}
+	}
+}
+
+func TestRegistryStickyRouting(t *testing.T) {
+	cfg := &config.Config{
+		Mock: []config.MockConfig{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+		Routes: []config.Route{
+			{Prefix: "local-", Provider: "a"},
+			{Prefix: "local-", Provider: "b"},
+			{Prefix: "local-", Provider: "c"},
+		},
+		RoutingStrategy: config.RoutingSticky,
+	}
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := func(conversation string) string {
+		t.Helper()
+		p, err := r.Route(&RouteRequest{Model: "local-llama", Needs: &ModelNeeds{Tokens: 10}, Conversation: conversation})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.GetInfo().Name
+	}
+
+	if got := route(""); got != "a" {
+		t.Errorf("Expected requests outside a conversation on the first route, got %s", got)
+	}
+	pinned := make(map[string]string)
+	used := make(map[string]bool)
+	for i := 0; i < 30; i++ {
+		conv := fmt.Sprintf("conv-%d", i)
+		pinned[conv] = route(conv)
+		used[pinned[conv]] = true
+		if again := route(conv); again != pinned[conv] {
+			t.Errorf("%s moved from %s to %s", conv, pinned[conv], again)
+		}
+	}
+	if len(used) != 3 {
+		t.Errorf("Expected conversations spread over every route, got %v", used)
+	}
+
+	// Only the conversations of a disabled provider move
+	if err := r.SetProviderDisabled("b", true); err != nil {
+		t.Fatal(err)
+	}
+	for conv, was := range pinned {
+		if got := route(conv); got == "b" || (was != "b" && got != was) {
+			t.Errorf("%s: expected to stay off b and on %s, got %s", conv, was, got)
+		}
+	}
+}
+
+func TestRegistryCanaryRouting(t *testing.T) {
+	canary := &config.RouteCanary{Provider: "next", Percent: 50}
+	cfg := &config.Config{
+		Mock:   []config.MockConfig{{Name: "current"}, {Name: "next"}},
+		Routes: []config.Route{{Prefix: "local-", Provider: "current", Canary: canary}},
+	}
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	route := func(conversation string) (string, string) {
+		t.Helper()
+		req := &RouteRequest{Model: "local-llama", Conversation: conversation}
+		p, err := r.Route(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.GetInfo().Name, req.Arm
+	}
+
+	arms := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		conv := fmt.Sprintf("conv-%d", i)
+		name, arm := route(conv)
+		if (arm == ArmCanary) != (name == "next") {
+			t.Fatalf("%s: arm %s does not match provider %s", conv, arm, name)
+		}
+		if again, _ := route(conv); again != name {
+			t.Errorf("%s moved from %s to %s", conv, name, again)
+		}
+		arms[arm]++
+	}
+	if arms[ArmCanary] < 25 || arms[ArmBaseline] < 25 {
+		t.Errorf("Expected conversations split about evenly, got %v", arms)
+	}
+
+	for percent, want := range map[float64]string{0: ArmBaseline, 100: ArmCanary} {
+		canary.Percent = percent
+		if _, arm := route(""); arm != want {
+			t.Errorf("At %v%%: expected %s, got %s", percent, want, arm)
+		}
+	}
+
+	// A disabled canary sends everything to the baseline
+	if err := r.SetProviderDisabled("next", true); err != nil {
+		t.Fatal(err)
+	}
+	if name, arm := route(""); name != "current" || arm != ArmBaseline {
+		t.Errorf("Expected the baseline with the canary disabled, got %s %s", name, arm)
+	}
+}
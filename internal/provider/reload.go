@@ -0,0 +1,214 @@
+package provider
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// ReloadResult describes what a configuration reload changed
+type ReloadResult struct {
+	Added   []string `json:"added"`
+	Updated []string `json:"updated"`
+	Removed []string `json:"removed"`
+	// RoutesChanged is set when the routes, failover pairs or model specs
+	// differ from the previous configuration
+	RoutesChanged bool `json:"routes_changed"`
+}
+
+// providerSettings is everything in a configuration that shapes the
+// instance registered under one provider name
+type providerSettings struct {
+	Section  any
+	TLS      config.ProviderTLS
+	Keys     *config.ProviderKeyPool
+	Retries  *config.RetryPolicy
+	Timeouts *config.ProviderTimeouts
+	VCR      config.VCRConfig
+	Pacer    PacerConfig
+}
+
+// configProviderSettings returns the settings of each provider cfg declares
+func configProviderSettings(cfg *config.Config) map[string]providerSettings {
+	sections := make(map[string]any)
+	builtin := func(name string, enabled bool, section any) {
+		if enabled {
+			sections[name] = section
+		}
+	}
+	builtin("openai", cfg.OpenAI.APIKey != "", cfg.OpenAI)
+	builtin("gemini", cfg.Gemini.APIKey != "", cfg.Gemini)
+	builtin("mistral", cfg.Mistral.APIKey != "", cfg.Mistral)
+	builtin("cohere", cfg.Cohere.APIKey != "", cfg.Cohere)
+	builtin("deepseek", cfg.DeepSeek.APIKey != "", cfg.DeepSeek)
+	builtin("openrouter", cfg.OpenRouter.APIKey != "", cfg.OpenRouter)
+	builtin("perplexity", cfg.Perplexity.APIKey != "", cfg.Perplexity)
+	builtin("zhipu", cfg.Zhipu.APIKey != "", cfg.Zhipu)
+	builtin("llamacpp", cfg.LlamaCpp.ModelPath != "", cfg.LlamaCpp)
+	for _, oc := range cfg.OpenAICompatible {
+		sections[oc.Name] = oc
+	}
+	for _, rc := range cfg.RemoteLetLLM {
+		sections[rc.Name] = rc
+	}
+	for _, mc := range cfg.Mock {
+		sections[mc.Name] = mc
+	}
+
+	settings := make(map[string]providerSettings, len(sections))
+	for name, section := range sections {
+		s := providerSettings{Section: section, TLS: cfg.ProviderTLS[name], VCR: cfg.VCR, Pacer: pacerConfig(cfg)}
+		if kp, ok := cfg.ProviderKeys[name]; ok {
+			s.Keys = &kp
+		}
+		if rp, ok := cfg.ProviderRetries[name]; ok {
+			s.Retries = &rp
+		}
+		if pt, ok := cfg.ProviderTimeouts[name]; ok {
+			s.Timeouts = &pt
+		}
+		settings[name] = s
+	}
+	return settings
+}
+
+// Reload applies a new configuration's providers, routes, failover pairs
+// and model specs. Providers whose settings did not change keep their
+// instance, along with changes made at runtime; providers registered at
+// runtime that neither configuration declares are kept. Replaced and
+// removed instances stay open for a while, so requests and streams already
+// routed to them finish. When cfg is invalid nothing is applied.
+func (r *Registry) Reload(cfg *config.Config) (ReloadResult, error) {
+	next, err := NewRegistry(cfg)
+	if err != nil {
+		return ReloadResult{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev := configProviderSettings(r.cfg)
+	settings := configProviderSettings(cfg)
+	var result ReloadResult
+	var unused []Provider
+	keep := func(name string) {
+		if p, ok := next.providers[name]; ok {
+			unused = append(unused, p)
+		}
+		next.providers[name] = r.providers[name]
+		if spec, ok := r.specs[name]; ok {
+			next.specs[name] = spec
+		}
+		for m, owner := range next.models {
+			if owner == name {
+				delete(next.models, m)
+			}
+		}
+		for m, owner := range r.models {
+			if owner == name {
+				if _, taken := next.models[m]; !taken {
+					next.models[m] = name
+				}
+			}
+		}
+	}
+
+	for name := range next.providers {
+		old, declared := prev[name]
+		_, registered := r.providers[name]
+		switch {
+		case declared && registered && reflect.DeepEqual(old, settings[name]):
+			keep(name)
+		case declared:
+			result.Updated = append(result.Updated, name)
+		default:
+			result.Added = append(result.Added, name)
+		}
+	}
+	for name := range r.providers {
+		if _, ok := next.providers[name]; ok {
+			continue
+		}
+		if _, declared := prev[name]; declared {
+			result.Removed = append(result.Removed, name)
+			continue
+		}
+		keep(name)
+	}
+	for name, p := range r.providers {
+		if next.providers[name] != p {
+			r.retire(p)
+		}
+	}
+	for _, p := range unused {
+		_ = p.Close()
+	}
+
+	for name := range next.providers {
+		if r.disabled[name] {
+			next.disabled[name] = true
+		}
+	}
+	for _, name := range append(append(result.Added, result.Updated...), result.Removed...) {
+		r.forget(name)
+	}
+	result.RoutesChanged = !reflect.DeepEqual(r.cfg.Routes, cfg.Routes) ||
+		r.cfg.RoutingStrategy != cfg.RoutingStrategy ||
+		!reflect.DeepEqual(r.cfg.ModelAliases, cfg.ModelAliases) ||
+		!reflect.DeepEqual(r.cfg.Pricing, cfg.Pricing) ||
+		!reflect.DeepEqual(r.cfg.Failover, cfg.Failover) ||
+		!reflect.DeepEqual(r.cfg.Models, cfg.Models)
+	if reflect.DeepEqual(r.cfg.Failover, cfg.Failover) {
+		next.failover = r.failover
+	}
+
+	r.cfg = cfg
+	r.providers = next.providers
+	r.models = next.models
+	r.specs = next.specs
+	r.disabled = next.disabled
+	r.failover = next.failover
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Updated)
+	sort.Strings(result.Removed)
+	return result, nil
+}
+
+// forget drops the cached health and discovery results of a provider
+func (r *Registry) forget(name string) {
+	r.healthMu.Lock()
+	delete(r.health, name)
+	r.healthMu.Unlock()
+
+	if err := r.cache.Delete(context.Background(), discoveryKey(name)); err != nil {
+		log.Printf("model list cache: %v", err)
+	}
+}
+
+// retire closes p, replaced by a reload, once the requests and streams
+// routed to it have had retireDelay to finish. Callers must hold r.mu.
+func (r *Registry) retire(p Provider) {
+	r.retired = append(r.retired, p)
+	delay := retireDelay
+	if r.retireAfter > 0 {
+		delay = r.retireAfter
+	}
+	time.AfterFunc(delay, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, q := range r.retired {
+			if q == p {
+				r.retired = append(r.retired[:i], r.retired[i+1:]...)
+				if err := p.Close(); err != nil {
+					log.Printf("failed to close replaced provider %s: %v", p.GetInfo().Name, err)
+				}
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestRegistryReload(t *testing.T) {
+	cfg := &config.Config{
+		OpenAICompatible: []config.OpenAICompatibleConfig{{Name: "local", BaseURL: "http://127.0.0.1:1/v1", Models: []string{"local-chat"}}},
+		Mock:             []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}}},
+	}
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+	extra := ProviderSpec{Name: "extra", Type: ProviderTypeOpenAICompatible, BaseURL: "http://127.0.0.1:2/v1", Models: []string{"extra-chat"}}
+	if err := r.PutProvider(extra); err != nil {
+		t.Fatal(err)
+	}
+	_ = r.SetProviderDisabled("demo", true)
+	demo, _ := r.GetProvider("demo")
+	local, _ := r.GetProvider("local")
+
+	next := &config.Config{
+		OpenAICompatible: []config.OpenAICompatibleConfig{{Name: "local", BaseURL: "http://127.0.0.1:3/v1", Models: []string{"local-chat"}}},
+		Mock:             []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}}, {Name: "other", Models: []string{"other-chat"}}},
+		Routes:           []config.Route{{Prefix: "gpt-", Provider: "other"}},
+	}
+	result, err := r.Reload(next)
+	if err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	want := ReloadResult{Added: []string{"other"}, Updated: []string{"local"}, RoutesChanged: true}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Expected %+v, got %+v", want, result)
+	}
+	if p, _ := r.GetProvider("demo"); p != demo || !r.ProviderDisabled("demo") {
+		t.Error("Expected the unchanged provider kept, still disabled")
+	}
+	if p, _ := r.GetProvider("local"); p == local {
+		t.Error("Expected the changed provider replaced")
+	}
+	if len(r.retired) != 1 || r.retired[0] != local {
+		t.Errorf("Expected the replaced instance kept open, got %v", r.retired)
+	}
+	if got := routedName(t, r, "extra-chat"); got != "extra" {
+		t.Errorf("Expected the runtime provider kept, got %s", got)
+	}
+	if got := routedName(t, r, "gpt-4o"); got != "other" {
+		t.Errorf("Expected the new route applied, got %s", got)
+	}
+
+	// Removing local from the file removes it; invalid files change nothing
+	next = &config.Config{Mock: next.Mock}
+	if result, err = r.Reload(next); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Removed, []string{"local"}) || !result.RoutesChanged {
+		t.Errorf("Expected local removed, got %+v", result)
+	}
+	if _, err := r.Route(&RouteRequest{Model: "local-chat"}); err == nil {
+		t.Error("Expected a removed provider not routed")
+	}
+	invalid := &config.Config{Failover: []config.FailoverPair{{Active: "nope", Standby: "demo"}}}
+	if _, err := r.Reload(invalid); err == nil {
+		t.Error("Expected an invalid configuration rejected")
+	}
+	if got := routedName(t, r, "other-chat"); got != "other" {
+		t.Errorf("Expected the registry unchanged, got %s", got)
+	}
+
+	// Changed retries or timeouts replace the provider
+	next.OpenAICompatible = cfg.OpenAICompatible
+	if _, err = r.Reload(next); err != nil {
+		t.Fatal(err)
+	}
+	retried := *next
+	retried.ProviderRetries = map[string]config.RetryPolicy{"local": {MaxAttempts: 2}}
+	if result, err = r.Reload(&retried); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Updated, []string{"local"}) {
+		t.Errorf("Expected local updated for its retries, got %+v", result)
+	}
+	timed := retried
+	timed.ProviderTimeouts = map[string]config.ProviderTimeouts{"local": {Total: time.Minute}}
+	if result, err = r.Reload(&timed); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Updated, []string{"local"}) {
+		t.Errorf("Expected local updated for its timeouts, got %+v", result)
+	}
+}
+
+func TestRegistryReloadClosesRetired(t *testing.T) {
+	cfg := &config.Config{
+		OpenAICompatible: []config.OpenAICompatibleConfig{{Name: "local", BaseURL: "http://127.0.0.1:1/v1", Models: []string{"local-chat"}}},
+	}
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+	r.retireAfter = 10 * time.Millisecond
+
+	next := &config.Config{
+		OpenAICompatible: []config.OpenAICompatibleConfig{{Name: "local", BaseURL: "http://127.0.0.1:3/v1", Models: []string{"local-chat"}}},
+	}
+	if _, err := r.Reload(next); err != nil {
+		t.Fatalf("Failed to reload: %v", err)
+	}
+	retired := func() int {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return len(r.retired)
+	}
+	if retired() != 1 {
+		t.Fatal("Expected the replaced instance kept open at first")
+	}
+	for deadline := time.Now().Add(time.Second); retired() != 0; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the replaced instance closed after the delay")
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Headers a gateway adds when it forwards a request to another gateway
+const (
+	// RequestIDHeader carries the origin's request ID, which the remote
+	// gateway adopts, so both log the request under one ID
+	RequestIDHeader = "X-Request-ID"
+	// OriginKeyHeader names the virtual key the origin gateway served the
+	// request for
+	OriginKeyHeader = "X-Letllm-Origin-Key"
+)
+
+// Caller identifies who a request is served for, so providers that hop to
+// another gateway can carry it along
+type Caller struct {
+	RequestID string
+	// ID of the caller's virtual key, if the caller used one
+	KeyID string
+	// Token is the credential the caller authenticated with
+	Token string
+}
+
+type callerContextKey struct{}
+
+// WithCaller returns ctx carrying the caller of the request
+func WithCaller(ctx context.Context, c Caller) context.Context {
+	return context.WithValue(ctx, callerContextKey{}, c)
+}
+
+// CallerFrom returns the caller ctx carries
+func CallerFrom(ctx context.Context) (Caller, bool) {
+	c, ok := ctx.Value(callerContextKey{}).(Caller)
+	return c, ok
+}
+
+// RemoteLetLLMProvider routes models to another letllm-go gateway, such as
+// a regional one. The remote speaks the OpenAI API; the caller's request ID
+// and key travel along in headers.
+type RemoteLetLLMProvider struct {
+	*OpenAIProvider
+	name string
+}
+
+// NewRemoteLetLLMProvider creates a provider forwarding to the gateway rc
+// describes
+func NewRemoteLetLLMProvider(rc config.RemoteLetLLMConfig) (*RemoteLetLLMProvider, error) {
+	if rc.Name == "" {
+		return nil, fmt.Errorf("remote_letllm name is required")
+	}
+	if rc.BaseURL == "" {
+		return nil, fmt.Errorf("remote_letllm %s: base_url is required", rc.Name)
+	}
+	modelName := rc.DefaultModel
+	if modelName == "" && len(rc.Models) > 0 {
+		modelName = rc.Models[0]
+	}
+
+	pacer := NewPacer()
+	httpClient := newPacedClient(pacer)
+	httpClient.Transport = &federationTransport{base: httpClient.Transport, forwardAuth: rc.ForwardAuth}
+	config := openai.DefaultConfig(rc.APIKey)
+	config.BaseURL = rc.BaseURL
+	config.HTTPClient = httpClient
+
+	// The remote gateway adapts requests to its own providers
+	capabilities := ProviderCapabilities{
+		SupportsStreaming:   true,
+		SupportsFunctions:   true,
+		SupportsSystemRole:  true,
+		SupportsPassthrough: true,
+		SupportedModels:     rc.Models,
+		SupportedParameters: []string{"temperature", "top_p", "max_tokens", "stream", "functions"},
+	}
+
+	return &RemoteLetLLMProvider{
+		OpenAIProvider: &OpenAIProvider{
+			chat:         newOAIClient(rc.APIKey, rc.BaseURL, httpClient),
+			client:       openai.NewClientWithConfig(config),
+			modelName:    modelName,
+			capabilities: capabilities,
+			pacer:        pacer,
+		},
+		name: rc.Name,
+	}, nil
+}
+
+// GetInfo returns information about the instance under its configured name
+func (o *RemoteLetLLMProvider) GetInfo() ProviderInfo {
+	return ProviderInfo{
+		Name:         o.name,
+		Version:      "1.0.0",
+		Capabilities: o.capabilities,
+		Status:       "active",
+		LastUpdated:  time.Now(),
+	}
+}
+
+// federationTransport adds the caller of each request to its headers
+type federationTransport struct {
+	base http.RoundTripper
+	// Authenticate as the caller rather than with the configured key
+	forwardAuth bool
+}
+
+func (t *federationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	caller, ok := CallerFrom(req.Context())
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	if caller.RequestID != "" {
+		req.Header.Set(RequestIDHeader, caller.RequestID)
+	}
+	if caller.KeyID != "" {
+		req.Header.Set(OriginKeyHeader, caller.KeyID)
+	}
+	if t.forwardAuth && caller.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+caller.Token)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,48 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestRemoteLetLLMHeaders(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c-1","model":"eu-chat","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	req := &GenerateRequest{StandardRequest: &StandardRequest{Model: "eu-chat", Messages: []Message{{Role: RoleUser, Content: "Hello"}}}}
+	ctx := WithCaller(context.Background(), Caller{RequestID: "req-1", KeyID: "team-a", Token: "sk-caller"})
+
+	for name, tc := range map[string]struct {
+		forwardAuth bool
+		ctx         context.Context
+		auth        string
+		requestID   string
+		originKey   string
+	}{
+		"gateway key":  {ctx: ctx, auth: "Bearer sk-peer", requestID: "req-1", originKey: "team-a"},
+		"forward auth": {forwardAuth: true, ctx: ctx, auth: "Bearer sk-caller", requestID: "req-1", originKey: "team-a"},
+		"no caller":    {ctx: context.Background(), auth: "Bearer sk-peer"},
+	} {
+		p, err := NewRemoteLetLLMProvider(config.RemoteLetLLMConfig{Name: "eu", BaseURL: srv.URL + "/v1", APIKey: "sk-peer",
+			ForwardAuth: tc.forwardAuth, Models: []string{"eu-chat"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Generate(tc.ctx, req); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got.Get("Authorization") != tc.auth || got.Get(RequestIDHeader) != tc.requestID || got.Get(OriginKeyHeader) != tc.originKey {
+			t.Errorf("%s: unexpected headers %v", name, got)
+		}
+	}
+}
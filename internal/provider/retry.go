@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// retryTransport retries a provider's upstream requests that fail to
+// connect or answer with a retryable status. It sits below the pacer and
+// above the key pool, so each attempt may use another key. Retries happen
+// before the response reaches the provider, which for streams is before
+// the first byte.
+type retryTransport struct {
+	base    http.RoundTripper
+	policy  config.RetryPolicy
+	jitter  float64
+	retryOn map[int]bool
+	now     func() time.Time
+	sleep   func(context.Context, time.Duration) error
+	rand    func() float64
+}
+
+func newRetryTransport(rp config.RetryPolicy, base http.RoundTripper) *retryTransport {
+	t := &retryTransport{base: base, policy: rp, jitter: config.DefaultRetryJitter, retryOn: make(map[int]bool),
+		now: time.Now, sleep: sleepContext, rand: rand.Float64}
+	if t.policy.MaxAttempts <= 0 {
+		t.policy.MaxAttempts = 3
+	}
+	if t.policy.InitialBackoff <= 0 {
+		t.policy.InitialBackoff = 500 * time.Millisecond
+	}
+	if t.policy.Multiplier < 1 {
+		t.policy.Multiplier = 2
+	}
+	if t.policy.MaxBackoff <= 0 {
+		t.policy.MaxBackoff = 10 * time.Second
+	}
+	if rp.Jitter != nil {
+		t.jitter = *rp.Jitter
+	}
+	retryOn := rp.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = config.DefaultRetryOn
+	}
+	for _, status := range retryOn {
+		t.retryOn[status] = true
+	}
+	return t
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.policy.MaxAttempts || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return resp, err
+		}
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			wait = t.backoff(attempt)
+		case t.retryOn[resp.StatusCode]:
+			wait = t.backoff(attempt)
+			if after := headerReset(resp.Header, []string{"retry-after"}, t.now()); after > 0 {
+				if after > t.policy.MaxBackoff {
+					return resp, nil
+				}
+				wait = after
+			}
+		default:
+			return resp, nil
+		}
+		// A retry that would end past the deadline is not worth the wait
+		if deadline, ok := ctx.Deadline(); ok && t.now().Add(wait).After(deadline) {
+			return resp, err
+		}
+		var body io.ReadCloser
+		if req.GetBody != nil {
+			var bodyErr error
+			if body, bodyErr = req.GetBody(); bodyErr != nil {
+				return resp, err
+			}
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if err := t.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		req = req.Clone(ctx)
+		if body != nil {
+			req.Body = body
+		}
+	}
+}
+
+// backoff is the wait before the retry following the given attempt:
+// exponential, capped and jittered
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	d := float64(t.policy.InitialBackoff) * math.Pow(t.policy.Multiplier, float64(attempt-1))
+	d *= 1 + t.jitter*(2*t.rand()-1)
+	return time.Duration(math.Min(d, float64(t.policy.MaxBackoff)))
+}
@@ -0,0 +1,145 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestProviderRetries(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		n := len(bodies)
+		mu.Unlock()
+		if n%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"error":{"message":"overloaded"}}`)
+			return
+		}
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hi\"},\"finish_reason\":\"stop\"}]}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "sk-1"
+	cfg.OpenAI.BaseURL = srv.URL
+	cfg.ProviderRetries = map[string]config.RetryPolicy{"openai": {InitialBackoff: time.Millisecond}}
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := registry.GetProvider("openai")
+	req := &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "gpt-4o",
+		Messages: []Message{{Role: RoleUser, Content: "Hello"}},
+	}}
+
+	resp, err := p.Generate(context.Background(), req)
+	if err != nil || resp.Choices[0].Message.Content != "hi" {
+		t.Fatalf("Expected the third attempt to succeed, got %+v, %v", resp, err)
+	}
+	if len(bodies) != 3 || bodies[0] == "" || bodies[2] != bodies[0] {
+		t.Errorf("Expected the body resent on each attempt, got %q", bodies)
+	}
+
+	req.Stream = true
+	stream, err := p.StreamGenerate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected the stream retried before its first byte, got %v", err)
+	}
+	out, _ := io.ReadAll(stream)
+	stream.Close()
+	if !strings.Contains(string(out), "hi") || len(bodies) != 6 {
+		t.Errorf("Unexpected stream after %d attempts: %s", len(bodies), out)
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	var statuses []int
+	var retryAfter string
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		status := statuses[0]
+		statuses = statuses[1:]
+		h := http.Header{}
+		if status == http.StatusTooManyRequests {
+			h.Set("Retry-After", retryAfter)
+		}
+		return &http.Response{StatusCode: status, Header: h, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	})
+	jitter := 0.0
+	rt := newRetryTransport(config.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 10 * time.Second,
+		Jitter: &jitter}, base)
+	var waits []time.Duration
+	rt.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	send := func(ctx context.Context) int {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://upstream/v1/chat/completions", strings.NewReader("{}"))
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	// Backoff doubles, and gives up after the last attempt
+	statuses, waits = []int{500, 502, 503}, nil
+	if status := send(context.Background()); status != 503 || fmt.Sprint(waits) != "[1s 2s]" {
+		t.Errorf("Expected two backoffs and the last answer, got %d after %v", status, waits)
+	}
+	// Retry-After replaces the backoff
+	statuses, waits, retryAfter = []int{429, 200}, nil, "4"
+	if status := send(context.Background()); status != 200 || fmt.Sprint(waits) != "[4s]" {
+		t.Errorf("Expected Retry-After honored, got %d after %v", status, waits)
+	}
+	// Waits beyond the cap are not made
+	statuses, waits, retryAfter = []int{429, 200}, nil, "60"
+	if status := send(context.Background()); status != 429 || len(waits) != 0 {
+		t.Errorf("Expected no retry past max_backoff, got %d after %v", status, waits)
+	}
+	// Nor are those ending past the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	statuses, waits = []int{503, 200}, nil
+	if status := send(ctx); status != 503 || len(waits) != 0 {
+		t.Errorf("Expected no retry past the deadline, got %d after %v", status, waits)
+	}
+	// Statuses not listed are returned as they are
+	statuses, waits = []int{400, 200}, nil
+	if status := send(context.Background()); status != 400 || len(waits) != 0 {
+		t.Errorf("Expected 400 not retried, got %d after %v", status, waits)
+	}
+}
+
+func TestProviderRetriesUnsupported(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Gemini.APIKey = "key"
+	cfg.ProviderRetries = map[string]config.RetryPolicy{"gemini": {MaxAttempts: 2}}
+	if _, err := NewRegistry(cfg); err == nil || !strings.Contains(err.Error(), "retries") {
+		t.Errorf("Expected retries refused for gemini, got %v", err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
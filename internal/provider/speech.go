@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// SpeechProvider is implemented by providers with a text-to-speech API
+type SpeechProvider interface {
+	// Speak synthesises the input; the caller must close the returned audio
+	Speak(ctx context.Context, req *SpeechRequest) (*SpeechResponse, error)
+}
+
+// maxSpeechInput is the longest input accepted, in characters, matching
+// OpenAI's limit
+const maxSpeechInput = 4096
+
+// SpeechFormats maps the audio formats a speech request may ask for to
+// their MIME types
+var SpeechFormats = map[string]string{
+	"mp3":  "audio/mpeg",
+	"opus": "audio/opus",
+	"aac":  "audio/aac",
+	"flac": "audio/flac",
+	"wav":  "audio/wav",
+	"pcm":  "audio/pcm",
+}
+
+// SpeechRequest asks for text to be read out
+type SpeechRequest struct {
+	Model string
+	Input string
+	Voice string
+	// Style guidance, for models that accept it
+	Instructions string
+	// One of SpeechFormats; empty means mp3
+	Format string
+	// Playback speed from 0.25 to 4 (default 1)
+	Speed *float64
+}
+
+// SpeechResponse streams the synthesised audio
+type SpeechResponse struct {
+	Audio       io.ReadCloser
+	ContentType string
+}
+
+// ValidateSpeechRequest validates a speech request
+func ValidateSpeechRequest(req *SpeechRequest) error {
+	if req == nil {
+		return fmt.Errorf("request cannot be nil")
+	}
+	if req.Model == "" {
+		return fmt.Errorf("model is required")
+	}
+	if req.Input == "" {
+		return fmt.Errorf("input is required")
+	}
+	if n := len([]rune(req.Input)); n > maxSpeechInput {
+		return fmt.Errorf("input is %d characters, the limit is %d", n, maxSpeechInput)
+	}
+	if req.Voice == "" {
+		return fmt.Errorf("voice is required")
+	}
+	if _, ok := SpeechFormats[req.Format]; req.Format != "" && !ok {
+		return fmt.Errorf("unsupported response_format %q", req.Format)
+	}
+	if req.Speed != nil && (*req.Speed < 0.25 || *req.Speed > 4) {
+		return fmt.Errorf("speed must be between 0.25 and 4")
+	}
+	return nil
+}
+
+// speechContentType returns the MIME type of format, empty meaning mp3
+func speechContentType(format string) string {
+	if format == "" {
+		format = "mp3"
+	}
+	return SpeechFormats[format]
+}
+
+// openAISpeak calls the speech endpoint of an OpenAI-compatible API
+func openAISpeak(ctx context.Context, client *openai.Client, req *SpeechRequest) (*SpeechResponse, error) {
+	if err := ValidateSpeechRequest(req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	speechReq := openai.CreateSpeechRequest{
+		Model:          openai.SpeechModel(req.Model),
+		Input:          req.Input,
+		Voice:          openai.SpeechVoice(req.Voice),
+		Instructions:   req.Instructions,
+		ResponseFormat: openai.SpeechResponseFormat(req.Format),
+	}
+	if req.Speed != nil {
+		speechReq.Speed = *req.Speed
+	}
+
+	raw, err := client.CreateSpeech(ctx, speechReq)
+	if err != nil {
+		return nil, err
+	}
+	contentType := raw.Header().Get("Content-Type")
+	if contentType == "" {
+		contentType = speechContentType(req.Format)
+	}
+	return &SpeechResponse{Audio: raw.ReadCloser, ContentType: contentType}, nil
+}
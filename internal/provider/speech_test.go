@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAISpeak(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/speech" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		for field, want := range map[string]any{"model": "tts-1", "input": "Hello there", "voice": "alloy", "response_format": "opus", "speed": 1.5} {
+			if body[field] != want {
+				t.Errorf("%s: expected %v, got %v", field, want, body[field])
+			}
+		}
+		w.Header().Set("Content-Type", "audio/ogg")
+		_, _ = w.Write([]byte("OggS audio"))
+	}))
+	defer srv.Close()
+
+	p, err := NewOpenAIProvider("test-key", srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	speed := 1.5
+	resp, err := p.Speak(context.Background(), &SpeechRequest{Model: "tts-1", Input: "Hello there", Voice: "alloy", Format: "opus", Speed: &speed})
+	if err != nil {
+		t.Fatalf("Speak: %v", err)
+	}
+	defer resp.Audio.Close()
+	audio, _ := io.ReadAll(resp.Audio)
+	if string(audio) != "OggS audio" || resp.ContentType != "audio/ogg" {
+		t.Errorf("Unexpected audio %q as %s", audio, resp.ContentType)
+	}
+}
+
+func TestValidateSpeechRequest(t *testing.T) {
+	slow, fast := 0.1, 4.0
+	for name, tc := range map[string]struct {
+		req   SpeechRequest
+		valid bool
+	}{
+		"minimal":      {SpeechRequest{Model: "tts-1", Input: "Hi", Voice: "alloy"}, true},
+		"max speed":    {SpeechRequest{Model: "tts-1", Input: "Hi", Voice: "alloy", Speed: &fast}, true},
+		"no voice":     {SpeechRequest{Model: "tts-1", Input: "Hi"}, false},
+		"no input":     {SpeechRequest{Model: "tts-1", Voice: "alloy"}, false},
+		"bad format":   {SpeechRequest{Model: "tts-1", Input: "Hi", Voice: "alloy", Format: "ogg"}, false},
+		"too slow":     {SpeechRequest{Model: "tts-1", Input: "Hi", Voice: "alloy", Speed: &slow}, false},
+		"input length": {SpeechRequest{Model: "tts-1", Input: string(make([]rune, maxSpeechInput+1)), Voice: "alloy"}, false},
+	} {
+		if err := ValidateSpeechRequest(&tc.req); (err == nil) != tc.valid {
+			t.Errorf("%s: expected valid=%v, got %v", name, tc.valid, err)
+		}
+	}
+}
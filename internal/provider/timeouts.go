@@ -0,0 +1,125 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TimeoutError reports an upstream request cut short by one of its
+// provider's timeouts
+type TimeoutError struct {
+	// "first_byte" or "total"
+	Kind  string
+	After time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("upstream %s timeout after %s", e.Kind, e.After)
+}
+
+// Timeout reports true, like the net package's timeouts
+func (e *TimeoutError) Timeout() bool { return true }
+
+// withConnectTimeout returns rt, or a copy of the default transport when
+// nil, bounding how long it waits for a connection
+func withConnectTimeout(rt http.RoundTripper, d time.Duration) http.RoundTripper {
+	tr, ok := rt.(*http.Transport)
+	if !ok {
+		tr = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	tr.DialContext = (&net.Dialer{Timeout: d, KeepAlive: 30 * time.Second}).DialContext
+	tr.TLSHandshakeTimeout = d
+	return tr
+}
+
+// timeoutTransport bounds the time to the first byte of a response body
+// and the time to its end. Below the retry transport it bounds each
+// attempt, so a response slow to start is retried; above it, the request
+// as a whole.
+type timeoutTransport struct {
+	base      http.RoundTripper
+	firstByte time.Duration
+	total     time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	release := func() { cancel(nil) }
+	if t.total > 0 {
+		var cancelTotal context.CancelFunc
+		ctx, cancelTotal = context.WithTimeoutCause(ctx, t.total, &TimeoutError{Kind: "total", After: t.total})
+		release = func() { cancelTotal(); cancel(nil) }
+	}
+	var timer *time.Timer
+	if t.firstByte > 0 {
+		timer = time.AfterFunc(t.firstByte, func() {
+			cancel(&TimeoutError{Kind: "first_byte", After: t.firstByte})
+		})
+	}
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err == nil && timer != nil {
+		// Wait for the first byte here, so that the retry transport sees
+		// a response slow to start as failed
+		br := bufio.NewReader(resp.Body)
+		if _, err = br.Peek(1); err == io.EOF {
+			err = nil
+		}
+		if err != nil {
+			resp.Body.Close()
+			resp = nil
+		} else {
+			resp.Body = &timeoutBody{Reader: br, body: resp.Body, ctx: ctx, release: release}
+		}
+	}
+	if timer != nil {
+		timer.Stop()
+	}
+	if err != nil {
+		release()
+		return nil, timeoutCause(ctx, err)
+	}
+	if timer == nil {
+		resp.Body = &timeoutBody{Reader: resp.Body, body: resp.Body, ctx: ctx, release: release}
+	}
+	return resp, nil
+}
+
+// timeoutBody reports reads cut short by a timeout as such and releases
+// the timeouts once closed
+type timeoutBody struct {
+	io.Reader
+	body    io.Closer
+	ctx     context.Context
+	release func()
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		err = timeoutCause(b.ctx, err)
+	}
+	return n, err
+}
+
+func (b *timeoutBody) Close() error {
+	err := b.body.Close()
+	b.release()
+	return err
+}
+
+// timeoutCause returns the timeout that cancelled ctx in place of err, or
+// err when no timeout did
+func timeoutCause(ctx context.Context, err error) error {
+	var te *TimeoutError
+	if errors.As(context.Cause(ctx), &te) {
+		return te
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestProviderTimeouts(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// The first attempt sends headers but stalls before the body
+		if calls.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Second):
+			}
+		}
+		fmt.Fprint(w, `{"id":"1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "sk-1"
+	cfg.OpenAI.BaseURL = srv.URL
+	cfg.ProviderTimeouts = map[string]config.ProviderTimeouts{"openai": {Connect: time.Second, FirstByte: 50 * time.Millisecond}}
+	registry, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := registry.GetProvider("openai")
+	req := &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "gpt-4o",
+		Messages: []Message{{Role: RoleUser, Content: "Hello"}},
+	}}
+
+	var te *TimeoutError
+	if _, err := p.Generate(context.Background(), req); !errors.As(err, &te) || te.Kind != "first_byte" {
+		t.Fatalf("Expected a first byte timeout, got %v", err)
+	}
+
+	// With retries, the slow attempt is abandoned and the next one served
+	calls.Store(0)
+	cfg.ProviderRetries = map[string]config.RetryPolicy{"openai": {InitialBackoff: time.Millisecond}}
+	if registry, err = NewRegistry(cfg); err != nil {
+		t.Fatal(err)
+	}
+	p, _ = registry.GetProvider("openai")
+	if resp, err := p.Generate(context.Background(), req); err != nil || resp.Choices[0].Message.Content != "hi" || calls.Load() != 2 {
+		t.Errorf("Expected the second attempt served, got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestTimeoutTransportTotal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 20; i++ {
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &timeoutTransport{base: http.DefaultTransport, total: 100 * time.Millisecond}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var sb strings.Builder
+	buf := make([]byte, 64)
+	for {
+		n, err := resp.Body.Read(buf)
+		sb.Write(buf[:n])
+		if err != nil {
+			var te *TimeoutError
+			if !errors.As(err, &te) || te.Kind != "total" {
+				t.Errorf("Expected a total timeout, got %v", err)
+			}
+			break
+		}
+	}
+	if !strings.HasPrefix(sb.String(), "data: 0") || strings.Contains(sb.String(), "data: 19") {
+		t.Errorf("Expected the stream cut short, got %q", sb.String())
+	}
+}
+
+func TestProviderTimeoutsUnsupported(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Gemini.APIKey = "key"
+	cfg.ProviderTimeouts = map[string]config.ProviderTimeouts{"gemini": {Total: time.Minute}}
+	if _, err := NewRegistry(cfg); err == nil || !strings.Contains(err.Error(), "timeouts") {
+		t.Errorf("Expected timeouts refused for gemini, got %v", err)
+	}
+}
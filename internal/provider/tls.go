@@ -0,0 +1,73 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// newTLSTransport returns a copy of the default transport that verifies
+// server certificates as configured in pt
+func newTLSTransport(pt config.ProviderTLS) (*http.Transport, error) {
+	tlsCfg, err := tlsClientConfig(pt)
+	if err != nil {
+		return nil, err
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = tlsCfg
+	return tr, nil
+}
+
+func tlsClientConfig(pt config.ProviderTLS) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if pt.CAFile != "" {
+		pool := x509.NewCertPool()
+		if !pt.ExcludeSystemRoots {
+			system, err := x509.SystemCertPool()
+			if err != nil {
+				return nil, fmt.Errorf("load system trust store: %w", err)
+			}
+			pool = system
+		}
+		pem, err := os.ReadFile(pt.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca_file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file %s contains no PEM certificates", pt.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(pt.PinnedSHA256) > 0 {
+		pins := make(map[[sha256.Size]byte]bool, len(pt.PinnedSHA256))
+		for _, pin := range pt.PinnedSHA256 {
+			b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+			if err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("invalid pinned_sha256 %q: want a base64 SHA-256 hash", pin)
+			}
+			pins[[sha256.Size]byte(b)] = true
+		}
+		// Runs after standard verification, so pins narrow trust and never
+		// replace it
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			for _, chain := range cs.VerifiedChains {
+				for _, cert := range chain {
+					if pins[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+						return nil
+					}
+				}
+			}
+			return fmt.Errorf("no pinned public key in the certificate chain of %s", cs.ServerName)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,80 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestProviderTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(srv.Certificate().RawSubjectPublicKeyInfo)
+	pin := "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+	otherPin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	cases := []struct {
+		name    string
+		tls     *config.ProviderTLS
+		wantErr string
+	}{
+		{"system trust store", nil, "certificate"},
+		{"custom ca", &config.ProviderTLS{CAFile: caFile, ExcludeSystemRoots: true}, ""},
+		{"matching pin", &config.ProviderTLS{CAFile: caFile, PinnedSHA256: []string{otherPin, pin}}, ""},
+		{"pin mismatch", &config.ProviderTLS{CAFile: caFile, PinnedSHA256: []string{otherPin}}, "no pinned public key"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.OpenAI.APIKey = "test-key"
+			cfg.OpenAI.BaseURL = srv.URL
+			if tc.tls != nil {
+				cfg.ProviderTLS = map[string]config.ProviderTLS{"openai": *tc.tls}
+			}
+			registry, err := NewRegistry(cfg)
+			if err != nil {
+				t.Fatalf("Failed to create registry: %v", err)
+			}
+			p, _ := registry.GetProvider("openai")
+
+			_, err = p.Generate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+				Model:    "gpt-4o",
+				Messages: []Message{{Role: RoleUser, Content: "Hello"}},
+			}})
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("Expected success, got %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("Expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestProviderTLSInvalid(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "test-key"
+	cfg.ProviderTLS = map[string]config.ProviderTLS{"openai": {PinnedSHA256: []string{"not-a-hash"}}}
+	if _, err := NewRegistry(cfg); err == nil || !strings.Contains(err.Error(), "pinned_sha256") {
+		t.Errorf("Expected invalid pin error, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"fmt"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Helpers for OpenAI-compatible upstreams that only accept tools, not the
+// legacy functions/function_call fields used by StandardRequest.
+
+// toolCallID derives the tool call ID for the n-th function call in a
+// conversation. The format satisfies Mistral, which requires IDs of exactly
+// nine alphanumeric characters, and is accepted by other upstreams.
+func toolCallID(n int) string {
+	return fmt.Sprintf("call%05d", n)
+}
+
+// toolMessages converts messages, turning function calls into tool calls and
+// function results into tool messages that reference the latest call
+func toolMessages(msgs []Message) []openai.ChatCompletionMessage {
+	messages := make([]openai.ChatCompletionMessage, 0, len(msgs))
+
+	calls := 0
+	for _, msg := range msgs {
+		out := openai.ChatCompletionMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		}
+
+		switch {
+		case msg.FunctionCall != nil:
+			calls++
+			out.ToolCalls = []openai.ToolCall{{
+				ID:   toolCallID(calls),
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      msg.FunctionCall.Name,
+					Arguments: msg.FunctionCall.Arguments,
+				},
+			}}
+		case msg.Role == RoleFunction:
+			out.Role = openai.ChatMessageRoleTool
+			out.ToolCallID = toolCallID(calls)
+			if msg.Name != nil {
+				out.Name = *msg.Name
+			}
+		}
+
+		messages = append(messages, out)
+	}
+	return messages
+}
+
+// functionTools wraps function definitions as function tools
+func functionTools(fns []Function) []openai.Tool {
+	if len(fns) == 0 {
+		return nil
+	}
+	tools := make([]openai.Tool, len(fns))
+	for i, fn := range fns {
+		tools[i] = openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        fn.Name,
+				Description: fn.Description,
+				Parameters:  fn.Parameters,
+			},
+		}
+	}
+	return tools
+}
+
+// toolFunctionCall maps the first function tool call back onto the standard
+// function_call field
+func toolFunctionCall(calls []openai.ToolCall) *FunctionCall {
+	for _, call := range calls {
+		if call.Type == "" || call.Type == openai.ToolTypeFunction {
+			return &FunctionCall{
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			}
+		}
+	}
+	return nil
+}
+
+// toolFinishReason normalizes finish reasons, reporting tool_calls as
+// function_call
+func toolFinishReason(reason openai.FinishReason) *string {
+	if reason == "" {
+		return nil
+	}
+	r := string(reason)
+	if reason == openai.FinishReasonToolCalls {
+		r = FinishReasonFunctionCall
+	}
+	return &r
+}
@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ErrTranscriptionUnsupported is returned by Transcribe on providers without
+// a speech-to-text API
+var ErrTranscriptionUnsupported = errors.New("transcription is not supported by this provider")
+
+// audioTypes maps the audio file extensions OpenAI accepts to MIME types
+var audioTypes = map[string]string{
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".mp3":  "audio/mpeg",
+	".mp4":  "audio/mp4",
+	".mpeg": "audio/mpeg",
+	".mpga": "audio/mpeg",
+	".oga":  "audio/ogg",
+	".ogg":  "audio/ogg",
+	".wav":  "audio/wav",
+	".webm": "audio/webm",
+}
+
+// ValidateTranscriptionRequest validates a transcription request
+func ValidateTranscriptionRequest(req *TranscriptionRequest) error {
+	if req == nil {
+		return fmt.Errorf("request cannot be nil")
+	}
+	if req.Model == "" {
+		return fmt.Errorf("model is required")
+	}
+	if len(req.Audio) == 0 {
+		return fmt.Errorf("audio file is required")
+	}
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 1) {
+		return fmt.Errorf("temperature must be between 0 and 1")
+	}
+	return nil
+}
+
+// AudioMIMEType returns the MIME type of the request's audio from its file
+// extension, falling back to the content type the client sent
+func AudioMIMEType(req *TranscriptionRequest) (string, error) {
+	if t, ok := audioTypes[strings.ToLower(filepath.Ext(req.Filename))]; ok {
+		return t, nil
+	}
+	if t, _, err := mime.ParseMediaType(req.ContentType); err == nil && strings.HasPrefix(t, "audio/") {
+		return t, nil
+	}
+	return "", fmt.Errorf("unsupported audio format %q", filepath.Ext(req.Filename))
+}
+
+// openAITranscribe calls the transcriptions endpoint of an OpenAI-compatible
+// API
+func openAITranscribe(ctx context.Context, client *openai.Client, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	if err := ValidateTranscriptionRequest(req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	audioReq := openai.AudioRequest{
+		Model:    req.Model,
+		FilePath: req.Filename,
+		Reader:   bytes.NewReader(req.Audio),
+		Prompt:   req.Prompt,
+		Language: req.Language,
+		Format:   openai.AudioResponseFormatJSON,
+	}
+	if audioReq.FilePath == "" {
+		audioReq.FilePath = "audio"
+	}
+	if req.Temperature != nil {
+		audioReq.Temperature = float32(*req.Temperature)
+	}
+
+	resp, err := client.CreateTranscription(ctx, audioReq)
+	if err != nil {
+		return nil, err
+	}
+	return &TranscriptionResponse{Text: resp.Text, Language: resp.Language, Duration: resp.Duration, Model: req.Model}, nil
+}
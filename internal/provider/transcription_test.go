@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestOpenAITranscribe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/transcriptions" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		f, fh, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("file: %v", err)
+		}
+		audio, _ := io.ReadAll(f)
+		if fh.Filename != "clip.mp3" || string(audio) != "ID3" {
+			t.Errorf("Unexpected upload %s %q", fh.Filename, audio)
+		}
+		for field, want := range map[string]string{"model": "whisper-1", "language": "fr", "prompt": "Letllm", "response_format": "json", "temperature": "0.20"} {
+			if got := r.FormValue(field); got != want {
+				t.Errorf("%s: expected %q, got %q", field, want, got)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"text":"Bonjour"}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewOpenAIProvider("test-key", srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	temp := 0.2
+	resp, err := p.Transcribe(context.Background(), &TranscriptionRequest{
+		Model: "whisper-1", Audio: []byte("ID3"), Filename: "clip.mp3", Language: "fr", Prompt: "Letllm", Temperature: &temp,
+	})
+	if err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+	if resp.Text != "Bonjour" || resp.Model != "whisper-1" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+}
+
+func TestAudioMIMEType(t *testing.T) {
+	for name, tc := range map[string]struct {
+		req  TranscriptionRequest
+		want string
+	}{
+		"extension":    {TranscriptionRequest{Filename: "a.MP3"}, "audio/mpeg"},
+		"content type": {TranscriptionRequest{Filename: "blob", ContentType: "audio/x-aiff; rate=44100"}, "audio/x-aiff"},
+		"unknown":      {TranscriptionRequest{Filename: "notes.txt", ContentType: "text/plain"}, ""},
+	} {
+		got, err := AudioMIMEType(&tc.req)
+		if got != tc.want || (err != nil) != (tc.want == "") {
+			t.Errorf("%s: expected %q, got %q, %v", name, tc.want, got, err)
+		}
+	}
+}
+
+func TestRegistryTranscriptionRouting(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.OpenAI.APIKey = "test-key"
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for _, model := range []string{"whisper-1", "gpt-4o-transcribe"} {
+		if p, err := r.GetProviderForModel(model); err != nil || p.GetInfo().Name != "openai" {
+			t.Errorf("%s: expected openai, got %v", model, err)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package provider
 import (
 	"fmt"
 	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/ids"
 )
 
 // RequestTransformer defines the interface for transforming requests between formats
@@ -103,6 +105,12 @@ func CreateStandardResponse(id, model string, choices []Choice, usage Usage) *St
 	}
 }
 
+// NewResponseID returns a unique OpenAI-style completion ID for providers
+// whose API does not assign one
+func NewResponseID() string {
+	return "chatcmpl-" + ids.New()
+}
+
 // CreateStreamChunk creates a stream chunk with common fields populated
 func CreateStreamChunk(id, model string, choices []Choice, done bool) *StreamChunk {
 	chunk := &StreamChunk{
@@ -132,6 +140,7 @@ func MergeCapabilities(capabilities ...ProviderCapabilities) ProviderCapabilitie
 		merged.SupportsStreaming = merged.SupportsStreaming || cap.SupportsStreaming
 		merged.SupportsFunctions = merged.SupportsFunctions || cap.SupportsFunctions
 		merged.SupportsSystemRole = merged.SupportsSystemRole || cap.SupportsSystemRole
+		merged.SupportsPassthrough = merged.SupportsPassthrough || cap.SupportsPassthrough
 
 		// Use maximum for numeric capabilities
 		if cap.MaxTokens > merged.MaxTokens {
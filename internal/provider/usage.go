@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"encoding/json"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// usageDetails are OpenAI's nested usage breakdowns, also sent by
+// Perplexity, OpenRouter and most OpenAI-compatible servers
+type usageDetails struct {
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+		AudioTokens  int `json:"audio_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails *struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+// apply fills the breakdowns of u that are not already set
+func (d usageDetails) apply(u *Usage) {
+	if p := d.PromptTokensDetails; p != nil {
+		if u.CachedPromptTokens == 0 {
+			u.CachedPromptTokens = p.CachedTokens
+		}
+		if u.AudioTokens == 0 {
+			u.AudioTokens = p.AudioTokens
+		}
+	}
+	if c := d.CompletionTokensDetails; c != nil && u.ReasoningTokens == 0 {
+		u.ReasoningTokens = c.ReasoningTokens
+	}
+}
+
+// UnmarshalJSON decodes usage in the gateway's flat form or in OpenAI's
+// form with prompt_tokens_details and completion_tokens_details
+func (u *Usage) UnmarshalJSON(data []byte) error {
+	type plain Usage
+	if err := json.Unmarshal(data, (*plain)(u)); err != nil {
+		return err
+	}
+	var d usageDetails
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+	d.apply(u)
+	return nil
+}
+
+// openAIUsage converts go-openai usage, including its breakdowns
+func openAIUsage(in openai.Usage) Usage {
+	u := Usage{
+		PromptTokens:     in.PromptTokens,
+		CompletionTokens: in.CompletionTokens,
+		TotalTokens:      in.TotalTokens,
+	}
+	if p := in.PromptTokensDetails; p != nil {
+		u.CachedPromptTokens = p.CachedTokens
+		u.AudioTokens = p.AudioTokens
+	}
+	if c := in.CompletionTokensDetails; c != nil {
+		u.ReasoningTokens = c.ReasoningTokens
+	}
+	return u
+}
+
+// Cost returns the USD cost of u at price. Cached prompt tokens, audio
+// input and reasoning tokens are billed at their own rates when the price
+// sets them, and at the plain input or output rate otherwise.
+func (u Usage) Cost(price config.ModelPrice) float64 {
+	rate := func(r, fallback float64) float64 {
+		if r > 0 {
+			return r
+		}
+		return fallback
+	}
+	cached := min(u.CachedPromptTokens, u.PromptTokens)
+	audio := min(u.AudioTokens, u.PromptTokens-cached)
+	reasoning := min(u.ReasoningTokens, u.CompletionTokens)
+
+	cost := float64(u.PromptTokens-cached-audio)*price.InputPerMTok +
+		float64(cached)*rate(price.CachedInputPerMTok, price.InputPerMTok) +
+		float64(audio)*rate(price.AudioInputPerMTok, price.InputPerMTok) +
+		float64(u.CompletionTokens-reasoning)*price.OutputPerMTok +
+		float64(reasoning)*rate(price.ReasoningPerMTok, price.OutputPerMTok)
+	return cost / 1e6
+}
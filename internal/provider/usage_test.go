@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestUsageUnmarshalJSON(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body string
+		want Usage
+	}{
+		"openai details": {
+			`{"prompt_tokens":100,"completion_tokens":40,"total_tokens":140,
+			  "prompt_tokens_details":{"cached_tokens":64,"audio_tokens":10},
+			  "completion_tokens_details":{"reasoning_tokens":32}}`,
+			Usage{PromptTokens: 100, CompletionTokens: 40, TotalTokens: 140, CachedPromptTokens: 64, ReasoningTokens: 32, AudioTokens: 10},
+		},
+		"flat": {
+			`{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15,"cached_prompt_tokens":4,"reasoning_tokens":2}`,
+			Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, CachedPromptTokens: 4, ReasoningTokens: 2},
+		},
+		"null details": {
+			`{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2,"prompt_tokens_details":null}`,
+			Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2},
+		},
+	} {
+		var got Usage
+		if err := json.Unmarshal([]byte(tc.body), &got); err != nil || got != tc.want {
+			t.Errorf("%s: expected %+v, got %+v, %v", name, tc.want, got, err)
+		}
+	}
+}
+
+func TestUsageCost(t *testing.T) {
+	price := config.ModelPrice{InputPerMTok: 2, OutputPerMTok: 8, CachedInputPerMTok: 0.5, AudioInputPerMTok: 40, ReasoningPerMTok: 10}
+	for name, tc := range map[string]struct {
+		usage Usage
+		price config.ModelPrice
+		want  float64
+	}{
+		"plain":           {Usage{PromptTokens: 1e6, CompletionTokens: 1e6}, price, 10},
+		"cached prompt":   {Usage{PromptTokens: 1e6, CachedPromptTokens: 5e5}, price, 1 + 0.25},
+		"audio input":     {Usage{PromptTokens: 1e6, AudioTokens: 1e5}, price, 1.8 + 4},
+		"reasoning":       {Usage{CompletionTokens: 1e6, ReasoningTokens: 5e5}, price, 4 + 5},
+		"default rates":   {Usage{PromptTokens: 1e6, CompletionTokens: 1e6, CachedPromptTokens: 1e6, ReasoningTokens: 1e6}, config.ModelPrice{InputPerMTok: 2, OutputPerMTok: 8}, 10},
+		"breakdown clamp": {Usage{PromptTokens: 100, CachedPromptTokens: 500}, config.ModelPrice{InputPerMTok: 1e6, CachedInputPerMTok: 1e4}, 1},
+	} {
+		if got := tc.usage.Cost(tc.price); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// ErrCassetteNotFound is returned in replay mode for requests that were
+// never recorded
+var ErrCassetteNotFound = errors.New("no cassette recorded for request")
+
+// Cassette is one recorded exchange with a provider
+type Cassette struct {
+	Provider   string    `json:"provider"`
+	RecordedAt time.Time `json:"recorded_at"`
+	// Set for Generate and StreamGenerate calls
+	Request *StandardRequest `json:"request,omitempty"`
+	// Set for Embed calls
+	EmbeddingRequest *EmbeddingRequest `json:"embedding_request,omitempty"`
+	// Set for Transcribe calls
+	TranscriptionRequest *TranscriptionRequest `json:"transcription_request,omitempty"`
+	// Set for Generate calls that succeeded
+	Response *StandardResponse `json:"response,omitempty"`
+	// Set for StreamGenerate calls, one entry per read from the stream
+	Chunks []CassetteChunk `json:"chunks,omitempty"`
+	// Set for Embed calls that succeeded
+	Embeddings *EmbeddingResponse `json:"embeddings,omitempty"`
+	// Set for Transcribe calls that succeeded
+	Transcription *TranscriptionResponse `json:"transcription,omitempty"`
+	// Error that failed the call, or ended the stream after Chunks
+	Error string `json:"error,omitempty"`
+}
+
+// CassetteChunk is one read from a recorded stream
+type CassetteChunk struct {
+	// Time since the previous chunk, or since the stream opened
+	DelayMS int64  `json:"delay_ms"`
+	Data    string `json:"data"`
+}
+
+// VCRProvider records the exchanges of the provider it wraps to cassette
+// files, or replays them without calling the provider. Cassettes are keyed
+// by provider name and request, so a request replays whatever was last
+// recorded for it. Wrapped providers are not health checked or paced.
+type VCRProvider struct {
+	Provider
+	name string
+	cfg  config.VCRConfig
+}
+
+// NewVCRProvider wraps inner, registered as name
+func NewVCRProvider(name string, inner Provider, cfg config.VCRConfig) *VCRProvider {
+	return &VCRProvider{Provider: inner, name: name, cfg: cfg}
+}
+
+// Generate records or replays a completion
+func (v *VCRProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	if v.cfg.Mode == config.VCRReplay {
+		c, err := v.load(req.StandardRequest)
+		if err != nil {
+			return nil, err
+		}
+		if c.Error != "" {
+			return nil, errors.New(c.Error)
+		}
+		if c.Response == nil {
+			return nil, fmt.Errorf("cassette for %s holds a stream, not a response", v.name)
+		}
+		return &GenerateResponse{StandardResponse: c.Response}, nil
+	}
+
+	resp, err := v.Provider.Generate(ctx, req)
+	c := v.cassette(req.StandardRequest)
+	if err != nil {
+		c.Error = err.Error()
+	} else {
+		c.Response = resp.StandardResponse
+	}
+	if serr := v.save(c); serr != nil {
+		return nil, serr
+	}
+	return resp, err
+}
+
+// StreamGenerate records or replays a stream, chunk by chunk
+func (v *VCRProvider) StreamGenerate(ctx context.Context, req *GenerateRequest) (io.ReadCloser, error) {
+	if v.cfg.Mode == config.VCRReplay {
+		c, err := v.load(req.StandardRequest)
+		if err != nil {
+			return nil, err
+		}
+		if c.Chunks == nil && c.Error != "" {
+			return nil, errors.New(c.Error)
+		}
+		return v.replay(ctx, c), nil
+	}
+
+	rc, err := v.Provider.StreamGenerate(ctx, req)
+	if err != nil {
+		c := v.cassette(req.StandardRequest)
+		c.Error = err.Error()
+		if serr := v.save(c); serr != nil {
+			return nil, serr
+		}
+		return nil, err
+	}
+	return &vcrRecorder{rc: rc, v: v, cassette: v.cassette(req.StandardRequest), last: time.Now()}, nil
+}
+
+// Embed records or replays embeddings
+func (v *VCRProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	if v.cfg.Mode == config.VCRReplay {
+		c, err := v.load(req)
+		if err != nil {
+			return nil, err
+		}
+		if c.Error != "" {
+			return nil, errors.New(c.Error)
+		}
+		if c.Embeddings == nil {
+			return nil, fmt.Errorf("cassette for %s holds no embeddings", v.name)
+		}
+		return c.Embeddings, nil
+	}
+
+	resp, err := v.Provider.Embed(ctx, req)
+	c := &Cassette{Provider: v.name, RecordedAt: time.Now().UTC(), EmbeddingRequest: req}
+	if err != nil {
+		c.Error = err.Error()
+	} else {
+		c.Embeddings = resp
+	}
+	if serr := v.save(c); serr != nil {
+		return nil, serr
+	}
+	return resp, err
+}
+
+// Transcribe records or replays transcriptions
+func (v *VCRProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	if v.cfg.Mode == config.VCRReplay {
+		c, err := v.load(req)
+		if err != nil {
+			return nil, err
+		}
+		if c.Error != "" {
+			return nil, errors.New(c.Error)
+		}
+		if c.Transcription == nil {
+			return nil, fmt.Errorf("cassette for %s holds no transcription", v.name)
+		}
+		return c.Transcription, nil
+	}
+
+	resp, err := v.Provider.Transcribe(ctx, req)
+	c := &Cassette{Provider: v.name, RecordedAt: time.Now().UTC(), TranscriptionRequest: req}
+	if err != nil {
+		c.Error = err.Error()
+	} else {
+		c.Transcription = resp
+	}
+	if serr := v.save(c); serr != nil {
+		return nil, serr
+	}
+	return resp, err
+}
+
+// replay streams the recorded chunks, ending with the recorded error if any
+func (v *VCRProvider) replay(ctx context.Context, c *Cassette) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		for _, chunk := range c.Chunks {
+			if v.cfg.ReplayDelays {
+				if err := sleepContext(ctx, time.Duration(chunk.DelayMS)*time.Millisecond); err != nil {
+					_ = pw.CloseWithError(err)
+					return
+				}
+			}
+			if _, err := pw.Write([]byte(chunk.Data)); err != nil {
+				return
+			}
+		}
+		if c.Error != "" {
+			_ = pw.CloseWithError(errors.New(c.Error))
+			return
+		}
+		_ = pw.Close()
+	}()
+	return pr
+}
+
+func (v *VCRProvider) cassette(req *StandardRequest) *Cassette {
+	return &Cassette{Provider: v.name, RecordedAt: time.Now().UTC(), Request: req}
+}
+
+// path returns the cassette file for req, a *StandardRequest, an
+// *EmbeddingRequest or a *TranscriptionRequest
+func (v *VCRProvider) path(req interface{}) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("vcr: marshal request: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	return filepath.Join(v.cfg.Dir, v.name+"-"+hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+func (v *VCRProvider) load(req interface{}) (*Cassette, error) {
+	path, err := v.path(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrCassetteNotFound, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("vcr: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("vcr: decode %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// save writes c through a temporary file so replays never see a partial
+// cassette
+func (v *VCRProvider) save(c *Cassette) error {
+	var req interface{} = c.Request
+	switch {
+	case c.EmbeddingRequest != nil:
+		req = c.EmbeddingRequest
+	case c.TranscriptionRequest != nil:
+		req = c.TranscriptionRequest
+	}
+	path, err := v.path(req)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("vcr: marshal cassette: %w", err)
+	}
+	if err := os.MkdirAll(v.cfg.Dir, 0o755); err != nil {
+		return fmt.Errorf("vcr: %w", err)
+	}
+	tmp, err := os.CreateTemp(v.cfg.Dir, ".cassette-*")
+	if err != nil {
+		return fmt.Errorf("vcr: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("vcr: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("vcr: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("vcr: %w", err)
+	}
+	return nil
+}
+
+// vcrRecorder passes a stream through, saving its cassette once the stream
+// ends. Streams the client abandons early are not saved.
+type vcrRecorder struct {
+	rc       io.ReadCloser
+	v        *VCRProvider
+	cassette *Cassette
+	last     time.Time
+	once     sync.Once
+}
+
+func (r *vcrRecorder) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	if n > 0 {
+		now := time.Now()
+		r.cassette.Chunks = append(r.cassette.Chunks, CassetteChunk{
+			DelayMS: now.Sub(r.last).Milliseconds(),
+			Data:    string(p[:n]),
+		})
+		r.last = now
+	}
+	if err != nil {
+		if err != io.EOF {
+			r.cassette.Error = err.Error()
+		}
+		r.once.Do(func() {
+			if serr := r.v.save(r.cassette); serr != nil {
+				err = serr
+			}
+		})
+	}
+	return n, err
+}
+
+func (r *vcrRecorder) Close() error {
+	return r.rc.Close()
+}
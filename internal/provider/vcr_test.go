@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestVCRRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	upstream, err := NewMockProvider(config.MockConfig{
+		Name:       "demo",
+		ChunkDelay: 20 * time.Millisecond,
+		Responses:  []config.MockResponse{{Match: "fail", Error: "upstream exploded"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replays must never reach the upstream
+	offline, err := NewMockProvider(config.MockConfig{Name: "demo", Responses: []config.MockResponse{{Error: "upstream called"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := NewVCRProvider("demo", upstream, config.VCRConfig{Mode: config.VCRRecord, Dir: dir})
+	player := NewVCRProvider("demo", offline, config.VCRConfig{Mode: config.VCRReplay, Dir: dir, ReplayDelays: true})
+
+	ctx := context.Background()
+	req := func(prompt string, stream bool) *GenerateRequest {
+		return &GenerateRequest{StandardRequest: &StandardRequest{
+			Model:    "demo-1",
+			Messages: []Message{{Role: RoleUser, Content: prompt}},
+			Stream:   stream,
+		}}
+	}
+
+	recorded, err := recorder.Generate(ctx, req("hello there", false))
+	if err != nil {
+		t.Fatalf("record Generate: %v", err)
+	}
+	replayed, err := player.Generate(ctx, req("hello there", false))
+	if err != nil {
+		t.Fatalf("replay Generate: %v", err)
+	}
+	if !reflect.DeepEqual(recorded.StandardResponse, replayed.StandardResponse) {
+		t.Errorf("Replayed response differs:\n%+v\n%+v", recorded.StandardResponse, replayed.StandardResponse)
+	}
+
+	rc, err := recorder.StreamGenerate(ctx, req("one two three", true))
+	if err != nil {
+		t.Fatalf("record StreamGenerate: %v", err)
+	}
+	live, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("read recorded stream: %v", err)
+	}
+
+	start := time.Now()
+	rc, err = player.StreamGenerate(ctx, req("one two three", true))
+	if err != nil {
+		t.Fatalf("replay StreamGenerate: %v", err)
+	}
+	played, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("read replayed stream: %v", err)
+	}
+	if string(played) != string(live) {
+		t.Errorf("Replayed stream differs:\n%s\n%s", live, played)
+	}
+	// Two gaps of ChunkDelay between three words
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected recorded chunk spacing to be replayed, took %v", elapsed)
+	}
+
+	if _, err := recorder.Generate(ctx, req("please fail", false)); err == nil {
+		t.Fatal("Expected the upstream error")
+	}
+	if _, err := player.Generate(ctx, req("please fail", false)); err == nil || err.Error() != "upstream exploded" {
+		t.Errorf("Expected the recorded error, got %v", err)
+	}
+
+	if _, err := player.Generate(ctx, req("never recorded", false)); !errors.Is(err, ErrCassetteNotFound) {
+		t.Errorf("Expected ErrCassetteNotFound, got %v", err)
+	}
+}
+
+func TestVCRAbandonedStreamNotSaved(t *testing.T) {
+	dir := t.TempDir()
+	upstream, err := NewMockProvider(config.MockConfig{Name: "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := NewVCRProvider("demo", upstream, config.VCRConfig{Mode: config.VCRRecord, Dir: dir})
+	player := NewVCRProvider("demo", upstream, config.VCRConfig{Mode: config.VCRReplay, Dir: dir})
+
+	req := &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "demo-1",
+		Messages: []Message{{Role: RoleUser, Content: "a b c"}},
+		Stream:   true,
+	}}
+	rc, err := recorder.StreamGenerate(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rc.Read(make([]byte, 4)); err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+
+	if _, err := player.StreamGenerate(context.Background(), req); !errors.Is(err, ErrCassetteNotFound) {
+		t.Errorf("Expected no cassette for an abandoned stream, got %v", err)
+	}
+}
+
+func TestRegistryVCR(t *testing.T) {
+	cfg := &config.Config{
+		Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-1"}}},
+		VCR:  config.VCRConfig{Mode: config.VCRReplay, Dir: t.TempDir()},
+	}
+	r, err := NewRegistry(cfg)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	p, err := r.GetProviderForModel("demo-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*VCRProvider); !ok {
+		t.Fatalf("Expected a VCR-wrapped provider, got %T", p)
+	}
+	if p.GetInfo().Name != "demo" {
+		t.Errorf("Expected info of the wrapped provider, got %+v", p.GetInfo())
+	}
+}
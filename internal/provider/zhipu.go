@@ -0,0 +1,319 @@
+package provider
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultZhipuBaseURL is the Zhipu open platform API endpoint
+const DefaultZhipuBaseURL = "https://open.bigmodel.cn/api/paas/v4"
+
+// zhipuTokenTTL is how long a signed token is valid; tokens are reused until
+// a minute before they expire
+const zhipuTokenTTL = 30 * time.Minute
+
+// ZhipuProvider implements the Provider interface using Zhipu's
+// OpenAI-compatible GLM-4 chat API. The "id.secret" API key is never sent
+// as is: each request carries a short-lived HS256 JWT signed with the secret.
+type ZhipuProvider struct {
+	client       *openai.Client
+	modelName    string
+	capabilities ProviderCapabilities
+	pacer        *Pacer
+}
+
+// NewZhipuProvider creates a new Zhipu provider instance
+func NewZhipuProvider(apiKey, baseURL, modelName string) (*ZhipuProvider, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("zhipu apiKey is required")
+	}
+	signer, err := newZhipuSigner(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	if modelName == "" {
+		modelName = "glm-4"
+	}
+	if baseURL == "" {
+		baseURL = DefaultZhipuBaseURL
+	}
+
+	pacer := NewPacer()
+	httpClient := newPacedClient(pacer)
+	signer.base = httpClient.Transport
+	httpClient.Transport = signer
+
+	config := openai.DefaultConfig("")
+	config.BaseURL = baseURL
+	config.HTTPClient = httpClient
+
+	// Define Zhipu capabilities
+	capabilities := ProviderCapabilities{
+		SupportsStreaming:   true,
+		SupportsFunctions:   true,
+		SupportsSystemRole:  true,
+		MaxTokens:           4096,
+		MaxContextLength:    128000,
+		SupportedModels:     []string{"glm-4", "glm-4-plus", "glm-4-air", "glm-4-airx", "glm-4-flash", "glm-4-long"},
+		SupportedParameters: []string{"temperature", "top_p", "max_tokens", "stream", "functions"},
+	}
+
+	return &ZhipuProvider{
+		client:       openai.NewClientWithConfig(config),
+		modelName:    modelName,
+		capabilities: capabilities,
+		pacer:        pacer,
+	}, nil
+}
+
+// Generate generates a completion for the given request
+func (z *ZhipuProvider) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	resp, err := z.client.CreateChatCompletion(ctx, *z.transformRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("zhipu completion error: %w", err)
+	}
+
+	return &GenerateResponse{
+		StandardResponse: z.transformResponse(&resp),
+	}, nil
+}
+
+// StreamGenerate generates a streaming completion for the given request
+func (z *ZhipuProvider) StreamGenerate(ctx context.Context, req *GenerateRequest) (io.ReadCloser, error) {
+	if err := ValidateStandardRequest(req.StandardRequest); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+
+	zhipuReq := z.transformRequest(req)
+	zhipuReq.Stream = true
+
+	stream, err := z.client.CreateChatCompletionStream(ctx, *zhipuReq)
+	if err != nil {
+		return nil, fmt.Errorf("zhipu start stream error: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer stream.Close()
+		defer pw.Close()
+
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("zhipu stream recv error: %w", err))
+				return
+			}
+
+			chunkData, err := json.Marshal(z.transformStreamChunk(&resp))
+			if err != nil {
+				_ = pw.CloseWithError(fmt.Errorf("failed to marshal chunk: %w", err))
+				return
+			}
+
+			if _, werr := pw.Write(append(chunkData, '\n')); werr != nil {
+				_ = pw.CloseWithError(werr)
+				return
+			}
+		}
+	}()
+
+	return pr, nil
+}
+
+// Embed is not supported
+func (z *ZhipuProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	return nil, fmt.Errorf("zhipu: %w", ErrEmbeddingsUnsupported)
+}
+
+// Transcribe is not supported
+func (z *ZhipuProvider) Transcribe(ctx context.Context, req *TranscriptionRequest) (*TranscriptionResponse, error) {
+	return nil, fmt.Errorf("zhipu: %w", ErrTranscriptionUnsupported)
+}
+
+// GetCapabilities returns the capabilities of the Zhipu provider
+func (z *ZhipuProvider) GetCapabilities() ProviderCapabilities {
+	return z.capabilities
+}
+
+// GetInfo returns information about the Zhipu provider
+func (z *ZhipuProvider) GetInfo() ProviderInfo {
+	return ProviderInfo{
+		Name:         "zhipu",
+		Version:      "1.0.0",
+		Capabilities: z.capabilities,
+		Status:       "active",
+		LastUpdated:  time.Now(),
+	}
+}
+
+// Health verifies the API is reachable and the key is accepted. Zhipu has no
+// models endpoint, so the cheapest check is a one-token completion.
+func (z *ZhipuProvider) Health(ctx context.Context) error {
+	_, err := z.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:     z.modelName,
+		Messages:  []openai.ChatCompletionMessage{{Role: RoleUser, Content: "ping"}},
+		MaxTokens: 1,
+	})
+	if err != nil {
+		return fmt.Errorf("zhipu completion error: %w", err)
+	}
+	return nil
+}
+
+// Pacer returns the pacer tracking Zhipu rate limit headers
+func (z *ZhipuProvider) Pacer() *Pacer {
+	return z.pacer
+}
+
+// Close closes any underlying resources (no-op for the HTTP client)
+func (z *ZhipuProvider) Close() error {
+	return nil
+}
+
+// transformRequest converts a StandardRequest to Zhipu format
+func (z *ZhipuProvider) transformRequest(req *GenerateRequest) *openai.ChatCompletionRequest {
+	zhipuReq := &openai.ChatCompletionRequest{
+		Model:    req.Model,
+		Messages: toolMessages(req.Messages),
+		Tools:    functionTools(req.Functions),
+		Stream:   req.Stream,
+	}
+
+	if req.MaxTokens != nil {
+		zhipuReq.MaxTokens = *req.MaxTokens
+	}
+
+	if req.Temperature != nil {
+		zhipuReq.Temperature = float32(*req.Temperature)
+	}
+
+	if req.TopP != nil {
+		zhipuReq.TopP = float32(*req.TopP)
+	}
+
+	return zhipuReq
+}
+
+// transformResponse converts a Zhipu response to StandardResponse
+func (z *ZhipuProvider) transformResponse(resp *openai.ChatCompletionResponse) *StandardResponse {
+	choices := make([]Choice, len(resp.Choices))
+
+	for i, choice := range resp.Choices {
+		choices[i] = Choice{
+			Index: choice.Index,
+			Message: &Message{
+				Role:         choice.Message.Role,
+				Content:      choice.Message.Content,
+				FunctionCall: toolFunctionCall(choice.Message.ToolCalls),
+			},
+			FinishReason: toolFinishReason(choice.FinishReason),
+		}
+	}
+
+	return CreateStandardResponse(resp.ID, resp.Model, choices, openAIUsage(resp.Usage))
+}
+
+// transformStreamChunk converts a Zhipu stream response to StreamChunk.
+// Zhipu emits each tool call whole in a single delta.
+func (z *ZhipuProvider) transformStreamChunk(resp *openai.ChatCompletionStreamResponse) *StreamChunk {
+	choices := make([]Choice, len(resp.Choices))
+
+	for i, choice := range resp.Choices {
+		choices[i] = Choice{
+			Index: choice.Index,
+			Delta: &Message{
+				Role:         choice.Delta.Role,
+				Content:      choice.Delta.Content,
+				FunctionCall: toolFunctionCall(choice.Delta.ToolCalls),
+			},
+			FinishReason: toolFinishReason(choice.FinishReason),
+		}
+	}
+
+	done := len(resp.Choices) > 0 && resp.Choices[0].FinishReason != ""
+
+	return CreateStreamChunk(resp.ID, resp.Model, choices, done)
+}
+
+// zhipuSigner authenticates requests with a JWT signed from the API key,
+// replacing whatever Authorization header the client set
+type zhipuSigner struct {
+	base   http.RoundTripper
+	id     string
+	secret []byte
+	now    func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newZhipuSigner(apiKey string) (*zhipuSigner, error) {
+	id, secret, ok := strings.Cut(apiKey, ".")
+	if !ok || id == "" || secret == "" {
+		return nil, fmt.Errorf("zhipu apiKey must have the form <id>.<secret>")
+	}
+	return &zhipuSigner{id: id, secret: []byte(secret), now: time.Now}, nil
+}
+
+func (s *zhipuSigner) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := s.sign()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return s.base.RoundTrip(req)
+}
+
+// sign returns the cached token, signing a new one when it is about to expire
+func (s *zhipuSigner) sign() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.token != "" && now.Add(time.Minute).Before(s.expires) {
+		return s.token, nil
+	}
+
+	expires := now.Add(zhipuTokenTTL)
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "sign_type": "SIGN"})
+	if err != nil {
+		return "", err
+	}
+	// Zhipu expects millisecond timestamps rather than the usual seconds
+	claims, err := json.Marshal(map[string]interface{}{
+		"api_key":   s.id,
+		"exp":       expires.UnixMilli(),
+		"timestamp": now.UnixMilli(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(signing))
+	s.token = signing + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	s.expires = expires
+	return s.token, nil
+}
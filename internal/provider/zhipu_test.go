@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestZhipuSignsRequests(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"glm-1","model":"glm-4","choices":[{"index":0,
+			"message":{"role":"assistant","content":"你好"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`)
+	}))
+	defer srv.Close()
+
+	p, err := NewZhipuProvider("key-id.key-secret", srv.URL, "")
+	if err != nil {
+		t.Fatalf("Failed to create Zhipu provider: %v", err)
+	}
+
+	resp, err := p.Generate(context.Background(), &GenerateRequest{StandardRequest: &StandardRequest{
+		Model:    "glm-4",
+		Messages: []Message{{Role: RoleUser, Content: "Hi"}},
+	}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "你好" || resp.Usage.TotalTokens != 5 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	token, ok := strings.CutPrefix(auth, "Bearer ")
+	if !ok || strings.Contains(token, "key-secret") {
+		t.Fatalf("Expected a signed bearer token, got %q", auth)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Expected a JWT, got %q", token)
+	}
+	mac := hmac.New(sha256.New, []byte("key-secret"))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if parts[2] != base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) {
+		t.Error("JWT signature does not match the key secret")
+	}
+
+	var header, claims map[string]interface{}
+	for part, v := range map[string]*map[string]interface{}{parts[0]: &header, parts[1]: &claims} {
+		raw, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil || json.Unmarshal(raw, v) != nil {
+			t.Fatalf("Invalid JWT segment %q", part)
+		}
+	}
+	if header["alg"] != "HS256" || header["sign_type"] != "SIGN" {
+		t.Errorf("Unexpected header: %v", header)
+	}
+	if claims["api_key"] != "key-id" || claims["exp"].(float64) <= claims["timestamp"].(float64) {
+		t.Errorf("Unexpected claims: %v", claims)
+	}
+}
+
+func TestZhipuSignerReusesToken(t *testing.T) {
+	s, err := newZhipuSigner("id.secret")
+	if err != nil {
+		t.Fatalf("newZhipuSigner: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	s.now = func() time.Time { return now }
+
+	first, _ := s.sign()
+	now = now.Add(zhipuTokenTTL / 2)
+	if second, _ := s.sign(); second != first {
+		t.Error("Expected the token to be reused while valid")
+	}
+	now = now.Add(zhipuTokenTTL / 2)
+	if third, _ := s.sign(); third == first {
+		t.Error("Expected a new token near expiry")
+	}
+}
+
+func TestZhipuRejectsMalformedKey(t *testing.T) {
+	for _, key := range []string{"no-secret", ".secret", "id."} {
+		if _, err := NewZhipuProvider(key, "", ""); err == nil {
+			t.Errorf("Expected error for key %q", key)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/health"
+	"go.uber.org/fx"
+)
+
+// Module provides the shared *Client (nil when redis.addr is unset).
+var Module = fx.Provide(
+	NewClient,
+	fx.Annotate(ReadinessCheck, fx.ResultTags(`group:"readiness"`)),
+)
+
+// NewClient connects to the configured server and closes the client on
+// shutdown
+func NewClient(lc fx.Lifecycle, cfg *config.Config) *Client {
+	if cfg.Redis.Addr == "" {
+		return nil
+	}
+	c := New(Options{
+		Addr:     cfg.Redis.Addr,
+		Username: cfg.Redis.Username,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+		PoolSize: cfg.Redis.PoolSize,
+		Timeout:  cfg.Redis.Timeout,
+	})
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			return c.Close()
+		},
+	})
+	return c
+}
+
+// ReadinessCheck reports whether Redis is reachable
+func ReadinessCheck(c *Client, cfg *config.Config) health.Check {
+	return health.Check{
+		Name: "redis",
+		Probe: func(ctx context.Context) (map[string]string, error) {
+			if c == nil {
+				return map[string]string{"configured": "false"}, nil
+			}
+			return map[string]string{"addr": cfg.Redis.Addr}, c.Ping(ctx)
+		},
+	}
+}
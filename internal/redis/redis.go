@@ -0,0 +1,279 @@
+// Package redis is a small client for the part of Redis the gateway keeps
+// state in that replicas share: plain commands and Lua scripts over RESP2
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options configures a Client
+type Options struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	// Idle connections kept for reuse (default 10)
+	PoolSize int
+	// Bound on dialing and on each command without an earlier deadline
+	// (default 1s)
+	Timeout time.Duration
+}
+
+// Error is an error reply from the server
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Nil is returned by Do for a nil reply
+var Nil = errors.New("redis: nil reply")
+
+// Client runs commands on one Redis server over a pool of connections. It
+// is safe for concurrent use.
+type Client struct {
+	opts Options
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+// New returns a client for the server at opts.Addr; connections are dialed
+// on first use
+func New(opts Options) *Client {
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 10
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Second
+	}
+	return &Client{opts: opts}
+}
+
+// Do runs a command and returns its reply: a string for simple and bulk
+// strings, an int64 for integers and a []any for arrays, whose nil elements
+// are nil. Error replies are returned as an Error.
+func (c *Client) Do(ctx context.Context, args ...any) (any, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := cn.do(ctx, args)
+	var replyErr Error
+	if err != nil && !errors.As(err, &replyErr) && !errors.Is(err, Nil) {
+		// The connection is in an unknown state after a network error
+		_ = cn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// Ping checks the server is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// Close closes the idle connections; connections in use are closed when
+// they are returned
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	for _, cn := range c.idle {
+		_ = cn.Close()
+	}
+	c.idle = nil
+	return nil
+}
+
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, errors.New("redis: client closed")
+	}
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cn, nil
+	}
+	c.mu.Unlock()
+	return c.dial(ctx)
+}
+
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || len(c.idle) >= c.opts.PoolSize {
+		_ = cn.Close()
+		return
+	}
+	c.idle = append(c.idle, cn)
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	d := net.Dialer{Timeout: c.opts.Timeout}
+	nc, err := d.DialContext(ctx, "tcp", c.opts.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("redis: dial %s: %w", c.opts.Addr, err)
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+	if c.opts.Password != "" {
+		args := []any{"AUTH", c.opts.Password}
+		if c.opts.Username != "" {
+			args = []any{"AUTH", c.opts.Username, c.opts.Password}
+		}
+		if _, err := cn.do(ctx, args); err != nil {
+			_ = cn.Close()
+			return nil, fmt.Errorf("redis: auth: %w", err)
+		}
+	}
+	if c.opts.DB != 0 {
+		if _, err := cn.do(ctx, []any{"SELECT", c.opts.DB}); err != nil {
+			_ = cn.Close()
+			return nil, fmt.Errorf("redis: select %d: %w", c.opts.DB, err)
+		}
+	}
+	return cn, nil
+}
+
+// Script is a Lua script run by its SHA1 digest, loading it on the server
+// the first time it is missing there
+type Script struct {
+	src, hash string
+}
+
+// NewScript returns the script with source src
+func NewScript(src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	return &Script{src: src, hash: hex.EncodeToString(sum[:])}
+}
+
+// Run runs the script on c with keys as KEYS and args as ARGV
+func (s *Script) Run(ctx context.Context, c *Client, keys []string, args ...any) (any, error) {
+	cmd := make([]any, 0, 3+len(keys)+len(args))
+	cmd = append(cmd, "EVALSHA", s.hash, len(keys))
+	for _, k := range keys {
+		cmd = append(cmd, k)
+	}
+	cmd = append(cmd, args...)
+	reply, err := c.Do(ctx, cmd...)
+	var replyErr Error
+	if errors.As(err, &replyErr) && strings.HasPrefix(string(replyErr), "NOSCRIPT") {
+		cmd[0], cmd[1] = "EVAL", s.src
+		return c.Do(ctx, cmd...)
+	}
+	return reply, err
+}
+
+// conn is one connection to the server
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// do writes a command and reads its reply, within ctx's deadline
+func (cn *conn) do(ctx context.Context, args []any) (any, error) {
+	deadline, _ := ctx.Deadline()
+	if err := cn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(cn.w, "*%d\r\n", len(args))
+	for _, a := range args {
+		s := arg(a)
+		fmt.Fprintf(cn.w, "$%d\r\n%s\r\n", len(s), s)
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, fmt.Errorf("redis: write: %w", err)
+	}
+	reply, err := cn.read()
+	if err == nil && reply == nil {
+		return nil, Nil
+	}
+	return reply, err
+}
+
+// read reads one reply
+func (cn *conn) read() (any, error) {
+	line, err := cn.r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: read: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch kind, rest := line[0], line[1:]; kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return nil, Error(rest)
+	case ':':
+		return strconv.ParseInt(rest, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.r, buf); err != nil {
+			return nil, fmt.Errorf("redis: read: %w", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		out := make([]any, n)
+		for i := range out {
+			v, err := cn.read()
+			var replyErr Error
+			if errors.As(err, &replyErr) {
+				v, err = replyErr, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+// arg formats a command argument as a bulk string
+func arg(a any) string {
+	switch v := a.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(a)
+}
@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeServer speaks enough RESP to answer the commands the tests send
+func fakeServer(t *testing.T) (addr string, conns *atomic.Int32, commands chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	conns, commands = new(atomic.Int32), make(chan []string, 100)
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns.Add(1)
+			go serveFake(nc, commands)
+		}
+	}()
+	return ln.Addr().String(), conns, commands
+}
+
+func serveFake(nc net.Conn, commands chan []string) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			line, _ = r.ReadString('\n')
+			size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			args[i] = string(buf[:size])
+		}
+		commands <- args
+
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "AUTH", "SELECT":
+			reply = "+OK\r\n"
+		case "PING":
+			reply = "+PONG\r\n"
+		case "GET":
+			reply = "$-1\r\n"
+		case "EVALSHA":
+			reply = "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+		case "EVAL":
+			reply = fmt.Sprintf("*3\r\n:%s\r\n$%d\r\n%s\r\n$-1\r\n", args[2], len(args[3]), args[3])
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err := io.WriteString(nc, reply); err != nil {
+			return
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	addr, conns, commands := fakeServer(t)
+	c := New(Options{Addr: addr, Username: "gateway", Password: "secret", DB: 2})
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	for _, want := range [][]string{{"AUTH", "gateway", "secret"}, {"SELECT", "2"}, {"PING"}} {
+		if got := <-commands; !reflect.DeepEqual(got, want) {
+			t.Fatalf("Expected %q, got %q", want, got)
+		}
+	}
+
+	if _, err := c.Do(ctx, "GET", "missing"); !errors.Is(err, Nil) {
+		t.Fatalf("Expected a nil reply, got %v", err)
+	}
+	var replyErr Error
+	if _, err := c.Do(ctx, "FLUSHALL"); !errors.As(err, &replyErr) || string(replyErr) != "ERR unknown command" {
+		t.Fatalf("Expected an error reply, got %v", err)
+	}
+
+	// Scripts missing on the server are sent in full
+	s := NewScript("return {#KEYS, KEYS[1], false}")
+	reply, err := s.Run(ctx, c, []string{"letllm:k"}, 1.5)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if want := []any{int64(1), "letllm:k", nil}; !reflect.DeepEqual(reply, want) {
+		t.Fatalf("Expected %v, got %v", want, reply)
+	}
+	<-commands // GET
+	<-commands // FLUSHALL
+	if got := <-commands; got[0] != "EVALSHA" || got[1] != s.hash || got[4] != "1.5" {
+		t.Fatalf("Expected the script to be run by digest, got %q", got)
+	}
+	if got := <-commands; got[0] != "EVAL" || got[1] != s.src {
+		t.Fatalf("Expected the script to be sent after NOSCRIPT, got %q", got)
+	}
+
+	// Replies, error replies included, leave the connection reusable
+	if n := conns.Load(); n != 1 {
+		t.Fatalf("Expected one connection to be reused, got %d", n)
+	}
+}
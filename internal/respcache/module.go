@@ -0,0 +1,7 @@
+package respcache
+
+import "go.uber.org/fx"
+
+// Module provides the chat completion response Cache, kept in the
+// configured cache backend.
+var Module = fx.Provide(New)
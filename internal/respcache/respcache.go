@@ -0,0 +1,171 @@
+package respcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/cache"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// maxEntries and maxBytes bound the in-memory cache NewCache creates
+const (
+	maxEntries = 10000
+	maxBytes   = 256 << 20
+)
+
+// Directives are the Cache-Control request directives the cache honors
+type Directives struct {
+	NoCache bool
+	NoStore bool
+	// MaxAge is nil unless the client sent max-age
+	MaxAge *time.Duration
+}
+
+// ParseCacheControl parses a Cache-Control request header. Unknown and
+// malformed directives are ignored.
+func ParseCacheControl(header string) Directives {
+	var d Directives
+	for _, part := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch strings.ToLower(name) {
+		case "no-cache":
+			d.NoCache = true
+		case "no-store":
+			d.NoStore = true
+		case "max-age":
+			if secs, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && secs >= 0 {
+				age := time.Duration(secs) * time.Second
+				d.MaxAge = &age
+			}
+		}
+	}
+	return d
+}
+
+// Plan is what the cache does for one request
+type Plan struct {
+	// Lookup allows answering from entries no older than MaxAge, or from
+	// any unexpired entry when MaxAge is zero
+	Lookup bool
+	MaxAge time.Duration
+	// StoreTTL is how long the response is cached; zero keeps it out
+	StoreTTL time.Duration
+}
+
+// Enabled reports whether the cache is involved at all
+func (p Plan) Enabled() bool {
+	return p.Lookup || p.StoreTTL > 0
+}
+
+// PlanFor combines a route's policy with the client's directives. Routes
+// without a policy are never cached, whatever the client asks for.
+func PlanFor(rc *config.RouteCache, d Directives) Plan {
+	if rc == nil || d.NoStore {
+		return Plan{}
+	}
+	var p Plan
+	if d.MaxAge != nil {
+		age := min(*d.MaxAge, rc.MaxTTL)
+		p = Plan{Lookup: age > 0, MaxAge: age, StoreTTL: age}
+	} else if rc.TTL > 0 {
+		p = Plan{Lookup: true, StoreTTL: rc.TTL}
+	}
+	if d.NoCache {
+		p.Lookup = false
+	}
+	return p
+}
+
+// Key identifies a request by the caller's key, the provider it routes to
+// and the request itself, so callers never see each other's answers
+func Key(keyID, providerName string, req *provider.StandardRequest) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(keyID))
+	h.Write([]byte{0})
+	h.Write([]byte(providerName))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// keyPrefix sets the responses apart from other entries of the backend
+const keyPrefix = "response:"
+
+// Cache keeps chat completion responses in a cache.Cache. Responses are
+// answered from fresh copies, which callers may modify. Backend errors are
+// logged and treated as misses.
+type Cache struct {
+	backend cache.Cache
+	now     func() time.Time
+}
+
+type entry struct {
+	Response *provider.StandardResponse `json:"response"`
+	Stored   time.Time                  `json:"stored"`
+	Expires  time.Time                  `json:"expires"`
+}
+
+// NewCache creates an empty cache in memory
+func NewCache() *Cache {
+	return New(cache.NewMemory(maxEntries, maxBytes))
+}
+
+// New keeps responses in backend
+func New(backend cache.Cache) *Cache {
+	return &Cache{backend: backend, now: time.Now}
+}
+
+// Get returns an unexpired response no older than maxAge, or of any age
+// when maxAge is zero, with its age
+func (c *Cache) Get(ctx context.Context, key string, maxAge time.Duration) (*provider.StandardResponse, time.Duration, bool) {
+	data, ok, err := c.backend.Get(ctx, keyPrefix+key)
+	if err != nil {
+		log.Printf("response cache: %v", err)
+		return nil, 0, false
+	}
+	if !ok {
+		return nil, 0, false
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Response == nil {
+		return nil, 0, false
+	}
+	now := c.now()
+	if !now.Before(e.Expires) {
+		return nil, 0, false
+	}
+	age := now.Sub(e.Stored)
+	if maxAge > 0 && age > maxAge {
+		return nil, 0, false
+	}
+	return e.Response, age, true
+}
+
+// Put stores a response for ttl
+func (c *Cache) Put(ctx context.Context, key string, resp *provider.StandardResponse, ttl time.Duration) {
+	now := c.now()
+	data, err := json.Marshal(entry{Response: resp, Stored: now, Expires: now.Add(ttl)})
+	if err == nil {
+		err = c.backend.Set(ctx, keyPrefix+key, data, ttl)
+	}
+	if err != nil {
+		log.Printf("response cache: %v", err)
+	}
+}
+
+// Flush drops every response, e.g. on an erasure request
+func (c *Cache) Flush(ctx context.Context) error {
+	return c.backend.Clear(ctx, keyPrefix)
+}
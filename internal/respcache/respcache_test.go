@@ -0,0 +1,113 @@
+package respcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+func TestParseCacheControl(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                       "",
+		"no-cache":               "no-cache",
+		"No-Store, max-age=30":   "no-store max-age=30s",
+		`max-age="120"`:          "max-age=2m0s",
+		"max-age=-1, private":    "",
+		"no-cache,max-age=0":     "no-cache max-age=0s",
+		"max-age=soon, no-store": "no-store",
+	} {
+		d := ParseCacheControl(header)
+		got := ""
+		add := func(s string) {
+			if got != "" {
+				got += " "
+			}
+			got += s
+		}
+		if d.NoCache {
+			add("no-cache")
+		}
+		if d.NoStore {
+			add("no-store")
+		}
+		if d.MaxAge != nil {
+			add("max-age=" + d.MaxAge.String())
+		}
+		if got != want {
+			t.Errorf("%q: expected %q, got %q", header, want, got)
+		}
+	}
+}
+
+func TestPlanFor(t *testing.T) {
+	age := func(d time.Duration) *time.Duration { return &d }
+	always := &config.RouteCache{TTL: time.Minute, MaxTTL: time.Hour}
+	optIn := &config.RouteCache{MaxTTL: 10 * time.Minute}
+
+	for name, tc := range map[string]struct {
+		route *config.RouteCache
+		d     Directives
+		want  Plan
+	}{
+		"route without cache":  {nil, Directives{MaxAge: age(time.Minute)}, Plan{}},
+		"route default":        {always, Directives{}, Plan{Lookup: true, StoreTTL: time.Minute}},
+		"no-cache":             {always, Directives{NoCache: true}, Plan{StoreTTL: time.Minute}},
+		"no-store":             {always, Directives{NoStore: true, MaxAge: age(time.Minute)}, Plan{}},
+		"max-age":              {always, Directives{MaxAge: age(5 * time.Minute)}, Plan{Lookup: true, MaxAge: 5 * time.Minute, StoreTTL: 5 * time.Minute}},
+		"max-age capped":       {optIn, Directives{MaxAge: age(time.Hour)}, Plan{Lookup: true, MaxAge: 10 * time.Minute, StoreTTL: 10 * time.Minute}},
+		"max-age zero":         {always, Directives{MaxAge: age(0)}, Plan{}},
+		"opt-in without hint":  {optIn, Directives{}, Plan{}},
+		"opt-in with no-cache": {optIn, Directives{NoCache: true, MaxAge: age(time.Minute)}, Plan{MaxAge: time.Minute, StoreTTL: time.Minute}},
+	} {
+		if got := PlanFor(tc.route, tc.d); got != tc.want {
+			t.Errorf("%s: expected %+v, got %+v", name, tc.want, got)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := NewCache()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	resp := provider.CreateStandardResponse("chatcmpl-1", "m", nil, provider.Usage{})
+	c.Put(ctx, "k", resp, time.Minute)
+	now = now.Add(20 * time.Second)
+
+	if got, age, ok := c.Get(ctx, "k", 0); !ok || got.ID != resp.ID || age != 20*time.Second {
+		t.Errorf("Expected a hit aged 20s, got %v %v %v", got, age, ok)
+	}
+	if _, _, ok := c.Get(ctx, "k", 10*time.Second); ok {
+		t.Error("Expected entries older than max-age to be skipped")
+	}
+	if _, _, ok := c.Get(ctx, "other", 0); ok {
+		t.Error("Expected a miss for an unknown key")
+	}
+	now = now.Add(time.Minute)
+	if _, _, ok := c.Get(ctx, "k", 0); ok {
+		t.Error("Expected expired entries to be skipped")
+	}
+
+	c.Put(ctx, "k", resp, time.Minute)
+	if err := c.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if _, _, ok := c.Get(ctx, "k", 0); ok {
+		t.Error("Expected no entries after Flush")
+	}
+}
+
+func TestKey(t *testing.T) {
+	req := &provider.StandardRequest{Model: "m", Messages: []provider.Message{{Role: provider.RoleUser, Content: "hi"}}}
+	a, _ := Key("key-a", "openai", req)
+	again, _ := Key("key-a", "openai", req)
+	b, _ := Key("key-b", "openai", req)
+	other, _ := Key("key-a", "mistral", req)
+	if a != again || a == b || a == other {
+		t.Errorf("Keys should depend on caller and provider: %s %s %s %s", a, again, b, other)
+	}
+}
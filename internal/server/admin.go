@@ -0,0 +1,305 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+)
+
+// AdminRouter is the /admin route group, guarded by the admin token
+type AdminRouter struct {
+	*gin.RouterGroup
+}
+
+// NewAdminRouter creates the /admin route group
+func NewAdminRouter(engine *gin.Engine, cfg *config.Config) *AdminRouter {
+	return &AdminRouter{RouterGroup: engine.Group("/admin", adminAuth(cfg))}
+}
+
+// adminAuth requires "Authorization: Bearer <admin token>"
+func adminAuth(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := cfg.Admin.Token
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "admin API disabled"})
+			return
+		}
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// KeyCreateRequest is the body of POST /admin/keys; only the ID is
+// generated when left out
+type KeyCreateRequest struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Owner        string            `json:"owner"`
+	Tags         []string          `json:"tags"`
+	ExpiresAt    *time.Time        `json:"expires_at"`
+	Budget       *keys.Budget      `json:"budget"`
+	Limits       *keys.Limits      `json:"limits"`
+	ModelAliases map[string]string `json:"model_aliases"`
+	Priority     string            `json:"priority"`
+}
+
+// KeyCreateResponse is a created key with its secret, which is not stored
+// and cannot be retrieved again
+type KeyCreateResponse struct {
+	*keys.Key
+	Secret string `json:"secret"`
+}
+
+// keyList is the body of GET /admin/keys
+type keyList struct {
+	Object string      `json:"object"`
+	Data   []*keys.Key `json:"data"`
+}
+
+// RegisterKeyAdminRoutes wires issuing, listing and revoking virtual keys,
+// and bulk key export/import for migrations. Keys declared in the config
+// keep the revocation, budgets and limits set here across restarts; their
+// other settings are reset to the configured ones.
+func RegisterKeyAdminRoutes(admin *AdminRouter, store keys.Store) {
+	admin.GET("/keys", func(c *gin.Context) {
+		all, err := store.List()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if all == nil {
+			all = []*keys.Key{}
+		}
+		c.JSON(http.StatusOK, keyList{Object: "list", Data: all})
+	})
+
+	admin.POST("/keys", func(c *gin.Context) {
+		var in KeyCreateRequest
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+			return
+		}
+		if err := keys.ValidateModelAliases(in.ModelAliases); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !config.ValidPriority(in.Priority) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown priority %q", in.Priority)})
+			return
+		}
+		if in.ExpiresAt != nil && !in.ExpiresAt.After(time.Now()) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+		if in.ID == "" {
+			in.ID = "key-" + strings.ToLower(ids.New())
+		}
+		if _, err := store.Get(in.ID); err == nil {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("key %s already exists", in.ID)})
+			return
+		} else if !errors.Is(err, keys.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		secret, err := keys.NewSecret()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		k := &keys.Key{
+			ID:           in.ID,
+			Name:         in.Name,
+			Hash:         keys.HashSecret(secret),
+			CreatedAt:    time.Now().UTC(),
+			Budget:       in.Budget,
+			Limits:       in.Limits,
+			ModelAliases: in.ModelAliases,
+			Priority:     in.Priority,
+			Owner:        in.Owner,
+			Tags:         in.Tags,
+			ExpiresAt:    in.ExpiresAt,
+		}
+		if err := store.Put(k); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, KeyCreateResponse{Key: k, Secret: secret})
+	})
+
+	admin.GET("/keys/:id", func(c *gin.Context) {
+		k, err := store.Get(c.Param("id"))
+		if errors.Is(err, keys.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, k)
+	})
+
+	// POST /admin/keys/:id/revoke disables the key; it is kept so its
+	// usage stays attributed
+	admin.POST("/keys/:id/revoke", func(c *gin.Context) {
+		k, err := store.Get(c.Param("id"))
+		if errors.Is(err, keys.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		k.Disabled = true
+		if err := store.Put(k); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, k)
+	})
+
+	admin.GET("/keys/export", func(c *gin.Context) {
+		export, err := keys.ExportAll(store, time.Now())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="letllm-keys.json"`)
+		c.JSON(http.StatusOK, export)
+	})
+
+	// POST /admin/keys/import?format=letllm|litellm&overwrite=true
+	admin.POST("/keys/import", func(c *gin.Context) {
+		body, err := c.GetRawData()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("read body: %v", err)})
+			return
+		}
+
+		var in []*keys.Key
+		switch format := c.DefaultQuery("format", "letllm"); format {
+		case "letllm":
+			var export keys.Export
+			if err := json.Unmarshal(body, &export); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+				return
+			}
+			if export.Version > keys.ExportVersion {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported export version %d", export.Version)})
+				return
+			}
+			in = export.Keys
+		case "litellm":
+			records, err := decodeLiteLLMKeys(body)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+				return
+			}
+			if in, err = keys.FromLiteLLM(records); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		default:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown format %q", format)})
+			return
+		}
+
+		res, err := keys.Import(store, in, c.Query("overwrite") == "true")
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	})
+}
+
+// keyModelAliases is the body of the model alias routes
+type keyModelAliases struct {
+	ModelAliases map[string]string `json:"model_aliases"`
+}
+
+// RegisterModelAliasRoutes wires per-key model aliases. Unlike their
+// revocation, budgets and limits, the aliases of keys declared in the
+// config are reset to the configured ones on restart.
+func RegisterModelAliasRoutes(admin *AdminRouter, store keys.Store) {
+	admin.GET("/keys/:id/model-aliases", func(c *gin.Context) {
+		k, err := store.Get(c.Param("id"))
+		if errors.Is(err, keys.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, keyModelAliases{ModelAliases: nonNilAliases(k.ModelAliases)})
+	})
+
+	// PUT /admin/keys/:id/model-aliases replaces the key's aliases; they
+	// apply from the key's next request
+	admin.PUT("/keys/:id/model-aliases", func(c *gin.Context) {
+		var in keyModelAliases
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+			return
+		}
+		if err := keys.ValidateModelAliases(in.ModelAliases); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		k, err := store.Get(c.Param("id"))
+		if errors.Is(err, keys.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		k.ModelAliases = in.ModelAliases
+		if err := store.Put(k); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, keyModelAliases{ModelAliases: nonNilAliases(k.ModelAliases)})
+	})
+}
+
+// nonNilAliases returns aliases, or an empty map so it encodes as {}
+func nonNilAliases(aliases map[string]string) map[string]string {
+	if aliases == nil {
+		return map[string]string{}
+	}
+	return aliases
+}
+
+// decodeLiteLLMKeys accepts either a bare array of key records or LiteLLM's
+// {"keys": [...]} list response
+func decodeLiteLLMKeys(body []byte) ([]keys.LiteLLMKey, error) {
+	var records []keys.LiteLLMKey
+	if err := json.Unmarshal(body, &records); err == nil {
+		return records, nil
+	}
+	var wrapped struct {
+		Keys []keys.LiteLLMKey `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &wrapped); err != nil {
+		return nil, err
+	}
+	return wrapped.Keys, nil
+}
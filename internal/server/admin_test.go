@@ -0,0 +1,85 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+)
+
+func TestKeyAdminRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{RequireKeys: true}
+	cfg.Admin.Token = "admin"
+	store := keys.NewMemoryStore()
+	engine := gin.New()
+	RegisterKeyAuth(engine, cfg, store)
+	RegisterKeyAdminRoutes(NewAdminRouter(engine, cfg), store)
+	engine.GET("/v1/models", func(c *gin.Context) { c.String(http.StatusOK, callerKeyID(c, store)) })
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/admin/keys", "admin", `{"id":"search","owner":"alice","tags":["prod"],"expires_at":"2099-01-01T00:00:00Z"}`)
+	var created KeyCreateResponse
+	if w.Code != http.StatusCreated || json.Unmarshal(w.Body.Bytes(), &created) != nil || !strings.HasPrefix(created.Secret, "sk-letllm-") {
+		t.Fatalf("Unexpected create response %d: %s", w.Code, w.Body.String())
+	}
+	if created.Owner != "alice" || created.Hash != keys.HashSecret(created.Secret) || created.ExpiresAt == nil {
+		t.Errorf("Unexpected key: %+v", created.Key)
+	}
+	if w := do(http.MethodPost, "/admin/keys", "admin", `{"id":"search"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected a duplicate ID to conflict, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/admin/keys", "admin", `{"expires_at":"2000-01-01T00:00:00Z"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a past expiry to be rejected, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/v1/models", created.Secret, ""); w.Code != http.StatusOK || w.Body.String() != "search" {
+		t.Errorf("Expected the new key to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+	// The admin token is only accepted as a bearer token
+	req := httptest.NewRequest(http.MethodGet, "/admin/keys", nil)
+	req.Header.Set("Authorization", "admin")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a bare admin token refused, got %d", w.Code)
+	}
+
+	// Generated IDs
+	w = do(http.MethodPost, "/admin/keys", "admin", `{"name":"batch jobs"}`)
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"id":"key-`) {
+		t.Errorf("Expected a generated ID, got %d: %s", w.Code, w.Body.String())
+	}
+	w = do(http.MethodGet, "/admin/keys", "admin", "")
+	var list keyList
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &list) != nil || len(list.Data) != 2 || strings.Contains(w.Body.String(), "sk-letllm-") {
+		t.Errorf("Unexpected list %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := do(http.MethodPost, "/admin/keys/search/revoke", "admin", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"disabled":true`) {
+		t.Errorf("Unexpected revoke response %d: %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, "/v1/models", created.Secret, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked key to be refused, got %d", w.Code)
+	}
+	if w := do(http.MethodPost, "/admin/keys/missing/revoke", "admin", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 revoking a missing key, got %d", w.Code)
+	}
+
+	// Expired keys are refused
+	_ = store.Put(&keys.Key{ID: "old", Hash: keys.HashSecret("sk-old"), ExpiresAt: &created.CreatedAt})
+	if w := do(http.MethodGet, "/v1/models", "sk-old", ""); w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "api_key_expired") {
+		t.Errorf("Expected the expired key to be refused, got %d: %s", w.Code, w.Body.String())
+	}
+}
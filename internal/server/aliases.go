@@ -0,0 +1,41 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// resolveModelAlias returns the model the caller's key, then the gateway's
+// model_aliases, map model to, or model itself. Substitutions are reported
+// as warnings. The key is the one recordUsage looked up.
+func resolveModelAlias(c *gin.Context, r *provider.Router, model string) string {
+	v, _ := c.Get(callerKeyKey)
+	if k, _ := v.(*keys.Key); k != nil {
+		target, ok := k.ResolveModel(model)
+		if ok {
+			addWarning(c, provider.Warning{
+				Code:    provider.WarningModelAliased,
+				Message: fmt.Sprintf("model %q is an alias of this key for %q", model, target),
+				Param:   "model",
+			})
+		}
+		model = target
+	}
+	target, ok := r.ResolveModelAlias(model)
+	if ok {
+		addWarning(c, gatewayAliasWarning(model, target))
+	}
+	return target
+}
+
+// gatewayAliasWarning reports the substitution of a model_aliases entry
+func gatewayAliasWarning(model, target string) provider.Warning {
+	return provider.Warning{
+		Code:    provider.WarningModelAliased,
+		Message: fmt.Sprintf("model %q is an alias of the gateway for %q", model, target),
+		Param:   "model",
+	}
+}
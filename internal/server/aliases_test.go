@@ -0,0 +1,136 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+func TestModelAliases(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat", "demo-large"}}}}
+	cfg.Admin.Token = "admin"
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	keyStore := keys.NewMemoryStore()
+	_ = keyStore.Put(&keys.Key{ID: "team-a", Hash: keys.HashSecret("sk-a"), ModelAliases: map[string]string{"prod-chat": "demo-chat"}})
+	_ = keyStore.Put(&keys.Key{ID: "team-b", Hash: keys.HashSecret("sk-b")})
+	usageStore := usage.NewMemoryStore(10)
+	engine := gin.New()
+	RegisterRoutes(engine, r, cfg, m, admission.NewController(cfg, m), artifacts.NewMemoryStore(), usageStore,
+		keyStore, enrich.NewEnricher(cfg), tools.NewRuntime(cfg, m), NewRecentRequests(cfg), respcache.NewCache(), loadshed.New(cfg, m), nil, nil, nil, nil)
+	RegisterModelAliasRoutes(NewAdminRouter(engine, cfg), keyStore)
+
+	call := func(path string, header http.Header, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header = header
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	chat := `{"model":"prod-chat","messages":[{"role":"user","content":"hi"}]}`
+
+	w := call("/v1/chat/completions", http.Header{"Authorization": {"Bearer sk-a"}}, chat)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the alias resolved, got %d: %s", w.Code, w.Body)
+	}
+	var out OpenAIChatCompletionResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	if len(out.Warnings) != 1 || out.Warnings[0].Code != provider.WarningModelAliased {
+		t.Errorf("Expected an alias warning, got %+v", out.Warnings)
+	}
+	records, _ := usageStore.Query(time.Time{}, time.Now().Add(time.Hour))
+	if len(records) != 1 || records[0].Model != "demo-chat" || records[0].KeyID != "team-a" {
+		t.Errorf("Expected usage under the resolved model, got %+v", records)
+	}
+
+	// Aliases belong to their key
+	if w := call("/v1/chat/completions", http.Header{"Authorization": {"Bearer sk-b"}}, chat); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected another key's alias unknown, got %d", w.Code)
+	}
+	// Anthropic clients authenticate with X-Api-Key
+	msg := `{"model":"prod-chat","max_tokens":10,"messages":[{"role":"user","content":"hi"}]}`
+	if w := call("/v1/messages", http.Header{"X-Api-Key": {"sk-a"}}, msg); w.Code != http.StatusOK {
+		t.Errorf("Expected the alias resolved for X-Api-Key, got %d: %s", w.Code, w.Body)
+	}
+
+	admin := func(method, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/admin/keys/team-a/model-aliases", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	if w := admin(http.MethodPut, `{"model_aliases":{"prod-chat":""}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an alias without target rejected, got %d", w.Code)
+	}
+	if w := admin(http.MethodPut, `{"model_aliases":{"prod-chat":"demo-large"}}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected aliases replaced, got %d: %s", w.Code, w.Body)
+	}
+	if w := admin(http.MethodGet, ""); !strings.Contains(w.Body.String(), `"prod-chat":"demo-large"`) {
+		t.Errorf("Unexpected aliases %s", w.Body)
+	}
+	w = call("/v1/chat/completions", http.Header{"Authorization": {"Bearer sk-a"}}, chat)
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	if w.Code != http.StatusOK || !strings.Contains(out.Warnings[0].Message, "demo-large") {
+		t.Errorf("Expected the new target used, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestGatewayModelAliases(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat", "demo-large"}}}}
+	cfg.ModelAliases = map[string]string{"smart": "demo-large"}
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	keyStore := keys.NewMemoryStore()
+	_ = keyStore.Put(&keys.Key{ID: "team-a", Hash: keys.HashSecret("sk-a"), ModelAliases: map[string]string{"prod-chat": "smart"}})
+	usageStore := usage.NewMemoryStore(10)
+	engine := gin.New()
+	RegisterRoutes(engine, r, cfg, m, admission.NewController(cfg, m), artifacts.NewMemoryStore(), usageStore,
+		keyStore, enrich.NewEnricher(cfg), tools.NewRuntime(cfg, m), NewRecentRequests(cfg), respcache.NewCache(), loadshed.New(cfg, m), nil, nil, nil, nil)
+
+	tests := map[string]struct {
+		key, model string
+		warnings   int
+	}{
+		"gateway alias":          {model: "smart", warnings: 1},
+		"key alias of a gateway": {key: "sk-a", model: "prod-chat", warnings: 2},
+	}
+	for name, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"`+tt.model+`","messages":[{"role":"user","content":"hi"}]}`))
+		if tt.key != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		var out OpenAIChatCompletionResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		if w.Code != http.StatusOK || out.Model != "demo-large" || len(out.Warnings) != tt.warnings {
+			t.Errorf("%s: expected demo-large with %d warnings, got %d: %s", name, tt.warnings, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// defaultArtifactMaxBytes bounds uploads when artifacts.max_bytes is unset
+const defaultArtifactMaxBytes = 8 << 20
+
+// RegisterArtifactRoutes wires upload and retrieval of prompt artifacts.
+// Messages reference uploaded artifacts as "{{artifact:<id>}}". Uploads made
+// with a virtual key are claimed by that key for erasure requests.
+func RegisterArtifactRoutes(engine *gin.Engine, admin *AdminRouter, store artifacts.Store, keyStore keys.Store, cfg *config.Config) {
+	maxBytes := cfg.Artifacts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultArtifactMaxBytes
+	}
+
+	// POST /v1/artifacts accepts the fragment as the raw body, or as
+	// {"content": "..."} when sent as JSON
+	engine.POST("/v1/artifacts", func(c *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBytes+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("read body: %v", err)})
+			return
+		}
+		// Checked before decoding, as a truncated JSON body would not decode
+		if int64(len(body)) > maxBytes {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("artifact exceeds %d bytes", maxBytes)})
+			return
+		}
+		if strings.HasPrefix(c.ContentType(), "application/json") {
+			var in struct {
+				Content string `json:"content"`
+			}
+			if err := json.Unmarshal(body, &in); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+				return
+			}
+			body = []byte(in.Content)
+		}
+		if len(body) == 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "artifact content is empty"})
+			return
+		}
+
+		a, err := store.Put(body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if keyID := callerKeyID(c, keyStore); keyID != "" {
+			if err := store.Claim(a.ID, keyID); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		c.JSON(http.StatusCreated, a)
+	})
+
+	engine.GET("/v1/artifacts/:id", func(c *gin.Context) {
+		id := c.Param("id")
+		if !artifacts.ValidID(id) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid artifact id"})
+			return
+		}
+		if c.Query("content") != "true" {
+			a, err := store.Stat(id)
+			if err != nil {
+				abortArtifactError(c, err)
+				return
+			}
+			c.JSON(http.StatusOK, a)
+			return
+		}
+		content, err := store.Get(id)
+		if err != nil {
+			abortArtifactError(c, err)
+			return
+		}
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", content)
+	})
+
+	admin.DELETE("/artifacts/:id", func(c *gin.Context) {
+		if err := store.Delete(c.Param("id")); err != nil {
+			abortArtifactError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+func abortArtifactError(c *gin.Context, err error) {
+	if errors.Is(err, artifacts.ErrNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// expandReferences expands the artifact and file references in message
+// content before the request is dispatched. Files are only expanded when
+// fileStore is set.
+func expandReferences(ctx context.Context, store artifacts.Store, fileStore *files.Store, req *provider.StandardRequest) error {
+	if err := expandArtifacts(store, req); err != nil {
+		return err
+	}
+	if fileStore == nil {
+		return nil
+	}
+	// Files are attached to messages of the key owning them
+	caller, _ := provider.CallerFrom(ctx)
+	for i := range req.Messages {
+		content := req.Messages[i].Content
+		if !files.HasReferences(content) {
+			continue
+		}
+		expanded, err := fileStore.Expand(ctx, caller.KeyID, content)
+		if err != nil {
+			return fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		req.Messages[i].Content = expanded
+	}
+	return nil
+}
+
+// referenceErrorCode is the error code of a request refused for a
+// reference that cannot be expanded, empty for other errors
+func referenceErrorCode(err error) string {
+	var missing *artifacts.MissingError
+	var fileErr *files.ReferenceError
+	switch {
+	case errors.As(err, &missing):
+		return "artifact_not_found"
+	case errors.As(err, &fileErr):
+		return "invalid_file_reference"
+	}
+	return ""
+}
+
+// expandArtifacts replaces artifact references in message content with the
+// stored fragments before the request is dispatched
+func expandArtifacts(store artifacts.Store, req *provider.StandardRequest) error {
+	for i := range req.Messages {
+		content := req.Messages[i].Content
+		if !artifacts.HasReferences(content) {
+			continue
+		}
+		expanded, err := artifacts.Expand(store, content)
+		if err != nil {
+			return fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		req.Messages[i].Content = expanded
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+)
+
+func TestArtifactUploadSize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Artifacts.MaxBytes = 16
+	engine := gin.New()
+	RegisterArtifactRoutes(engine, NewAdminRouter(engine, cfg), artifacts.NewMemoryStore(), keys.NewMemoryStore(), cfg)
+
+	for name, tt := range map[string]struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		"raw":           {"text/plain", "short fragment", http.StatusCreated},
+		"raw too large": {"text/plain", strings.Repeat("a", 17), http.StatusRequestEntityTooLarge},
+		"json":          {"application/json", `{"content":"ok"}`, http.StatusCreated},
+		// The envelope is cut short when read, so must not be decoded
+		"json too large": {"application/json", fmt.Sprintf(`{"content":%q}`, strings.Repeat("a", 32)), http.StatusRequestEntityTooLarge},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/artifacts", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d: %s", name, tt.status, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+)
+
+// RegisterKeyAuth requires a virtual key on every API request when
+// require_keys is set. It must run before any API route is registered, as
+// gin only applies middleware to routes added after it.
+func RegisterKeyAuth(engine *gin.Engine, cfg *config.Config, keyStore keys.Store) {
+	if cfg.RequireKeys {
+		engine.Use(requireKey(keyStore))
+	}
+}
+
+// requireKey refuses /v1 and /v1beta requests whose credential is not an
+// enabled virtual key. The key found is kept on the context, where usage,
+// limits and routing look it up.
+func requireKey(keyStore keys.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !strings.HasPrefix(path, "/v1/") && !strings.HasPrefix(path, "/v1beta/") {
+			c.Next()
+			return
+		}
+		if msg, code := checkKey(callerKey(c, keyStore)); msg != "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": msg, "code": code})
+			return
+		}
+		c.Next()
+	}
+}
+
+// checkKey returns why a request made with k is refused, and its error
+// code, or "" when k may be used
+func checkKey(k *keys.Key) (string, string) {
+	switch {
+	case k == nil:
+		return "a valid API key is required", "invalid_api_key"
+	case k.Disabled:
+		return "API key is disabled", "api_key_disabled"
+	case k.Expired(time.Now()):
+		return "API key has expired", "api_key_expired"
+	}
+	return "", ""
+}
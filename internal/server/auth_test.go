@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+)
+
+func TestRequireKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := keys.NewMemoryStore()
+	_ = store.Put(&keys.Key{ID: "team-a", Hash: keys.HashSecret("sk-a")})
+	_ = store.Put(&keys.Key{ID: "revoked", Hash: keys.HashSecret("sk-revoked"), Disabled: true})
+
+	engine := gin.New()
+	RegisterKeyAuth(engine, &config.Config{RequireKeys: true}, store)
+	engine.GET("/v1/models", func(c *gin.Context) { c.String(http.StatusOK, callerKeyID(c, store)) })
+	engine.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, tc := range []struct {
+		path, header, value string
+		want                int
+		body                string
+	}{
+		{"/v1/models", "Authorization", "Bearer sk-a", http.StatusOK, "team-a"},
+		{"/v1/models", "X-Api-Key", "sk-a", http.StatusOK, "team-a"},
+		{"/v1/models", "", "", http.StatusUnauthorized, "invalid_api_key"},
+		{"/v1/models", "Authorization", "Bearer sk-unknown", http.StatusUnauthorized, "invalid_api_key"},
+		{"/v1/models", "Authorization", "Bearer sk-revoked", http.StatusUnauthorized, "api_key_disabled"},
+		{"/healthz", "", "", http.StatusOK, ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != tc.want || (tc.body != "" && !strings.Contains(w.Body.String(), tc.body)) {
+			t.Errorf("%s %s %q: expected %d %q, got %d: %s", tc.path, tc.header, tc.value, tc.want, tc.body, w.Code, w.Body.String())
+		}
+	}
+}
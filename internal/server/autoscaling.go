@@ -0,0 +1,16 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/autoscale"
+)
+
+// RegisterAutoscalingRoutes exposes the latest autoscaling signals so that
+// external autoscalers can poll them instead of scraping /metrics
+func RegisterAutoscalingRoutes(engine *gin.Engine, signaler *autoscale.Signaler) {
+	engine.GET("/autoscaling/signals", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"signals": signaler.Signals()})
+	})
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/batch"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+)
+
+// batchKeyIDContextKey carries the key a batch request runs as, in place of
+// the Authorization header the gateway does not keep
+type batchKeyIDContextKey struct{}
+
+// batchExecutor runs batch requests through the engine, so they are routed,
+// admitted and accounted for like any other request
+type batchExecutor struct {
+	engine *gin.Engine
+}
+
+// NewBatchExecutor returns the batch.Executor serving requests with engine
+func NewBatchExecutor(engine *gin.Engine) batch.Executor {
+	return &batchExecutor{engine: engine}
+}
+
+// Execute sends one request to endpoint as keyID
+func (e *batchExecutor) Execute(ctx context.Context, keyID, endpoint string, body []byte) (int, string, []byte) {
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, batchKeyIDContextKey{}, keyID), http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return http.StatusInternalServerError, "", []byte(strconv.Quote(err.Error()))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	w := &bufferedResponse{header: make(http.Header)}
+	e.engine.ServeHTTP(w, req)
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.status, w.header.Get(requestIDHeader), w.body.Bytes()
+}
+
+// bufferedResponse is an http.ResponseWriter that keeps the response in memory
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponse) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponse) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *bufferedResponse) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// batchErrorStatus maps batch errors to HTTP statuses
+func batchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, files.ErrNotFound), errors.Is(err, batch.ErrBatchNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, batch.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, batch.ErrFinished):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// RegisterBatchRoutes wires the OpenAI-compatible batches API. Batches
+// belong to the virtual key that created them and are invisible to other
+// callers; their input and output files are served by the files API.
+func RegisterBatchRoutes(engine *gin.Engine, store batch.Store, runner *batch.Runner, keyStore keys.Store) {
+	ownBatch := func(c *gin.Context) (*batch.Batch, bool) {
+		b, err := store.Get(c.Param("id"))
+		if err == nil && b.KeyID != callerKeyID(c, keyStore) {
+			err = batch.ErrBatchNotFound
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(batchErrorStatus(err), gin.H{"error": err.Error()})
+			return nil, false
+		}
+		return b, true
+	}
+
+	engine.POST("/v1/batches", func(c *gin.Context) {
+		var in struct {
+			InputFileID      string            `json:"input_file_id"`
+			Endpoint         string            `json:"endpoint"`
+			CompletionWindow string            `json:"completion_window"`
+			Metadata         map[string]string `json:"metadata"`
+		}
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+			return
+		}
+		if in.InputFileID == "" || in.Endpoint == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "input_file_id and endpoint are required"})
+			return
+		}
+		if in.CompletionWindow != batch.CompletionWindow {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("completion_window must be %q", batch.CompletionWindow)})
+			return
+		}
+
+		b, err := runner.Create(callerKeyID(c, keyStore), in.Endpoint, in.InputFileID, in.Metadata)
+		if err != nil {
+			c.AbortWithStatusJSON(batchErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, b)
+	})
+
+	engine.GET("/v1/batches", func(c *gin.Context) {
+		limit := 20
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 100 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+				return
+			}
+			limit = n
+		}
+		// One more than asked tells whether there are more
+		batches, err := store.List(callerKeyID(c, keyStore), c.Query("after"), limit+1)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		hasMore := len(batches) > limit
+		if hasMore {
+			batches = batches[:limit]
+		}
+		out := gin.H{"object": "list", "data": batches, "has_more": hasMore, "first_id": nil, "last_id": nil}
+		if len(batches) > 0 {
+			out["first_id"], out["last_id"] = batches[0].ID, batches[len(batches)-1].ID
+		} else {
+			out["data"] = []*batch.Batch{}
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	engine.GET("/v1/batches/:id", func(c *gin.Context) {
+		if b, ok := ownBatch(c); ok {
+			c.JSON(http.StatusOK, b)
+		}
+	})
+
+	engine.POST("/v1/batches/:id/cancel", func(c *gin.Context) {
+		b, ok := ownBatch(c)
+		if !ok {
+			return
+		}
+		b, err := runner.Cancel(b.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(batchErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, b)
+	})
+}
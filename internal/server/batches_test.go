@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/batch"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestBatchAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}}}}
+	cfg.Batches.Concurrency = 2
+	cfg.Batches.MaxRequests = 100
+	cfg.Batches.MaxFileBytes = 1 << 20
+	cfg.Batches.PollInterval = time.Hour
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	keyStore := keys.NewMemoryStore()
+	_ = keyStore.Put(&keys.Key{ID: "key_owner", Hash: keys.HashSecret("sk-owner")})
+	_ = keyStore.Put(&keys.Key{ID: "key_other", Hash: keys.HashSecret("sk-other")})
+	usageStore := usage.NewMemoryStore(10)
+
+	engine := gin.New()
+	engine.Use(assignRequestID())
+	RegisterRoutes(engine, r, cfg, m, admission.NewController(cfg, m), artifacts.NewMemoryStore(), usageStore,
+		keyStore, enrich.NewEnricher(cfg), tools.NewRuntime(cfg, m), NewRecentRequests(cfg), respcache.NewCache(), loadshed.New(cfg, m), nil, nil, nil, nil)
+	store := batch.NewMemoryStore()
+	fileStore := files.NewStore(files.NewMemoryMetadata(), files.NewMemoryBlobs())
+	lc := fxtest.NewLifecycle(t)
+	runner := batch.NewRunner(lc, cfg, store, fileStore, NewBatchExecutor(engine), m)
+	RegisterFileRoutes(engine, cfg, fileStore, keyStore)
+	RegisterBatchRoutes(engine, store, runner, keyStore)
+	lc.RequireStart()
+	defer lc.RequireStop()
+
+	call := func(method, path, token, contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// Upload the input file
+	input := `{"custom_id":"ok","method":"POST","url":"/v1/chat/completions","body":{"model":"demo-chat","messages":[{"role":"user","content":"hello batch"}]}}
+{"custom_id":"unrouted","method":"POST","url":"/v1/chat/completions","body":{"model":"nobody-serves-this","messages":[{"role":"user","content":"hi"}]}}
+`
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	_ = mw.WriteField("purpose", "batch")
+	fw, _ := mw.CreateFormFile("file", "requests.jsonl")
+	_, _ = fw.Write([]byte(input))
+	_ = mw.Close()
+	w := call(http.MethodPost, "/v1/files", "sk-owner", mw.FormDataContentType(), form.Bytes())
+	if w.Code != http.StatusOK {
+		t.Fatalf("Upload failed: %d %s", w.Code, w.Body)
+	}
+	var file files.File
+	_ = json.Unmarshal(w.Body.Bytes(), &file)
+	if !strings.HasPrefix(file.ID, "file-") || file.Bytes != len(input) || file.Purpose != "batch" {
+		t.Errorf("Unexpected file %+v", file)
+	}
+
+	// Create the batch
+	body := fmt.Sprintf(`{"input_file_id":%q,"endpoint":"/v1/chat/completions","completion_window":"24h","metadata":{"job":"nightly"}}`, file.ID)
+	if w := call(http.MethodPost, "/v1/batches", "sk-other", "application/json", []byte(body)); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another key's file to be invisible, got %d", w.Code)
+	}
+	w = call(http.MethodPost, "/v1/batches", "sk-owner", "application/json", []byte(body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Create failed: %d %s", w.Code, w.Body)
+	}
+	var b batch.Batch
+	_ = json.Unmarshal(w.Body.Bytes(), &b)
+	if b.Status != batch.StatusValidating || b.Metadata["job"] != "nightly" {
+		t.Errorf("Unexpected new batch %+v", b)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for b.Status != batch.StatusCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("Batch did not complete: %+v", b)
+		}
+		time.Sleep(10 * time.Millisecond)
+		w = call(http.MethodGet, "/v1/batches/"+b.ID, "sk-owner", "", nil)
+		_ = json.Unmarshal(w.Body.Bytes(), &b)
+	}
+	if b.RequestCounts != (batch.RequestCounts{Total: 2, Completed: 1, Failed: 1}) || b.OutputFileID == nil || b.ErrorFileID == nil {
+		t.Fatalf("Unexpected finished batch %+v", b)
+	}
+	if w := call(http.MethodGet, "/v1/batches/"+b.ID, "sk-other", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another key's batch to be invisible, got %d", w.Code)
+	}
+
+	w = call(http.MethodGet, "/v1/files/"+*b.OutputFileID+"/content", "sk-owner", "", nil)
+	var res batch.Result
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Bad output %q: %v", w.Body, err)
+	}
+	var completion OpenAIChatCompletionResponse
+	_ = json.Unmarshal(res.Response.Body, &completion)
+	if res.CustomID != "ok" || res.Response.StatusCode != 200 || res.Response.RequestID == "" || completion.Choices[0].Message.Content == "" {
+		t.Errorf("Unexpected output line %+v", res)
+	}
+	w = call(http.MethodGet, "/v1/files/"+*b.ErrorFileID+"/content", "sk-owner", "", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &res)
+	if res.CustomID != "unrouted" || res.Response.StatusCode != http.StatusBadRequest || res.Error == nil {
+		t.Errorf("Unexpected error line %+v", res)
+	}
+
+	// Batch requests are accounted to the batch's key
+	records, _ := usageStore.Query(time.Time{}, time.Now().Add(time.Minute))
+	if len(records) != 2 || records[0].KeyID != "key_owner" {
+		t.Errorf("Expected 2 usage records for key_owner, got %+v", records)
+	}
+
+	w = call(http.MethodGet, "/v1/batches?limit=5", "sk-owner", "", nil)
+	var list struct {
+		Data    []batch.Batch `json:"data"`
+		HasMore bool          `json:"has_more"`
+		FirstID string        `json:"first_id"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) != 1 || list.FirstID != b.ID || list.HasMore {
+		t.Errorf("Unexpected list %s", w.Body)
+	}
+	if w := call(http.MethodGet, "/v1/batches", "sk-other", "", nil); !strings.Contains(w.Body.String(), `"data":[]`) {
+		t.Errorf("Expected an empty list for another key, got %s", w.Body)
+	}
+
+	if w := call(http.MethodPost, "/v1/batches/"+b.ID+"/cancel", "sk-owner", "", nil); w.Code != http.StatusConflict {
+		t.Errorf("Expected a finished batch not to be cancellable, got %d", w.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/budget"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+)
+
+// budgetList is the GET /admin/budgets response
+type budgetList struct {
+	Object string          `json:"object"`
+	Data   []budget.Status `json:"data"`
+}
+
+// RegisterBudgets refuses API requests whose key has exhausted a budget
+// and counts the cost of the others toward it. Like RegisterKeyAuth it must
+// run before any API route is registered.
+func RegisterBudgets(engine *gin.Engine, tracker *budget.Tracker, keyStore keys.Store) {
+	if tracker.Enabled() {
+		engine.Use(enforceBudgets(tracker, keyStore))
+	}
+}
+
+// RegisterBudgetAdminRoutes reports the spend of every budget
+func RegisterBudgetAdminRoutes(admin *AdminRouter, tracker *budget.Tracker) {
+	admin.GET("/budgets", func(c *gin.Context) {
+		statuses, err := tracker.Statuses(c.Request.Context())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, budgetList{Object: "list", Data: statuses})
+	})
+}
+
+// enforceBudgets checks /v1 and /v1beta requests against the budgets of
+// their key and, once served, spends the cost kept for their usage record
+func enforceBudgets(tracker *budget.Tracker, keyStore keys.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !strings.HasPrefix(path, "/v1/") && !strings.HasPrefix(path, "/v1beta/") {
+			c.Next()
+			return
+		}
+		k := callerKey(c, keyStore)
+		if err := tracker.Check(c.Request.Context(), k); err != nil {
+			var exceeded *budget.ExceededError
+			if errors.As(err, &exceeded) {
+				c.Header("Retry-After", strconv.Itoa(budgetRetryAfter(exceeded)))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": "budget_exceeded"})
+				return
+			}
+		}
+		c.Next()
+
+		if v, ok := c.Get(costKey); ok {
+			// Counted even when the client went away before the end
+			tracker.Spend(context.WithoutCancel(c.Request.Context()), k, v.(float64))
+		}
+	}
+}
+
+// budgetRetryAfter is the Retry-After of a request refused for an
+// exhausted budget: the seconds until its period ends
+func budgetRetryAfter(e *budget.ExceededError) int {
+	return max(1, int(math.Ceil(time.Until(e.Reset).Seconds())))
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/budget"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+func TestBudgets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		Budgets: []config.BudgetConfig{{Key: "team-a", DailyUSD: 0.5}},
+	}
+	store := keys.NewMemoryStore()
+	_ = store.Put(&keys.Key{ID: "team-a", Hash: keys.HashSecret("sk-a")})
+	tracker, err := budget.NewTracker(cfg, metrics.NewRegistry(), nil, usage.NewMemoryStore(10), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := gin.New()
+	RegisterBudgets(engine, tracker, store)
+	// Each request spends $0.30
+	engine.POST("/v1/chat/completions", func(c *gin.Context) {
+		setRequestModel(c, "demo-chat")
+		c.Set(costKey, 0.3)
+		c.Status(http.StatusOK)
+	})
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		req.Header.Set("Authorization", "Bearer sk-a")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	for i := 0; i < 2; i++ {
+		if w := send(); w.Code != http.StatusOK {
+			t.Fatalf("Request %d within the budget refused: %d %s", i, w.Code, w.Body.String())
+		}
+	}
+	w := send()
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), "budget_exceeded") {
+		t.Fatalf("Expected the exhausted budget to refuse, got %d %s", w.Code, w.Body.String())
+	}
+	if secs, _ := strconv.Atoi(w.Header().Get("Retry-After")); secs < 1 || secs > 86400 {
+		t.Errorf("Expected a Retry-After until the end of the day, got %q", w.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestChatResponseCache(t *testing.T) {
+	cfg := &config.Config{
+		Mock: []config.MockConfig{{Name: "demo"}},
+		Routes: []config.Route{
+			{Prefix: "cached-", Provider: "demo", Cache: &config.RouteCache{TTL: time.Minute, MaxTTL: time.Hour}},
+			{Prefix: "opt-in-", Provider: "demo", Cache: &config.RouteCache{MaxTTL: time.Hour}},
+			{Prefix: "plain-", Provider: "demo"},
+		},
+	}
+	engine, m, _ := newChatTestServer(t, cfg)
+
+	// chat returns the response ID, which only repeats on cache hits, and
+	// the cache result header
+	chat := func(model, cacheControl string) (string, string) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"`+model+`","messages":[{"role":"user","content":"hi"}]}`))
+		if cacheControl != "" {
+			req.Header.Set("Cache-Control", cacheControl)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", model, w.Code, w.Body)
+		}
+		var out OpenAIChatCompletionResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return out.ID, w.Header().Get("X-LetLLM-Cache")
+	}
+
+	first, result := chat("cached-a", "")
+	if result != "miss" {
+		t.Errorf("Expected a miss, got %q", result)
+	}
+	if id, result := chat("cached-a", ""); id != first || result != "hit" {
+		t.Errorf("Expected a hit on %s, got %s %q", first, id, result)
+	}
+	if id, result := chat("cached-a", "no-cache"); id == first || result != "bypass" {
+		t.Errorf("no-cache should skip the cache, got %s %q", id, result)
+	}
+	// The no-cache answer replaced the cached one
+	refreshed, _ := chat("cached-a", "")
+	if refreshed == first {
+		t.Error("Expected the no-cache response to be stored")
+	}
+	if id, _ := chat("cached-b", "no-store"); id == refreshed {
+		t.Error("no-store must not be answered from the cache")
+	}
+	if _, result := chat("cached-b", ""); result != "miss" {
+		t.Errorf("no-store responses must not be stored, got %q", result)
+	}
+
+	if _, result := chat("opt-in-a", ""); result != "bypass" {
+		t.Errorf("Expected opt-in routes to skip clients without max-age, got %q", result)
+	}
+	optIn, _ := chat("opt-in-a", "max-age=60")
+	if id, result := chat("opt-in-a", "max-age=60"); id != optIn || result != "hit" {
+		t.Errorf("Expected max-age to opt in, got %s %q", id, result)
+	}
+
+	plain, result := chat("plain-a", "max-age=60")
+	if id, _ := chat("plain-a", "max-age=60"); id == plain || result != "" {
+		t.Errorf("Routes without caching must ignore max-age, got %q", result)
+	}
+	if hits := m.Counter("letllm_response_cache_requests_total", "", "result").Value("hit"); hits != 3 {
+		t.Errorf("Expected 3 hits counted, got %v", hits)
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+)
+
+// armKey holds whether a route's canary or baseline serves the request
+const armKey = "letllm.route_arm"
+
+// setRequestArm records the arm Route picked, if the route has a canary
+func setRequestArm(c *gin.Context, arm string) {
+	if arm != "" {
+		c.Set(armKey, arm)
+	}
+}
+
+// countRouteArms counts the requests served on routes with a canary and
+// their duration by arm, so a canary can be compared with its baseline
+func countRouteArms(m *metrics.Registry) gin.HandlerFunc {
+	requests := m.Counter("letllm_route_arm_requests_total",
+		"Requests on routes with a canary by arm (canary or baseline) and status class.",
+		"model", "provider", "arm", "status")
+	seconds := m.Counter("letllm_route_arm_request_seconds_total",
+		"Time spent serving requests on routes with a canary by arm.",
+		"model", "provider", "arm")
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		arm := c.GetString(armKey)
+		if arm == "" {
+			return
+		}
+		model, providerName := c.GetString(modelKey), c.GetString(providerNameKey)
+		requests.Inc(model, providerName, arm, strconv.Itoa(c.Writer.Status()/100)+"xx")
+		seconds.Add(time.Since(start).Seconds(), model, providerName, arm)
+	}
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/compare"
+)
+
+// RegisterCompareRoutes wires side-by-side comparison of two models, used
+// to check a default-model upgrade before rolling it out
+func RegisterCompareRoutes(admin *AdminRouter, comparer *compare.Comparer) {
+	admin.POST("/compare", func(c *gin.Context) {
+		var in compare.Request
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+			return
+		}
+		report, err := comparer.Compare(c.Request.Context(), &in)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	})
+}
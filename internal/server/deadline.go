@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timeoutHeader carries the longest the client will wait for a request, in
+// seconds ("30", "2.5") or as a Go duration ("1m30s")
+const timeoutHeader = "X-LetLLM-Timeout"
+
+// applyClientTimeout sets the deadline of requests carrying
+// X-LetLLM-Timeout, which the upstream calls they make inherit, so work the
+// client will not wait for is abandoned
+func applyClientTimeout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v := c.GetHeader(timeoutHeader)
+		if v == "" {
+			c.Next()
+			return
+		}
+		d, ok := parseClientTimeout(v)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": timeoutHeader + " must be a positive number of seconds or a duration such as 30s", "code": "invalid_timeout"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+func parseClientTimeout(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		secs, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(secs > 0) || secs > float64(1<<62)/float64(time.Second) {
+			return 0, false
+		}
+		d = time.Duration(secs * float64(time.Second))
+	}
+	return d, d > 0
+}
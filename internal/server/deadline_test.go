@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestApplyClientTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(applyClientTimeout())
+	engine.GET("/", func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		if !ok {
+			c.String(http.StatusOK, "none")
+			return
+		}
+		c.String(http.StatusOK, time.Until(deadline).Round(100*time.Millisecond).String())
+	})
+
+	for header, want := range map[string]string{
+		"":      "none",
+		"30":    "30s",
+		"2.5":   "2.5s",
+		"1m30s": "1m30s",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(timeoutHeader, header)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("%q: expected deadline in %s, got %d %s", header, want, w.Code, w.Body.String())
+		}
+	}
+	for _, header := range []string{"0", "-5", "soon", "NaN"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(timeoutHeader, header)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", header, w.Code)
+		}
+	}
+}
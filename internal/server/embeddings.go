@@ -0,0 +1,157 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// OpenAIEmbeddingRequest is the body of POST /v1/embeddings
+type OpenAIEmbeddingRequest struct {
+	Model string `json:"model"`
+	// A string or an array of strings
+	Input      json.RawMessage `json:"input"`
+	Dimensions *int            `json:"dimensions,omitempty"`
+	// "float" (default) or "base64"
+	EncodingFormat string `json:"encoding_format,omitempty"`
+	User           string `json:"user,omitempty"`
+}
+
+type OpenAIEmbeddingResponse struct {
+	Object string            `json:"object"`
+	Data   []OpenAIEmbedding `json:"data"`
+	Model  string            `json:"model"`
+	Usage  OpenAIEmbedUsage  `json:"usage"`
+}
+
+type OpenAIEmbedding struct {
+	Object string `json:"object"`
+	Index  int    `json:"index"`
+	// []float32, or a base64 string of little-endian float32s
+	Embedding interface{} `json:"embedding"`
+}
+
+type OpenAIEmbedUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// embeddingInputs decodes input, which OpenAI accepts as a string or an
+// array of strings. Token arrays are not supported.
+func embeddingInputs(raw json.RawMessage) ([]string, error) {
+	var one string
+	if err := json.Unmarshal(raw, &one); err == nil {
+		return []string{one}, nil
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return nil, fmt.Errorf("input must be a string or an array of strings")
+	}
+	return many, nil
+}
+
+// encodeEmbedding returns v in the requested encoding format
+func encodeEmbedding(v []float32, format string) interface{} {
+	if format != "base64" {
+		return v
+	}
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// embeddingsHandler serves POST /v1/embeddings, routed by model like chat
+// completions
+func embeddingsHandler(r *provider.Router, adm *admission.Controller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in OpenAIEmbeddingRequest
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+			return
+		}
+		if in.Model == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "model is required"})
+			return
+		}
+		in.Model = resolveModelAlias(c, r, in.Model)
+		if in.EncodingFormat != "" && in.EncodingFormat != "float" && in.EncodingFormat != "base64" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown encoding_format %q", in.EncodingFormat)})
+			return
+		}
+		inputs, err := embeddingInputs(in.Input)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req := &provider.EmbeddingRequest{Model: in.Model, Input: inputs, Dimensions: in.Dimensions}
+		if err := provider.ValidateEmbeddingRequest(req); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		setRequestModel(c, in.Model)
+
+		attrs := requestAttributes(c)
+		routeReq := &provider.RouteRequest{Model: in.Model, Endpoint: c.FullPath(), Headers: routeHeaders(c.Request.Header), Attributes: attrs}
+		p, err := r.Route(routeReq)
+		if err != nil {
+			var policyErr *provider.PolicyError
+			if errors.As(err, &policyErr) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": policyErr.Code})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		setRequestProvider(c, p)
+		setRequestArm(c, routeReq.Arm)
+
+		release, err := adm.Acquire(c.Request.Context(), p.GetInfo().Name, in.Model, attrs)
+		if err != nil {
+			var rejected *admission.RejectedError
+			if errors.As(err, &rejected) {
+				c.Header("Retry-After", strconv.Itoa(rejected.RetryAfterSeconds()))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": "admission_" + rejected.Reason})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		defer release()
+
+		req.Model = upstreamModel(routeReq)
+		resp, err := p.Embed(c.Request.Context(), req)
+		if errors.Is(err, provider.ErrEmbeddingsUnsupported) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "embeddings_unsupported"})
+			return
+		}
+		r.ReportOutcome(p.GetInfo().Name, err)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		setTokenUsage(c, resp.Usage)
+		setResponseCost(c, r, resp.Usage, nil)
+
+		out := OpenAIEmbeddingResponse{
+			Object: "list",
+			Data:   make([]OpenAIEmbedding, len(resp.Data)),
+			Model:  clientModel(routeReq, resp.Model),
+			Usage:  OpenAIEmbedUsage{PromptTokens: resp.Usage.PromptTokens, TotalTokens: resp.Usage.TotalTokens},
+		}
+		for i, e := range resp.Data {
+			out.Data[i] = OpenAIEmbedding{Object: "embedding", Index: e.Index, Embedding: encodeEmbedding(e.Embedding, in.EncodingFormat)}
+		}
+		c.JSON(http.StatusOK, out)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+func TestEmbeddingsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-embed"}}}}
+	cfg.Cohere.APIKey = "test-key"
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	engine.POST("/v1/embeddings", embeddingsHandler(r, admission.NewController(cfg, metrics.NewRegistry())))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(body)))
+		return w
+	}
+
+	var floats OpenAIEmbeddingResponse
+	w := post(`{"model":"demo-embed","input":["hello world","bye"],"dimensions":4}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &floats); err != nil {
+		t.Fatal(err)
+	}
+	if floats.Object != "list" || len(floats.Data) != 2 || floats.Data[1].Index != 1 || floats.Usage.PromptTokens != 3 {
+		t.Fatalf("Unexpected response %s", w.Body)
+	}
+	first, _ := floats.Data[0].Embedding.([]interface{})
+	if len(first) != 4 {
+		t.Fatalf("Expected 4 dimensions, got %v", floats.Data[0].Embedding)
+	}
+
+	// A single string input, base64 encoded as the OpenAI SDKs request
+	var encoded OpenAIEmbeddingResponse
+	w = post(`{"model":"demo-embed","input":"hello world","dimensions":4,"encoding_format":"base64"}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &encoded); err != nil || len(encoded.Data) != 1 {
+		t.Fatalf("Unexpected response %d %s", w.Code, w.Body)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded.Data[0].Embedding.(string))
+	if err != nil || len(raw) != 16 {
+		t.Fatalf("Invalid base64 embedding: %v", err)
+	}
+	for i, want := range first {
+		got := math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+		if got != float32(want.(float64)) {
+			t.Errorf("Component %d: base64 %v, float %v", i, got, want)
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		body string
+		code int
+	}{
+		"token arrays":   {`{"model":"demo-embed","input":[[1,2,3]]}`, http.StatusBadRequest},
+		"empty input":    {`{"model":"demo-embed","input":[]}`, http.StatusBadRequest},
+		"no model":       {`{"input":"hi"}`, http.StatusBadRequest},
+		"bad encoding":   {`{"model":"demo-embed","input":"hi","encoding_format":"int8"}`, http.StatusBadRequest},
+		"unrouted model": {`{"model":"nope","input":"hi"}`, http.StatusBadRequest},
+		"no embeddings":  {`{"model":"command-r","input":"hi"}`, http.StatusBadRequest},
+	} {
+		if w := post(tc.body); w.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d: %s", name, tc.code, w.Code, w.Body)
+		}
+	}
+	if w := post(`{"model":"command-r","input":"hi"}`); !strings.Contains(w.Body.String(), `"code":"embeddings_unsupported"`) {
+		t.Errorf("Expected embeddings_unsupported, got %s", w.Body)
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+)
+
+// attributesKey holds the request's enrichment attributes
+const attributesKey = "letllm.attributes"
+
+// enrichRequest attaches attributes from the identity service before the
+// request is routed. When the service fails the request is rejected
+// unless failOpen is set, in which case it proceeds without attributes.
+func enrichRequest(e *enrich.Enricher, failOpen bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !e.Enabled() {
+			return
+		}
+		attrs, err := e.Enrich(c.Request.Context(), c.Request.Header)
+		if err != nil {
+			if !failOpen {
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "code": "enrichment_unavailable"})
+				return
+			}
+			log.Printf("request %s: enrichment: %v", requestID(c), err)
+		}
+		c.Set(attributesKey, attrs)
+	}
+}
+
+// requestAttributes returns the attributes attached by enrichRequest
+func requestAttributes(c *gin.Context) map[string]string {
+	if v, ok := c.Get(attributesKey); ok {
+		attrs, _ := v.(map[string]string)
+		return attrs
+	}
+	return nil
+}
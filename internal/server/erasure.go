@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/erasure"
+)
+
+// RegisterErasureRoutes wires data subject erasure for GDPR requests.
+// Subjects are virtual key IDs.
+func RegisterErasureRoutes(admin *AdminRouter, eraser *erasure.Eraser) {
+	// DELETE /admin/data/subject/:id?delete_key=true&reference=<ticket>
+	admin.DELETE("/data/subject/:id", func(c *gin.Context) {
+		rec, err := eraser.Erase(c.Param("id"), erasure.Options{
+			DeleteKey: c.Query("delete_key") == "true",
+			Reference: c.Query("reference"),
+		})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, rec)
+	})
+
+	admin.GET("/data/erasures", func(c *gin.Context) {
+		records, err := eraser.History()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if records == nil {
+			records = []*erasure.Record{}
+		}
+		c.JSON(http.StatusOK, gin.H{"erasures": records})
+	})
+}
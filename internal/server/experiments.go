@@ -0,0 +1,35 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/experiments"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// Response headers naming the experiment and variant serving a request
+const (
+	experimentHeader = "X-LetLLM-Experiment"
+	variantHeader    = "X-LetLLM-Variant"
+)
+
+// assignExperiment applies the variant of any experiment on req's model
+// the caller, identified by user or else its key, is bucketed into. The
+// assignment is returned in response headers and kept for the usage record.
+func assignExperiment(c *gin.Context, assigner *experiments.Assigner, keyStore keys.Store, user string, routeReq *provider.RouteRequest, req *provider.StandardRequest) {
+	caller := user
+	if caller == "" {
+		caller = callerKeyID(c, keyStore)
+	}
+	a, ok := assigner.Assign(req.Model, caller)
+	if !ok {
+		return
+	}
+	a.Apply(req)
+	routeReq.Model, routeReq.Provider = req.Model, a.Provider()
+	routeReq.Needs = provider.NeedsOf(req)
+	setRequestModel(c, req.Model)
+	c.Set(experimentKey, a)
+	c.Header(experimentHeader, a.Experiment)
+	c.Header(variantHeader, a.Variant)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// RegisterFailoverAdminRoutes wires inspection and switching of
+// active/standby provider pairs, e.g. ahead of upstream maintenance
+func RegisterFailoverAdminRoutes(admin *AdminRouter, r *provider.Router) {
+	admin.GET("/failover", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"pairs": r.FailoverStates()})
+	})
+
+	// POST /admin/failover/:provider?to=standby|active
+	admin.POST("/failover/:provider", func(c *gin.Context) {
+		var toStandby bool
+		switch to := c.Query("to"); to {
+		case "standby":
+			toStandby = true
+		case "active":
+		default:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": `to must be "standby" or "active"`})
+			return
+		}
+
+		st, err := r.SetFailover(c.Param("provider"), toStandby)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, st)
+	})
+}
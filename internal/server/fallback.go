@@ -0,0 +1,81 @@
+package server
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// fallbackHeader names the provider that served a request after the one
+// routed to refused it
+const fallbackHeader = "X-LetLLM-Fallback"
+
+// filterFallbacks retries chat completions refused by a content filter on
+// their route's content_filter_fallback
+type filterFallbacks struct {
+	r        *provider.Router
+	requests *metrics.CounterVec
+}
+
+func newFilterFallbacks(r *provider.Router, m *metrics.Registry) *filterFallbacks {
+	return &filterFallbacks{
+		r: r,
+		requests: m.Counter("letllm_content_filter_fallbacks_total",
+			"Refused chat completions retried on a route's content_filter_fallback by result: ok, refused or error.",
+			"provider", "result"),
+	}
+}
+
+// retry returns resp unless it was refused by a content filter and the
+// route routeReq matches has a fallback, in which case req is generated
+// again there. The fallback's answer is returned when it is not refused
+// too, and the refusal otherwise.
+func (f *filterFallbacks) retry(c *gin.Context, routeReq *provider.RouteRequest, req *provider.StandardRequest, resp *provider.StandardResponse,
+	generate func(provider.Provider, *provider.StandardRequest) (*provider.StandardResponse, error)) *provider.StandardResponse {
+	if !contentFiltered(resp) {
+		return resp
+	}
+	p, model, ok := f.r.ContentFilterFallback(routeReq)
+	if !ok {
+		return resp
+	}
+	name := p.GetInfo().Name
+
+	retried := *req
+	retried.Model = model
+	fb, err := generate(p, &retried)
+	f.r.ReportOutcome(name, err)
+	switch {
+	case err != nil:
+		f.requests.Inc(name, "error")
+		log.Printf("request %s: content filter fallback %s: %v", requestID(c), name, err)
+		return resp
+	case contentFiltered(fb):
+		f.requests.Inc(name, "refused")
+		return resp
+	}
+	f.requests.Inc(name, "ok")
+
+	setRequestProvider(c, p)
+	setRequestModel(c, model)
+	c.Header(fallbackHeader, name)
+	addWarning(c, provider.Warning{
+		Code:    provider.WarningContentFilterFallback,
+		Message: fmt.Sprintf("request was refused by a content filter and is served by %q on %s", model, name),
+		Param:   "model",
+	})
+	return fb
+}
+
+// contentFiltered reports whether resp was cut off by a content filter
+func contentFiltered(resp *provider.StandardResponse) bool {
+	for _, ch := range resp.Choices {
+		if ch.FinishReason != nil && *ch.FinishReason == provider.FinishReasonContentFilter {
+			return true
+		}
+	}
+	return false
+}
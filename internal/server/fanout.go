@@ -0,0 +1,80 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/compare"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+// RegisterFanoutRoutes wires sending one prompt to several models at once,
+// for evaluation or for generations worth paying several times for. Like
+// the other /v1 routes, the upstream calls are admitted, rate limited,
+// recorded and counted toward the key's budgets.
+func RegisterFanoutRoutes(engine *gin.Engine, comparer *compare.Comparer, r *provider.Router, cfg *config.Config, m *metrics.Registry, adm *admission.Controller, store artifacts.Store, fileStore *files.Store, usageStore usage.Store, keyStore keys.Store, enricher *enrich.Enricher, recent *RecentRequests) {
+	engine.POST("/v1/fanout", recordUsage(cfg, usageStore, keyStore, recent), spendTokens(adm), priceRequest(r), countRouteArms(m), enrichRequest(enricher, cfg.Enrichment.FailOpen), func(c *gin.Context) {
+		var in compare.FanoutRequest
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+			return
+		}
+		// Targets are routed and admitted with the caller's attributes,
+		// never with ones the client sent
+		attrs := requestAttributes(c)
+		for i := range in.Targets {
+			in.Targets[i].Attributes = attrs
+		}
+		if in.Judge != nil {
+			in.Judge.Attributes = attrs
+		}
+
+		if len(in.Messages) == 0 && in.Prompt != "" {
+			in.Messages = []provider.Message{{Role: provider.RoleUser, Content: in.Prompt}}
+		}
+		standardReq := &provider.StandardRequest{Messages: in.Messages, MaxTokens: in.MaxTokens}
+		if !checkRequestLimits(c, r, standardReq) {
+			return
+		}
+		if err := expandReferences(c.Request.Context(), store, fileStore, standardReq); err != nil {
+			if code := referenceErrorCode(err); code != "" {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": code})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		in.Messages = standardReq.Messages
+
+		report, err := comparer.Fanout(c.Request.Context(), &in)
+		if err != nil {
+			var rejected *admission.RejectedError
+			if errors.As(err, &rejected) {
+				c.Header("Retry-After", strconv.Itoa(rejected.RetryAfterSeconds()))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": "admission_" + rejected.Reason})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// A fan-out spans several models, so its usage is recorded and
+		// rate limited without one
+		setTokenUsage(c, report.Usage)
+		if report.CostUSD != nil {
+			c.Set(costKey, *report.CostUSD)
+			c.Header(costHeader, strconv.FormatFloat(*report.CostUSD, 'f', -1, 64))
+		}
+		c.JSON(http.StatusOK, report)
+	})
+}
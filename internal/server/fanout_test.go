@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/compare"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+func TestFanoutIsRateLimited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat", "demo-large"}}}}
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	adm := admission.NewController(cfg, m)
+	keyStore := keys.NewMemoryStore()
+	// Enough for the two targets of one fan-out
+	_ = keyStore.Put(&keys.Key{ID: "team-a", Hash: keys.HashSecret("sk-a"), Limits: &keys.Limits{RequestsPerMinute: 2}})
+	usageStore := usage.NewMemoryStore(10)
+	engine := gin.New()
+	RegisterFanoutRoutes(engine, compare.NewComparer(cfg, r, adm), r, cfg, m, adm, artifacts.NewMemoryStore(), nil, usageStore,
+		keyStore, enrich.NewEnricher(cfg), NewRecentRequests(cfg))
+
+	send := func() *httptest.ResponseRecorder {
+		body := `{"targets":[{"model":"demo-chat"},{"model":"demo-large"}],"prompt":"hi"}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/fanout", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer sk-a")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the first fan-out served, got %d: %s", w.Code, w.Body)
+	}
+	var report compare.FanoutReport
+	_ = json.Unmarshal(w.Body.Bytes(), &report)
+	if len(report.Candidates) != 2 || report.Usage.TotalTokens != report.Candidates[0].Usage.TotalTokens+report.Candidates[1].Usage.TotalTokens {
+		t.Errorf("Expected the usage of both candidates totalled, got %+v", report)
+	}
+	recs, _ := usageStore.Query(time.Time{}, time.Now().Add(time.Hour))
+	if len(recs) != 1 || recs[0].KeyID != "team-a" || recs[0].PromptTokens != report.Usage.PromptTokens {
+		t.Errorf("Expected one usage record with the total, got %+v", recs)
+	}
+
+	w = send()
+	if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), "admission_rate_limited") {
+		t.Fatalf("Expected the second fan-out over the key's limit refused, got %d: %s", w.Code, w.Body)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After")
+	}
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+// newGatewayForTest is a gateway with its own keys, usage and recent requests
+func newGatewayForTest(t *testing.T, cfg *config.Config, keyStore keys.Store) (*gin.Engine, *RecentRequests) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	recent := NewRecentRequests(cfg)
+	engine := gin.New()
+	engine.Use(assignRequestID())
+	RegisterRoutes(engine, r, cfg, m, admission.NewController(cfg, m), artifacts.NewMemoryStore(), usage.NewMemoryStore(10),
+		keyStore, enrich.NewEnricher(cfg), tools.NewRuntime(cfg, m), recent, respcache.NewCache(), loadshed.New(cfg, m), nil, nil, nil, nil)
+	return engine, recent
+}
+
+func TestFederation(t *testing.T) {
+	// The regional gateway trusts the origin's federation key
+	remoteCfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"eu-chat"}}}}
+	remoteCfg.Federation.TrustedKeys = []string{"peer"}
+	remoteKeys := keys.NewMemoryStore()
+	_ = remoteKeys.Put(&keys.Key{ID: "peer", Hash: keys.HashSecret("sk-peer")})
+	_ = remoteKeys.Put(&keys.Key{ID: "other", Hash: keys.HashSecret("sk-other")})
+	remote, remoteRecent := newGatewayForTest(t, remoteCfg, remoteKeys)
+	srv := httptest.NewServer(remote)
+	defer srv.Close()
+
+	originCfg := &config.Config{RemoteLetLLM: []config.RemoteLetLLMConfig{{Name: "eu", BaseURL: srv.URL + "/v1", APIKey: "sk-peer", Models: []string{"eu-chat"}}}}
+	originKeys := keys.NewMemoryStore()
+	_ = originKeys.Put(&keys.Key{ID: "team-a", Hash: keys.HashSecret("sk-a")})
+	origin, originRecent := newGatewayForTest(t, originCfg, originKeys)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"eu-chat","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Authorization", "Bearer sk-a")
+	req.Header.Set("X-Request-ID", "req-fed-1")
+	w := httptest.NewRecorder()
+	origin.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 across the hop, got %d: %s", w.Code, w.Body)
+	}
+
+	all := func(*RequestSummary) bool { return true }
+	local := originRecent.List(1, all)
+	if len(local) != 1 || local[0].Provider != "eu" || local[0].KeyID != "team-a" {
+		t.Errorf("Unexpected origin record %+v", local)
+	}
+	hop := remoteRecent.List(1, all)
+	if len(hop) != 1 || hop[0].RequestID != "req-fed-1" || hop[0].KeyID != "peer" || hop[0].OriginKeyID != "team-a" || hop[0].Provider != "demo" {
+		t.Errorf("Unexpected remote record %+v", hop)
+	}
+
+	// Keys the remote does not trust cannot claim an origin
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"eu-chat","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Authorization", "Bearer sk-other")
+	req.Header.Set(provider.OriginKeyHeader, "team-a")
+	remote.ServeHTTP(httptest.NewRecorder(), req)
+	if hop := remoteRecent.List(1, all); hop[0].KeyID != "other" || hop[0].OriginKeyID != "" {
+		t.Errorf("Expected the origin claim ignored, got %+v", hop[0])
+	}
+}
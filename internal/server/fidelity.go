@@ -0,0 +1,75 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// droppedFieldsHeader lists fields that did not reach the provider as sent
+const droppedFieldsHeader = "X-LetLLM-Dropped-Fields"
+
+// fidelityTracker counts transformation losses per provider and field
+type fidelityTracker struct {
+	issues *metrics.CounterVec
+	report bool
+}
+
+func newFidelityTracker(m *metrics.Registry, report bool) *fidelityTracker {
+	return &fidelityTracker{
+		issues: m.Counter("letllm_transform_fidelity_issues_total",
+			"Request fields dropped, unsupported or degraded during transformation.",
+			"provider", "field", "kind"),
+		report: report,
+	}
+}
+
+// check collects ingress and provider fidelity issues for a request, records
+// them in metrics and optionally reports them to the client in a header
+func (t *fidelityTracker) check(c *gin.Context, in *OpenAIChatCompletionRequest, p provider.Provider, model provider.ModelCapabilities, req *provider.StandardRequest) []provider.FidelityIssue {
+	issues := ingressIssues(in)
+	issues = append(issues, provider.CheckFidelity(p.GetCapabilities(), model, req)...)
+
+	name := p.GetInfo().Name
+	fields := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		t.issues.Inc(name, issue.Field, issue.Kind)
+		addWarning(c, issue.Warning())
+		if issue.Kind != provider.FidelityDegraded {
+			fields = append(fields, issue.Field)
+		}
+	}
+
+	if t.report && len(fields) > 0 {
+		c.Header(droppedFieldsHeader, strings.Join(fields, ","))
+	}
+	return issues
+}
+
+// ingressIssues reports OpenAI request fields the gateway accepts but cannot
+// represent in a StandardRequest
+func ingressIssues(in *OpenAIChatCompletionRequest) []provider.FidelityIssue {
+	var issues []provider.FidelityIssue
+	unsupported := func(field, detail string) {
+		issues = append(issues, provider.FidelityIssue{Field: field, Kind: provider.FidelityUnsupported, Detail: detail})
+	}
+
+	if in.Logprobs != nil && *in.Logprobs {
+		unsupported("logprobs", "logprobs are not supported by the gateway")
+	}
+	if in.TopLogprobs != nil {
+		unsupported("top_logprobs", "logprobs are not supported by the gateway")
+	}
+	if in.N != nil && *in.N > 1 {
+		unsupported("n", "only a single choice is generated")
+	}
+	for _, tool := range in.Tools {
+		if tool.Type != "function" {
+			unsupported("tools", "only function tools are supported")
+			break
+		}
+	}
+	return issues
+}
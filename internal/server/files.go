@@ -0,0 +1,159 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+)
+
+// fileErrorStatus maps file store errors to HTTP statuses
+func fileErrorStatus(err error) int {
+	if errors.Is(err, files.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// RegisterFileRoutes wires the OpenAI-compatible files API. Files belong to
+// the virtual key that uploaded them and are invisible to other callers;
+// other requests, such as batches, refer to them by ID. Messages attach the
+// caller's user_data files as "{{file:<id>}}".
+func RegisterFileRoutes(engine *gin.Engine, cfg *config.Config, store *files.Store, keyStore keys.Store) {
+	maxBytes := cfg.Files.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 512 << 20
+	}
+
+	// ownFile returns the caller's file, or writes a 404
+	ownFile := func(c *gin.Context) (*files.File, bool) {
+		f, err := store.Get(c.Param("id"))
+		if err == nil && f.KeyID != callerKeyID(c, keyStore) {
+			err = files.ErrNotFound
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+			return nil, false
+		}
+		return f, true
+	}
+
+	// POST /v1/files is a multipart upload of one file and its purpose
+	engine.POST("/v1/files", func(c *gin.Context) {
+		// Room for the form fields on top of the file
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+1<<20)
+
+		fh, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file exceeds %d bytes", maxBytes)})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("file is required: %v", err)})
+			return
+		}
+		purpose := c.PostForm("purpose")
+		if !files.Uploadable[purpose] {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid purpose %q", purpose)})
+			return
+		}
+		limit := maxBytes
+		if purpose == files.PurposeBatch && cfg.Batches.MaxFileBytes > 0 && cfg.Batches.MaxFileBytes < limit {
+			limit = cfg.Batches.MaxFileBytes
+		}
+		if fh.Size > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file exceeds %d bytes", limit)})
+			return
+		}
+
+		src, err := fh.Open()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer src.Close()
+		content, err := io.ReadAll(src)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		f, err := store.Put(c.Request.Context(), callerKeyID(c, keyStore), fh.Filename, purpose, content)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, f)
+	})
+
+	engine.GET("/v1/files", func(c *gin.Context) {
+		limit := 10000
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 10000 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 10000"})
+				return
+			}
+			limit = n
+		}
+		// One more than asked tells whether there are more
+		list, err := store.List(callerKeyID(c, keyStore), c.Query("purpose"), c.Query("after"), limit+1)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		hasMore := len(list) > limit
+		if hasMore {
+			list = list[:limit]
+		}
+		out := gin.H{"object": "list", "data": list, "has_more": hasMore, "first_id": nil, "last_id": nil}
+		if len(list) > 0 {
+			out["first_id"], out["last_id"] = list[0].ID, list[len(list)-1].ID
+		} else {
+			out["data"] = []*files.File{}
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	engine.GET("/v1/files/:id", func(c *gin.Context) {
+		if f, ok := ownFile(c); ok {
+			c.JSON(http.StatusOK, f)
+		}
+	})
+
+	engine.GET("/v1/files/:id/content", func(c *gin.Context) {
+		f, ok := ownFile(c)
+		if !ok {
+			return
+		}
+		content, err := store.Content(c.Request.Context(), f.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		contentType := "application/octet-stream"
+		if f.Purpose == files.PurposeBatch || f.Purpose == files.PurposeBatchOutput {
+			contentType = "application/jsonl"
+		}
+		c.Data(http.StatusOK, contentType, content)
+	})
+
+	engine.DELETE("/v1/files/:id", func(c *gin.Context) {
+		f, ok := ownFile(c)
+		if !ok {
+			return
+		}
+		if err := store.Delete(c.Request.Context(), f.ID); err != nil {
+			c.AbortWithStatusJSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": f.ID, "object": "file", "deleted": true})
+	})
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+func TestFileAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Files.MaxBytes = 64
+	keyStore := keys.NewMemoryStore()
+	_ = keyStore.Put(&keys.Key{ID: "key_owner", Hash: keys.HashSecret("sk-owner")})
+	_ = keyStore.Put(&keys.Key{ID: "key_other", Hash: keys.HashSecret("sk-other")})
+	engine := gin.New()
+	RegisterFileRoutes(engine, cfg, files.NewStore(files.NewMemoryMetadata(), files.NewMemoryBlobs()), keyStore)
+
+	call := func(method, path, token, contentType string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	upload := func(purpose, name, content string) *httptest.ResponseRecorder {
+		var form bytes.Buffer
+		mw := multipart.NewWriter(&form)
+		_ = mw.WriteField("purpose", purpose)
+		fw, _ := mw.CreateFormFile("file", name)
+		_, _ = fw.Write([]byte(content))
+		_ = mw.Close()
+		return call(http.MethodPost, "/v1/files", "sk-owner", mw.FormDataContentType(), form.Bytes())
+	}
+
+	for name, tc := range map[string]struct {
+		purpose, content string
+		code             int
+	}{
+		"vision":        {files.PurposeVision, "\x89PNG", http.StatusBadRequest},
+		"batch":         {files.PurposeBatch, "{}", http.StatusOK},
+		"output":        {files.PurposeBatchOutput, "{}", http.StatusBadRequest},
+		"unknown":       {"pictures", "x", http.StatusBadRequest},
+		"too large":     {files.PurposeUserData, strings.Repeat("x", 65), http.StatusRequestEntityTooLarge},
+		"user data":     {files.PurposeUserData, "notes", http.StatusOK},
+		"empty purpose": {"", "x", http.StatusBadRequest},
+	} {
+		if w := upload(tc.purpose, name+".bin", tc.content); w.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d %s", name, tc.code, w.Code, w.Body)
+		}
+	}
+
+	var list struct {
+		Data    []files.File `json:"data"`
+		HasMore bool         `json:"has_more"`
+	}
+	w := call(http.MethodGet, "/v1/files?purpose=user_data", "sk-owner", "", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) != 1 || list.Data[0].Filename != "user data.bin" || list.HasMore {
+		t.Fatalf("Unexpected user data files %s", w.Body)
+	}
+	f := list.Data[0]
+	if w := call(http.MethodGet, "/v1/files?limit=1", "sk-owner", "", nil); !strings.Contains(w.Body.String(), `"has_more":true`) {
+		t.Errorf("Expected more files past the limit, got %s", w.Body)
+	}
+	if w := call(http.MethodGet, "/v1/files", "sk-other", "", nil); !strings.Contains(w.Body.String(), `"data":[]`) {
+		t.Errorf("Expected an empty list for another key, got %s", w.Body)
+	}
+
+	if w := call(http.MethodGet, "/v1/files/"+f.ID+"/content", "sk-owner", "", nil); w.Code != http.StatusOK || w.Body.String() != "notes" {
+		t.Errorf("Unexpected content %d %q", w.Code, w.Body)
+	}
+	if w := call(http.MethodDelete, "/v1/files/"+f.ID, "sk-other", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected another key's file to be invisible, got %d", w.Code)
+	}
+	if w := call(http.MethodDelete, "/v1/files/"+f.ID, "sk-owner", "", nil); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"deleted":true`) {
+		t.Errorf("Delete failed: %d %s", w.Code, w.Body)
+	}
+	if w := call(http.MethodGet, "/v1/files/"+f.ID, "sk-owner", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected a deleted file to be gone, got %d", w.Code)
+	}
+}
+
+func TestFileReferences(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"},
+		Responses: []config.MockResponse{{Match: "meeting notes", Content: "read them"}}}}}
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	keyStore := keys.NewMemoryStore()
+	_ = keyStore.Put(&keys.Key{ID: "key_owner", Hash: keys.HashSecret("sk-owner")})
+	_ = keyStore.Put(&keys.Key{ID: "key_other", Hash: keys.HashSecret("sk-other")})
+	fileStore := files.NewStore(files.NewMemoryMetadata(), files.NewMemoryBlobs())
+	notes, _ := fileStore.Put(context.Background(), "key_owner", "notes.txt", files.PurposeUserData, []byte("meeting notes"))
+	engine := gin.New()
+	RegisterRoutes(engine, r, cfg, m, admission.NewController(cfg, m), artifacts.NewMemoryStore(), usage.NewMemoryStore(10),
+		keyStore, enrich.NewEnricher(cfg), tools.NewRuntime(cfg, m), NewRecentRequests(cfg), respcache.NewCache(), loadshed.New(cfg, m), nil, nil, nil, fileStore)
+
+	chat := func(token string) *httptest.ResponseRecorder {
+		body := `{"model":"demo-chat","messages":[{"role":"user","content":"Summarize {{file:` + notes.ID + `}}"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+	if w := chat("sk-owner"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "read them") {
+		t.Errorf("Expected the file attached, got %d %s", w.Code, w.Body)
+	}
+	if w := chat("sk-other"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_file_reference") {
+		t.Errorf("Expected another key's file refused, got %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,657 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/tracing"
+)
+
+// GeminiRequest is the body of POST /v1beta/models/{model}:generateContent
+// and :streamGenerateContent, in the wire format of Google's Gemini API
+type GeminiRequest struct {
+	Contents          []GeminiContent         `json:"contents"`
+	SystemInstruction *GeminiContent          `json:"systemInstruction,omitempty"`
+	Tools             []GeminiTool            `json:"tools,omitempty"`
+	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// GeminiContent is a turn of the conversation
+type GeminiContent struct {
+	// "user" or "model"
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiPart is a text, function call or function response part
+type GeminiPart struct {
+	Text string `json:"text,omitempty"`
+	// Marks text as the model's reasoning
+	Thought          bool                    `json:"thought,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+	// Accepted only to be rejected by name
+	InlineData json.RawMessage `json:"inlineData,omitempty"`
+	FileData   json.RawMessage `json:"fileData,omitempty"`
+}
+
+type GeminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type GeminiFunctionResponse struct {
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+// GeminiTool holds function declarations; other tool kinds are rejected
+type GeminiTool struct {
+	FunctionDeclarations []provider.Function `json:"functionDeclarations,omitempty"`
+	GoogleSearch         json.RawMessage     `json:"googleSearch,omitempty"`
+	CodeExecution        json.RawMessage     `json:"codeExecution,omitempty"`
+}
+
+type GeminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	MaxOutputTokens *int     `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+	CandidateCount  *int     `json:"candidateCount,omitempty"`
+}
+
+// GeminiResponse is a generateContent response, or one chunk of a stream
+type GeminiResponse struct {
+	Candidates    []GeminiCandidate    `json:"candidates"`
+	UsageMetadata *GeminiUsageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string               `json:"modelVersion"`
+	ResponseID    string               `json:"responseId"`
+
+	// Extension: non-fatal notices about gateway-side adjustments
+	Warnings []provider.Warning `json:"warnings,omitempty"`
+}
+
+type GeminiCandidate struct {
+	Content      GeminiContent `json:"content"`
+	FinishReason string        `json:"finishReason,omitempty"`
+	Index        int           `json:"index"`
+}
+
+type GeminiUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+}
+
+// GeminiErrorResponse is the body of /v1beta errors, so Google clients can
+// parse them
+type GeminiErrorResponse struct {
+	Error GeminiError `json:"error"`
+}
+
+type GeminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// Gemini API methods served under /v1beta/models/{model}
+const (
+	geminiGenerate       = "generateContent"
+	geminiStreamGenerate = "streamGenerateContent"
+)
+
+// geminiRole maps a content role to the standard one
+func geminiRole(role string) (string, error) {
+	switch role {
+	case "user", "":
+		return provider.RoleUser, nil
+	case "model":
+		return provider.RoleAssistant, nil
+	default:
+		return "", fmt.Errorf("unknown role %q", role)
+	}
+}
+
+// convertGeminiRequest converts a Gemini request to the standard format.
+// Function calls carry their arguments as JSON text and function
+// responses become function messages.
+func convertGeminiRequest(model string, stream bool, in *GeminiRequest) (*provider.StandardRequest, error) {
+	var messages []provider.Message
+	if si := in.SystemInstruction; si != nil {
+		texts := make([]string, 0, len(si.Parts))
+		for _, part := range si.Parts {
+			texts = append(texts, part.Text)
+		}
+		if text := strings.Join(texts, "\n"); text != "" {
+			messages = append(messages, provider.Message{Role: provider.RoleSystem, Content: text})
+		}
+	}
+
+	for i, content := range in.Contents {
+		role, err := geminiRole(content.Role)
+		if err != nil {
+			return nil, fmt.Errorf("contents[%d]: %w", i, err)
+		}
+		var texts []string
+		var calls []*provider.FunctionCall
+		responses := 0
+		for j, part := range content.Parts {
+			switch {
+			case part.Thought:
+				// Earlier reasoning is not replayed to providers
+			case part.FunctionCall != nil:
+				args := string(part.FunctionCall.Args)
+				if args == "" {
+					args = "{}"
+				}
+				calls = append(calls, &provider.FunctionCall{Name: part.FunctionCall.Name, Arguments: args})
+			case part.FunctionResponse != nil:
+				name := part.FunctionResponse.Name
+				messages = append(messages, provider.Message{Role: provider.RoleFunction, Name: &name, Content: string(part.FunctionResponse.Response)})
+				responses++
+			case part.InlineData != nil || part.FileData != nil:
+				return nil, fmt.Errorf("contents[%d].parts[%d]: inline and file data are not supported", i, j)
+			default:
+				texts = append(texts, part.Text)
+			}
+		}
+
+		// A message carries one function call, so further calls of the
+		// same turn follow as assistant messages of their own
+		if len(texts) > 0 || len(calls)+responses == 0 {
+			messages = append(messages, provider.Message{Role: role, Content: strings.Join(texts, "\n")})
+		}
+		for k, call := range calls {
+			if k == 0 && len(texts) > 0 {
+				messages[len(messages)-1].FunctionCall = call
+				continue
+			}
+			messages = append(messages, provider.Message{Role: provider.RoleAssistant, FunctionCall: call})
+		}
+	}
+
+	var functions []provider.Function
+	for i, tool := range in.Tools {
+		if tool.GoogleSearch != nil || tool.CodeExecution != nil {
+			return nil, fmt.Errorf("tools[%d]: only function declarations are supported", i)
+		}
+		functions = append(functions, tool.FunctionDeclarations...)
+	}
+
+	req := &provider.StandardRequest{
+		Model:     model,
+		Messages:  messages,
+		Stream:    stream,
+		Functions: functions,
+	}
+	if gc := in.GenerationConfig; gc != nil {
+		if gc.CandidateCount != nil && *gc.CandidateCount > 1 {
+			return nil, fmt.Errorf("generationConfig.candidateCount above 1 is not supported")
+		}
+		req.Temperature, req.TopP, req.MaxTokens = gc.Temperature, gc.TopP, gc.MaxOutputTokens
+		if len(gc.StopSequences) > 0 {
+			// Forwarded under the OpenAI name by providers that support passthrough
+			stop, _ := json.Marshal(gc.StopSequences)
+			req.Passthrough = map[string]json.RawMessage{"stop": stop}
+		}
+	}
+	return req, nil
+}
+
+// geminiFinishReason maps a finish reason to the Gemini one
+func geminiFinishReason(finishReason string) string {
+	switch finishReason {
+	case "length":
+		return "MAX_TOKENS"
+	case "content_filter":
+		return "SAFETY"
+	default:
+		return "STOP"
+	}
+}
+
+func geminiUsage(u provider.Usage) *GeminiUsageMetadata {
+	return &GeminiUsageMetadata{
+		PromptTokenCount:        u.PromptTokens,
+		CandidatesTokenCount:    u.CompletionTokens - u.ReasoningTokens,
+		TotalTokenCount:         u.TotalTokens,
+		CachedContentTokenCount: u.CachedPromptTokens,
+		ThoughtsTokenCount:      u.ReasoningTokens,
+	}
+}
+
+// geminiCall returns a function call as a part; arguments that are not a
+// JSON object are passed as a string
+func geminiCall(call *provider.FunctionCall) GeminiPart {
+	return GeminiPart{FunctionCall: &GeminiFunctionCall{Name: call.Name, Args: toolInput(call.Arguments)}}
+}
+
+// geminiParts returns the parts of a message or message delta
+func geminiParts(msg *provider.Message) []GeminiPart {
+	var parts []GeminiPart
+	if msg.ReasoningContent != "" {
+		parts = append(parts, GeminiPart{Text: msg.ReasoningContent, Thought: true})
+	}
+	if msg.Content != "" {
+		parts = append(parts, GeminiPart{Text: msg.Content})
+	}
+	return parts
+}
+
+// newGeminiResponse returns a response of one candidate holding parts
+func newGeminiResponse(model, id string, parts []GeminiPart) GeminiResponse {
+	if parts == nil {
+		parts = []GeminiPart{}
+	}
+	return GeminiResponse{
+		Candidates:   []GeminiCandidate{{Content: GeminiContent{Role: "model", Parts: parts}}},
+		ModelVersion: model,
+		ResponseID:   id,
+	}
+}
+
+// convertToGeminiResponse converts the first choice of a standard response
+// to a Gemini response
+func convertToGeminiResponse(model string, resp *provider.StandardResponse) GeminiResponse {
+	var parts []GeminiPart
+	finishReason := ""
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		if choice.FinishReason != nil {
+			finishReason = *choice.FinishReason
+		}
+		if msg := choice.Message; msg != nil {
+			parts = geminiParts(msg)
+			if msg.FunctionCall != nil {
+				parts = append(parts, geminiCall(msg.FunctionCall))
+			}
+		}
+	}
+	out := newGeminiResponse(model, ids.New(), parts)
+	out.Candidates[0].FinishReason = geminiFinishReason(finishReason)
+	out.UsageMetadata = geminiUsage(resp.Usage)
+	out.Warnings = resp.Warnings
+	return out
+}
+
+// geminiStatus is the Google RPC status name of an HTTP status
+func geminiStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	default:
+		return "INTERNAL"
+	}
+}
+
+// abortGemini ends a /v1beta request with an error in the Gemini API shape
+func abortGemini(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, GeminiErrorResponse{Error: GeminiError{Code: status, Message: message, Status: geminiStatus(status)}})
+}
+
+// geminiEncoder turns provider stream chunks into Gemini response chunks,
+// sent as server-sent events with alt=sse and as the items of a JSON array
+// otherwise. Function calls are sent whole, once their arguments are
+// complete.
+type geminiEncoder struct {
+	w       io.Writer
+	sse     bool
+	model   string
+	id      string
+	partial []byte
+	chunks  int
+
+	call   *provider.FunctionCall
+	finish string
+	usage  *provider.Usage
+}
+
+func newGeminiEncoder(w io.Writer, model string, sse bool) *geminiEncoder {
+	return &geminiEncoder{w: w, sse: sse, model: model, id: ids.New()}
+}
+
+// Write encodes every complete line in b, keeping a trailing partial line
+// for the next call
+func (e *geminiEncoder) Write(b []byte) error {
+	e.partial = append(e.partial, b...)
+	for {
+		i := bytes.IndexByte(e.partial, '\n')
+		if i < 0 {
+			return nil
+		}
+		if err := e.encodeLine(e.partial[:i]); err != nil {
+			return err
+		}
+		e.partial = e.partial[i+1:]
+	}
+}
+
+// Close encodes any unterminated last line and sends the final chunk with
+// the finish reason and usage
+func (e *geminiEncoder) Close() error {
+	if err := e.encodeLine(e.partial); err != nil {
+		return err
+	}
+	e.partial = nil
+	var parts []GeminiPart
+	if e.call != nil {
+		parts = append(parts, geminiCall(e.call))
+		e.call = nil
+	}
+	last := newGeminiResponse(e.model, e.id, parts)
+	last.Candidates[0].FinishReason = geminiFinishReason(e.finish)
+	if e.usage != nil {
+		last.UsageMetadata = geminiUsage(*e.usage)
+	}
+	if err := e.send(last); err != nil {
+		return err
+	}
+	return e.end()
+}
+
+// Usage returns the token usage reported by the stream, if any
+func (e *geminiEncoder) Usage() *provider.Usage {
+	return e.usage
+}
+
+func (e *geminiEncoder) encodeLine(line []byte) error {
+	line = bytes.TrimSpace(line)
+	line = bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+	if len(line) == 0 || string(line) == "[DONE]" {
+		return nil
+	}
+
+	var chunk provider.StreamChunk
+	if err := json.Unmarshal(line, &chunk); err != nil {
+		return e.fail(fmt.Errorf("invalid stream chunk from provider: %w", err))
+	}
+	if chunk.Error != nil {
+		return e.fail(errors.New(chunk.Error.Message))
+	}
+	if chunk.Usage != nil {
+		e.usage = chunk.Usage
+	}
+	for _, choice := range chunk.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		if choice.FinishReason != nil {
+			e.finish = *choice.FinishReason
+		}
+		if choice.Delta != nil {
+			if err := e.delta(choice.Delta); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// delta sends the text of one message delta and collects function calls
+func (e *geminiEncoder) delta(msg *provider.Message) error {
+	parts := geminiParts(msg)
+	if call := msg.FunctionCall; call != nil {
+		// A name starts a new call; argument fragments continue the open one
+		if call.Name != "" || e.call == nil {
+			if e.call != nil {
+				parts = append(parts, geminiCall(e.call))
+			}
+			e.call = &provider.FunctionCall{Name: call.Name}
+		}
+		e.call.Arguments += call.Arguments
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return e.send(newGeminiResponse(e.model, e.id, parts))
+}
+
+// send writes one chunk as an event or an array item
+func (e *geminiEncoder) send(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var prefix, suffix string
+	switch {
+	case e.sse:
+		prefix, suffix = "data: ", "\n\n"
+	case e.chunks == 0:
+		prefix = "["
+	default:
+		prefix = ",\n"
+	}
+	e.chunks++
+	if _, err := e.w.Write([]byte(prefix)); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(b); err != nil {
+		return err
+	}
+	_, err = e.w.Write([]byte(suffix))
+	return err
+}
+
+// end closes the JSON array
+func (e *geminiEncoder) end() error {
+	if e.sse {
+		return nil
+	}
+	_, err := e.w.Write([]byte("]"))
+	return err
+}
+
+// fail tells the client the stream broke and returns err to stop the relay
+func (e *geminiEncoder) fail(err error) error {
+	_ = e.send(GeminiErrorResponse{Error: GeminiError{Code: http.StatusInternalServerError, Message: err.Error(), Status: geminiStatus(http.StatusInternalServerError)}})
+	_ = e.end()
+	return err
+}
+
+// geminiHandler serves POST /v1beta/models/{model}:generateContent and
+// :streamGenerateContent over the same providers as chat completions, for
+// clients built for Google's Gemini API. gin has no parameter syntax for
+// the method suffix, so the handler splits it from the model itself.
+func geminiHandler(r *provider.Router, adm *admission.Controller, store artifacts.Store, fileStore *files.Store, toolRuntime *tools.Runtime, shedder *loadshed.Shedder, relay *streamRelay, tracer *tracing.Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		model, method, _ := strings.Cut(c.Param("model"), ":")
+		if method != geminiGenerate && method != geminiStreamGenerate {
+			abortGemini(c, http.StatusNotFound, fmt.Sprintf("method %q is not supported", method))
+			return
+		}
+		stream := method == geminiStreamGenerate
+
+		var in GeminiRequest
+		if err := c.ShouldBindJSON(&in); err != nil {
+			abortGemini(c, http.StatusBadRequest, fmt.Sprintf("invalid json: %v", err))
+			return
+		}
+		if model == "" {
+			abortGemini(c, http.StatusBadRequest, "model is required")
+			return
+		}
+		model = resolveModelAlias(c, r, model)
+		standardReq, err := convertGeminiRequest(model, stream, &in)
+		if err != nil {
+			abortGemini(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := r.CheckRequestLimits(standardReq); err != nil {
+			abortGemini(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		setRequestModel(c, model)
+
+		if stream {
+			if shed := shedder.Check(); shed != nil {
+				c.Header("Retry-After", strconv.Itoa(int(shed.RetryAfter.Seconds())))
+				abortGemini(c, http.StatusServiceUnavailable, shed.Error())
+				return
+			}
+		}
+
+		attrs := requestAttributes(c)
+		routeReq := &provider.RouteRequest{Model: model, Endpoint: c.FullPath(), Headers: routeHeaders(c.Request.Header), Attributes: attrs, Needs: provider.NeedsOf(standardReq),
+			Conversation: conversationID(c)}
+		model = fitContextLength(c, r, routeReq, standardReq)
+		p, err := r.Route(routeReq)
+		if err != nil {
+			var policyErr *provider.PolicyError
+			if errors.As(err, &policyErr) {
+				abortGemini(c, http.StatusForbidden, err.Error())
+				return
+			}
+			abortGemini(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		setRequestProvider(c, p)
+		setRequestArm(c, routeReq.Arm)
+		standardReq.Model = upstreamModel(routeReq)
+
+		release, err := adm.Acquire(c.Request.Context(), p.GetInfo().Name, model, attrs)
+		if err != nil {
+			var rejected *admission.RejectedError
+			if errors.As(err, &rejected) {
+				c.Header("Retry-After", strconv.Itoa(rejected.RetryAfterSeconds()))
+				abortGemini(c, http.StatusTooManyRequests, err.Error())
+				return
+			}
+			abortGemini(c, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		defer release()
+
+		if err := expandReferences(c.Request.Context(), store, fileStore, standardReq); err != nil {
+			if referenceErrorCode(err) != "" {
+				abortGemini(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			abortGemini(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		warnings, err := r.ModelCapabilities(p, model).ApplyLimits(standardReq)
+		if err != nil {
+			abortGemini(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		for _, w := range warnings {
+			addWarning(c, w)
+		}
+
+		sse := c.Query("alt") == "sse"
+		if stream {
+			streamGemini(c, r, p, relay, tracer, toolRuntime, standardReq, model, sse)
+			return
+		}
+
+		var resp *provider.StandardResponse
+		if toolRuntime.Enabled() {
+			resp, err = toolRuntime.Run(c.Request.Context(), p, standardReq, nil)
+		} else {
+			var gen *provider.GenerateResponse
+			if gen, err = p.Generate(c.Request.Context(), &provider.GenerateRequest{StandardRequest: standardReq}); err == nil {
+				resp = gen.StandardResponse
+			}
+		}
+		var budgetErr *tools.BudgetExceededError
+		if errors.As(err, &budgetErr) {
+			setTokenUsage(c, budgetErr.Usage)
+			abortGemini(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		r.ReportOutcome(p.GetInfo().Name, err)
+		if err != nil {
+			abortGemini(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		setTokenUsage(c, resp.Usage)
+		setResponseCost(c, r, resp.Usage, resp.Metadata)
+
+		out := convertToGeminiResponse(model, resp)
+		out.Warnings = append(requestWarnings(c), out.Warnings...)
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// geminiStreamHeaders sets the content type of a stream in either framing
+func geminiStreamHeaders(c *gin.Context, sse bool) {
+	if sse {
+		setEventStreamHeaders(c)
+		return
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+}
+
+// streamGemini relays a provider stream as Gemini response chunks
+func streamGemini(c *gin.Context, r *provider.Router, p provider.Provider, relay *streamRelay, tracer *tracing.Tracer, toolRuntime *tools.Runtime, req *provider.StandardRequest, model string, sse bool) {
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		abortGemini(c, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	trace := tracer.Start(requestID(c), traceKeyID(c), c.FullPath(), p.GetInfo().Name, model)
+	rc, err := openStream(c.Request.Context(), r, toolRuntime, p, req)
+	defer func() { finishTrace(trace, err) }()
+	if err != nil {
+		abortGemini(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	trace.Upstream()
+	rc = trace.WrapUpstream(rc)
+	defer rc.Close()
+	geminiStreamHeaders(c, sse)
+
+	enc := newGeminiEncoder(c.Writer, model, sse)
+	deadline := relay.writeDeadline(c.Writer)
+	defer deadline.clear()
+	err = relay.pump(c.Request.Context(), rc, func(b []byte) error {
+		deadline.extend()
+		trace.Write(len(b))
+		return enc.Write(b)
+	}, func() {
+		deadline.extend()
+		flusher.Flush()
+		trace.Flush()
+	})
+	if usage := enc.Usage(); usage != nil {
+		setTokenUsage(c, *usage)
+	}
+	if errors.Is(err, errSlowClient) {
+		deadline.extend()
+		_ = enc.fail(err)
+	}
+	if err != nil {
+		flusher.Flush()
+		return
+	}
+	deadline.extend()
+	_ = enc.Close()
+	flusher.Flush()
+}
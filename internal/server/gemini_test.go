@@ -0,0 +1,185 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConvertGeminiRequest(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body  string
+		roles []string
+		err   string
+	}{
+		"text": {
+			body:  `{"systemInstruction":{"parts":[{"text":"be brief"}]},"contents":[{"role":"user","parts":[{"text":"a"},{"text":"b"}]},{"role":"model","parts":[{"text":"hm","thought":true},{"text":"c"}]}]}`,
+			roles: []string{"system", "user", "assistant"},
+		},
+		"function call round trip": {
+			body:  `{"contents":[{"role":"user","parts":[{"text":"weather?"}]},{"role":"model","parts":[{"text":"checking"},{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}},{"functionCall":{"name":"get_time"}}]},{"role":"user","parts":[{"functionResponse":{"name":"get_weather","response":{"sky":"sunny"}}},{"functionResponse":{"name":"get_time","response":{"time":"noon"}}}]}]}`,
+			roles: []string{"user", "assistant", "assistant", "function", "function"},
+		},
+		"inline data": {
+			body: `{"contents":[{"parts":[{"inlineData":{"mimeType":"image/png","data":""}}]}]}`,
+			err:  `contents[0].parts[0]: inline and file data are not supported`,
+		},
+		"unknown role": {
+			body: `{"contents":[{"role":"system","parts":[{"text":"x"}]}]}`,
+			err:  `unknown role "system"`,
+		},
+		"built-in tool": {
+			body: `{"contents":[{"parts":[{"text":"x"}]}],"tools":[{"googleSearch":{}}]}`,
+			err:  `only function declarations are supported`,
+		},
+		"several candidates": {
+			body: `{"contents":[{"parts":[{"text":"x"}]}],"generationConfig":{"candidateCount":2}}`,
+			err:  `candidateCount above 1 is not supported`,
+		},
+	} {
+		var in GeminiRequest
+		if err := json.Unmarshal([]byte(tc.body), &in); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		req, err := convertGeminiRequest("m", false, &in)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: expected error %q, got %v", name, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		var roles []string
+		for _, m := range req.Messages {
+			roles = append(roles, m.Role)
+		}
+		if strings.Join(roles, ",") != strings.Join(tc.roles, ",") {
+			t.Errorf("%s: expected roles %v, got %v", name, tc.roles, roles)
+		}
+		if name == "text" && (req.Messages[1].Content != "a\nb" || req.Messages[2].Content != "c") {
+			t.Errorf("%s: expected parts joined and thoughts dropped, got %+v", name, req.Messages)
+		}
+		if name == "function call round trip" {
+			if call := req.Messages[1].FunctionCall; call == nil || call.Name != "get_weather" || call.Arguments != `{"city":"Paris"}` || req.Messages[1].Content != "checking" {
+				t.Errorf("%s: expected the first call with the turn's text, got %+v", name, req.Messages[1])
+			}
+			if call := req.Messages[2].FunctionCall; call == nil || call.Arguments != "{}" {
+				t.Errorf("%s: expected empty arguments for a call without args, got %+v", name, req.Messages[2])
+			}
+			if req.Messages[4].Name == nil || *req.Messages[4].Name != "get_time" || req.Messages[4].Content != `{"time":"noon"}` {
+				t.Errorf("%s: expected the response named after its function, got %+v", name, req.Messages[4])
+			}
+		}
+	}
+
+	var in GeminiRequest
+	_ = json.Unmarshal([]byte(`{"contents":[{"parts":[{"text":"hi"}]}],"generationConfig":{"maxOutputTokens":5,"stopSequences":["END"]}}`), &in)
+	req, err := convertGeminiRequest("m", false, &in)
+	if err != nil || req.MaxTokens == nil || *req.MaxTokens != 5 || string(req.Passthrough["stop"]) != `["END"]` {
+		t.Errorf("Expected generation config applied, got %+v, %v", req, err)
+	}
+}
+
+func TestGeminiEndpoint(t *testing.T) {
+	engine := newResponsesTestServer(t)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1beta/models/demo-chat:generateContent", strings.NewReader(`{"contents":[{"role":"user","parts":[{"text":"what is the weather"}]}]}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var out GeminiResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Candidates) != 1 || out.Candidates[0].Content.Role != "model" || out.Candidates[0].FinishReason != "STOP" {
+		t.Fatalf("Unexpected candidates: %+v", out.Candidates)
+	}
+	parts := out.Candidates[0].Content.Parts
+	if len(parts) != 2 || parts[0].Text != "Let me check" || parts[1].FunctionCall == nil ||
+		parts[1].FunctionCall.Name != "get_weather" || string(parts[1].FunctionCall.Args) != `{"city":"Paris"}` {
+		t.Errorf("Unexpected parts: %+v", parts)
+	}
+	if out.UsageMetadata == nil || out.UsageMetadata.PromptTokenCount == 0 || out.UsageMetadata.TotalTokenCount == 0 {
+		t.Errorf("Expected usage metadata, got %+v", out.UsageMetadata)
+	}
+
+	for name, tc := range map[string]struct {
+		path   string
+		body   string
+		code   int
+		status string
+	}{
+		"unsupported part": {"/v1beta/models/demo-chat:generateContent", `{"contents":[{"parts":[{"fileData":{"fileUri":"gs://x"}}]}]}`, http.StatusBadRequest, "INVALID_ARGUMENT"},
+		"unknown method":   {"/v1beta/models/demo-chat:countTokens", `{}`, http.StatusNotFound, "NOT_FOUND"},
+		"unknown model":    {"/v1beta/models/nope:generateContent", `{"contents":[{"parts":[{"text":"hi"}]}]}`, http.StatusBadRequest, "INVALID_ARGUMENT"},
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body)))
+		var errResp GeminiErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil || w.Code != tc.code ||
+			errResp.Error.Code != tc.code || errResp.Error.Status != tc.status {
+			t.Errorf("%s: expected a Google %s error, got %d: %s", name, tc.status, w.Code, w.Body)
+		}
+	}
+}
+
+func TestGeminiStreaming(t *testing.T) {
+	engine := newResponsesTestServer(t)
+	body := `{"contents":[{"role":"user","parts":[{"text":"weather in Paris"}]}]}`
+
+	collect := func(name string, chunks []GeminiResponse) {
+		var text string
+		var calls []*GeminiFunctionCall
+		for _, chunk := range chunks {
+			for _, part := range chunk.Candidates[0].Content.Parts {
+				text += part.Text
+				if part.FunctionCall != nil {
+					calls = append(calls, part.FunctionCall)
+				}
+			}
+		}
+		last := chunks[len(chunks)-1]
+		if text == "" || len(calls) != 1 || string(calls[0].Args) != `{"city":"Paris"}` {
+			t.Errorf("%s: expected text and one whole function call, got %q, %+v", name, text, calls)
+		}
+		if last.Candidates[0].FinishReason != "STOP" || last.UsageMetadata == nil {
+			t.Errorf("%s: expected the last chunk to finish with usage, got %+v", name, last)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1beta/models/demo-chat:streamGenerateContent?alt=sse", strings.NewReader(body)))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("Expected an event stream, got %d: %s", w.Code, w.Body)
+	}
+	var chunks []GeminiResponse
+	sc := bufio.NewScanner(w.Body)
+	for sc.Scan() {
+		data, ok := strings.CutPrefix(sc.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var chunk GeminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("bad event data %q: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	collect("sse", chunks)
+
+	// Without alt=sse the chunks are the items of one JSON array
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1beta/models/demo-chat:streamGenerateContent", strings.NewReader(body)))
+	chunks = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &chunks); err != nil || w.Code != http.StatusOK || len(chunks) < 2 {
+		t.Fatalf("Expected a JSON array of chunks, got %d: %s", w.Code, w.Body)
+	}
+	collect("array", chunks)
+}
@@ -0,0 +1,577 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/budget"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/server/chatpb"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/tracing"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+	"go.uber.org/fx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCModule serves the chat service over gRPC on server.grpc_addr, for
+// internal services that prefer it to SSE. Calls share the HTTP API's
+// router, admission control and usage records.
+var GRPCModule = fx.Module("grpc-server",
+	fx.Provide(NewChatService),
+	fx.Invoke(StartGRPCServer),
+)
+
+// ChatService implements the gRPC chat service
+type ChatService struct {
+	chatpb.UnimplementedChatServiceServer
+
+	r           *provider.Router
+	adm         *admission.Controller
+	store       artifacts.Store
+	files       *files.Store
+	usageStore  usage.Store
+	keyStore    keys.Store
+	toolRuntime *tools.Runtime
+	recent      *RecentRequests
+	shedder     *loadshed.Shedder
+	tracer      *tracing.Tracer
+	budgets     *budget.Tracker
+	trusted     map[string]bool
+	requireKeys bool
+}
+
+// NewChatService creates the gRPC chat service
+func NewChatService(cfg *config.Config, r *provider.Router, adm *admission.Controller, store artifacts.Store, fileStore *files.Store, usageStore usage.Store, keyStore keys.Store,
+	toolRuntime *tools.Runtime, recent *RecentRequests, shedder *loadshed.Shedder, tracer *tracing.Tracer, budgets *budget.Tracker) *ChatService {
+	trusted := make(map[string]bool, len(cfg.Federation.TrustedKeys))
+	for _, id := range cfg.Federation.TrustedKeys {
+		trusted[id] = true
+	}
+	return &ChatService{r: r, adm: adm, store: store, files: fileStore, usageStore: usageStore, keyStore: keyStore, toolRuntime: toolRuntime,
+		recent: recent, shedder: shedder, tracer: tracer, budgets: budgets, trusted: trusted, requireKeys: cfg.RequireKeys}
+}
+
+// StartGRPCServer serves the chat service when server.grpc_addr is set
+func StartGRPCServer(lc fx.Lifecycle, cfg *config.Config, svc *ChatService) {
+	if cfg.Server.GRPCAddr == "" {
+		return
+	}
+	srv := grpc.NewServer()
+	chatpb.RegisterChatServiceServer(srv, svc)
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", cfg.Server.GRPCAddr)
+			if err != nil {
+				return fmt.Errorf("grpc listen on %s: %w", cfg.Server.GRPCAddr, err)
+			}
+			go func() {
+				if err := srv.Serve(ln); err != nil {
+					log.Printf("grpc server on %s: %v", cfg.Server.GRPCAddr, err)
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				srv.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				srv.Stop()
+			}
+			return nil
+		},
+	})
+}
+
+// rpcError fails a call with the status the HTTP API would answer with,
+// which usage records keep
+type rpcError struct {
+	status int
+	msg    string
+}
+
+func (e *rpcError) Error() string {
+	return e.msg
+}
+
+// GRPCStatus makes grpc send the error with its gRPC code
+func (e *rpcError) GRPCStatus() *status.Status {
+	return status.New(grpcCode(e.status), e.msg)
+}
+
+func rpcErrorf(status int, format string, args ...interface{}) error {
+	return &rpcError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+// grpcCode maps an HTTP status to a gRPC code
+func grpcCode(status int) codes.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// chatCall is one call in progress, recorded like an HTTP request once done
+type chatCall struct {
+	ctx      context.Context
+	id       string
+	method   string
+	start    time.Time
+	key      *keys.Key
+	keyID    string
+	originID string
+	stream   bool
+	// Metadata routes match on as headers
+	headers map[string]string
+
+	model    string
+	provider string
+	// Routing of the call, once routed
+	route    *provider.RouteRequest
+	usage    *provider.Usage
+	warnings []provider.Warning
+	// When a stream's first chunk was sent
+	first time.Time
+}
+
+// begin identifies the caller from the call's metadata, which carries the
+// same request ID and bearer token headers as HTTP requests
+func (s *ChatService) begin(ctx context.Context, method string, stream bool) *chatCall {
+	md, _ := metadata.FromIncomingContext(ctx)
+	get := func(name string) string {
+		if v := md.Get(name); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	call := &chatCall{id: get(requestIDHeader), method: method, start: time.Now(), stream: stream, headers: routeHeaders(md)}
+	if !validRequestID(call.id) {
+		call.id = ids.New()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, call.id))
+
+	token, _ := strings.CutPrefix(get("authorization"), "Bearer ")
+	if token != "" {
+		call.key, _ = s.keyStore.GetByHash(keys.HashSecret(token))
+	}
+	if call.key != nil {
+		call.keyID = call.key.ID
+	}
+	if s.trusted[call.keyID] {
+		call.originID = get(provider.OriginKeyHeader)
+	}
+	call.ctx = provider.WithCaller(ctx, provider.Caller{RequestID: call.id, KeyID: call.keyID, Token: token})
+	if call.key != nil && call.key.Priority != "" {
+		call.ctx = admission.WithPriority(call.ctx, call.key.Priority)
+	}
+	if call.key != nil && call.key.Limits != nil {
+		call.ctx = admission.WithRateLimit(call.ctx, call.keyID, keyRateLimit(call.key))
+	}
+	return call
+}
+
+// finish records the call's usage and summary
+func (s *ChatService) finish(call *chatCall, err error) {
+	rec := &usage.Record{
+		Time:        call.start.UTC(),
+		Status:      http.StatusOK,
+		LatencyMS:   time.Since(call.start).Milliseconds(),
+		Stream:      call.stream,
+		Provider:    call.provider,
+		Model:       call.model,
+		KeyID:       call.keyID,
+		OriginKeyID: call.originID,
+	}
+	if err != nil {
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			rec.Status = rpcErr.status
+		} else {
+			rec.Status = http.StatusInternalServerError
+		}
+	}
+	if u := call.usage; u != nil {
+		s.adm.Spend(call.ctx, call.model, u.PromptTokens+u.CompletionTokens)
+		rec.PromptTokens = u.PromptTokens
+		rec.CompletionTokens = u.CompletionTokens
+		rec.CachedPromptTokens = u.CachedPromptTokens
+		rec.ReasoningTokens = u.ReasoningTokens
+		rec.AudioTokens = u.AudioTokens
+		if price, ok := s.r.Price(call.model); ok {
+			cost := u.Cost(price)
+			rec.CostUSD = &cost
+			s.budgets.Spend(context.WithoutCancel(call.ctx), call.key, cost)
+		}
+	}
+	if !call.first.IsZero() {
+		rec.TTFTMS = call.first.Sub(call.start).Milliseconds()
+	}
+	s.recent.Add(RequestSummary{RequestID: call.id, Method: http.MethodPost, Path: call.method, Record: *rec})
+	if err := s.usageStore.Add(rec); err != nil {
+		log.Printf("request %s: usage: %v", call.id, err)
+	}
+}
+
+// prepare converts and routes a call and admits it to its provider. The
+// returned release must be called once the call is done.
+func (s *ChatService) prepare(call *chatCall, in *chatpb.GenerateRequest) (provider.Provider, *provider.StandardRequest, func(), error) {
+	if s.requireKeys {
+		if msg, _ := checkKey(call.key); msg != "" {
+			return nil, nil, nil, rpcErrorf(http.StatusUnauthorized, "%s", msg)
+		}
+	}
+	if err := s.budgets.Check(call.ctx, call.key); err != nil {
+		return nil, nil, nil, rpcErrorf(http.StatusTooManyRequests, "%v", err)
+	}
+	if in.Model == "" {
+		return nil, nil, nil, rpcErrorf(http.StatusBadRequest, "model is required")
+	}
+	model := in.Model
+	if call.key != nil {
+		target, ok := call.key.ResolveModel(model)
+		if ok {
+			call.warnings = append(call.warnings, provider.Warning{
+				Code:    provider.WarningModelAliased,
+				Message: fmt.Sprintf("model %q is an alias of this key for %q", model, target),
+				Param:   "model",
+			})
+		}
+		model = target
+	}
+	if target, ok := s.r.ResolveModelAlias(model); ok {
+		call.warnings = append(call.warnings, gatewayAliasWarning(model, target))
+		model = target
+	}
+	call.model = model
+	req, err := convertGRPCRequest(model, call.stream, in)
+	if err != nil {
+		return nil, nil, nil, rpcErrorf(http.StatusBadRequest, "%v", err)
+	}
+	if err := s.r.CheckRequestLimits(req); err != nil {
+		return nil, nil, nil, rpcErrorf(http.StatusBadRequest, "%v", err)
+	}
+
+	if call.stream {
+		if shed := s.shedder.Check(); shed != nil {
+			return nil, nil, nil, rpcErrorf(http.StatusServiceUnavailable, "%v", shed)
+		}
+	}
+	// Enrichment attributes come from HTTP headers, so routing rules
+	// matching on them do not apply to gRPC calls
+	routeReq := &provider.RouteRequest{Model: model, Endpoint: call.method, Headers: call.headers, Needs: provider.NeedsOf(req),
+		Conversation: call.headers[textproto.CanonicalMIMEHeaderKey(conversationHeader)]}
+	if variant, ok := s.r.LongContextModel(routeReq); ok {
+		call.warnings = append(call.warnings, longContextWarning(model, variant))
+		model, call.model = variant, variant
+		routeReq.Model, req.Model = variant, variant
+	}
+	p, err := s.r.Route(routeReq)
+	if err != nil {
+		var policyErr *provider.PolicyError
+		if errors.As(err, &policyErr) {
+			return nil, nil, nil, rpcErrorf(http.StatusForbidden, "%v", err)
+		}
+		return nil, nil, nil, rpcErrorf(http.StatusBadRequest, "%v", err)
+	}
+	call.provider, call.route = p.GetInfo().Name, routeReq
+	req.Model = upstreamModel(routeReq)
+
+	release, err := s.adm.Acquire(call.ctx, call.provider, model, nil)
+	if err != nil {
+		var rejected *admission.RejectedError
+		if errors.As(err, &rejected) {
+			return nil, nil, nil, rpcErrorf(http.StatusTooManyRequests, "%v", err)
+		}
+		return nil, nil, nil, rpcErrorf(http.StatusServiceUnavailable, "%v", err)
+	}
+	if err := expandReferences(call.ctx, s.store, s.files, req); err != nil {
+		release()
+		if referenceErrorCode(err) != "" {
+			return nil, nil, nil, rpcErrorf(http.StatusBadRequest, "%v", err)
+		}
+		return nil, nil, nil, rpcErrorf(http.StatusInternalServerError, "%v", err)
+	}
+	warnings, err := s.r.ModelCapabilities(p, model).ApplyLimits(req)
+	if err != nil {
+		release()
+		return nil, nil, nil, rpcErrorf(http.StatusBadRequest, "%v", err)
+	}
+	call.warnings = append(call.warnings, warnings...)
+	return p, req, release, nil
+}
+
+// run generates the whole response, with server-side tools when enabled
+func (s *ChatService) run(call *chatCall, p provider.Provider, req *provider.StandardRequest) (*provider.StandardResponse, error) {
+	var resp *provider.StandardResponse
+	var err error
+	if s.toolRuntime.Enabled() {
+		resp, err = s.toolRuntime.Run(call.ctx, p, req, nil)
+	} else {
+		var gen *provider.GenerateResponse
+		if gen, err = p.Generate(call.ctx, &provider.GenerateRequest{StandardRequest: req}); err == nil {
+			resp = gen.StandardResponse
+		}
+	}
+	var budgetErr *tools.BudgetExceededError
+	if errors.As(err, &budgetErr) {
+		call.usage = &budgetErr.Usage
+		return nil, rpcErrorf(http.StatusUnprocessableEntity, "%v", err)
+	}
+	s.r.ReportOutcome(call.provider, err)
+	if err != nil {
+		return nil, rpcErrorf(http.StatusInternalServerError, "%v", err)
+	}
+	call.usage = &resp.Usage
+	return resp, nil
+}
+
+// Generate returns the whole completion
+func (s *ChatService) Generate(ctx context.Context, in *chatpb.GenerateRequest) (_ *chatpb.GenerateResponse, err error) {
+	call := s.begin(ctx, chatpb.ChatService_Generate_FullMethodName, false)
+	defer func() { s.finish(call, err) }()
+
+	p, req, release, err := s.prepare(call, in)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	resp, err := s.run(call, p, req)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &chatpb.GenerateResponse{
+		Id:       resp.ID,
+		Model:    clientModel(call.route, resp.Model),
+		Usage:    grpcUsage(resp.Usage),
+		Warnings: grpcWarnings(append(call.warnings, resp.Warnings...)),
+	}
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		out.Message = grpcMessage(choice.Message)
+		if choice.FinishReason != nil {
+			out.FinishReason = *choice.FinishReason
+		}
+	}
+	return out, nil
+}
+
+// GenerateStream sends the completion as it is produced
+func (s *ChatService) GenerateStream(in *chatpb.GenerateRequest, stream chatpb.ChatService_GenerateStreamServer) (err error) {
+	call := s.begin(stream.Context(), chatpb.ChatService_GenerateStream_FullMethodName, true)
+	defer func() { s.finish(call, err) }()
+
+	p, req, release, err := s.prepare(call, in)
+	if err != nil {
+		return err
+	}
+	defer release()
+	sender := &chunkSender{stream: stream, model: call.model, warnings: grpcWarnings(call.warnings)}
+	defer func() { call.first = sender.first }()
+
+	trace := s.tracer.Start(call.id, call.keyID, call.method, call.provider, call.model)
+	rc, err := openStream(call.ctx, s.r, s.toolRuntime, p, req)
+	defer func() { finishTrace(trace, err) }()
+	if err != nil {
+		return rpcErrorf(http.StatusInternalServerError, "%v", err)
+	}
+	trace.Upstream()
+	rc = trace.WrapUpstream(rc)
+	defer rc.Close()
+
+	sc := bufio.NewScanner(rc)
+	sc.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		trace.Write(len(sc.Bytes()))
+		if err := sender.line(sc.Bytes()); err != nil {
+			call.usage = sender.usage
+			return err
+		}
+		trace.Flush()
+	}
+	call.usage = sender.usage
+	if err := sc.Err(); err != nil {
+		return rpcErrorf(http.StatusInternalServerError, "%v", err)
+	}
+	return sender.close()
+}
+
+// chunkSender sends provider stream lines as chunks
+type chunkSender struct {
+	stream   chatpb.ChatService_GenerateStreamServer
+	id       string
+	model    string
+	finish   string
+	usage    *provider.Usage
+	warnings []*chatpb.Warning
+	first    time.Time
+}
+
+func (cs *chunkSender) line(line []byte) error {
+	line = bytes.TrimSpace(line)
+	line = bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+	if len(line) == 0 || string(line) == "[DONE]" {
+		return nil
+	}
+	var chunk provider.StreamChunk
+	if err := json.Unmarshal(line, &chunk); err != nil {
+		return rpcErrorf(http.StatusInternalServerError, "invalid stream chunk from provider: %v", err)
+	}
+	if chunk.Error != nil {
+		if chunk.Error.Code == "tool_budget_exceeded" {
+			return rpcErrorf(http.StatusUnprocessableEntity, "%s", chunk.Error.Message)
+		}
+		return rpcErrorf(http.StatusInternalServerError, "%s", chunk.Error.Message)
+	}
+	if cs.id == "" {
+		cs.id = chunk.ID
+	}
+	if chunk.Usage != nil {
+		cs.usage = chunk.Usage
+	}
+	for _, choice := range chunk.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		if choice.FinishReason != nil {
+			cs.finish = *choice.FinishReason
+		}
+		if err := cs.delta(choice.Delta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delta sends one message delta, skipping empty ones
+func (cs *chunkSender) delta(msg *provider.Message) error {
+	if msg == nil || msg.Content == "" && msg.ReasoningContent == "" && msg.FunctionCall == nil {
+		return nil
+	}
+	return cs.send(&chatpb.GenerateChunk{Delta: grpcMessage(msg)})
+}
+
+// close sends the last chunk with the finish reason and usage
+func (cs *chunkSender) close() error {
+	last := &chatpb.GenerateChunk{FinishReason: cs.finish}
+	if cs.usage != nil {
+		last.Usage = grpcUsage(*cs.usage)
+	}
+	return cs.send(last)
+}
+
+func (cs *chunkSender) send(chunk *chatpb.GenerateChunk) error {
+	chunk.Id, chunk.Model = cs.id, cs.model
+	chunk.Warnings, cs.warnings = cs.warnings, nil
+	if cs.first.IsZero() {
+		cs.first = time.Now()
+	}
+	return cs.stream.Send(chunk)
+}
+
+// convertGRPCRequest converts a gRPC request to the standard format.
+// Function parameters arrive as JSON text.
+func convertGRPCRequest(model string, stream bool, in *chatpb.GenerateRequest) (*provider.StandardRequest, error) {
+	req := &provider.StandardRequest{
+		Model:       model,
+		Stream:      stream,
+		Temperature: in.Temperature,
+		TopP:        in.TopP,
+	}
+	if in.MaxTokens != nil {
+		n := int(*in.MaxTokens)
+		req.MaxTokens = &n
+	}
+	for _, m := range in.Messages {
+		msg := provider.Message{Role: m.Role, Content: m.Content, ReasoningContent: m.ReasoningContent}
+		if m.Name != "" {
+			name := m.Name
+			msg.Name = &name
+		}
+		if call := m.FunctionCall; call != nil {
+			msg.FunctionCall = &provider.FunctionCall{Name: call.Name, Arguments: call.Arguments}
+		}
+		req.Messages = append(req.Messages, msg)
+	}
+	for i, f := range in.Functions {
+		fn := provider.Function{Name: f.Name, Description: f.Description}
+		if f.Parameters != "" {
+			if err := json.Unmarshal([]byte(f.Parameters), &fn.Parameters); err != nil {
+				return nil, fmt.Errorf("functions[%d].parameters: %w", i, err)
+			}
+		}
+		req.Functions = append(req.Functions, fn)
+	}
+	return req, nil
+}
+
+func grpcMessage(msg *provider.Message) *chatpb.Message {
+	if msg == nil {
+		return nil
+	}
+	out := &chatpb.Message{Role: msg.Role, Content: msg.Content, ReasoningContent: msg.ReasoningContent}
+	if msg.Name != nil {
+		out.Name = *msg.Name
+	}
+	if call := msg.FunctionCall; call != nil {
+		out.FunctionCall = &chatpb.FunctionCall{Name: call.Name, Arguments: call.Arguments}
+	}
+	return out
+}
+
+func grpcUsage(u provider.Usage) *chatpb.Usage {
+	return &chatpb.Usage{
+		PromptTokens:       int32(u.PromptTokens),
+		CompletionTokens:   int32(u.CompletionTokens),
+		TotalTokens:        int32(u.TotalTokens),
+		CachedPromptTokens: int32(u.CachedPromptTokens),
+		ReasoningTokens:    int32(u.ReasoningTokens),
+	}
+}
+
+func grpcWarnings(warnings []provider.Warning) []*chatpb.Warning {
+	var out []*chatpb.Warning
+	for _, w := range warnings {
+		out = append(out, &chatpb.Warning{Code: w.Code, Message: w.Message, Param: w.Param})
+	}
+	return out
+}
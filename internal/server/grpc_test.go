@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/budget"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/server/chatpb"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPCChatService(t *testing.T) {
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}, Responses: []config.MockResponse{
+		{Match: "weather", Content: "Let me check", FunctionCall: &config.MockFunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+		{Content: "Hello there"},
+	}}}}
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	keyStore := keys.NewMemoryStore()
+	_ = keyStore.Put(&keys.Key{ID: "team-a", Hash: keys.HashSecret("sk-a"), ModelAliases: map[string]string{"prod-chat": "demo-chat"}})
+	usageStore := usage.NewMemoryStore(10)
+	recent := NewRecentRequests(cfg)
+	budgets, err := budget.NewTracker(cfg, m, nil, usageStore, keyStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewChatService(cfg, r, admission.NewController(cfg, m), artifacts.NewMemoryStore(), nil, usageStore, keyStore,
+		tools.NewRuntime(cfg, m), recent, loadshed.New(cfg, m), nil, budgets)
+
+	ln := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	chatpb.RegisterChatServiceServer(srv, svc)
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Stop()
+	conn, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := chatpb.NewChatServiceClient(conn)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer sk-a", "x-request-id", "req-grpc-1")
+	var header metadata.MD
+	resp, err := client.Generate(ctx, &chatpb.GenerateRequest{Model: "prod-chat", Messages: []*chatpb.Message{{Role: "user", Content: "what is the weather"}}},
+		grpc.Header(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Message.GetContent() != "Let me check" || resp.Message.GetFunctionCall().GetName() != "get_weather" || resp.Usage.GetTotalTokens() == 0 {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if len(resp.Warnings) != 1 || resp.Warnings[0].Code != provider.WarningModelAliased {
+		t.Errorf("Expected an alias warning, got %+v", resp.Warnings)
+	}
+	if ids := header.Get("x-request-id"); len(ids) != 1 || ids[0] != "req-grpc-1" {
+		t.Errorf("Expected the request ID echoed, got %v", ids)
+	}
+	records, _ := usageStore.Query(time.Time{}, time.Now().Add(time.Hour))
+	if len(records) != 1 || records[0].KeyID != "team-a" || records[0].Model != "demo-chat" || records[0].Provider != "demo" || records[0].Status != 200 {
+		t.Errorf("Unexpected usage %+v", records)
+	}
+
+	stream, err := client.GenerateStream(ctx, &chatpb.GenerateRequest{Model: "demo-chat", Messages: []*chatpb.Message{{Role: "user", Content: "weather in Paris"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text, args string
+	var last *chatpb.GenerateChunk
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		text += chunk.Delta.GetContent()
+		args += chunk.Delta.GetFunctionCall().GetArguments()
+		last = chunk
+	}
+	if text != "Let me check" || args != `{"city":"Paris"}` {
+		t.Errorf("Unexpected streamed text %q and arguments %q", text, args)
+	}
+	if last == nil || last.Delta != nil || last.FinishReason == "" || last.Usage == nil {
+		t.Errorf("Expected a last chunk with the finish reason and usage, got %+v", last)
+	}
+	summary := recent.List(1, func(*RequestSummary) bool { return true })
+	if len(summary) != 1 || summary[0].Path != chatpb.ChatService_GenerateStream_FullMethodName || !summary[0].Stream || summary[0].CompletionTokens == 0 {
+		t.Errorf("Unexpected stream summary %+v", summary)
+	}
+
+	for name, tc := range map[string]struct {
+		req  *chatpb.GenerateRequest
+		code codes.Code
+	}{
+		"no model":       {&chatpb.GenerateRequest{}, codes.InvalidArgument},
+		"unknown model":  {&chatpb.GenerateRequest{Model: "nope", Messages: []*chatpb.Message{{Role: "user", Content: "hi"}}}, codes.InvalidArgument},
+		"bad parameters": {&chatpb.GenerateRequest{Model: "demo-chat", Functions: []*chatpb.Function{{Name: "f", Parameters: "{"}}}, codes.InvalidArgument},
+	} {
+		if _, err := client.Generate(context.Background(), tc.req); status.Code(err) != tc.code {
+			t.Errorf("%s: expected %s, got %v", name, tc.code, err)
+		}
+	}
+	records, _ = usageStore.Query(time.Time{}, time.Now().Add(time.Hour))
+	if last := records[len(records)-1]; last.Status != 400 {
+		t.Errorf("Expected failed calls recorded with their HTTP status, got %+v", last)
+	}
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/health"
+)
+
+// RegisterHealthRoutes wires liveness and readiness probes
+func RegisterHealthRoutes(engine *gin.Engine, checker *health.Checker) {
+	// Liveness: the process is up and serving HTTP
+	engine.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+	})
+
+	// Readiness: every dependency check passes; 503 keeps the pod out of rotation
+	engine.GET("/readyz", func(c *gin.Context) {
+		report := checker.Run(c.Request.Context())
+		status := http.StatusOK
+		if report.Status != health.StatusReady {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, report)
+	})
+}
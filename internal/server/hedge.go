@@ -0,0 +1,206 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"golang.org/x/sync/errgroup"
+)
+
+// hedgeHeader names the provider whose stream was relayed when a route
+// hedged the request
+const hedgeHeader = "X-LetLLM-Hedge"
+
+// firstChunkSize bounds the first read from a hedged stream
+const firstChunkSize = 4096
+
+// hedger starts streams on their route's hedge provider too when the routed
+// provider is slow to start streaming, and relays whichever starts first
+type hedger struct {
+	r       *provider.Router
+	adm     *admission.Controller
+	streams *metrics.CounterVec
+}
+
+func newHedger(r *provider.Router, adm *admission.Controller, m *metrics.Registry) *hedger {
+	return &hedger{
+		r:   r,
+		adm: adm,
+		streams: m.Counter("letllm_hedged_streams_total",
+			"Streams sent to a route's hedge provider by the provider relayed and winner: primary or hedge.",
+			"provider", "winner"),
+	}
+}
+
+// stream starts req on p and returns the provider streaming it. When the
+// route routeReq matches has a hedge and p streams nothing within its
+// delay, or fails before it, req is started on the hedge too, once
+// admitted there with attrs; the first of the two to stream a chunk is
+// returned and the other cancelled.
+func (h *hedger) stream(c *gin.Context, routeReq *provider.RouteRequest, p provider.Provider, req *provider.StandardRequest, attrs map[string]string) (provider.Provider, io.ReadCloser, error) {
+	ctx := c.Request.Context()
+	hp, model, after, ok := h.r.Hedge(routeReq)
+	if !ok {
+		rc, err := p.StreamGenerate(ctx, &provider.GenerateRequest{StandardRequest: req})
+		h.r.ReportOutcome(p.GetInfo().Name, err)
+		return p, rc, err
+	}
+
+	primaryCtx, cancelPrimary := context.WithCancel(ctx)
+	hedgeCtx, cancelHedge := context.WithCancel(ctx)
+	var (
+		mu     sync.Mutex
+		won    *hedgeAttempt
+		hedged bool
+	)
+	// claim makes a the stream relayed, cancelling the other attempt,
+	// unless the other already is; a is closed then
+	claim := func(a *hedgeAttempt) {
+		mu.Lock()
+		defer mu.Unlock()
+		if won != nil {
+			a.close()
+			return
+		}
+		won = a
+		if a.hedge {
+			cancelPrimary()
+		} else {
+			cancelHedge()
+		}
+	}
+	lost := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return won != nil
+	}
+	// attempt streams req on p and reports the outcome, unless p lost the
+	// race and was cancelled for it
+	attempt := func(ctx context.Context, cancel context.CancelFunc, p provider.Provider, req *provider.StandardRequest, release func()) error {
+		a := firstChunk(ctx, cancel, p, req)
+		a.hedge, a.release = release != nil, release
+		if a.err != nil {
+			a.close()
+			if ctx.Err() != nil && lost() {
+				return nil
+			}
+			h.r.ReportOutcome(p.GetInfo().Name, a.err)
+			return a.err
+		}
+		h.r.ReportOutcome(p.GetInfo().Name, nil)
+		claim(a)
+		return nil
+	}
+
+	primaryFailed := make(chan struct{})
+	var g errgroup.Group
+	g.Go(func() error {
+		err := attempt(primaryCtx, cancelPrimary, p, req, nil)
+		if err != nil {
+			close(primaryFailed)
+		}
+		return err
+	})
+	g.Go(func() error {
+		timer := time.NewTimer(after)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-primaryFailed:
+		case <-hedgeCtx.Done():
+			return nil
+		}
+		hedgeReq := *req
+		hedgeReq.Model = model
+		release, err := h.adm.Acquire(hedgeCtx, hp.GetInfo().Name, model, attrs)
+		if err != nil {
+			cancelHedge()
+			return err
+		}
+		hedged = true
+		return attempt(hedgeCtx, cancelHedge, hp, &hedgeReq, release)
+	})
+	err := g.Wait()
+	if won == nil {
+		return p, nil, err
+	}
+
+	if hedged {
+		winner := "primary"
+		if won.hedge {
+			winner = "hedge"
+			setRequestProvider(c, won.p)
+		}
+		h.streams.Inc(won.p.GetInfo().Name, winner)
+		c.Header(hedgeHeader, won.p.GetInfo().Name)
+	}
+	return won.p, won, nil
+}
+
+// hedgeAttempt is a stream started on one provider with its first chunk
+// already read
+type hedgeAttempt struct {
+	p      provider.Provider
+	rc     io.ReadCloser
+	r      io.Reader
+	err    error
+	cancel context.CancelFunc
+	// hedge is set on the attempt on the hedge provider, and release frees
+	// its admission slot there
+	hedge   bool
+	release func()
+}
+
+// firstChunk starts req on p and waits for the first chunk it streams.
+// Failed attempts hold no stream but must still be closed.
+func firstChunk(ctx context.Context, cancel context.CancelFunc, p provider.Provider, req *provider.StandardRequest) *hedgeAttempt {
+	a := &hedgeAttempt{p: p, cancel: cancel}
+	if a.rc, a.err = p.StreamGenerate(ctx, &provider.GenerateRequest{StandardRequest: req}); a.err != nil {
+		a.rc = nil
+		return a
+	}
+	buf := make([]byte, firstChunkSize)
+	for {
+		n, err := a.rc.Read(buf)
+		if n > 0 || err == io.EOF {
+			a.r = io.MultiReader(bytes.NewReader(buf[:n]), a.rc)
+			return a
+		}
+		if err != nil {
+			_ = a.rc.Close()
+			a.rc, a.err = nil, err
+			return a
+		}
+	}
+}
+
+func (a *hedgeAttempt) Read(b []byte) (int, error) {
+	return a.r.Read(b)
+}
+
+func (a *hedgeAttempt) Close() error {
+	defer a.done()
+	return a.rc.Close()
+}
+
+// close releases an attempt that is not relayed
+func (a *hedgeAttempt) close() {
+	if a.rc != nil {
+		_ = a.rc.Close()
+	}
+	a.done()
+}
+
+func (a *hedgeAttempt) done() {
+	a.cancel()
+	if a.release != nil {
+		a.release()
+	}
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/idempotency"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+)
+
+// idempotencyKeyHeader carries the client's key for a request it may retry
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyHeader is set to "replayed" on responses served from an
+// earlier request with the same Idempotency-Key
+const idempotencyHeader = "X-LetLLM-Idempotency"
+
+// maxIdempotencyKeyLength bounds the keys clients may send
+const maxIdempotencyKeyLength = 255
+
+// RegisterIdempotency honors the Idempotency-Key header of POST API
+// requests. It runs after RegisterKeyAuth, so replays need a valid key, but
+// before RegisterBudgets, so replays are neither refused nor charged.
+func RegisterIdempotency(engine *gin.Engine, store *idempotency.Store, keyStore keys.Store) {
+	if store.Enabled() {
+		engine.Use(honorIdempotencyKey(store, keyStore))
+	}
+}
+
+// honorIdempotencyKey answers POST /v1 and /v1beta requests carrying an
+// Idempotency-Key with the response of the first request the caller sent
+// with it, waiting for that request when it is still in flight. A key
+// reused for a different request is refused with 422. Multipart uploads
+// are passed on as they are: their bodies are only bounded by their
+// routes, so they are never read whole here.
+func honorIdempotencyKey(store *idempotency.Store, keyStore keys.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		key := c.GetHeader(idempotencyKeyHeader)
+		if key == "" || c.Request.Method != http.MethodPost || strings.HasPrefix(c.ContentType(), "multipart/") ||
+			!strings.HasPrefix(path, "/v1/") && !strings.HasPrefix(path, "/v1beta/") {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters", "code": "invalid_idempotency_key"})
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(c.Request, body)
+
+		stored, finish, err := store.Begin(c.Request.Context(), idempotency.Key(callerKeyID(c, keyStore), key))
+		if err != nil {
+			// The client went away while waiting
+			c.Abort()
+			return
+		}
+		if stored != nil {
+			replayResponse(c, stored, fingerprint)
+			return
+		}
+
+		w := &capturingWriter{ResponseWriter: c.Writer, limit: store.MaxResponseBytes()}
+		var kept *idempotency.Response
+		defer func() { finish(kept) }()
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		// Responses cut short by the client leaving are not complete
+		if idempotency.Storable(w.Status()) && !w.overflow && c.Request.Context().Err() == nil {
+			header := w.Header().Clone()
+			header.Del(requestIDHeader)
+			kept = &idempotency.Response{Status: w.Status(), Header: header, Body: w.body.Bytes(), Fingerprint: fingerprint}
+		}
+	}
+}
+
+// replayResponse writes a stored response, unless it answered a different
+// request
+func replayResponse(c *gin.Context, resp *idempotency.Response, fingerprint string) {
+	if resp.Fingerprint != fingerprint {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": "Idempotency-Key was already used for a different request",
+			"code":  "idempotency_key_reused",
+		})
+		return
+	}
+	header := c.Writer.Header()
+	for name, values := range resp.Header {
+		header[name] = values
+	}
+	header.Set(idempotencyHeader, "replayed")
+	c.Status(resp.Status)
+	_, _ = c.Writer.Write(resp.Body)
+	c.Abort()
+}
+
+// requestFingerprint identifies a request by its method, URL and body
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI()))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// capturingWriter keeps a copy of the response body, giving up beyond limit
+type capturingWriter struct {
+	gin.ResponseWriter
+	limit    int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *capturingWriter) capture(b []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(b) > w.limit {
+		w.overflow = true
+		w.body = bytes.Buffer{}
+		return
+	}
+	w.body.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the connection
+func (w *capturingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
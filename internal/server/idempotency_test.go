@@ -0,0 +1,148 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/cache"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/idempotency"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Idempotency.TTL = time.Hour
+	cfg.Idempotency.MaxResponseBytes = 1 << 20
+	keyStore := keys.NewMemoryStore()
+	_ = keyStore.Put(&keys.Key{ID: "team-a", Hash: keys.HashSecret("sk-a")})
+	_ = keyStore.Put(&keys.Key{ID: "team-b", Hash: keys.HashSecret("sk-b")})
+
+	engine := gin.New()
+	engine.Use(assignRequestID())
+	RegisterIdempotency(engine, idempotency.NewStore(cfg, cache.NewMemory(100, 1<<20)), keyStore)
+	var calls atomic.Int32
+	release := make(chan struct{})
+	engine.POST("/v1/chat/completions", func(c *gin.Context) {
+		n := calls.Add(1)
+		<-release
+		body, _ := io.ReadAll(c.Request.Body)
+		if strings.Contains(string(body), "fail") {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "upstream failed"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"call": n})
+	})
+
+	send := func(token, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(idempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// Duplicates sent while the first is in flight wait for its response
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 3)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = send("sk-a", "retry-1", `{"n":1}`)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	replayed := 0
+	for _, w := range responses {
+		if w.Code != http.StatusOK || w.Body.String() != `{"call":1}` {
+			t.Fatalf("Expected the first call's response, got %d %s", w.Code, w.Body)
+		}
+		if w.Header().Get(idempotencyHeader) == "replayed" {
+			replayed++
+		}
+	}
+	if calls.Load() != 1 || replayed != 2 {
+		t.Fatalf("Expected one call and two replays, got %d calls and %d replays", calls.Load(), replayed)
+	}
+
+	// Retries get the stored response, with their own request ID
+	w := send("sk-a", "retry-1", `{"n":1}`)
+	if w.Body.String() != `{"call":1}` || w.Header().Get(idempotencyHeader) != "replayed" || w.Header().Get(requestIDHeader) == responses[0].Header().Get(requestIDHeader) {
+		t.Errorf("Expected a replay, got %d %s %v", w.Code, w.Body, w.Header())
+	}
+	if w := send("sk-a", "retry-1", `{"n":2}`); w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "idempotency_key_reused") {
+		t.Errorf("Expected a key reused for another request refused, got %d %s", w.Code, w.Body)
+	}
+	// Keys are scoped to the caller
+	if w := send("sk-b", "retry-1", `{"n":1}`); w.Body.String() != `{"call":2}` {
+		t.Errorf("Expected another caller's request served, got %d %s", w.Code, w.Body)
+	}
+
+	// Server errors are not kept, so retries try again
+	send("sk-a", "retry-2", `{"fail":true}`)
+	if w := send("sk-a", "retry-2", `{"fail":true}`); w.Code != http.StatusBadGateway || w.Header().Get(idempotencyHeader) != "" || calls.Load() != 4 {
+		t.Errorf("Expected a failed request retried, got %d after %d calls", w.Code, calls.Load())
+	}
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestIdempotencyKeyLeavesUploadsToTheirRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Idempotency.TTL = time.Hour
+	cfg.Idempotency.MaxResponseBytes = 1 << 20
+	cfg.Files.MaxBytes = 64
+	keyStore := keys.NewMemoryStore()
+	_ = keyStore.Put(&keys.Key{ID: "team-a", Hash: keys.HashSecret("sk-a")})
+	engine := gin.New()
+	RegisterIdempotency(engine, idempotency.NewStore(cfg, cache.NewMemory(100, 1<<20)), keyStore)
+	RegisterFileRoutes(engine, cfg, files.NewStore(files.NewMemoryMetadata(), files.NewMemoryBlobs()), keyStore)
+
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	_ = mw.WriteField("purpose", files.PurposeUserData)
+	fw, _ := mw.CreateFormFile("file", "big.bin")
+	_, _ = fw.Write(bytes.Repeat([]byte("x"), 8<<20))
+	_ = mw.Close()
+	size := form.Len()
+	body := &countingReader{Reader: &form}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/files", body)
+	req.Header.Set("Authorization", "Bearer sk-a")
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set(idempotencyKeyHeader, "upload-1")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected the oversized upload refused, got %d %s", w.Code, w.Body)
+	}
+	if body.n >= size {
+		t.Errorf("Expected the upload not read whole, read %d of %d bytes", body.n, size)
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// RegisterRequestLimits refuses API requests with bodies larger than
+// request_limits.max_body_bytes. It runs before RegisterIdempotency, so
+// oversized bodies are not read whole, let alone kept.
+func RegisterRequestLimits(engine *gin.Engine, cfg *config.Config) {
+	if cfg.RequestLimits.MaxBodyBytes > 0 {
+		engine.Use(limitBodySize(cfg.RequestLimits.MaxBodyBytes))
+	}
+}
+
+// limitBodySize answers /v1 and /v1beta requests with bodies over max bytes
+// with a 413. Multipart uploads are left to the limits of their routes.
+func limitBodySize(max int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if c.Request.Body == nil || strings.HasPrefix(c.ContentType(), "multipart/") ||
+			!strings.HasPrefix(path, "/v1/") && !strings.HasPrefix(path, "/v1beta/") {
+			c.Next()
+			return
+		}
+		tooLarge := func() {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes", max), "code": "request_too_large"})
+		}
+		if c.Request.ContentLength > max {
+			tooLarge()
+			return
+		}
+		// Chunked bodies declare no length
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, max+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if int64(len(body)) > max {
+			tooLarge()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+// checkRequestLimits answers requests over the request_limits with a 400
+// and returns false. It is given the client's own messages, before routing
+// and admission, so refused requests take no concurrency slot.
+func checkRequestLimits(c *gin.Context, r *provider.Router, req *provider.StandardRequest) bool {
+	err := r.CheckRequestLimits(req)
+	var limitErr *provider.LimitError
+	if errors.As(err, &limitErr) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": limitErr.Code})
+		return false
+	}
+	return true
+}
+
+// applyModelLimits fits req to the limits of the model serving it, warning
+// the client about any adjustment. Requests that cannot fit are answered
+// with a 400 and false is returned.
+func applyModelLimits(c *gin.Context, model provider.ModelCapabilities, req *provider.StandardRequest) bool {
+	warnings, err := model.ApplyLimits(req)
+	if err != nil {
+		var limitErr *provider.LimitError
+		if errors.As(err, &limitErr) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": limitErr.Code})
+			return false
+		}
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	for _, w := range warnings {
+		addWarning(c, w)
+	}
+	return true
+}
+
+// fitContextLength switches routeReq and req to the long_context_model of
+// their model when the request does not fit it, warning the client, and
+// returns the model to serve
+func fitContextLength(c *gin.Context, r *provider.Router, routeReq *provider.RouteRequest, req *provider.StandardRequest) string {
+	variant, ok := r.LongContextModel(routeReq)
+	if !ok {
+		return routeReq.Model
+	}
+	addWarning(c, longContextWarning(routeReq.Model, variant))
+	routeReq.Model, req.Model = variant, variant
+	setRequestModel(c, variant)
+	return variant
+}
+
+// longContextWarning reports the substitution of a long_context_model
+func longContextWarning(model, variant string) provider.Warning {
+	return provider.Warning{
+		Code:    provider.WarningLongContextModel,
+		Message: fmt.Sprintf("request exceeds the context length of %q and is served by %q", model, variant),
+		Param:   "model",
+	}
+}
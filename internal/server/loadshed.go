@@ -0,0 +1,31 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+)
+
+// RegisterLoadSheddingRoutes wires inspection and tuning of load shedding
+func RegisterLoadSheddingRoutes(admin *AdminRouter, shedder *loadshed.Shedder) {
+	admin.GET("/load-shedding", func(c *gin.Context) {
+		c.JSON(http.StatusOK, shedder.Status())
+	})
+
+	// PUT /admin/load-shedding replaces the policy; it is not persisted and
+	// reverts to the configured one on restart
+	admin.PUT("/load-shedding", func(c *gin.Context) {
+		var p loadshed.Policy
+		if err := c.ShouldBindJSON(&p); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+			return
+		}
+		if err := shedder.SetPolicy(p); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, shedder.Status())
+	})
+}
@@ -0,0 +1,735 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/tracing"
+)
+
+// AnthropicMessagesRequest is the body of POST /v1/messages, in the wire
+// format of Anthropic's Messages API
+type AnthropicMessagesRequest struct {
+	Model string `json:"model"`
+	// A string, or an array of text blocks
+	System        json.RawMessage    `json:"system,omitempty"`
+	Messages      []AnthropicMessage `json:"messages"`
+	MaxTokens     *int               `json:"max_tokens,omitempty"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream"`
+	Tools         []AnthropicTool    `json:"tools,omitempty"`
+}
+
+// AnthropicMessage is a turn of the conversation
+type AnthropicMessage struct {
+	Role string `json:"role"`
+	// A string, or an array of content blocks
+	Content json.RawMessage `json:"content"`
+}
+
+// AnthropicTool is a client tool; its input schema becomes the function's
+// parameters
+type AnthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema,omitempty"`
+}
+
+// AnthropicContentBlock is a text, thinking, tool_use or tool_result block
+type AnthropicContentBlock struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Thinking string `json:"thinking,omitempty"`
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// tool_result; content is a string or an array of text blocks
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// AnthropicMessageResponse is the message returned by POST /v1/messages
+type AnthropicMessageResponse struct {
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	Role         string                  `json:"role"`
+	Content      []AnthropicContentBlock `json:"content"`
+	Model        string                  `json:"model"`
+	StopReason   *string                 `json:"stop_reason"`
+	StopSequence *string                 `json:"stop_sequence"`
+	Usage        AnthropicUsage          `json:"usage"`
+
+	// Extension: non-fatal notices about gateway-side adjustments
+	Warnings []provider.Warning `json:"warnings,omitempty"`
+}
+
+type AnthropicUsage struct {
+	InputTokens          int `json:"input_tokens"`
+	OutputTokens         int `json:"output_tokens"`
+	CacheReadInputTokens int `json:"cache_read_input_tokens"`
+}
+
+// AnthropicErrorResponse is the body of /v1/messages errors, so Anthropic
+// clients can parse them
+type AnthropicErrorResponse struct {
+	Type  string         `json:"type"`
+	Error AnthropicError `json:"error"`
+}
+
+type AnthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	// Extension: the gateway's error code, as in other endpoints' errors
+	Code string `json:"code,omitempty"`
+}
+
+// Content block types of the Messages API
+const (
+	anthropicBlockText       = "text"
+	anthropicBlockThinking   = "thinking"
+	anthropicBlockToolUse    = "tool_use"
+	anthropicBlockToolResult = "tool_result"
+)
+
+// anthropicBlocks decodes content, which is a string or an array of blocks
+func anthropicBlocks(raw json.RawMessage) ([]AnthropicContentBlock, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return []AnthropicContentBlock{{Type: anthropicBlockText, Text: text}}, nil
+	}
+	var blocks []AnthropicContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return nil, fmt.Errorf("content must be a string or an array of content blocks")
+	}
+	return blocks, nil
+}
+
+// anthropicText returns the text of content made only of text blocks
+func anthropicText(raw json.RawMessage) (string, error) {
+	blocks, err := anthropicBlocks(raw)
+	if err != nil {
+		return "", err
+	}
+	texts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Type != anthropicBlockText {
+			return "", fmt.Errorf("content block type %q is not supported here", block.Type)
+		}
+		texts = append(texts, block.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// convertMessagesRequest converts a Messages API request to the standard
+// format. Each tool_use becomes an assistant function call and each
+// tool_result a function message named after the tool it answers.
+func convertMessagesRequest(in *AnthropicMessagesRequest) (*provider.StandardRequest, error) {
+	var messages []provider.Message
+	system, err := anthropicText(in.System)
+	if err != nil {
+		return nil, fmt.Errorf("system: %w", err)
+	}
+	if system != "" {
+		messages = append(messages, provider.Message{Role: provider.RoleSystem, Content: system})
+	}
+
+	toolNames := make(map[string]string)
+	for i, msg := range in.Messages {
+		if msg.Role != provider.RoleUser && msg.Role != provider.RoleAssistant {
+			return nil, fmt.Errorf("messages[%d]: unknown role %q", i, msg.Role)
+		}
+		blocks, err := anthropicBlocks(msg.Content)
+		if err != nil {
+			return nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		var texts []string
+		var calls []*provider.FunctionCall
+		results := 0
+		for j, block := range blocks {
+			switch block.Type {
+			case anthropicBlockText:
+				texts = append(texts, block.Text)
+			case anthropicBlockToolUse:
+				if msg.Role != provider.RoleAssistant {
+					return nil, fmt.Errorf("messages[%d].content[%d]: tool_use is only allowed in assistant messages", i, j)
+				}
+				toolNames[block.ID] = block.Name
+				args := string(block.Input)
+				if args == "" {
+					args = "{}"
+				}
+				calls = append(calls, &provider.FunctionCall{Name: block.Name, Arguments: args})
+			case anthropicBlockToolResult:
+				if msg.Role != provider.RoleUser {
+					return nil, fmt.Errorf("messages[%d].content[%d]: tool_result is only allowed in user messages", i, j)
+				}
+				name, ok := toolNames[block.ToolUseID]
+				if !ok {
+					return nil, fmt.Errorf("messages[%d].content[%d]: no tool_use with id %q", i, j, block.ToolUseID)
+				}
+				output, err := anthropicText(block.Content)
+				if err != nil {
+					return nil, fmt.Errorf("messages[%d].content[%d]: %w", i, j, err)
+				}
+				if block.IsError {
+					output = "Error: " + output
+				}
+				messages = append(messages, provider.Message{Role: provider.RoleFunction, Name: &name, Content: output})
+				results++
+			case anthropicBlockThinking, "redacted_thinking":
+				// Earlier reasoning is not replayed to providers
+			default:
+				return nil, fmt.Errorf("messages[%d].content[%d]: content block type %q is not supported", i, j, block.Type)
+			}
+		}
+
+		// A message carries one function call, so further calls of the
+		// same turn follow as assistant messages of their own
+		if len(texts) > 0 || len(calls)+results == 0 {
+			messages = append(messages, provider.Message{Role: msg.Role, Content: strings.Join(texts, "\n")})
+		}
+		for k, call := range calls {
+			if k == 0 && len(texts) > 0 {
+				messages[len(messages)-1].FunctionCall = call
+				continue
+			}
+			messages = append(messages, provider.Message{Role: provider.RoleAssistant, FunctionCall: call})
+		}
+	}
+
+	var functions []provider.Function
+	for _, tool := range in.Tools {
+		functions = append(functions, provider.Function{Name: tool.Name, Description: tool.Description, Parameters: tool.InputSchema})
+	}
+
+	req := &provider.StandardRequest{
+		Model:       in.Model,
+		Messages:    messages,
+		Stream:      in.Stream,
+		MaxTokens:   in.MaxTokens,
+		Temperature: in.Temperature,
+		TopP:        in.TopP,
+		Functions:   functions,
+	}
+	if len(in.StopSequences) > 0 {
+		// Forwarded under the OpenAI name by providers that support passthrough
+		stop, _ := json.Marshal(in.StopSequences)
+		req.Passthrough = map[string]json.RawMessage{"stop": stop}
+	}
+	return req, nil
+}
+
+// anthropicStopReason maps a finish reason to the Messages API stop reason
+func anthropicStopReason(finishReason string) string {
+	switch finishReason {
+	case "length":
+		return "max_tokens"
+	case "function_call", "tool_calls":
+		return "tool_use"
+	case "content_filter":
+		return "refusal"
+	default:
+		return "end_turn"
+	}
+}
+
+func anthropicUsage(u provider.Usage) AnthropicUsage {
+	return AnthropicUsage{
+		InputTokens:          u.PromptTokens - u.CachedPromptTokens,
+		OutputTokens:         u.CompletionTokens,
+		CacheReadInputTokens: u.CachedPromptTokens,
+	}
+}
+
+// toolInput returns function call arguments as a tool_use input, which must
+// be a JSON value
+func toolInput(arguments string) json.RawMessage {
+	if strings.TrimSpace(arguments) == "" {
+		return json.RawMessage("{}")
+	}
+	if json.Valid([]byte(arguments)) {
+		return json.RawMessage(arguments)
+	}
+	b, _ := json.Marshal(arguments)
+	return b
+}
+
+// newAnthropicMessage returns an assistant message without content
+func newAnthropicMessage(model string) AnthropicMessageResponse {
+	return AnthropicMessageResponse{
+		ID:      "msg_" + ids.New(),
+		Type:    "message",
+		Role:    provider.RoleAssistant,
+		Content: []AnthropicContentBlock{},
+		Model:   model,
+	}
+}
+
+// convertToAnthropicMessage converts the first choice of a standard
+// response to a Messages API message
+func convertToAnthropicMessage(resp *provider.StandardResponse) AnthropicMessageResponse {
+	out := newAnthropicMessage(resp.Model)
+	finishReason := ""
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		if choice.FinishReason != nil {
+			finishReason = *choice.FinishReason
+		}
+		if msg := choice.Message; msg != nil {
+			if msg.ReasoningContent != "" {
+				out.Content = append(out.Content, AnthropicContentBlock{Type: anthropicBlockThinking, Thinking: msg.ReasoningContent})
+			}
+			if msg.Content != "" {
+				out.Content = append(out.Content, AnthropicContentBlock{Type: anthropicBlockText, Text: msg.Content})
+			}
+			if call := msg.FunctionCall; call != nil {
+				out.Content = append(out.Content, AnthropicContentBlock{
+					Type:  anthropicBlockToolUse,
+					ID:    "toolu_" + ids.New(),
+					Name:  call.Name,
+					Input: toolInput(call.Arguments),
+				})
+				finishReason = "function_call"
+			}
+		}
+	}
+	stop := anthropicStopReason(finishReason)
+	out.StopReason = &stop
+	out.Usage = anthropicUsage(resp.Usage)
+	out.Warnings = resp.Warnings
+	return out
+}
+
+// anthropicErrorType is the Messages API error type of an HTTP status
+func anthropicErrorType(status int) string {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusForbidden:
+		return "permission_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case http.StatusServiceUnavailable:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// abortMessages ends a /v1/messages request with an error in the Messages
+// API shape
+func abortMessages(c *gin.Context, status int, message, code string) {
+	c.AbortWithStatusJSON(status, AnthropicErrorResponse{
+		Type:  "error",
+		Error: AnthropicError{Type: anthropicErrorType(status), Message: message, Code: code},
+	})
+}
+
+// messagesEncoder turns provider stream chunks into Messages API streaming
+// events. Content blocks are started as the deltas for them arrive and
+// stopped when the next kind of delta starts.
+type messagesEncoder struct {
+	w       io.Writer
+	enc     *json.Encoder
+	msg     AnthropicMessageResponse
+	partial []byte
+	started bool
+
+	// Type of the open content block, or ""
+	open   string
+	blocks int
+	calls  bool
+	finish string
+	usage  *provider.Usage
+}
+
+func newMessagesEncoder(w io.Writer, model string) *messagesEncoder {
+	return &messagesEncoder{w: w, enc: json.NewEncoder(w), msg: newAnthropicMessage(model)}
+}
+
+// Write encodes every complete line in b, keeping a trailing partial line
+// for the next call
+func (e *messagesEncoder) Write(b []byte) error {
+	e.partial = append(e.partial, b...)
+	for {
+		i := bytes.IndexByte(e.partial, '\n')
+		if i < 0 {
+			return nil
+		}
+		if err := e.encodeLine(e.partial[:i]); err != nil {
+			return err
+		}
+		e.partial = e.partial[i+1:]
+	}
+}
+
+// Close encodes any unterminated last line, stops the open block and ends
+// the message
+func (e *messagesEncoder) Close() error {
+	if err := e.encodeLine(e.partial); err != nil {
+		return err
+	}
+	e.partial = nil
+	if err := e.start(); err != nil {
+		return err
+	}
+	if err := e.stopBlock(); err != nil {
+		return err
+	}
+	finish := e.finish
+	if e.calls {
+		finish = "function_call"
+	}
+	var usage AnthropicUsage
+	if e.usage != nil {
+		usage = anthropicUsage(*e.usage)
+	}
+	delta := gin.H{"stop_reason": anthropicStopReason(finish), "stop_sequence": nil}
+	if err := e.event("message_delta", gin.H{"delta": delta, "usage": usage}); err != nil {
+		return err
+	}
+	return e.event("message_stop", gin.H{})
+}
+
+// Usage returns the token usage reported by the stream, if any
+func (e *messagesEncoder) Usage() *provider.Usage {
+	return e.usage
+}
+
+func (e *messagesEncoder) encodeLine(line []byte) error {
+	line = bytes.TrimSpace(line)
+	line = bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+	if len(line) == 0 || string(line) == "[DONE]" {
+		return nil
+	}
+
+	var chunk provider.StreamChunk
+	if err := json.Unmarshal(line, &chunk); err != nil {
+		return e.fail(fmt.Errorf("invalid stream chunk from provider: %w", err))
+	}
+	if chunk.Error != nil {
+		return e.fail(errors.New(chunk.Error.Message))
+	}
+	if chunk.Usage != nil {
+		e.usage = chunk.Usage
+	}
+	for _, choice := range chunk.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		if choice.FinishReason != nil {
+			e.finish = *choice.FinishReason
+		}
+		if choice.Delta != nil {
+			if err := e.delta(choice.Delta); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// delta sends the events for one message delta
+func (e *messagesEncoder) delta(msg *provider.Message) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	if msg.ReasoningContent != "" {
+		if err := e.startBlock(AnthropicContentBlock{Type: anthropicBlockThinking}, false); err != nil {
+			return err
+		}
+		if err := e.blockDelta(gin.H{"type": "thinking_delta", "thinking": msg.ReasoningContent}); err != nil {
+			return err
+		}
+	}
+	if msg.Content != "" {
+		if err := e.startBlock(AnthropicContentBlock{Type: anthropicBlockText}, false); err != nil {
+			return err
+		}
+		if err := e.blockDelta(gin.H{"type": "text_delta", "text": msg.Content}); err != nil {
+			return err
+		}
+	}
+	if call := msg.FunctionCall; call != nil {
+		// A name starts a new tool_use; argument fragments continue the open one
+		if call.Name != "" || e.open != anthropicBlockToolUse {
+			block := AnthropicContentBlock{Type: anthropicBlockToolUse, ID: "toolu_" + ids.New(), Name: call.Name, Input: json.RawMessage("{}")}
+			if err := e.startBlock(block, true); err != nil {
+				return err
+			}
+			e.calls = true
+		}
+		if call.Arguments != "" {
+			if err := e.blockDelta(gin.H{"type": "input_json_delta", "partial_json": call.Arguments}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// start sends message_start ahead of the first content
+func (e *messagesEncoder) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	return e.event("message_start", gin.H{"message": e.msg})
+}
+
+// startBlock makes block the open one, stopping any other. With fresh, an
+// open block of the same type is stopped too.
+func (e *messagesEncoder) startBlock(block AnthropicContentBlock, fresh bool) error {
+	if e.open == block.Type && !fresh {
+		return nil
+	}
+	if err := e.stopBlock(); err != nil {
+		return err
+	}
+	e.open = block.Type
+	// Empty text and thinking are part of the block's start
+	fields := gin.H{"type": block.Type}
+	switch block.Type {
+	case anthropicBlockText:
+		fields["text"] = ""
+	case anthropicBlockThinking:
+		fields["thinking"] = ""
+	case anthropicBlockToolUse:
+		fields["id"], fields["name"], fields["input"] = block.ID, block.Name, block.Input
+	}
+	return e.event("content_block_start", gin.H{"index": e.blocks, "content_block": fields})
+}
+
+// stopBlock stops the open block, if any
+func (e *messagesEncoder) stopBlock() error {
+	if e.open == "" {
+		return nil
+	}
+	index := e.blocks
+	e.open = ""
+	e.blocks++
+	return e.event("content_block_stop", gin.H{"index": index})
+}
+
+func (e *messagesEncoder) blockDelta(delta gin.H) error {
+	return e.event("content_block_delta", gin.H{"index": e.blocks, "delta": delta})
+}
+
+func (e *messagesEncoder) event(typ string, fields gin.H) error {
+	fields["type"] = typ
+	if _, err := e.w.Write([]byte("event: " + typ + "\ndata: ")); err != nil {
+		return err
+	}
+	if err := e.enc.Encode(fields); err != nil {
+		return err
+	}
+	_, err := e.w.Write([]byte("\n"))
+	return err
+}
+
+// fail tells the client the stream broke and returns err to stop the relay
+func (e *messagesEncoder) fail(err error) error {
+	return e.abort(err, "")
+}
+
+// abort is fail with an error code for the client
+func (e *messagesEncoder) abort(err error, code string) error {
+	_ = e.event("error", gin.H{"error": AnthropicError{Type: "api_error", Message: err.Error(), Code: code}})
+	return err
+}
+
+// messagesHandler serves POST /v1/messages over the same providers as chat
+// completions, for clients built for Anthropic's Messages API
+func messagesHandler(r *provider.Router, adm *admission.Controller, store artifacts.Store, fileStore *files.Store, toolRuntime *tools.Runtime, shedder *loadshed.Shedder, relay *streamRelay, tracer *tracing.Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in AnthropicMessagesRequest
+		if err := c.ShouldBindJSON(&in); err != nil {
+			abortMessages(c, http.StatusBadRequest, fmt.Sprintf("invalid json: %v", err), "")
+			return
+		}
+		if in.Model == "" {
+			abortMessages(c, http.StatusBadRequest, "model is required", "")
+			return
+		}
+		in.Model = resolveModelAlias(c, r, in.Model)
+		standardReq, err := convertMessagesRequest(&in)
+		if err != nil {
+			abortMessages(c, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+		var limitErr *provider.LimitError
+		if err := r.CheckRequestLimits(standardReq); errors.As(err, &limitErr) {
+			abortMessages(c, http.StatusBadRequest, err.Error(), limitErr.Code)
+			return
+		}
+		setRequestModel(c, in.Model)
+
+		if in.Stream {
+			if shed := shedder.Check(); shed != nil {
+				c.Header("Retry-After", strconv.Itoa(int(shed.RetryAfter.Seconds())))
+				abortMessages(c, http.StatusServiceUnavailable, shed.Error(), "load_shed_"+shed.Reason)
+				return
+			}
+		}
+
+		attrs := requestAttributes(c)
+		routeReq := &provider.RouteRequest{Model: in.Model, Endpoint: c.FullPath(), Headers: routeHeaders(c.Request.Header), Attributes: attrs, Needs: provider.NeedsOf(standardReq),
+			Conversation: conversationID(c)}
+		in.Model = fitContextLength(c, r, routeReq, standardReq)
+		p, err := r.Route(routeReq)
+		if err != nil {
+			var policyErr *provider.PolicyError
+			if errors.As(err, &policyErr) {
+				abortMessages(c, http.StatusForbidden, err.Error(), policyErr.Code)
+				return
+			}
+			abortMessages(c, http.StatusBadRequest, err.Error(), "")
+			return
+		}
+		setRequestProvider(c, p)
+		setRequestArm(c, routeReq.Arm)
+		standardReq.Model = upstreamModel(routeReq)
+
+		release, err := adm.Acquire(c.Request.Context(), p.GetInfo().Name, in.Model, attrs)
+		if err != nil {
+			var rejected *admission.RejectedError
+			if errors.As(err, &rejected) {
+				c.Header("Retry-After", strconv.Itoa(rejected.RetryAfterSeconds()))
+				abortMessages(c, http.StatusTooManyRequests, err.Error(), "admission_"+rejected.Reason)
+				return
+			}
+			abortMessages(c, http.StatusServiceUnavailable, err.Error(), "")
+			return
+		}
+		defer release()
+
+		if err := expandReferences(c.Request.Context(), store, fileStore, standardReq); err != nil {
+			if code := referenceErrorCode(err); code != "" {
+				abortMessages(c, http.StatusBadRequest, err.Error(), code)
+				return
+			}
+			abortMessages(c, http.StatusInternalServerError, err.Error(), "")
+			return
+		}
+		warnings, err := r.ModelCapabilities(p, in.Model).ApplyLimits(standardReq)
+		if err != nil {
+			var limitErr *provider.LimitError
+			code := ""
+			if errors.As(err, &limitErr) {
+				code = limitErr.Code
+			}
+			abortMessages(c, http.StatusBadRequest, err.Error(), code)
+			return
+		}
+		for _, w := range warnings {
+			addWarning(c, w)
+		}
+
+		if in.Stream {
+			streamMessages(c, r, p, relay, tracer, toolRuntime, standardReq, &in)
+			return
+		}
+
+		var resp *provider.StandardResponse
+		if toolRuntime.Enabled() {
+			resp, err = toolRuntime.Run(c.Request.Context(), p, standardReq, nil)
+		} else {
+			var gen *provider.GenerateResponse
+			if gen, err = p.Generate(c.Request.Context(), &provider.GenerateRequest{StandardRequest: standardReq}); err == nil {
+				resp = gen.StandardResponse
+			}
+		}
+		var budgetErr *tools.BudgetExceededError
+		if errors.As(err, &budgetErr) {
+			setTokenUsage(c, budgetErr.Usage)
+			abortMessages(c, http.StatusUnprocessableEntity, err.Error(), "tool_budget_exceeded")
+			return
+		}
+		r.ReportOutcome(p.GetInfo().Name, err)
+		if err != nil {
+			abortMessages(c, http.StatusInternalServerError, err.Error(), "")
+			return
+		}
+		setTokenUsage(c, resp.Usage)
+		resp.Model = clientModel(routeReq, resp.Model)
+		setResponseCost(c, r, resp.Usage, resp.Metadata)
+
+		out := convertToAnthropicMessage(resp)
+		out.Warnings = append(requestWarnings(c), out.Warnings...)
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// streamMessages relays a provider stream as Messages API events
+func streamMessages(c *gin.Context, r *provider.Router, p provider.Provider, relay *streamRelay, tracer *tracing.Tracer, toolRuntime *tools.Runtime, req *provider.StandardRequest, in *AnthropicMessagesRequest) {
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		abortMessages(c, http.StatusInternalServerError, "streaming unsupported", "")
+		return
+	}
+	trace := tracer.Start(requestID(c), traceKeyID(c), c.FullPath(), p.GetInfo().Name, in.Model)
+	rc, err := openStream(c.Request.Context(), r, toolRuntime, p, req)
+	defer func() { finishTrace(trace, err) }()
+	if err != nil {
+		abortMessages(c, http.StatusInternalServerError, err.Error(), "")
+		return
+	}
+	trace.Upstream()
+	rc = trace.WrapUpstream(rc)
+	defer rc.Close()
+	setEventStreamHeaders(c)
+
+	enc := newMessagesEncoder(c.Writer, in.Model)
+	deadline := relay.writeDeadline(c.Writer)
+	defer deadline.clear()
+	err = relay.pump(c.Request.Context(), rc, func(b []byte) error {
+		deadline.extend()
+		trace.Write(len(b))
+		return enc.Write(b)
+	}, func() {
+		deadline.extend()
+		flusher.Flush()
+		trace.Flush()
+	})
+	if usage := enc.Usage(); usage != nil {
+		setTokenUsage(c, *usage)
+	}
+	if errors.Is(err, errSlowClient) {
+		deadline.extend()
+		_ = enc.abort(err, "slow_client")
+	}
+	if err != nil {
+		flusher.Flush()
+		return
+	}
+	deadline.extend()
+	_ = enc.Close()
+	flusher.Flush()
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConvertMessagesRequest(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body  string
+		roles []string
+		err   string
+	}{
+		"string content": {
+			body:  `{"model":"m","system":"be brief","messages":[{"role":"user","content":"hi"}]}`,
+			roles: []string{"system", "user"},
+		},
+		"content blocks": {
+			body:  `{"model":"m","system":[{"type":"text","text":"rules"}],"messages":[{"role":"user","content":[{"type":"text","text":"a"},{"type":"text","text":"b"}]},{"role":"assistant","content":[{"type":"thinking","thinking":"hm"},{"type":"text","text":"c"}]}]}`,
+			roles: []string{"system", "user", "assistant"},
+		},
+		"tool use round trip": {
+			body:  `{"model":"m","messages":[{"role":"user","content":"weather?"},{"role":"assistant","content":[{"type":"text","text":"checking"},{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris"}},{"type":"tool_use","id":"toolu_2","name":"get_time","input":{}}]},{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"sunny"},{"type":"tool_result","tool_use_id":"toolu_2","content":[{"type":"text","text":"noon"}]}]}]}`,
+			roles: []string{"user", "assistant", "assistant", "function", "function"},
+		},
+		"unknown tool use id": {
+			body: `{"model":"m","messages":[{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_9","content":"x"}]}]}`,
+			err:  `no tool_use with id "toolu_9"`,
+		},
+		"image block": {
+			body: `{"model":"m","messages":[{"role":"user","content":[{"type":"image","source":{}}]}]}`,
+			err:  `content block type "image" is not supported`,
+		},
+		"system role": {
+			body: `{"model":"m","messages":[{"role":"system","content":"x"}]}`,
+			err:  `unknown role "system"`,
+		},
+	} {
+		var in AnthropicMessagesRequest
+		if err := json.Unmarshal([]byte(tc.body), &in); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		req, err := convertMessagesRequest(&in)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: expected error %q, got %v", name, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		var roles []string
+		for _, m := range req.Messages {
+			roles = append(roles, m.Role)
+		}
+		if strings.Join(roles, ",") != strings.Join(tc.roles, ",") {
+			t.Errorf("%s: expected roles %v, got %v", name, tc.roles, roles)
+		}
+		if name == "content blocks" && (req.Messages[1].Content != "a\nb" || req.Messages[2].Content != "c") {
+			t.Errorf("%s: expected text blocks joined and thinking dropped, got %+v", name, req.Messages)
+		}
+		if name == "tool use round trip" {
+			if call := req.Messages[1].FunctionCall; call == nil || call.Name != "get_weather" || call.Arguments != `{"city":"Paris"}` || req.Messages[1].Content != "checking" {
+				t.Errorf("%s: expected the first call with the turn's text, got %+v", name, req.Messages[1])
+			}
+			if req.Messages[4].Name == nil || *req.Messages[4].Name != "get_time" || req.Messages[4].Content != "noon" {
+				t.Errorf("%s: expected the result named after its tool, got %+v", name, req.Messages[4])
+			}
+		}
+	}
+
+	var in AnthropicMessagesRequest
+	_ = json.Unmarshal([]byte(`{"model":"m","messages":[{"role":"user","content":"hi"}],"stop_sequences":["END"]}`), &in)
+	req, err := convertMessagesRequest(&in)
+	if err != nil || string(req.Passthrough["stop"]) != `["END"]` {
+		t.Errorf("Expected stop sequences passed through as stop, got %v, %v", req, err)
+	}
+}
+
+func TestMessagesEndpoint(t *testing.T) {
+	engine := newResponsesTestServer(t)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"demo-chat","max_tokens":100,"messages":[{"role":"user","content":"what is the weather"}]}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var out AnthropicMessageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.ID, "msg_") || out.Type != "message" || out.Role != "assistant" || out.StopReason == nil || *out.StopReason != "tool_use" {
+		t.Errorf("Unexpected message envelope: %+v", out)
+	}
+	if len(out.Content) != 2 || out.Content[0].Type != "text" || out.Content[0].Text != "Let me check" ||
+		out.Content[1].Type != "tool_use" || out.Content[1].Name != "get_weather" || string(out.Content[1].Input) != `{"city":"Paris"}` {
+		t.Errorf("Unexpected content blocks: %+v", out.Content)
+	}
+	if out.Usage.InputTokens == 0 || out.Usage.OutputTokens == 0 {
+		t.Errorf("Expected usage, got %+v", out.Usage)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{"model":"demo-chat","messages":[{"role":"user","content":[{"type":"document"}]}]}`)))
+	var errResp AnthropicErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &errResp); err != nil || w.Code != http.StatusBadRequest ||
+		errResp.Type != "error" || errResp.Error.Type != "invalid_request_error" {
+		t.Errorf("Expected an Anthropic invalid_request_error, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestMessagesStreaming(t *testing.T) {
+	engine := newResponsesTestServer(t)
+
+	for name, tc := range map[string]struct {
+		input  string
+		events []string
+		stop   string
+	}{
+		"text": {
+			input: "hi",
+			events: []string{
+				"message_start", "content_block_start",
+				"content_block_delta", "content_block_delta", "content_block_stop",
+				"message_delta", "message_stop",
+			},
+			stop: "end_turn",
+		},
+		"tool use": {
+			input: "weather in Paris",
+			events: []string{
+				"message_start", "content_block_start",
+				"content_block_delta", "content_block_delta", "content_block_delta", "content_block_stop",
+				"content_block_start", "content_block_delta", "content_block_stop",
+				"message_delta", "message_stop",
+			},
+			stop: "tool_use",
+		},
+	} {
+		body, _ := json.Marshal(map[string]interface{}{"model": "demo-chat", "stream": true, "max_tokens": 100,
+			"messages": []map[string]string{{"role": "user", "content": tc.input}}})
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(string(body))))
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+			t.Fatalf("%s: expected an event stream, got %d: %s", name, w.Code, w.Body)
+		}
+
+		var events []string
+		var text, args, stop string
+		sc := bufio.NewScanner(w.Body)
+		for sc.Scan() {
+			if typ, ok := strings.CutPrefix(sc.Text(), "event: "); ok {
+				events = append(events, typ)
+			}
+			data, ok := strings.CutPrefix(sc.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			var ev struct {
+				Type  string `json:"type"`
+				Index int    `json:"index"`
+				Delta struct {
+					Type        string `json:"type"`
+					Text        string `json:"text"`
+					PartialJSON string `json:"partial_json"`
+					StopReason  string `json:"stop_reason"`
+				} `json:"delta"`
+			}
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				t.Fatalf("%s: bad event data %q: %v", name, data, err)
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				text += ev.Delta.Text
+			case "input_json_delta":
+				if ev.Index != 1 {
+					t.Errorf("%s: expected tool input in block 1, got %d", name, ev.Index)
+				}
+				args += ev.Delta.PartialJSON
+			}
+			if ev.Type == "message_delta" {
+				stop = ev.Delta.StopReason
+			}
+		}
+		if strings.Join(events, ",") != strings.Join(tc.events, ",") {
+			t.Errorf("%s: unexpected events\n got %v\nwant %v", name, events, tc.events)
+		}
+		if text == "" || stop != tc.stop {
+			t.Errorf("%s: expected text and stop reason %q, got %q, %q", name, tc.stop, text, stop)
+		}
+		if name == "tool use" && args != `{"city":"Paris"}` {
+			t.Errorf("%s: expected accumulated input, got %q", name, args)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/compare"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// mirrorTimeout bounds one mirrored generation
+const mirrorTimeout = 2 * time.Minute
+
+// maxMirrorsInFlight bounds the mirrored generations running at once;
+// requests beyond it are not mirrored
+const maxMirrorsInFlight = 64
+
+// mirrorer copies chat completions to their route's mirror provider in the
+// background and logs how its answers compare to the ones clients got
+type mirrorer struct {
+	r          *provider.Router
+	slots      chan struct{}
+	requests   *metrics.CounterVec
+	similarity *metrics.CounterVec
+}
+
+func newMirrorer(r *provider.Router, m *metrics.Registry) *mirrorer {
+	return &mirrorer{
+		r:     r,
+		slots: make(chan struct{}, maxMirrorsInFlight),
+		requests: m.Counter("letllm_mirror_requests_total",
+			"Chat completions copied to a route's mirror provider by result: ok, error or dropped.",
+			"provider", "result"),
+		similarity: m.Counter("letllm_mirror_similarity_sum",
+			"Summed word similarity of mirrored answers to the answers served; divide by ok requests for the mean.",
+			"provider"),
+	}
+}
+
+// mirror generates req on the mirror of the route routeReq matches, if
+// any, once the client was answered with answer after latency. The client's
+// cancellation does not stop the mirror.
+func (mr *mirrorer) mirror(c *gin.Context, routeReq *provider.RouteRequest, req *provider.StandardRequest, answer string, latency time.Duration) {
+	p, model, ok := mr.r.Mirror(routeReq)
+	if !ok {
+		return
+	}
+	name := p.GetInfo().Name
+	select {
+	case mr.slots <- struct{}{}:
+	default:
+		mr.requests.Inc(name, "dropped")
+		return
+	}
+
+	mirrored := *req
+	mirrored.Model = model
+	mirrored.Stream = false
+	mirrored.Messages = append([]provider.Message(nil), req.Messages...)
+	id := requestID(c)
+	ctx := context.WithoutCancel(c.Request.Context())
+	go func() {
+		defer func() { <-mr.slots }()
+		ctx, cancel := context.WithTimeout(ctx, mirrorTimeout)
+		defer cancel()
+
+		start := time.Now()
+		resp, err := p.Generate(ctx, &provider.GenerateRequest{StandardRequest: &mirrored})
+		if err != nil {
+			mr.requests.Inc(name, "error")
+			log.Printf("request %s: mirror %s: %v", id, name, err)
+			return
+		}
+		mr.requests.Inc(name, "ok")
+		var content string
+		if len(resp.Choices) > 0 && resp.Choices[0].Message != nil {
+			content = resp.Choices[0].Message.Content
+		}
+		similarity := -1.0
+		if _, s, ok := compare.DiffWords(answer, content); ok {
+			similarity = s
+			mr.similarity.Add(s, name)
+		}
+		log.Printf("request %s: mirror %s %s: similarity %.2f, latency %dms (served %dms), %d completion tokens",
+			id, name, model, similarity, time.Since(start).Milliseconds(), latency.Milliseconds(), resp.Usage.CompletionTokens)
+	}()
+}
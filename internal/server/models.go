@@ -0,0 +1,123 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+type OpenAIModelList struct {
+	Object string        `json:"object"`
+	Data   []OpenAIModel `json:"data"`
+
+	// Gateway extension: freshness of the cached upstream model lists
+	Discovery []provider.ModelListFreshness `json:"discovery"`
+}
+
+type OpenAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+
+	// Gateway extensions: the provider requests for the model route to,
+	// and whether the model was only found through upstream discovery
+	Provider   string `json:"provider,omitempty"`
+	Discovered bool   `json:"discovered"`
+	// Limits and features of the model on that provider
+	Capabilities provider.ModelCapabilities `json:"capabilities"`
+}
+
+// RegisterModelRoutes serves GET /v1/models, listing the models of all
+// configured providers in OpenAI's format. The ETag covers the models only,
+// so clients polling with If-None-Match get 304 until the list changes.
+func RegisterModelRoutes(engine *gin.Engine, r *provider.Router) {
+	engine.GET("/v1/models", func(c *gin.Context) {
+		models := r.Models(c.Request.Context())
+		out := OpenAIModelList{Object: "list", Data: make([]OpenAIModel, len(models)), Discovery: r.ModelListFreshness()}
+		for i, m := range models {
+			out.Data[i] = OpenAIModel{
+				ID:         m.ID,
+				Object:     "model",
+				OwnedBy:    m.OwnedBy,
+				Provider:   m.Provider,
+				Discovered: m.Discovered,
+
+				Capabilities: m.Capabilities,
+			}
+		}
+		data, err := json.Marshal(out.Data)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		sum := sha256.Sum256(data)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		c.Header("ETag", etag)
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
+
+// ModelCapabilitiesResponse is the body of GET /v1/models/:model/capabilities
+type ModelCapabilitiesResponse struct {
+	ID     string `json:"id"`
+	Object string `json:"object"`
+	// The provider requests for the model route to
+	Provider string `json:"provider"`
+	// Limits and features of the model on that provider, with the
+	// configured override applied
+	Capabilities         provider.ModelCapabilities    `json:"capabilities"`
+	ProviderCapabilities provider.ProviderCapabilities `json:"provider_capabilities"`
+	// The models entry of the configuration that applies, if any
+	Override *ModelOverride `json:"override,omitempty"`
+}
+
+// ModelOverride is a models entry of the configuration
+type ModelOverride struct {
+	Model           string   `json:"model"`
+	Provider        string   `json:"provider,omitempty"`
+	ContextLength   int      `json:"context_length,omitempty"`
+	MaxOutputTokens int      `json:"max_output_tokens,omitempty"`
+	Functions       *bool    `json:"functions,omitempty"`
+	InputModalities []string `json:"input_modalities,omitempty"`
+}
+
+// RegisterModelCapabilitiesRoute serves GET /v1/models/:model/capabilities,
+// resolving the model as a chat completion would be routed so clients can
+// detect features such as vision, functions and context length
+func RegisterModelCapabilitiesRoute(engine *gin.Engine, r *provider.Router) {
+	engine.GET("/v1/models/:model/capabilities", func(c *gin.Context) {
+		model := c.Param("model")
+		p, err := r.Route(&provider.RouteRequest{Model: model, Endpoint: "/v1/chat/completions"})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "model_not_found"})
+			return
+		}
+		out := ModelCapabilitiesResponse{
+			ID:                   model,
+			Object:               "model.capabilities",
+			Provider:             p.GetInfo().Name,
+			Capabilities:         r.ModelCapabilities(p, model),
+			ProviderCapabilities: p.GetCapabilities(),
+		}
+		if spec, ok := r.ModelSpec(p, model); ok {
+			out.Override = &ModelOverride{
+				Model:           spec.Model,
+				Provider:        spec.Provider,
+				ContextLength:   spec.ContextLength,
+				MaxOutputTokens: spec.MaxOutputTokens,
+				Functions:       spec.Functions,
+				InputModalities: spec.InputModalities,
+			}
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+func TestModelsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-b", "demo-a"}}}}
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	RegisterModelRoutes(engine, r)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	var list OpenAIModelList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	caps := provider.ModelCapabilities{ContextLength: 128000, MaxOutputTokens: 4096, InputModalities: []string{"text"}}
+	want := OpenAIModelList{Object: "list", Data: []OpenAIModel{
+		{ID: "demo-a", Object: "model", OwnedBy: "demo", Provider: "demo", Capabilities: caps},
+		{ID: "demo-b", Object: "model", OwnedBy: "demo", Provider: "demo", Capabilities: caps},
+	}, Discovery: []provider.ModelListFreshness{}}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("Expected %+v, got %s", want, w.Body)
+	}
+
+	etag := w.Header().Get("ETag")
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if etag == "" || w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("Expected 304 for an unchanged list, got %d %q", w.Code, etag)
+	}
+}
+
+func TestModelCapabilitiesEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		Mock:   []config.MockConfig{{Name: "demo", Models: []string{"demo-a", "demo-b"}}},
+		Models: []config.ModelSpec{{Model: "demo-a", ContextLength: 8192, InputModalities: []string{"text", "image"}}},
+	}
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	RegisterModelCapabilitiesRoute(engine, r)
+
+	get := func(model string) (*httptest.ResponseRecorder, ModelCapabilitiesResponse) {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/models/"+model+"/capabilities", nil))
+		var out ModelCapabilitiesResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		return w, out
+	}
+
+	w, out := get("demo-a")
+	if w.Code != http.StatusOK || out.Provider != "demo" || out.Object != "model.capabilities" {
+		t.Fatalf("Unexpected response %d: %s", w.Code, w.Body)
+	}
+	if out.Capabilities.ContextLength != 8192 || !reflect.DeepEqual(out.Capabilities.InputModalities, []string{"text", "image"}) {
+		t.Errorf("Expected the override applied, got %+v", out.Capabilities)
+	}
+	if out.Override == nil || out.Override.Model != "demo-a" || out.Override.ContextLength != 8192 {
+		t.Errorf("Expected the override reported, got %+v", out.Override)
+	}
+	if !out.ProviderCapabilities.SupportsStreaming {
+		t.Errorf("Expected the provider's capabilities, got %+v", out.ProviderCapabilities)
+	}
+
+	if w, out = get("demo-b"); w.Code != http.StatusOK || out.Override != nil || out.Capabilities.ContextLength != 128000 {
+		t.Errorf("Expected the provider defaults without an override, got %d: %s", w.Code, w.Body)
+	}
+	if w, _ = get("unknown"); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "model_not_found") {
+		t.Errorf("Expected 404 for an unroutable model, got %d: %s", w.Code, w.Body)
+	}
+}
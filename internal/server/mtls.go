@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// certKeyIDContextKey carries the key a request's client certificate maps
+// to in its listener's client_keys
+type certKeyIDContextKey struct{}
+
+// listenerTLSConfig returns the TLS settings of a listener serving TLS, with
+// its certificate loaded, or nil when it serves plain HTTP
+func listenerTLSConfig(l config.Listener) (*tls.Config, error) {
+	if l.TLSCertFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(l.TLSCertFile, l.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load TLS certificate: %w", err)
+	}
+	tlsConfig, err := clientTLSConfig(l)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+	return tlsConfig, nil
+}
+
+// clientTLSConfig returns the TLS settings of a listener requiring client
+// certificates, or nil when it has no client CA bundle
+func clientTLSConfig(l config.Listener) (*tls.Config, error) {
+	if l.ClientCAFile == "" {
+		return nil, nil
+	}
+	pem, err := os.ReadFile(l.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("client CA bundle %s holds no certificates", l.ClientCAFile)
+	}
+	return &tls.Config{ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert, MinVersion: tls.VersionTLS12}, nil
+}
+
+// certIdentity makes requests whose verified client certificate subject is
+// in clientKeys run as the key it maps to. The distinguished name is
+// looked up before the common name.
+func certIdentity(clientKeys map[string]string, next http.Handler) http.Handler {
+	if len(clientKeys) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+			subject := r.TLS.VerifiedChains[0][0].Subject
+			keyID, ok := clientKeys[subject.String()]
+			if !ok {
+				keyID, ok = clientKeys[subject.CommonName]
+			}
+			if ok {
+				r = r.WithContext(context.WithValue(r.Context(), certKeyIDContextKey{}, keyID))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+)
+
+// issueCert creates a certificate for subject, signed by parent or self
+// signed when parent is nil
+func issueCert(t *testing.T, subject pkix.Name, parent *tls.Certificate) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, interface{}(key)
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func TestClientCertificates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ca := issueCert(t, pkix.Name{CommonName: "letllm test CA"}, nil)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	l := config.Listener{ClientCAFile: caFile, ClientKeys: map[string]string{"search": "team-search", "CN=billing,O=Acme": "team-billing"}}
+	tlsConfig, err := clientTLSConfig(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := keys.NewMemoryStore()
+	_ = store.Put(&keys.Key{ID: "team-search", Hash: keys.HashSecret("sk-search")})
+	_ = store.Put(&keys.Key{ID: "team-billing", Hash: keys.HashSecret("sk-billing")})
+	_ = store.Put(&keys.Key{ID: "team-other", Hash: keys.HashSecret("sk-other")})
+	engine := gin.New()
+	RegisterKeyAuth(engine, &config.Config{RequireKeys: true}, store)
+	engine.GET("/v1/models", func(c *gin.Context) { c.String(http.StatusOK, callerKeyID(c, store)) })
+
+	srv := httptest.NewUnstartedServer(certIdentity(l.ClientKeys, engine))
+	srv.TLS = tlsConfig
+	// Refused handshakes are expected
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	get := func(cert *tls.Certificate, token string) (int, string, error) {
+		transport := srv.Client().Transport.(*http.Transport).Clone()
+		if cert != nil {
+			transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+		}
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/models", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := (&http.Client{Transport: transport}).Do(req)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), nil
+	}
+
+	for _, tc := range []struct {
+		subject pkix.Name
+		token   string
+		want    int
+		keyID   string
+	}{
+		{pkix.Name{CommonName: "search"}, "", http.StatusOK, "team-search"},
+		{pkix.Name{CommonName: "billing", Organization: []string{"Acme"}}, "", http.StatusOK, "team-billing"},
+		// The certificate's identity wins over a bearer token
+		{pkix.Name{CommonName: "search"}, "sk-other", http.StatusOK, "team-search"},
+		// Unmapped certificates fall back to the bearer token
+		{pkix.Name{CommonName: "unmapped"}, "sk-other", http.StatusOK, "team-other"},
+		{pkix.Name{CommonName: "unmapped"}, "", http.StatusUnauthorized, ""},
+	} {
+		cert := issueCert(t, tc.subject, &ca)
+		code, body, err := get(&cert, tc.token)
+		if err != nil || code != tc.want || (tc.keyID != "" && body != tc.keyID) {
+			t.Errorf("%s with %q: expected %d %q, got %d %q: %v", tc.subject, tc.token, tc.want, tc.keyID, code, body, err)
+		}
+	}
+
+	// Certificates from other CAs, and no certificate, are refused in the handshake
+	other := issueCert(t, pkix.Name{CommonName: "search"}, nil)
+	for name, cert := range map[string]*tls.Certificate{"untrusted": &other, "none": nil} {
+		if _, _, err := get(cert, "sk-search"); err == nil {
+			t.Errorf("%s client certificate: expected the handshake to fail", name)
+		}
+	}
+}
+
+func TestListenerTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	cert := issueCert(t, pkix.Name{CommonName: "letllm"}, nil)
+	der, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := config.Listener{TLSCertFile: filepath.Join(dir, "cert.pem"), TLSKeyFile: filepath.Join(dir, "key.pem")}
+	_ = os.WriteFile(l.TLSCertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0o600)
+
+	// A key pair that cannot be loaded fails startup instead of every handshake
+	if _, err := listenerTLSConfig(l); err == nil {
+		t.Fatal("Expected a missing key file refused")
+	}
+
+	_ = os.WriteFile(l.TLSKeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600)
+	tlsConfig, err := listenerTLSConfig(l)
+	if err != nil || len(tlsConfig.Certificates) != 1 {
+		t.Fatalf("Expected the certificate loaded, got %+v, %v", tlsConfig, err)
+	}
+	if tlsConfig, _ := listenerTLSConfig(config.Listener{}); tlsConfig != nil {
+		t.Errorf("Expected plain HTTP listeners left without TLS, got %+v", tlsConfig)
+	}
+}
@@ -0,0 +1,363 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/autoscale"
+	"github.com/luguanyu1234/letllm-go/internal/batch"
+	"github.com/luguanyu1234/letllm-go/internal/compare"
+	"github.com/luguanyu1234/letllm-go/internal/erasure"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/health"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/openapi"
+	"github.com/luguanyu1234/letllm-go/internal/pii"
+	"github.com/luguanyu1234/letllm-go/internal/prompts"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/tracing"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+// ErrorResponse is the body of error responses
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Machine-readable reason, for errors clients are expected to handle
+	Code string `json:"code,omitempty"`
+}
+
+// apiRoute documents a route in the OpenAPI document. Bodies are given as
+// values of the types the handler decodes and encodes.
+type apiRoute struct {
+	summary string
+	tag     string
+	// Query parameters and their descriptions
+	query map[string]string
+	// JSON request body, or multipart form when form is set
+	body interface{}
+	form bool
+	// Success status, 200 when zero
+	status int
+	// JSON response body, or a response of media type when set
+	resp  interface{}
+	media string
+	// Also answers with server-sent events when the request asks to stream
+	stream bool
+}
+
+var limitQuery = map[string]string{"limit": "Maximum number of items returned"}
+
+// apiRoutes documents every route by "METHOD path" as gin registers it;
+// the test suite fails when a registered route is missing
+var apiRoutes = map[string]apiRoute{
+	"POST /v1/chat/completions": {summary: "Create a chat completion", tag: "inference",
+		body: OpenAIChatCompletionRequest{}, resp: OpenAIChatCompletionResponse{}, stream: true},
+	"POST /v1/responses": {summary: "Create a response", tag: "inference",
+		body: OpenAIResponsesRequest{}, resp: OpenAIResponse{}, stream: true},
+	"POST /v1/messages": {summary: "Create a message in Anthropic's Messages API format", tag: "inference",
+		body: AnthropicMessagesRequest{}, resp: AnthropicMessageResponse{}, stream: true},
+	"GET /v1/realtime": {summary: "Open an OpenAI Realtime API session over a WebSocket",
+		tag: "inference", query: map[string]string{"model": "Realtime model of the session"}},
+	"POST /v1beta/models/:model": {summary: "Generate content in Gemini API format; model is followed by :generateContent or :streamGenerateContent",
+		tag: "inference", query: map[string]string{"alt": "sse for server-sent events when streaming"},
+		body: GeminiRequest{}, resp: GeminiResponse{}, stream: true},
+	"POST /v1/embeddings": {summary: "Create embeddings", tag: "inference",
+		body: OpenAIEmbeddingRequest{}, resp: OpenAIEmbeddingResponse{}},
+	"POST /v1/audio/speech": {summary: "Synthesize speech", tag: "inference",
+		body: OpenAISpeechRequest{}, media: "audio/*"},
+	"POST /v1/audio/transcriptions": {summary: "Transcribe audio", tag: "inference", form: true,
+		body: struct {
+			File           []byte  `json:"file"`
+			Model          string  `json:"model"`
+			Language       string  `json:"language,omitempty"`
+			Prompt         string  `json:"prompt,omitempty"`
+			ResponseFormat string  `json:"response_format,omitempty"`
+			Temperature    float64 `json:"temperature,omitempty"`
+		}{}, resp: OpenAITranscriptionResponse{}},
+	"POST /v1/fanout": {summary: "Send a prompt to several models and pick the best answer", tag: "inference",
+		body: compare.FanoutRequest{}, resp: compare.FanoutReport{}},
+	"GET /v1/models":                     {summary: "List models", tag: "models", resp: OpenAIModelList{}},
+	"GET /v1/models/:model/capabilities": {summary: "Capabilities of a model as routed", tag: "models", resp: ModelCapabilitiesResponse{}},
+
+	"POST /v1/sessions": {summary: "Start a conversation session", tag: "sessions", status: http.StatusCreated,
+		body: SessionCreateRequest{}, resp: SessionResponse{}},
+	"GET /v1/sessions/:id": {summary: "Get a session and its history", tag: "sessions", resp: SessionResponse{}},
+	"DELETE /v1/sessions/:id": {summary: "Delete a session", tag: "sessions", resp: struct {
+		ID      string `json:"id"`
+		Object  string `json:"object"`
+		Deleted bool   `json:"deleted"`
+	}{}},
+
+	"GET /v1/templates": {summary: "List prompt templates", tag: "templates", resp: struct {
+		Object string              `json:"object"`
+		Data   []*prompts.Template `json:"data"`
+	}{}},
+	"POST /v1/templates/:name/render": {summary: "Expand a prompt template into messages", tag: "templates",
+		body: TemplateRenderRequest{}, resp: TemplateRenderResponse{}},
+
+	"POST /v1/artifacts": {summary: "Store a prompt artifact", tag: "artifacts", status: http.StatusCreated,
+		body: struct {
+			Content string `json:"content"`
+		}{}, resp: artifacts.Artifact{}},
+	"GET /v1/artifacts/:id": {summary: "Get a prompt artifact", tag: "artifacts",
+		query: map[string]string{"content": "true returns the artifact's content as text/plain"}, resp: artifacts.Artifact{}},
+
+	"POST /v1/files": {summary: "Upload a file", tag: "files", form: true,
+		body: struct {
+			File    []byte `json:"file"`
+			Purpose string `json:"purpose"`
+		}{}, resp: files.File{}},
+	"GET /v1/files": {summary: "List files", tag: "files",
+		query: map[string]string{"purpose": "Only files of this purpose", "limit": "Maximum number of files (1-10000)", "after": "ID of the file to list from"},
+		resp: struct {
+			Object  string        `json:"object"`
+			Data    []*files.File `json:"data"`
+			HasMore bool          `json:"has_more"`
+			FirstID *string       `json:"first_id"`
+			LastID  *string       `json:"last_id"`
+		}{}},
+	"GET /v1/files/:id":         {summary: "Get a file", tag: "files", resp: files.File{}},
+	"GET /v1/files/:id/content": {summary: "Get a file's content", tag: "files", media: "application/octet-stream"},
+	"DELETE /v1/files/:id": {summary: "Delete a file", tag: "files", resp: struct {
+		ID      string `json:"id"`
+		Object  string `json:"object"`
+		Deleted bool   `json:"deleted"`
+	}{}},
+
+	"POST /v1/batches": {summary: "Create a batch", tag: "batches",
+		body: struct {
+			InputFileID      string            `json:"input_file_id"`
+			Endpoint         string            `json:"endpoint"`
+			CompletionWindow string            `json:"completion_window"`
+			Metadata         map[string]string `json:"metadata,omitempty"`
+		}{}, resp: batch.Batch{}},
+	"GET /v1/batches": {summary: "List batches", tag: "batches",
+		query: map[string]string{"limit": "Maximum number of batches (1-100)", "after": "ID of the batch to list from"},
+		resp: struct {
+			Object  string         `json:"object"`
+			Data    []*batch.Batch `json:"data"`
+			HasMore bool           `json:"has_more"`
+			FirstID *string        `json:"first_id"`
+			LastID  *string        `json:"last_id"`
+		}{}},
+	"GET /v1/batches/:id":         {summary: "Get a batch", tag: "batches", resp: batch.Batch{}},
+	"POST /v1/batches/:id/cancel": {summary: "Cancel a batch", tag: "batches", resp: batch.Batch{}},
+
+	"GET /healthz": {summary: "Liveness probe", tag: "operations", resp: struct {
+		Status string `json:"status"`
+	}{}},
+	"GET /readyz":  {summary: "Readiness probe", tag: "operations", resp: health.Report{}},
+	"GET /version": {summary: "Build and version information", tag: "operations", resp: versionResponse{}},
+	"GET /metrics": {summary: "Prometheus metrics", tag: "operations", media: "text/plain"},
+	"GET /autoscaling/signals": {summary: "Autoscaling signals", tag: "operations", resp: struct {
+		Signals []autoscale.Signal `json:"signals"`
+	}{}},
+	"GET /openapi.json": {summary: "This document", tag: "operations", media: "application/json"},
+	"GET /debug/pprof/*name": {summary: "Runtime profiles from net/http/pprof", tag: "operations", media: "application/octet-stream",
+		query: map[string]string{"seconds": "duration of CPU profiles and traces", "debug": "1 or 2 for text output"}},
+	"POST /debug/pprof/symbol": {summary: "Look up program counters", tag: "operations", media: "text/plain"},
+
+	"GET /admin/keys": {summary: "List virtual keys", tag: "admin", resp: keyList{}},
+	"POST /admin/keys": {summary: "Issue a virtual key", tag: "admin", status: http.StatusCreated,
+		body: KeyCreateRequest{}, resp: KeyCreateResponse{}},
+	"GET /admin/keys/:id":               {summary: "Get a virtual key", tag: "admin", resp: keys.Key{}},
+	"POST /admin/keys/:id/revoke":       {summary: "Revoke a virtual key", tag: "admin", resp: keys.Key{}},
+	"GET /admin/keys/export":            {summary: "Export virtual keys", tag: "admin", resp: keys.Export{}},
+	"GET /admin/keys/:id/model-aliases": {summary: "Get a key's model aliases", tag: "admin", resp: keyModelAliases{}},
+	"PUT /admin/keys/:id/model-aliases": {summary: "Replace a key's model aliases", tag: "admin",
+		body: keyModelAliases{}, resp: keyModelAliases{}},
+	"POST /admin/keys/import": {summary: "Import virtual keys", tag: "admin",
+		query: map[string]string{"format": "letllm (default) or litellm", "overwrite": "true replaces existing keys"},
+		body:  keys.Export{}, resp: keys.ImportResult{}},
+	"DELETE /admin/artifacts/:id": {summary: "Delete a prompt artifact", tag: "admin", status: http.StatusNoContent},
+	"GET /admin/budgets":          {summary: "Get the spend of every budget", tag: "admin", resp: budgetList{}},
+	"GET /admin/usage/records": {summary: "Export usage records", tag: "admin",
+		query: map[string]string{"from": "RFC 3339 start, default 24 hours ago", "to": "RFC 3339 end, default now"},
+		resp: struct {
+			From    string          `json:"from"`
+			To      string          `json:"to"`
+			Records []*usage.Record `json:"records"`
+		}{}},
+	"GET /admin/usage/aggregate": {summary: "Export aggregated usage", tag: "admin",
+		query: map[string]string{"from": "RFC 3339 start, default 24 hours ago", "to": "RFC 3339 end, default now",
+			"bucket": "hour or day (default)", "group_by": "Comma-separated provider, model, key, experiment and variant", "min_group_size": "Suppress smaller groups"},
+		resp: usage.Aggregate{}},
+	"GET /admin/recent": {summary: "List recent requests", tag: "admin",
+		query: map[string]string{"limit": "Maximum number of requests", "model": "Only this model", "provider": "Only this provider",
+			"key_id": "Only this key", "status": "Only this status", "errors": "true lists failed requests only", "since": "RFC 3339 time to list from"},
+		resp: struct {
+			Requests []RequestSummary `json:"requests"`
+		}{}},
+	"GET /admin/traces": {summary: "List stream traces", tag: "admin", query: limitQuery,
+		resp: struct {
+			Traces []*tracing.Trace `json:"traces"`
+		}{}},
+	"GET /admin/traces/:id": {summary: "Get a stream trace", tag: "admin", resp: tracing.Trace{}},
+	"GET /admin/failover": {summary: "List failover pairs", tag: "admin", resp: struct {
+		Pairs []provider.FailoverState `json:"pairs"`
+	}{}},
+	"POST /admin/failover/:provider": {summary: "Switch a failover pair", tag: "admin",
+		query: map[string]string{"to": "standby or active"}, resp: provider.FailoverState{}},
+	"GET /admin/providers": {summary: "List providers", tag: "admin", resp: struct {
+		Providers []providerView `json:"providers"`
+	}{}},
+	"POST /admin/providers": {summary: "Register a provider", tag: "admin", status: http.StatusCreated,
+		body: provider.ProviderSpec{}, resp: providerView{}},
+	"PATCH /admin/providers/:name":  {summary: "Change or disable a provider", tag: "admin", body: providerUpdate{}, resp: providerView{}},
+	"DELETE /admin/providers/:name": {summary: "Remove a provider", tag: "admin", status: http.StatusNoContent},
+	"POST /admin/reload":            {summary: "Reload providers and routes from the configuration file", tag: "admin", resp: provider.ReloadResult{}},
+	"GET /admin/load-shedding":      {summary: "Get the load shedding status", tag: "admin", resp: loadshed.Status{}},
+	"PUT /admin/load-shedding":      {summary: "Set the load shedding policy", tag: "admin", body: loadshed.Policy{}, resp: loadshed.Status{}},
+	"DELETE /admin/data/subject/:id": {summary: "Erase a data subject", tag: "admin",
+		query: map[string]string{"delete_key": "true deletes the key instead of disabling it", "reference": "External ticket kept in the audit record"},
+		resp:  erasure.Record{}},
+	"GET /admin/data/erasures": {summary: "List erasures", tag: "admin", resp: struct {
+		Erasures []*erasure.Record `json:"erasures"`
+	}{}},
+	"GET /admin/pii/reports": {summary: "List PII reports", tag: "admin", query: limitQuery,
+		resp: struct {
+			Reports []*pii.Report `json:"reports"`
+		}{}},
+	"POST /admin/pii/reports": {summary: "Run a PII report", tag: "admin", status: http.StatusCreated, resp: pii.Report{}},
+	"POST /admin/compare":     {summary: "Compare two models", tag: "admin", body: compare.Request{}, resp: compare.Report{}},
+}
+
+var apiTags = map[string]string{
+	"inference":  "OpenAI-compatible model APIs",
+	"models":     "Models the gateway routes",
+	"artifacts":  "Prompt fragments referenced from requests",
+	"files":      "Uploaded files",
+	"sessions":   "Conversations whose history the gateway keeps",
+	"templates":  "Prompt templates expanded with variables",
+	"batches":    "Asynchronous batches",
+	"operations": "Probes, metrics and autoscaling",
+	"admin":      "Administration, guarded by the admin token",
+}
+
+// BuildOpenAPI describes the routes registered on engine
+func BuildOpenAPI(engine *gin.Engine) *openapi.Document {
+	b := openapi.NewBuilder(openapi.Info{
+		Title:       "letllm gateway",
+		Version:     "1.0.0",
+		Description: "OpenAI-compatible LLM gateway",
+	})
+	b.AddSecurityScheme("virtualKey", &openapi.SecurityScheme{Type: "http", Scheme: "bearer",
+		Description: "Virtual key issued by the gateway"})
+	b.AddSecurityScheme("adminToken", &openapi.SecurityScheme{Type: "http", Scheme: "bearer",
+		Description: "admin.token from the gateway configuration"})
+	for name, desc := range apiTags {
+		b.AddTag(name, desc)
+	}
+	errSchema := b.SchemaOf(ErrorResponse{})
+
+	for _, ri := range engine.Routes() {
+		doc, ok := apiRoutes[ri.Method+" "+ri.Path]
+		if !ok {
+			doc = apiRoute{tag: "undocumented"}
+		}
+		path, params := openapiPath(ri.Path)
+		op := &openapi.Operation{
+			OperationID: operationID(ri.Method, ri.Path),
+			Summary:     doc.summary,
+			Tags:        []string{doc.tag},
+			Parameters:  params,
+			Responses:   map[string]openapi.Response{},
+		}
+		names := make([]string, 0, len(doc.query))
+		for name := range doc.query {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			op.Parameters = append(op.Parameters, openapi.Parameter{Name: name, In: "query", Description: doc.query[name], Schema: &openapi.Schema{Type: "string"}})
+		}
+
+		if doc.body != nil {
+			media := "application/json"
+			if doc.form {
+				media = "multipart/form-data"
+			}
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{media: {Schema: b.SchemaOf(doc.body)}}}
+		}
+
+		status := doc.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		resp := openapi.Response{Description: http.StatusText(status)}
+		switch {
+		case doc.media != "":
+			resp.Content = map[string]openapi.MediaType{doc.media: {Schema: &openapi.Schema{Type: "string"}}}
+		case doc.resp != nil:
+			resp.Content = map[string]openapi.MediaType{"application/json": {Schema: b.SchemaOf(doc.resp)}}
+		}
+		if doc.stream {
+			if resp.Content == nil {
+				resp.Content = map[string]openapi.MediaType{}
+			}
+			resp.Content["text/event-stream"] = openapi.MediaType{Schema: &openapi.Schema{Type: "string"}}
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+		op.Responses["default"] = openapi.Response{Description: "Error", Content: map[string]openapi.MediaType{"application/json": {Schema: errSchema}}}
+
+		switch {
+		case strings.HasPrefix(ri.Path, "/admin/"), strings.HasPrefix(ri.Path, "/debug/"):
+			op.Security = []map[string][]string{{"adminToken": {}}}
+		case strings.HasPrefix(ri.Path, "/v1/"):
+			// Virtual keys are optional unless the deployment requires them
+			op.Security = []map[string][]string{{"virtualKey": {}}, {}}
+		}
+		b.AddOperation(ri.Method, path, op)
+	}
+	return b.Document()
+}
+
+// openapiPath rewrites gin's :param segments as {param}
+func openapiPath(path string) (string, []openapi.Parameter) {
+	var params []openapi.Parameter
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			name := seg[1:]
+			segs[i] = "{" + name + "}"
+			params = append(params, openapi.Parameter{Name: name, In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}})
+		}
+	}
+	return strings.Join(segs, "/"), params
+}
+
+// operationID names an operation after its method and path, e.g.
+// getV1FilesByIdContent for GET /v1/files/:id/content
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, seg := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' }) {
+		by := strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*")
+		if by {
+			seg = seg[1:]
+			id += "By"
+		}
+		for _, word := range strings.FieldsFunc(seg, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return id
+}
+
+// RegisterOpenAPIRoutes serves the OpenAPI document of every route. It is
+// built on first request, once all routes are registered.
+func RegisterOpenAPIRoutes(engine *gin.Engine) {
+	var (
+		once sync.Once
+		doc  *openapi.Document
+	)
+	engine.GET("/openapi.json", func(c *gin.Context) {
+		once.Do(func() { doc = BuildOpenAPI(engine) })
+		c.JSON(http.StatusOK, doc)
+	})
+}
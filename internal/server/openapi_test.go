@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/autoscale"
+	"github.com/luguanyu1234/letllm-go/internal/batch"
+	"github.com/luguanyu1234/letllm-go/internal/budget"
+	"github.com/luguanyu1234/letllm-go/internal/buildinfo"
+	"github.com/luguanyu1234/letllm-go/internal/cache"
+	"github.com/luguanyu1234/letllm-go/internal/compare"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/erasure"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/health"
+	"github.com/luguanyu1234/letllm-go/internal/idempotency"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/pii"
+	"github.com/luguanyu1234/letllm-go/internal/prompts"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/redis"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
+	"github.com/luguanyu1234/letllm-go/internal/sessions"
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/tracing"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+	"go.uber.org/fx"
+)
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Storage.Driver = storage.DriverMemory
+	var engine *gin.Engine
+	// The application's own modules, so every route it registers is seen
+	app := fx.New(fx.NopLogger, fx.Supply(cfg),
+		buildinfo.Module, health.Module, metrics.Module, storage.Module, redis.Module, cache.Module, keys.Module, artifacts.Module, usage.Module, budget.Module, idempotency.Module,
+		enrich.Module, provider.Module, admission.Module, loadshed.Module, tools.Module, respcache.Module,
+		tracing.Module, files.Module, sessions.Module, prompts.Module, batch.Module, autoscale.Module, erasure.Module, pii.Module, compare.Module,
+		Module, fx.Populate(&engine))
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	registered := make(map[string]bool)
+	for _, ri := range engine.Routes() {
+		registered[ri.Method+" "+ri.Path] = true
+		if _, ok := apiRoutes[ri.Method+" "+ri.Path]; !ok {
+			t.Errorf("%s %s is missing from apiRoutes", ri.Method, ri.Path)
+		}
+	}
+	for route := range apiRoutes {
+		if !registered[route] {
+			t.Errorf("apiRoutes documents %s, which is not registered", route)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var doc struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Unexpected document %d: %v", w.Code, err)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("Expected OpenAPI 3.1.0, got %q", doc.OpenAPI)
+	}
+	for path, method := range map[string]string{
+		"/v1/chat/completions":     "post",
+		"/v1/files/{id}/content":   "get",
+		"/admin/data/subject/{id}": "delete",
+		"/v1/batches/{id}/cancel":  "post",
+	} {
+		if _, ok := doc.Paths[path][method]; !ok {
+			t.Errorf("Expected %s %s in the document", method, path)
+		}
+	}
+	for _, ref := range regexp.MustCompile(`"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(w.Body.String(), -1) {
+		if _, ok := doc.Components.Schemas[ref[1]]; !ok {
+			t.Errorf("Dangling reference to %s", ref[1])
+		}
+	}
+	if _, ok := doc.Components.Schemas["OpenAIChatCompletionRequest"]; !ok {
+		t.Error("Expected the chat completion request among the schemas")
+	}
+}
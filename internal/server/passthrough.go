@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+// passthroughCredentialHeaders carry the caller's gateway key, which the
+// provider's credentials replace
+var passthroughCredentialHeaders = []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key"}
+
+// passthroughTarget is where one passthrough request is forwarded
+type passthroughTarget struct {
+	base   *url.URL
+	header http.Header
+}
+
+// passthroughTargetKey holds the request's passthroughTarget in its context
+type passthroughTargetKey struct{}
+
+// resolvePassthrough returns the passthrough target of provider name as
+// the router currently has it, so reloaded base URLs and credentials apply
+func resolvePassthrough(r *provider.Router, name string) (provider.Provider, *passthroughTarget, error) {
+	p, ok := r.GetProvider(name)
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown provider %q", name)
+	}
+	target, ok := p.(provider.PassthroughProvider)
+	if !ok {
+		return nil, nil, fmt.Errorf("provider %q does not take passthrough requests", name)
+	}
+	base, header, err := target.PassthroughTarget()
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, &passthroughTarget{base: base, header: header}, nil
+}
+
+// RegisterPassthrough forwards /v1 requests that match no route to the
+// provider named by passthrough.provider, so clients using endpoints the
+// gateway does not implement keep working through it. Requests and
+// responses are relayed as they are, streams included; only the
+// credentials are swapped. The provider is looked up for every request,
+// so a reload changing it takes effect. Other unmatched paths are still
+// 404s.
+func RegisterPassthrough(engine *gin.Engine, r *provider.Router, cfg *config.Config, usageStore usage.Store, keyStore keys.Store, recent *RecentRequests) error {
+	name := cfg.Passthrough.Provider
+	if name == "" {
+		return nil
+	}
+	if _, _, err := resolvePassthrough(r, name); err != nil {
+		return fmt.Errorf("passthrough: %w", err)
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			target := pr.In.Context().Value(passthroughTargetKey{}).(*passthroughTarget)
+			// The base URL already ends in the API version
+			pr.Out.URL.Path = strings.TrimPrefix(pr.In.URL.Path, "/v1")
+			pr.Out.URL.RawPath = ""
+			pr.SetURL(target.base)
+			for _, h := range passthroughCredentialHeaders {
+				pr.Out.Header.Del(h)
+			}
+			for h, v := range target.header {
+				pr.Out.Header[h] = v
+			}
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			log.Printf("passthrough %s %s: %v", req.Method, req.URL.Path, err)
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(http.StatusBadGateway)
+			_ = json.NewEncoder(w).Encode(gin.H{"error": fmt.Sprintf("passthrough to %s: %v", name, err)})
+		},
+	}
+
+	engine.NoRoute(
+		func(c *gin.Context) {
+			// Aborting without a response leaves gin's 404
+			if !strings.HasPrefix(c.Request.URL.Path, "/v1/") {
+				c.Abort()
+			}
+		},
+		recordUsage(cfg, usageStore, keyStore, recent),
+		func(c *gin.Context) {
+			p, target, err := resolvePassthrough(r, name)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("passthrough: %v", err)})
+				return
+			}
+			setRequestProvider(c, p)
+			ctx := context.WithValue(c.Request.Context(), passthroughTargetKey{}, target)
+			proxy.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+		},
+	)
+	return nil
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+func TestPassthrough(t *testing.T) {
+	var got *http.Request
+	var body string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Clone(r.Context())
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":"vs_1","object":"vector_store"}`)
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		OpenAICompatible: []config.OpenAICompatibleConfig{{Name: "up", BaseURL: upstream.URL + "/v1", APIKey: "sk-upstream", Models: []string{"up-chat"}}},
+		Mock:             []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}}},
+	}
+	cfg.Passthrough.Provider = "up"
+	keyStore := keys.NewMemoryStore()
+	_ = keyStore.Put(&keys.Key{ID: "team-a", Hash: keys.HashSecret("sk-a")})
+	engine, recent := newGatewayForTest(t, cfg, keyStore)
+	r, _ := provider.NewRouter(cfg)
+	if err := RegisterPassthrough(engine, r, cfg, usage.NewMemoryStore(10), keyStore, recent); err != nil {
+		t.Fatal(err)
+	}
+
+	// The reverse proxy needs a real connection
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/vector_stores?limit=2", strings.NewReader(`{"name":"docs"}`))
+	req.Header.Set("Authorization", "Bearer sk-a")
+	req.Header.Set("OpenAI-Beta", "assistants=v2")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || !strings.Contains(string(out), "vs_1") {
+		t.Fatalf("Expected the upstream response relayed, got %d: %s", resp.StatusCode, out)
+	}
+	if got.URL.Path != "/v1/vector_stores" || got.URL.RawQuery != "limit=2" || body != `{"name":"docs"}` {
+		t.Errorf("Expected the request forwarded verbatim, got %s?%s %q", got.URL.Path, got.URL.RawQuery, body)
+	}
+	if got.Header.Get("Authorization") != "Bearer sk-upstream" || got.Header.Get("OpenAI-Beta") != "assistants=v2" {
+		t.Errorf("Expected the provider's key and the client's other headers, got %v", got.Header)
+	}
+	// The record is added once the handler returns, after the client has
+	// its response
+	all := func(*RequestSummary) bool { return true }
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recent.List(1, all)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	rec := recent.List(1, all)
+	if len(rec) != 1 || rec[0].Path != "/v1/vector_stores" || rec[0].Provider != "up" || rec[0].KeyID != "team-a" || rec[0].Status != http.StatusCreated {
+		t.Errorf("Unexpected record %+v", rec)
+	}
+
+	// Implemented endpoints and paths outside /v1 are unaffected
+	got = nil
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"demo-chat","messages":[{"role":"user","content":"hi"}]}`)))
+	if w.Code != http.StatusOK || got != nil {
+		t.Errorf("Expected the chat route served locally, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v2/things", nil))
+	if w.Code != http.StatusNotFound || got != nil {
+		t.Errorf("Expected a 404 outside /v1, got %d", w.Code)
+	}
+
+	for name, p := range map[string]string{"unknown provider": "nope", "provider without passthrough": "demo"} {
+		cfg.Passthrough.Provider = p
+		if err := RegisterPassthrough(engine, r, cfg, usage.NewMemoryStore(10), keyStore, recent); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPassthroughFollowsReload(t *testing.T) {
+	upstream := func(name string, auth *string) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*auth = r.Header.Get("Authorization")
+			fmt.Fprintf(w, `{"upstream":%q}`, name)
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	var oldAuth, newAuth string
+	old, moved := upstream("old", &oldAuth), upstream("new", &newAuth)
+
+	cfg := &config.Config{
+		OpenAICompatible: []config.OpenAICompatibleConfig{{Name: "up", BaseURL: old.URL + "/v1", APIKey: "sk-old", Models: []string{"up-chat"}}},
+	}
+	cfg.Passthrough.Provider = "up"
+	keyStore := keys.NewMemoryStore()
+	engine, recent := newGatewayForTest(t, cfg, keyStore)
+	r, _ := provider.NewRouter(cfg)
+	if err := RegisterPassthrough(engine, r, cfg, usage.NewMemoryStore(10), keyStore, recent); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	get := func() string {
+		resp, err := http.Get(srv.URL + "/v1/vector_stores")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		return string(out)
+	}
+
+	if out := get(); !strings.Contains(out, "old") || oldAuth != "Bearer sk-old" {
+		t.Fatalf("Expected the configured upstream, got %s with %q", out, oldAuth)
+	}
+
+	// The provider moves to another base URL with another key
+	next := *cfg
+	next.OpenAICompatible = []config.OpenAICompatibleConfig{{Name: "up", BaseURL: moved.URL + "/v1", APIKey: "sk-new", Models: []string{"up-chat"}}}
+	if _, err := r.Reload(&next); err != nil {
+		t.Fatal(err)
+	}
+	if out := get(); !strings.Contains(out, "new") || newAuth != "Bearer sk-new" {
+		t.Errorf("Expected the reloaded upstream, got %s with %q", out, newAuth)
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/pii"
+)
+
+// RegisterPIIRoutes wires the reports of personal data found in stored
+// prompt artifacts
+func RegisterPIIRoutes(admin *AdminRouter, reporter *pii.Reporter) {
+	// GET /admin/pii/reports?limit=
+	admin.GET("/pii/reports", func(c *gin.Context) {
+		limit := 0
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+				return
+			}
+			limit = n
+		}
+		c.JSON(http.StatusOK, gin.H{"reports": reporter.Reports(limit)})
+	})
+
+	// POST /admin/pii/reports samples and classifies artifacts now
+	admin.POST("/pii/reports", func(c *gin.Context) {
+		rep, err := reporter.Run()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, rep)
+	})
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http/pprof"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// RegisterPprofRoutes serves the runtime profiles of net/http/pprof under
+// /debug/pprof. They need the admin token and are disabled with the admin
+// API; fetch a profile with e.g. "curl -H 'Authorization: Bearer <token>'
+// http://host/debug/pprof/heap > heap.pb.gz" and open it with go tool pprof.
+func RegisterPprofRoutes(engine *gin.Engine, cfg *config.Config) {
+	debug := engine.Group("/debug/pprof", adminAuth(cfg))
+	debug.GET("/*name", func(c *gin.Context) {
+		switch c.Param("name") {
+		case "/cmdline":
+			pprof.Cmdline(c.Writer, c.Request)
+		case "/profile":
+			pprof.Profile(c.Writer, c.Request)
+		case "/symbol":
+			pprof.Symbol(c.Writer, c.Request)
+		case "/trace":
+			pprof.Trace(c.Writer, c.Request)
+		default:
+			// The index, and named profiles such as heap and goroutine
+			pprof.Index(c.Writer, c.Request)
+		}
+	})
+	debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestPprofRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	engine := gin.New()
+	RegisterPprofRoutes(engine, cfg)
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("/debug/pprof/", "admin"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected profiles disabled without an admin token, got %d", w.Code)
+	}
+	cfg.Admin.Token = "admin"
+	if w := get("/debug/pprof/heap", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a wrong token rejected, got %d", w.Code)
+	}
+
+	for path, want := range map[string]string{
+		"/debug/pprof/":                  "goroutine",
+		"/debug/pprof/goroutine?debug=1": "goroutine profile",
+		"/debug/pprof/cmdline":           "server.test",
+		"/debug/pprof/heap?debug=1":      "heap profile",
+	} {
+		w := get(path, "admin")
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), want) {
+			t.Errorf("%s: expected %q, got %d: %.200s", path, want, w.Code, w.Body)
+		}
+	}
+}
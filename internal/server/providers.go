@@ -0,0 +1,141 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// providerView is a provider as the admin API shows it; API keys are never
+// returned
+type providerView struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type,omitempty"`
+	Status       string   `json:"status"`
+	BaseURL      string   `json:"base_url,omitempty"`
+	DefaultModel string   `json:"default_model,omitempty"`
+	Models       []string `json:"models,omitempty"`
+	APIKeySet    bool     `json:"api_key_set"`
+	// Whether the API key, base URL and models can be changed at runtime
+	Editable bool `json:"editable"`
+}
+
+// providerUpdate is the body of PATCH /admin/providers/:name; absent
+// fields are left as they are
+type providerUpdate struct {
+	APIKey       *string   `json:"api_key"`
+	BaseURL      *string   `json:"base_url"`
+	DefaultModel *string   `json:"default_model"`
+	Models       *[]string `json:"models"`
+	Disabled     *bool     `json:"disabled"`
+}
+
+// viewProvider describes the provider registered under name
+func viewProvider(r *provider.Router, name string) providerView {
+	v := providerView{Name: name, Status: "active"}
+	if p, ok := r.GetProvider(name); ok {
+		v.Status = p.GetInfo().Status
+	}
+	if r.ProviderDisabled(name) {
+		v.Status = "disabled"
+	}
+	if spec, ok := r.ProviderSpec(name); ok {
+		v.Type, v.BaseURL, v.DefaultModel, v.Models = spec.Type, spec.BaseURL, spec.DefaultModel, spec.Models
+		v.APIKeySet = spec.APIKey != ""
+		v.Editable = true
+	}
+	return v
+}
+
+// RegisterProviderAdminRoutes wires registering, changing, disabling and
+// removing providers without a restart. Changes last until the process
+// restarts; the configuration file is not rewritten.
+func RegisterProviderAdminRoutes(admin *AdminRouter, r *provider.Router) {
+	admin.GET("/providers", func(c *gin.Context) {
+		infos := r.ListProviders()
+		views := make([]providerView, 0, len(infos))
+		for _, info := range infos {
+			views = append(views, viewProvider(r, info.Name))
+		}
+		sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+		c.JSON(http.StatusOK, gin.H{"providers": views})
+	})
+
+	admin.POST("/providers", func(c *gin.Context) {
+		var spec provider.ProviderSpec
+		if err := c.ShouldBindJSON(&spec); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+			return
+		}
+		if _, exists := r.GetProvider(spec.Name); exists {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("provider %s already exists", spec.Name)})
+			return
+		}
+		if err := r.PutProvider(spec); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, viewProvider(r, spec.Name))
+	})
+
+	// PATCH /admin/providers/:name rebuilds the provider with the changed
+	// settings; disabled alone applies to any provider
+	admin.PATCH("/providers/:name", func(c *gin.Context) {
+		name := c.Param("name")
+		var in providerUpdate
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+			return
+		}
+		if _, exists := r.GetProvider(name); !exists {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("provider %s not configured", name)})
+			return
+		}
+
+		if in.APIKey != nil || in.BaseURL != nil || in.DefaultModel != nil || in.Models != nil {
+			spec, ok := r.ProviderSpec(name)
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("provider %s cannot be changed at runtime", name)})
+				return
+			}
+			if in.APIKey != nil {
+				spec.APIKey = *in.APIKey
+			}
+			if in.BaseURL != nil {
+				spec.BaseURL = *in.BaseURL
+			}
+			if in.DefaultModel != nil {
+				spec.DefaultModel = *in.DefaultModel
+			}
+			if in.Models != nil {
+				spec.Models = *in.Models
+			}
+			if err := r.PutProvider(spec); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if in.Disabled != nil {
+			if err := r.SetProviderDisabled(name, *in.Disabled); err != nil {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		c.JSON(http.StatusOK, viewProvider(r, name))
+	})
+
+	admin.DELETE("/providers/:name", func(c *gin.Context) {
+		if _, exists := r.GetProvider(c.Param("name")); !exists {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("provider %s not configured", c.Param("name"))})
+			return
+		}
+		if err := r.RemoveProvider(c.Param("name")); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+func TestProviderAdminRoutes(t *testing.T) {
+	var upstreamAuth string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c1","object":"chat.completion","model":"local-chat","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+	}))
+	defer upstream.Close()
+
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}}}}
+	cfg.Admin.Token = "admin"
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	engine := gin.New()
+	RegisterRoutes(engine, r, cfg, m, admission.NewController(cfg, m), artifacts.NewMemoryStore(), usage.NewMemoryStore(10),
+		keys.NewMemoryStore(), enrich.NewEnricher(cfg), tools.NewRuntime(cfg, m), NewRecentRequests(cfg), respcache.NewCache(), loadshed.New(cfg, m), nil, nil, nil, nil)
+	RegisterProviderAdminRoutes(NewAdminRouter(engine, cfg), r)
+
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := call(http.MethodPost, "/admin/providers", fmt.Sprintf(`{"name":"local","type":"openai_compatible","api_key":"sk-1","base_url":%q,"models":["local-chat"]}`, upstream.URL+"/v1"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected the provider created, got %d: %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "sk-1") {
+		t.Errorf("Expected the API key withheld, got %s", w.Body)
+	}
+	if w := call(http.MethodPost, "/admin/providers", `{"name":"local","type":"openai_compatible"}`); w.Code != http.StatusConflict {
+		t.Errorf("Expected a conflict for an existing name, got %d", w.Code)
+	}
+
+	if w := call(http.MethodPatch, "/admin/providers/local", `{"api_key":"sk-2"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected the key changed, got %d: %s", w.Code, w.Body)
+	}
+	chat := `{"model":"local-chat","messages":[{"role":"user","content":"hi"}]}`
+	if w := call(http.MethodPost, "/v1/chat/completions", chat); w.Code != http.StatusOK {
+		t.Fatalf("Expected the new provider served, got %d: %s", w.Code, w.Body)
+	}
+	if upstreamAuth != "Bearer sk-2" {
+		t.Errorf("Expected the new key upstream, got %q", upstreamAuth)
+	}
+
+	w = call(http.MethodPatch, "/admin/providers/local", `{"disabled":true}`)
+	var view providerView
+	_ = json.Unmarshal(w.Body.Bytes(), &view)
+	if w.Code != http.StatusOK || view.Status != "disabled" || !view.APIKeySet || !view.Editable {
+		t.Errorf("Expected the provider disabled, got %d: %s", w.Code, w.Body)
+	}
+	if w := call(http.MethodPost, "/v1/chat/completions", chat); w.Code == http.StatusOK {
+		t.Error("Expected a disabled provider not routed")
+	}
+
+	w = call(http.MethodGet, "/admin/providers", "")
+	var list struct {
+		Providers []providerView `json:"providers"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Providers) != 2 || list.Providers[0].Name != "demo" || list.Providers[0].Editable || list.Providers[1].Name != "local" {
+		t.Errorf("Unexpected provider list %s", w.Body)
+	}
+
+	for name, tc := range map[string]struct {
+		method, path, body string
+		code               int
+	}{
+		"change a configured mock":  {http.MethodPatch, "/admin/providers/demo", `{"base_url":"http://x"}`, http.StatusConflict},
+		"disable a configured mock": {http.MethodPatch, "/admin/providers/demo", `{"disabled":true}`, http.StatusOK},
+		"unknown provider":          {http.MethodPatch, "/admin/providers/nope", `{"disabled":true}`, http.StatusNotFound},
+		"invalid spec":              {http.MethodPost, "/admin/providers", `{"name":"x","type":"nope"}`, http.StatusBadRequest},
+	} {
+		if w := call(tc.method, tc.path, tc.body); w.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d: %s", name, tc.code, w.Code, w.Body)
+		}
+	}
+
+	if w := call(http.MethodDelete, "/admin/providers/local", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected the provider removed, got %d", w.Code)
+	}
+	if w := call(http.MethodDelete, "/admin/providers/local", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected a 404 once removed, got %d", w.Code)
+	}
+}
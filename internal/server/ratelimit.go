@@ -0,0 +1,86 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// rateLimitSource reports upstream quota and pacing state, as the router
+// does
+type rateLimitSource interface {
+	RateLimits() map[string]provider.RateLimitState
+	PacingDelays() map[string]time.Duration
+}
+
+// rateLimitExporter mirrors upstream quota and pacing state into gauges
+type rateLimitExporter struct {
+	limit     *metrics.GaugeVec
+	remaining *metrics.GaugeVec
+	delay     *metrics.GaugeVec
+
+	// mu guards the providers exported by the last refresh, whose series
+	// are removed once they are gone, e.g. after a reload
+	mu     sync.Mutex
+	limits map[string]bool
+	paced  map[string]bool
+}
+
+func newRateLimitExporter(m *metrics.Registry) *rateLimitExporter {
+	return &rateLimitExporter{
+		limit: m.Gauge("letllm_upstream_ratelimit_limit",
+			"Upstream quota per window as reported in rate limit headers.",
+			"provider", "resource"),
+		remaining: m.Gauge("letllm_upstream_ratelimit_remaining",
+			"Upstream quota remaining as reported in the latest rate limit headers.",
+			"provider", "resource"),
+		delay: m.Gauge("letllm_upstream_pacing_delay_seconds",
+			"Delay the next request to the provider incurs from adaptive pacing.",
+			"provider"),
+	}
+}
+
+// refresh updates the gauges from the registry; called before each scrape
+func (e *rateLimitExporter) refresh(r rateLimitSource) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	limits := make(map[string]bool)
+	for name, s := range r.RateLimits() {
+		e.set(name, "requests", s.LimitRequests, s.RemainingRequests)
+		e.set(name, "tokens", s.LimitTokens, s.RemainingTokens)
+		limits[name] = true
+	}
+	for name := range e.limits {
+		if !limits[name] {
+			for _, resource := range []string{"requests", "tokens"} {
+				e.limit.Delete(name, resource)
+				e.remaining.Delete(name, resource)
+			}
+		}
+	}
+	e.limits = limits
+
+	paced := make(map[string]bool)
+	for name, d := range r.PacingDelays() {
+		e.delay.Set(d.Seconds(), name)
+		paced[name] = true
+	}
+	for name := range e.paced {
+		if !paced[name] {
+			e.delay.Delete(name)
+		}
+	}
+	e.paced = paced
+}
+
+func (e *rateLimitExporter) set(providerName, resource string, limit, remaining int) {
+	if limit >= 0 {
+		e.limit.Set(float64(limit), providerName, resource)
+	}
+	if remaining >= 0 {
+		e.remaining.Set(float64(remaining), providerName, resource)
+	}
+}
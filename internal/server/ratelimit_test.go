@@ -0,0 +1,47 @@
+package server
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// staticRateLimits reports fixed rate limit state
+type staticRateLimits struct {
+	limits map[string]provider.RateLimitState
+	delays map[string]time.Duration
+}
+
+func (s staticRateLimits) RateLimits() map[string]provider.RateLimitState { return s.limits }
+
+func (s staticRateLimits) PacingDelays() map[string]time.Duration { return s.delays }
+
+func TestRateLimitExporterDropsRemovedProviders(t *testing.T) {
+	m := metrics.NewRegistry()
+	e := newRateLimitExporter(m)
+	state := provider.RateLimitState{LimitRequests: 100, RemainingRequests: 40, LimitTokens: 1000, RemainingTokens: 900}
+	e.refresh(staticRateLimits{
+		limits: map[string]provider.RateLimitState{"openai": state, "old": state},
+		delays: map[string]time.Duration{"openai": time.Second, "old": time.Second},
+	})
+	// A reload removes the old provider
+	e.refresh(staticRateLimits{
+		limits: map[string]provider.RateLimitState{"openai": state},
+		delays: map[string]time.Duration{"openai": time.Second},
+	})
+
+	var buf bytes.Buffer
+	_ = m.WriteText(&buf)
+	out := buf.String()
+	if strings.Contains(out, `provider="old"`) {
+		t.Errorf("Expected the removed provider's series gone, got %s", out)
+	}
+	if !strings.Contains(out, `letllm_upstream_ratelimit_remaining{provider="openai",resource="requests"} 40`) ||
+		!strings.Contains(out, `letllm_upstream_pacing_delay_seconds{provider="openai"} 1`) {
+		t.Errorf("Expected the remaining provider's series kept, got %s", out)
+	}
+}
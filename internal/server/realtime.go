@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"golang.org/x/net/websocket"
+)
+
+// realtimeDialTimeout bounds connecting to the upstream session
+const realtimeDialTimeout = 10 * time.Second
+
+// realtimeKeyProtocol prefixes the WebSocket subprotocol browser clients,
+// which cannot set headers, pass their API key in
+const realtimeKeyProtocol = "openai-insecure-api-key."
+
+// realtimeUsage is the usage of one response in a response.done event
+type realtimeUsage struct {
+	InputTokens       int `json:"input_tokens"`
+	OutputTokens      int `json:"output_tokens"`
+	TotalTokens       int `json:"total_tokens"`
+	InputTokenDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+		AudioTokens  int `json:"audio_tokens"`
+	} `json:"input_token_details"`
+}
+
+// realtimeHandler serves GET /v1/realtime?model=, the OpenAI Realtime API.
+// The upstream session is opened with the provider's credentials before
+// the client's connection is upgraded, so routing and upstream failures
+// are plain HTTP errors. Events are then relayed both ways until either
+// side closes, and the session's token usage is summed from its
+// response.done events.
+func realtimeHandler(r *provider.Router, adm *admission.Controller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "expected a WebSocket upgrade"})
+			return
+		}
+		model := c.Query("model")
+		if model == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "model is required"})
+			return
+		}
+		model = resolveModelAlias(c, r, model)
+		setRequestModel(c, model)
+
+		attrs := requestAttributes(c)
+		routeReq := &provider.RouteRequest{Model: model, Endpoint: c.FullPath(), Headers: routeHeaders(c.Request.Header), Attributes: attrs}
+		p, err := r.Route(routeReq)
+		if err != nil {
+			var policyErr *provider.PolicyError
+			if errors.As(err, &policyErr) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": policyErr.Code})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		setRequestProvider(c, p)
+		setRequestArm(c, routeReq.Arm)
+
+		rt, ok := p.(provider.RealtimeProvider)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("%s: the realtime API is not supported by this provider", p.GetInfo().Name),
+				"code":  "realtime_unsupported",
+			})
+			return
+		}
+
+		// The slot is held for the whole session
+		release, err := adm.Acquire(c.Request.Context(), p.GetInfo().Name, model, attrs)
+		if err != nil {
+			var rejected *admission.RejectedError
+			if errors.As(err, &rejected) {
+				c.Header("Retry-After", strconv.Itoa(rejected.RetryAfterSeconds()))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": "admission_" + rejected.Reason})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		defer release()
+
+		upstream, err := dialRealtime(c.Request.Context(), rt, upstreamModel(routeReq), requestID(c))
+		r.ReportOutcome(p.GetInfo().Name, err)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		defer upstream.Close()
+
+		c.Status(http.StatusSwitchingProtocols)
+		srv := websocket.Server{
+			// Browsers offer "realtime" next to the key protocol and fail
+			// the connection unless one of their offers is selected. The
+			// key itself is never echoed.
+			Handshake: func(cfg *websocket.Config, _ *http.Request) error {
+				offered := cfg.Protocol
+				cfg.Protocol = nil
+				for _, proto := range offered {
+					if proto == "realtime" {
+						cfg.Protocol = []string{proto}
+					}
+				}
+				return nil
+			},
+			Handler: func(client *websocket.Conn) {
+				setTokenUsage(c, relayRealtime(client, upstream))
+			},
+		}
+		srv.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// dialRealtime opens the upstream session with the provider's credentials
+// in place of the client's
+func dialRealtime(ctx context.Context, rt provider.RealtimeProvider, model, id string) (*websocket.Conn, error) {
+	u, header, err := rt.RealtimeEndpoint(model)
+	if err != nil {
+		return nil, err
+	}
+	header.Set(requestIDHeader, id)
+	origin := &url.URL{Scheme: "https", Host: u.Host}
+	if u.Scheme == "ws" {
+		origin.Scheme = "http"
+	}
+	cfg := &websocket.Config{
+		Location: u,
+		Origin:   origin,
+		Version:  websocket.ProtocolVersionHybi13,
+		Header:   header,
+	}
+	ctx, cancel := context.WithTimeout(ctx, realtimeDialTimeout)
+	defer cancel()
+	conn, err := cfg.DialContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("realtime session: %w", err)
+	}
+	return conn, nil
+}
+
+// relayRealtime copies events between client and upstream until either
+// side closes, then closes both. It returns the usage of the session's
+// responses.
+func relayRealtime(client, upstream *websocket.Conn) provider.Usage {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer upstream.Close()
+		for {
+			var event string
+			if err := websocket.Message.Receive(client, &event); err != nil {
+				return
+			}
+			if err := websocket.Message.Send(upstream, event); err != nil {
+				return
+			}
+		}
+	}()
+
+	var total provider.Usage
+	for {
+		var event string
+		if err := websocket.Message.Receive(upstream, &event); err != nil {
+			break
+		}
+		addRealtimeUsage(&total, event)
+		if err := websocket.Message.Send(client, event); err != nil {
+			break
+		}
+	}
+	client.Close()
+	<-done
+	return total
+}
+
+// addRealtimeUsage adds the usage of a response.done event to total.
+// Other events, most of them audio, are not decoded.
+func addRealtimeUsage(total *provider.Usage, event string) {
+	if !strings.Contains(event, `"response.done"`) {
+		return
+	}
+	var done struct {
+		Type     string `json:"type"`
+		Response struct {
+			Usage *realtimeUsage `json:"usage"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal([]byte(event), &done); err != nil || done.Type != "response.done" || done.Response.Usage == nil {
+		return
+	}
+	u := done.Response.Usage
+	total.PromptTokens += u.InputTokens
+	total.CompletionTokens += u.OutputTokens
+	total.TotalTokens += u.TotalTokens
+	total.CachedPromptTokens += u.InputTokenDetails.CachedTokens
+	total.AudioTokens += u.InputTokenDetails.AudioTokens
+}
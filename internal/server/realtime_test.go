@@ -0,0 +1,100 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"golang.org/x/net/websocket"
+)
+
+func TestRealtimeProxy(t *testing.T) {
+	// The upstream answers every client event with a finished response
+	var upstreamAuth, upstreamModel string
+	upstream := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		upstreamAuth = ws.Request().Header.Get("Authorization")
+		upstreamModel = ws.Request().URL.Query().Get("model")
+		_ = websocket.Message.Send(ws, `{"type":"session.created"}`)
+		for {
+			var event string
+			if err := websocket.Message.Receive(ws, &event); err != nil {
+				return
+			}
+			_ = websocket.Message.Send(ws, `{"type":"response.done","response":{"usage":{"total_tokens":30,"input_tokens":10,"output_tokens":20,"input_token_details":{"cached_tokens":4,"audio_tokens":6}}}}`)
+		}
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{
+		OpenAICompatible: []config.OpenAICompatibleConfig{{Name: "voice", BaseURL: upstream.URL + "/v1", APIKey: "sk-upstream", Models: []string{"gpt-realtime"}}},
+		Mock:             []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}}},
+	}
+	keyStore := keys.NewMemoryStore()
+	_ = keyStore.Put(&keys.Key{ID: "team-a", Hash: keys.HashSecret("sk-a"), ModelAliases: map[string]string{"voice": "gpt-realtime"}})
+	engine, recent := newGatewayForTest(t, cfg, keyStore)
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/realtime?model=voice"
+	wsCfg, _ := websocket.NewConfig(wsURL, srv.URL)
+	wsCfg.Protocol = []string{"realtime", realtimeKeyProtocol + "sk-a"}
+	client, err := websocket.DialConfig(wsCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var event string
+	if err := websocket.Message.Receive(client, &event); err != nil || !strings.Contains(event, "session.created") {
+		t.Fatalf("Expected the session created, got %q, %v", event, err)
+	}
+	for i := 0; i < 2; i++ {
+		_ = websocket.Message.Send(client, `{"type":"response.create"}`)
+		if err := websocket.Message.Receive(client, &event); err != nil || !strings.Contains(event, "response.done") {
+			t.Fatalf("Expected the response relayed, got %q, %v", event, err)
+		}
+	}
+	if upstreamAuth != "Bearer sk-upstream" || upstreamModel != "gpt-realtime" {
+		t.Errorf("Expected the provider's key and the resolved model upstream, got %q, %q", upstreamAuth, upstreamModel)
+	}
+	client.Close()
+
+	// The session is recorded once the gateway has closed both sides
+	all := func(*RequestSummary) bool { return true }
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recent.List(1, all)) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := recent.List(1, all)
+	if len(got) != 1 {
+		t.Fatal("Expected the session recorded")
+	}
+	rec := got[0]
+	if rec.KeyID != "team-a" || rec.Provider != "voice" || rec.Model != "gpt-realtime" || rec.Status != http.StatusSwitchingProtocols ||
+		rec.PromptTokens != 20 || rec.CompletionTokens != 40 || rec.CachedPromptTokens != 8 || rec.AudioTokens != 12 {
+		t.Errorf("Unexpected session record %+v", rec)
+	}
+
+	for name, tc := range map[string]struct {
+		path    string
+		upgrade bool
+		code    int
+	}{
+		"plain request":        {"/v1/realtime?model=gpt-realtime", false, http.StatusBadRequest},
+		"no model":             {"/v1/realtime", true, http.StatusBadRequest},
+		"provider without API": {"/v1/realtime?model=demo-chat", true, http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.upgrade {
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d: %s", name, tc.code, w.Code, w.Body)
+		}
+	}
+}
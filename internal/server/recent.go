@@ -0,0 +1,114 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+// RequestSummary is the metadata of a recent request kept for triage
+type RequestSummary struct {
+	RequestID string `json:"request_id"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	usage.Record
+}
+
+// RecentRequests is a fixed-size ring of the latest request summaries. It
+// lives in memory only, so it is available on deployments without a
+// database but is lost on restart and local to each replica.
+type RecentRequests struct {
+	mu   sync.Mutex
+	ring []RequestSummary
+	next int
+	full bool
+}
+
+// NewRecentRequests creates a ring sized by admin.recent_requests
+func NewRecentRequests(cfg *config.Config) *RecentRequests {
+	size := cfg.Admin.RecentRequests
+	if size <= 0 {
+		size = 500
+	}
+	return &RecentRequests{ring: make([]RequestSummary, size)}
+}
+
+// Add stores s, overwriting the oldest summary once the ring is full
+func (r *RecentRequests) Add(s RequestSummary) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ring[r.next] = s
+	r.next = (r.next + 1) % len(r.ring)
+	r.full = r.full || r.next == 0
+}
+
+// List returns up to limit summaries matching keep, newest first
+func (r *RecentRequests) List(limit int, keep func(*RequestSummary) bool) []RequestSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.next
+	if r.full {
+		n = len(r.ring)
+	}
+	out := []RequestSummary{}
+	for i := 0; i < n && len(out) < limit; i++ {
+		s := &r.ring[(r.next-1-i+len(r.ring))%len(r.ring)]
+		if keep(s) {
+			out = append(out, *s)
+		}
+	}
+	return out
+}
+
+// RegisterRecentAdminRoutes serves the recent request ring
+func RegisterRecentAdminRoutes(admin *AdminRouter, recent *RecentRequests) {
+	// GET /admin/recent?limit=&model=&provider=&key_id=&status=&errors=true&since=
+	admin.GET("/recent", func(c *gin.Context) {
+		limit := 100
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+				return
+			}
+			limit = n
+		}
+		status := 0
+		if v := c.Query("status"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "status must be an integer"})
+				return
+			}
+			status = n
+		}
+		var since time.Time
+		if v := c.Query("since"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "since must be an RFC 3339 time"})
+				return
+			}
+			since = t
+		}
+		model, providerName, keyID := c.Query("model"), c.Query("provider"), c.Query("key_id")
+		errorsOnly := c.Query("errors") == "true"
+
+		requests := recent.List(limit, func(s *RequestSummary) bool {
+			return (model == "" || s.Model == model) &&
+				(providerName == "" || s.Provider == providerName) &&
+				(keyID == "" || s.KeyID == keyID) &&
+				(status == 0 || s.Status == status) &&
+				(!errorsOnly || s.Status >= http.StatusBadRequest) &&
+				!s.Time.Before(since)
+		})
+		c.JSON(http.StatusOK, gin.H{"requests": requests})
+	})
+}
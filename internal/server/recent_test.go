@@ -0,0 +1,72 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+func TestRecentRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	cfg.Admin.Token = "admin"
+	cfg.Admin.RecentRequests = 3
+	recent := NewRecentRequests(cfg)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		status := http.StatusOK
+		if i%2 == 1 {
+			status = http.StatusBadGateway
+		}
+		recent.Add(RequestSummary{
+			RequestID: fmt.Sprintf("req-%d", i),
+			Record:    usage.Record{Time: start.Add(time.Duration(i) * time.Minute), Model: "m", Status: status},
+		})
+	}
+
+	engine := gin.New()
+	RegisterRecentAdminRoutes(NewAdminRouter(engine, cfg), recent)
+	get := func(query string) (int, []string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin/recent"+query, nil)
+		req.Header.Set("Authorization", "Bearer admin")
+		engine.ServeHTTP(w, req)
+		var out struct {
+			Requests []RequestSummary `json:"requests"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		ids := []string{}
+		for _, s := range out.Requests {
+			ids = append(ids, s.RequestID)
+		}
+		return w.Code, ids
+	}
+
+	for query, want := range map[string][]string{
+		"":                                    {"req-4", "req-3", "req-2"},
+		"?limit=2":                            {"req-4", "req-3"},
+		"?errors=true":                        {"req-3"},
+		"?status=200":                         {"req-4", "req-2"},
+		"?model=other":                        {},
+		"?since=2025-01-01T00:03:00Z":         {"req-4", "req-3"},
+		"?since=2025-01-01T00:03:00Z&limit=1": {"req-4"},
+	} {
+		code, got := get(query)
+		if code != http.StatusOK || fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%q: expected %v, got %d %v", query, want, code, got)
+		}
+	}
+	for _, query := range []string{"?limit=0", "?status=bad", "?since=yesterday"} {
+		if code, _ := get(query); code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, code)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"go.uber.org/fx"
+)
+
+// reloadConfig re-reads the configuration file and applies its providers,
+// routes, failover pairs and model specs to the registry. Other settings
+// take effect on restart.
+func reloadConfig(r *provider.Router) (provider.ReloadResult, error) {
+	cfg, err := config.LoadFromEnv()
+	if err != nil {
+		return provider.ReloadResult{}, err
+	}
+	return r.Reload(cfg)
+}
+
+// RegisterReload wires POST /admin/reload and SIGHUP to reload the
+// configuration file without a restart
+func RegisterReload(lc fx.Lifecycle, admin *AdminRouter, r *provider.Router) {
+	admin.POST("/reload", func(c *gin.Context) {
+		result, err := reloadConfig(r)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	})
+
+	hup := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			signal.Notify(hup, syscall.SIGHUP)
+			go func() {
+				defer close(done)
+				for range hup {
+					result, err := reloadConfig(r)
+					if err != nil {
+						log.Printf("config reload failed: %v", err)
+						continue
+					}
+					log.Printf("config reloaded: added %v, updated %v, removed %v, routes changed %t",
+						result.Added, result.Updated, result.Removed, result.RoutesChanged)
+				}
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			signal.Stop(hup)
+			close(hup)
+			<-done
+			return nil
+		},
+	})
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"go.uber.org/fx/fxtest"
+)
+
+func TestReloadEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("LETLLM_CONFIG", path)
+	write := func(yaml string) {
+		if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("mock:\n  - name: demo\n    models: [demo-chat]\n")
+	cfg, err := config.LoadFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Admin.Token = "admin"
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	lc := fxtest.NewLifecycle(t)
+	RegisterReload(lc, NewAdminRouter(engine, cfg), r)
+	lc.RequireStart()
+	defer lc.RequireStop()
+
+	reload := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/reload", nil)
+		req.Header.Set("Authorization", "Bearer admin")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	write("mock:\n  - name: demo\n    models: [demo-chat]\n  - name: other\n    models: [other-chat]\n")
+	w := reload()
+	var result provider.ReloadResult
+	_ = json.Unmarshal(w.Body.Bytes(), &result)
+	if w.Code != http.StatusOK || len(result.Added) != 1 || result.Added[0] != "other" {
+		t.Fatalf("Expected other added, got %d: %s", w.Code, w.Body)
+	}
+	if _, err := r.Route(&provider.RouteRequest{Model: "other-chat"}); err != nil {
+		t.Errorf("Expected the new provider routed: %v", err)
+	}
+
+	write("routes: [{prefix: x, provider: missing}]\nfailover: [{active: missing, standby: demo}]\n")
+	if w := reload(); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected an invalid file rejected, got %d: %s", w.Code, w.Body)
+	}
+	if _, err := r.Route(&provider.RouteRequest{Model: "other-chat"}); err != nil {
+		t.Errorf("Expected the registry unchanged: %v", err)
+	}
+}
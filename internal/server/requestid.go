@@ -0,0 +1,49 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+)
+
+// requestIDHeader carries the request ID in both directions
+const requestIDHeader = "X-Request-ID"
+
+// requestIDKey is the gin context key holding the request ID
+const requestIDKey = "letllm.request_id"
+
+// assignRequestID adopts the caller's X-Request-ID when it is well formed,
+// so logs correlate across services, and otherwise assigns a new one. The
+// ID is echoed in the response header.
+func assignRequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = ids.New()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// requestID returns the ID assigned to the request
+func requestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// validRequestID accepts short IDs of URL-safe characters, keeping arbitrary
+// client input out of logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
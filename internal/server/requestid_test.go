@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAssignRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(assignRequestID())
+	engine.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, requestID(c))
+	})
+
+	for name, tc := range map[string]struct {
+		header string
+		kept   bool
+	}{
+		"generated":  {},
+		"propagated": {header: "trace-1234.abc", kept: true},
+		"rejected":   {header: "bad id\nwith newline"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.header != "" {
+			req.Header.Set(requestIDHeader, tc.header)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+
+		id := w.Header().Get(requestIDHeader)
+		if id == "" || id != w.Body.String() {
+			t.Errorf("%s: expected the header to echo the context ID, got %q and %q", name, id, w.Body.String())
+		}
+		if (id == tc.header) != tc.kept {
+			t.Errorf("%s: unexpected request ID %q", name, id)
+		}
+	}
+}
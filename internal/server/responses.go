@@ -0,0 +1,745 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/tracing"
+)
+
+// OpenAIResponsesRequest is the body of POST /v1/responses. The gateway is
+// stateless, so conversations are replayed in input rather than continued
+// with previous_response_id.
+type OpenAIResponsesRequest struct {
+	Model string `json:"model"`
+	// A string, or an array of input items
+	Input           json.RawMessage   `json:"input"`
+	Instructions    string            `json:"instructions,omitempty"`
+	Stream          bool              `json:"stream"`
+	MaxOutputTokens *int              `json:"max_output_tokens,omitempty"`
+	Temperature     *float64          `json:"temperature,omitempty"`
+	TopP            *float64          `json:"top_p,omitempty"`
+	Tools           []ResponsesTool   `json:"tools,omitempty"`
+	PreviousID      string            `json:"previous_response_id,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+}
+
+// ResponsesTool is a tool in the flattened Responses API shape; only
+// "function" tools are forwarded
+type ResponsesTool struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ResponsesInputItem is a message, function_call or function_call_output
+// item of the request input
+type ResponsesInputItem struct {
+	Type string `json:"type,omitempty"`
+	Role string `json:"role,omitempty"`
+	// A string, or an array of content parts
+	Content   json.RawMessage `json:"content,omitempty"`
+	CallID    string          `json:"call_id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Arguments string          `json:"arguments,omitempty"`
+	Output    string          `json:"output,omitempty"`
+}
+
+// ResponsesContent is a content part of a message, or a reasoning summary
+type ResponsesContent struct {
+	Type        string        `json:"type"`
+	Text        string        `json:"text"`
+	Annotations []interface{} `json:"annotations,omitempty"`
+}
+
+// ResponsesOutputItem is a message, function_call or reasoning item of a
+// response
+type ResponsesOutputItem struct {
+	Type      string             `json:"type"`
+	ID        string             `json:"id"`
+	Status    string             `json:"status,omitempty"`
+	Role      string             `json:"role,omitempty"`
+	Content   []ResponsesContent `json:"content,omitempty"`
+	CallID    string             `json:"call_id,omitempty"`
+	Name      string             `json:"name,omitempty"`
+	Arguments string             `json:"arguments,omitempty"`
+	Summary   []ResponsesContent `json:"summary,omitempty"`
+}
+
+type OpenAIResponse struct {
+	ID                string                `json:"id"`
+	Object            string                `json:"object"`
+	CreatedAt         int64                 `json:"created_at"`
+	Status            string                `json:"status"`
+	Model             string                `json:"model"`
+	Output            []ResponsesOutputItem `json:"output"`
+	IncompleteDetails *ResponsesIncomplete  `json:"incomplete_details,omitempty"`
+	Usage             *ResponsesUsage       `json:"usage,omitempty"`
+	Metadata          map[string]string     `json:"metadata,omitempty"`
+
+	// Extension: non-fatal notices about gateway-side adjustments
+	Warnings []provider.Warning `json:"warnings,omitempty"`
+}
+
+type ResponsesIncomplete struct {
+	Reason string `json:"reason"`
+}
+
+type ResponsesUsage struct {
+	InputTokens         int                         `json:"input_tokens"`
+	InputTokensDetails  ResponsesInputTokenDetails  `json:"input_tokens_details"`
+	OutputTokens        int                         `json:"output_tokens"`
+	OutputTokensDetails ResponsesOutputTokenDetails `json:"output_tokens_details"`
+	TotalTokens         int                         `json:"total_tokens"`
+}
+
+type ResponsesInputTokenDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+type ResponsesOutputTokenDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+// Item and content part types of the Responses API
+const (
+	responsesItemMessage      = "message"
+	responsesItemFunctionCall = "function_call"
+	responsesItemCallOutput   = "function_call_output"
+	responsesItemReasoning    = "reasoning"
+	responsesPartOutputText   = "output_text"
+	responsesPartSummaryText  = "summary_text"
+)
+
+// responsesInput decodes input, which is a string or an array of items
+func responsesInput(raw json.RawMessage) ([]ResponsesInputItem, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		content, _ := json.Marshal(text)
+		return []ResponsesInputItem{{Type: responsesItemMessage, Role: provider.RoleUser, Content: content}}, nil
+	}
+	var items []ResponsesInputItem
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("input must be a string or an array of items")
+	}
+	return items, nil
+}
+
+// responsesText returns the text of message content, a string or an array
+// of text parts
+func responsesText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	var parts []ResponsesContent
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", fmt.Errorf("content must be a string or an array of content parts")
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case "input_text", "output_text", "text":
+			texts = append(texts, part.Text)
+		default:
+			return "", fmt.Errorf("content part type %q is not supported", part.Type)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// convertResponsesRequest converts a Responses API request to the standard
+// format. Function call outputs become function messages named after the
+// call they answer.
+func convertResponsesRequest(in *OpenAIResponsesRequest) (*provider.StandardRequest, error) {
+	if in.PreviousID != "" {
+		return nil, fmt.Errorf("previous_response_id is not supported; send the whole conversation in input")
+	}
+	items, err := responsesInput(in.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []provider.Message
+	if in.Instructions != "" {
+		messages = append(messages, provider.Message{Role: provider.RoleSystem, Content: in.Instructions})
+	}
+	callNames := make(map[string]string)
+	for i, item := range items {
+		switch item.Type {
+		case responsesItemMessage, "":
+			role := item.Role
+			switch role {
+			case "developer":
+				role = provider.RoleSystem
+			case provider.RoleSystem, provider.RoleUser, provider.RoleAssistant:
+			default:
+				return nil, fmt.Errorf("input[%d]: unknown role %q", i, item.Role)
+			}
+			text, err := responsesText(item.Content)
+			if err != nil {
+				return nil, fmt.Errorf("input[%d]: %w", i, err)
+			}
+			messages = append(messages, provider.Message{Role: role, Content: text})
+		case responsesItemFunctionCall:
+			callNames[item.CallID] = item.Name
+			messages = append(messages, provider.Message{
+				Role:         provider.RoleAssistant,
+				FunctionCall: &provider.FunctionCall{Name: item.Name, Arguments: item.Arguments},
+			})
+		case responsesItemCallOutput:
+			name, ok := callNames[item.CallID]
+			if !ok {
+				return nil, fmt.Errorf("input[%d]: no function_call with call_id %q", i, item.CallID)
+			}
+			messages = append(messages, provider.Message{Role: provider.RoleFunction, Name: &name, Content: item.Output})
+		case responsesItemReasoning:
+			// Earlier reasoning is not replayed to providers
+		default:
+			return nil, fmt.Errorf("input[%d]: item type %q is not supported", i, item.Type)
+		}
+	}
+
+	var functions []provider.Function
+	for _, tool := range in.Tools {
+		if tool.Type == "function" {
+			functions = append(functions, provider.Function{Name: tool.Name, Description: tool.Description, Parameters: tool.Parameters})
+		}
+	}
+
+	return &provider.StandardRequest{
+		Model:       in.Model,
+		Messages:    messages,
+		Stream:      in.Stream,
+		MaxTokens:   in.MaxOutputTokens,
+		Temperature: in.Temperature,
+		TopP:        in.TopP,
+		Functions:   functions,
+	}, nil
+}
+
+// responsesStatus maps a finish reason to the response status and, for
+// incomplete responses, the reason
+func responsesStatus(finishReason string) (string, *ResponsesIncomplete) {
+	switch finishReason {
+	case "length":
+		return "incomplete", &ResponsesIncomplete{Reason: "max_output_tokens"}
+	case "content_filter":
+		return "incomplete", &ResponsesIncomplete{Reason: "content_filter"}
+	default:
+		return "completed", nil
+	}
+}
+
+func responsesUsage(u provider.Usage) *ResponsesUsage {
+	return &ResponsesUsage{
+		InputTokens:         u.PromptTokens,
+		InputTokensDetails:  ResponsesInputTokenDetails{CachedTokens: u.CachedPromptTokens},
+		OutputTokens:        u.CompletionTokens,
+		OutputTokensDetails: ResponsesOutputTokenDetails{ReasoningTokens: u.ReasoningTokens},
+		TotalTokens:         u.TotalTokens,
+	}
+}
+
+// newResponse returns an in-progress response without output
+func newResponse(model string, metadata map[string]string) OpenAIResponse {
+	return OpenAIResponse{
+		ID:        "resp_" + ids.New(),
+		Object:    "response",
+		CreatedAt: time.Now().Unix(),
+		Status:    "in_progress",
+		Model:     model,
+		Output:    []ResponsesOutputItem{},
+		Metadata:  metadata,
+	}
+}
+
+// convertToResponse converts the first choice of a standard response to
+// Responses API output items
+func convertToResponse(resp *provider.StandardResponse, metadata map[string]string) OpenAIResponse {
+	out := newResponse(resp.Model, metadata)
+	finishReason := ""
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		if choice.FinishReason != nil {
+			finishReason = *choice.FinishReason
+		}
+		if msg := choice.Message; msg != nil {
+			if msg.ReasoningContent != "" {
+				out.Output = append(out.Output, ResponsesOutputItem{
+					Type:    responsesItemReasoning,
+					ID:      "rs_" + ids.New(),
+					Summary: []ResponsesContent{{Type: responsesPartSummaryText, Text: msg.ReasoningContent}},
+				})
+			}
+			if msg.Content != "" || msg.FunctionCall == nil {
+				out.Output = append(out.Output, ResponsesOutputItem{
+					Type:    responsesItemMessage,
+					ID:      "msg_" + ids.New(),
+					Status:  "completed",
+					Role:    provider.RoleAssistant,
+					Content: []ResponsesContent{{Type: responsesPartOutputText, Text: msg.Content, Annotations: []interface{}{}}},
+				})
+			}
+			if call := msg.FunctionCall; call != nil {
+				out.Output = append(out.Output, ResponsesOutputItem{
+					Type:      responsesItemFunctionCall,
+					ID:        "fc_" + ids.New(),
+					Status:    "completed",
+					CallID:    "call_" + ids.New(),
+					Name:      call.Name,
+					Arguments: call.Arguments,
+				})
+			}
+		}
+	}
+	out.Status, out.IncompleteDetails = responsesStatus(finishReason)
+	out.Usage = responsesUsage(resp.Usage)
+	out.Warnings = resp.Warnings
+	return out
+}
+
+// responsesEncoder turns provider stream chunks into Responses API
+// streaming events. Output items are opened as the deltas for them arrive
+// and closed when the next kind of delta starts.
+type responsesEncoder struct {
+	w       io.Writer
+	enc     *json.Encoder
+	resp    OpenAIResponse
+	seq     int
+	partial []byte
+	started bool
+
+	// Index in resp.Output of the open item, or -1
+	open   int
+	text   strings.Builder
+	finish string
+	usage  *provider.Usage
+}
+
+func newResponsesEncoder(w io.Writer, model string, metadata map[string]string) *responsesEncoder {
+	return &responsesEncoder{w: w, enc: json.NewEncoder(w), resp: newResponse(model, metadata), open: -1}
+}
+
+// Write encodes every complete line in b, keeping a trailing partial line
+// for the next call
+func (e *responsesEncoder) Write(b []byte) error {
+	e.partial = append(e.partial, b...)
+	for {
+		i := bytes.IndexByte(e.partial, '\n')
+		if i < 0 {
+			return nil
+		}
+		if err := e.encodeLine(e.partial[:i]); err != nil {
+			return err
+		}
+		e.partial = e.partial[i+1:]
+	}
+}
+
+// Close encodes any unterminated last line, closes the open item and sends
+// the final response
+func (e *responsesEncoder) Close(warnings []provider.Warning) error {
+	if err := e.encodeLine(e.partial); err != nil {
+		return err
+	}
+	e.partial = nil
+	if err := e.start(); err != nil {
+		return err
+	}
+	if err := e.closeItem(); err != nil {
+		return err
+	}
+	var event string
+	e.resp.Status, e.resp.IncompleteDetails = responsesStatus(e.finish)
+	if e.resp.Status == "completed" {
+		event = "response.completed"
+	} else {
+		event = "response.incomplete"
+	}
+	if e.usage != nil {
+		e.resp.Usage = responsesUsage(*e.usage)
+	}
+	e.resp.Warnings = warnings
+	return e.event(event, gin.H{"response": e.resp})
+}
+
+// Usage returns the token usage reported by the stream, if any
+func (e *responsesEncoder) Usage() *provider.Usage {
+	return e.usage
+}
+
+func (e *responsesEncoder) encodeLine(line []byte) error {
+	line = bytes.TrimSpace(line)
+	line = bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+	if len(line) == 0 || string(line) == "[DONE]" {
+		return nil
+	}
+
+	var chunk provider.StreamChunk
+	if err := json.Unmarshal(line, &chunk); err != nil {
+		return e.fail(fmt.Errorf("invalid stream chunk from provider: %w", err))
+	}
+	if chunk.Error != nil {
+		return e.fail(errors.New(chunk.Error.Message))
+	}
+	if chunk.Usage != nil {
+		e.usage = chunk.Usage
+	}
+	for _, choice := range chunk.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		if choice.FinishReason != nil {
+			e.finish = *choice.FinishReason
+		}
+		if choice.Delta != nil {
+			if err := e.delta(choice.Delta); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// delta sends the events for one message delta
+func (e *responsesEncoder) delta(msg *provider.Message) error {
+	if err := e.start(); err != nil {
+		return err
+	}
+	if msg.ReasoningContent != "" {
+		if err := e.openItem(responsesItemReasoning, false); err != nil {
+			return err
+		}
+		if err := e.itemEvent("response.reasoning_summary_text.delta", gin.H{"summary_index": 0, "delta": msg.ReasoningContent}); err != nil {
+			return err
+		}
+		e.text.WriteString(msg.ReasoningContent)
+	}
+	if msg.Content != "" {
+		if err := e.openItem(responsesItemMessage, false); err != nil {
+			return err
+		}
+		if err := e.itemEvent("response.output_text.delta", gin.H{"content_index": 0, "delta": msg.Content}); err != nil {
+			return err
+		}
+		e.text.WriteString(msg.Content)
+	}
+	if call := msg.FunctionCall; call != nil {
+		// A name starts a new call; argument fragments continue the open one
+		if err := e.openItem(responsesItemFunctionCall, call.Name != ""); err != nil {
+			return err
+		}
+		item := &e.resp.Output[e.open]
+		if call.Name != "" {
+			item.Name = call.Name
+		}
+		if call.Arguments != "" {
+			item.Arguments += call.Arguments
+			if err := e.itemEvent("response.function_call_arguments.delta", gin.H{"delta": call.Arguments}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// start sends the created events ahead of the first output
+func (e *responsesEncoder) start() error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+	if err := e.event("response.created", gin.H{"response": e.resp}); err != nil {
+		return err
+	}
+	return e.event("response.in_progress", gin.H{"response": e.resp})
+}
+
+// openItem makes an item of kind the open one, closing any other. With
+// fresh, an open item of the same kind is closed too.
+func (e *responsesEncoder) openItem(kind string, fresh bool) error {
+	if e.open >= 0 && e.resp.Output[e.open].Type == kind && !fresh {
+		return nil
+	}
+	if err := e.closeItem(); err != nil {
+		return err
+	}
+
+	item := ResponsesOutputItem{Type: kind, Status: "in_progress"}
+	switch kind {
+	case responsesItemMessage:
+		item.ID, item.Role, item.Content = "msg_"+ids.New(), provider.RoleAssistant, []ResponsesContent{}
+	case responsesItemFunctionCall:
+		item.ID, item.CallID = "fc_"+ids.New(), "call_"+ids.New()
+	case responsesItemReasoning:
+		item.ID, item.Status, item.Summary = "rs_"+ids.New(), "", []ResponsesContent{}
+	}
+	e.resp.Output = append(e.resp.Output, item)
+	e.open = len(e.resp.Output) - 1
+	e.text.Reset()
+
+	if err := e.event("response.output_item.added", gin.H{"output_index": e.open, "item": item}); err != nil {
+		return err
+	}
+	switch kind {
+	case responsesItemMessage:
+		return e.itemEvent("response.content_part.added", gin.H{"content_index": 0, "part": ResponsesContent{Type: responsesPartOutputText, Annotations: []interface{}{}}})
+	case responsesItemReasoning:
+		return e.itemEvent("response.reasoning_summary_part.added", gin.H{"summary_index": 0, "part": ResponsesContent{Type: responsesPartSummaryText}})
+	}
+	return nil
+}
+
+// closeItem completes the open item, if any
+func (e *responsesEncoder) closeItem() error {
+	if e.open < 0 {
+		return nil
+	}
+	item := &e.resp.Output[e.open]
+	text := e.text.String()
+	var err error
+	switch item.Type {
+	case responsesItemMessage:
+		part := ResponsesContent{Type: responsesPartOutputText, Text: text, Annotations: []interface{}{}}
+		item.Content = []ResponsesContent{part}
+		if err = e.itemEvent("response.output_text.done", gin.H{"content_index": 0, "text": text}); err == nil {
+			err = e.itemEvent("response.content_part.done", gin.H{"content_index": 0, "part": part})
+		}
+	case responsesItemFunctionCall:
+		err = e.itemEvent("response.function_call_arguments.done", gin.H{"arguments": item.Arguments})
+	case responsesItemReasoning:
+		part := ResponsesContent{Type: responsesPartSummaryText, Text: text}
+		item.Summary = []ResponsesContent{part}
+		if err = e.itemEvent("response.reasoning_summary_text.done", gin.H{"summary_index": 0, "text": text}); err == nil {
+			err = e.itemEvent("response.reasoning_summary_part.done", gin.H{"summary_index": 0, "part": part})
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if item.Status != "" {
+		item.Status = "completed"
+	}
+	index := e.open
+	e.open = -1
+	return e.event("response.output_item.done", gin.H{"output_index": index, "item": *item})
+}
+
+// itemEvent sends an event about the open item
+func (e *responsesEncoder) itemEvent(typ string, fields gin.H) error {
+	fields["item_id"] = e.resp.Output[e.open].ID
+	fields["output_index"] = e.open
+	return e.event(typ, fields)
+}
+
+func (e *responsesEncoder) event(typ string, fields gin.H) error {
+	fields["type"] = typ
+	fields["sequence_number"] = e.seq
+	e.seq++
+	if _, err := e.w.Write([]byte("event: " + typ + "\ndata: ")); err != nil {
+		return err
+	}
+	if err := e.enc.Encode(fields); err != nil {
+		return err
+	}
+	_, err := e.w.Write([]byte("\n"))
+	return err
+}
+
+// fail tells the client the stream broke and returns err to stop the relay
+func (e *responsesEncoder) fail(err error) error {
+	return e.abort(err, "")
+}
+
+// abort is fail with an error code for the client
+func (e *responsesEncoder) abort(err error, code string) error {
+	fields := gin.H{"message": err.Error()}
+	if code != "" {
+		fields["code"] = code
+	}
+	_ = e.event("error", fields)
+	return err
+}
+
+// responsesHandler serves POST /v1/responses over the same providers as
+// chat completions
+func responsesHandler(r *provider.Router, adm *admission.Controller, store artifacts.Store, fileStore *files.Store, toolRuntime *tools.Runtime, shedder *loadshed.Shedder, relay *streamRelay, tracer *tracing.Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in OpenAIResponsesRequest
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+			return
+		}
+		if in.Model == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "model is required"})
+			return
+		}
+		in.Model = resolveModelAlias(c, r, in.Model)
+		standardReq, err := convertResponsesRequest(&in)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !checkRequestLimits(c, r, standardReq) {
+			return
+		}
+		setRequestModel(c, in.Model)
+
+		if in.Stream {
+			if shed := shedder.Check(); shed != nil {
+				c.Header("Retry-After", strconv.Itoa(int(shed.RetryAfter.Seconds())))
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": shed.Error(), "code": "load_shed_" + shed.Reason})
+				return
+			}
+		}
+
+		attrs := requestAttributes(c)
+		routeReq := &provider.RouteRequest{Model: in.Model, Endpoint: c.FullPath(), Headers: routeHeaders(c.Request.Header), Attributes: attrs, Needs: provider.NeedsOf(standardReq),
+			Conversation: conversationID(c)}
+		in.Model = fitContextLength(c, r, routeReq, standardReq)
+		p, err := r.Route(routeReq)
+		if err != nil {
+			var policyErr *provider.PolicyError
+			if errors.As(err, &policyErr) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": policyErr.Code})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		setRequestProvider(c, p)
+		setRequestArm(c, routeReq.Arm)
+		standardReq.Model = upstreamModel(routeReq)
+
+		release, err := adm.Acquire(c.Request.Context(), p.GetInfo().Name, in.Model, attrs)
+		if err != nil {
+			var rejected *admission.RejectedError
+			if errors.As(err, &rejected) {
+				c.Header("Retry-After", strconv.Itoa(rejected.RetryAfterSeconds()))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": "admission_" + rejected.Reason})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		defer release()
+
+		if err := expandReferences(c.Request.Context(), store, fileStore, standardReq); err != nil {
+			if code := referenceErrorCode(err); code != "" {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": code})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !applyModelLimits(c, r.ModelCapabilities(p, in.Model), standardReq) {
+			return
+		}
+
+		if in.Stream {
+			streamResponse(c, r, p, relay, tracer, toolRuntime, standardReq, &in)
+			return
+		}
+
+		var resp *provider.StandardResponse
+		if toolRuntime.Enabled() {
+			resp, err = toolRuntime.Run(c.Request.Context(), p, standardReq, nil)
+		} else {
+			var gen *provider.GenerateResponse
+			if gen, err = p.Generate(c.Request.Context(), &provider.GenerateRequest{StandardRequest: standardReq}); err == nil {
+				resp = gen.StandardResponse
+			}
+		}
+		var budgetErr *tools.BudgetExceededError
+		if errors.As(err, &budgetErr) {
+			setTokenUsage(c, budgetErr.Usage)
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "tool_budget_exceeded", "budget": budgetErr})
+			return
+		}
+		r.ReportOutcome(p.GetInfo().Name, err)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		setTokenUsage(c, resp.Usage)
+		resp.Model = clientModel(routeReq, resp.Model)
+		setResponseCost(c, r, resp.Usage, resp.Metadata)
+
+		out := convertToResponse(resp, in.Metadata)
+		out.Warnings = append(requestWarnings(c), out.Warnings...)
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+func setEventStreamHeaders(c *gin.Context) {
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+}
+
+// streamResponse relays a provider stream as Responses API events
+func streamResponse(c *gin.Context, r *provider.Router, p provider.Provider, relay *streamRelay, tracer *tracing.Tracer, toolRuntime *tools.Runtime, req *provider.StandardRequest, in *OpenAIResponsesRequest) {
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "streaming unsupported"})
+		return
+	}
+	trace := tracer.Start(requestID(c), traceKeyID(c), c.FullPath(), p.GetInfo().Name, in.Model)
+	rc, err := openStream(c.Request.Context(), r, toolRuntime, p, req)
+	defer func() { finishTrace(trace, err) }()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	trace.Upstream()
+	rc = trace.WrapUpstream(rc)
+	defer rc.Close()
+	setEventStreamHeaders(c)
+
+	enc := newResponsesEncoder(c.Writer, in.Model, in.Metadata)
+	deadline := relay.writeDeadline(c.Writer)
+	defer deadline.clear()
+	err = relay.pump(c.Request.Context(), rc, func(b []byte) error {
+		deadline.extend()
+		trace.Write(len(b))
+		return enc.Write(b)
+	}, func() {
+		deadline.extend()
+		flusher.Flush()
+		trace.Flush()
+	})
+	if usage := enc.Usage(); usage != nil {
+		setTokenUsage(c, *usage)
+	}
+	if errors.Is(err, errSlowClient) {
+		deadline.extend()
+		_ = enc.abort(err, "slow_client")
+	}
+	if err != nil {
+		flusher.Flush()
+		return
+	}
+	deadline.extend()
+	_ = enc.Close(requestWarnings(c))
+	flusher.Flush()
+}
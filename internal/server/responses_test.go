@@ -0,0 +1,212 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+func TestConvertResponsesRequest(t *testing.T) {
+	for name, tc := range map[string]struct {
+		body  string
+		roles []string
+		err   string
+	}{
+		"string input": {
+			body:  `{"model":"m","instructions":"be brief","input":"hi"}`,
+			roles: []string{"system", "user"},
+		},
+		"message items": {
+			body:  `{"model":"m","input":[{"role":"developer","content":"rules"},{"type":"message","role":"user","content":[{"type":"input_text","text":"a"},{"type":"input_text","text":"b"}]},{"role":"assistant","content":[{"type":"output_text","text":"c"}]}]}`,
+			roles: []string{"system", "user", "assistant"},
+		},
+		"function call round trip": {
+			body:  `{"model":"m","input":[{"role":"user","content":"weather?"},{"type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{}"},{"type":"function_call_output","call_id":"call_1","output":"sunny"}]}`,
+			roles: []string{"user", "assistant", "function"},
+		},
+		"unknown call id": {
+			body: `{"model":"m","input":[{"type":"function_call_output","call_id":"call_9","output":"x"}]}`,
+			err:  `no function_call with call_id "call_9"`,
+		},
+		"image part": {
+			body: `{"model":"m","input":[{"role":"user","content":[{"type":"input_image","image_url":"x"}]}]}`,
+			err:  `content part type "input_image" is not supported`,
+		},
+		"previous response": {
+			body: `{"model":"m","input":"hi","previous_response_id":"resp_1"}`,
+			err:  "previous_response_id is not supported",
+		},
+	} {
+		var in OpenAIResponsesRequest
+		if err := json.Unmarshal([]byte(tc.body), &in); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		req, err := convertResponsesRequest(&in)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: expected error %q, got %v", name, tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		var roles []string
+		for _, m := range req.Messages {
+			roles = append(roles, m.Role)
+		}
+		if strings.Join(roles, ",") != strings.Join(tc.roles, ",") {
+			t.Errorf("%s: expected roles %v, got %v", name, tc.roles, roles)
+		}
+		if name == "message items" && req.Messages[1].Content != "a\nb" {
+			t.Errorf("%s: expected text parts joined, got %q", name, req.Messages[1].Content)
+		}
+		if name == "function call round trip" && (req.Messages[2].Name == nil || *req.Messages[2].Name != "get_weather") {
+			t.Errorf("%s: expected the output named after its call, got %+v", name, req.Messages[2])
+		}
+	}
+}
+
+func newResponsesTestServer(t *testing.T) http.Handler {
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}, Responses: []config.MockResponse{
+		{Match: "weather", Content: "Let me check", FunctionCall: &config.MockFunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+		{Content: "Hello there"},
+	}}}}
+	cfg.Server.Streaming = config.Streaming{ReadBufferSize: 64, ChannelBuffer: 4, CoalesceBytes: 256, FlushBytes: 256, FlushInterval: time.Second}
+	engine, _, _ := newChatTestServer(t, cfg)
+	return engine
+}
+
+func TestResponsesEndpoint(t *testing.T) {
+	engine := newResponsesTestServer(t)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(`{"model":"demo-chat","input":"what is the weather","metadata":{"k":"v"}}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var out OpenAIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.ID, "resp_") || out.Object != "response" || out.Status != "completed" || out.Metadata["k"] != "v" {
+		t.Errorf("Unexpected response envelope: %+v", out)
+	}
+	if len(out.Output) != 2 || out.Output[0].Type != "message" || out.Output[0].Content[0].Text != "Let me check" ||
+		out.Output[1].Type != "function_call" || out.Output[1].Name != "get_weather" || out.Output[1].CallID == "" {
+		t.Errorf("Unexpected output items: %+v", out.Output)
+	}
+	if out.Usage == nil || out.Usage.TotalTokens == 0 {
+		t.Errorf("Expected usage, got %+v", out.Usage)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(`{"model":"demo-chat","input":[{"type":"file_search_call"}]}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected unsupported items rejected, got %d", w.Code)
+	}
+}
+
+func TestResponsesStreaming(t *testing.T) {
+	engine := newResponsesTestServer(t)
+
+	for name, tc := range map[string]struct {
+		input  string
+		events []string
+		output string
+	}{
+		"text": {
+			input: "hi",
+			events: []string{
+				"response.created", "response.in_progress",
+				"response.output_item.added", "response.content_part.added",
+				"response.output_text.delta", "response.output_text.delta",
+				"response.output_text.done", "response.content_part.done", "response.output_item.done",
+				"response.completed",
+			},
+			output: "message",
+		},
+		"function call": {
+			input: "weather in Paris",
+			events: []string{
+				"response.created", "response.in_progress",
+				"response.output_item.added", "response.content_part.added",
+				"response.output_text.delta", "response.output_text.delta", "response.output_text.delta",
+				"response.output_text.done", "response.content_part.done", "response.output_item.done",
+				"response.output_item.added", "response.function_call_arguments.delta",
+				"response.function_call_arguments.done", "response.output_item.done",
+				"response.completed",
+			},
+			output: "message,function_call",
+		},
+	} {
+		body, _ := json.Marshal(map[string]interface{}{"model": "demo-chat", "stream": true, "input": tc.input})
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/responses", strings.NewReader(string(body))))
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+			t.Fatalf("%s: expected an event stream, got %d: %s", name, w.Code, w.Body)
+		}
+
+		var events []string
+		var last map[string]json.RawMessage
+		sc := bufio.NewScanner(w.Body)
+		for sc.Scan() {
+			if typ, ok := strings.CutPrefix(sc.Text(), "event: "); ok {
+				events = append(events, typ)
+			}
+			if data, ok := strings.CutPrefix(sc.Text(), "data: "); ok {
+				last = nil
+				if err := json.Unmarshal([]byte(data), &last); err != nil {
+					t.Fatalf("%s: bad event data %q: %v", name, data, err)
+				}
+			}
+		}
+		if strings.Join(events, ",") != strings.Join(tc.events, ",") {
+			t.Errorf("%s: unexpected events\n got %v\nwant %v", name, events, tc.events)
+		}
+
+		var final OpenAIResponse
+		if err := json.Unmarshal(last["response"], &final); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var types []string
+		for _, item := range final.Output {
+			types = append(types, item.Type)
+			if item.Status != "completed" {
+				t.Errorf("%s: expected item %s completed, got %q", name, item.ID, item.Status)
+			}
+		}
+		if strings.Join(types, ",") != tc.output || final.Status != "completed" {
+			t.Errorf("%s: unexpected final response %+v", name, final)
+		}
+		if name == "function call" && final.Output[1].Arguments != `{"city":"Paris"}` {
+			t.Errorf("%s: expected accumulated arguments, got %q", name, final.Output[1].Arguments)
+		}
+	}
+}
+
+func TestConvertToResponseIncomplete(t *testing.T) {
+	length := "length"
+	out := convertToResponse(&provider.StandardResponse{
+		Model:   "m",
+		Choices: []provider.Choice{{Message: &provider.Message{Content: "cut", ReasoningContent: "thinking"}, FinishReason: &length}},
+		Usage:   provider.Usage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8, CachedPromptTokens: 2, ReasoningTokens: 4},
+	}, nil)
+	if out.Status != "incomplete" || out.IncompleteDetails == nil || out.IncompleteDetails.Reason != "max_output_tokens" {
+		t.Errorf("Expected an incomplete response, got %+v", out)
+	}
+	if len(out.Output) != 2 || out.Output[0].Type != "reasoning" || out.Output[0].Summary[0].Text != "thinking" {
+		t.Errorf("Expected reasoning ahead of the message, got %+v", out.Output)
+	}
+	if out.Usage.InputTokensDetails.CachedTokens != 2 || out.Usage.OutputTokensDetails.ReasoningTokens != 4 {
+		t.Errorf("Expected usage details carried over, got %+v", out.Usage)
+	}
+}
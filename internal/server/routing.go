@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"net/textproto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// conversationHeader identifies the conversation a request belongs to, for
+// the sticky routing strategy
+const conversationHeader = "X-LetLLM-Conversation"
+
+// credentialHeaders are kept out of the headers routes match on
+var credentialHeaders = map[string]bool{"Authorization": true, "X-Api-Key": true, "X-Goog-Api-Key": true, "Cookie": true}
+
+// routeHeaders returns the first value of each request header, keyed by
+// canonical name, for routes that match on headers. gRPC metadata, whose
+// keys are lower case, is accepted as well.
+func routeHeaders(h map[string][]string) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		if len(values) == 0 || credentialHeaders[name] {
+			continue
+		}
+		out[name] = values[0]
+	}
+	return out
+}
+
+// conversationID returns the conversation identifier of a request for
+// sticky routing: the X-LetLLM-Conversation header, or else the first of
+// ids set
+func conversationID(c *gin.Context, ids ...string) string {
+	if id := c.GetHeader(conversationHeader); id != "" {
+		return id
+	}
+	for _, id := range ids {
+		if id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// passthroughString returns a string field of passthrough, e.g. the OpenAI
+// user field, or "" when it is missing or not a string
+func passthroughString(passthrough map[string]json.RawMessage, field string) string {
+	var s string
+	if raw, ok := passthrough[field]; ok {
+		_ = json.Unmarshal(raw, &s)
+	}
+	return s
+}
+
+// upstreamModel returns the model name sent to the provider for routeReq:
+// the route's upstream name, such as an Azure deployment, when it sets one
+func upstreamModel(routeReq *provider.RouteRequest) string {
+	if routeReq.UpstreamModel != "" {
+		return routeReq.UpstreamModel
+	}
+	return routeReq.Model
+}
+
+// clientModel returns the model a response reports to the client: the one
+// requested when the route renamed it upstream, otherwise upstream's
+func clientModel(routeReq *provider.RouteRequest, upstream string) string {
+	if routeReq.UpstreamModel != "" {
+		return routeReq.Model
+	}
+	return upstream
+}
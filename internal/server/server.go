@@ -2,23 +2,78 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
+	"net"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
 	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/experiments"
+	"github.com/luguanyu1234/letllm-go/internal/files"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/prompts"
 	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
+	"github.com/luguanyu1234/letllm-go/internal/sessions"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/tracing"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
 	"go.uber.org/fx"
-	"github.com/gin-gonic/gin"
 )
 
 // Module provides the HTTP server lifecycle using Gin
 var Module = fx.Module("http-server",
 	fx.Provide(NewEngine),
+	fx.Provide(NewAdminRouter),
+	fx.Provide(NewRecentRequests),
+	fx.Provide(NewBatchExecutor),
+	fx.Invoke(RegisterKeyAuth),
+	fx.Invoke(RegisterRequestLimits),
+	fx.Invoke(RegisterIdempotency),
+	fx.Invoke(RegisterBudgets),
 	fx.Invoke(RegisterRoutes),
+	fx.Invoke(RegisterPassthrough),
+	fx.Invoke(RegisterKeyAdminRoutes),
+	fx.Invoke(RegisterModelAliasRoutes),
+	fx.Invoke(RegisterArtifactRoutes),
+	fx.Invoke(RegisterFileRoutes),
+	fx.Invoke(RegisterSessionRoutes),
+	fx.Invoke(RegisterPromptTemplateRoutes),
+	fx.Invoke(RegisterBatchRoutes),
+	fx.Invoke(RegisterUsageAdminRoutes),
+	fx.Invoke(RegisterBudgetAdminRoutes),
+	fx.Invoke(RegisterRecentAdminRoutes),
+	fx.Invoke(RegisterTraceAdminRoutes),
+	fx.Invoke(RegisterAutoscalingRoutes),
+	fx.Invoke(RegisterFailoverAdminRoutes),
+	fx.Invoke(RegisterProviderAdminRoutes),
+	fx.Invoke(RegisterReload),
+	fx.Invoke(RegisterLoadSheddingRoutes),
+	fx.Invoke(RegisterModelRoutes),
+	fx.Invoke(RegisterModelCapabilitiesRoute),
+	fx.Invoke(RegisterErasureRoutes),
+	fx.Invoke(RegisterPIIRoutes),
+	fx.Invoke(RegisterCompareRoutes),
+	fx.Invoke(RegisterFanoutRoutes),
+	fx.Invoke(RegisterHealthRoutes),
+	fx.Invoke(RegisterVersionRoute),
+	fx.Invoke(RegisterPprofRoutes),
+	fx.Invoke(RegisterOpenAPIRoutes),
 	fx.Invoke(StartServer),
 )
 
@@ -29,60 +84,250 @@ func NewEngine() *gin.Engine {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.New()
-	r.Use(gin.Recovery())
+	r.Use(gin.Recovery(), assignRequestID(), applyClientTimeout())
 	return r
 }
 
-// StartServer starts the HTTP server and registers lifecycle hooks
+// StartServer starts an HTTP server per configured listener and registers
+// lifecycle hooks. Listen errors, such as an address family the host lacks,
+// fail startup.
 func StartServer(lc fx.Lifecycle, engine *gin.Engine, cfg *config.Config) {
-	addr := cfg.Server.Addr
-	if addr == "" {
-		addr = ":8080"
-	}
-	srv := &http.Server{
-		Addr:              addr,
-		Handler:           engine,
-		ReadHeaderTimeout: 10 * time.Second,
-	}
+	var servers []*http.Server
 
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
-			go func() {
-				if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-					log.Printf("http server error: %v", err)
+			for _, l := range listeners(cfg) {
+				tlsConfig, err := listenerTLSConfig(l)
+				if err != nil {
+					for _, srv := range servers {
+						_ = srv.Close()
+					}
+					return fmt.Errorf("listener %s: %w", l.Addr, err)
 				}
-			}()
+				ln, err := net.Listen(l.Network, l.Addr)
+				if err != nil {
+					for _, srv := range servers {
+						_ = srv.Close()
+					}
+					return fmt.Errorf("listen on %s %s: %w", l.Network, l.Addr, err)
+				}
+				if tlsConfig != nil {
+					ln = tls.NewListener(ln, tlsConfig)
+				}
+				srv := &http.Server{
+					Addr:              l.Addr,
+					Handler:           certIdentity(l.ClientKeys, engine),
+					TLSConfig:         tlsConfig,
+					ReadHeaderTimeout: 10 * time.Second,
+				}
+				servers = append(servers, srv)
+
+				go func(l config.Listener) {
+					if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+						log.Printf("http server error on %s: %v", l.Addr, err)
+					}
+				}(l)
+			}
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
 			shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 			defer cancel()
-			return srv.Shutdown(shutdownCtx)
+			var errs []error
+			for _, srv := range servers {
+				errs = append(errs, srv.Shutdown(shutdownCtx))
+			}
+			return errors.Join(errs...)
 		},
 	})
 }
 
+// listeners returns the configured listeners, or a single one on
+// server.addr when none are configured
+func listeners(cfg *config.Config) []config.Listener {
+	if len(cfg.Server.Listeners) > 0 {
+		return cfg.Server.Listeners
+	}
+	addr := cfg.Server.Addr
+	if addr == "" {
+		addr = ":8080"
+	}
+	return []config.Listener{{Addr: addr, Network: config.NetworkTCP}}
+}
+
 // RegisterRoutes wires handlers on Gin
-func RegisterRoutes(engine *gin.Engine, r *provider.Router) {
-	engine.POST("/v1/chat/completions", func(c *gin.Context) {
+func RegisterRoutes(engine *gin.Engine, r *provider.Router, cfg *config.Config, m *metrics.Registry, adm *admission.Controller, store artifacts.Store, usageStore usage.Store, keyStore keys.Store, enricher *enrich.Enricher, toolRuntime *tools.Runtime, recent *RecentRequests, responses *respcache.Cache, shedder *loadshed.Shedder, tracer *tracing.Tracer, sessionStore sessions.Store, templates *prompts.Registry, fileStore *files.Store) {
+	fidelity := newFidelityTracker(m, cfg.Transform.ReportDroppedFields)
+	relay := newStreamRelay(cfg.Server.Streaming)
+	slowClients := m.Counter("letllm_stream_slow_client_aborts_total",
+		"Streams ended because the client read slower than the upstream for too long.",
+		"provider")
+	rateLimits := newRateLimitExporter(m)
+	cacheRequests := m.Counter("letllm_response_cache_requests_total",
+		"Chat completions on caching routes by cache result: hit, miss or bypass.",
+		"result")
+
+	arms := countRouteArms(m)
+	spend := spendTokens(adm)
+	price := priceRequest(r)
+	mirrors := newMirrorer(r, m)
+	fallbacks := newFilterFallbacks(r, m)
+	hedges := newHedger(r, adm, m)
+	assigner := experiments.NewAssigner(cfg)
+
+	engine.GET("/metrics", func(c *gin.Context) {
+		rateLimits.refresh(r)
+		c.Header("Content-Type", "text/plain; version=0.0.4")
+		c.Status(http.StatusOK)
+		_ = m.WriteText(c.Writer)
+	})
+
+	engine.POST("/v1/embeddings", recordUsage(cfg, usageStore, keyStore, recent), spend, price, arms, enrichRequest(enricher, cfg.Enrichment.FailOpen), embeddingsHandler(r, adm))
+
+	engine.POST("/v1/audio/transcriptions", recordUsage(cfg, usageStore, keyStore, recent), spend, price, arms, enrichRequest(enricher, cfg.Enrichment.FailOpen), transcriptionsHandler(r, adm))
+	engine.POST("/v1/audio/speech", recordUsage(cfg, usageStore, keyStore, recent), spend, price, arms, enrichRequest(enricher, cfg.Enrichment.FailOpen), speechHandler(r, adm))
+
+	engine.POST("/v1/responses", recordUsage(cfg, usageStore, keyStore, recent), spend, price, arms, enrichRequest(enricher, cfg.Enrichment.FailOpen), responsesHandler(r, adm, store, fileStore, toolRuntime, shedder, relay, tracer))
+	engine.POST("/v1/messages", recordUsage(cfg, usageStore, keyStore, recent), spend, price, arms, enrichRequest(enricher, cfg.Enrichment.FailOpen), messagesHandler(r, adm, store, fileStore, toolRuntime, shedder, relay, tracer))
+	// Upgraded to a WebSocket relayed to the provider's realtime session
+	engine.GET("/v1/realtime", recordUsage(cfg, usageStore, keyStore, recent), spend, price, arms, enrichRequest(enricher, cfg.Enrichment.FailOpen), realtimeHandler(r, adm))
+	// The model segment ends in ":generateContent" or ":streamGenerateContent"
+	engine.POST("/v1beta/models/:model", recordUsage(cfg, usageStore, keyStore, recent), spend, price, arms, enrichRequest(enricher, cfg.Enrichment.FailOpen), geminiHandler(r, adm, store, fileStore, toolRuntime, shedder, relay, tracer))
+
+	engine.POST("/v1/chat/completions", recordUsage(cfg, usageStore, keyStore, recent), spend, price, arms, enrichRequest(enricher, cfg.Enrichment.FailOpen), func(c *gin.Context) {
 		var in OpenAIChatCompletionRequest
 		if err := c.ShouldBindJSON(&in); err != nil {
 			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
 			return
 		}
+		var preamble []provider.Message
+		var tmpl *prompts.Template
+		if in.Template != "" {
+			var ok bool
+			if tmpl, preamble, ok = renderTemplate(c, templates, in.Template, in.Variables); !ok {
+				return
+			}
+		}
+		var history []provider.Message
+		if in.SessionID != "" {
+			sess, messages, ok := openSession(c, sessionStore, keyStore, in.SessionID)
+			if !ok {
+				return
+			}
+			history = messages
+			if in.Model == "" {
+				in.Model = sess.Model
+			}
+		}
+		if in.Model == "" && tmpl != nil {
+			in.Model = tmpl.Model
+		}
 		if in.Model == "" {
 			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "model is required"})
 			return
 		}
+		in.Model = resolveModelAlias(c, r, in.Model)
+		setRequestModel(c, in.Model)
 
-		p, err := r.ForModel(in.Model)
+		// Streams hold memory and a goroutine for their whole duration, so
+		// they are refused first when the process is under pressure
+		if in.Stream {
+			if shed := shedder.Check(); shed != nil {
+				c.Header("Retry-After", strconv.Itoa(int(shed.RetryAfter.Seconds())))
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": shed.Error(), "code": "load_shed_" + shed.Reason})
+				return
+			}
+		}
+
+		attrs := requestAttributes(c)
+		standardReq := convertToStandardRequest(&in)
+		if !checkRequestLimits(c, r, standardReq) {
+			return
+		}
+		turn := append(preamble, standardReq.Messages...)
+		standardReq.Messages = turn
+		if in.SessionID != "" {
+			standardReq.Messages = append(history, turn...)
+		}
+		routeReq := &provider.RouteRequest{Model: in.Model, Endpoint: c.FullPath(), Headers: routeHeaders(c.Request.Header), Attributes: attrs, Needs: provider.NeedsOf(standardReq),
+			Conversation: conversationID(c, in.SessionID, passthroughString(in.Passthrough, "user"))}
+		assignExperiment(c, assigner, keyStore, passthroughString(in.Passthrough, "user"), routeReq, standardReq)
+		in.Model = fitContextLength(c, r, routeReq, standardReq)
+		p, err := r.Route(routeReq)
 		if err != nil {
+			var policyErr *provider.PolicyError
+			if errors.As(err, &policyErr) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": policyErr.Code})
+				return
+			}
 			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		// Convert to standard request format
-		standardReq := convertToStandardRequest(&in)
+		setRequestProvider(c, p)
+		setRequestArm(c, routeReq.Arm)
+		standardReq.Model = upstreamModel(routeReq)
+
+		// Answer from the response cache when both the route and the
+		// client's Cache-Control allow it
+		var cachePlan respcache.Plan
+		var cacheKey string
+		if rc := r.RouteCache(routeReq); rc != nil && !in.Stream {
+			cachePlan = respcache.PlanFor(rc, respcache.ParseCacheControl(c.GetHeader("Cache-Control")))
+			if cachePlan.Enabled() {
+				if cacheKey, err = respcache.Key(callerKeyID(c, keyStore), p.GetInfo().Name, standardReq); err != nil {
+					cachePlan = respcache.Plan{}
+				}
+			}
+			result := "bypass"
+			if cachePlan.Lookup {
+				result = "miss"
+				if resp, age, ok := responses.Get(c.Request.Context(), cacheKey, cachePlan.MaxAge); ok {
+					cacheRequests.Inc("hit")
+					c.Header("X-LetLLM-Cache", "hit")
+					c.Header("Age", strconv.Itoa(int(age.Seconds())))
+					if in.SessionID != "" && len(resp.Choices) > 0 && resp.Choices[0].Message != nil {
+						recordTurn(sessionStore, in.SessionID, turn, *resp.Choices[0].Message)
+					}
+					out := convertFromStandardResponse(resp)
+					out.Model = clientModel(routeReq, out.Model)
+					out.Warnings = append(requestWarnings(c), out.Warnings...)
+					c.JSON(http.StatusOK, out)
+					return
+				}
+			}
+			cacheRequests.Inc(result)
+			c.Header("X-LetLLM-Cache", result)
+		}
+
+		// Hold a concurrency slot for the model and provider until the
+		// response, including any stream, has been fully relayed
+		release, err := adm.Acquire(c.Request.Context(), p.GetInfo().Name, in.Model, attrs)
+		if err != nil {
+			var rejected *admission.RejectedError
+			if errors.As(err, &rejected) {
+				c.Header("Retry-After", strconv.Itoa(rejected.RetryAfterSeconds()))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": "admission_" + rejected.Reason})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		defer release()
+
+		if err := expandReferences(c.Request.Context(), store, fileStore, standardReq); err != nil {
+			if code := referenceErrorCode(err); code != "" {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": code})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		model := r.ModelCapabilities(p, in.Model)
+		if !applyModelLimits(c, model, standardReq) {
+			return
+		}
+		fidelity.check(c, &in, p, model, standardReq)
 
 		if in.Stream {
 			// SSE streaming compatible with OpenAI
@@ -95,102 +340,212 @@ func RegisterRoutes(engine *gin.Engine, r *provider.Router) {
 				return
 			}
 
-			rc, err := p.StreamGenerate(c.Request.Context(), &provider.GenerateRequest{StandardRequest: standardReq})
+			trace := tracer.Start(requestID(c), traceKeyID(c), c.FullPath(), p.GetInfo().Name, in.Model)
+			start := time.Now()
+			var rc io.ReadCloser
+			if toolRuntime.Enabled() {
+				// Server-side tools are run by the tool runtime between
+				// streamed model calls
+				rc, err = openStream(c.Request.Context(), r, toolRuntime, p, standardReq)
+			} else {
+				p, rc, err = hedges.stream(c, routeReq, p, standardReq, attrs)
+			}
+			defer func() { finishTrace(trace, err) }()
 			if err != nil {
 				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
+			trace.Upstream()
+			rc = trace.WrapUpstream(rc)
 			defer rc.Close()
 
-			// exchange channel between reader and sender
-			chunks := make(chan []byte, 8)
-			errCh := make(chan error, 1)
-
-			// receiver goroutine: reads from provider stream
-			go func() {
-				defer close(chunks)
-				buf := make([]byte, 4096)
-				for {
-					n, readErr := rc.Read(buf)
-					if n > 0 {
-						cp := make([]byte, n)
-						copy(cp, buf[:n])
-						chunks <- cp
-					}
-					if readErr == io.EOF {
-						return
-					}
-					if readErr != nil {
-						errCh <- readErr
-						return
-					}
-				}
-			}()
-
-			enc := json.NewEncoder(c.Writer)
-			for {
-				select {
-				case <-c.Request.Context().Done():
-					return
-				case err := <-errCh:
-					if err != nil {
-						return
-					}
-				case b, ok := <-chunks:
-					if !ok {
-						// finished
-						_, _ = c.Writer.Write([]byte("data: [DONE]\n\n"))
-						flusher.Flush()
-						return
-					}
-					payload := OpenAIChatCompletionChunk{
-						Object: "chat.completion.chunk",
-						Choices: []OpenAIChatChunkChoice{{
-							Delta:        OpenAIChatMessage{Role: "assistant", Content: string(b)},
-							Index:        0,
-							FinishReason: nil,
-						}},
-						Model: in.Model,
-					}
-					_, _ = c.Writer.Write([]byte("data: "))
-					if err := enc.Encode(payload); err != nil {
-						return
-					}
-					_, _ = c.Writer.Write([]byte("\n"))
-					flusher.Flush()
-				}
+			enc := newChunkEncoder(c.Writer, in.Model)
+			deadline := relay.writeDeadline(c.Writer)
+			defer deadline.clear()
+			err = relay.pump(c.Request.Context(), rc, func(b []byte) error {
+				deadline.extend()
+				trace.Write(len(b))
+				return enc.Write(b)
+			}, func() {
+				deadline.extend()
+				flusher.Flush()
+				trace.Flush()
+			})
+			if usage := enc.Usage(); usage != nil {
+				setTokenUsage(c, *usage)
 			}
+			if errors.Is(err, errSlowClient) {
+				slowClients.Inc(p.GetInfo().Name)
+				deadline.extend()
+				_ = enc.abort(err, "slow_client")
+			}
+			if err != nil {
+				flusher.Flush()
+				return
+			}
+			deadline.extend()
+			_ = enc.Close(requestWarnings(c))
+			flusher.Flush()
+			if in.SessionID != "" {
+				recordTurn(sessionStore, in.SessionID, turn, enc.Reply())
+			}
+			mirrors.mirror(c, routeReq, standardReq, enc.Reply().Content, time.Since(start))
+			return
 		}
 
-		// Non-streaming
-		resp, err := p.Generate(c.Request.Context(), &provider.GenerateRequest{StandardRequest: standardReq})
+		// Non-streaming; server-side tools are run by the tool runtime
+		generate := func(p provider.Provider, req *provider.StandardRequest) (*provider.StandardResponse, error) {
+			if toolRuntime.Enabled() {
+				return toolRuntime.Run(c.Request.Context(), p, req, nil)
+			}
+			gen, err := p.Generate(c.Request.Context(), &provider.GenerateRequest{StandardRequest: req})
+			if err != nil {
+				return nil, err
+			}
+			return gen.StandardResponse, nil
+		}
+		start := time.Now()
+		resp, err := generate(p, standardReq)
+		var budgetErr *tools.BudgetExceededError
+		if errors.As(err, &budgetErr) {
+			setTokenUsage(c, budgetErr.Usage)
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "tool_budget_exceeded", "budget": budgetErr})
+			return
+		}
+		r.ReportOutcome(p.GetInfo().Name, err)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		resp = fallbacks.retry(c, routeReq, standardReq, resp, generate)
+
+		setTokenUsage(c, resp.Usage)
+		cost, priced := setResponseCost(c, r, resp.Usage, resp.Metadata)
+		if cachePlan.StoreTTL > 0 {
+			responses.Put(c.Request.Context(), cacheKey, resp, cachePlan.StoreTTL)
+		}
+		if in.SessionID != "" && len(resp.Choices) > 0 && resp.Choices[0].Message != nil {
+			recordTurn(sessionStore, in.SessionID, turn, *resp.Choices[0].Message)
+		}
+
+		if len(resp.Choices) > 0 && resp.Choices[0].Message != nil {
+			mirrors.mirror(c, routeReq, standardReq, resp.Choices[0].Message.Content, time.Since(start))
+		}
 
 		// Convert back to OpenAI format
-		out := convertFromStandardResponse(resp.StandardResponse)
+		out := convertFromStandardResponse(resp)
+		out.Model = clientModel(routeReq, out.Model)
+		out.Warnings = append(requestWarnings(c), out.Warnings...)
+		if priced {
+			out.Metadata = maps.Clone(out.Metadata)
+			if out.Metadata == nil {
+				out.Metadata = map[string]interface{}{}
+			}
+			out.Metadata[provider.MetadataCostUSD] = cost
+		}
 		c.JSON(http.StatusOK, out)
 	})
 }
 
 // --- Minimal OpenAI-compatible types ---
 type OpenAIChatCompletionRequest struct {
-	Model    string              `json:"model"`
-	Messages []OpenAIChatMessage `json:"messages"`
-	Stream   bool                `json:"stream"`
+	Model       string              `json:"model"`
+	Messages    []OpenAIChatMessage `json:"messages"`
+	Stream      bool                `json:"stream"`
+	MaxTokens   *int                `json:"max_tokens,omitempty"`
+	Temperature *float64            `json:"temperature,omitempty"`
+	TopP        *float64            `json:"top_p,omitempty"`
+	Functions   []provider.Function `json:"functions,omitempty"`
+	Tools       []OpenAITool        `json:"tools,omitempty"`
+
+	// Extension: continue a conversation session; its history is sent
+	// ahead of messages, and model defaults to the session's
+	SessionID string `json:"session_id,omitempty"`
+	// Extension: expand a configured prompt template with variables ahead
+	// of messages; model defaults to the template's
+	Template  string            `json:"template,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+
+	// JSON mode; forwarded like passthrough fields, so requests setting it
+	// are routed to providers that forward them
+	ResponseFormat json.RawMessage `json:"response_format,omitempty"`
+
+	// Accepted but not forwarded; reported as fidelity issues
+	Logprobs    *bool `json:"logprobs,omitempty"`
+	TopLogprobs *int  `json:"top_logprobs,omitempty"`
+	N           *int  `json:"n,omitempty"`
+
+	// Top-level fields the gateway does not know, forwarded as-is to
+	// providers that support passthrough
+	Passthrough map[string]json.RawMessage `json:"-"`
+}
+
+// chatRequestFields are the JSON names of the fields the gateway decodes
+var chatRequestFields = jsonFieldNames(reflect.TypeOf(OpenAIChatCompletionRequest{}))
+
+// UnmarshalJSON decodes the request and collects unknown fields into
+// Passthrough
+func (r *OpenAIChatCompletionRequest) UnmarshalJSON(data []byte) error {
+	type plain OpenAIChatCompletionRequest
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	r.Passthrough = nil
+	for k, v := range fields {
+		if chatRequestFields[k] {
+			continue
+		}
+		if r.Passthrough == nil {
+			r.Passthrough = make(map[string]json.RawMessage)
+		}
+		r.Passthrough[k] = v
+	}
+	return nil
+}
+
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+type OpenAITool struct {
+	Type     string            `json:"type"`
+	Function provider.Function `json:"function"`
 }
 
 type OpenAIChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// Function that produced a "function" role message
+	Name         string                 `json:"name,omitempty"`
+	FunctionCall *provider.FunctionCall `json:"function_call,omitempty"`
+
+	// Chain of thought from reasoning models; only set in responses
+	ReasoningContent string `json:"reasoning_content,omitempty"`
 }
 
 type OpenAIChatCompletionResponse struct {
+	ID      string             `json:"id"`
 	Object  string             `json:"object"`
+	Created int64              `json:"created"`
 	Model   string             `json:"model"`
 	Choices []OpenAIChatChoice `json:"choices"`
+
+	// Extension: non-fatal notices about gateway-side adjustments
+	Warnings []provider.Warning `json:"warnings,omitempty"`
+	// Extension: provider metadata such as the upstream that served the
+	// request and its cost, or the sources cited by the answer
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
 type OpenAIChatChoice struct {
@@ -200,9 +555,13 @@ type OpenAIChatChoice struct {
 }
 
 type OpenAIChatCompletionChunk struct {
-	Object  string                  `json:"object"`
-	Model   string                  `json:"model"`
-	Choices []OpenAIChatChunkChoice `json:"choices"`
+	ID       string                  `json:"id"`
+	Object   string                  `json:"object"`
+	Created  int64                   `json:"created"`
+	Model    string                  `json:"model"`
+	Choices  []OpenAIChatChunkChoice `json:"choices"`
+	Usage    *provider.Usage         `json:"usage,omitempty"`
+	Warnings []provider.Warning      `json:"warnings,omitempty"`
 }
 
 type OpenAIChatChunkChoice struct {
@@ -232,15 +591,42 @@ func convertToStandardRequest(req *OpenAIChatCompletionRequest) *provider.Standa
 	messages := make([]provider.Message, len(req.Messages))
 	for i, msg := range req.Messages {
 		messages[i] = provider.Message{
-			Role:    msg.Role,
-			Content: msg.Content,
+			Role:         msg.Role,
+			Content:      msg.Content,
+			FunctionCall: msg.FunctionCall,
+		}
+		if msg.Name != "" {
+			name := msg.Name
+			messages[i].Name = &name
+		}
+	}
+
+	// Tools of type "function" map onto the legacy functions list
+	functions := append([]provider.Function(nil), req.Functions...)
+	for _, tool := range req.Tools {
+		if tool.Type == "function" {
+			functions = append(functions, tool.Function)
+		}
+	}
+
+	passthrough := req.Passthrough
+	if len(req.ResponseFormat) > 0 {
+		passthrough = make(map[string]json.RawMessage, len(req.Passthrough)+1)
+		for field, value := range req.Passthrough {
+			passthrough[field] = value
 		}
+		passthrough["response_format"] = req.ResponseFormat
 	}
 
 	return &provider.StandardRequest{
-		Model:    req.Model,
-		Messages: messages,
-		Stream:   req.Stream,
+		Model:       req.Model,
+		Messages:    messages,
+		Stream:      req.Stream,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Functions:   functions,
+		Passthrough: passthrough,
 	}
 }
 
@@ -253,24 +639,40 @@ func convertFromStandardResponse(resp *provider.StandardResponse) OpenAIChatComp
 			finishReason = *choice.FinishReason
 		}
 
-		content := ""
+		message := OpenAIChatMessage{Role: "assistant"}
 		if choice.Message != nil {
-			content = choice.Message.Content
+			message.Content = choice.Message.Content
+			message.ReasoningContent = choice.Message.ReasoningContent
+			message.FunctionCall = choice.Message.FunctionCall
 		}
 
 		choices[i] = OpenAIChatChoice{
-			Index: choice.Index,
-			Message: OpenAIChatMessage{
-				Role:    "assistant",
-				Content: content,
-			},
+			Index:        choice.Index,
+			Message:      message,
 			FinishReason: finishReason,
 		}
 	}
 
+	// Citations some providers attach to the message would otherwise be lost
+	// with the rest of the message metadata
+	metadata := resp.Metadata
+	if len(resp.Choices) > 0 && resp.Choices[0].Message != nil {
+		if citations, ok := resp.Choices[0].Message.Metadata[provider.MetadataCitations]; ok && metadata[provider.MetadataCitations] == nil {
+			metadata = maps.Clone(metadata)
+			if metadata == nil {
+				metadata = map[string]interface{}{}
+			}
+			metadata[provider.MetadataCitations] = citations
+		}
+	}
+
 	return OpenAIChatCompletionResponse{
-		Object:  "chat.completion",
-		Model:   resp.Model,
-		Choices: choices,
+		ID:       resp.ID,
+		Object:   "chat.completion",
+		Created:  resp.Created,
+		Model:    resp.Model,
+		Choices:  choices,
+		Warnings: resp.Warnings,
+		Metadata: metadata,
 	}
 }
@@ -0,0 +1,580 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/tracing"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+// newChatTestServer registers the API routes over in-memory stores
+func newChatTestServer(t *testing.T, cfg *config.Config) (*gin.Engine, *metrics.Registry, *loadshed.Shedder) {
+	t.Helper()
+	return newTracedTestServer(t, cfg, nil)
+}
+
+// newTracedTestServer is newChatTestServer with streams sampled by tracer
+func newTracedTestServer(t *testing.T, cfg *config.Config, tracer *tracing.Tracer) (*gin.Engine, *metrics.Registry, *loadshed.Shedder) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	shedder := loadshed.New(cfg, m)
+	engine := gin.New()
+	engine.Use(assignRequestID())
+	RegisterRoutes(engine, r, cfg, m, admission.NewController(cfg, m), artifacts.NewMemoryStore(), usage.NewMemoryStore(10),
+		keys.NewMemoryStore(), enrich.NewEnricher(cfg), tools.NewRuntime(cfg, m), NewRecentRequests(cfg), respcache.NewCache(), shedder, tracer, nil, nil, nil)
+	return engine, m, shedder
+}
+
+func TestChatRequestPassthrough(t *testing.T) {
+	var in OpenAIChatCompletionRequest
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"n":1,"seed":7,"service_tier":"flex"}`
+	if err := json.Unmarshal([]byte(body), &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Model != "gpt-4o" || len(in.Messages) != 1 || in.N == nil {
+		t.Fatalf("Known fields not decoded: %+v", in)
+	}
+	if len(in.Passthrough) != 2 || string(in.Passthrough["seed"]) != "7" || string(in.Passthrough["service_tier"]) != `"flex"` {
+		t.Errorf("Unexpected passthrough %v", in.Passthrough)
+	}
+	if req := convertToStandardRequest(&in); len(req.Passthrough) != 2 {
+		t.Errorf("Passthrough not carried into the standard request: %v", req.Passthrough)
+	}
+
+	var jsonMode OpenAIChatCompletionRequest
+	_ = json.Unmarshal([]byte(`{"model":"m","response_format":{"type":"json_object"}}`), &jsonMode)
+	if req := convertToStandardRequest(&jsonMode); string(req.Passthrough["response_format"]) != `{"type":"json_object"}` || !provider.NeedsOf(req).Passthrough {
+		t.Errorf("Expected response_format forwarded as passthrough, got %v", req.Passthrough)
+	}
+
+	var known OpenAIChatCompletionRequest
+	if err := json.Unmarshal([]byte(`{"model":"m","stream":true}`), &known); err != nil || known.Passthrough != nil {
+		t.Errorf("Expected no passthrough, got %v, %v", known.Passthrough, err)
+	}
+}
+
+func TestChatFunctionCallRoundTrip(t *testing.T) {
+	var in OpenAIChatCompletionRequest
+	body := `{"model":"m","messages":[
+		{"role":"user","content":"weather?"},
+		{"role":"assistant","content":null,"function_call":{"name":"get_weather","arguments":"{}"}},
+		{"role":"function","name":"get_weather","content":"sunny"}]}`
+	if err := json.Unmarshal([]byte(body), &in); err != nil {
+		t.Fatal(err)
+	}
+	req := convertToStandardRequest(&in)
+	if call := req.Messages[1].FunctionCall; call == nil || call.Name != "get_weather" {
+		t.Errorf("Function call not carried over: %+v", req.Messages[1])
+	}
+	if name := req.Messages[2].Name; name == nil || *name != "get_weather" {
+		t.Errorf("Function name not carried over: %+v", req.Messages[2])
+	}
+
+	finish := "function_call"
+	out := convertFromStandardResponse(provider.CreateStandardResponse("id", "m", []provider.Choice{{
+		Message:      &provider.Message{Role: provider.RoleAssistant, FunctionCall: &provider.FunctionCall{Name: "lookup", Arguments: `{"q":1}`}},
+		FinishReason: &finish,
+	}}, provider.Usage{}))
+	if call := out.Choices[0].Message.FunctionCall; call == nil || call.Arguments != `{"q":1}` || out.Choices[0].FinishReason != finish {
+		t.Errorf("Unexpected response choice %+v", out.Choices[0])
+	}
+}
+
+func TestChatLoadShedding(t *testing.T) {
+	engine, m, shedder := newChatTestServer(t, &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}}}})
+	// The test process always runs more than one goroutine
+	if err := shedder.SetPolicy(loadshed.Policy{MaxGoroutines: 1, RetryAfterSeconds: 7}); err != nil {
+		t.Fatal(err)
+	}
+	shedder.Sample()
+
+	chat := func(stream bool) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"model":"demo-chat","stream":%t,"messages":[{"role":"user","content":"hi"}]}`, stream)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+		return w
+	}
+
+	w := chat(true)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "7" || !strings.Contains(w.Body.String(), `"code":"load_shed_goroutines"`) {
+		t.Errorf("Expected the stream to be shed, got %d %q %s", w.Code, w.Header().Get("Retry-After"), w.Body)
+	}
+	if w := chat(false); w.Code != http.StatusOK {
+		t.Errorf("Non-streaming requests must not be shed, got %d", w.Code)
+	}
+	if n := m.Counter("letllm_load_shed_total", "", "reason").Value(loadshed.ReasonGoroutines); n != 1 {
+		t.Errorf("Expected 1 shed request counted, got %v", n)
+	}
+
+	_ = shedder.SetPolicy(loadshed.Policy{RetryAfterSeconds: 5})
+	if shed := shedder.Check(); shed != nil {
+		t.Errorf("Expected streams admitted once the policy is relaxed, got %+v", shed)
+	}
+}
+
+func TestChatModelLimits(t *testing.T) {
+	cfg := &config.Config{
+		Mock:   []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}}},
+		Models: []config.ModelSpec{{Model: "demo-*", ContextLength: 200, MaxOutputTokens: 64}},
+	}
+	engine, _, _ := newChatTestServer(t, cfg)
+
+	chat := func(content string, maxTokens int) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"model":"demo-chat","max_tokens":%d,"messages":[{"role":"user","content":%q}]}`, maxTokens, content)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+		return w
+	}
+
+	w := chat("hi", 1000)
+	var out OpenAIChatCompletionResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	if w.Code != http.StatusOK || len(out.Warnings) != 1 || out.Warnings[0].Code != provider.WarningTruncated || out.Warnings[0].Param != "max_tokens" {
+		t.Errorf("Expected max_tokens lowered with a warning, got %d %s", w.Code, w.Body)
+	}
+
+	w = chat(strings.Repeat("long ", 200), 10)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"code":"context_length_exceeded"`) {
+		t.Errorf("Expected the prompt to be refused, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestChatRequestLimits(t *testing.T) {
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}}}}
+	cfg.RequestLimits.MaxBodyBytes = 2048
+	cfg.RequestLimits.MaxMessages = 2
+	cfg.RequestLimits.MaxPromptChars = 100
+	gin.SetMode(gin.TestMode)
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	engine := gin.New()
+	RegisterRequestLimits(engine, cfg)
+	RegisterRoutes(engine, r, cfg, m, admission.NewController(cfg, m), artifacts.NewMemoryStore(), usage.NewMemoryStore(10),
+		keys.NewMemoryStore(), enrich.NewEnricher(cfg), tools.NewRuntime(cfg, m), NewRecentRequests(cfg), respcache.NewCache(), loadshed.New(cfg, m), nil, nil, nil, nil)
+
+	for name, tt := range map[string]struct {
+		model    string
+		messages string
+		status   int
+		code     string
+	}{
+		"within limits": {messages: `{"role":"user","content":"hi"}`, status: http.StatusOK},
+		"too many":      {messages: `{"role":"user","content":"a"},{"role":"assistant","content":"b"},{"role":"user","content":"c"}`, status: http.StatusBadRequest, code: provider.LimitCodeMessages},
+		"too long":      {messages: fmt.Sprintf(`{"role":"user","content":%q}`, strings.Repeat("é", 101)), status: http.StatusBadRequest, code: provider.LimitCodePromptChars},
+		"too large":     {messages: fmt.Sprintf(`{"role":"user","content":%q}`, strings.Repeat("a", 4096)), status: http.StatusRequestEntityTooLarge, code: "request_too_large"},
+		// Limits are checked before the request is routed
+		"unroutable": {model: "unknown-model", messages: fmt.Sprintf(`{"role":"user","content":%q}`, strings.Repeat("a", 101)), status: http.StatusBadRequest, code: provider.LimitCodePromptChars},
+	} {
+		if tt.model == "" {
+			tt.model = "demo-chat"
+		}
+		body := `{"model":"` + tt.model + `","messages":[` + tt.messages + `]}`
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), `"code":"`+tt.code+`"`) && tt.code != "" {
+			t.Errorf("%s: expected %d %s, got %d %s", name, tt.status, tt.code, w.Code, w.Body)
+		}
+	}
+}
+
+func TestChatLongContextModel(t *testing.T) {
+	cfg := &config.Config{
+		Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat", "demo-chat-32k"},
+			Responses: []config.MockResponse{{Content: "{{.Model}}"}}}},
+		Models: []config.ModelSpec{
+			{Model: "demo-chat", ContextLength: 200, LongContextModel: "demo-chat-32k"},
+			{Model: "demo-chat-32k", ContextLength: 32768},
+		},
+	}
+	engine, _, _ := newChatTestServer(t, cfg)
+
+	for name, tt := range map[string]struct {
+		content, model string
+		warnings       int
+	}{
+		"fits":      {content: "hi", model: "demo-chat"},
+		"too long":  {content: strings.Repeat("long ", 200), model: "demo-chat-32k", warnings: 1},
+		"too large": {content: strings.Repeat("long ", 40000), model: ""},
+	} {
+		body := fmt.Sprintf(`{"model":"demo-chat","messages":[{"role":"user","content":%q}]}`, tt.content)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+		if tt.model == "" {
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"code":"context_length_exceeded"`) {
+				t.Errorf("%s: expected the prompt refused, got %d %s", name, w.Code, w.Body)
+			}
+			continue
+		}
+		var out OpenAIChatCompletionResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		if w.Code != http.StatusOK || out.Choices[0].Message.Content != tt.model || len(out.Warnings) != tt.warnings {
+			t.Errorf("%s: expected %s with %d warnings, got %d %s", name, tt.model, tt.warnings, w.Code, w.Body)
+		}
+	}
+}
+
+func TestChatHeaderRouting(t *testing.T) {
+	cfg := &config.Config{
+		Mock: []config.MockConfig{
+			{Name: "staging", Responses: []config.MockResponse{{Content: "from staging"}}},
+			{Name: "prod", Responses: []config.MockResponse{{Content: "from prod"}}},
+		},
+		Routes: []config.Route{
+			{Prefix: "demo-", Provider: "staging", Headers: map[string]string{"X-Env": "staging"}},
+			{Prefix: "demo-", Provider: "prod"},
+		},
+	}
+	engine, _, _ := newChatTestServer(t, cfg)
+
+	for env, want := range map[string]string{"staging": "from staging", "": "from prod"} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"demo-chat","messages":[{"role":"user","content":"hi"}]}`))
+		if env != "" {
+			req.Header.Set("X-Env", env)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		var out OpenAIChatCompletionResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &out)
+		if w.Code != http.StatusOK || out.Choices[0].Message.Content != want {
+			t.Errorf("X-Env %q: expected %q, got %d %s", env, want, w.Code, w.Body)
+		}
+	}
+}
+
+func TestChatCanaryMetrics(t *testing.T) {
+	cfg := &config.Config{
+		Mock: []config.MockConfig{{Name: "current"}, {Name: "next"}},
+		Routes: []config.Route{{Prefix: "demo-", Provider: "current",
+			Canary: &config.RouteCanary{Provider: "next", Percent: 100}}},
+	}
+	engine, m, _ := newChatTestServer(t, cfg)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"demo-chat","messages":[{"role":"user","content":"hi"}]}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d: %s", w.Code, w.Body)
+	}
+	requests := m.Counter("letllm_route_arm_requests_total", "", "model", "provider", "arm", "status")
+	if got := requests.Value("demo-chat", "next", provider.ArmCanary, "2xx"); got != 1 {
+		t.Errorf("Expected the request counted on the canary, got %v", got)
+	}
+}
+
+func TestChatMirror(t *testing.T) {
+	cfg := &config.Config{
+		Mock: []config.MockConfig{
+			{Name: "current", Responses: []config.MockResponse{{Content: "the answer is four"}}},
+			{Name: "next", Responses: []config.MockResponse{{Content: "the answer is 4"}}},
+		},
+		Routes: []config.Route{{Prefix: "demo-", Provider: "current",
+			Mirror: &config.RouteMirror{Provider: "next", Percent: 100}}},
+	}
+	engine, m, _ := newChatTestServer(t, cfg)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"demo-chat","messages":[{"role":"user","content":"2+2?"}]}`)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "the answer is four") {
+		t.Fatalf("Expected the primary answer, got %d: %s", w.Code, w.Body)
+	}
+
+	requests := m.Counter("letllm_mirror_requests_total", "", "provider", "result")
+	similarity := m.Counter("letllm_mirror_similarity_sum", "", "provider")
+	deadline := time.Now().Add(5 * time.Second)
+	for requests.Value("next", "ok") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the request mirrored")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := similarity.Value("next"); got <= 0 || got >= 1 {
+		t.Errorf("Expected a partial similarity, got %v", got)
+	}
+}
+
+func TestChatExperiment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		Mock: []config.MockConfig{
+			{Name: "current", Models: []string{"demo-chat"}, Responses: []config.MockResponse{{Content: "current {{.Model}}"}}},
+			{Name: "next", Responses: []config.MockResponse{{Content: "next {{.Model}}"}}},
+		},
+		Experiments: []config.Experiment{{Name: "demo-v2", Model: "demo-chat", Variants: []config.ExperimentVariant{
+			{Name: "control", Weight: 1},
+			{Name: "candidate", Weight: 1, Model: "demo-v2", Provider: "next"},
+		}}},
+	}
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	usageStore := usage.NewMemoryStore(100)
+	engine := gin.New()
+	RegisterRoutes(engine, r, cfg, m, admission.NewController(cfg, m), artifacts.NewMemoryStore(), usageStore,
+		keys.NewMemoryStore(), enrich.NewEnricher(cfg), tools.NewRuntime(cfg, m), NewRecentRequests(cfg), respcache.NewCache(), loadshed.New(cfg, m), nil, nil, nil, nil)
+
+	chat := func(user string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"demo-chat","user":"`+user+`","messages":[{"role":"user","content":"hi"}]}`)))
+		return w
+	}
+	want := map[string]string{"control": "current demo-chat", "candidate": "next demo-v2"}
+	seen := map[string]bool{}
+	for i := 0; i < 20 && len(seen) < 2; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		w := chat(user)
+		variant := w.Header().Get(variantHeader)
+		if w.Code != http.StatusOK || w.Header().Get(experimentHeader) != "demo-v2" || !strings.Contains(w.Body.String(), want[variant]) {
+			t.Fatalf("Unexpected answer for variant %q: %d %s", variant, w.Code, w.Body)
+		}
+		if again := chat(user); again.Header().Get(variantHeader) != variant {
+			t.Fatalf("Expected %s to keep variant %s", user, variant)
+		}
+		seen[variant] = true
+	}
+	if len(seen) != 2 {
+		t.Fatalf("Expected both variants served, got %v", seen)
+	}
+
+	records, _ := usageStore.Query(time.Time{}, time.Now().Add(time.Hour))
+	for _, rec := range records {
+		if rec.Experiment != "demo-v2" || (rec.Variant == "candidate") != (rec.Provider == "next") {
+			t.Errorf("Unexpected usage record %+v", rec)
+		}
+	}
+
+	// Anonymous requests are not enrolled
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"demo-chat","messages":[{"role":"user","content":"hi"}]}`)))
+	if w.Code != http.StatusOK || w.Header().Get(experimentHeader) != "" {
+		t.Errorf("Expected an anonymous request not enrolled, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestChatRouteModelRewrite(t *testing.T) {
+	cfg := &config.Config{
+		Mock:   []config.MockConfig{{Name: "azure", Responses: []config.MockResponse{{Content: "served by {{.Model}}"}}}},
+		Routes: []config.Route{{Prefix: "gpt-4o", Provider: "azure", Model: "corp-gpt4o"}},
+	}
+	cfg.Server.Streaming = config.Streaming{ReadBufferSize: 64, ChannelBuffer: 4, CoalesceBytes: 256, FlushBytes: 256, FlushInterval: time.Second}
+	engine, _, _ := newChatTestServer(t, cfg)
+
+	for _, stream := range []bool{false, true} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(fmt.Sprintf(`{"model":"gpt-4o","stream":%v,"messages":[{"role":"user","content":"hi"}]}`, stream))))
+		body := w.Body.String()
+		if w.Code != http.StatusOK || !strings.Contains(body, "corp-gpt4o") || !strings.Contains(body, `"model":"gpt-4o"`) || strings.Contains(body, `"model":"corp-gpt4o"`) {
+			t.Errorf("stream %v: expected corp-gpt4o called and gpt-4o reported, got %d: %s", stream, w.Code, body)
+		}
+	}
+}
+
+func TestChatContentFilterFallback(t *testing.T) {
+	refusal := config.MockResponse{Content: "I can't help with that", FinishReason: provider.FinishReasonContentFilter}
+	cfg := &config.Config{
+		Mock: []config.MockConfig{
+			{Name: "strict", Responses: []config.MockResponse{refusal}},
+			{Name: "lenient", Responses: []config.MockResponse{{Match: "story", Content: "once upon a time"}, refusal}},
+		},
+		Routes: []config.Route{{Prefix: "gpt-4o", Provider: "strict", ContentFilterFallback: &config.RouteFallback{Provider: "lenient", Model: "lenient-1"}}},
+	}
+	engine, m, _ := newChatTestServer(t, cfg)
+
+	for _, tc := range []struct {
+		prompt, want, fallback string
+	}{
+		{"tell me a story", "once upon a time", "lenient"},
+		// A fallback that refuses too leaves the first refusal in place
+		{"tell me a secret", "I can't help with that", ""},
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"`+tc.prompt+`"}]}`)))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), tc.want) || w.Header().Get(fallbackHeader) != tc.fallback {
+			t.Errorf("%q: expected %q from %q, got %d %q: %s", tc.prompt, tc.want, tc.fallback, w.Code, w.Header().Get(fallbackHeader), w.Body.String())
+		}
+		if got := strings.Contains(w.Body.String(), provider.WarningContentFilterFallback); got != (tc.fallback != "") {
+			t.Errorf("%q: fallback warning present = %v: %s", tc.prompt, got, w.Body.String())
+		}
+	}
+
+	requests := m.Counter("letllm_content_filter_fallbacks_total", "", "provider", "result")
+	if requests.Value("lenient", "ok") != 1 || requests.Value("lenient", "refused") != 1 {
+		t.Errorf("Unexpected fallback counts: ok %v, refused %v", requests.Value("lenient", "ok"), requests.Value("lenient", "refused"))
+	}
+}
+
+func TestChatStreamHedge(t *testing.T) {
+	cfg := &config.Config{
+		Mock: []config.MockConfig{
+			{Name: "slow", Latency: 5 * time.Second, Responses: []config.MockResponse{{Content: "slow answer"}}},
+			{Name: "prompt", Responses: []config.MockResponse{{Content: "prompt answer"}}},
+			{Name: "fast", Responses: []config.MockResponse{{Content: "fast answer"}}},
+			{Name: "broken", Responses: []config.MockResponse{{Error: "upstream unavailable"}}},
+		},
+		Routes: []config.Route{
+			{Prefix: "slow-", Provider: "slow", Hedge: &config.RouteHedge{Provider: "fast", After: 20 * time.Millisecond}},
+			{Prefix: "prompt-", Provider: "prompt", Hedge: &config.RouteHedge{Provider: "fast", After: time.Minute}},
+			// Failures start the hedge without waiting out its delay
+			{Prefix: "broken-", Provider: "broken", Hedge: &config.RouteHedge{Provider: "fast", After: time.Minute}},
+		},
+	}
+	cfg.Server.Streaming = config.Streaming{ReadBufferSize: 64, ChannelBuffer: 4, CoalesceBytes: 256, FlushBytes: 256, FlushInterval: time.Second}
+	engine, m, _ := newChatTestServer(t, cfg)
+
+	for model, want := range map[string]string{"slow-1": "fast", "prompt-1": "", "broken-1": "fast"} {
+		start := time.Now()
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"`+model+`","stream":true,"messages":[{"role":"user","content":"hi"}]}`)))
+		if w.Code != http.StatusOK || w.Header().Get(hedgeHeader) != want || time.Since(start) > 2*time.Second {
+			t.Errorf("%s: expected stream hedged to %q, got %d %q after %v: %s", model, want, w.Code, w.Header().Get(hedgeHeader), time.Since(start), w.Body.String())
+		}
+		if want != "" && (!strings.Contains(w.Body.String(), `"content":"fast"`) || strings.Contains(w.Body.String(), `"content":"slow"`)) {
+			t.Errorf("%s: expected the hedge's answer only: %s", model, w.Body.String())
+		}
+	}
+
+	streams := m.Counter("letllm_hedged_streams_total", "", "provider", "winner")
+	if streams.Value("fast", "hedge") != 2 {
+		t.Errorf("Expected two streams won by the hedge, got %v", streams.Value("fast", "hedge"))
+	}
+}
+
+func TestChatStreamTracing(t *testing.T) {
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}}}}
+	cfg.Server.Streaming = config.Streaming{ReadBufferSize: 64, ChannelBuffer: 4, CoalesceBytes: 256, FlushBytes: 256, FlushInterval: time.Second}
+	cfg.Tracing.SampleRate = 1
+	traces := tracing.NewMemoryStore(10)
+	engine, _, _ := newTracedTestServer(t, cfg, tracing.NewTracer(cfg, traces))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"demo-chat","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set(requestIDHeader, "trace-me")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Stream failed: %d %s", w.Code, w.Body)
+	}
+
+	tr, err := traces.Get("trace-me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Endpoint != "/v1/chat/completions" || tr.Provider != "demo" || tr.Model != "demo-chat" || tr.Error != "" {
+		t.Errorf("Unexpected trace %+v", tr)
+	}
+	var read int64
+	for _, e := range tr.Reads {
+		read += int64(e.Bytes)
+	}
+	if len(tr.Reads) == 0 || read != tr.Bytes || len(tr.Writes) == 0 || len(tr.Flushes) == 0 || tr.DurationUS < tr.FirstByteUS {
+		t.Errorf("Incomplete timeline %+v", tr)
+	}
+}
+
+func TestChatRequestCost(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"}, Latency: 50 * time.Millisecond, ChunkDelay: 50 * time.Millisecond,
+			Responses: []config.MockResponse{{Content: "Hello there"}}}},
+		Pricing: map[string]config.ModelPrice{"demo-chat": {InputPerMTok: 1_000_000, OutputPerMTok: 2_000_000}},
+	}
+	cfg.Server.Streaming = config.Streaming{ReadBufferSize: 64, ChannelBuffer: 4, CoalesceBytes: 256, FlushBytes: 256, FlushInterval: time.Second}
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	usageStore := usage.NewMemoryStore(10)
+	engine := gin.New()
+	RegisterRoutes(engine, r, cfg, m, admission.NewController(cfg, m), artifacts.NewMemoryStore(), usageStore,
+		keys.NewMemoryStore(), enrich.NewEnricher(cfg), tools.NewRuntime(cfg, m), NewRecentRequests(cfg), respcache.NewCache(), loadshed.New(cfg, m), nil, nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"demo-chat","messages":[{"role":"user","content":"hi"}]}`)))
+	var out OpenAIChatCompletionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Chat failed: %d %s", w.Code, w.Body)
+	}
+	header, err := strconv.ParseFloat(w.Header().Get(costHeader), 64)
+	if err != nil || header <= 0 || out.Metadata[provider.MetadataCostUSD] != header {
+		t.Fatalf("Expected the cost in the header and metadata, got %q and %v", w.Header().Get(costHeader), out.Metadata)
+	}
+
+	// Streams have no header to carry the cost, but it is recorded
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"demo-chat","stream":true,"messages":[{"role":"user","content":"hi"}]}`)))
+	if w.Code != http.StatusOK || w.Header().Get(costHeader) != "" {
+		t.Fatalf("Unexpected stream: %d %v", w.Code, w.Header())
+	}
+	records, _ := usageStore.Query(time.Time{}, time.Now().Add(time.Hour))
+	if len(records) != 2 || records[0].CostUSD == nil || *records[0].CostUSD != header || records[1].CostUSD == nil || *records[1].CostUSD <= 0 {
+		t.Fatalf("Expected both requests' cost recorded, got %+v", records)
+	}
+	// Streams are timed to their first chunk as well
+	if records[0].TTFTMS != 0 || records[1].TTFTMS < 50 || records[1].TTFTMS >= records[1].LatencyMS {
+		t.Errorf("Expected the stream's time to first token recorded, got %d of %dms", records[1].TTFTMS, records[1].LatencyMS)
+	}
+}
+
+func TestChatCitationMetadata(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"response_id":"r-1","text":"Paris is the capital.","finish_reason":"COMPLETE",
+			"citations":[{"start":0,"end":5,"text":"Paris","document_ids":["doc_0"]}]}`)
+	}))
+	defer upstream.Close()
+
+	cfg := &config.Config{Routes: []config.Route{{Prefix: "command-r", Provider: "cohere"}}}
+	cfg.Cohere.APIKey = "test-key"
+	cfg.Cohere.BaseURL = upstream.URL
+	engine, _, _ := newChatTestServer(t, cfg)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"command-r","messages":[{"role":"user","content":"Capital of France?"}]}`)))
+	var out struct {
+		Metadata struct {
+			Citations []provider.Citation `json:"citations"`
+		} `json:"metadata"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	if w.Code != http.StatusOK || len(out.Metadata.Citations) != 1 || out.Metadata.Citations[0].Text != "Paris" {
+		t.Errorf("Expected the message citations in the response metadata, got %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/sessions"
+)
+
+// SessionCreateRequest is the body of POST /v1/sessions; every field is
+// optional
+type SessionCreateRequest struct {
+	// Model of chat requests on the session that name none
+	Model string `json:"model"`
+	// History to start from, e.g. a system prompt
+	Messages []OpenAIChatMessage `json:"messages"`
+	Metadata map[string]string   `json:"metadata"`
+}
+
+// SessionResponse is a session with its history
+type SessionResponse struct {
+	*sessions.Session
+	Messages []OpenAIChatMessage `json:"messages"`
+}
+
+// RegisterSessionRoutes wires conversation sessions. Chat completions that
+// name a session_id get the session's history in front of their messages,
+// and their messages and the reply are added to it.
+func RegisterSessionRoutes(engine *gin.Engine, store sessions.Store, keyStore keys.Store) {
+	engine.POST("/v1/sessions", func(c *gin.Context) {
+		var in SessionCreateRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&in); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+				return
+			}
+		}
+		sess := sessions.New(callerKeyID(c, keyStore), in.Model, in.Metadata)
+		messages := convertToStandardRequest(&OpenAIChatCompletionRequest{Messages: in.Messages}).Messages
+		if err := store.Create(sess, messages); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, SessionResponse{Session: sess, Messages: in.Messages})
+	})
+
+	engine.GET("/v1/sessions/:id", func(c *gin.Context) {
+		sess, history, ok := openSession(c, store, keyStore, c.Param("id"))
+		if !ok {
+			return
+		}
+		out := SessionResponse{Session: sess, Messages: make([]OpenAIChatMessage, len(history))}
+		for i, msg := range history {
+			out.Messages[i] = OpenAIChatMessage{Role: msg.Role, Content: msg.Content, FunctionCall: msg.FunctionCall}
+			if msg.Name != nil {
+				out.Messages[i].Name = *msg.Name
+			}
+		}
+		c.JSON(http.StatusOK, out)
+	})
+
+	engine.DELETE("/v1/sessions/:id", func(c *gin.Context) {
+		sess, _, ok := openSession(c, store, keyStore, c.Param("id"))
+		if !ok {
+			return
+		}
+		if err := store.Delete(sess.ID); err != nil {
+			c.AbortWithStatusJSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": sess.ID, "object": "session", "deleted": true})
+	})
+}
+
+// openSession returns the caller's session and its history, or writes a 404
+func openSession(c *gin.Context, store sessions.Store, keyStore keys.Store, id string) (*sessions.Session, []provider.Message, bool) {
+	sess, err := store.Get(id)
+	if err == nil && sess.KeyID != callerKeyID(c, keyStore) {
+		err = sessions.ErrNotFound
+	}
+	var history []provider.Message
+	if err == nil {
+		history, err = store.Messages(id)
+	}
+	if err != nil {
+		body := gin.H{"error": err.Error()}
+		if errors.Is(err, sessions.ErrNotFound) {
+			body["code"] = "session_not_found"
+		}
+		c.AbortWithStatusJSON(sessionErrorStatus(err), body)
+		return nil, nil, false
+	}
+	return sess, history, true
+}
+
+// recordTurn adds a chat request's own messages and the reply to its
+// session. The reply has been sent by then, so failures are only logged.
+// Reasoning is not kept, as providers reject it in later requests.
+func recordTurn(store sessions.Store, id string, turn []provider.Message, reply provider.Message) {
+	reply = provider.Message{Role: "assistant", Content: reply.Content, FunctionCall: reply.FunctionCall}
+	if err := store.Append(id, append(turn, reply)); err != nil {
+		log.Printf("record turn of session %s: %v", id, err)
+	}
+}
+
+func sessionErrorStatus(err error) int {
+	if errors.Is(err, sessions.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
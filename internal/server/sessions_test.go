@@ -0,0 +1,107 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
+	"github.com/luguanyu1234/letllm-go/internal/sessions"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+func TestSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"},
+		Responses: []config.MockResponse{{Content: "turn {{.Turn}} after {{.FirstPrompt}}: {{.Prompt}}"}}}}}
+	cfg.Server.Streaming = config.Streaming{ReadBufferSize: 64, ChannelBuffer: 4, CoalesceBytes: 256, FlushBytes: 256, FlushInterval: time.Second}
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	keyStore := keys.NewMemoryStore()
+	_ = keyStore.Put(&keys.Key{ID: "team-a", Hash: keys.HashSecret("sk-a")})
+	_ = keyStore.Put(&keys.Key{ID: "team-b", Hash: keys.HashSecret("sk-b")})
+	store := sessions.NewMemoryStore()
+	engine := gin.New()
+	RegisterRoutes(engine, r, cfg, m, admission.NewController(cfg, m), artifacts.NewMemoryStore(), usage.NewMemoryStore(10),
+		keyStore, enrich.NewEnricher(cfg), tools.NewRuntime(cfg, m), NewRecentRequests(cfg), respcache.NewCache(), loadshed.New(cfg, m), nil, store, nil, nil)
+	RegisterSessionRoutes(engine, store, keyStore)
+
+	call := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := call(http.MethodPost, "/v1/sessions", "sk-a", `{"model":"demo-chat","messages":[{"role":"system","content":"Be brief."}]}`)
+	var sess SessionResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &sess)
+	if w.Code != http.StatusCreated || !strings.HasPrefix(sess.ID, "sess_") || sess.Model != "demo-chat" {
+		t.Fatalf("Expected the session created, got %d: %s", w.Code, w.Body)
+	}
+
+	// The model comes from the session, and the second turn sees the first
+	w = call(http.MethodPost, "/v1/chat/completions", "sk-a", `{"session_id":"`+sess.ID+`","messages":[{"role":"user","content":"hello"}]}`)
+	var out OpenAIChatCompletionResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	if w.Code != http.StatusOK || out.Choices[0].Message.Content != "turn 1 after hello: hello" {
+		t.Fatalf("Unexpected first turn %d: %s", w.Code, w.Body)
+	}
+	w = call(http.MethodPost, "/v1/chat/completions", "sk-a", `{"session_id":"`+sess.ID+`","stream":true,"messages":[{"role":"user","content":"again"}]}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "[DONE]") {
+		t.Fatalf("Unexpected second turn %d: %s", w.Code, w.Body)
+	}
+
+	w = call(http.MethodGet, "/v1/sessions/"+sess.ID, "sk-a", "")
+	sess = SessionResponse{}
+	_ = json.Unmarshal(w.Body.Bytes(), &sess)
+	want := []OpenAIChatMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "hello"},
+		{Role: "assistant", Content: "turn 1 after hello: hello"},
+		{Role: "user", Content: "again"},
+		{Role: "assistant", Content: "turn 2 after hello: again"},
+	}
+	if len(sess.Messages) != len(want) {
+		t.Fatalf("Expected %d messages, got %s", len(want), w.Body)
+	}
+	for i := range want {
+		if sess.Messages[i] != want[i] {
+			t.Errorf("Message %d: expected %+v, got %+v", i, want[i], sess.Messages[i])
+		}
+	}
+
+	// Sessions are private to the key that created them
+	for name, w := range map[string]*httptest.ResponseRecorder{
+		"get":  call(http.MethodGet, "/v1/sessions/"+sess.ID, "sk-b", ""),
+		"chat": call(http.MethodPost, "/v1/chat/completions", "sk-b", `{"session_id":"`+sess.ID+`","messages":[{"role":"user","content":"hi"}]}`),
+	} {
+		if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "session_not_found") {
+			t.Errorf("%s: expected another key's session hidden, got %d: %s", name, w.Code, w.Body)
+		}
+	}
+
+	if w := call(http.MethodDelete, "/v1/sessions/"+sess.ID, "sk-a", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected the session deleted, got %d: %s", w.Code, w.Body)
+	}
+	if w := call(http.MethodGet, "/v1/sessions/"+sess.ID, "sk-a", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected a deleted session gone, got %d", w.Code)
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// speechChunkBytes is how much audio is relayed per flush
+const speechChunkBytes = 32 << 10
+
+// OpenAISpeechRequest is the body of POST /v1/audio/speech
+type OpenAISpeechRequest struct {
+	Model          string   `json:"model"`
+	Input          string   `json:"input"`
+	Voice          string   `json:"voice"`
+	Instructions   string   `json:"instructions,omitempty"`
+	ResponseFormat string   `json:"response_format,omitempty"`
+	Speed          *float64 `json:"speed,omitempty"`
+}
+
+// speechHandler serves POST /v1/audio/speech, relaying the provider's audio
+// to the client as it arrives
+func speechHandler(r *provider.Router, adm *admission.Controller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in OpenAISpeechRequest
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid request: %v", err)})
+			return
+		}
+		req := &provider.SpeechRequest{
+			Model:        in.Model,
+			Input:        in.Input,
+			Voice:        in.Voice,
+			Instructions: in.Instructions,
+			Format:       in.ResponseFormat,
+			Speed:        in.Speed,
+		}
+		if err := provider.ValidateSpeechRequest(req); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Model = resolveModelAlias(c, r, req.Model)
+		setRequestModel(c, req.Model)
+
+		attrs := requestAttributes(c)
+		routeReq := &provider.RouteRequest{Model: req.Model, Endpoint: c.FullPath(), Headers: routeHeaders(c.Request.Header), Attributes: attrs}
+		p, err := r.Route(routeReq)
+		if err != nil {
+			var policyErr *provider.PolicyError
+			if errors.As(err, &policyErr) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": policyErr.Code})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		setRequestProvider(c, p)
+		setRequestArm(c, routeReq.Arm)
+
+		speaker, ok := p.(provider.SpeechProvider)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("%s: speech synthesis is not supported by this provider", p.GetInfo().Name),
+				"code":  "speech_unsupported",
+			})
+			return
+		}
+
+		release, err := adm.Acquire(c.Request.Context(), p.GetInfo().Name, req.Model, attrs)
+		if err != nil {
+			var rejected *admission.RejectedError
+			if errors.As(err, &rejected) {
+				c.Header("Retry-After", strconv.Itoa(rejected.RetryAfterSeconds()))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": "admission_" + rejected.Reason})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		defer release()
+
+		req.Model = upstreamModel(routeReq)
+		resp, err := speaker.Speak(c.Request.Context(), req)
+		r.ReportOutcome(p.GetInfo().Name, err)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer resp.Audio.Close()
+
+		c.Header("Content-Type", resp.ContentType)
+		c.Status(http.StatusOK)
+		buf := make([]byte, speechChunkBytes)
+		for {
+			n, err := resp.Audio.Read(buf)
+			if n > 0 {
+				if _, werr := c.Writer.Write(buf[:n]); werr != nil {
+					return
+				}
+				c.Writer.Flush()
+			}
+			if err != nil {
+				// The status is already sent; a truncated body is all the
+				// client can be told
+				if !errors.Is(err, io.EOF) {
+					log.Printf("speech stream from %s: %v", p.GetInfo().Name, err)
+				}
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+func TestSpeechEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-tts"}}}}
+	cfg.Cohere.APIKey = "test-key"
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	engine.POST("/v1/audio/speech", speechHandler(r, admission.NewController(cfg, metrics.NewRegistry())))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/audio/speech", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// Two words of 100ms at 8kHz, 16-bit, behind a 44 byte header
+	w := post(`{"model":"demo-tts","input":"Hello world","voice":"alloy","response_format":"wav"}`)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "audio/wav" {
+		t.Fatalf("Unexpected response %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if body := w.Body.Bytes(); len(body) != 44+2*800*2 || string(body[:4]) != "RIFF" {
+		t.Errorf("Unexpected wav of %d bytes", len(body))
+	}
+	w = post(`{"model":"demo-tts","input":"Hello","voice":"alloy","response_format":"pcm"}`)
+	if w.Code != http.StatusOK || w.Body.Len() != 2*800 {
+		t.Errorf("Unexpected pcm response %d of %d bytes", w.Code, w.Body.Len())
+	}
+
+	for name, tc := range map[string]struct {
+		body string
+		code int
+	}{
+		"no voice":       {`{"model":"demo-tts","input":"Hello"}`, http.StatusBadRequest},
+		"bad format":     {`{"model":"demo-tts","input":"Hello","voice":"alloy","response_format":"ogg"}`, http.StatusBadRequest},
+		"invalid json":   {`{"model":`, http.StatusBadRequest},
+		"unrouted model": {`{"model":"nope","input":"Hello","voice":"alloy"}`, http.StatusBadRequest},
+	} {
+		if w := post(tc.body); w.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d: %s", name, tc.code, w.Code, w.Body)
+		}
+	}
+	w = post(`{"model":"command-r","input":"Hello","voice":"alloy"}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"code":"speech_unsupported"`) {
+		t.Errorf("Expected speech_unsupported, got %d %s", w.Code, w.Body)
+	}
+}
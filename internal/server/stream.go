@@ -0,0 +1,365 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"golang.org/x/sync/errgroup"
+)
+
+// errSlowClient ends streams whose client stayed behind the upstream for
+// longer than the slow client timeout
+var errSlowClient = errors.New("client is reading the stream too slowly")
+
+// streamRelay copies provider streams to clients with the configured
+// buffering. Read buffers are pooled across requests.
+type streamRelay struct {
+	cfg  config.Streaming
+	bufs sync.Pool
+}
+
+func newStreamRelay(cfg config.Streaming) *streamRelay {
+	s := &streamRelay{cfg: cfg}
+	s.bufs.New = func() interface{} {
+		b := make([]byte, cfg.ReadBufferSize)
+		return &b
+	}
+	return s
+}
+
+// pump reads rc on its own goroutine and hands the data to send on the
+// caller's goroutine, calling flush once the queue drains or the flush
+// thresholds are reached. It returns nil once rc is exhausted, or the first
+// send, read or ctx error, or errSlowClient; either way rc is closed and the
+// reader has exited.
+func (s *streamRelay) pump(ctx context.Context, rc io.ReadCloser, send func([]byte) error, flush func()) error {
+	pumpCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	g, gctx := errgroup.WithContext(pumpCtx)
+	chunks := make(chan *[]byte, s.cfg.ChannelBuffer)
+
+	g.Go(func() error {
+		defer close(chunks)
+		for {
+			bp := s.bufs.Get().(*[]byte)
+			n, err := rc.Read(*bp)
+			if n > 0 {
+				*bp = (*bp)[:n]
+				select {
+				case chunks <- bp:
+				case <-gctx.Done():
+					return gctx.Err()
+				}
+			} else {
+				s.release(bp)
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	})
+
+	var sendErr error
+	err := func() error {
+		// Stopping early cancels the reader and closes rc to unblock a pending Read
+		defer rc.Close()
+		defer cancel()
+
+		var (
+			merged    []byte
+			unflushed int
+			lastFlush = time.Now()
+			// Set while reads stay queued after each send
+			behindSince time.Time
+		)
+		for {
+			select {
+			case <-gctx.Done():
+				return gctx.Err()
+			case bp, ok := <-chunks:
+				if !ok {
+					if unflushed > 0 {
+						flush()
+					}
+					return nil
+				}
+
+				data := *bp
+				if !s.cfg.DisableCoalescing && len(chunks) > 0 {
+					// The client is behind; catch up with fewer, larger writes
+					merged = append(merged[:0], data...)
+					s.release(bp)
+					for len(merged) < s.cfg.CoalesceBytes && len(chunks) > 0 {
+						next := <-chunks
+						merged = append(merged, *next...)
+						s.release(next)
+					}
+					data, bp = merged, nil
+				}
+
+				sendErr = send(data)
+				if bp != nil {
+					s.release(bp)
+				}
+				if sendErr != nil {
+					return sendErr
+				}
+
+				unflushed += len(data)
+				if len(chunks) == 0 || unflushed >= s.cfg.FlushBytes || time.Since(lastFlush) >= s.cfg.FlushInterval {
+					flush()
+					unflushed, lastFlush = 0, time.Now()
+				}
+
+				// A client that never catches up would hold the upstream
+				// stream open for as long as it takes to drain it
+				switch {
+				case len(chunks) == 0:
+					behindSince = time.Time{}
+				case s.cfg.SlowClientTimeout <= 0:
+				case behindSince.IsZero():
+					behindSince = time.Now()
+				case time.Since(behindSince) >= s.cfg.SlowClientTimeout:
+					sendErr = errSlowClient
+					return sendErr
+				}
+			}
+		}
+	}()
+
+	// Closing rc makes the reader fail too; report why the relay stopped
+	readErr := g.Wait()
+	switch {
+	case sendErr != nil:
+		return sendErr
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case readErr != nil:
+		return readErr
+	}
+	return err
+}
+
+func (s *streamRelay) release(bp *[]byte) {
+	*bp = (*bp)[:cap(*bp)]
+	s.bufs.Put(bp)
+}
+
+// writeDeadline bounds each write to a client by the configured write
+// timeout. Writers without deadline support, such as test recorders, are
+// written to without one.
+type writeDeadline struct {
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (s *streamRelay) writeDeadline(w http.ResponseWriter) writeDeadline {
+	return writeDeadline{rc: http.NewResponseController(w), timeout: s.cfg.WriteTimeout}
+}
+
+// extend gives the next write the full timeout
+func (d writeDeadline) extend() {
+	if d.timeout > 0 {
+		_ = d.rc.SetWriteDeadline(time.Now().Add(d.timeout))
+	}
+}
+
+// clear removes the deadline so it does not outlive the response
+func (d writeDeadline) clear() {
+	if d.timeout > 0 {
+		_ = d.rc.SetWriteDeadline(time.Time{})
+	}
+}
+
+// chunkEncoder turns the provider's newline-delimited StreamChunks into
+// OpenAI chat.completion.chunk events. Every event of a response carries the
+// ID and created time of the first chunk, as OpenAI's do, whatever the
+// provider put on later chunks. Blank lines, stray [DONE] markers and empty
+// chunks after the finish are dropped so the client sees one terminator.
+type chunkEncoder struct {
+	w     io.Writer
+	enc   *json.Encoder
+	model string
+
+	id      string
+	created int64
+	done    bool
+	usage   *provider.Usage
+	partial []byte
+	// reply is the first choice's message assembled from its deltas
+	reply provider.Message
+}
+
+func newChunkEncoder(w io.Writer, model string) *chunkEncoder {
+	return &chunkEncoder{w: w, enc: json.NewEncoder(w), model: model}
+}
+
+// Write encodes every complete line in b, keeping a trailing partial line
+// for the next call
+func (e *chunkEncoder) Write(b []byte) error {
+	e.partial = append(e.partial, b...)
+	for {
+		i := bytes.IndexByte(e.partial, '\n')
+		if i < 0 {
+			return nil
+		}
+		line := e.partial[:i]
+		if err := e.encodeLine(line); err != nil {
+			return err
+		}
+		e.partial = e.partial[i+1:]
+	}
+}
+
+// Close encodes any unterminated last line, then the warnings and the [DONE]
+// terminator
+func (e *chunkEncoder) Close(warnings []provider.Warning) error {
+	if err := e.encodeLine(e.partial); err != nil {
+		return err
+	}
+	e.partial = nil
+	if len(warnings) > 0 {
+		// Surfaced in a final choice-less chunk
+		if err := e.event(OpenAIChatCompletionChunk{Choices: []OpenAIChatChunkChoice{}, Warnings: warnings}); err != nil {
+			return err
+		}
+	}
+	_, err := e.w.Write([]byte("data: [DONE]\n\n"))
+	return err
+}
+
+// Usage returns the token usage reported by the stream, if any
+func (e *chunkEncoder) Usage() *provider.Usage {
+	return e.usage
+}
+
+// Reply returns the first choice's message as streamed so far
+func (e *chunkEncoder) Reply() provider.Message {
+	reply := e.reply
+	reply.Role = "assistant"
+	return reply
+}
+
+func (e *chunkEncoder) encodeLine(line []byte) error {
+	line = bytes.TrimSpace(line)
+	line = bytes.TrimSpace(bytes.TrimPrefix(line, []byte("data:")))
+	if len(line) == 0 || string(line) == "[DONE]" {
+		return nil
+	}
+
+	var chunk provider.StreamChunk
+	if err := json.Unmarshal(line, &chunk); err != nil {
+		return e.fail(fmt.Errorf("invalid stream chunk from provider: %w", err))
+	}
+	if chunk.Error != nil {
+		if chunk.Error.Code == "tool_budget_exceeded" {
+			// As the non-streaming answer, with the loop's details
+			return e.send(errors.New(chunk.Error.Message), gin.H{"error": chunk.Error.Message, "code": chunk.Error.Code, "budget": chunk.Error.Details})
+		}
+		return e.fail(errors.New(chunk.Error.Message))
+	}
+	if chunk.ToolProgress != nil {
+		_, _ = e.w.Write([]byte("event: " + toolProgressEvent + "\ndata: "))
+		_, _ = e.w.Write(chunk.ToolProgress)
+		_, err := e.w.Write([]byte("\n\n"))
+		return err
+	}
+	if e.id == "" {
+		e.id, e.created = chunk.ID, chunk.Created
+		if e.id == "" {
+			e.id = provider.NewResponseID()
+		}
+		if e.created == 0 {
+			e.created = time.Now().Unix()
+		}
+	}
+	if chunk.Usage != nil {
+		e.usage = chunk.Usage
+	}
+
+	choices := make([]OpenAIChatChunkChoice, 0, len(chunk.Choices))
+	for _, choice := range chunk.Choices {
+		delta := OpenAIChatMessage{Role: "assistant"}
+		if choice.Delta != nil {
+			delta.Content = choice.Delta.Content
+			delta.ReasoningContent = choice.Delta.ReasoningContent
+			delta.FunctionCall = choice.Delta.FunctionCall
+			if choice.Index == 0 {
+				e.addToReply(choice.Delta)
+			}
+		}
+		choices = append(choices, OpenAIChatChunkChoice{Index: choice.Index, Delta: delta, FinishReason: choice.FinishReason})
+	}
+	if e.done && len(choices) == 0 && chunk.Usage == nil {
+		return nil
+	}
+	e.done = e.done || chunk.Done
+
+	return e.event(OpenAIChatCompletionChunk{Choices: choices, Usage: chunk.Usage})
+}
+
+// addToReply appends a delta of the first choice to the assembled reply
+func (e *chunkEncoder) addToReply(delta *provider.Message) {
+	e.reply.Content += delta.Content
+	e.reply.ReasoningContent += delta.ReasoningContent
+	if fc := delta.FunctionCall; fc != nil {
+		if e.reply.FunctionCall == nil {
+			e.reply.FunctionCall = &provider.FunctionCall{}
+		}
+		// The name comes whole, in the first delta or repeated in each
+		if e.reply.FunctionCall.Name == "" {
+			e.reply.FunctionCall.Name = fc.Name
+		}
+		e.reply.FunctionCall.Arguments += fc.Arguments
+	}
+}
+
+// event writes one chunk stamped with the response's ID and created time
+func (e *chunkEncoder) event(chunk OpenAIChatCompletionChunk) error {
+	if e.id == "" {
+		e.id, e.created = provider.NewResponseID(), time.Now().Unix()
+	}
+	chunk.ID, chunk.Object, chunk.Created, chunk.Model = e.id, provider.ObjectChatCompletionChunk, e.created, e.model
+	_, _ = e.w.Write([]byte("data: "))
+	if err := e.enc.Encode(chunk); err != nil {
+		return err
+	}
+	_, err := e.w.Write([]byte("\n"))
+	return err
+}
+
+// fail tells the client the stream broke, as there is no status code left
+// to do so, and returns err to stop the relay
+func (e *chunkEncoder) fail(err error) error {
+	return e.abort(err, "")
+}
+
+// abort is fail with an error code for the client
+func (e *chunkEncoder) abort(err error, code string) error {
+	body := gin.H{"error": err.Error()}
+	if code != "" {
+		body["code"] = code
+	}
+	return e.send(err, body)
+}
+
+// send writes an error body and returns err
+func (e *chunkEncoder) send(err error, body gin.H) error {
+	_, _ = e.w.Write([]byte("data: "))
+	_ = e.enc.Encode(body)
+	_, _ = e.w.Write([]byte("\n"))
+	return err
+}
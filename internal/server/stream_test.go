@@ -0,0 +1,267 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// closeRecorder is a stream that records whether it was closed
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestStreamRelayPump(t *testing.T) {
+	text := strings.Repeat("0123456789", 100)
+	for name, cfg := range map[string]config.Streaming{
+		"one write per read": {ReadBufferSize: 7, ChannelBuffer: 2, CoalesceBytes: 64, FlushBytes: 32, FlushInterval: time.Second, DisableCoalescing: true},
+		"coalesced":          {ReadBufferSize: 7, ChannelBuffer: 16, CoalesceBytes: 64, FlushBytes: 32, FlushInterval: time.Second},
+		"unbuffered":         {ReadBufferSize: 1024, ChannelBuffer: 0, CoalesceBytes: 64, FlushBytes: 1, FlushInterval: time.Second},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rc := &closeRecorder{Reader: strings.NewReader(text)}
+			var (
+				got            strings.Builder
+				writes, unsent int
+				flushes        int
+			)
+			err := newStreamRelay(cfg).pump(context.Background(), rc, func(b []byte) error {
+				// Slow client so reads queue up
+				time.Sleep(time.Millisecond)
+				if cfg.DisableCoalescing && len(b) > cfg.ReadBufferSize {
+					t.Errorf("Expected no coalescing, got a %d byte write", len(b))
+				}
+				got.Write(b)
+				writes++
+				unsent += len(b)
+				return nil
+			}, func() {
+				flushes++
+				unsent = 0
+			})
+			if err != nil {
+				t.Fatalf("pump: %v", err)
+			}
+			if got.String() != text {
+				t.Errorf("Stream corrupted: got %d bytes", got.Len())
+			}
+			if unsent != 0 || flushes == 0 {
+				t.Errorf("Expected every write to be flushed, %d bytes unflushed after %d flushes", unsent, flushes)
+			}
+			if !rc.closed {
+				t.Error("Expected the stream to be closed")
+			}
+		})
+	}
+}
+
+func TestStreamRelayPumpStopsOnSendError(t *testing.T) {
+	cfg := config.Streaming{ReadBufferSize: 4, ChannelBuffer: 1, CoalesceBytes: 8, FlushBytes: 8, FlushInterval: time.Second}
+	pr, pw := io.Pipe()
+	go func() {
+		// Writes block until the relay reads, so this only ends once pr is closed
+		for {
+			if _, err := pw.Write([]byte("data")); err != nil {
+				return
+			}
+		}
+	}()
+
+	errClient := errors.New("client gone")
+	err := newStreamRelay(cfg).pump(context.Background(), pr, func([]byte) error { return errClient }, func() {})
+	if !errors.Is(err, errClient) {
+		t.Fatalf("Expected the send error, got %v", err)
+	}
+	if _, err := pw.Write([]byte("x")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Expected the upstream to be closed, got %v", err)
+	}
+}
+
+func TestStreamRelayPumpCancelled(t *testing.T) {
+	cfg := config.Streaming{ReadBufferSize: 4, ChannelBuffer: 1, CoalesceBytes: 8, FlushBytes: 8, FlushInterval: time.Second}
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, _ = pw.Write([]byte("data"))
+		cancel()
+	}()
+	err := newStreamRelay(cfg).pump(ctx, pr, func([]byte) error { return nil }, func() {})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestStreamRelayPumpSlowClient(t *testing.T) {
+	cfg := config.Streaming{ReadBufferSize: 4, ChannelBuffer: 2, CoalesceBytes: 8, FlushBytes: 8, FlushInterval: time.Second, SlowClientTimeout: 20 * time.Millisecond}
+	pr, pw := io.Pipe()
+	go func() {
+		for {
+			if _, err := pw.Write([]byte("data")); err != nil {
+				return
+			}
+		}
+	}()
+
+	// The client takes 5ms per write, so the queue stays full
+	start := time.Now()
+	err := newStreamRelay(cfg).pump(context.Background(), pr, func([]byte) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}, func() {})
+	if !errors.Is(err, errSlowClient) {
+		t.Fatalf("Expected errSlowClient, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the stream to end soon after the timeout, took %v", elapsed)
+	}
+	if _, err := pw.Write([]byte("x")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Expected the upstream to be closed, got %v", err)
+	}
+
+	// A client that keeps up is never behind for long
+	rc := &closeRecorder{Reader: strings.NewReader(strings.Repeat("x", 64))}
+	if err := newStreamRelay(cfg).pump(context.Background(), rc, func([]byte) error { return nil }, func() {}); err != nil {
+		t.Errorf("Expected a fast client to finish, got %v", err)
+	}
+}
+
+func TestChunkEncoderAbort(t *testing.T) {
+	var buf strings.Builder
+	_ = newChunkEncoder(&buf, "m").abort(errSlowClient, "slow_client")
+	events := sseEvents(t, buf.String())
+	var body map[string]string
+	if len(events) != 1 || json.Unmarshal([]byte(events[0]), &body) != nil || body["code"] != "slow_client" || body["error"] != errSlowClient.Error() {
+		t.Errorf("Unexpected abort event %q", buf.String())
+	}
+}
+
+// sseEvents splits an SSE body into its data payloads
+func sseEvents(t *testing.T, body string) []string {
+	t.Helper()
+	var events []string
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		data, ok := strings.CutPrefix(block, "data: ")
+		if !ok {
+			t.Fatalf("Malformed SSE event %q", block)
+		}
+		events = append(events, strings.TrimSpace(data))
+	}
+	return events
+}
+
+func TestChunkEncoderConformance(t *testing.T) {
+	for name, tc := range map[string]struct {
+		lines     []string
+		wantID    string
+		wantTexts []string
+	}{
+		"ids vary per chunk": {
+			lines: []string{
+				`{"id":"chatcmpl-a","created":100,"choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`,
+				`{"id":"chatcmpl-b","created":101,"choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}],"done":true}`,
+			},
+			wantID:    "chatcmpl-a",
+			wantTexts: []string{"Hel", "lo"},
+		},
+		"no id and trailing empty chunk": {
+			lines: []string{
+				`{"choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}],"done":true}`,
+				`{"choices":[],"done":true}`,
+			},
+			wantTexts: []string{"Hi"},
+		},
+		"duplicate done markers and blank lines": {
+			lines: []string{
+				``,
+				`{"id":"chatcmpl-c","created":5,"choices":[{"index":0,"delta":{"content":"ok"},"finish_reason":"stop"}],"done":true}`,
+				`[DONE]`,
+				`data: [DONE]`,
+			},
+			wantID:    "chatcmpl-c",
+			wantTexts: []string{"ok"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var out strings.Builder
+			enc := newChunkEncoder(&out, "test-model")
+			// Split writes mid-line as reads from the provider would
+			stream := strings.Join(tc.lines, "\n")
+			for len(stream) > 0 {
+				n := min(5, len(stream))
+				if err := enc.Write([]byte(stream[:n])); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+				stream = stream[n:]
+			}
+			if err := enc.Close([]provider.Warning{{Code: "test", Message: "note"}}); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			events := sseEvents(t, out.String())
+			if events[len(events)-1] != "[DONE]" {
+				t.Fatalf("Expected [DONE] last, got %q", events[len(events)-1])
+			}
+			chunks := events[:len(events)-1]
+			for _, ev := range chunks {
+				if ev == "[DONE]" {
+					t.Fatal("Expected a single [DONE]")
+				}
+			}
+
+			var (
+				id      string
+				created int64
+				texts   []string
+			)
+			for i, ev := range chunks {
+				var chunk OpenAIChatCompletionChunk
+				if err := json.Unmarshal([]byte(ev), &chunk); err != nil {
+					t.Fatalf("Invalid chunk %q: %v", ev, err)
+				}
+				if i == 0 {
+					id, created = chunk.ID, chunk.Created
+				}
+				if chunk.ID != id || chunk.Created != created || chunk.Object != "chat.completion.chunk" || chunk.Model != "test-model" {
+					t.Errorf("Chunk %d not stamped like the first: %+v", i, chunk)
+				}
+				for _, choice := range chunk.Choices {
+					texts = append(texts, choice.Delta.Content)
+				}
+			}
+			if !strings.HasPrefix(id, "chatcmpl-") || created == 0 || (tc.wantID != "" && id != tc.wantID) {
+				t.Errorf("Unexpected response ID %q created %d", id, created)
+			}
+			if strings.Join(texts, "|") != strings.Join(tc.wantTexts, "|") {
+				t.Errorf("Expected deltas %q, got %q", tc.wantTexts, texts)
+			}
+			// Content chunks plus the warnings chunk
+			if len(chunks) != len(tc.wantTexts)+1 {
+				t.Errorf("Expected %d chunks, got %d", len(tc.wantTexts)+1, len(chunks))
+			}
+		})
+	}
+}
+
+func TestChunkEncoderProviderError(t *testing.T) {
+	var out strings.Builder
+	enc := newChunkEncoder(&out, "m")
+	err := enc.Write([]byte(`{"error":{"type":"server_error","message":"overloaded"}}` + "\n"))
+	if err == nil || !strings.Contains(out.String(), `"error":"overloaded"`) {
+		t.Errorf("Expected the error to be relayed, got %v and %q", err, out.String())
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/prompts"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// TemplateRenderRequest is the body of POST /v1/templates/:name/render
+type TemplateRenderRequest struct {
+	Variables map[string]string `json:"variables"`
+}
+
+// TemplateRenderResponse is a prompt template expanded into messages
+type TemplateRenderResponse struct {
+	Template string              `json:"template"`
+	Model    string              `json:"model,omitempty"`
+	Messages []OpenAIChatMessage `json:"messages"`
+}
+
+// RegisterPromptTemplateRoutes wires the prompt template listing and a
+// preview of what a template expands to
+func RegisterPromptTemplateRoutes(engine *gin.Engine, registry *prompts.Registry) {
+	engine.GET("/v1/templates", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"object": "list", "data": registry.List()})
+	})
+
+	engine.POST("/v1/templates/:name/render", func(c *gin.Context) {
+		var in TemplateRenderRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&in); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid json: %v", err)})
+				return
+			}
+		}
+		t, messages, ok := renderTemplate(c, registry, c.Param("name"), in.Variables)
+		if !ok {
+			return
+		}
+		out := TemplateRenderResponse{Template: t.Name, Model: t.Model, Messages: make([]OpenAIChatMessage, len(messages))}
+		for i, msg := range messages {
+			out.Messages[i] = OpenAIChatMessage{Role: msg.Role, Content: msg.Content}
+		}
+		c.JSON(http.StatusOK, out)
+	})
+}
+
+// renderTemplate expands a prompt template, or writes a 404 for unknown
+// templates and a 400 for bad variables
+func renderTemplate(c *gin.Context, registry *prompts.Registry, name string, vars map[string]string) (*prompts.Template, []provider.Message, bool) {
+	t, messages, err := registry.Render(name, vars)
+	if err != nil {
+		var verr *prompts.VariableError
+		switch {
+		case errors.Is(err, prompts.ErrNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error(), "code": "template_not_found"})
+		case errors.As(err, &verr):
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "invalid_template_variables"})
+		default:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return nil, nil, false
+	}
+	return t, messages, true
+}
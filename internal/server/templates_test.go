@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/artifacts"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/enrich"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/loadshed"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/prompts"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/respcache"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+func TestPromptTemplates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-chat"},
+		Responses: []config.MockResponse{{Content: "answering {{.FirstPrompt}} then {{.Prompt}}"}}}}}
+	cfg.Server.Streaming = config.Streaming{ReadBufferSize: 64, ChannelBuffer: 4, CoalesceBytes: 256, FlushBytes: 256, FlushInterval: time.Second}
+	cfg.PromptTemplates = []config.PromptTemplate{{
+		Name:      "support_agent",
+		Model:     "demo-chat",
+		Variables: []config.TemplateVariable{{Name: "customer"}},
+		Messages:  []config.TemplateMessage{{Role: "user", Content: "I am {{.customer}}."}},
+	}}
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := prompts.NewRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := metrics.NewRegistry()
+	engine := gin.New()
+	RegisterRoutes(engine, r, cfg, m, admission.NewController(cfg, m), artifacts.NewMemoryStore(), usage.NewMemoryStore(10),
+		keys.NewMemoryStore(), enrich.NewEnricher(cfg), tools.NewRuntime(cfg, m), NewRecentRequests(cfg), respcache.NewCache(), loadshed.New(cfg, m), nil, nil, registry, nil)
+	RegisterPromptTemplateRoutes(engine, registry)
+
+	call := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := call("/v1/templates/support_agent/render", `{"variables":{"customer":"Acme"}}`)
+	var rendered TemplateRenderResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &rendered)
+	if w.Code != http.StatusOK || rendered.Model != "demo-chat" || len(rendered.Messages) != 1 || rendered.Messages[0].Content != "I am Acme." {
+		t.Fatalf("Unexpected render %d: %s", w.Code, w.Body)
+	}
+
+	// The template's messages go ahead of the caller's, on its model
+	w = call("/v1/chat/completions", `{"template":"support_agent","variables":{"customer":"Acme"},"messages":[{"role":"user","content":"hello"}]}`)
+	var out OpenAIChatCompletionResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	if w.Code != http.StatusOK || out.Choices[0].Message.Content != "answering I am Acme. then hello" {
+		t.Fatalf("Unexpected completion %d: %s", w.Code, w.Body)
+	}
+
+	for body, code := range map[string]string{
+		`{"template":"support_agent","messages":[{"role":"user","content":"hello"}]}`:                           "invalid_template_variables",
+		`{"template":"billing","variables":{"customer":"Acme"},"messages":[{"role":"user","content":"hello"}]}`: "template_not_found",
+	} {
+		if w := call("/v1/chat/completions", body); w.Code == http.StatusOK || !strings.Contains(w.Body.String(), code) {
+			t.Errorf("Expected %s for %s, got %d: %s", code, body, w.Code, w.Body)
+		}
+	}
+}
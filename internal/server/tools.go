@@ -0,0 +1,28 @@
+package server
+
+import (
+	"context"
+	"io"
+
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/tools"
+)
+
+// toolProgressEvent is the SSE event type carrying tools.Progress while the
+// gateway runs server-side tools; clients that do not know it ignore it
+const toolProgressEvent = "letllm.tool_progress"
+
+// openStream starts streaming req on p, through the tool runtime when
+// server-side tools are configured, and reports the outcome to the router.
+// Answers calling no server-side tool stream as they would without them.
+func openStream(ctx context.Context, r *provider.Router, rt *tools.Runtime, p provider.Provider, req *provider.StandardRequest) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	var err error
+	if rt.Enabled() {
+		rc, err = rt.Stream(ctx, p, req)
+	} else {
+		rc, err = p.StreamGenerate(ctx, &provider.GenerateRequest{StandardRequest: req})
+	}
+	r.ReportOutcome(p.GetInfo().Name, err)
+	return rc, err
+}
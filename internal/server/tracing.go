@@ -0,0 +1,63 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/tracing"
+)
+
+// finishTrace stores a sampled stream's trace; a trace that cannot be
+// stored is only logged, the stream has been served already
+func finishTrace(trace *tracing.Recorder, err error) {
+	if err := trace.Finish(err); err != nil {
+		log.Printf("stream trace: %v", err)
+	}
+}
+
+// traceKeyID returns the key a traced request is made with, as set by
+// recordUsage
+func traceKeyID(c *gin.Context) string {
+	caller, _ := provider.CallerFrom(c.Request.Context())
+	return caller.KeyID
+}
+
+// RegisterTraceAdminRoutes serves the stored stream traces
+func RegisterTraceAdminRoutes(admin *AdminRouter, store tracing.Store) {
+	// GET /admin/traces?limit= lists traces newest first, without events
+	admin.GET("/traces", func(c *gin.Context) {
+		limit := 100
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+				return
+			}
+			limit = n
+		}
+		list, err := store.List(limit)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"traces": list})
+	})
+
+	// GET /admin/traces/:id returns a trace with every read, write and flush
+	admin.GET("/traces/:id", func(c *gin.Context) {
+		t, err := store.Get(c.Param("id"))
+		if errors.Is(err, tracing.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, t)
+	})
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// maxAudioBytes is the largest audio upload accepted, matching OpenAI's limit
+const maxAudioBytes = 25 << 20
+
+// OpenAITranscriptionResponse is the json and verbose_json body of POST
+// /v1/audio/transcriptions
+type OpenAITranscriptionResponse struct {
+	// Only set for verbose_json
+	Task     string  `json:"task,omitempty"`
+	Language string  `json:"language,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	Text     string  `json:"text"`
+}
+
+// transcriptionsHandler serves POST /v1/audio/transcriptions, a multipart
+// upload routed by model like chat completions
+func transcriptionsHandler(r *provider.Router, adm *admission.Controller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Room for the form fields on top of the file
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAudioBytes+1<<20)
+
+		fh, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("audio file exceeds %d MB", maxAudioBytes>>20)})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("file is required: %v", err)})
+			return
+		}
+		if fh.Size > maxAudioBytes {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("audio file exceeds %d MB", maxAudioBytes>>20)})
+			return
+		}
+
+		format := c.DefaultPostForm("response_format", "json")
+		switch format {
+		case "json", "text", "verbose_json":
+		default:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported response_format %q", format)})
+			return
+		}
+
+		req := &provider.TranscriptionRequest{
+			Model:       c.PostForm("model"),
+			Filename:    fh.Filename,
+			ContentType: fh.Header.Get("Content-Type"),
+			Language:    c.PostForm("language"),
+			Prompt:      c.PostForm("prompt"),
+		}
+		if v := c.PostForm("temperature"); v != "" {
+			t, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "temperature must be a number"})
+				return
+			}
+			req.Temperature = &t
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("read file: %v", err)})
+			return
+		}
+		req.Audio, err = io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("read file: %v", err)})
+			return
+		}
+		if err := provider.ValidateTranscriptionRequest(req); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Model = resolveModelAlias(c, r, req.Model)
+		setRequestModel(c, req.Model)
+
+		attrs := requestAttributes(c)
+		routeReq := &provider.RouteRequest{Model: req.Model, Endpoint: c.FullPath(), Headers: routeHeaders(c.Request.Header), Attributes: attrs}
+		p, err := r.Route(routeReq)
+		if err != nil {
+			var policyErr *provider.PolicyError
+			if errors.As(err, &policyErr) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": policyErr.Code})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		setRequestProvider(c, p)
+		setRequestArm(c, routeReq.Arm)
+
+		release, err := adm.Acquire(c.Request.Context(), p.GetInfo().Name, req.Model, attrs)
+		if err != nil {
+			var rejected *admission.RejectedError
+			if errors.As(err, &rejected) {
+				c.Header("Retry-After", strconv.Itoa(rejected.RetryAfterSeconds()))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": "admission_" + rejected.Reason})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		defer release()
+
+		req.Model = upstreamModel(routeReq)
+		resp, err := p.Transcribe(c.Request.Context(), req)
+		if errors.Is(err, provider.ErrTranscriptionUnsupported) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "transcription_unsupported"})
+			return
+		}
+		r.ReportOutcome(p.GetInfo().Name, err)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		switch format {
+		case "text":
+			c.String(http.StatusOK, resp.Text)
+		case "verbose_json":
+			c.JSON(http.StatusOK, OpenAITranscriptionResponse{Task: "transcribe", Language: resp.Language, Duration: resp.Duration, Text: resp.Text})
+		default:
+			c.JSON(http.StatusOK, OpenAITranscriptionResponse{Text: resp.Text})
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+func TestTranscriptionsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo", Models: []string{"demo-whisper"}}}}
+	cfg.Cohere.APIKey = "test-key"
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	engine.POST("/v1/audio/transcriptions", transcriptionsHandler(r, admission.NewController(cfg, metrics.NewRegistry())))
+
+	post := func(fields map[string]string, audio string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for k, v := range fields {
+			_ = mw.WriteField(k, v)
+		}
+		if audio != "" {
+			fw, _ := mw.CreateFormFile("file", "clip.wav")
+			_, _ = fw.Write([]byte(audio))
+		}
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/v1/audio/transcriptions", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(map[string]string{"model": "demo-whisper"}, "RIFF1234")
+	if w.Code != http.StatusOK || w.Body.String() != `{"text":"Transcript of clip.wav (8 bytes)"}` {
+		t.Errorf("Unexpected json response %d %s", w.Code, w.Body)
+	}
+	w = post(map[string]string{"model": "demo-whisper", "response_format": "text"}, "RIFF")
+	if w.Code != http.StatusOK || w.Body.String() != "Transcript of clip.wav (4 bytes)" {
+		t.Errorf("Unexpected text response %d %s", w.Code, w.Body)
+	}
+	w = post(map[string]string{"model": "demo-whisper", "response_format": "verbose_json", "language": "en"}, "RIFF")
+	if !strings.Contains(w.Body.String(), `"task":"transcribe","language":"en"`) {
+		t.Errorf("Unexpected verbose response %s", w.Body)
+	}
+
+	for name, tc := range map[string]struct {
+		fields map[string]string
+		audio  string
+		code   int
+	}{
+		"no file":         {map[string]string{"model": "demo-whisper"}, "", http.StatusBadRequest},
+		"no model":        {map[string]string{}, "RIFF", http.StatusBadRequest},
+		"srt":             {map[string]string{"model": "demo-whisper", "response_format": "srt"}, "RIFF", http.StatusBadRequest},
+		"bad temperature": {map[string]string{"model": "demo-whisper", "temperature": "warm"}, "RIFF", http.StatusBadRequest},
+		"unrouted model":  {map[string]string{"model": "nope"}, "RIFF", http.StatusBadRequest},
+	} {
+		if w := post(tc.fields, tc.audio); w.Code != tc.code {
+			t.Errorf("%s: expected %d, got %d: %s", name, tc.code, w.Code, w.Body)
+		}
+	}
+	if w := post(map[string]string{"model": "command-r"}, "RIFF"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"code":"transcription_unsupported"`) {
+		t.Errorf("Expected transcription_unsupported, got %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,354 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/admission"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/experiments"
+	"github.com/luguanyu1234/letllm-go/internal/keys"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/usage"
+)
+
+// Context keys the chat handler uses to report what the usage recorder
+// cannot see from the outside
+const (
+	providerNameKey = "letllm.provider"
+	tokenUsageKey   = "letllm.usage"
+	modelKey        = "letllm.model"
+	costKey         = "letllm.cost"
+	callerKeyKey    = "letllm.caller_key"
+	experimentKey   = "letllm.experiment"
+)
+
+// setRequestModel records the model the client asked for
+func setRequestModel(c *gin.Context, model string) {
+	c.Set(modelKey, model)
+}
+
+// setRequestProvider records the provider serving the request
+func setRequestProvider(c *gin.Context, p provider.Provider) {
+	c.Set(providerNameKey, p.GetInfo().Name)
+}
+
+// setTokenUsage records the token counts reported by the provider
+func setTokenUsage(c *gin.Context, u provider.Usage) {
+	c.Set(tokenUsageKey, u)
+}
+
+// costHeader carries the USD cost of a request that was not streamed
+const costHeader = "X-LetLLM-Cost"
+
+// setResponseCost works out the cost of a response that used u: the cost
+// its provider reported in metadata, or u at the price of the request's
+// model. The cost is sent in the X-LetLLM-Cost header and kept for the
+// usage record.
+func setResponseCost(c *gin.Context, r *provider.Router, u provider.Usage, metadata map[string]interface{}) (float64, bool) {
+	cost, ok := metadata[provider.MetadataCostUSD].(float64)
+	if !ok {
+		price, priced := r.Price(c.GetString(modelKey))
+		if !priced {
+			return 0, false
+		}
+		cost = u.Cost(price)
+	}
+	c.Set(costKey, cost)
+	c.Header(costHeader, strconv.FormatFloat(cost, 'f', -1, 64))
+	return cost, true
+}
+
+// priceRequest prices the tokens of requests whose handler did not work out
+// their cost, such as streams, for the usage record
+func priceRequest(r *provider.Router) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if _, ok := c.Get(costKey); ok {
+			return
+		}
+		if v, ok := c.Get(tokenUsageKey); ok {
+			if price, ok := r.Price(c.GetString(modelKey)); ok {
+				c.Set(costKey, v.(provider.Usage).Cost(price))
+			}
+		}
+	}
+}
+
+// keyRateLimit is the rate limit of key k, which has limits
+func keyRateLimit(k *keys.Key) config.RateLimit {
+	return config.RateLimit{RequestsPerMinute: k.Limits.RequestsPerMinute, TokensPerMinute: k.Limits.TokensPerMinute}
+}
+
+// spendTokens counts the tokens a request used against the token rate
+// limits of its key, model and the gateway once it has been served
+func spendTokens(adm *admission.Controller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if v, ok := c.Get(tokenUsageKey); ok {
+			u := v.(provider.Usage)
+			adm.Spend(c.Request.Context(), c.GetString(modelKey), u.PromptTokens+u.CompletionTokens)
+		}
+	}
+}
+
+// recordUsage stores one usage record per request once it has completed,
+// and a summary in the recent request ring. The calling key is identified
+// from its bearer token when it is known. Requests from peer gateways'
+// federation keys are also attributed to the key they were made for there.
+// Streams are timed to their first byte.
+func recordUsage(cfg *config.Config, store usage.Store, keyStore keys.Store, recent *RecentRequests) gin.HandlerFunc {
+	trusted := make(map[string]bool, len(cfg.Federation.TrustedKeys))
+	for _, id := range cfg.Federation.TrustedKeys {
+		trusted[id] = true
+	}
+	return func(c *gin.Context) {
+		start := time.Now()
+		// Looked up ahead of the handler, which resolves the key's model
+		// aliases, and providers forwarding to other gateways pass it on
+		k := callerKey(c, keyStore)
+		keyID := callerKeyID(c, keyStore)
+		caller := provider.Caller{RequestID: requestID(c), KeyID: keyID, Token: callerToken(c)}
+		ctx := provider.WithCaller(c.Request.Context(), caller)
+		// Admission control queues and sheds by the key's priority class
+		if k != nil && k.Priority != "" {
+			ctx = admission.WithPriority(ctx, k.Priority)
+		}
+		// and rate limits by the key's limits
+		if k != nil && k.Limits != nil {
+			ctx = admission.WithRateLimit(ctx, k.ID, keyRateLimit(k))
+		}
+		c.Request = c.Request.WithContext(ctx)
+		w := &firstWriteWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		rec := &usage.Record{
+			Time:      start.UTC(),
+			Status:    c.Writer.Status(),
+			LatencyMS: time.Since(start).Milliseconds(),
+			Stream:    strings.HasPrefix(c.Writer.Header().Get("Content-Type"), "text/event-stream"),
+		}
+		if rec.Stream && !w.first.IsZero() {
+			rec.TTFTMS = w.first.Sub(start).Milliseconds()
+		}
+		rec.Provider = c.GetString(providerNameKey)
+		rec.Model = c.GetString(modelKey)
+		if v, ok := c.Get(tokenUsageKey); ok {
+			u := v.(provider.Usage)
+			rec.PromptTokens = u.PromptTokens
+			rec.CompletionTokens = u.CompletionTokens
+			rec.CachedPromptTokens = u.CachedPromptTokens
+			rec.ReasoningTokens = u.ReasoningTokens
+			rec.AudioTokens = u.AudioTokens
+		}
+		if v, ok := c.Get(costKey); ok {
+			cost := v.(float64)
+			rec.CostUSD = &cost
+		}
+		rec.KeyID = keyID
+		if v, ok := c.Get(experimentKey); ok {
+			a := v.(experiments.Assignment)
+			rec.Experiment, rec.Variant = a.Experiment, a.Variant
+		}
+		if trusted[keyID] {
+			rec.OriginKeyID = c.GetHeader(provider.OriginKeyHeader)
+		}
+
+		// Passthrough requests match no route
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		recent.Add(RequestSummary{RequestID: requestID(c), Method: c.Request.Method, Path: path, Record: *rec})
+		if err := store.Add(rec); err != nil {
+			log.Printf("request %s: usage: %v", requestID(c), err)
+		}
+	}
+}
+
+// firstWriteWriter notes when the first byte of a response body is written
+type firstWriteWriter struct {
+	gin.ResponseWriter
+	first time.Time
+}
+
+func (w *firstWriteWriter) Write(b []byte) (int, error) {
+	if w.first.IsZero() && len(b) > 0 {
+		w.first = time.Now()
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *firstWriteWriter) WriteString(s string) (int, error) {
+	if w.first.IsZero() && len(s) > 0 {
+		w.first = time.Now()
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Unwrap lets http.ResponseController reach the connection
+func (w *firstWriteWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// callerKeyID returns the ID of the virtual key the request's bearer token
+// belongs to, or "" when the token is not a known key
+func callerKeyID(c *gin.Context, keyStore keys.Store) string {
+	if keyID, ok := assignedKeyID(c); ok {
+		return keyID
+	}
+	if k := callerKey(c, keyStore); k != nil {
+		return k.ID
+	}
+	return ""
+}
+
+// callerKey returns the virtual key the request is made with, or nil. The
+// lookup is kept on the context, so a request hits the store once.
+func callerKey(c *gin.Context, keyStore keys.Store) *keys.Key {
+	if v, ok := c.Get(callerKeyKey); ok {
+		return v.(*keys.Key)
+	}
+	var k *keys.Key
+	if keyID, ok := assignedKeyID(c); ok {
+		k, _ = keyStore.Get(keyID)
+	} else if token := callerToken(c); token != "" {
+		k, _ = keyStore.GetByHash(keys.HashSecret(token))
+	}
+	c.Set(callerKeyKey, k)
+	return k
+}
+
+// assignedKeyID returns the key a request runs as without presenting its
+// secret: a batch's, or the one its client certificate maps to
+func assignedKeyID(c *gin.Context) (string, bool) {
+	if keyID, ok := c.Request.Context().Value(batchKeyIDContextKey{}).(string); ok {
+		return keyID, true
+	}
+	keyID, ok := c.Request.Context().Value(certKeyIDContextKey{}).(string)
+	return keyID, ok
+}
+
+// callerToken returns the credential the request carries, if any.
+// Anthropic clients send it in X-Api-Key and Google's in X-Goog-Api-Key
+// instead of Authorization, and browser WebSocket clients as a subprotocol.
+func callerToken(c *gin.Context) string {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return token
+	}
+	if token := c.GetHeader("X-Api-Key"); token != "" {
+		return token
+	}
+	if token := c.GetHeader("X-Goog-Api-Key"); token != "" {
+		return token
+	}
+	for _, proto := range strings.Split(c.GetHeader("Sec-WebSocket-Protocol"), ",") {
+		if token, ok := strings.CutPrefix(strings.TrimSpace(proto), realtimeKeyProtocol); ok {
+			return token
+		}
+	}
+	return ""
+}
+
+// RegisterUsageAdminRoutes wires usage export. In aggregated export mode
+// only grouped counts and histograms are available.
+func RegisterUsageAdminRoutes(admin *AdminRouter, store usage.Store, cfg *config.Config) {
+	admin.GET("/usage/records", func(c *gin.Context) {
+		if cfg.Usage.ExportMode == config.UsageExportAggregated {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "raw usage export is disabled; use /admin/usage/aggregate"})
+			return
+		}
+		from, to, err := usageRange(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		records, err := store.Query(from, to)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if records == nil {
+			records = []*usage.Record{}
+		}
+		c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "records": records})
+	})
+
+	// GET /admin/usage/aggregate?from=&to=&bucket=hour|day&group_by=provider,model,key&min_group_size=
+	admin.GET("/usage/aggregate", func(c *gin.Context) {
+		from, to, err := usageRange(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		opts := usage.AggregateOptions{
+			GroupBy:      strings.Split(c.DefaultQuery("group_by", "provider,model"), ","),
+			MinGroupSize: cfg.Usage.MinGroupSize,
+		}
+		switch bucket := c.DefaultQuery("bucket", "day"); bucket {
+		case "hour":
+			opts.Bucket = time.Hour
+		case "day":
+			opts.Bucket = 24 * time.Hour
+		default:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown bucket %q", bucket)})
+			return
+		}
+		// Callers may raise the threshold but never lower it below the configured minimum
+		if v := c.Query("min_group_size"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid min_group_size"})
+				return
+			}
+			if n > opts.MinGroupSize {
+				opts.MinGroupSize = n
+			}
+		}
+
+		records, err := store.Query(from, to)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		agg, err := usage.AggregateRecords(records, from, to, opts)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, agg)
+	})
+}
+
+// usageRange parses RFC 3339 from/to query parameters, defaulting to the
+// last 24 hours
+func usageRange(c *gin.Context) (time.Time, time.Time, error) {
+	to := time.Now().UTC()
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to: %w", err)
+		}
+		to = t
+	}
+	from := to.Add(-24 * time.Hour)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from: %w", err)
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/buildinfo"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// versionResponse is the body of GET /version
+type versionResponse struct {
+	buildinfo.Info
+	// Providers currently registered, by name
+	Providers []string `json:"providers"`
+}
+
+// RegisterVersionRoute wires GET /version, so operators can check what is
+// deployed
+func RegisterVersionRoute(engine *gin.Engine, info buildinfo.Info, r *provider.Router) {
+	engine.GET("/version", func(c *gin.Context) {
+		resp := versionResponse{Info: info, Providers: []string{}}
+		for _, p := range r.ListProviders() {
+			resp.Providers = append(resp.Providers, p.Name)
+		}
+		sort.Strings(resp.Providers)
+		c.JSON(http.StatusOK, resp)
+	})
+}
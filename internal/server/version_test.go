@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/buildinfo"
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+func TestVersionRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{Mock: []config.MockConfig{{Name: "demo-b"}, {Name: "demo-a"}}}
+	r, err := provider.NewRouter(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := gin.New()
+	buildinfo.Version, buildinfo.Commit = "v1.2.3", "abc123"
+	defer func() { buildinfo.Version, buildinfo.Commit = "dev", "" }()
+	RegisterVersionRoute(engine, buildinfo.Get(), r)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	var got versionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || got.Version != "v1.2.3" || got.Commit != "abc123" || got.GoVersion != runtime.Version() {
+		t.Errorf("Unexpected build info %d: %s", w.Code, w.Body)
+	}
+	if len(got.Providers) != 2 || got.Providers[0] != "demo-a" || got.Providers[1] != "demo-b" {
+		t.Errorf("Expected the providers by name, got %v", got.Providers)
+	}
+}
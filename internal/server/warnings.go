@@ -0,0 +1,24 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// warningsKey stores request-scoped warnings on the gin context
+const warningsKey = "letllm.warnings"
+
+// addWarning records a non-fatal notice to be returned with the response
+func addWarning(c *gin.Context, w provider.Warning) {
+	c.Set(warningsKey, append(requestWarnings(c), w))
+}
+
+// requestWarnings returns the warnings recorded for the current request
+func requestWarnings(c *gin.Context) []provider.Warning {
+	if v, ok := c.Get(warningsKey); ok {
+		if ws, ok := v.([]provider.Warning); ok {
+			return ws
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package sessions
+
+import (
+	"sync"
+
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// MemoryStore is an in-memory Store
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*memorySession
+}
+
+type memorySession struct {
+	meta     Session
+	messages []provider.Message
+}
+
+// NewMemoryStore creates an empty in-memory session store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]*memorySession)}
+}
+
+// Create stores a new session
+func (s *MemoryStore) Create(sess *Session, messages []provider.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[sess.ID] = &memorySession{meta: *sess, messages: append([]provider.Message(nil), messages...)}
+	return nil
+}
+
+// Get returns a session
+func (s *MemoryStore) Get(id string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ms, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	meta := ms.meta
+	return &meta, nil
+}
+
+// Messages returns a session's history
+func (s *MemoryStore) Messages(id string) ([]provider.Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ms, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]provider.Message(nil), ms.messages...), nil
+}
+
+// Append adds messages to a session's history
+func (s *MemoryStore) Append(id string, messages []provider.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms, ok := s.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	ms.messages = append(ms.messages, messages...)
+	return nil
+}
+
+// Delete removes a session and its history
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+// Erase deletes every session of a key and their history
+func (s *MemoryStore) Erase(keyID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, ms := range s.sessions {
+		if ms.meta.KeyID == keyID {
+			delete(s.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,18 @@
+package sessions
+
+import (
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+	"go.uber.org/fx"
+)
+
+// Module provides the conversation session Store.
+var Module = fx.Provide(NewStore)
+
+// NewStore creates the session store: the shared database when one is
+// configured, memory otherwise
+func NewStore(db *storage.DB) Store {
+	if db == nil {
+		return NewMemoryStore()
+	}
+	return NewSQLStore(db)
+}
@@ -0,0 +1,52 @@
+package sessions
+
+import (
+	"errors"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/ids"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// ErrNotFound is returned for unknown session IDs
+var ErrNotFound = errors.New("session not found")
+
+// Session is a conversation whose history the gateway keeps, so clients
+// send only their new messages
+type Session struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	CreatedAt int64  `json:"created_at"`
+	// Model of chat requests on the session that name none
+	Model    string            `json:"model,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// KeyID is the virtual key owning the session, empty for callers without one
+	KeyID string `json:"-"`
+}
+
+// New describes a new session owned by keyID
+func New(keyID, model string, metadata map[string]string) *Session {
+	return &Session{
+		ID:        "sess_" + ids.New(),
+		Object:    "session",
+		CreatedAt: time.Now().Unix(),
+		Model:     model,
+		Metadata:  metadata,
+		KeyID:     keyID,
+	}
+}
+
+// Store persists sessions and their history
+type Store interface {
+	// Create stores a new session starting with messages, e.g. a system prompt
+	Create(s *Session, messages []provider.Message) error
+	Get(id string) (*Session, error)
+	// Messages returns a session's history, oldest first
+	Messages(id string) ([]provider.Message, error)
+	// Append adds messages to the end of a session's history
+	Append(id string, messages []provider.Message) error
+	Delete(id string) error
+	// Erase deletes every session of a key with its history and returns
+	// how many sessions were removed
+	Erase(keyID string) (int, error)
+}
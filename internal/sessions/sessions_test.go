@@ -0,0 +1,72 @@
+package sessions
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/storage/storagetest"
+)
+
+func TestStores(t *testing.T) {
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"sql":    NewSQLStore(storagetest.Open(t)),
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			sess := New("team-a", "demo-chat", map[string]string{"app": "cli"})
+			system := provider.Message{Role: "system", Content: "Be brief."}
+			if err := s.Create(sess, []provider.Message{system}); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			got, err := s.Get(sess.ID)
+			if err != nil || !reflect.DeepEqual(got, sess) {
+				t.Errorf("Expected %+v, got %+v, %v", sess, got, err)
+			}
+
+			name := "lookup"
+			turn := []provider.Message{
+				{Role: "user", Content: "hi"},
+				{Role: "assistant", FunctionCall: &provider.FunctionCall{Name: "lookup", Arguments: "{}"}},
+				{Role: "function", Name: &name, Content: "42"},
+			}
+			if err := s.Append(sess.ID, turn[:2]); err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+			if err := s.Append(sess.ID, turn[2:]); err != nil {
+				t.Fatalf("Append failed: %v", err)
+			}
+			history, err := s.Messages(sess.ID)
+			if err != nil || !reflect.DeepEqual(history, append([]provider.Message{system}, turn...)) {
+				t.Errorf("Unexpected history %+v, %v", history, err)
+			}
+
+			if err := s.Delete(sess.ID); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			for op, err := range map[string]error{
+				"get":      func() error { _, err := s.Get(sess.ID); return err }(),
+				"messages": func() error { _, err := s.Messages(sess.ID); return err }(),
+				"append":   s.Append(sess.ID, turn),
+				"delete":   s.Delete(sess.ID),
+			} {
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("%s: expected ErrNotFound, got %v", op, err)
+				}
+			}
+
+			kept := New("team-b", "", nil)
+			_ = s.Create(New("team-a", "", nil), []provider.Message{system})
+			_ = s.Create(New("team-a", "", nil), nil)
+			_ = s.Create(kept, []provider.Message{system})
+			if n, err := s.Erase("team-a"); err != nil || n != 2 {
+				t.Errorf("Expected 2 sessions erased, got %d, %v", n, err)
+			}
+			if history, err := s.Messages(kept.ID); err != nil || len(history) != 1 {
+				t.Errorf("Expected other keys' sessions kept, got %v, %v", history, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package sessions
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+)
+
+// SQLStore is a Store backed by the shared database
+type SQLStore struct {
+	db *storage.DB
+}
+
+// NewSQLStore creates a session store on db; the sessions and
+// session_messages tables are created by the storage migrations
+func NewSQLStore(db *storage.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// Create stores a new session and its first messages in one transaction
+func (s *SQLStore) Create(sess *Session, messages []provider.Message) error {
+	metadata, err := json.Marshal(sess.Metadata)
+	if err != nil {
+		return fmt.Errorf("create session %s: %w", sess.ID, err)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("create session %s: %w", sess.ID, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(s.db.Rebind(`INSERT INTO sessions (id, key_id, model, metadata, created_at) VALUES (?, ?, ?, ?, ?)`),
+		sess.ID, sess.KeyID, sess.Model, string(metadata), sess.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create session %s: %w", sess.ID, err)
+	}
+	if err := s.insertMessages(tx, sess.ID, 0, messages); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("create session %s: %w", sess.ID, err)
+	}
+	return nil
+}
+
+// Get returns a session
+func (s *SQLStore) Get(id string) (*Session, error) {
+	sess := Session{Object: "session"}
+	var metadata string
+	err := s.db.QueryRow(s.db.Rebind("SELECT id, key_id, model, metadata, created_at FROM sessions WHERE id = ?"), id).
+		Scan(&sess.ID, &sess.KeyID, &sess.Model, &metadata, &sess.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get session %s: %w", id, err)
+	}
+	if err := json.Unmarshal([]byte(metadata), &sess.Metadata); err != nil {
+		return nil, fmt.Errorf("get session %s: %w", id, err)
+	}
+	return &sess, nil
+}
+
+// Messages returns a session's history
+func (s *SQLStore) Messages(id string) ([]provider.Message, error) {
+	if _, err := s.Get(id); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(s.db.Rebind("SELECT message FROM session_messages WHERE session_id = ? ORDER BY seq"), id)
+	if err != nil {
+		return nil, fmt.Errorf("messages of session %s: %w", id, err)
+	}
+	defer rows.Close()
+
+	var out []provider.Message
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		var msg provider.Message
+		if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+			return nil, fmt.Errorf("messages of session %s: %w", id, err)
+		}
+		out = append(out, msg)
+	}
+	return out, rows.Err()
+}
+
+// Append adds messages to a session's history in one transaction
+func (s *SQLStore) Append(id string, messages []provider.Message) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("append to session %s: %w", id, err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	err = tx.QueryRow(s.db.Rebind("SELECT COUNT(*) FROM sessions WHERE id = ?"), id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("append to session %s: %w", id, err)
+	}
+	if exists == 0 {
+		return ErrNotFound
+	}
+	var last int64
+	err = tx.QueryRow(s.db.Rebind("SELECT COALESCE(MAX(seq), 0) FROM session_messages WHERE session_id = ?"), id).Scan(&last)
+	if err != nil {
+		return fmt.Errorf("append to session %s: %w", id, err)
+	}
+	if err := s.insertMessages(tx, id, last, messages); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("append to session %s: %w", id, err)
+	}
+	return nil
+}
+
+// insertMessages stores messages numbered after seq
+func (s *SQLStore) insertMessages(tx *sql.Tx, id string, seq int64, messages []provider.Message) error {
+	for _, msg := range messages {
+		raw, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("append to session %s: %w", id, err)
+		}
+		seq++
+		_, err = tx.Exec(s.db.Rebind("INSERT INTO session_messages (session_id, seq, message) VALUES (?, ?, ?)"), id, seq, string(raw))
+		if err != nil {
+			return fmt.Errorf("append to session %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// Delete removes a session and its history
+func (s *SQLStore) Delete(id string) error {
+	res, err := s.db.Exec(s.db.Rebind("DELETE FROM sessions WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("delete session %s: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if _, err := s.db.Exec(s.db.Rebind("DELETE FROM session_messages WHERE session_id = ?"), id); err != nil {
+		return fmt.Errorf("delete history of session %s: %w", id, err)
+	}
+	return nil
+}
+
+// Erase deletes every session of a key and their history in one transaction
+func (s *SQLStore) Erase(keyID string) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("erase sessions of %s: %w", keyID, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(s.db.Rebind("DELETE FROM session_messages WHERE session_id IN (SELECT id FROM sessions WHERE key_id = ?)"), keyID)
+	if err != nil {
+		return 0, fmt.Errorf("erase sessions of %s: %w", keyID, err)
+	}
+	res, err := tx.Exec(s.db.Rebind("DELETE FROM sessions WHERE key_id = ?"), keyID)
+	if err != nil {
+		return 0, fmt.Errorf("erase sessions of %s: %w", keyID, err)
+	}
+	n, _ := res.RowsAffected()
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("erase sessions of %s: %w", keyID, err)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL DEFAULT '',
+	hash       TEXT NOT NULL UNIQUE,
+	disabled   BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL,
+	budget     TEXT,
+	limits     TEXT
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS prompt_artifacts (
+	id         TEXT PRIMARY KEY,
+	content    BYTEA NOT NULL,
+	size       INTEGER NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS usage_records (
+	id                BIGSERIAL PRIMARY KEY,
+	ts                TIMESTAMPTZ NOT NULL,
+	key_id            TEXT NOT NULL DEFAULT '',
+	provider          TEXT NOT NULL DEFAULT '',
+	model             TEXT NOT NULL,
+	status            INTEGER NOT NULL,
+	latency_ms        INTEGER NOT NULL,
+	prompt_tokens     INTEGER NOT NULL DEFAULT 0,
+	completion_tokens INTEGER NOT NULL DEFAULT 0,
+	stream            BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE INDEX IF NOT EXISTS usage_records_ts ON usage_records (ts);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS stream_traces (
+	id                  TEXT PRIMARY KEY,
+	started_at          TIMESTAMPTZ NOT NULL,
+	endpoint            TEXT NOT NULL DEFAULT '',
+	provider            TEXT NOT NULL DEFAULT '',
+	model               TEXT NOT NULL DEFAULT '',
+	upstream_headers_us BIGINT NOT NULL DEFAULT 0,
+	first_byte_us       BIGINT NOT NULL DEFAULT 0,
+	duration_us         BIGINT NOT NULL DEFAULT 0,
+	bytes               BIGINT NOT NULL DEFAULT 0,
+	error               TEXT NOT NULL DEFAULT '',
+	truncated           BOOLEAN NOT NULL DEFAULT FALSE,
+	events              TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS stream_traces_started_at ON stream_traces (started_at);
@@ -0,0 +1 @@
+ALTER TABLE api_keys ADD COLUMN priority TEXT NOT NULL DEFAULT '';
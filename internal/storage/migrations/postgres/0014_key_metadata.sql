@@ -0,0 +1,3 @@
+ALTER TABLE api_keys ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE api_keys ADD COLUMN tags TEXT;
+ALTER TABLE api_keys ADD COLUMN expires_at TIMESTAMPTZ;
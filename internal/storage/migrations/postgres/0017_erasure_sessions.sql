@@ -0,0 +1,2 @@
+ALTER TABLE erasure_audit ADD COLUMN sessions INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS sessions_key_id ON sessions (key_id);
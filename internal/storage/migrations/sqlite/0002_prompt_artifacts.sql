@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS prompt_artifacts (
+	id         TEXT PRIMARY KEY,
+	content    BLOB NOT NULL,
+	size       INTEGER NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS artifact_owners (
+	artifact_id TEXT NOT NULL,
+	subject     TEXT NOT NULL,
+	PRIMARY KEY (artifact_id, subject)
+);
+CREATE INDEX IF NOT EXISTS artifact_owners_subject ON artifact_owners (subject);
+CREATE INDEX IF NOT EXISTS usage_records_key_id ON usage_records (key_id);
+CREATE TABLE IF NOT EXISTS erasure_audit (
+	id            TEXT PRIMARY KEY,
+	subject_hash  TEXT NOT NULL,
+	requested_at  TIMESTAMP NOT NULL,
+	reference     TEXT NOT NULL DEFAULT '',
+	usage_records INTEGER NOT NULL DEFAULT 0,
+	artifacts     INTEGER NOT NULL DEFAULT 0,
+	key_action    TEXT NOT NULL DEFAULT '',
+	caches        TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS erasure_audit_requested_at ON erasure_audit (requested_at);
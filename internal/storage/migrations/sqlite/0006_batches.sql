@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS batch_files (
+	id         TEXT PRIMARY KEY,
+	key_id     TEXT NOT NULL DEFAULT '',
+	filename   TEXT NOT NULL,
+	purpose    TEXT NOT NULL,
+	bytes      INTEGER NOT NULL,
+	content    BLOB NOT NULL,
+	created_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS batches (
+	id         TEXT PRIMARY KEY,
+	key_id     TEXT NOT NULL DEFAULT '',
+	status     TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	data       TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS batches_key_id ON batches (key_id, created_at);
+CREATE INDEX IF NOT EXISTS batches_status ON batches (status);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS files (
+	id         TEXT PRIMARY KEY,
+	key_id     TEXT NOT NULL DEFAULT '',
+	filename   TEXT NOT NULL,
+	purpose    TEXT NOT NULL,
+	bytes      INTEGER NOT NULL,
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS files_key_id ON files (key_id, created_at);
+CREATE TABLE IF NOT EXISTS file_blobs (
+	id      TEXT PRIMARY KEY,
+	content BLOB NOT NULL
+);
+INSERT INTO files (id, key_id, filename, purpose, bytes, created_at)
+	SELECT id, key_id, filename, purpose, bytes, created_at FROM batch_files;
+INSERT INTO file_blobs (id, content) SELECT id, content FROM batch_files;
+DROP TABLE batch_files;
@@ -0,0 +1 @@
+ALTER TABLE api_keys ADD COLUMN model_aliases TEXT;
@@ -0,0 +1 @@
+ALTER TABLE usage_records ADD COLUMN origin_key_id TEXT NOT NULL DEFAULT '';
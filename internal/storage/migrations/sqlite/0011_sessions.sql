@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS sessions (
+	id         TEXT PRIMARY KEY,
+	key_id     TEXT NOT NULL DEFAULT '',
+	model      TEXT NOT NULL DEFAULT '',
+	metadata   TEXT NOT NULL DEFAULT '{}',
+	created_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS session_messages (
+	session_id TEXT NOT NULL,
+	seq        INTEGER NOT NULL,
+	message    TEXT NOT NULL,
+	PRIMARY KEY (session_id, seq)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE usage_records ADD COLUMN experiment TEXT NOT NULL DEFAULT '';
+ALTER TABLE usage_records ADD COLUMN variant TEXT NOT NULL DEFAULT '';
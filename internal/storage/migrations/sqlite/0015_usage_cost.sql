@@ -0,0 +1 @@
+ALTER TABLE usage_records ADD COLUMN cost_usd REAL;
@@ -0,0 +1,2 @@
+ALTER TABLE erasure_audit ADD COLUMN files INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE erasure_audit ADD COLUMN batches INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE stream_traces ADD COLUMN key_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS stream_traces_key_id ON stream_traces (key_id);
+ALTER TABLE erasure_audit ADD COLUMN traces INTEGER NOT NULL DEFAULT 0;
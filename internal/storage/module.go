@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/health"
+	"go.uber.org/fx"
+)
+
+// Module provides the shared *DB (nil when storage.driver is "memory").
+var Module = fx.Provide(
+	NewDB,
+	fx.Annotate(ReadinessCheck, fx.ResultTags(`group:"readiness"`)),
+)
+
+// NewDB opens the configured database and closes it on shutdown
+func NewDB(lc fx.Lifecycle, cfg *config.Config) (*DB, error) {
+	db, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if db != nil {
+		lc.Append(fx.Hook{
+			OnStop: func(ctx context.Context) error {
+				return db.Close()
+			},
+		})
+	}
+	return db, nil
+}
+
+// ReadinessCheck reports whether the database is reachable
+func ReadinessCheck(db *DB) health.Check {
+	return health.Check{
+		Name: "storage",
+		Probe: func(ctx context.Context) (map[string]string, error) {
+			if db == nil {
+				return map[string]string{"driver": DriverMemory}, nil
+			}
+			return map[string]string{"driver": db.Driver}, db.Health(ctx)
+		},
+	}
+}
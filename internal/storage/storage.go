@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Postgres driver registered as "pgx"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	// Pure-Go SQLite driver so the gateway stays a single static binary
+	_ "modernc.org/sqlite"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// Supported storage drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+)
+
+// DB wraps a database handle with the SQL dialect it speaks
+type DB struct {
+	*sql.DB
+	Driver string
+}
+
+// Open opens the database configured in cfg.Storage and applies pending
+// migrations. It returns a nil DB for the memory driver, in which case
+// subsystems keep state in-process.
+func Open(cfg *config.Config) (*DB, error) {
+	driver := cfg.Storage.Driver
+	if driver == "" {
+		driver = DriverSQLite
+	}
+
+	var db *DB
+	switch driver {
+	case DriverMemory:
+		return nil, nil
+	case DriverSQLite:
+		dsn := cfg.Storage.DSN
+		if dsn == "" {
+			dsn = "letllm.db"
+		}
+		sqlDB, err := sql.Open("sqlite", sqliteDSN(dsn))
+		if err != nil {
+			return nil, fmt.Errorf("open sqlite: %w", err)
+		}
+		// SQLite serializes writers; a single connection avoids SQLITE_BUSY
+		// and keeps ":memory:" databases shared across queries
+		sqlDB.SetMaxOpenConns(1)
+		db = &DB{DB: sqlDB, Driver: DriverSQLite}
+	case DriverPostgres:
+		if cfg.Storage.DSN == "" {
+			return nil, fmt.Errorf("storage.dsn is required for postgres")
+		}
+		sqlDB, err := sql.Open("pgx", cfg.Storage.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("open postgres: %w", err)
+		}
+		if cfg.Storage.MaxOpenConns > 0 {
+			sqlDB.SetMaxOpenConns(cfg.Storage.MaxOpenConns)
+		}
+		sqlDB.SetConnMaxIdleTime(5 * time.Minute)
+		db = &DB{DB: sqlDB, Driver: DriverPostgres}
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connect %s: %w", driver, err)
+	}
+	if _, err := db.Migrate(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate %s: %w", driver, err)
+	}
+	return db, nil
+}
+
+// sqliteDSN enables WAL journaling and a busy timeout for file databases
+func sqliteDSN(dsn string) string {
+	if dsn == ":memory:" || strings.Contains(dsn, "?") {
+		return dsn
+	}
+	return "file:" + dsn + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+}
+
+// Rebind rewrites "?" placeholders into the driver's native form
+func (db *DB) Rebind(query string) string {
+	if db.Driver != DriverPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Health reports whether the database is reachable
+func (db *DB) Health(ctx context.Context) error {
+	return db.PingContext(ctx)
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+func TestOpenSQLiteAppliesMigrations(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Storage.DSN = ":memory:"
+
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	if db.Driver != DriverSQLite {
+		t.Errorf("Expected sqlite by default, got %s", db.Driver)
+	}
+
+	migrations, err := Migrations(DriverSQLite)
+	if err != nil {
+		t.Fatalf("Migrations failed: %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count); err != nil {
+		t.Fatalf("Query schema_migrations failed: %v", err)
+	}
+	if count != len(migrations) {
+		t.Errorf("Expected %d applied migrations, got %d", len(migrations), count)
+	}
+
+	// Re-running is a no-op
+	applied, err := db.Migrate(context.Background())
+	if err != nil || applied != 0 {
+		t.Errorf("Expected no pending migrations, got %d (%v)", applied, err)
+	}
+}
+
+func TestMigrationsMatchAcrossDrivers(t *testing.T) {
+	sqlite, err := Migrations(DriverSQLite)
+	if err != nil {
+		t.Fatalf("sqlite migrations: %v", err)
+	}
+	postgres, err := Migrations(DriverPostgres)
+	if err != nil {
+		t.Fatalf("postgres migrations: %v", err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("Drivers have different migration counts: %d vs %d", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].Name != postgres[i].Name {
+			t.Errorf("Migration %d differs: %s vs %s", i, sqlite[i].Name, postgres[i].Name)
+		}
+	}
+}
+
+func TestOpenMemory(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Storage.Driver = DriverMemory
+	db, err := Open(cfg)
+	if err != nil || db != nil {
+		t.Errorf("Expected nil DB for memory driver, got %v (%v)", db, err)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	pg := &DB{Driver: DriverPostgres}
+	if got := pg.Rebind("SELECT * FROM t WHERE a = ? AND b = ?"); got != "SELECT * FROM t WHERE a = $1 AND b = $2" {
+		t.Errorf("Unexpected postgres rebind: %s", got)
+	}
+	lite := &DB{Driver: DriverSQLite}
+	if got := lite.Rebind("a = ?"); got != "a = ?" {
+		t.Errorf("Unexpected sqlite rebind: %s", got)
+	}
+}
+
+// TestOpenPostgres runs against a real server when LETLLM_TEST_POSTGRES_DSN is set
+func TestOpenPostgres(t *testing.T) {
+	dsn := os.Getenv("LETLLM_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("LETLLM_TEST_POSTGRES_DSN not set")
+	}
+	cfg := &config.Config{}
+	cfg.Storage.Driver = DriverPostgres
+	cfg.Storage.DSN = dsn
+
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
+
+	if applied, err := db.Migrate(context.Background()); err != nil || applied != 0 {
+		t.Errorf("Expected no pending migrations, got %d (%v)", applied, err)
+	}
+}
@@ -0,0 +1,24 @@
+// Package storagetest opens databases for tests.
+package storagetest
+
+import (
+	"testing"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+)
+
+// Open returns an in-memory SQLite database with every migration applied,
+// closed when t ends
+func Open(t testing.TB) *storage.DB {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Storage.Driver = storage.DriverSQLite
+	cfg.Storage.DSN = ":memory:"
+	db, err := storage.Open(cfg)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
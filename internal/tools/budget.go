@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// defaultMaxIterations bounds the model calls of a tool loop when the
+// config does not
+const defaultMaxIterations = 8
+
+// Budget limits enforced on each request's tool loop
+const (
+	LimitIterations = "iterations"
+	LimitTokens     = "tokens"
+	LimitDuration   = "duration"
+	LimitToolCost   = "tool_cost"
+)
+
+// Budget caps the resources one request's tool loop may use; zero fields
+// are unlimited, except MaxIterations which always applies
+type Budget struct {
+	MaxIterations  int
+	MaxTotalTokens int
+	MaxDuration    time.Duration
+	MaxToolCostUSD float64
+}
+
+// budgetFromConfig reads the tool loop budget, applying defaults
+func budgetFromConfig(cfg *config.Config) Budget {
+	b := Budget{
+		MaxIterations:  cfg.ToolLoop.MaxIterations,
+		MaxTotalTokens: cfg.ToolLoop.MaxTotalTokens,
+		MaxDuration:    cfg.ToolLoop.MaxDuration,
+		MaxToolCostUSD: cfg.ToolLoop.MaxToolCostUSD,
+	}
+	if b.MaxIterations <= 0 {
+		b.MaxIterations = defaultMaxIterations
+	}
+	return b
+}
+
+// BudgetExceededError is returned when a tool loop runs out of budget
+// before the model produced a final answer. It carries what was spent and
+// the messages produced so far, so clients can inspect or resume the loop.
+type BudgetExceededError struct {
+	// Limit is the budget that ran out (LimitIterations, LimitTokens, ...)
+	Limit       string         `json:"limit"`
+	Iterations  int            `json:"iterations"`
+	Usage       provider.Usage `json:"usage"`
+	ToolCostUSD float64        `json:"tool_cost_usd"`
+	ElapsedMS   int64          `json:"elapsed_ms"`
+	// Transcript holds the assistant calls and tool results appended to the
+	// client's messages
+	Transcript []provider.Message `json:"transcript"`
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("tool loop %s budget exhausted after %d iterations", e.Limit, e.Iterations)
+}
@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// staticTool returns a fixed result after an optional delay
+type staticTool struct {
+	name  string
+	delay time.Duration
+}
+
+func (s *staticTool) Definition() provider.Function {
+	return provider.Function{Name: s.name}
+}
+
+func (s *staticTool) Execute(ctx context.Context, arguments string) (string, error) {
+	select {
+	case <-time.After(s.delay):
+		return "ok", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func newBudgetRuntime(configure func(cfg *config.Config)) *Runtime {
+	cfg := &config.Config{}
+	cfg.Tools = []config.ToolConfig{{Name: "search", URL: "http://unused.invalid", CostUSD: 0.01}}
+	configure(cfg)
+	rt := NewRuntime(cfg, metrics.NewRegistry())
+	rt.Register(&staticTool{name: "search"}, 0)
+	return rt
+}
+
+func loopingProvider(n int) *scriptedProvider {
+	p := &scriptedProvider{}
+	for i := 0; i < n; i++ {
+		p.replies = append(p.replies, callMsg("search", `{"q":"x"}`))
+	}
+	return p
+}
+
+func runLoop(t *testing.T, rt *Runtime, p provider.Provider) *BudgetExceededError {
+	t.Helper()
+	_, err := rt.Run(context.Background(), p, &provider.StandardRequest{
+		Model:    "m",
+		Messages: []provider.Message{{Role: provider.RoleUser, Content: "go"}},
+	}, nil)
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("Expected budget error, got %v", err)
+	}
+	return budgetErr
+}
+
+func TestRunIterationBudget(t *testing.T) {
+	rt := newBudgetRuntime(func(cfg *config.Config) { cfg.ToolLoop.MaxIterations = 3 })
+	err := runLoop(t, rt, loopingProvider(10))
+
+	if err.Limit != LimitIterations || err.Iterations != 3 {
+		t.Errorf("Unexpected budget error: %+v", err)
+	}
+	// Two completed rounds plus the unanswered third call
+	if len(err.Transcript) != 5 || err.Transcript[4].FunctionCall == nil {
+		t.Errorf("Unexpected partial transcript: %+v", err.Transcript)
+	}
+	if err.Usage.TotalTokens != 36 {
+		t.Errorf("Expected usage of all model calls, got %+v", err.Usage)
+	}
+}
+
+func TestRunTokenBudget(t *testing.T) {
+	rt := newBudgetRuntime(func(cfg *config.Config) { cfg.ToolLoop.MaxTotalTokens = 20 })
+	if err := runLoop(t, rt, loopingProvider(10)); err.Limit != LimitTokens || err.Iterations != 2 {
+		t.Errorf("Unexpected budget error: %+v", err)
+	}
+}
+
+func TestRunToolCostBudget(t *testing.T) {
+	rt := newBudgetRuntime(func(cfg *config.Config) { cfg.ToolLoop.MaxToolCostUSD = 0.025 })
+	rt.costs["search"] = 0.01
+	err := runLoop(t, rt, loopingProvider(10))
+	if err.Limit != LimitToolCost || err.ToolCostUSD != 0.02 {
+		t.Errorf("Unexpected budget error: %+v", err)
+	}
+}
+
+func TestRunDurationBudget(t *testing.T) {
+	rt := newBudgetRuntime(func(cfg *config.Config) { cfg.ToolLoop.MaxDuration = 20 * time.Millisecond })
+	rt.Register(&staticTool{name: "search", delay: time.Second}, 0)
+	if err := runLoop(t, rt, loopingProvider(10)); err.Limit != LimitDuration {
+		t.Errorf("Unexpected budget error: %+v", err)
+	}
+}
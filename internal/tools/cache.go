@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// maxCacheEntries bounds the result cache; expired entries are dropped
+// first and the oldest entries after that
+const maxCacheEntries = 10000
+
+// ResultCache keeps results of deterministic tools for a TTL, keyed by the
+// tool name and a hash of its arguments
+type ResultCache struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedResult
+}
+
+type cachedResult struct {
+	result  string
+	expires time.Time
+}
+
+// NewResultCache creates an empty cache
+func NewResultCache() *ResultCache {
+	return &ResultCache{now: time.Now, entries: make(map[string]cachedResult)}
+}
+
+// CacheKey identifies a call by tool name and arguments. Arguments that are
+// valid JSON are compacted first, so whitespace differences between model
+// outputs do not cause misses.
+func CacheKey(name, arguments string) string {
+	args := []byte(arguments)
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, args); err == nil {
+		args = buf.Bytes()
+	}
+	h := sha256.New()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write(args)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns an unexpired result
+func (c *ResultCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !c.now().Before(e.expires) {
+		return "", false
+	}
+	return e.result, true
+}
+
+// Put stores a result for ttl
+func (c *ResultCache) Put(key, result string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.entries) >= maxCacheEntries {
+		c.evictLocked(now)
+	}
+	c.entries[key] = cachedResult{result: result, expires: now.Add(ttl)}
+}
+
+// Flush drops every entry
+func (c *ResultCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cachedResult)
+}
+
+// evictLocked drops expired entries, then those closest to expiry until
+// there is room; callers must hold c.mu
+func (c *ResultCache) evictLocked(now time.Time) {
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	for len(c.entries) >= maxCacheEntries {
+		var oldest string
+		var oldestExp time.Time
+		for k, e := range c.entries {
+			if oldest == "" || e.expires.Before(oldestExp) {
+				oldest, oldestExp = k, e.expires
+			}
+		}
+		delete(c.entries, oldest)
+	}
+}
@@ -0,0 +1,6 @@
+package tools
+
+import "go.uber.org/fx"
+
+// Module provides the server-side tool runtime.
+var Module = fx.Provide(NewRuntime)
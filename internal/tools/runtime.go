@@ -0,0 +1,284 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// Tool call outcomes reported in letllm_tool_calls_total
+const (
+	OutcomeOK     = "ok"
+	OutcomeCached = "cached"
+	OutcomeError  = "error"
+)
+
+// Runtime executes server-side tools. When a model calls one of them, the
+// gateway runs it, appends the result to the conversation and asks the
+// model again, until the model answers or calls a client-side function.
+type Runtime struct {
+	tools map[string]Tool
+	// ttls holds the cache TTL of deterministic tools
+	ttls  map[string]time.Duration
+	order []string
+	cache *ResultCache
+	// costs holds the USD charged per call of each tool
+	costs  map[string]float64
+	budget Budget
+
+	calls    *metrics.CounterVec
+	duration *metrics.CounterVec
+}
+
+// NewRuntime creates a runtime for the configured tools
+func NewRuntime(cfg *config.Config, m *metrics.Registry) *Runtime {
+	rt := &Runtime{
+		tools:  make(map[string]Tool),
+		ttls:   make(map[string]time.Duration),
+		cache:  NewResultCache(),
+		costs:  make(map[string]float64),
+		budget: budgetFromConfig(cfg),
+		calls: m.Counter("letllm_tool_calls_total",
+			"Server-side tool calls by outcome.", "tool", "outcome"),
+		duration: m.Counter("letllm_tool_call_seconds_total",
+			"Time spent executing server-side tools.", "tool"),
+	}
+	for _, tc := range cfg.Tools {
+		rt.Register(NewHTTPTool(tc), tc.CacheTTL)
+		rt.costs[tc.Name] = tc.CostUSD
+	}
+	return rt
+}
+
+// Register adds a tool. A positive cacheTTL marks it deterministic, so its
+// results are reused for calls with the same arguments.
+func (rt *Runtime) Register(t Tool, cacheTTL time.Duration) {
+	name := t.Definition().Name
+	if _, ok := rt.tools[name]; !ok {
+		rt.order = append(rt.order, name)
+	}
+	rt.tools[name] = t
+	if cacheTTL > 0 {
+		rt.ttls[name] = cacheTTL
+	} else {
+		delete(rt.ttls, name)
+	}
+}
+
+// Enabled reports whether any server-side tools are configured
+func (rt *Runtime) Enabled() bool {
+	return len(rt.tools) > 0
+}
+
+// FlushCache drops every cached tool result, e.g. on an erasure request
+func (rt *Runtime) FlushCache() {
+	rt.cache.Flush()
+}
+
+// Execute runs one call, serving deterministic tools from the cache when
+// possible. It reports whether the result came from the cache.
+func (rt *Runtime) Execute(ctx context.Context, call provider.FunctionCall) (string, bool, error) {
+	t, ok := rt.tools[call.Name]
+	if !ok {
+		return "", false, fmt.Errorf("unknown tool %s", call.Name)
+	}
+
+	ttl, deterministic := rt.ttls[call.Name]
+	key := CacheKey(call.Name, call.Arguments)
+	if deterministic {
+		if result, ok := rt.cache.Get(key); ok {
+			rt.calls.Inc(call.Name, OutcomeCached)
+			return result, true, nil
+		}
+	}
+
+	start := time.Now()
+	result, err := t.Execute(ctx, call.Arguments)
+	rt.duration.Add(time.Since(start).Seconds(), call.Name)
+	if err != nil {
+		rt.calls.Inc(call.Name, OutcomeError)
+		return "", false, err
+	}
+	rt.calls.Inc(call.Name, OutcomeOK)
+	if deterministic {
+		rt.cache.Put(key, result, ttl)
+	}
+	return result, false, nil
+}
+
+// Tool progress statuses
+const (
+	StatusStarted   = "started"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Progress reports a server-side tool call starting or finishing
+type Progress struct {
+	Tool      string `json:"tool"`
+	Status    string `json:"status"`
+	Iteration int    `json:"iteration"`
+	// Set once the call has finished
+	Cached     bool   `json:"cached,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Run completes req on p, executing server-side tool calls in between.
+// Functions defined by the client shadow server-side tools of the same
+// name and are returned to the client as usual. Usage is summed over all
+// model calls. The loop is bounded by the runtime's Budget; when it runs
+// out a *BudgetExceededError is returned. onProgress, if set, is called
+// before and after each tool call.
+func (rt *Runtime) Run(ctx context.Context, p provider.Provider, req *provider.StandardRequest, onProgress func(Progress)) (*provider.StandardResponse, error) {
+	if onProgress == nil {
+		onProgress = func(Progress) {}
+	}
+	// Each model call is awaited in full to see whether it calls a tool
+	l := rt.newLoop(req)
+	ctx, cancel := l.context(ctx)
+	defer cancel()
+
+	for i := 1; ; i++ {
+		resp, err := p.Generate(ctx, &provider.GenerateRequest{StandardRequest: &l.req})
+		if err != nil {
+			if l.timedOut(ctx) {
+				return nil, l.exceeded(LimitDuration, i-1, nil)
+			}
+			return nil, err
+		}
+		addUsage(&l.total, resp.Usage)
+
+		if serverCall(resp.StandardResponse, rt.tools, l.clientFns) == nil {
+			resp.Usage = l.total
+			return resp.StandardResponse, nil
+		}
+		if err := l.call(ctx, i, resp.Choices[0].Message, onProgress); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// loop is the state of one request's tool loop
+type loop struct {
+	rt *Runtime
+	// req is the request to send next, the client's followed by the tool
+	// calls and results so far, with server-side tools added
+	req       provider.StandardRequest
+	sent      int
+	clientFns map[string]bool
+	start     time.Time
+	total     provider.Usage
+	cost      float64
+}
+
+func (rt *Runtime) newLoop(req *provider.StandardRequest) *loop {
+	l := &loop{rt: rt, req: *req, sent: len(req.Messages), clientFns: make(map[string]bool, len(req.Functions)), start: time.Now()}
+	l.req.Stream = false
+	l.req.Messages = append([]provider.Message(nil), req.Messages...)
+	l.req.Functions = append([]provider.Function(nil), req.Functions...)
+	for _, fn := range req.Functions {
+		l.clientFns[fn.Name] = true
+	}
+	for _, name := range rt.order {
+		if !l.clientFns[name] {
+			l.req.Functions = append(l.req.Functions, rt.tools[name].Definition())
+		}
+	}
+	return l
+}
+
+// context bounds ctx by the budget's MaxDuration
+func (l *loop) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.rt.budget.MaxDuration > 0 {
+		return context.WithTimeout(ctx, l.rt.budget.MaxDuration)
+	}
+	return context.WithCancel(ctx)
+}
+
+// timedOut reports whether ctx ended by running out of MaxDuration
+func (l *loop) timedOut(ctx context.Context) bool {
+	return l.rt.budget.MaxDuration > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+func addUsage(total *provider.Usage, u provider.Usage) {
+	total.PromptTokens += u.PromptTokens
+	total.CompletionTokens += u.CompletionTokens
+	total.TotalTokens += u.TotalTokens
+}
+
+func (l *loop) exceeded(limit string, iterations int, last *provider.Message) error {
+	transcript := append([]provider.Message(nil), l.req.Messages[l.sent:]...)
+	if last != nil {
+		transcript = append(transcript, *last)
+	}
+	return &BudgetExceededError{
+		Limit:       limit,
+		Iterations:  iterations,
+		Usage:       l.total,
+		ToolCostUSD: l.cost,
+		ElapsedMS:   time.Since(l.start).Milliseconds(),
+		Transcript:  transcript,
+	}
+}
+
+// call runs the server-side tool called by last, the model's i-th answer,
+// if the budget has room for another round, and appends the call and its
+// result to the request
+func (l *loop) call(ctx context.Context, i int, last *provider.Message, onProgress func(Progress)) error {
+	rt, call := l.rt, last.FunctionCall
+	switch {
+	case i >= rt.budget.MaxIterations:
+		return l.exceeded(LimitIterations, i, last)
+	case rt.budget.MaxTotalTokens > 0 && l.total.TotalTokens >= rt.budget.MaxTotalTokens:
+		return l.exceeded(LimitTokens, i, last)
+	case rt.budget.MaxToolCostUSD > 0 && l.cost+rt.costs[call.Name] > rt.budget.MaxToolCostUSD:
+		return l.exceeded(LimitToolCost, i, last)
+	}
+
+	onProgress(Progress{Tool: call.Name, Status: StatusStarted, Iteration: i})
+	callStart := time.Now()
+	result, cached, err := rt.Execute(ctx, *call)
+	if !cached {
+		l.cost += rt.costs[call.Name]
+	}
+	done := Progress{Tool: call.Name, Status: StatusCompleted, Iteration: i, Cached: cached, DurationMS: time.Since(callStart).Milliseconds()}
+	if err != nil {
+		done.Status, done.Error = StatusFailed, err.Error()
+	}
+	onProgress(done)
+	if err != nil {
+		if l.timedOut(ctx) {
+			return l.exceeded(LimitDuration, i, last)
+		}
+		// Let the model see the failure and recover
+		result = fmt.Sprintf("error: %v", err)
+	}
+	name := call.Name
+	l.req.Messages = append(l.req.Messages,
+		provider.Message{Role: provider.RoleAssistant, FunctionCall: call},
+		provider.Message{Role: provider.RoleFunction, Name: &name, Content: result},
+	)
+	return nil
+}
+
+// serverCall returns the first choice's function call when it targets a
+// server-side tool
+func serverCall(resp *provider.StandardResponse, tools map[string]Tool, clientFns map[string]bool) *provider.FunctionCall {
+	if len(resp.Choices) == 0 || resp.Choices[0].Message == nil {
+		return nil
+	}
+	call := resp.Choices[0].Message.FunctionCall
+	if call == nil || clientFns[call.Name] {
+		return nil
+	}
+	if _, ok := tools[call.Name]; !ok {
+		return nil
+	}
+	return call
+}
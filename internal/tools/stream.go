@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// Stream is Run for streaming requests. Each model call is streamed and its
+// chunks are passed on as they arrive, so answers that call no server-side
+// tool stream as they would without the runtime. Once an answer turns out
+// to call a server-side tool, the rest of it is held back, the tool is run
+// and the model streamed again with the result. Tool progress is passed on
+// as chunks carrying only ToolProgress; usage chunks carry the usage of all
+// model calls so far. Failures after the first model call, an exhausted
+// Budget among them, end the stream with an error chunk.
+func (rt *Runtime) Stream(ctx context.Context, p provider.Provider, req *provider.StandardRequest) (io.ReadCloser, error) {
+	l := rt.newLoop(req)
+	l.req.Stream = true
+	ctx, cancel := l.context(ctx)
+	rc, err := p.StreamGenerate(ctx, &provider.GenerateRequest{StandardRequest: &l.req})
+	if err != nil {
+		cancel()
+		if l.timedOut(ctx) {
+			return nil, l.exceeded(LimitDuration, 0, nil)
+		}
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer cancel()
+		pw.CloseWithError(l.stream(ctx, p, rc, pw))
+	}()
+	return &streamBody{PipeReader: pr, cancel: cancel}, nil
+}
+
+// streamBody stops the loop once the caller closes it
+type streamBody struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (b *streamBody) Close() error {
+	b.cancel()
+	return b.PipeReader.Close()
+}
+
+// stream passes on the model calls of the loop, starting with rc, to w
+func (l *loop) stream(ctx context.Context, p provider.Provider, rc io.ReadCloser, w io.Writer) error {
+	progress := func(ev Progress) {
+		raw, _ := json.Marshal(ev)
+		_ = writeChunk(w, &provider.StreamChunk{Choices: []provider.Choice{}, ToolProgress: raw})
+	}
+	for i := 1; ; i++ {
+		last, err := l.pass(rc, w, i > 1)
+		rc.Close()
+		if err != nil || last == nil {
+			return err
+		}
+		if err := l.call(ctx, i, last, progress); err != nil {
+			return writeError(w, err)
+		}
+		if rc, err = p.StreamGenerate(ctx, &provider.GenerateRequest{StandardRequest: &l.req}); err != nil {
+			if l.timedOut(ctx) {
+				err = l.exceeded(LimitDuration, i, nil)
+			}
+			return writeError(w, err)
+		}
+	}
+}
+
+// pass copies one model call's stream to w until it calls a server-side
+// tool, and then returns the answer with the call, having read the rest.
+// It returns a nil message when the stream ended without such a call. The
+// call's usage is added to the loop's once it ends; when earlier calls have
+// been counted, usage chunks are rewritten to carry the total.
+func (l *loop) pass(rc io.Reader, w io.Writer, resumed bool) (*provider.Message, error) {
+	var (
+		last  *provider.Message
+		usage *provider.Usage
+	)
+	br := bufio.NewReader(rc)
+	for {
+		line, readErr := br.ReadBytes('\n')
+		data := bytes.TrimSpace(bytes.TrimPrefix(bytes.TrimSpace(line), []byte("data:")))
+		var chunk provider.StreamChunk
+		if len(data) > 0 && string(data) != "[DONE]" && json.Unmarshal(data, &chunk) == nil {
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+				if resumed {
+					total := l.total
+					line, chunk.Usage = nil, &total
+					addUsage(chunk.Usage, *usage)
+				}
+			}
+			for _, choice := range chunk.Choices {
+				if choice.Index != 0 || choice.Delta == nil || choice.Delta.FunctionCall == nil {
+					continue
+				}
+				fc := choice.Delta.FunctionCall
+				if last != nil {
+					last.FunctionCall.Arguments += fc.Arguments
+				} else if fc.Name != "" && !l.clientFns[fc.Name] && l.rt.tools[fc.Name] != nil {
+					last = &provider.Message{Role: provider.RoleAssistant, FunctionCall: &provider.FunctionCall{Name: fc.Name, Arguments: fc.Arguments}}
+				}
+			}
+		}
+		// Anything else is left to the caller's decoder, unless the answer
+		// is being held back
+		if last == nil && len(line) > 0 {
+			if !bytes.HasSuffix(line, []byte("\n")) {
+				line = append(line, '\n')
+			}
+			if _, err := w.Write(line); err != nil {
+				return nil, err
+			}
+		} else if last == nil && chunk.Usage != nil {
+			if err := writeChunk(w, &chunk); err != nil {
+				return nil, err
+			}
+		}
+		if readErr == io.EOF {
+			if usage != nil {
+				addUsage(&l.total, *usage)
+			}
+			return last, nil
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+}
+
+// writeChunk writes one chunk as a line
+func writeChunk(w io.Writer, chunk *provider.StreamChunk) error {
+	b, err := json.Marshal(chunk)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// writeError ends a stream with an error chunk. An exhausted budget is
+// reported with code tool_budget_exceeded and the details of the
+// BudgetExceededError.
+func writeError(w io.Writer, err error) error {
+	detail := &provider.ErrorDetail{Type: "server_error", Message: err.Error()}
+	var budgetErr *BudgetExceededError
+	if errors.As(err, &budgetErr) {
+		detail.Type, detail.Code = "tool_budget_exceeded", "tool_budget_exceeded"
+		raw, _ := json.Marshal(budgetErr)
+		_ = json.Unmarshal(raw, &detail.Details)
+	}
+	return writeChunk(w, &provider.StreamChunk{Choices: []provider.Choice{}, Error: detail})
+}
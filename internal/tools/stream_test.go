@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// readChunks decodes every chunk of a stream from the runtime
+func readChunks(t *testing.T, rc io.ReadCloser) []provider.StreamChunk {
+	t.Helper()
+	defer rc.Close()
+	var chunks []provider.StreamChunk
+	sc := bufio.NewScanner(rc)
+	for sc.Scan() {
+		data := strings.TrimSpace(strings.TrimPrefix(sc.Text(), "data:"))
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		var chunk provider.StreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("Undecodable chunk %q: %v", data, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	return chunks
+}
+
+func TestRuntimeStreamPassesAnswersThrough(t *testing.T) {
+	rt := NewRuntime(&config.Config{}, metrics.NewRegistry())
+	rt.Register(&staticTool{name: "search"}, 0)
+
+	p := &scriptedProvider{replies: []provider.Message{{Role: provider.RoleAssistant, Content: "no tools needed"}}}
+	rc, err := rt.Stream(context.Background(), p, &provider.StandardRequest{
+		Model:    "m",
+		Messages: []provider.Message{{Role: provider.RoleUser, Content: "go"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	chunks := readChunks(t, rc)
+
+	if len(chunks) != 4 || chunks[0].Choices[0].Delta.Content != "no " {
+		t.Fatalf("Expected the deltas passed on one by one, got %+v", chunks)
+	}
+	if chunks[3].Usage == nil || chunks[3].Usage.TotalTokens != 12 {
+		t.Errorf("Unexpected usage: %+v", chunks[3].Usage)
+	}
+}
+
+func TestRuntimeStreamRunsServerTools(t *testing.T) {
+	rt := NewRuntime(&config.Config{}, metrics.NewRegistry())
+	rt.Register(&staticTool{name: "search"}, time.Minute)
+
+	call := callMsg("search", `{"q":"x"}`)
+	call.Content = "looking "
+	p := &scriptedProvider{replies: []provider.Message{call, {Role: provider.RoleAssistant, Content: "found it"}}}
+	rc, err := rt.Stream(context.Background(), p, &provider.StandardRequest{
+		Model:    "m",
+		Messages: []provider.Message{{Role: provider.RoleUser, Content: "go"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	chunks := readChunks(t, rc)
+
+	var content string
+	var progress []Progress
+	for _, chunk := range chunks {
+		for _, choice := range chunk.Choices {
+			if choice.Delta.FunctionCall != nil {
+				t.Errorf("Expected the server-side call held back, got %+v", choice.Delta.FunctionCall)
+			}
+			content += choice.Delta.Content
+		}
+		if chunk.ToolProgress != nil {
+			var ev Progress
+			if err := json.Unmarshal(chunk.ToolProgress, &ev); err != nil {
+				t.Fatal(err)
+			}
+			progress = append(progress, ev)
+		}
+	}
+	if content != "looking found it" {
+		t.Errorf("Expected both answers streamed, got %q", content)
+	}
+	if len(progress) != 2 || progress[0].Status != StatusStarted || progress[1].Status != StatusCompleted {
+		t.Errorf("Unexpected progress: %+v", progress)
+	}
+	if last := chunks[len(chunks)-1]; last.Usage == nil || last.Usage.TotalTokens != 24 {
+		t.Errorf("Expected usage of both model calls, got %+v", last.Usage)
+	}
+
+	second := p.requests[1]
+	if len(second.Messages) != 3 || second.Messages[1].FunctionCall == nil || second.Messages[2].Content != "ok" {
+		t.Errorf("Unexpected transcript: %+v", second.Messages)
+	}
+}
+
+func TestRuntimeStreamReportsBudget(t *testing.T) {
+	rt := newBudgetRuntime(func(cfg *config.Config) { cfg.ToolLoop.MaxIterations = 2 })
+	rc, err := rt.Stream(context.Background(), loopingProvider(10), &provider.StandardRequest{
+		Model:    "m",
+		Messages: []provider.Message{{Role: provider.RoleUser, Content: "go"}},
+	})
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	chunks := readChunks(t, rc)
+
+	last := chunks[len(chunks)-1]
+	if last.Error == nil || last.Error.Code != "tool_budget_exceeded" {
+		t.Fatalf("Expected the stream to end with a budget error, got %+v", last)
+	}
+	if last.Error.Details["limit"] != LimitIterations {
+		t.Errorf("Unexpected budget details: %+v", last.Error.Details)
+	}
+}
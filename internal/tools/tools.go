@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// maxResultBytes bounds a tool result fed back to the model
+const maxResultBytes = 1 << 20
+
+// defaultToolTimeout bounds a tool call when the tool does not set one
+const defaultToolTimeout = 30 * time.Second
+
+// Tool is a function the gateway executes on the model's behalf
+type Tool interface {
+	Definition() provider.Function
+	// Execute runs the tool with the model's JSON arguments and returns the
+	// result passed back to the model
+	Execute(ctx context.Context, arguments string) (string, error)
+}
+
+// HTTPTool executes calls by POSTing the arguments to an HTTP endpoint; the
+// response body is the result
+type HTTPTool struct {
+	def    provider.Function
+	url    string
+	client *http.Client
+}
+
+// NewHTTPTool creates a tool from its config
+func NewHTTPTool(tc config.ToolConfig) *HTTPTool {
+	timeout := tc.Timeout
+	if timeout <= 0 {
+		timeout = defaultToolTimeout
+	}
+	return &HTTPTool{
+		def: provider.Function{
+			Name:        tc.Name,
+			Description: tc.Description,
+			Parameters:  tc.Parameters,
+		},
+		url:    tc.URL,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Definition returns the function definition offered to models
+func (t *HTTPTool) Definition() provider.Function {
+	return t.def
+}
+
+// Execute POSTs the arguments to the tool endpoint
+func (t *HTTPTool) Execute(ctx context.Context, arguments string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader([]byte(arguments)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("tool %s: %w", t.def.Name, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResultBytes))
+	if err != nil {
+		return "", fmt.Errorf("tool %s: read result: %w", t.def.Name, err)
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("tool %s returned status %d", t.def.Name, resp.StatusCode)
+	}
+	return string(body), nil
+}
@@ -0,0 +1,205 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/provider"
+)
+
+// scriptedProvider answers each Generate or StreamGenerate call with the
+// next scripted message and records the requests it received
+type scriptedProvider struct {
+	replies  []provider.Message
+	requests []*provider.StandardRequest
+}
+
+func (s *scriptedProvider) Generate(ctx context.Context, req *provider.GenerateRequest) (*provider.GenerateResponse, error) {
+	cp := *req.StandardRequest
+	cp.Messages = append([]provider.Message(nil), req.Messages...)
+	s.requests = append(s.requests, &cp)
+	msg := s.replies[0]
+	s.replies = s.replies[1:]
+	return &provider.GenerateResponse{StandardResponse: provider.CreateStandardResponse("r", req.Model,
+		[]provider.Choice{{Message: &msg}}, provider.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12})}, nil
+}
+
+// StreamGenerate streams the reply a word or argument at a time, ending
+// with a usage chunk
+func (s *scriptedProvider) StreamGenerate(ctx context.Context, req *provider.GenerateRequest) (io.ReadCloser, error) {
+	resp, err := s.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	msg := resp.Choices[0].Message
+	var deltas []*provider.Message
+	for _, word := range strings.SplitAfter(msg.Content, " ") {
+		if word != "" {
+			deltas = append(deltas, &provider.Message{Content: word})
+		}
+	}
+	if fc := msg.FunctionCall; fc != nil {
+		deltas = append(deltas,
+			&provider.Message{FunctionCall: &provider.FunctionCall{Name: fc.Name}},
+			&provider.Message{FunctionCall: &provider.FunctionCall{Arguments: fc.Arguments}})
+	}
+	var b strings.Builder
+	for _, d := range deltas {
+		raw, _ := json.Marshal(provider.StreamChunk{Choices: []provider.Choice{{Delta: d}}})
+		fmt.Fprintf(&b, "data: %s\n\n", raw)
+	}
+	raw, _ := json.Marshal(provider.StreamChunk{Choices: []provider.Choice{}, Usage: &resp.Usage})
+	fmt.Fprintf(&b, "data: %s\n\ndata: [DONE]\n\n", raw)
+	return io.NopCloser(strings.NewReader(b.String())), nil
+}
+
+func (s *scriptedProvider) Embed(ctx context.Context, req *provider.EmbeddingRequest) (*provider.EmbeddingResponse, error) {
+	return nil, provider.ErrEmbeddingsUnsupported
+}
+
+func (s *scriptedProvider) Transcribe(ctx context.Context, req *provider.TranscriptionRequest) (*provider.TranscriptionResponse, error) {
+	return nil, provider.ErrTranscriptionUnsupported
+}
+
+func (s *scriptedProvider) GetCapabilities() provider.ProviderCapabilities {
+	return provider.ProviderCapabilities{}
+}
+
+func (s *scriptedProvider) GetInfo() provider.ProviderInfo {
+	return provider.ProviderInfo{Name: "scripted"}
+}
+
+func (s *scriptedProvider) Close() error { return nil }
+
+func callMsg(name, args string) provider.Message {
+	return provider.Message{Role: provider.RoleAssistant, FunctionCall: &provider.FunctionCall{Name: name, Arguments: args}}
+}
+
+func TestCacheKeyNormalizesJSON(t *testing.T) {
+	if CacheKey("lookup", `{"id": 1}`) != CacheKey("lookup", `{"id":1}`) {
+		t.Error("Expected whitespace-insensitive keys for JSON arguments")
+	}
+	if CacheKey("lookup", `{"id":1}`) == CacheKey("search", `{"id":1}`) {
+		t.Error("Expected keys to differ per tool")
+	}
+}
+
+func TestResultCacheTTL(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewResultCache()
+	c.now = func() time.Time { return now }
+
+	c.Put("k", "v", time.Minute)
+	if v, ok := c.Get("k"); !ok || v != "v" {
+		t.Errorf("Expected cached result, got %q, %v", v, ok)
+	}
+	now = now.Add(time.Minute)
+	if _, ok := c.Get("k"); ok {
+		t.Error("Expected result to expire")
+	}
+}
+
+func TestRuntimeRunCachesDeterministicTools(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"echo":%s}`, body)
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{}
+	cfg.Tools = []config.ToolConfig{{Name: "lookup", URL: srv.URL, CacheTTL: time.Minute}}
+	m := metrics.NewRegistry()
+	rt := NewRuntime(cfg, m)
+
+	p := &scriptedProvider{replies: []provider.Message{
+		callMsg("lookup", `{"id": 7}`),
+		callMsg("lookup", `{"id":7}`),
+		{Role: provider.RoleAssistant, Content: "done"},
+	}}
+	req := &provider.StandardRequest{Model: "m", Messages: []provider.Message{{Role: provider.RoleUser, Content: "go"}}}
+
+	resp, err := rt.Run(context.Background(), p, req, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "done" || resp.Usage.TotalTokens != 36 {
+		t.Errorf("Unexpected final response: %+v", resp)
+	}
+	if hits != 1 {
+		t.Errorf("Expected the repeated call to be served from cache, got %d hits", hits)
+	}
+	if len(req.Messages) != 1 {
+		t.Error("Run must not modify the caller's request")
+	}
+
+	last := p.requests[2]
+	if len(last.Messages) != 5 || last.Messages[2].Content != `{"echo":{"id": 7}}` {
+		t.Errorf("Unexpected transcript: %+v", last.Messages)
+	}
+	if len(last.Functions) != 1 || last.Functions[0].Name != "lookup" {
+		t.Errorf("Expected server tool definition to be offered, got %+v", last.Functions)
+	}
+	if v := m.Counter("letllm_tool_calls_total", "", "tool", "outcome").Value("lookup", OutcomeCached); v != 1 {
+		t.Errorf("Expected one cached call, got %v", v)
+	}
+}
+
+func TestRuntimeRunReturnsClientCalls(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Tools = []config.ToolConfig{{Name: "lookup", URL: "http://unused.invalid"}}
+	rt := NewRuntime(cfg, metrics.NewRegistry())
+
+	// The client defines its own lookup; it shadows the server tool
+	p := &scriptedProvider{replies: []provider.Message{callMsg("lookup", `{}`)}}
+	resp, err := rt.Run(context.Background(), p, &provider.StandardRequest{
+		Model:     "m",
+		Messages:  []provider.Message{{Role: provider.RoleUser, Content: "go"}},
+		Functions: []provider.Function{{Name: "lookup"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if resp.Choices[0].Message.FunctionCall == nil || len(p.requests) != 1 {
+		t.Errorf("Expected the client-side call to be returned, got %+v", resp.Choices[0].Message)
+	}
+}
+
+func TestRuntimeRunReportsProgress(t *testing.T) {
+	rt := NewRuntime(&config.Config{}, metrics.NewRegistry())
+	rt.Register(&staticTool{name: "search"}, time.Minute)
+
+	p := &scriptedProvider{replies: []provider.Message{
+		callMsg("search", `{"q":"x"}`),
+		callMsg("search", `{"q":"x"}`),
+		{Role: provider.RoleAssistant, Content: "done"},
+	}}
+	var events []Progress
+	_, err := rt.Run(context.Background(), p, &provider.StandardRequest{
+		Model:    "m",
+		Messages: []provider.Message{{Role: provider.RoleUser, Content: "go"}},
+	}, func(ev Progress) { events = append(events, ev) })
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(events) != 4 {
+		t.Fatalf("Expected start and finish events for two calls, got %+v", events)
+	}
+	if events[0].Status != StatusStarted || events[1].Status != StatusCompleted || events[1].Cached {
+		t.Errorf("Unexpected first call events: %+v", events[:2])
+	}
+	if events[3].Iteration != 2 || !events[3].Cached {
+		t.Errorf("Expected second call to be served from cache: %+v", events[3])
+	}
+}
@@ -0,0 +1,76 @@
+package tracing
+
+import "sync"
+
+// MemoryStore keeps the most recent traces in memory
+type MemoryStore struct {
+	mu     sync.RWMutex
+	keep   int
+	traces []*Trace
+}
+
+// NewMemoryStore creates a store holding up to keep traces (default 1000)
+func NewMemoryStore(keep int) *MemoryStore {
+	if keep <= 0 {
+		keep = defaultKeep
+	}
+	return &MemoryStore{keep: keep}
+}
+
+// Add appends a trace, dropping the oldest once at capacity
+func (s *MemoryStore) Add(t *Trace) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.traces) >= s.keep {
+		s.traces = append(s.traces[:0], s.traces[len(s.traces)-s.keep+1:]...)
+	}
+	cp := *t
+	s.traces = append(s.traces, &cp)
+	return nil
+}
+
+// Get returns a trace
+func (s *MemoryStore) Get(id string) (*Trace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.traces {
+		if t.ID == id {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// List returns up to limit traces newest first, without their events
+func (s *MemoryStore) List(limit int) ([]*Trace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := []*Trace{}
+	for i := len(s.traces) - 1; i >= 0 && len(out) < limit; i-- {
+		cp := *s.traces[i]
+		cp.Reads, cp.Writes, cp.Flushes = nil, nil, nil
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+// Erase deletes every trace of a key
+func (s *MemoryStore) Erase(keyID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.traces[:0]
+	for _, t := range s.traces {
+		if t.KeyID != keyID {
+			kept = append(kept, t)
+		}
+	}
+	n := len(s.traces) - len(kept)
+	clear(s.traces[len(kept):])
+	s.traces = kept
+	return n, nil
+}
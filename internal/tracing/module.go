@@ -0,0 +1,19 @@
+package tracing
+
+import (
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+	"go.uber.org/fx"
+)
+
+// Module provides the stream trace Store and the Tracer.
+var Module = fx.Provide(NewStore, NewTracer)
+
+// NewStore keeps traces in the shared database when one is configured, and
+// in memory otherwise
+func NewStore(cfg *config.Config, db *storage.DB) Store {
+	if db == nil {
+		return NewMemoryStore(cfg.Tracing.Keep)
+	}
+	return NewSQLStore(db, cfg.Tracing.Keep)
+}
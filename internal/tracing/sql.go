@@ -0,0 +1,119 @@
+package tracing
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+)
+
+// SQLStore is a Store backed by the stream_traces table of the shared
+// database. Events are kept as one JSON document per trace.
+type SQLStore struct {
+	db   *storage.DB
+	keep int
+}
+
+// NewSQLStore creates a store on db keeping the latest keep traces
+// (default 1000)
+func NewSQLStore(db *storage.DB, keep int) *SQLStore {
+	if keep <= 0 {
+		keep = defaultKeep
+	}
+	return &SQLStore{db: db, keep: keep}
+}
+
+// events is the stored form of a trace's timeline
+type events struct {
+	Reads   []Event `json:"reads,omitempty"`
+	Writes  []Event `json:"writes,omitempty"`
+	Flushes []int64 `json:"flushes_us,omitempty"`
+}
+
+// Add inserts a trace and drops those past the retention limit. A request
+// ID reused by a caller keeps its first trace.
+func (s *SQLStore) Add(t *Trace) error {
+	ev, err := json.Marshal(events{Reads: t.Reads, Writes: t.Writes, Flushes: t.Flushes})
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(s.db.Rebind(`INSERT INTO stream_traces
+		(id, key_id, started_at, endpoint, provider, model, upstream_headers_us, first_byte_us, duration_us, bytes, error, truncated, events)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`),
+		t.ID, t.KeyID, t.StartedAt.UTC(), t.Endpoint, t.Provider, t.Model, t.UpstreamHeadersUS, t.FirstByteUS, t.DurationUS,
+		t.Bytes, t.Error, t.Truncated, string(ev))
+	if err != nil {
+		return fmt.Errorf("add trace %s: %w", t.ID, err)
+	}
+	_, err = s.db.Exec(s.db.Rebind(`DELETE FROM stream_traces WHERE started_at <
+		(SELECT started_at FROM stream_traces ORDER BY started_at DESC LIMIT 1 OFFSET ?)`), s.keep-1)
+	if err != nil {
+		return fmt.Errorf("prune traces: %w", err)
+	}
+	return nil
+}
+
+const traceColumns = "id, started_at, endpoint, provider, model, upstream_headers_us, first_byte_us, duration_us, bytes, error, truncated"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTrace(row scanner, extra ...interface{}) (*Trace, error) {
+	var t Trace
+	dest := append([]interface{}{&t.ID, &t.StartedAt, &t.Endpoint, &t.Provider, &t.Model, &t.UpstreamHeadersUS,
+		&t.FirstByteUS, &t.DurationUS, &t.Bytes, &t.Error, &t.Truncated}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Get returns a trace
+func (s *SQLStore) Get(id string) (*Trace, error) {
+	var raw string
+	t, err := scanTrace(s.db.QueryRow(s.db.Rebind("SELECT "+traceColumns+", events FROM stream_traces WHERE id = ?"), id), &raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get trace %s: %w", id, err)
+	}
+	var ev events
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+		return nil, fmt.Errorf("get trace %s: %w", id, err)
+	}
+	t.Reads, t.Writes, t.Flushes = ev.Reads, ev.Writes, ev.Flushes
+	return t, nil
+}
+
+// List returns up to limit traces newest first, without their events
+func (s *SQLStore) List(limit int) ([]*Trace, error) {
+	rows, err := s.db.Query(s.db.Rebind("SELECT "+traceColumns+" FROM stream_traces ORDER BY started_at DESC, id DESC LIMIT ?"), limit)
+	if err != nil {
+		return nil, fmt.Errorf("list traces: %w", err)
+	}
+	defer rows.Close()
+
+	out := []*Trace{}
+	for rows.Next() {
+		t, err := scanTrace(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// Erase deletes every trace of a key
+func (s *SQLStore) Erase(keyID string) (int, error) {
+	res, err := s.db.Exec(s.db.Rebind("DELETE FROM stream_traces WHERE key_id = ?"), keyID)
+	if err != nil {
+		return 0, fmt.Errorf("erase traces of %s: %w", keyID, err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
@@ -0,0 +1,213 @@
+package tracing
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// ErrNotFound is returned for unknown trace IDs
+var ErrNotFound = errors.New("trace not found")
+
+// defaultKeep is the number of traces retained when tracing.keep is unset
+const defaultKeep = 1000
+
+// Event is one read or write of a traced stream
+type Event struct {
+	// Microseconds since the request started
+	AtUS  int64 `json:"at_us"`
+	Bytes int   `json:"bytes"`
+}
+
+// Trace is the timeline of one sampled streaming request. Only sizes and
+// timings are recorded, never content.
+type Trace struct {
+	// The request ID
+	ID string `json:"id"`
+	// KeyID is the virtual key the stream was made with, kept only so that
+	// erasing the key erases its traces
+	KeyID     string    `json:"-"`
+	Endpoint  string    `json:"endpoint"`
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	StartedAt time.Time `json:"started_at"`
+	// Time until the upstream answered with response headers
+	UpstreamHeadersUS int64 `json:"upstream_headers_us"`
+	// Time until the first byte of the upstream body
+	FirstByteUS int64  `json:"first_byte_us"`
+	DurationUS  int64  `json:"duration_us"`
+	Bytes       int64  `json:"bytes"`
+	Error       string `json:"error,omitempty"`
+	// Set when the stream had more events than tracing.max_events
+	Truncated bool `json:"truncated,omitempty"`
+	// Reads of the upstream body and writes and flushes to the client; List
+	// leaves them out
+	Reads   []Event `json:"reads,omitempty"`
+	Writes  []Event `json:"writes,omitempty"`
+	Flushes []int64 `json:"flushes_us,omitempty"`
+}
+
+// Store persists traces
+type Store interface {
+	Add(t *Trace) error
+	Get(id string) (*Trace, error)
+	// List returns up to limit traces newest first, without their events
+	List(limit int) ([]*Trace, error)
+	// Erase deletes every trace of a key and returns how many were removed
+	Erase(keyID string) (int, error)
+}
+
+// Tracer samples streaming requests for tracing
+type Tracer struct {
+	store     Store
+	rate      float64
+	maxEvents int
+	now       func() time.Time
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewTracer creates a tracer sampling tracing.sample_rate of streams
+func NewTracer(cfg *config.Config, store Store) *Tracer {
+	return &Tracer{
+		store:     store,
+		rate:      cfg.Tracing.SampleRate,
+		maxEvents: cfg.Tracing.MaxEvents,
+		now:       time.Now,
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Start returns a recorder for a stream made with keyID if it is sampled,
+// and nil otherwise. A nil Tracer never samples.
+func (t *Tracer) Start(id, keyID, endpoint, provider, model string) *Recorder {
+	if t == nil || t.rate <= 0 {
+		return nil
+	}
+	if t.rate < 1 {
+		t.mu.Lock()
+		skip := t.rand.Float64() >= t.rate
+		t.mu.Unlock()
+		if skip {
+			return nil
+		}
+	}
+	start := t.now()
+	return &Recorder{
+		tracer: t,
+		start:  start,
+		trace:  Trace{ID: id, KeyID: keyID, Endpoint: endpoint, Provider: provider, Model: model, StartedAt: start},
+	}
+}
+
+// Recorder collects the timeline of one stream. Its methods may be called
+// on a nil Recorder, which records nothing, so callers need not check
+// whether the stream was sampled.
+type Recorder struct {
+	tracer *Tracer
+	start  time.Time
+
+	mu     sync.Mutex
+	events int
+	trace  Trace
+}
+
+func (r *Recorder) since() int64 {
+	return r.tracer.now().Sub(r.start).Microseconds()
+}
+
+// room counts an event, reporting whether it fits under the cap
+func (r *Recorder) room() bool {
+	if r.tracer.maxEvents > 0 && r.events >= r.tracer.maxEvents {
+		r.trace.Truncated = true
+		return false
+	}
+	r.events++
+	return true
+}
+
+// Upstream marks the upstream's response headers
+func (r *Recorder) Upstream() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trace.UpstreamHeadersUS = r.since()
+}
+
+// WrapUpstream returns rc timing each read of the upstream body
+func (r *Recorder) WrapUpstream(rc io.ReadCloser) io.ReadCloser {
+	if r == nil {
+		return rc
+	}
+	return &tracedBody{ReadCloser: rc, rec: r}
+}
+
+// Write records n bytes written to the client
+func (r *Recorder) Write(n int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.room() {
+		r.trace.Writes = append(r.trace.Writes, Event{AtUS: r.since(), Bytes: n})
+	}
+}
+
+// Flush records a flush to the client
+func (r *Recorder) Flush() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.room() {
+		r.trace.Flushes = append(r.trace.Flushes, r.since())
+	}
+}
+
+// Finish stores the trace; err is why the stream ended early, if it did
+func (r *Recorder) Finish(err error) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	t := r.trace
+	r.mu.Unlock()
+	t.DurationUS = r.since()
+	if err != nil {
+		t.Error = err.Error()
+	}
+	return r.tracer.store.Add(&t)
+}
+
+// tracedBody records reads of an upstream body
+type tracedBody struct {
+	io.ReadCloser
+	rec *Recorder
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		r := b.rec
+		r.mu.Lock()
+		at := r.since()
+		if r.trace.Bytes == 0 {
+			r.trace.FirstByteUS = at
+		}
+		r.trace.Bytes += int64(n)
+		if r.room() {
+			r.trace.Reads = append(r.trace.Reads, Event{AtUS: at, Bytes: n})
+		}
+		r.mu.Unlock()
+	}
+	return n, err
+}
@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/storage/storagetest"
+)
+
+func TestRecorder(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Tracing.SampleRate = 1
+	cfg.Tracing.MaxEvents = 4
+	store := NewMemoryStore(10)
+	tracer := NewTracer(cfg, store)
+	clock := time.Unix(1000, 0)
+	tracer.now = func() time.Time { clock = clock.Add(time.Millisecond); return clock }
+
+	rec := tracer.Start("req-1", "alice", "/v1/chat/completions", "demo", "demo-chat")
+	rec.Upstream()
+	body := rec.WrapUpstream(io.NopCloser(strings.NewReader("hello world")))
+	buf := make([]byte, 4)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			rec.Write(n)
+		}
+		if err != nil {
+			break
+		}
+	}
+	rec.Flush()
+	if err := rec.Finish(errors.New("client went away")); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := store.Get("req-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.UpstreamHeadersUS != 1000 || tr.FirstByteUS != 2000 || tr.Bytes != 11 || tr.Error != "client went away" {
+		t.Errorf("Unexpected timings %+v", tr)
+	}
+	// Three reads and three writes exceed the four allowed events
+	if !tr.Truncated || len(tr.Reads)+len(tr.Writes)+len(tr.Flushes) != 4 {
+		t.Errorf("Expected the timeline capped at 4 events, got %+v", tr)
+	}
+
+	var nilRec *Recorder
+	nilRec.Write(1)
+	if err := nilRec.Finish(nil); err != nil {
+		t.Error(err)
+	}
+	cfg.Tracing.SampleRate = 0
+	if NewTracer(cfg, store).Start("req-2", "", "", "", "") != nil {
+		t.Error("Expected no stream sampled at rate 0")
+	}
+}
+
+func TestStores(t *testing.T) {
+	for name, s := range map[string]Store{
+		"memory": NewMemoryStore(2),
+		"sql":    NewSQLStore(storagetest.Open(t), 2),
+	} {
+		start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, id := range []string{"a", "b", "c"} {
+			tr := &Trace{ID: id, KeyID: "key-" + id, StartedAt: start.Add(time.Duration(i) * time.Second), Provider: "demo", Bytes: 5,
+				Reads: []Event{{AtUS: 10, Bytes: 5}}, Writes: []Event{{AtUS: 12, Bytes: 5}}, Flushes: []int64{13}}
+			if err := s.Add(tr); err != nil {
+				t.Fatalf("%s: Add: %v", name, err)
+			}
+		}
+
+		if _, err := s.Get("a"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected the oldest trace dropped, got %v", name, err)
+		}
+		tr, err := s.Get("c")
+		if err != nil || len(tr.Reads) != 1 || tr.Writes[0].AtUS != 12 || tr.Flushes[0] != 13 || tr.Provider != "demo" {
+			t.Errorf("%s: Get returned %+v, %v", name, tr, err)
+		}
+		list, err := s.List(10)
+		if err != nil || len(list) != 2 || list[0].ID != "c" || list[1].ID != "b" || list[0].Reads != nil {
+			t.Errorf("%s: List returned %+v, %v", name, list, err)
+		}
+
+		if n, err := s.Erase("key-c"); err != nil || n != 1 {
+			t.Errorf("%s: expected one trace erased, got %d, %v", name, n, err)
+		}
+		if _, err := s.Get("c"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected the erased trace gone, got %v", name, err)
+		}
+		if _, err := s.Get("b"); err != nil {
+			t.Errorf("%s: expected other keys' traces kept, got %v", name, err)
+		}
+	}
+}
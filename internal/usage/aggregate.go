@@ -0,0 +1,193 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dimensions records can be grouped by in an aggregate export
+const (
+	DimProvider   = "provider"
+	DimModel      = "model"
+	DimKey        = "key"
+	DimExperiment = "experiment"
+	DimVariant    = "variant"
+)
+
+// Histogram bucket upper bounds; the last bucket is unbounded
+var (
+	LatencyBucketsMS = []int64{100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+	TokenBuckets     = []int64{100, 500, 1000, 5000, 10000, 50000, 100000}
+)
+
+// AggregateOptions controls how records are grouped and suppressed
+type AggregateOptions struct {
+	// Bucket is the time window of each group, e.g. an hour or a day
+	Bucket time.Duration
+	// GroupBy lists dimensions to group by (DimProvider, DimModel, DimKey,
+	// DimExperiment, DimVariant)
+	GroupBy []string
+	// MinGroupSize suppresses groups with fewer requests, so that no row can
+	// be traced back to an individual request or user
+	MinGroupSize int
+}
+
+// Validate checks the options
+func (o AggregateOptions) Validate() error {
+	if o.Bucket <= 0 {
+		return fmt.Errorf("bucket must be positive")
+	}
+	if o.MinGroupSize < 1 {
+		return fmt.Errorf("min group size must be at least 1")
+	}
+	for _, d := range o.GroupBy {
+		switch d {
+		case DimProvider, DimModel, DimKey, DimExperiment, DimVariant:
+		default:
+			return fmt.Errorf("unknown group_by dimension %q", d)
+		}
+	}
+	return nil
+}
+
+// Histogram counts values per bucket; Counts[i] holds values up to
+// Bounds[i], and the final count holds values above every bound
+type Histogram struct {
+	Bounds []int64 `json:"bounds"`
+	Counts []int   `json:"counts"`
+}
+
+func newHistogram(bounds []int64) *Histogram {
+	return &Histogram{Bounds: bounds, Counts: make([]int, len(bounds)+1)}
+}
+
+func (h *Histogram) observe(v int64) {
+	i := sort.Search(len(h.Bounds), func(i int) bool { return v <= h.Bounds[i] })
+	h.Counts[i]++
+}
+
+// Group is one aggregated row; dimensions not grouped by are empty
+type Group struct {
+	WindowStart      time.Time `json:"window_start"`
+	Provider         string    `json:"provider,omitempty"`
+	Model            string    `json:"model,omitempty"`
+	KeyID            string    `json:"key_id,omitempty"`
+	Experiment       string    `json:"experiment,omitempty"`
+	Variant          string    `json:"variant,omitempty"`
+	Requests         int       `json:"requests"`
+	Errors           int       `json:"errors"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	// Breakdowns, summed over the records that report them
+	CachedPromptTokens int64      `json:"cached_prompt_tokens"`
+	ReasoningTokens    int64      `json:"reasoning_tokens"`
+	AudioTokens        int64      `json:"audio_tokens"`
+	LatencyMS          *Histogram `json:"latency_ms"`
+	TotalTokens        *Histogram `json:"total_tokens"`
+}
+
+// Aggregate is an export containing only grouped counts and histograms
+type Aggregate struct {
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	Bucket       string    `json:"bucket"`
+	GroupBy      []string  `json:"group_by"`
+	MinGroupSize int       `json:"min_group_size"`
+	Groups       []*Group  `json:"groups"`
+	// Requests in groups below MinGroupSize, reported only as a total
+	SuppressedGroups   int `json:"suppressed_groups"`
+	SuppressedRequests int `json:"suppressed_requests"`
+}
+
+// AggregateRecords groups records into time windows and the requested
+// dimensions, dropping groups smaller than MinGroupSize
+func AggregateRecords(records []*Record, from, to time.Time, opts AggregateOptions) (*Aggregate, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	by := make(map[string]bool, len(opts.GroupBy))
+	for _, d := range opts.GroupBy {
+		by[d] = true
+	}
+
+	groups := make(map[string]*Group)
+	for _, r := range records {
+		g := Group{WindowStart: r.Time.UTC().Truncate(opts.Bucket)}
+		if by[DimProvider] {
+			g.Provider = r.Provider
+		}
+		if by[DimModel] {
+			g.Model = r.Model
+		}
+		if by[DimKey] {
+			g.KeyID = r.KeyID
+		}
+		if by[DimExperiment] {
+			g.Experiment = r.Experiment
+		}
+		if by[DimVariant] {
+			g.Variant = r.Variant
+		}
+
+		key := strings.Join([]string{g.WindowStart.Format(time.RFC3339), g.Provider, g.Model, g.KeyID, g.Experiment, g.Variant}, "\x00")
+		agg, ok := groups[key]
+		if !ok {
+			agg = &g
+			agg.LatencyMS = newHistogram(LatencyBucketsMS)
+			agg.TotalTokens = newHistogram(TokenBuckets)
+			groups[key] = agg
+		}
+
+		agg.Requests++
+		if r.Status >= 400 {
+			agg.Errors++
+		}
+		agg.PromptTokens += int64(r.PromptTokens)
+		agg.CompletionTokens += int64(r.CompletionTokens)
+		agg.CachedPromptTokens += int64(r.CachedPromptTokens)
+		agg.ReasoningTokens += int64(r.ReasoningTokens)
+		agg.AudioTokens += int64(r.AudioTokens)
+		agg.LatencyMS.observe(r.LatencyMS)
+		agg.TotalTokens.observe(int64(r.PromptTokens + r.CompletionTokens))
+	}
+
+	out := &Aggregate{
+		From:         from.UTC(),
+		To:           to.UTC(),
+		Bucket:       opts.Bucket.String(),
+		GroupBy:      opts.GroupBy,
+		MinGroupSize: opts.MinGroupSize,
+		Groups:       []*Group{},
+	}
+	for _, g := range groups {
+		if g.Requests < opts.MinGroupSize {
+			out.SuppressedGroups++
+			out.SuppressedRequests += g.Requests
+			continue
+		}
+		out.Groups = append(out.Groups, g)
+	}
+	sort.Slice(out.Groups, func(i, j int) bool {
+		a, b := out.Groups[i], out.Groups[j]
+		if !a.WindowStart.Equal(b.WindowStart) {
+			return a.WindowStart.Before(b.WindowStart)
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		if a.KeyID != b.KeyID {
+			return a.KeyID < b.KeyID
+		}
+		if a.Experiment != b.Experiment {
+			return a.Experiment < b.Experiment
+		}
+		return a.Variant < b.Variant
+	})
+	return out, nil
+}
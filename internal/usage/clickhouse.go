@@ -0,0 +1,197 @@
+package usage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// clickHouseTimeout bounds each request to ClickHouse
+const clickHouseTimeout = 30 * time.Second
+
+// clickHouseTime is the format of DateTime64(3) query parameters
+const clickHouseTime = "2006-01-02 15:04:05.000"
+
+// ClickHouseStore is a Store backed by a ClickHouse table, written to and
+// read over ClickHouse's HTTP interface
+type ClickHouseStore struct {
+	cfg    config.ClickHouseConfig
+	client *http.Client
+}
+
+// clickHouseRow is a record as a row of the table
+type clickHouseRow struct {
+	TS                 time.Time `json:"ts"`
+	KeyID              string    `json:"key_id"`
+	Provider           string    `json:"provider"`
+	Model              string    `json:"model"`
+	Status             int       `json:"status"`
+	LatencyMS          int64     `json:"latency_ms"`
+	TTFTMS             int64     `json:"ttft_ms"`
+	PromptTokens       int       `json:"prompt_tokens"`
+	CompletionTokens   int       `json:"completion_tokens"`
+	CachedPromptTokens int       `json:"cached_prompt_tokens"`
+	ReasoningTokens    int       `json:"reasoning_tokens"`
+	AudioTokens        int       `json:"audio_tokens"`
+	Stream             bool      `json:"stream"`
+	OriginKeyID        string    `json:"origin_key_id"`
+	Experiment         string    `json:"experiment"`
+	Variant            string    `json:"variant"`
+	CostUSD            *float64  `json:"cost_usd"`
+}
+
+const clickHouseColumns = `ts, key_id, provider, model, status, latency_ms, ttft_ms, prompt_tokens, completion_tokens,
+	cached_prompt_tokens, reasoning_tokens, audio_tokens, stream, origin_key_id, experiment, variant, cost_usd`
+
+// NewClickHouseStore connects to the table cfg locates, creating it when
+// missing
+func NewClickHouseStore(cfg config.ClickHouseConfig) (*ClickHouseStore, error) {
+	s := &ClickHouseStore{cfg: cfg, client: &http.Client{Timeout: clickHouseTimeout}}
+	_, err := s.exec(`CREATE TABLE IF NOT EXISTS `+cfg.Table+` (
+		ts DateTime64(3, 'UTC'),
+		key_id String,
+		provider LowCardinality(String),
+		model LowCardinality(String),
+		status UInt16,
+		latency_ms Int64,
+		ttft_ms Int64,
+		prompt_tokens Int64,
+		completion_tokens Int64,
+		cached_prompt_tokens Int64,
+		reasoning_tokens Int64,
+		audio_tokens Int64,
+		stream Bool,
+		origin_key_id String,
+		experiment String,
+		variant String,
+		cost_usd Nullable(Float64)
+	) ENGINE = MergeTree ORDER BY ts`, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create clickhouse table %s: %w", cfg.Table, err)
+	}
+	return s, nil
+}
+
+// Add inserts a record
+func (s *ClickHouseStore) Add(r *Record) error {
+	return s.AddBatch([]*Record{r})
+}
+
+// AddBatch inserts records in one request
+func (s *ClickHouseStore) AddBatch(records []*Record) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, r := range records {
+		if err := enc.Encode(clickHouseRow{
+			TS: r.Time.UTC(), KeyID: r.KeyID, Provider: r.Provider, Model: r.Model, Status: r.Status,
+			LatencyMS: r.LatencyMS, TTFTMS: r.TTFTMS, PromptTokens: r.PromptTokens, CompletionTokens: r.CompletionTokens,
+			CachedPromptTokens: r.CachedPromptTokens, ReasoningTokens: r.ReasoningTokens, AudioTokens: r.AudioTokens,
+			Stream: r.Stream, OriginKeyID: r.OriginKeyID, Experiment: r.Experiment, Variant: r.Variant, CostUSD: r.CostUSD,
+		}); err != nil {
+			return err
+		}
+	}
+	if _, err := s.exec(`INSERT INTO `+s.cfg.Table+` FORMAT JSONEachRow`, nil, &body); err != nil {
+		return fmt.Errorf("add usage records: %w", err)
+	}
+	return nil
+}
+
+// Query returns records with from <= Time < to, oldest first
+func (s *ClickHouseStore) Query(from, to time.Time) ([]*Record, error) {
+	out, err := s.exec(`SELECT `+clickHouseColumns+` FROM `+s.cfg.Table+`
+		WHERE ts >= {from:DateTime64(3, 'UTC')} AND ts < {to:DateTime64(3, 'UTC')} ORDER BY ts FORMAT JSONEachRow`,
+		url.Values{"param_from": {from.UTC().Format(clickHouseTime)}, "param_to": {to.UTC().Format(clickHouseTime)}}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("query usage records: %w", err)
+	}
+	var records []*Record
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		var row clickHouseRow
+		if err := json.Unmarshal(sc.Bytes(), &row); err != nil {
+			return nil, fmt.Errorf("query usage records: %w", err)
+		}
+		records = append(records, &Record{
+			Time: row.TS, KeyID: row.KeyID, Provider: row.Provider, Model: row.Model, Status: row.Status,
+			LatencyMS: row.LatencyMS, TTFTMS: row.TTFTMS, PromptTokens: row.PromptTokens, CompletionTokens: row.CompletionTokens,
+			CachedPromptTokens: row.CachedPromptTokens, ReasoningTokens: row.ReasoningTokens, AudioTokens: row.AudioTokens,
+			Stream: row.Stream, OriginKeyID: row.OriginKeyID, Experiment: row.Experiment, Variant: row.Variant, CostUSD: row.CostUSD,
+		})
+	}
+	return records, sc.Err()
+}
+
+// Erase deletes every record of a key. The deletion is a mutation that
+// ClickHouse applies before answering.
+func (s *ClickHouseStore) Erase(keyID string) (int, error) {
+	params := url.Values{"param_key": {keyID}}
+	out, err := s.exec(`SELECT count() FROM `+s.cfg.Table+` WHERE key_id = {key:String}`, params, nil)
+	if err != nil {
+		return 0, fmt.Errorf("erase usage records of %s: %w", keyID, err)
+	}
+	var n int
+	if _, err := fmt.Sscan(string(out), &n); err != nil {
+		return 0, fmt.Errorf("erase usage records of %s: %w", keyID, err)
+	}
+	params.Set("mutations_sync", "1")
+	if _, err := s.exec(`ALTER TABLE `+s.cfg.Table+` DELETE WHERE key_id = {key:String}`, params, nil); err != nil {
+		return 0, fmt.Errorf("erase usage records of %s: %w", keyID, err)
+	}
+	return n, nil
+}
+
+// exec runs query with params and body, for INSERT, as its data, and
+// returns the response body
+func (s *ClickHouseStore) exec(query string, params url.Values, body io.Reader) ([]byte, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	if s.cfg.Database != "" {
+		params.Set("database", s.cfg.Database)
+	}
+	// Times are read as ISO 8601 and 64-bit integers written unquoted, so
+	// rows decode as JSON
+	params.Set("date_time_input_format", "best_effort")
+	params.Set("date_time_output_format", "iso")
+	params.Set("output_format_json_quote_64bit_integers", "0")
+
+	if body == nil {
+		body = strings.NewReader(query)
+	} else {
+		params.Set("query", query)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), clickHouseTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(s.cfg.URL, "/")+"/?"+params.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	if s.cfg.Username != "" {
+		req.Header.Set("X-ClickHouse-User", s.cfg.Username)
+		req.Header.Set("X-ClickHouse-Key", s.cfg.Password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("clickhouse: %s", strings.TrimSpace(string(out)))
+	}
+	return out, nil
+}
@@ -0,0 +1,139 @@
+package usage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+)
+
+// fakeClickHouse answers the statements ClickHouseStore sends, keeping
+// inserted rows as they were sent
+func fakeClickHouse(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var rows []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("X-ClickHouse-User") != "gateway" || r.Header.Get("X-ClickHouse-Key") != "secret" {
+			http.Error(w, "Authentication failed", http.StatusUnauthorized)
+			return
+		}
+		if got := r.URL.Query().Get("database"); got != "letllm" {
+			http.Error(w, "Unknown database "+got, http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		query := r.URL.Query().Get("query")
+		if query == "" {
+			query = string(body)
+		}
+		switch {
+		case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS usage_records"):
+		case strings.HasPrefix(query, "INSERT INTO usage_records"):
+			rows = append(rows, strings.Split(strings.TrimSpace(string(body)), "\n")...)
+		case strings.HasPrefix(query, "SELECT count()"):
+			fmt.Fprintf(w, "%d\n", len(rows))
+		case strings.HasPrefix(query, "ALTER TABLE usage_records DELETE"):
+			if r.URL.Query().Get("mutations_sync") != "1" || r.URL.Query().Get("param_key") != "team-a" {
+				http.Error(w, "unexpected mutation", http.StatusBadRequest)
+				return
+			}
+			rows = nil
+		case strings.HasPrefix(query, "SELECT"):
+			if r.URL.Query().Get("param_from") != "2025-03-01 10:00:00.000" {
+				http.Error(w, "unexpected range "+r.URL.Query().Get("param_from"), http.StatusBadRequest)
+				return
+			}
+			for _, row := range rows {
+				fmt.Fprintln(w, row)
+			}
+		default:
+			http.Error(w, "Syntax error: "+query, http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &rows
+}
+
+func TestClickHouseStore(t *testing.T) {
+	srv, rows := fakeClickHouse(t)
+	s, err := NewClickHouseStore(config.ClickHouseConfig{URL: srv.URL, Database: "letllm", Table: "usage_records", Username: "gateway", Password: "secret"})
+	if err != nil {
+		t.Fatalf("NewClickHouseStore failed: %v", err)
+	}
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cost := 0.0025
+	err = s.AddBatch([]*Record{
+		{Time: base, KeyID: "team-a", Provider: "openai", Model: "gpt-4o", Status: 200, LatencyMS: 420, TTFTMS: 180,
+			PromptTokens: 10, CompletionTokens: 5, Stream: true, CostUSD: &cost},
+		{Time: base.Add(time.Minute), KeyID: "team-a", Model: "gpt-4o", Status: 429},
+	})
+	if err != nil {
+		t.Fatalf("AddBatch failed: %v", err)
+	}
+	if len(*rows) != 2 || !bytes.Contains([]byte((*rows)[1]), []byte(`"cost_usd":null`)) {
+		t.Fatalf("Expected two rows in one insert, got %q", *rows)
+	}
+
+	got, err := s.Query(base, base.Add(time.Hour))
+	if err != nil || len(got) != 2 {
+		t.Fatalf("Expected 2 records, got %d, %v", len(got), err)
+	}
+	if r := got[0]; !r.Time.Equal(base) || r.TTFTMS != 180 || r.LatencyMS != 420 || !r.Stream || r.CostUSD == nil || *r.CostUSD != cost {
+		t.Errorf("Unexpected record: %+v", r)
+	}
+	if got[1].CostUSD != nil || got[1].Status != 429 {
+		t.Errorf("Unexpected record: %+v", got[1])
+	}
+
+	if n, err := s.Erase("team-a"); err != nil || n != 2 {
+		t.Errorf("Expected 2 records erased, got %d, %v", n, err)
+	}
+
+	// Errors carry ClickHouse's message
+	s.cfg.Password = "wrong"
+	if err := s.Add(&Record{Time: base}); err == nil || !strings.Contains(err.Error(), "Authentication failed") {
+		t.Errorf("Expected an authentication error, got %v", err)
+	}
+}
+
+// TestClickHouseServer runs against a real server when
+// LETLLM_TEST_CLICKHOUSE_URL is set
+func TestClickHouseServer(t *testing.T) {
+	url := os.Getenv("LETLLM_TEST_CLICKHOUSE_URL")
+	if url == "" {
+		t.Skip("LETLLM_TEST_CLICKHOUSE_URL not set")
+	}
+	table := fmt.Sprintf("usage_test_%d", time.Now().UnixNano())
+	s, err := NewClickHouseStore(config.ClickHouseConfig{URL: url, Table: table})
+	if err != nil {
+		t.Fatalf("NewClickHouseStore failed: %v", err)
+	}
+	defer s.exec("DROP TABLE "+table, nil, nil)
+
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cost := 0.0025
+	for i := 0; i < 3; i++ {
+		if err := s.Add(&Record{Time: base.Add(time.Duration(i) * time.Hour), KeyID: "team-a", Model: "gpt-4o",
+			Status: 200, LatencyMS: 420, TTFTMS: 180, CostUSD: &cost}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	got, err := s.Query(base, base.Add(2*time.Hour))
+	if err != nil || len(got) != 2 || !got[0].Time.Equal(base) || got[0].TTFTMS != 180 || got[0].CostUSD == nil || *got[0].CostUSD != cost {
+		t.Fatalf("Unexpected records: %d, %v", len(got), err)
+	}
+	if n, err := s.Erase("team-a"); err != nil || n != 3 {
+		t.Errorf("Expected 3 records erased, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,66 @@
+package usage
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultMemoryCapacity is the number of records kept by a MemoryStore
+const DefaultMemoryCapacity = 100000
+
+// MemoryStore keeps the most recent records in memory
+type MemoryStore struct {
+	mu       sync.RWMutex
+	capacity int
+	records  []*Record
+}
+
+// NewMemoryStore creates a store holding up to capacity records
+func NewMemoryStore(capacity int) *MemoryStore {
+	return &MemoryStore{capacity: capacity}
+}
+
+// Add appends a record, dropping the oldest once at capacity
+func (s *MemoryStore) Add(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.records) >= s.capacity {
+		s.records = append(s.records[:0], s.records[len(s.records)-s.capacity+1:]...)
+	}
+	cp := *r
+	s.records = append(s.records, &cp)
+	return nil
+}
+
+// Query returns records with from <= Time < to
+func (s *MemoryStore) Query(from, to time.Time) ([]*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*Record
+	for _, r := range s.records {
+		if !r.Time.Before(from) && r.Time.Before(to) {
+			cp := *r
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+// Erase deletes every record of a key
+func (s *MemoryStore) Erase(keyID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.records[:0]
+	for _, r := range s.records {
+		if r.KeyID != keyID {
+			kept = append(kept, r)
+		}
+	}
+	n := len(s.records) - len(kept)
+	clear(s.records[len(kept):])
+	s.records = kept
+	return n, nil
+}
@@ -0,0 +1,52 @@
+package usage
+
+import (
+	"context"
+
+	"github.com/luguanyu1234/letllm-go/internal/config"
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+	"go.uber.org/fx"
+)
+
+// Module provides the usage record Store.
+var Module = fx.Provide(NewStore)
+
+// NewStore keeps usage records in ClickHouse when configured, otherwise in
+// the shared database when one is configured, and the most recent records
+// in memory otherwise. Records are written in the background, and the
+// waiting ones on shutdown.
+func NewStore(lc fx.Lifecycle, cfg *config.Config, db *storage.DB, m *metrics.Registry) (Store, error) {
+	var store Store
+	switch {
+	case cfg.Usage.Backend == config.UsageBackendClickHouse:
+		s, err := NewClickHouseStore(cfg.Usage.ClickHouse)
+		if err != nil {
+			return nil, err
+		}
+		store = s
+	case db != nil:
+		store = NewSQLStore(db)
+	default:
+		store = NewMemoryStore(DefaultMemoryCapacity)
+	}
+
+	r := NewRecorder(store, cfg.Usage.BatchSize, cfg.Usage.QueueSize, m)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	lc.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go func() {
+				defer close(done)
+				r.Run(ctx, cfg.Usage.FlushInterval)
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			<-done
+			return nil
+		},
+	})
+	return r, nil
+}
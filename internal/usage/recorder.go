@@ -0,0 +1,129 @@
+package usage
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+)
+
+// Recorder is a Store that writes records to another in the background, in
+// batches, so that requests do not wait on it. Queries and erasures see
+// every record added before them.
+type Recorder struct {
+	store     Store
+	batchSize int
+	queueSize int
+
+	mu      sync.Mutex
+	pending []*Record
+	wake    chan struct{}
+	// writing serializes batches, so records are written in order
+	writing sync.Mutex
+
+	dropped *metrics.CounterVec
+	failed  *metrics.CounterVec
+}
+
+// NewRecorder writes records to store in batches of up to batchSize,
+// dropping records added while queueSize are waiting
+func NewRecorder(store Store, batchSize, queueSize int, m *metrics.Registry) *Recorder {
+	return &Recorder{
+		store:     store,
+		batchSize: batchSize,
+		queueSize: queueSize,
+		wake:      make(chan struct{}, 1),
+		dropped: m.Counter("letllm_usage_records_dropped_total",
+			"Usage records dropped because too many were waiting to be written."),
+		failed: m.Counter("letllm_usage_records_failed_total",
+			"Usage records lost because writing them failed."),
+	}
+}
+
+// Add queues a record to be written
+func (r *Recorder) Add(rec *Record) error {
+	cp := *rec
+	r.mu.Lock()
+	if len(r.pending) >= r.queueSize {
+		r.mu.Unlock()
+		r.dropped.Inc()
+		return nil
+	}
+	r.pending = append(r.pending, &cp)
+	full := len(r.pending) >= r.batchSize
+	r.mu.Unlock()
+	if full {
+		select {
+		case r.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Query returns records with from <= Time < to once the waiting ones are
+// written
+func (r *Recorder) Query(from, to time.Time) ([]*Record, error) {
+	r.Flush()
+	return r.store.Query(from, to)
+}
+
+// Erase deletes every record of a key, waiting ones included
+func (r *Recorder) Erase(keyID string) (int, error) {
+	r.Flush()
+	return r.store.Erase(keyID)
+}
+
+// Run writes waiting records every interval, or as soon as a batch is full,
+// until ctx is done, and then writes the rest
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.Flush()
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+		r.Flush()
+	}
+}
+
+// Flush writes the waiting records
+func (r *Recorder) Flush() {
+	r.writing.Lock()
+	defer r.writing.Unlock()
+	for {
+		r.mu.Lock()
+		n := min(len(r.pending), r.batchSize)
+		batch := r.pending[:n:n]
+		r.pending = r.pending[n:]
+		if len(r.pending) == 0 {
+			r.pending = nil
+		}
+		r.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if err := r.write(batch); err != nil {
+			r.failed.Add(float64(len(batch)))
+			log.Printf("usage: write %d records: %v", len(batch), err)
+		}
+	}
+}
+
+func (r *Recorder) write(batch []*Record) error {
+	if bs, ok := r.store.(BatchStore); ok {
+		return bs.AddBatch(batch)
+	}
+	for _, rec := range batch {
+		if err := r.store.Add(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
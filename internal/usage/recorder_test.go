@@ -0,0 +1,106 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/metrics"
+)
+
+// batchRecorder is a Store that keeps the batches it is given
+type batchRecorder struct {
+	*MemoryStore
+	mu      sync.Mutex
+	batches []int
+	err     error
+}
+
+func (s *batchRecorder) AddBatch(records []*Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, len(records))
+	for _, r := range records {
+		_ = s.MemoryStore.Add(r)
+	}
+	return nil
+}
+
+func TestRecorder(t *testing.T) {
+	store := &batchRecorder{MemoryStore: NewMemoryStore(100)}
+	m := metrics.NewRegistry()
+	r := NewRecorder(store, 2, 4, m)
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		_ = r.Add(&Record{Time: base.Add(time.Duration(i) * time.Minute), KeyID: "team-a", Model: "gpt-4o"})
+	}
+	// Records beyond the queue are dropped rather than held up
+	if got := m.Counter("letllm_usage_records_dropped_total", "").Value(); got != 1 {
+		t.Fatalf("Expected 1 record dropped, got %v", got)
+	}
+	if got, _ := store.MemoryStore.Query(base, base.Add(time.Hour)); len(got) != 0 {
+		t.Fatalf("Expected records to wait for a flush, got %d written", len(got))
+	}
+
+	// Queries see every record added before them, written in batches
+	got, err := r.Query(base, base.Add(time.Hour))
+	if err != nil || len(got) != 4 || !got[0].Time.Equal(base) {
+		t.Fatalf("Expected 4 records, got %d, %v", len(got), err)
+	}
+	if len(store.batches) != 2 || store.batches[0] != 2 || store.batches[1] != 2 {
+		t.Errorf("Expected two batches of 2, got %v", store.batches)
+	}
+
+	// Failed writes are counted and do not block later ones
+	store.err = errors.New("unavailable")
+	_ = r.Add(&Record{Time: base, KeyID: "team-a"})
+	r.Flush()
+	if got := m.Counter("letllm_usage_records_failed_total", "").Value(); got != 1 {
+		t.Errorf("Expected 1 record failed, got %v", got)
+	}
+	store.err = nil
+
+	if n, err := r.Erase("team-a"); err != nil || n != 4 {
+		t.Errorf("Expected 4 records erased, got %d, %v", n, err)
+	}
+}
+
+func TestRecorderRun(t *testing.T) {
+	store := &batchRecorder{MemoryStore: NewMemoryStore(100)}
+	r := NewRecorder(store, 2, 100, metrics.NewRegistry())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Run(ctx, time.Hour)
+	}()
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	// A full batch is written without waiting for the interval
+	_ = r.Add(&Record{Time: base, Model: "gpt-4o"})
+	_ = r.Add(&Record{Time: base, Model: "gpt-4o"})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if got, _ := store.MemoryStore.Query(base, base.Add(time.Hour)); len(got) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a full batch to be written")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// and the rest once stopped
+	_ = r.Add(&Record{Time: base, Model: "gpt-4o"})
+	cancel()
+	<-done
+	if got, _ := store.MemoryStore.Query(base, base.Add(time.Hour)); len(got) != 3 {
+		t.Errorf("Expected waiting records to be written on stop, got %d", len(got))
+	}
+}
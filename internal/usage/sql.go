@@ -0,0 +1,97 @@
+package usage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/storage"
+)
+
+// SQLStore is a Store backed by the shared database
+type SQLStore struct {
+	db *storage.DB
+}
+
+// NewSQLStore creates a usage store on db; the usage_records table is
+// created by the storage migrations
+func NewSQLStore(db *storage.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+const insertRecord = `INSERT INTO usage_records
+	(ts, key_id, provider, model, status, latency_ms, ttft_ms, prompt_tokens, completion_tokens,
+	cached_prompt_tokens, reasoning_tokens, audio_tokens, stream, origin_key_id, experiment, variant, cost_usd)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+func insertArgs(r *Record) []any {
+	return []any{r.Time.UTC(), r.KeyID, r.Provider, r.Model, r.Status, r.LatencyMS, r.TTFTMS,
+		r.PromptTokens, r.CompletionTokens, r.CachedPromptTokens, r.ReasoningTokens, r.AudioTokens, r.Stream, r.OriginKeyID, r.Experiment, r.Variant, r.CostUSD}
+}
+
+// Add inserts a record
+func (s *SQLStore) Add(r *Record) error {
+	if _, err := s.db.Exec(s.db.Rebind(insertRecord), insertArgs(r)...); err != nil {
+		return fmt.Errorf("add usage record: %w", err)
+	}
+	return nil
+}
+
+// AddBatch inserts records in one transaction
+func (s *SQLStore) AddBatch(records []*Record) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("add usage records: %w", err)
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(s.db.Rebind(insertRecord))
+	if err != nil {
+		return fmt.Errorf("add usage records: %w", err)
+	}
+	defer stmt.Close()
+	for _, r := range records {
+		if _, err := stmt.Exec(insertArgs(r)...); err != nil {
+			return fmt.Errorf("add usage records: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("add usage records: %w", err)
+	}
+	return nil
+}
+
+// Query returns records with from <= Time < to, oldest first
+func (s *SQLStore) Query(from, to time.Time) ([]*Record, error) {
+	rows, err := s.db.Query(s.db.Rebind(`SELECT ts, key_id, provider, model, status, latency_ms, ttft_ms,
+		prompt_tokens, completion_tokens, cached_prompt_tokens, reasoning_tokens, audio_tokens, stream, origin_key_id, experiment, variant, cost_usd FROM usage_records WHERE ts >= ? AND ts < ? ORDER BY ts, id`),
+		from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("query usage records: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*Record
+	for rows.Next() {
+		var r Record
+		var cost sql.NullFloat64
+		if err := rows.Scan(&r.Time, &r.KeyID, &r.Provider, &r.Model, &r.Status, &r.LatencyMS, &r.TTFTMS,
+			&r.PromptTokens, &r.CompletionTokens, &r.CachedPromptTokens, &r.ReasoningTokens, &r.AudioTokens, &r.Stream, &r.OriginKeyID, &r.Experiment, &r.Variant, &cost); err != nil {
+			return nil, err
+		}
+		if cost.Valid {
+			r.CostUSD = &cost.Float64
+		}
+		out = append(out, &r)
+	}
+	return out, rows.Err()
+}
+
+// Erase deletes every record of a key
+func (s *SQLStore) Erase(keyID string) (int, error) {
+	res, err := s.db.Exec(s.db.Rebind("DELETE FROM usage_records WHERE key_id = ?"), keyID)
+	if err != nil {
+		return 0, fmt.Errorf("erase usage records of %s: %w", keyID, err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
@@ -0,0 +1,48 @@
+package usage
+
+import "time"
+
+// Record is the metadata of one gateway request. Request and response bodies
+// are never recorded.
+type Record struct {
+	Time             time.Time `json:"time"`
+	KeyID            string    `json:"key_id,omitempty"`
+	Provider         string    `json:"provider,omitempty"`
+	Model            string    `json:"model"`
+	Status           int       `json:"status"`
+	LatencyMS        int64     `json:"latency_ms"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	// Breakdowns of the token counts, for providers that report them
+	CachedPromptTokens int  `json:"cached_prompt_tokens,omitempty"`
+	ReasoningTokens    int  `json:"reasoning_tokens,omitempty"`
+	AudioTokens        int  `json:"audio_tokens,omitempty"`
+	Stream             bool `json:"stream,omitempty"`
+	// Key a peer gateway served the request for, when KeyID is that
+	// gateway's federation key
+	OriginKeyID string `json:"origin_key_id,omitempty"`
+	// Experiment and variant the caller was bucketed into
+	Experiment string `json:"experiment,omitempty"`
+	Variant    string `json:"variant,omitempty"`
+	// Cost the provider reported, or the tokens' cost at the model's
+	// price; nil when neither is known
+	CostUSD *float64 `json:"cost_usd,omitempty"`
+	// Time to the first byte of a streamed response
+	TTFTMS int64 `json:"ttft_ms,omitempty"`
+}
+
+// Store persists usage records
+type Store interface {
+	Add(r *Record) error
+	// Query returns records with from <= Time < to, oldest first
+	Query(from, to time.Time) ([]*Record, error)
+	// Erase deletes every record of a key and returns how many were removed
+	Erase(keyID string) (int, error)
+}
+
+// BatchStore is a Store that writes many records at once more cheaply than
+// one by one
+type BatchStore interface {
+	Store
+	AddBatch(records []*Record) error
+}
@@ -0,0 +1,101 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/luguanyu1234/letllm-go/internal/storage/storagetest"
+)
+
+func TestStores(t *testing.T) {
+	db := storagetest.Open(t)
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cost := 0.0025
+	for name, s := range map[string]Store{"memory": NewMemoryStore(10), "sql": NewSQLStore(db)} {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				err := s.Add(&Record{Time: base.Add(time.Duration(i) * time.Hour), KeyID: "team-a", Provider: "openai",
+					Model: "gpt-4o", Status: 200, LatencyMS: 420, PromptTokens: 10, CompletionTokens: 5,
+					CachedPromptTokens: 4, ReasoningTokens: 2, AudioTokens: 1, OriginKeyID: "eu-team", CostUSD: &cost, TTFTMS: 180})
+				if err != nil {
+					t.Fatalf("Add failed: %v", err)
+				}
+			}
+
+			got, err := s.Query(base, base.Add(2*time.Hour))
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(got) != 2 {
+				t.Fatalf("Expected 2 records in range, got %d", len(got))
+			}
+			if got[0].KeyID != "team-a" || got[0].LatencyMS != 420 || !got[0].Time.Equal(base) ||
+				got[0].CachedPromptTokens != 4 || got[0].ReasoningTokens != 2 || got[0].AudioTokens != 1 || got[0].OriginKeyID != "eu-team" ||
+				got[0].CostUSD == nil || *got[0].CostUSD != cost || got[0].TTFTMS != 180 {
+				t.Errorf("Unexpected record: %+v", got[0])
+			}
+
+			_ = s.Add(&Record{Time: base, KeyID: "team-b", Model: "gpt-4o", Status: 200})
+			if n, err := s.Erase("team-a"); err != nil || n != 3 {
+				t.Errorf("Expected 3 records erased, got %d, %v", n, err)
+			}
+			if left, _ := s.Query(base, base.Add(24*time.Hour)); len(left) != 1 || left[0].KeyID != "team-b" || left[0].CostUSD != nil {
+				t.Errorf("Expected only team-b to remain, got %d records", len(left))
+			}
+		})
+	}
+}
+
+func TestMemoryStoreCapacity(t *testing.T) {
+	s := NewMemoryStore(2)
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		_ = s.Add(&Record{Time: base.Add(time.Duration(i) * time.Minute), Model: "m"})
+	}
+	got, _ := s.Query(base, base.Add(time.Hour))
+	if len(got) != 2 || !got[0].Time.Equal(base.Add(time.Minute)) {
+		t.Errorf("Expected the oldest record to be dropped, got %d records", len(got))
+	}
+}
+
+func TestAggregateRecords(t *testing.T) {
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	var records []*Record
+	for i := 0; i < 12; i++ {
+		records = append(records, &Record{Time: day.Add(time.Duration(i) * time.Minute), KeyID: "team-a",
+			Provider: "openai", Model: "gpt-4o", Status: 200, LatencyMS: 300, PromptTokens: 100, CompletionTokens: 50})
+	}
+	records[0].Status = 500
+	// A lone request for a rare model must not be exported on its own
+	records = append(records, &Record{Time: day, KeyID: "team-b", Provider: "openai", Model: "o3", Status: 200, LatencyMS: 9000})
+
+	agg, err := AggregateRecords(records, day, day.Add(24*time.Hour), AggregateOptions{
+		Bucket:       24 * time.Hour,
+		GroupBy:      []string{DimProvider, DimModel},
+		MinGroupSize: 10,
+	})
+	if err != nil {
+		t.Fatalf("AggregateRecords failed: %v", err)
+	}
+
+	if len(agg.Groups) != 1 {
+		t.Fatalf("Expected 1 group, got %d", len(agg.Groups))
+	}
+	g := agg.Groups[0]
+	if g.Model != "gpt-4o" || g.KeyID != "" || g.Requests != 12 || g.Errors != 1 || g.PromptTokens != 1200 {
+		t.Errorf("Unexpected group: %+v", g)
+	}
+	if g.LatencyMS.Counts[2] != 12 {
+		t.Errorf("Expected all latencies in the 500ms bucket, got %v", g.LatencyMS.Counts)
+	}
+	if g.TotalTokens.Counts[1] != 12 {
+		t.Errorf("Expected all token totals in the 500 bucket, got %v", g.TotalTokens.Counts)
+	}
+	if agg.SuppressedGroups != 1 || agg.SuppressedRequests != 1 {
+		t.Errorf("Expected the o3 group to be suppressed, got %d/%d", agg.SuppressedGroups, agg.SuppressedRequests)
+	}
+
+	if _, err := AggregateRecords(records, day, day, AggregateOptions{Bucket: time.Hour, GroupBy: []string{"prompt"}, MinGroupSize: 1}); err == nil {
+		t.Error("Expected error for unknown dimension")
+	}
+}